@@ -19,6 +19,10 @@ package main
 import (
 	"encoding/json"
 	"fmt"
+	"io"
+	"net/url"
+	"regexp"
+	"strconv"
 	"strings"
 	"time"
 
@@ -38,16 +42,25 @@ func newAgentCmd(c *Client) *cobra.Command {
 		Example: "  agentry-admin --admin-key-file admin.key agent register user --mode pull\n" +
 			"  agentry-admin --admin-key-file admin.key agent register api-service --mode push --target http://webhook:8080\n" +
 			"  agentry-admin --admin-key-file admin.key agent register purchase-bot --mode push --target http://webhook:8080 --header \"Auth=Bearer token\"\n" +
-			"  agentry-admin --admin-key-file admin.key agent register sales --mode pull --schema \"agntcy:commerce.*\" --schema \"agntcy:crm.lead.v1\"",
+			"  agentry-admin --admin-key-file admin.key agent register orders --mode push --target http://primary:8080 --target http://audit:8080 --push-policy any\n" +
+			"  agentry-admin --admin-key-file admin.key agent register orders --mode push --target http://east:8080 --target http://west:8080 --push-strategy balanced --target-weight http://east:8080=3\n" +
+			"  agentry-admin --admin-key-file admin.key agent register sales --mode pull --schema \"agntcy:commerce.*\" --schema \"agntcy:crm.lead.v1\"\n" +
+			"  agentry-admin --admin-key-file admin.key agent register '*' --mode push --target http://fallback:8080\n" +
+			"  agentry-admin --admin-key-file admin.key agent register billing --mode kafka --target billing-events",
 		Args: cobra.ExactArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			return runAgentRegister(c, cmd, args)
 		},
 	}
-	registerCmd.Flags().String("mode", "pull", "Delivery mode: 'push' or 'pull'")
-	registerCmd.Flags().String("target", "", "Push target URL (required for push mode)")
-	registerCmd.Flags().StringArray("header", nil, "Custom header in format key=value (can be used multiple times)")
+	registerCmd.Flags().String("mode", "pull", "Delivery mode: 'push', 'pull' or 'kafka'")
+	registerCmd.Flags().StringArray("target", nil, "Push target URL (required for push mode, can be used multiple times to fan out), or topic for kafka mode")
+	registerCmd.Flags().String("push-policy", "", "Fan-out success policy: 'all' (every target must succeed) or 'any'")
+	registerCmd.Flags().String("push-strategy", "", "Multi-target strategy: 'fanout' (every target gets each message) or 'balanced' (one target per message, by weight)")
+	registerCmd.Flags().StringArray("target-weight", nil, "Balanced strategy weight in format url=N (default 1, can be used multiple times)")
+	registerCmd.Flags().StringArray("header", nil, "Custom header in format key=value; values may use templates like {{.Subject}} (can be used multiple times)")
 	registerCmd.Flags().StringArray("schema", nil, "Supported schema in format agntcy:domain.entity.version or agntcy:domain.* (can be used multiple times)")
+	registerCmd.Flags().StringArray("allow-sender", nil, "Only deliver messages from this sender address, domain or *.domain pattern (can be used multiple times; default allows all)")
+	registerCmd.Flags().Int("max-concurrent-deliveries", 0, "Push deliveries in flight to the agent at once (push mode only; default: the gateway's per-agent limit)")
 
 	unregisterCmd := &cobra.Command{
 		Use:   "unregister <name>",
@@ -61,6 +74,41 @@ func newAgentCmd(c *Client) *cobra.Command {
 		},
 	}
 
+	drainInboxCmd := &cobra.Command{
+		Use:     "drain-inbox <name>",
+		Short:   "Acknowledge every message in a local agent's inbox",
+		Long:    "Acknowledge every pending message in a local agent's inbox without delivering it, for example when the agent is being decommissioned.",
+		Example: "  agentry-admin --admin-key-file admin.key agent drain-inbox user",
+		Args:    cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runAgentDrainInbox(c, cmd, args)
+		},
+	}
+
+	getCmd := &cobra.Command{
+		Use:     "get <name>",
+		Short:   "Show a registered agent",
+		Long:    "Show a registered agent. Push agents are shown with the deliveries to them currently in flight and the concurrency limit that applies.",
+		Example: "  agentry-admin --admin-key-file admin.key agent get user",
+		Args:    cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runAgentGet(c, cmd, args)
+		},
+	}
+
+	tokenCmd := &cobra.Command{
+		Use:   "token <name>",
+		Short: "Mint a short-lived token for an agent's inbox",
+		Long: "Mint a token that reads and acknowledges the agent's inbox until it expires, to hand to a short-lived consumer instead of the agent's API key. " +
+			"The token is printed alone on stdout; use it as the bearer credential for the inbox endpoints.",
+		Example: "  agentry-admin --admin-key-file admin.key agent token user --ttl 30m",
+		Args:    cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runAgentToken(c, cmd, args)
+		},
+	}
+	tokenCmd.Flags().String("ttl", "", "How long the token is valid, e.g. 30m (default: the gateway's)")
+
 	listCmd := &cobra.Command{
 		Use:   "list",
 		Short: "List all registered agents",
@@ -70,29 +118,84 @@ func newAgentCmd(c *Client) *cobra.Command {
 		},
 	}
 
-	agentCmd.AddCommand(registerCmd, unregisterCmd, listCmd)
+	idleCmd := &cobra.Command{
+		Use:   "idle",
+		Short: "List agents idle past a threshold with empty inboxes",
+		Long:  "List agents that have neither polled their inbox nor received a delivery within the idle threshold and have no messages waiting, i.e. the agents the gateway's idle agent action would flag or unregister.",
+		Example: "  agentry-admin --admin-key-file admin.key agent idle\n" +
+			"  agentry-admin --admin-key-file admin.key agent idle --idle-after 720h",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runAgentIdle(c, cmd, args)
+		},
+	}
+	idleCmd.Flags().String("idle-after", "", "Idle threshold as a duration (default: the gateway's configured threshold)")
+
+	agentCmd.AddCommand(registerCmd, unregisterCmd, drainInboxCmd, getCmd, tokenCmd, listCmd, idleCmd)
 	return agentCmd
 }
 
 func runAgentRegister(c *Client, cmd *cobra.Command, args []string) error {
 	agentName := args[0]
 	mode, _ := cmd.Flags().GetString("mode")
-	target, _ := cmd.Flags().GetString("target")
+	targets, _ := cmd.Flags().GetStringArray("target")
+	pushPolicy, _ := cmd.Flags().GetString("push-policy")
+	pushStrategy, _ := cmd.Flags().GetString("push-strategy")
+	targetWeights, _ := cmd.Flags().GetStringArray("target-weight")
 	headers, _ := cmd.Flags().GetStringArray("header")
 	schemas, _ := cmd.Flags().GetStringArray("schema")
+	allowedSenders, _ := cmd.Flags().GetStringArray("allow-sender")
+	maxConcurrent, _ := cmd.Flags().GetInt("max-concurrent-deliveries")
 
 	// Validate mode
-	if mode != "push" && mode != "pull" {
-		fmt.Fprintf(cmd.ErrOrStderr(), "Error: Delivery mode must be 'push' or 'pull'\n")
+	if mode != "push" && mode != "pull" && mode != "kafka" {
+		fmt.Fprintf(cmd.ErrOrStderr(), "Error: Delivery mode must be 'push', 'pull' or 'kafka'\n")
 		return errExit
 	}
 
 	// Validate push mode requirements
-	if mode == "push" && target == "" {
+	if mode == "push" && len(targets) == 0 {
 		fmt.Fprintf(cmd.ErrOrStderr(), "Error: Push target URL is required for push mode (--target flag)\n")
 		_ = cmd.Usage()
 		return errExit
 	}
+	if maxConcurrent < 0 || (maxConcurrent > 0 && mode != "push") {
+		fmt.Fprintf(cmd.ErrOrStderr(), "Error: --max-concurrent-deliveries must be positive and requires push mode\n")
+		return errExit
+	}
+	if mode == "kafka" && len(targets) != 1 {
+		fmt.Fprintf(cmd.ErrOrStderr(), "Error: Exactly one topic is required for kafka mode (--target flag)\n")
+		_ = cmd.Usage()
+		return errExit
+	}
+
+	// Validate push policy
+	if pushPolicy != "" && pushPolicy != "all" && pushPolicy != "any" {
+		fmt.Fprintf(cmd.ErrOrStderr(), "Error: Push policy must be 'all' or 'any'\n")
+		return errExit
+	}
+
+	// Validate push strategy
+	if pushStrategy != "" && pushStrategy != "fanout" && pushStrategy != "balanced" {
+		fmt.Fprintf(cmd.ErrOrStderr(), "Error: Push strategy must be 'fanout' or 'balanced'\n")
+		return errExit
+	}
+	if len(targetWeights) > 0 && pushStrategy != "balanced" {
+		fmt.Fprintf(cmd.ErrOrStderr(), "Error: Target weights require --push-strategy balanced\n")
+		return errExit
+	}
+
+	// Parse target weights; the URL may itself contain '=', so split on the last one
+	weightMap := make(map[string]int)
+	for _, tw := range targetWeights {
+		i := strings.LastIndex(tw, "=")
+		weight, err := strconv.Atoi(tw[i+1:])
+		if i <= 0 || err != nil || weight < 1 {
+			fmt.Fprintf(cmd.ErrOrStderr(), "Error: Invalid target weight '%s'. Use url=N format with N of at least 1\n", tw)
+			return errExit
+		}
+		weightMap[tw[:i]] = weight
+	}
 
 	// Parse headers
 	headerMap := make(map[string]string)
@@ -109,9 +212,20 @@ func runAgentRegister(c *Client, cmd *cobra.Command, args []string) error {
 	agent := LocalAgent{
 		Address:          agentName,
 		DeliveryMode:     mode,
-		PushTarget:       target,
+		PushPolicy:       pushPolicy,
+		PushStrategy:     pushStrategy,
 		Headers:          headerMap,
 		SupportedSchemas: schemas,
+		AllowedSenders:   allowedSenders,
+
+		MaxConcurrentDeliveries: maxConcurrent,
+	}
+	if len(weightMap) > 0 {
+		agent.PushTargetWeights = weightMap
+	}
+	if len(targets) > 0 {
+		agent.PushTarget = targets[0]
+		agent.PushTargets = targets[1:]
 	}
 
 	// Make HTTP request with admin authentication
@@ -146,13 +260,37 @@ func runAgentRegister(c *Client, cmd *cobra.Command, args []string) error {
 		fmt.Fprintf(out, "  ⚠️  IMPORTANT: Save this API key securely! It's required for inbox access.\n")
 	}
 	if mode == "push" {
-		fmt.Fprintf(out, "  Target: %s\n", target)
+		for _, target := range targets {
+			if pushStrategy == "balanced" {
+				fmt.Fprintf(out, "  Target: %s (weight %d)\n", target, targetWeight(weightMap, target))
+				continue
+			}
+			fmt.Fprintf(out, "  Target: %s\n", target)
+		}
+		if pushStrategy == "balanced" {
+			fmt.Fprintf(out, "  Push Strategy: balanced\n")
+		} else if len(targets) > 1 {
+			policy := pushPolicy
+			if policy == "" {
+				policy = "all"
+			}
+			fmt.Fprintf(out, "  Push Policy: %s\n", policy)
+		}
 		if len(headerMap) > 0 {
 			fmt.Fprintf(out, "  Headers:\n")
 			for key, value := range headerMap {
 				fmt.Fprintf(out, "    %s: %s\n", key, value)
 			}
 		}
+		if maxConcurrent > 0 {
+			fmt.Fprintf(out, "  Max Concurrent Deliveries: %d\n", maxConcurrent)
+		}
+	}
+	if mode == "kafka" {
+		fmt.Fprintf(out, "  Topic: %s\n", targets[0])
+	}
+	if len(allowedSenders) > 0 {
+		fmt.Fprintf(out, "  Allowed Senders: %s\n", strings.Join(allowedSenders, ", "))
 	}
 	return nil
 }
@@ -189,6 +327,32 @@ func runAgentUnregister(c *Client, cmd *cobra.Command, args []string) error {
 	return nil
 }
 
+func runAgentDrainInbox(c *Client, cmd *cobra.Command, args []string) error {
+	agentName := args[0]
+
+	// Reject full addresses - only accept agent names
+	if strings.Contains(agentName, "@") {
+		fmt.Fprintf(cmd.ErrOrStderr(), "Error: Only agent names are allowed, not full addresses. Use '%s' instead of '%s'\n",
+			strings.Split(agentName, "@")[0], agentName)
+		return errExit
+	}
+
+	resp, err := c.AdminRequest("DELETE", "/v1/admin/agents/"+agentName+"/inbox", nil)
+	if err != nil {
+		fmt.Fprintf(cmd.ErrOrStderr(), "Failed to drain inbox: %v\n", err)
+		return errExit
+	}
+
+	var response DrainInboxResponse
+	if err := json.Unmarshal(resp, &response); err != nil {
+		fmt.Fprintf(cmd.ErrOrStderr(), "Failed to parse response: %v\n", err)
+		return errExit
+	}
+
+	fmt.Fprintf(cmd.OutOrStdout(), "Drained %d message(s) from the inbox of %s\n", response.Drained, response.Address)
+	return nil
+}
+
 func runAgentList(c *Client, cmd *cobra.Command, args []string) error {
 	// Make HTTP request with admin authentication
 	resp, err := c.AdminRequest("GET", "/v1/admin/agents", nil)
@@ -212,28 +376,185 @@ func runAgentList(c *Client, cmd *cobra.Command, args []string) error {
 
 	for address, agent := range response.Agents {
 		fmt.Fprintf(out, "  %s\n", address)
-		fmt.Fprintf(out, "    Mode: %s\n", agent.DeliveryMode)
-		if agent.APIKey != "" {
-			// Show only first 8 characters for security
-			maskedKey := agent.APIKey[:8] + "..."
-			fmt.Fprintf(out, "    API Key: %s (masked)\n", maskedKey)
+		printAgentDetails(out, agent, "    ")
+		fmt.Fprintln(out)
+	}
+	return nil
+}
+
+func runAgentGet(c *Client, cmd *cobra.Command, args []string) error {
+	agentName := args[0]
+
+	// Reject full addresses - only accept agent names
+	if strings.Contains(agentName, "@") {
+		fmt.Fprintf(cmd.ErrOrStderr(), "Error: Only agent names are allowed, not full addresses. Use '%s' instead of '%s'\n",
+			strings.Split(agentName, "@")[0], agentName)
+		return errExit
+	}
+
+	resp, err := c.AdminRequest("GET", "/v1/admin/agents/"+agentName, nil)
+	if err != nil {
+		fmt.Fprintf(cmd.ErrOrStderr(), "Failed to get agent: %v\n", err)
+		return errExit
+	}
+
+	var response GetAgentResponse
+	if err := json.Unmarshal(resp, &response); err != nil || response.Agent == nil {
+		fmt.Fprintf(cmd.ErrOrStderr(), "Failed to parse response: %v\n", err)
+		return errExit
+	}
+
+	out := cmd.OutOrStdout()
+	fmt.Fprintf(out, "%s\n", response.Agent.Address)
+	printAgentDetails(out, response.Agent, "  ")
+	if response.DeliveriesInFlight != nil {
+		limit := "unlimited"
+		if response.DeliveryLimit != nil && *response.DeliveryLimit > 0 {
+			limit = strconv.Itoa(*response.DeliveryLimit)
+		}
+		fmt.Fprintf(out, "  Deliveries In Flight: %d (limit %s)\n", *response.DeliveriesInFlight, limit)
+	}
+	return nil
+}
+
+func runAgentToken(c *Client, cmd *cobra.Command, args []string) error {
+	agentName := args[0]
+	ttl, _ := cmd.Flags().GetString("ttl")
+
+	// Reject full addresses - only accept agent names
+	if strings.Contains(agentName, "@") {
+		fmt.Fprintf(cmd.ErrOrStderr(), "Error: Only agent names are allowed, not full addresses. Use '%s' instead of '%s'\n",
+			strings.Split(agentName, "@")[0], agentName)
+		return errExit
+	}
+	if ttl != "" {
+		if _, err := time.ParseDuration(ttl); err != nil {
+			fmt.Fprintf(cmd.ErrOrStderr(), "Error: Invalid --ttl %q: %v\n", ttl, err)
+			return errExit
 		}
-		if !agent.CreatedAt.IsZero() {
-			fmt.Fprintf(out, "    Created: %s\n", agent.CreatedAt.Format(time.RFC3339))
+	}
+
+	resp, err := c.AdminRequest("POST", "/v1/admin/agents/"+agentName+"/tokens", MintInboxTokenRequest{TTL: ttl})
+	if err != nil {
+		fmt.Fprintf(cmd.ErrOrStderr(), "Failed to mint inbox token: %v\n", err)
+		return errExit
+	}
+
+	var response MintInboxTokenResponse
+	if err := json.Unmarshal(resp, &response); err != nil || response.Token == "" {
+		fmt.Fprintf(cmd.ErrOrStderr(), "Failed to parse response: %v\n", err)
+		return errExit
+	}
+
+	fmt.Fprintln(cmd.OutOrStdout(), response.Token)
+	fmt.Fprintf(cmd.ErrOrStderr(), "Inbox token for %s expires at %s\n", response.Agent, response.ExpiresAt.Format(time.RFC3339))
+	return nil
+}
+
+// printAgentDetails prints everything but the address, each line indented
+func printAgentDetails(out io.Writer, agent *LocalAgent, indent string) {
+	fmt.Fprintf(out, "%sMode: %s\n", indent, agent.DeliveryMode)
+	if agent.APIKey != "" {
+		fmt.Fprintf(out, "%sAPI Key: %s (masked)\n", indent, maskAPIKey(agent.APIKey))
+	}
+	if !agent.CreatedAt.IsZero() {
+		fmt.Fprintf(out, "%sCreated: %s\n", indent, agent.CreatedAt.Format(time.RFC3339))
+	}
+	if !agent.LastAccess.IsZero() {
+		fmt.Fprintf(out, "%sLast Access: %s\n", indent, agent.LastAccess.Format(time.RFC3339))
+	}
+	if agent.DeliveryMode == "kafka" {
+		fmt.Fprintf(out, "%sTopic: %s\n", indent, agent.PushTarget)
+	}
+	if agent.DeliveryMode == "push" {
+		for _, target := range append([]string{agent.PushTarget}, agent.PushTargets...) {
+			if agent.PushStrategy == "balanced" {
+				fmt.Fprintf(out, "%sTarget: %s (weight %d)\n", indent, target, targetWeight(agent.PushTargetWeights, target))
+				continue
+			}
+			fmt.Fprintf(out, "%sTarget: %s\n", indent, target)
 		}
-		if !agent.LastAccess.IsZero() {
-			fmt.Fprintf(out, "    Last Access: %s\n", agent.LastAccess.Format(time.RFC3339))
+		if agent.PushStrategy == "balanced" {
+			fmt.Fprintf(out, "%sPush Strategy: balanced\n", indent)
+		} else if len(agent.PushTargets) > 0 && agent.PushPolicy != "" {
+			fmt.Fprintf(out, "%sPush Policy: %s\n", indent, agent.PushPolicy)
 		}
-		if agent.DeliveryMode == "push" {
-			fmt.Fprintf(out, "    Target: %s\n", agent.PushTarget)
-			if len(agent.Headers) > 0 {
-				fmt.Fprintf(out, "    Headers:\n")
-				for key, value := range agent.Headers {
-					fmt.Fprintf(out, "      %s: %s\n", key, value)
-				}
+		if len(agent.Headers) > 0 {
+			fmt.Fprintf(out, "%sHeaders:\n", indent)
+			for key, value := range agent.Headers {
+				fmt.Fprintf(out, "%s  %s: %s\n", indent, key, value)
 			}
 		}
-		fmt.Fprintln(out)
+		if agent.MaxConcurrentDeliveries > 0 {
+			fmt.Fprintf(out, "%sMax Concurrent Deliveries: %d\n", indent, agent.MaxConcurrentDeliveries)
+		}
+	}
+	if len(agent.AllowedSenders) > 0 {
+		fmt.Fprintf(out, "%sAllowed Senders: %s\n", indent, strings.Join(agent.AllowedSenders, ", "))
+	}
+}
+
+func runAgentIdle(c *Client, cmd *cobra.Command, args []string) error {
+	idleAfter, _ := cmd.Flags().GetString("idle-after")
+
+	path := "/v1/admin/agents/idle"
+	if idleAfter != "" {
+		if d, err := time.ParseDuration(idleAfter); err != nil || d <= 0 {
+			fmt.Fprintf(cmd.ErrOrStderr(), "Error: Idle threshold must be a positive duration such as 720h\n")
+			return errExit
+		}
+		path += "?idle_after=" + url.QueryEscape(idleAfter)
+	}
+
+	resp, err := c.AdminRequest("GET", path, nil)
+	if err != nil {
+		fmt.Fprintf(cmd.ErrOrStderr(), "Failed to list idle agents: %v\n", err)
+		return errExit
+	}
+
+	var response ListIdleAgentsResponse
+	if err := json.Unmarshal(resp, &response); err != nil {
+		fmt.Fprintf(cmd.ErrOrStderr(), "Failed to parse response: %v\n", err)
+		return errExit
+	}
+
+	out := cmd.OutOrStdout()
+	fmt.Fprintf(out, "Found %d agent(s) idle for %s (idle action: %s):\n\n", response.Count, response.IdleAfter, response.Action)
+	if response.Count == 0 {
+		fmt.Fprintln(out, "  No idle agents")
+		return nil
+	}
+	for _, agent := range response.Agents {
+		fmt.Fprintf(out, "  %s\n", agent.Address)
+		fmt.Fprintf(out, "    Mode: %s\n", agent.DeliveryMode)
+		fmt.Fprintf(out, "    Last Active: %s\n", agent.LastActive.Format(time.RFC3339))
+		fmt.Fprintf(out, "    Idle For: %s\n", agent.IdleFor)
 	}
 	return nil
 }
+
+// targetWeight returns a balanced push target's weight, which defaults to 1
+func targetWeight(weights map[string]int, target string) int {
+	if weight, ok := weights[target]; ok {
+		return weight
+	}
+	return 1
+}
+
+// apiKeyPrefixPattern matches a key-scanning prefix such as "amtp_", in the
+// form the gateway accepts for auth.api_key_prefix
+var apiKeyPrefixPattern = regexp.MustCompile(`^[a-z][a-z0-9]{0,14}_`)
+
+// maskedKeyChars is how much key material a masked API key reveals
+const maskedKeyChars = 4
+
+// maskAPIKey keeps any prefix and the first few characters of key material,
+// so prefixed keys stay recognizable without revealing more of the secret
+func maskAPIKey(key string) string {
+	prefix := apiKeyPrefixPattern.FindString(key)
+	material := strings.TrimPrefix(key, prefix)
+	if len(material) <= 2*maskedKeyChars {
+		return prefix + "..."
+	}
+	return prefix + material[:maskedKeyChars] + "..."
+}
@@ -76,6 +76,91 @@ func TestAgentRegister_PushHeadersParsed(t *testing.T) {
 	}
 }
 
+func TestAgentRegister_MultiplePushTargets(t *testing.T) {
+	resp := `{"agent":{"address":"orders@localhost","delivery_mode":"push"}}`
+	srv, cap := newMockGateway(t, 200, resp)
+	keyFile := writeTempFile(t, "admin-key")
+
+	stdout, stderr, err := runCLI(t, srv.URL, srv.Client(),
+		"--admin-key-file", keyFile,
+		"agent", "register", "orders", "--mode", "push",
+		"--target", "http://primary:8080", "--target", "http://audit:8080",
+		"--push-policy", "any")
+	if err != nil {
+		t.Fatalf("unexpected error: %v (stderr: %s)", err, stderr)
+	}
+	var sent LocalAgent
+	if e := json.Unmarshal(cap.Body, &sent); e != nil {
+		t.Fatalf("decode request body: %v", e)
+	}
+	if sent.PushTarget != "http://primary:8080" {
+		t.Errorf("push_target = %q", sent.PushTarget)
+	}
+	if len(sent.PushTargets) != 1 || sent.PushTargets[0] != "http://audit:8080" {
+		t.Errorf("push_targets = %v", sent.PushTargets)
+	}
+	if sent.PushPolicy != "any" {
+		t.Errorf("push_policy = %q", sent.PushPolicy)
+	}
+	if !strings.Contains(stdout, "Target: http://audit:8080") || !strings.Contains(stdout, "Push Policy: any") {
+		t.Errorf("stdout = %q", stdout)
+	}
+}
+
+func TestAgentRegister_BalancedPushTargets(t *testing.T) {
+	resp := `{"agent":{"address":"orders@localhost","delivery_mode":"push"}}`
+	srv, cap := newMockGateway(t, 200, resp)
+	keyFile := writeTempFile(t, "admin-key")
+
+	stdout, stderr, err := runCLI(t, srv.URL, srv.Client(),
+		"--admin-key-file", keyFile,
+		"agent", "register", "orders", "--mode", "push",
+		"--target", "http://east:8080/hook?v=1", "--target", "http://west:8080",
+		"--push-strategy", "balanced", "--target-weight", "http://east:8080/hook?v=1=3")
+	if err != nil {
+		t.Fatalf("unexpected error: %v (stderr: %s)", err, stderr)
+	}
+	var sent LocalAgent
+	if e := json.Unmarshal(cap.Body, &sent); e != nil {
+		t.Fatalf("decode request body: %v", e)
+	}
+	if sent.PushStrategy != "balanced" {
+		t.Errorf("push_strategy = %q", sent.PushStrategy)
+	}
+	if len(sent.PushTargetWeights) != 1 || sent.PushTargetWeights["http://east:8080/hook?v=1"] != 3 {
+		t.Errorf("push_target_weights = %v", sent.PushTargetWeights)
+	}
+	if !strings.Contains(stdout, "Target: http://east:8080/hook?v=1 (weight 3)") ||
+		!strings.Contains(stdout, "Target: http://west:8080 (weight 1)") ||
+		!strings.Contains(stdout, "Push Strategy: balanced") {
+		t.Errorf("stdout = %q", stdout)
+	}
+}
+
+func TestAgentRegister_InvalidTargetWeight(t *testing.T) {
+	keyFile := writeTempFile(t, "admin-key")
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"--push-strategy", "random"}, "Push strategy must be 'fanout' or 'balanced'"},
+		{[]string{"--target-weight", "http://a:8080=2"}, "Target weights require --push-strategy balanced"},
+		{[]string{"--push-strategy", "balanced", "--target-weight", "http://a:8080"}, "Invalid target weight"},
+		{[]string{"--push-strategy", "balanced", "--target-weight", "http://a:8080=0"}, "Invalid target weight"},
+	}
+	for _, tt := range tests {
+		args := append([]string{"--admin-key-file", keyFile,
+			"agent", "register", "x", "--mode", "push", "--target", "http://a:8080"}, tt.args...)
+		_, stderr, err := runCLI(t, "http://127.0.0.1:0", nil, args...)
+		if !errors.Is(err, errExit) {
+			t.Fatalf("%v: err = %v, want errExit", tt.args, err)
+		}
+		if !strings.Contains(stderr, tt.want) {
+			t.Errorf("%v: stderr = %q", tt.args, stderr)
+		}
+	}
+}
+
 func TestAgentRegister_InvalidMode(t *testing.T) {
 	keyFile := writeTempFile(t, "admin-key")
 	_, stderr, err := runCLI(t, "http://127.0.0.1:0", nil,
@@ -84,11 +169,41 @@ func TestAgentRegister_InvalidMode(t *testing.T) {
 	if !errors.Is(err, errExit) {
 		t.Fatalf("err = %v, want errExit", err)
 	}
-	if !strings.Contains(stderr, "Delivery mode must be 'push' or 'pull'") {
+	if !strings.Contains(stderr, "Delivery mode must be 'push', 'pull' or 'kafka'") {
 		t.Errorf("stderr = %q", stderr)
 	}
 }
 
+func TestAgentRegister_Kafka(t *testing.T) {
+	resp := `{"agent":{"address":"billing@localhost","delivery_mode":"kafka","push_target":"billing-events"}}`
+	srv, cap := newMockGateway(t, 200, resp)
+	keyFile := writeTempFile(t, "admin-key")
+
+	stdout, stderr, err := runCLI(t, srv.URL, srv.Client(),
+		"--admin-key-file", keyFile,
+		"agent", "register", "billing", "--mode", "kafka", "--target", "billing-events")
+	if err != nil {
+		t.Fatalf("unexpected error: %v (stderr: %s)", err, stderr)
+	}
+	var sent LocalAgent
+	if e := json.Unmarshal(cap.Body, &sent); e != nil {
+		t.Fatalf("decode request body: %v", e)
+	}
+	if sent.DeliveryMode != "kafka" || sent.PushTarget != "billing-events" {
+		t.Errorf("sent agent = %+v", sent)
+	}
+	if !strings.Contains(stdout, "Topic: billing-events") {
+		t.Errorf("stdout = %q", stdout)
+	}
+
+	_, stderr, err = runCLI(t, srv.URL, srv.Client(),
+		"--admin-key-file", keyFile,
+		"agent", "register", "billing", "--mode", "kafka")
+	if !errors.Is(err, errExit) || !strings.Contains(stderr, "Exactly one topic is required") {
+		t.Errorf("err = %v, stderr = %q", err, stderr)
+	}
+}
+
 func TestAgentRegister_PushRequiresTarget(t *testing.T) {
 	keyFile := writeTempFile(t, "admin-key")
 	_, stderr, err := runCLI(t, "http://127.0.0.1:0", nil,
@@ -115,6 +230,149 @@ func TestAgentUnregister_RejectsFullAddress(t *testing.T) {
 	}
 }
 
+func TestAgentDrainInbox(t *testing.T) {
+	srv, cap := newMockGateway(t, 200, `{"message":"Inbox drained","address":"user@localhost","drained":3}`)
+	keyFile := writeTempFile(t, "admin-key")
+	stdout, stderr, err := runCLI(t, srv.URL, srv.Client(),
+		"--admin-key-file", keyFile, "agent", "drain-inbox", "user")
+	if err != nil {
+		t.Fatalf("unexpected error: %v (stderr: %s)", err, stderr)
+	}
+	if cap.Method != "DELETE" || cap.Path != "/v1/admin/agents/user/inbox" {
+		t.Errorf("request = %s %s", cap.Method, cap.Path)
+	}
+	if !strings.Contains(stdout, "Drained 3 message(s) from the inbox of user@localhost") {
+		t.Errorf("stdout = %q", stdout)
+	}
+}
+
+func TestAgentDrainInbox_RejectsFullAddress(t *testing.T) {
+	keyFile := writeTempFile(t, "admin-key")
+	_, stderr, err := runCLI(t, "http://127.0.0.1:0", nil,
+		"--admin-key-file", keyFile,
+		"agent", "drain-inbox", "user@localhost")
+	if !errors.Is(err, errExit) {
+		t.Fatalf("err = %v, want errExit", err)
+	}
+	if !strings.Contains(stderr, "Use 'user' instead of 'user@localhost'") {
+		t.Errorf("stderr = %q", stderr)
+	}
+}
+
+func TestAgentRegister_AllowedSenders(t *testing.T) {
+	resp := `{"agent":{"address":"orders@localhost","delivery_mode":"pull"}}`
+	srv, cap := newMockGateway(t, 200, resp)
+	keyFile := writeTempFile(t, "admin-key")
+
+	stdout, stderr, err := runCLI(t, srv.URL, srv.Client(),
+		"--admin-key-file", keyFile,
+		"agent", "register", "orders",
+		"--allow-sender", "billing@partner.com", "--allow-sender", "*.trusted.net")
+	if err != nil {
+		t.Fatalf("unexpected error: %v (stderr: %s)", err, stderr)
+	}
+	var sent LocalAgent
+	if e := json.Unmarshal(cap.Body, &sent); e != nil {
+		t.Fatalf("decode request body: %v", e)
+	}
+	if strings.Join(sent.AllowedSenders, ",") != "billing@partner.com,*.trusted.net" {
+		t.Errorf("allowed_senders = %v", sent.AllowedSenders)
+	}
+	if !strings.Contains(stdout, "Allowed Senders: billing@partner.com, *.trusted.net") {
+		t.Errorf("stdout = %q", stdout)
+	}
+}
+
+func TestAgentGet(t *testing.T) {
+	resp := `{"agent":{"address":"hook@localhost","delivery_mode":"push","push_target":"http://hook:8080","max_concurrent_deliveries":2},"deliveries_in_flight":1,"delivery_limit":2}`
+	srv, cap := newMockGateway(t, 200, resp)
+	keyFile := writeTempFile(t, "admin-key")
+	stdout, stderr, err := runCLI(t, srv.URL, srv.Client(),
+		"--admin-key-file", keyFile, "agent", "get", "hook")
+	if err != nil {
+		t.Fatalf("unexpected error: %v (stderr: %s)", err, stderr)
+	}
+	if cap.Method != "GET" || cap.Path != "/v1/admin/agents/hook" {
+		t.Errorf("request = %s %s", cap.Method, cap.Path)
+	}
+	for _, want := range []string{"hook@localhost", "  Target: http://hook:8080", "  Max Concurrent Deliveries: 2", "  Deliveries In Flight: 1 (limit 2)"} {
+		if !strings.Contains(stdout, want) {
+			t.Errorf("stdout = %q, want %q", stdout, want)
+		}
+	}
+}
+
+func TestAgentToken(t *testing.T) {
+	resp := `{"token":"eyJhbGciOiJIUzI1NiJ9.e30.sig","agent":"user@localhost","scope":"inbox","expires_at":"2026-01-01T12:30:00Z"}`
+	srv, cap := newMockGateway(t, 201, resp)
+	keyFile := writeTempFile(t, "admin-key")
+
+	stdout, stderr, err := runCLI(t, srv.URL, srv.Client(),
+		"--admin-key-file", keyFile, "agent", "token", "user", "--ttl", "30m")
+	if err != nil {
+		t.Fatalf("unexpected error: %v (stderr: %s)", err, stderr)
+	}
+	if cap.Method != "POST" || cap.Path != "/v1/admin/agents/user/tokens" {
+		t.Errorf("request = %s %s", cap.Method, cap.Path)
+	}
+	var sent MintInboxTokenRequest
+	if e := json.Unmarshal(cap.Body, &sent); e != nil || sent.TTL != "30m" {
+		t.Errorf("request body = %s", cap.Body)
+	}
+	if stdout != "eyJhbGciOiJIUzI1NiJ9.e30.sig\n" {
+		t.Errorf("stdout = %q, want only the token", stdout)
+	}
+	if !strings.Contains(stderr, "expires at 2026-01-01T12:30:00Z") {
+		t.Errorf("stderr = %q", stderr)
+	}
+
+	if _, _, err := runCLI(t, srv.URL, srv.Client(),
+		"--admin-key-file", keyFile, "agent", "token", "user", "--ttl", "soon"); !errors.Is(err, errExit) {
+		t.Errorf("err = %v, want errExit for an invalid ttl", err)
+	}
+}
+
+func TestAgentRegister_MaxConcurrentDeliveries(t *testing.T) {
+	resp := `{"agent":{"address":"hook@localhost","delivery_mode":"push"}}`
+	srv, cap := newMockGateway(t, 200, resp)
+	keyFile := writeTempFile(t, "admin-key")
+
+	_, stderr, err := runCLI(t, srv.URL, srv.Client(),
+		"--admin-key-file", keyFile,
+		"agent", "register", "hook", "--mode", "push",
+		"--target", "http://hook:8080", "--max-concurrent-deliveries", "3")
+	if err != nil {
+		t.Fatalf("unexpected error: %v (stderr: %s)", err, stderr)
+	}
+	var sent LocalAgent
+	if e := json.Unmarshal(cap.Body, &sent); e != nil {
+		t.Fatalf("decode request body: %v", e)
+	}
+	if sent.MaxConcurrentDeliveries != 3 {
+		t.Errorf("max_concurrent_deliveries = %d", sent.MaxConcurrentDeliveries)
+	}
+
+	_, stderr, err = runCLI(t, srv.URL, srv.Client(),
+		"--admin-key-file", keyFile,
+		"agent", "register", "puller", "--max-concurrent-deliveries", "3")
+	if !errors.Is(err, errExit) || !strings.Contains(stderr, "requires push mode") {
+		t.Errorf("err = %v, stderr = %q", err, stderr)
+	}
+}
+
+func TestAgentList_AllowedSenders(t *testing.T) {
+	srv, _ := newMockGateway(t, 200, `{"count":1,"agents":{"orders@localhost":{"address":"orders@localhost","delivery_mode":"pull","allowed_senders":["example.com"]}}}`)
+	keyFile := writeTempFile(t, "admin-key")
+	stdout, stderr, err := runCLI(t, srv.URL, srv.Client(),
+		"--admin-key-file", keyFile, "agent", "list")
+	if err != nil {
+		t.Fatalf("unexpected error: %v (stderr: %s)", err, stderr)
+	}
+	if !strings.Contains(stdout, "    Allowed Senders: example.com") {
+		t.Errorf("stdout = %q", stdout)
+	}
+}
+
 func TestAgentList_Empty(t *testing.T) {
 	srv, _ := newMockGateway(t, 200, `{"count":0,"agents":{}}`)
 	keyFile := writeTempFile(t, "admin-key")
@@ -127,3 +385,50 @@ func TestAgentList_Empty(t *testing.T) {
 		t.Errorf("stdout = %q", stdout)
 	}
 }
+
+func TestAgentIdle(t *testing.T) {
+	resp := `{"count":1,"idle_after":"720h0m0s","action":"flag","agents":[{"address":"old@localhost","delivery_mode":"pull","last_active":"2026-01-02T03:04:05Z","idle_for":"2000h0m0s"}]}`
+	srv, cap := newMockGateway(t, 200, resp)
+	keyFile := writeTempFile(t, "admin-key")
+	stdout, stderr, err := runCLI(t, srv.URL, srv.Client(),
+		"--admin-key-file", keyFile, "agent", "idle", "--idle-after", "720h")
+	if err != nil {
+		t.Fatalf("unexpected error: %v (stderr: %s)", err, stderr)
+	}
+	if cap.Path != "/v1/admin/agents/idle" || cap.Query != "idle_after=720h" {
+		t.Errorf("request = %s?%s", cap.Path, cap.Query)
+	}
+	for _, want := range []string{
+		"Found 1 agent(s) idle for 720h0m0s (idle action: flag)",
+		"  old@localhost",
+		"Last Active: 2026-01-02T03:04:05Z",
+		"Idle For: 2000h0m0s",
+	} {
+		if !strings.Contains(stdout, want) {
+			t.Errorf("stdout missing %q: %q", want, stdout)
+		}
+	}
+
+	_, stderr, err = runCLI(t, srv.URL, srv.Client(),
+		"--admin-key-file", keyFile, "agent", "idle", "--idle-after", "a month")
+	if !errors.Is(err, errExit) || !strings.Contains(stderr, "positive duration") {
+		t.Errorf("err = %v, stderr = %q", err, stderr)
+	}
+}
+
+func TestMaskAPIKey(t *testing.T) {
+	tests := []struct {
+		key  string
+		want string
+	}{
+		{"amtp_Xy9zQwErTyUiOp", "amtp_Xy9z..."},
+		{"Xy9zQwErTyUiOp", "Xy9z..."},
+		{"amtp_short", "amtp_..."},
+		{"abc", "..."},
+	}
+	for _, tt := range tests {
+		if got := maskAPIKey(tt.key); got != tt.want {
+			t.Errorf("maskAPIKey(%q) = %q, want %q", tt.key, got, tt.want)
+		}
+	}
+}
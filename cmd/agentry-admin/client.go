@@ -23,8 +23,12 @@ import (
 	"io"
 	"net/http"
 	"os"
+	"strconv"
 	"strings"
 	"time"
+
+	"github.com/amtp-protocol/agentry/internal/signing"
+	"github.com/amtp-protocol/agentry/pkg/uuid"
 )
 
 // Client talks to an Agentry gateway's admin and inbox APIs. Its configuration
@@ -56,23 +60,146 @@ func (c *Client) logf(format string, args ...interface{}) {
 // AdminRequest performs an admin-authenticated request, reading the admin key
 // from the configured key file and sending it in the X-Admin-Key header.
 func (c *Client) AdminRequest(method, endpoint string, body interface{}) ([]byte, error) {
+	adminKey, err := c.adminKey()
+	if err != nil {
+		return nil, err
+	}
+
+	return c.do("admin", method, endpoint, body, func(req *http.Request) {
+		req.Header.Set("X-Admin-Key", adminKey)
+	})
+}
+
+// AdminStream performs an admin-authenticated GET and hands the response body
+// to consume without buffering it, for endpoints that stream large results.
+// The client timeout is lifted for the duration of the stream because a long
+// export can legitimately outlive it.
+func (c *Client) AdminStream(endpoint string, consume func(io.Reader) error) error {
+	adminKey, err := c.adminKey()
+	if err != nil {
+		return err
+	}
+
+	return c.stream("admin", endpoint, consume, func(req *http.Request) {
+		req.Header.Set("X-Admin-Key", adminKey)
+	})
+}
+
+// AuthenticatedStream is AdminStream for endpoints authenticated with an
+// agent API key.
+func (c *Client) AuthenticatedStream(endpoint, apiKey string, consume func(io.Reader) error) error {
+	return c.stream("authenticated", endpoint, consume, func(req *http.Request) {
+		req.Header.Set("Authorization", "Bearer "+apiKey)
+	})
+}
+
+func (c *Client) stream(kind, endpoint string, consume func(io.Reader) error, auth func(*http.Request)) error {
+	url := strings.TrimRight(c.GatewayURL, "/") + endpoint
+	c.logf("Making %s GET stream request to: %s\n", kind, url)
+
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	auth(req)
+
+	streamClient := *c.HTTP
+	streamClient.Timeout = 0
+	resp, err := streamClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to make request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	c.logf("Response status: %d\n", resp.StatusCode)
+
+	if resp.StatusCode >= 400 {
+		respBody, _ := io.ReadAll(resp.Body)
+		var errorResp struct {
+			Error struct {
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		if json.Unmarshal(respBody, &errorResp) == nil && errorResp.Error.Message != "" {
+			return fmt.Errorf("API error (%d): %s", resp.StatusCode, errorResp.Error.Message)
+		}
+		return fmt.Errorf("API error (%d): %s", resp.StatusCode, string(respBody))
+	}
+
+	return consume(resp.Body)
+}
+
+// Environment variables consulted when the corresponding flags are not set.
+// AGENTRY_ADMIN_KEY_FILE suits keys mounted from a secret.
+const (
+	envGatewayURL   = "AGENTRY_GATEWAY_URL"
+	envAdminKey     = "AGENTRY_ADMIN_KEY"
+	envAdminKeyFile = "AGENTRY_ADMIN_KEY_FILE"
+)
+
+// adminKey returns the admin key, taken in order from the --admin-key-file
+// flag, the AGENTRY_ADMIN_KEY environment variable, or the file named by
+// AGENTRY_ADMIN_KEY_FILE.
+func (c *Client) adminKey() (string, error) {
 	if c.AdminKeyFile == "" {
-		return nil, fmt.Errorf("admin key file is required for administrative operations. Use --admin-key-file flag")
+		if adminKey := strings.TrimSpace(os.Getenv(envAdminKey)); adminKey != "" {
+			return adminKey, nil
+		}
 	}
 
-	adminKeyBytes, err := os.ReadFile(c.AdminKeyFile)
+	keyFile := c.AdminKeyFile
+	if keyFile == "" {
+		keyFile = os.Getenv(envAdminKeyFile)
+	}
+	if keyFile == "" {
+		return "", fmt.Errorf("admin key is required for administrative operations. Use --admin-key-file or set %s", envAdminKey)
+	}
+
+	adminKeyBytes, err := os.ReadFile(keyFile)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read admin key file: %w", err)
+		return "", fmt.Errorf("failed to read admin key file: %w", err)
 	}
 	adminKey := strings.TrimSpace(string(adminKeyBytes))
 
 	if adminKey == "" {
-		return nil, fmt.Errorf("admin key file is empty")
+		return "", fmt.Errorf("admin key file is empty")
 	}
+	return adminKey, nil
+}
 
-	return c.do("admin", method, endpoint, body, func(req *http.Request) {
-		req.Header.Set("X-Admin-Key", adminKey)
-	})
+// hasAdminKey reports whether an admin key source is configured
+func (c *Client) hasAdminKey() bool {
+	return c.AdminKeyFile != "" || strings.TrimSpace(os.Getenv(envAdminKey)) != "" || os.Getenv(envAdminKeyFile) != ""
+}
+
+// Probe performs an unauthenticated GET and returns the status code and body
+// without treating error statuses as failures, for endpoints such as /ready
+// whose error responses carry the details worth reporting.
+func (c *Client) Probe(endpoint string) (int, []byte, error) {
+	url := strings.TrimRight(c.GatewayURL, "/") + endpoint
+	c.logf("Making probe GET request to: %s\n", url)
+
+	resp, err := c.HTTP.Get(url)
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to make request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return resp.StatusCode, nil, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	c.logf("Response status: %d\n", resp.StatusCode)
+	c.logf("Response body: %s\n", string(respBody))
+
+	return resp.StatusCode, respBody, nil
+}
+
+// Request performs a request without credentials, for gateway endpoints that
+// do not require an admin key or agent API key.
+func (c *Client) Request(method, endpoint string, body interface{}) ([]byte, error) {
+	return c.do("unauthenticated", method, endpoint, body, func(*http.Request) {})
 }
 
 // AuthenticatedRequest performs a request authenticated with an agent API key
@@ -84,7 +211,7 @@ func (c *Client) AuthenticatedRequest(method, endpoint string, body interface{},
 }
 
 // do builds, sends, and reads a single request. kind labels the request in
-// verbose output ("admin"/"authenticated"); auth sets the relevant auth header.
+// verbose output ("admin"/"authenticated"/"unauthenticated"); auth sets the relevant auth header.
 func (c *Client) do(kind, method, endpoint string, body interface{}, auth func(*http.Request)) ([]byte, error) {
 	url := strings.TrimRight(c.GatewayURL, "/") + endpoint
 
@@ -110,6 +237,14 @@ func (c *Client) do(kind, method, endpoint string, body interface{}, auth func(*
 		req.Header.Set("Content-Type", "application/json")
 	}
 
+	// Gateways with replay protection refuse changes without these
+	nonce, err := uuid.GenerateV4()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	req.Header.Set(signing.TimestampHeader, strconv.FormatInt(time.Now().Unix(), 10))
+	req.Header.Set(signing.NonceHeader, nonce)
+
 	auth(req)
 
 	resp, err := c.HTTP.Do(req)
@@ -51,6 +51,9 @@ func TestAdminRequest_SetsKeyHeaderAndBuildsURL(t *testing.T) {
 	if got := cap.Header.Get("Content-Type"); got != "application/json" {
 		t.Errorf("Content-Type = %q (want application/json for body request)", got)
 	}
+	if cap.Header.Get("X-AMTP-Timestamp") == "" || cap.Header.Get("X-AMTP-Nonce") == "" {
+		t.Errorf("replay headers missing: %v", cap.Header)
+	}
 }
 
 func TestAdminRequest_TrimsKeyFileWhitespace(t *testing.T) {
@@ -82,11 +85,47 @@ func TestAdminRequest_NoContentTypeWithoutBody(t *testing.T) {
 }
 
 func TestAdminRequest_MissingKeyFile(t *testing.T) {
+	t.Setenv(envAdminKey, "")
+	t.Setenv(envAdminKeyFile, "")
 	c := newClient()
 	c.AdminKeyFile = ""
 	_, err := c.AdminRequest("GET", "/v1/admin/schemas", nil)
-	if err == nil || !strings.Contains(err.Error(), "admin key file is required") {
-		t.Fatalf("err = %v, want 'admin key file is required'", err)
+	if err == nil || !strings.Contains(err.Error(), "admin key is required") || !strings.Contains(err.Error(), envAdminKey) {
+		t.Fatalf("err = %v, want 'admin key is required' naming %s", err, envAdminKey)
+	}
+}
+
+func TestAdminRequest_KeyFromEnvironment(t *testing.T) {
+	srv, cap := newMockGateway(t, 200, `{}`)
+	mountedKey := writeTempFile(t, "mounted-key\n")
+
+	tests := []struct {
+		name     string
+		flagFile string
+		envKey   string
+		envFile  string
+		want     string
+	}{
+		{"env key", "", "env-key", "", "env-key"},
+		{"env key file", "", "", mountedKey, "mounted-key"},
+		{"env key wins over env key file", "", "env-key", mountedKey, "env-key"},
+		{"flag wins over env", writeTempFile(t, "flag-key"), "env-key", mountedKey, "flag-key"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(envAdminKey, tt.envKey)
+			t.Setenv(envAdminKeyFile, tt.envFile)
+			c := newTestClient(srv.URL, tt.flagFile)
+			c.HTTP = srv.Client()
+
+			if _, err := c.AdminRequest("GET", "/v1/admin/schemas", nil); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := cap.Header.Get("X-Admin-Key"); got != tt.want {
+				t.Errorf("X-Admin-Key = %q, want %q", got, tt.want)
+			}
+		})
 	}
 }
 
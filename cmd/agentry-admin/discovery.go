@@ -0,0 +1,135 @@
+/*
+ * Copyright 2026 Cong Wang
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+func newDiscoveryCmd(c *Client) *cobra.Command {
+	discoveryCmd := &cobra.Command{
+		Use:   "discovery <domain>",
+		Short: "Show how the gateway discovers a domain (requires admin key)",
+		Example: "  agentry-admin discovery partner.example\n" +
+			"  agentry-admin discovery partner.example --fresh --output json",
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runDiscovery(c, cmd, args)
+		},
+	}
+	discoveryCmd.Flags().Bool("fresh", false, "Look the domain up again instead of using the gateway's cache")
+	discoveryCmd.Flags().StringP("output", "o", "text", "Output format: text or json")
+
+	return discoveryCmd
+}
+
+func runDiscovery(c *Client, cmd *cobra.Command, args []string) error {
+	domain := strings.TrimSpace(args[0])
+	fresh, _ := cmd.Flags().GetBool("fresh")
+	output, _ := cmd.Flags().GetString("output")
+
+	if output != "text" && output != "json" {
+		fmt.Fprintf(cmd.ErrOrStderr(), "Error: Invalid output format %q. Must be 'text' or 'json'\n", output)
+		return errExit
+	}
+	if domain == "" {
+		fmt.Fprintf(cmd.ErrOrStderr(), "Error: Domain is required\n")
+		return errExit
+	}
+
+	endpoint := "/v1/admin/discovery/" + url.PathEscape(domain)
+	if fresh {
+		endpoint += "?fresh=true"
+	}
+	resp, err := c.AdminRequest("GET", endpoint, nil)
+	if err != nil {
+		fmt.Fprintf(cmd.ErrOrStderr(), "Failed to inspect discovery: %v\n", err)
+		return errExit
+	}
+
+	var response DiscoveryResponse
+	if err := json.Unmarshal(resp, &response); err != nil {
+		fmt.Fprintf(cmd.ErrOrStderr(), "Failed to parse response: %v\n", err)
+		return errExit
+	}
+
+	out := cmd.OutOrStdout()
+	if output == "json" {
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(response); err != nil {
+			return err
+		}
+	} else {
+		printDiscovery(cmd, response)
+	}
+
+	// Deliveries to the domain would fail, so scripts should see a failure too
+	if response.Inspection.Capabilities == nil || response.GatewayError != "" {
+		return errExit
+	}
+	return nil
+}
+
+func printDiscovery(cmd *cobra.Command, response DiscoveryResponse) {
+	out := cmd.OutOrStdout()
+	inspection := response.Inspection
+
+	source := "live lookup"
+	if inspection.Cached {
+		source = "cache (use --fresh to look it up again)"
+	}
+	fmt.Fprintf(out, "Domain:   %s\n", inspection.Domain)
+	fmt.Fprintf(out, "Source:   %s\n", source)
+
+	if capabilities := inspection.Capabilities; capabilities != nil {
+		fmt.Fprintf(out, "Gateway:  %s\n", capabilities.Gateway)
+		fmt.Fprintf(out, "Version:  %s\n", capabilities.Version)
+		fmt.Fprintf(out, "TTL:      %s\n", capabilities.TTL)
+		if len(capabilities.Auth) > 0 {
+			fmt.Fprintf(out, "Auth:     %s\n", strings.Join(capabilities.Auth, ", "))
+		}
+		if len(capabilities.Features) > 0 {
+			fmt.Fprintf(out, "Features: %s\n", strings.Join(capabilities.Features, ", "))
+		}
+		if capabilities.MaxSize > 0 {
+			fmt.Fprintf(out, "Max size: %d bytes\n", capabilities.MaxSize)
+		}
+	}
+	if response.GatewayError != "" {
+		fmt.Fprintf(out, "Gateway error: %s\n", response.GatewayError)
+	}
+
+	if len(inspection.Records) > 0 {
+		fmt.Fprintln(out, "\nRecords:")
+		for _, record := range inspection.Records {
+			if record.Error != "" {
+				fmt.Fprintf(out, "  [FAIL] %s\n         %s\n", record.Record, record.Error)
+				continue
+			}
+			fmt.Fprintf(out, "  [OK]   %s\n", record.Record)
+		}
+	}
+	if inspection.Error != "" {
+		fmt.Fprintf(out, "\nError: %s\n", inspection.Error)
+	}
+}
@@ -0,0 +1,92 @@
+/*
+ * Copyright 2026 Cong Wang
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+const discoveryResponse = `{"inspection":{"domain":"partner.example","cached":false,` +
+	`"capabilities":{"version":"1.0","gateway":"https://gw.partner.example","features":["agent-discovery"],"ttl":300000000000},` +
+	`"records":[{"record":"v=amtp2;gateway=https://old.partner.example","error":"unsupported version \"amtp2\""},` +
+	`{"record":"v=amtp1;gateway=https://gw.partner.example;features=agent-discovery","capabilities":{"version":"1.0","gateway":"https://gw.partner.example"}}]},` +
+	`"timestamp":"2026-01-02T03:04:05Z"}`
+
+func TestDiscovery_Text(t *testing.T) {
+	srv, cap := newMockGateway(t, 200, discoveryResponse)
+	keyFile := writeTempFile(t, "admin-key")
+
+	stdout, stderr, err := runCLI(t, srv.URL, srv.Client(),
+		"--admin-key-file", keyFile, "discovery", "partner.example", "--fresh")
+	if err != nil {
+		t.Fatalf("unexpected error: %v (stderr: %s)", err, stderr)
+	}
+	if cap.Method != "GET" || cap.Path != "/v1/admin/discovery/partner.example" || cap.Query != "fresh=true" {
+		t.Errorf("request = %s %s?%s", cap.Method, cap.Path, cap.Query)
+	}
+	for _, want := range []string{
+		"Source:   live lookup",
+		"Gateway:  https://gw.partner.example",
+		"TTL:      5m0s",
+		"Features: agent-discovery",
+		"[FAIL] v=amtp2",
+		`unsupported version "amtp2"`,
+		"[OK]   v=amtp1",
+	} {
+		if !strings.Contains(stdout, want) {
+			t.Errorf("expected output to contain %q, got:\n%s", want, stdout)
+		}
+	}
+}
+
+func TestDiscovery_NoValidRecord(t *testing.T) {
+	srv, cap := newMockGateway(t, 200, `{"inspection":{"domain":"broken.example","cached":false,`+
+		`"records":[{"record":"v=amtp1","error":"missing gateway"}],"error":"no valid AMTP TXT record found"}}`)
+	keyFile := writeTempFile(t, "admin-key")
+
+	stdout, _, err := runCLI(t, srv.URL, srv.Client(), "--admin-key-file", keyFile, "discovery", "broken.example", "-o", "json")
+	if !errors.Is(err, errExit) {
+		t.Errorf("expected errExit, got %v", err)
+	}
+	if cap.Query != "" {
+		t.Errorf("query = %q, want the cache to be used", cap.Query)
+	}
+
+	var response DiscoveryResponse
+	if err := json.Unmarshal([]byte(stdout), &response); err != nil {
+		t.Fatalf("stdout is not JSON: %v (%q)", err, stdout)
+	}
+	if response.Inspection.Error == "" || response.Inspection.Records[0].Error != "missing gateway" {
+		t.Errorf("response = %+v", response)
+	}
+}
+
+func TestDiscovery_InvalidArgs(t *testing.T) {
+	for _, args := range [][]string{
+		{"discovery"},
+		{"discovery", "partner.example", "--output", "yaml"},
+	} {
+		// No server should be hit
+		_, _, err := runCLI(t, "http://127.0.0.1:0", nil, args...)
+		if err == nil {
+			t.Errorf("%v: expected an error", args)
+		}
+	}
+}
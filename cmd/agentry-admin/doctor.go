@@ -0,0 +1,236 @@
+/*
+ * Copyright 2026 Cong Wang
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// checkStatus is the outcome of a single diagnostic check.
+type checkStatus string
+
+const (
+	checkPass checkStatus = "PASS"
+	checkFail checkStatus = "FAIL"
+	checkSkip checkStatus = "SKIP"
+)
+
+// checkResult describes one diagnostic check. Hint tells the operator what to
+// try next and is only printed for checks that did not pass.
+type checkResult struct {
+	Name   string
+	Status checkStatus
+	Detail string
+	Hint   string
+}
+
+func newDoctorCmd(c *Client) *cobra.Command {
+	doctorCmd := &cobra.Command{
+		Use:   "doctor",
+		Short: "Diagnose gateway connectivity, readiness, admin access, and discovery",
+		Example: "  agentry-admin doctor\n" +
+			"  agentry-admin --admin-key-file admin.key doctor --domain example.com",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runDoctor(c, cmd, args)
+		},
+	}
+	doctorCmd.Flags().String("domain", "localhost", "Local domain of the gateway, used for the capabilities lookup")
+
+	return doctorCmd
+}
+
+func runDoctor(c *Client, cmd *cobra.Command, args []string) error {
+	domain, _ := cmd.Flags().GetString("domain")
+
+	results := []checkResult{
+		checkHealth(c),
+		checkReady(c),
+		checkAdminKey(c),
+		checkCapabilities(c, domain),
+	}
+
+	failed := printDoctorReport(cmd.OutOrStdout(), c.GatewayURL, results)
+	if failed > 0 {
+		return errExit
+	}
+	return nil
+}
+
+// printDoctorReport writes the report and returns the number of failed checks.
+func printDoctorReport(out io.Writer, gatewayURL string, results []checkResult) int {
+	fmt.Fprintf(out, "Agentry diagnostics for %s\n\n", gatewayURL)
+
+	counts := make(map[checkStatus]int)
+	for _, r := range results {
+		counts[r.Status]++
+		fmt.Fprintf(out, "[%s] %s: %s\n", r.Status, r.Name, r.Detail)
+		if r.Status != checkPass && r.Hint != "" {
+			fmt.Fprintf(out, "       Hint: %s\n", r.Hint)
+		}
+	}
+
+	fmt.Fprintf(out, "\n%d passed, %d failed, %d skipped\n",
+		counts[checkPass], counts[checkFail], counts[checkSkip])
+	return counts[checkFail]
+}
+
+func checkHealth(c *Client) checkResult {
+	result := checkResult{Name: "Gateway health"}
+
+	status, body, err := c.Probe("/health")
+	if err != nil {
+		result.Status = checkFail
+		result.Detail = err.Error()
+		result.Hint = "Check that the gateway is running and that --gateway-url points at it."
+		return result
+	}
+
+	var health struct {
+		Status     string            `json:"status"`
+		Healthy    bool              `json:"healthy"`
+		Version    string            `json:"version"`
+		Components map[string]string `json:"components"`
+	}
+	if err := json.Unmarshal(body, &health); err != nil {
+		result.Status = checkFail
+		result.Detail = fmt.Sprintf("unexpected response (%d) from /health", status)
+		result.Hint = "Check that --gateway-url points at an Agentry gateway and not a proxy or another service."
+		return result
+	}
+
+	if status != 200 || !health.Healthy {
+		result.Status = checkFail
+		result.Detail = fmt.Sprintf("%s (%s)", health.Status, unhealthyComponents(health.Components, "healthy"))
+		result.Hint = "Check the gateway logs for the failing components listed above."
+		return result
+	}
+
+	result.Status = checkPass
+	result.Detail = fmt.Sprintf("%s (version %s)", health.Status, health.Version)
+	return result
+}
+
+func checkReady(c *Client) checkResult {
+	result := checkResult{Name: "Gateway readiness"}
+
+	status, body, err := c.Probe("/ready")
+	if err != nil {
+		result.Status = checkFail
+		result.Detail = err.Error()
+		result.Hint = "Check that the gateway is running and that --gateway-url points at it."
+		return result
+	}
+
+	var ready struct {
+		Status       string            `json:"status"`
+		Ready        bool              `json:"ready"`
+		Dependencies map[string]string `json:"dependencies"`
+	}
+	if err := json.Unmarshal(body, &ready); err != nil {
+		result.Status = checkFail
+		result.Detail = fmt.Sprintf("unexpected response (%d) from /ready", status)
+		result.Hint = "Check that --gateway-url points at an Agentry gateway and not a proxy or another service."
+		return result
+	}
+
+	if status != 200 || !ready.Ready {
+		result.Status = checkFail
+		result.Detail = fmt.Sprintf("%s (%s)", ready.Status, unhealthyComponents(ready.Dependencies, "ready"))
+		result.Hint = "Check storage connectivity (AMTP_STORAGE_*) and the schema registry configuration."
+		return result
+	}
+
+	result.Status = checkPass
+	result.Detail = ready.Status
+	return result
+}
+
+func checkAdminKey(c *Client) checkResult {
+	result := checkResult{Name: "Admin key"}
+
+	if !c.hasAdminKey() {
+		result.Status = checkSkip
+		result.Detail = "no admin key given"
+		result.Hint = "Pass --admin-key-file or set " + envAdminKey + " to verify administrative access."
+		return result
+	}
+
+	// Listing agents is read-only, so it is safe to use as a probe
+	if _, err := c.AdminRequest("GET", "/v1/admin/agents", nil); err != nil {
+		result.Status = checkFail
+		result.Detail = err.Error()
+		result.Hint = "Check that the key file holds a key from the gateway's admin key file (AMTP_ADMIN_KEY_FILE)."
+		return result
+	}
+
+	result.Status = checkPass
+	result.Detail = "accepted by /v1/admin/agents"
+	return result
+}
+
+func checkCapabilities(c *Client, domain string) checkResult {
+	result := checkResult{Name: "Capabilities lookup"}
+
+	status, body, err := c.Probe("/v1/capabilities/" + url.PathEscape(domain))
+	if err != nil {
+		result.Status = checkFail
+		result.Detail = err.Error()
+		result.Hint = "Check that the gateway is running and that --gateway-url points at it."
+		return result
+	}
+
+	var caps struct {
+		Version string   `json:"version"`
+		Gateway string   `json:"gateway"`
+		Schemas []string `json:"schemas"`
+	}
+	if status != 200 || json.Unmarshal(body, &caps) != nil {
+		result.Status = checkFail
+		result.Detail = fmt.Sprintf("no capabilities found for %s (%d)", domain, status)
+		result.Hint = "Check that --domain matches the gateway's AMTP_DOMAIN and that its _amtp TXT record " +
+			"resolves (set AMTP_DNS_MOCK_MODE=true for local setups)."
+		return result
+	}
+
+	result.Status = checkPass
+	result.Detail = fmt.Sprintf("%s -> %s (AMTP %s, %d schema(s))", domain, caps.Gateway, caps.Version, len(caps.Schemas))
+	return result
+}
+
+// unhealthyComponents lists the components whose state differs from ok, in a
+// stable order for the report.
+func unhealthyComponents(components map[string]string, ok string) string {
+	var failing []string
+	for name, state := range components {
+		if state != ok {
+			failing = append(failing, name+": "+state)
+		}
+	}
+	if len(failing) == 0 {
+		return "no failing components reported"
+	}
+	sort.Strings(failing)
+	return strings.Join(failing, ", ")
+}
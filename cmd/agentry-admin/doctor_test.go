@@ -0,0 +1,145 @@
+/*
+ * Copyright 2026 Cong Wang
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// stubRoute is a canned gateway response.
+type stubRoute struct {
+	status int
+	body   string
+}
+
+// newDoctorGateway starts a gateway stub answering the endpoints doctor
+// probes. Responses are keyed by path; unknown paths return 404.
+func newDoctorGateway(t *testing.T, adminKey string, routes map[string]stubRoute) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if strings.HasPrefix(r.URL.Path, "/v1/admin/") && r.Header.Get("X-Admin-Key") != adminKey {
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = io.WriteString(w, `{"message":"Invalid admin API key"}`)
+			return
+		}
+		route, ok := routes[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = io.WriteString(w, `{"error":{"code":"NOT_FOUND"}}`)
+			return
+		}
+		w.WriteHeader(route.status)
+		_, _ = io.WriteString(w, route.body)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+var healthyRoutes = map[string]stubRoute{
+	"/health":                    {200, `{"status":"healthy","healthy":true,"version":"1.2.3","components":{"router":"healthy"}}`},
+	"/ready":                     {200, `{"status":"ready","ready":true,"dependencies":{"agent_registry":"ready"}}`},
+	"/v1/admin/agents":           {200, `{"agents":{},"count":0}`},
+	"/v1/capabilities/localhost": {200, `{"version":"1.0","gateway":"http://localhost:8080","schemas":["agntcy:commerce.order.v1"]}`},
+}
+
+func TestDoctor_AllPass(t *testing.T) {
+	srv := newDoctorGateway(t, "admin-key", healthyRoutes)
+	keyFile := writeTempFile(t, "admin-key")
+
+	stdout, stderr, err := runCLI(t, srv.URL, srv.Client(), "--admin-key-file", keyFile, "doctor")
+	if err != nil {
+		t.Fatalf("unexpected error: %v (stderr: %s)", err, stderr)
+	}
+
+	for _, want := range []string{
+		"[PASS] Gateway health: healthy (version 1.2.3)",
+		"[PASS] Gateway readiness: ready",
+		"[PASS] Admin key",
+		"[PASS] Capabilities lookup: localhost -> http://localhost:8080 (AMTP 1.0, 1 schema(s))",
+		"4 passed, 0 failed, 0 skipped",
+	} {
+		if !strings.Contains(stdout, want) {
+			t.Errorf("stdout missing %q:\n%s", want, stdout)
+		}
+	}
+	if strings.Contains(stdout, "Hint:") {
+		t.Errorf("expected no hints when all checks pass:\n%s", stdout)
+	}
+}
+
+func TestDoctor_Failures(t *testing.T) {
+	routes := map[string]stubRoute{}
+	for path, route := range healthyRoutes {
+		routes[path] = route
+	}
+	routes["/ready"] = stubRoute{503, `{"status":"not_ready","ready":false,"dependencies":{"agent_registry":"ready","schema_manager":"not_ready"}}`}
+
+	srv := newDoctorGateway(t, "admin-key", routes)
+	keyFile := writeTempFile(t, "wrong-key")
+
+	stdout, _, err := runCLI(t, srv.URL, srv.Client(),
+		"--admin-key-file", keyFile, "doctor", "--domain", "example.com")
+	if !errors.Is(err, errExit) {
+		t.Fatalf("expected errExit, got %v", err)
+	}
+
+	for _, want := range []string{
+		"[PASS] Gateway health",
+		"[FAIL] Gateway readiness: not_ready (schema_manager: not_ready)",
+		"[FAIL] Admin key: API error (401): Invalid admin API key",
+		"[FAIL] Capabilities lookup: no capabilities found for example.com (404)",
+		"Hint: Check that --domain matches",
+		"1 passed, 3 failed, 0 skipped",
+	} {
+		if !strings.Contains(stdout, want) {
+			t.Errorf("stdout missing %q:\n%s", want, stdout)
+		}
+	}
+}
+
+func TestDoctor_NoAdminKeySkips(t *testing.T) {
+	srv := newDoctorGateway(t, "admin-key", healthyRoutes)
+
+	stdout, stderr, err := runCLI(t, srv.URL, srv.Client(), "doctor")
+	if err != nil {
+		t.Fatalf("unexpected error: %v (stderr: %s)", err, stderr)
+	}
+	if !strings.Contains(stdout, "[SKIP] Admin key") || !strings.Contains(stdout, "3 passed, 0 failed, 1 skipped") {
+		t.Errorf("stdout = %s", stdout)
+	}
+}
+
+func TestDoctor_GatewayUnreachable(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	url := srv.URL
+	srv.Close()
+
+	stdout, _, err := runCLI(t, url, nil, "doctor")
+	if !errors.Is(err, errExit) {
+		t.Fatalf("expected errExit, got %v", err)
+	}
+	if !strings.Contains(stdout, "[FAIL] Gateway health: failed to make request") ||
+		!strings.Contains(stdout, "Hint: Check that the gateway is running") {
+		t.Errorf("stdout = %s", stdout)
+	}
+}
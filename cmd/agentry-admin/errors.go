@@ -0,0 +1,114 @@
+/*
+ * Copyright 2026 Cong Wang
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+func newErrorsCmd(c *Client) *cobra.Command {
+	errorsCmd := &cobra.Command{
+		Use:   "errors",
+		Short: "List recent failed deliveries, newest first (requires admin key)",
+		Example: "  agentry-admin errors --since 1h\n" +
+			"  agentry-admin errors --since 2026-01-01T00:00:00Z --limit 20 --output json",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runErrors(c, cmd, args)
+		},
+	}
+	errorsCmd.Flags().String("since", "", "Only failures since an RFC3339 time or a duration ago (e.g. 1h)")
+	errorsCmd.Flags().Int("limit", 100, "Maximum number of failures to list (1-1000)")
+	errorsCmd.Flags().StringP("output", "o", "table", "Output format: table or json")
+
+	return errorsCmd
+}
+
+func runErrors(c *Client, cmd *cobra.Command, args []string) error {
+	since, _ := cmd.Flags().GetString("since")
+	limit, _ := cmd.Flags().GetInt("limit")
+	output, _ := cmd.Flags().GetString("output")
+
+	if output != "table" && output != "json" {
+		fmt.Fprintf(cmd.ErrOrStderr(), "Error: Invalid output format %q. Must be 'table' or 'json'\n", output)
+		return errExit
+	}
+	if limit < 1 || limit > 1000 {
+		fmt.Fprintf(cmd.ErrOrStderr(), "Error: Limit must be between 1 and 1000\n")
+		return errExit
+	}
+
+	query := url.Values{}
+	query.Set("limit", strconv.Itoa(limit))
+	if since != "" {
+		sinceTime, err := parseSince(since, time.Now())
+		if err != nil {
+			fmt.Fprintf(cmd.ErrOrStderr(), "Error: %v\n", err)
+			return errExit
+		}
+		query.Set("since", sinceTime.UTC().Format(time.RFC3339))
+	}
+
+	resp, err := c.AdminRequest("GET", "/v1/admin/errors?"+query.Encode(), nil)
+	if err != nil {
+		fmt.Fprintf(cmd.ErrOrStderr(), "Failed to list delivery errors: %v\n", err)
+		return errExit
+	}
+
+	var response ListDeliveryErrorsResponse
+	if err := json.Unmarshal(resp, &response); err != nil {
+		fmt.Fprintf(cmd.ErrOrStderr(), "Failed to parse response: %v\n", err)
+		return errExit
+	}
+
+	out := cmd.OutOrStdout()
+	if output == "json" {
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(response)
+	}
+
+	if len(response.Errors) == 0 {
+		fmt.Fprintln(out, "No failed deliveries found")
+		return nil
+	}
+
+	tw := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "TIMESTAMP\tMESSAGE ID\tRECIPIENT\tATTEMPTS\tERROR")
+	for _, deliveryError := range response.Errors {
+		errorText := deliveryError.ErrorCode
+		if deliveryError.ErrorMessage != "" {
+			if errorText != "" {
+				errorText += ": "
+			}
+			errorText += deliveryError.ErrorMessage
+		}
+		if errorText == "" {
+			errorText = "-"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%d\t%s\n", deliveryError.Timestamp.Format(time.RFC3339),
+			deliveryError.MessageID, deliveryError.Recipient, deliveryError.Attempts, errorText)
+	}
+	return tw.Flush()
+}
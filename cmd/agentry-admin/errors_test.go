@@ -0,0 +1,104 @@
+/*
+ * Copyright 2026 Cong Wang
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+const deliveryErrorsResponse = `{"errors":[` +
+	`{"message_id":"m1","recipient":"u@example.com","error_code":"DELIVERY_FAILED","error_message":"connection refused","attempts":3,"timestamp":"2026-01-02T03:04:05Z"},` +
+	`{"message_id":"m2","recipient":"v@example.com","attempts":1,"timestamp":"2026-01-02T03:00:00Z"}` +
+	`],"total":2,"limit":100}`
+
+func TestErrors_Table(t *testing.T) {
+	srv, cap := newMockGateway(t, 200, deliveryErrorsResponse)
+	keyFile := writeTempFile(t, "admin-key")
+
+	before := time.Now()
+	stdout, stderr, err := runCLI(t, srv.URL, srv.Client(),
+		"--admin-key-file", keyFile, "errors", "--since", "1h", "--limit", "10")
+	if err != nil {
+		t.Fatalf("unexpected error: %v (stderr: %s)", err, stderr)
+	}
+	if cap.Method != "GET" || cap.Path != "/v1/admin/errors" {
+		t.Errorf("request = %s %s", cap.Method, cap.Path)
+	}
+	query, _ := url.ParseQuery(cap.Query)
+	if query.Get("limit") != "10" {
+		t.Errorf("limit = %q, want 10", query.Get("limit"))
+	}
+	since, err := time.Parse(time.RFC3339, query.Get("since"))
+	if err != nil || since.After(before.Add(-time.Hour)) || since.Before(before.Add(-time.Hour-time.Minute)) {
+		t.Errorf("since = %q, want an RFC3339 time an hour ago", query.Get("since"))
+	}
+
+	lines := strings.Split(strings.TrimSpace(stdout), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("expected a header and two rows, got %q", stdout)
+	}
+	if fields := strings.Fields(lines[0]); strings.Join(fields, " ") != "TIMESTAMP MESSAGE ID RECIPIENT ATTEMPTS ERROR" {
+		t.Errorf("header = %q", lines[0])
+	}
+	if !strings.Contains(lines[1], "u@example.com") || !strings.Contains(lines[1], "DELIVERY_FAILED: connection refused") {
+		t.Errorf("row = %q", lines[1])
+	}
+	if fields := strings.Fields(lines[2]); fields[len(fields)-1] != "-" {
+		t.Errorf("expected a placeholder for a missing error, got %q", lines[2])
+	}
+}
+
+func TestErrors_JSON(t *testing.T) {
+	srv, cap := newMockGateway(t, 200, deliveryErrorsResponse)
+	keyFile := writeTempFile(t, "admin-key")
+
+	stdout, stderr, err := runCLI(t, srv.URL, srv.Client(), "--admin-key-file", keyFile, "errors", "-o", "json")
+	if err != nil {
+		t.Fatalf("unexpected error: %v (stderr: %s)", err, stderr)
+	}
+	query, _ := url.ParseQuery(cap.Query)
+	if query.Get("limit") != "100" || query.Has("since") {
+		t.Errorf("query = %q, want only the default limit", cap.Query)
+	}
+
+	var response ListDeliveryErrorsResponse
+	if err := json.Unmarshal([]byte(stdout), &response); err != nil {
+		t.Fatalf("stdout is not JSON: %v (%q)", err, stdout)
+	}
+	if response.Total != 2 || response.Errors[0].ErrorCode != "DELIVERY_FAILED" {
+		t.Errorf("response = %+v", response)
+	}
+}
+
+func TestErrors_InvalidFlags(t *testing.T) {
+	for _, args := range [][]string{
+		{"errors", "--since", "yesterday"},
+		{"errors", "--limit", "0"},
+		{"errors", "--output", "yaml"},
+	} {
+		// No server should be hit
+		_, _, err := runCLI(t, "http://127.0.0.1:0", nil, args...)
+		if !errors.Is(err, errExit) {
+			t.Errorf("%v: expected errExit, got %v", args, err)
+		}
+	}
+}
@@ -0,0 +1,133 @@
+/*
+ * Copyright 2026 Cong Wang
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+)
+
+// maxExportLineSize bounds a single NDJSON record; it must fit the largest
+// message the gateway accepts plus its status.
+const maxExportLineSize = 16 * 1024 * 1024
+
+func newExportCmd(c *Client) *cobra.Command {
+	exportCmd := &cobra.Command{
+		Use:   "export",
+		Short: "Export message history for an address as NDJSON (requires admin key)",
+		Example: "  agentry-admin export --address alice@localhost > alice.ndjson\n" +
+			"  agentry-admin export --address alice@localhost -o alice.ndjson",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runExport(c, cmd, args)
+		},
+	}
+	exportCmd.Flags().String("address", "", "Address whose messages are exported (required)")
+	exportCmd.Flags().StringP("output", "o", "", "Write the export to a file instead of stdout")
+
+	return exportCmd
+}
+
+func runExport(c *Client, cmd *cobra.Command, args []string) error {
+	address, _ := cmd.Flags().GetString("address")
+	output, _ := cmd.Flags().GetString("output")
+
+	if address == "" {
+		fmt.Fprintf(cmd.ErrOrStderr(), "Error: Address is required (--address flag)\n")
+		_ = cmd.Usage()
+		return errExit
+	}
+
+	out := cmd.OutOrStdout()
+	if output != "" {
+		f, err := os.OpenFile(filepath.Clean(output), os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+		if err != nil {
+			fmt.Fprintf(cmd.ErrOrStderr(), "Failed to create output file: %v\n", err)
+			return errExit
+		}
+		defer f.Close()
+		out = f
+	}
+
+	var count int
+	err := c.AdminStream("/v1/admin/export?address="+url.QueryEscape(address), func(body io.Reader) error {
+		var err error
+		count, err = copyExportRecords(body, out)
+		return err
+	})
+	if err != nil {
+		fmt.Fprintf(cmd.ErrOrStderr(), "Failed to export messages: %v\n", err)
+		return errExit
+	}
+
+	// Keep stdout clean for the NDJSON stream; the summary goes to stderr
+	// unless the export was written to a file.
+	summary := cmd.ErrOrStderr()
+	if output != "" {
+		summary = cmd.OutOrStdout()
+	}
+	fmt.Fprintf(summary, "Exported %d message(s) for %s\n", count, address)
+	return nil
+}
+
+// copyExportRecords copies message records from the gateway's NDJSON stream
+// to out and returns how many were written. The stream must finish with an
+// end record; an in-band error record or a missing trailer means the export
+// is incomplete.
+func copyExportRecords(body io.Reader, out io.Writer) (int, error) {
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 64*1024), maxExportLineSize)
+
+	count := 0
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		var record struct {
+			Type  string `json:"type"`
+			Count *int   `json:"count"`
+			Error string `json:"error"`
+		}
+		if err := json.Unmarshal(line, &record); err != nil {
+			return count, fmt.Errorf("invalid export record: %w", err)
+		}
+
+		switch record.Type {
+		case "message":
+			if _, err := fmt.Fprintf(out, "%s\n", line); err != nil {
+				return count, fmt.Errorf("failed to write export: %w", err)
+			}
+			count++
+		case "error":
+			return count, fmt.Errorf("export interrupted by gateway after %d message(s): %s", count, record.Error)
+		case "end":
+			if record.Count != nil && *record.Count != count {
+				return count, fmt.Errorf("export incomplete: received %d of %d message(s)", count, *record.Count)
+			}
+			return count, nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return count, fmt.Errorf("failed to read export stream: %w", err)
+	}
+	return count, fmt.Errorf("export stream ended unexpectedly after %d message(s)", count)
+}
@@ -0,0 +1,140 @@
+/*
+ * Copyright 2026 Cong Wang
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const exportStream = `{"type":"message","message":{"message_id":"m1"}}
+{"type":"message","message":{"message_id":"m2"},"status":{"status":"delivered"}}
+{"type":"end","count":2}
+`
+
+func TestExport_Success(t *testing.T) {
+	srv, cap := newMockGateway(t, 200, exportStream)
+	keyFile := writeTempFile(t, "admin-key")
+
+	stdout, stderr, err := runCLI(t, srv.URL, srv.Client(),
+		"--admin-key-file", keyFile,
+		"export", "--address", "alice+tag@localhost")
+	if err != nil {
+		t.Fatalf("unexpected error: %v (stderr: %s)", err, stderr)
+	}
+
+	if cap.Method != "GET" || cap.Path != "/v1/admin/export" {
+		t.Errorf("request = %s %s", cap.Method, cap.Path)
+	}
+	if cap.Query != "address=alice%2Btag%40localhost" {
+		t.Errorf("query = %q", cap.Query)
+	}
+	if cap.Header.Get("X-Admin-Key") != "admin-key" {
+		t.Errorf("admin key header = %q", cap.Header.Get("X-Admin-Key"))
+	}
+
+	lines := strings.Split(strings.TrimSpace(stdout), "\n")
+	if len(lines) != 2 || !strings.Contains(lines[0], `"m1"`) || !strings.Contains(lines[1], `"m2"`) {
+		t.Errorf("stdout = %q", stdout)
+	}
+	if !strings.Contains(stderr, "Exported 2 message(s) for alice+tag@localhost") {
+		t.Errorf("stderr = %q", stderr)
+	}
+}
+
+func TestExport_OutputFile(t *testing.T) {
+	srv, _ := newMockGateway(t, 200, exportStream)
+	keyFile := writeTempFile(t, "admin-key")
+	outFile := filepath.Join(t.TempDir(), "export.ndjson")
+
+	stdout, stderr, err := runCLI(t, srv.URL, srv.Client(),
+		"--admin-key-file", keyFile,
+		"export", "--address", "alice@localhost", "-o", outFile)
+	if err != nil {
+		t.Fatalf("unexpected error: %v (stderr: %s)", err, stderr)
+	}
+
+	data, err := os.ReadFile(outFile)
+	if err != nil {
+		t.Fatalf("read output: %v", err)
+	}
+	if strings.Count(string(data), "\n") != 2 || strings.Contains(string(data), `"end"`) {
+		t.Errorf("output file = %q", data)
+	}
+	if !strings.Contains(stdout, "Exported 2 message(s) for alice@localhost") {
+		t.Errorf("stdout = %q", stdout)
+	}
+}
+
+func TestExport_IncompleteStream(t *testing.T) {
+	tests := []struct {
+		name   string
+		stream string
+		want   string
+	}{
+		{"missing trailer", `{"type":"message","message":{"message_id":"m1"}}` + "\n", "ended unexpectedly after 1 message(s)"},
+		{"gateway error", `{"type":"message","message":{"message_id":"m1"}}` + "\n" + `{"type":"error","error":"db down"}` + "\n", "db down"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv, _ := newMockGateway(t, 200, tt.stream)
+			keyFile := writeTempFile(t, "admin-key")
+
+			_, stderr, err := runCLI(t, srv.URL, srv.Client(),
+				"--admin-key-file", keyFile,
+				"export", "--address", "alice@localhost")
+			if !errors.Is(err, errExit) {
+				t.Fatalf("err = %v, want errExit", err)
+			}
+			if !strings.Contains(stderr, tt.want) {
+				t.Errorf("stderr = %q", stderr)
+			}
+		})
+	}
+}
+
+func TestExport_APIError(t *testing.T) {
+	srv, _ := newMockGateway(t, 400, `{"error":{"code":"INVALID_ADDRESS","message":"A valid address query parameter is required"}}`)
+	keyFile := writeTempFile(t, "admin-key")
+
+	_, stderr, err := runCLI(t, srv.URL, srv.Client(),
+		"--admin-key-file", keyFile,
+		"export", "--address", "alice")
+	if !errors.Is(err, errExit) {
+		t.Fatalf("err = %v, want errExit", err)
+	}
+	if !strings.Contains(stderr, "API error (400): A valid address query parameter is required") {
+		t.Errorf("stderr = %q", stderr)
+	}
+}
+
+func TestExport_MissingAddress(t *testing.T) {
+	keyFile := writeTempFile(t, "admin-key")
+	_, stderr, err := runCLI(t, "http://127.0.0.1:0", nil,
+		"--admin-key-file", keyFile,
+		"export")
+	if !errors.Is(err, errExit) {
+		t.Fatalf("err = %v, want errExit", err)
+	}
+	if !strings.Contains(stderr, "Address is required") {
+		t.Errorf("stderr = %q", stderr)
+	}
+}
@@ -30,6 +30,7 @@ import (
 type capturedRequest struct {
 	Method string
 	Path   string
+	Query  string
 	Header http.Header
 	Body   []byte
 }
@@ -44,6 +45,7 @@ func newMockGateway(t *testing.T, status int, respBody string) (*httptest.Server
 		body, _ := io.ReadAll(r.Body)
 		cap.Method = r.Method
 		cap.Path = r.URL.Path
+		cap.Query = r.URL.RawQuery
 		cap.Header = r.Header.Clone()
 		cap.Body = body
 		w.Header().Set("Content-Type", "application/json")
@@ -17,11 +17,16 @@
 package main
 
 import (
+	"bufio"
 	"encoding/json"
 	"fmt"
+	"io"
+	"net/url"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"text/tabwriter"
 	"time"
 
 	"github.com/spf13/cobra"
@@ -30,14 +35,16 @@ import (
 func newInboxCmd(c *Client) *cobra.Command {
 	inboxCmd := &cobra.Command{
 		Use:   "inbox",
-		Short: "Inbox management commands (requires agent API key)",
+		Short: "Inbox management commands (get and ack require an agent API key)",
 	}
 
 	getCmd := &cobra.Command{
 		Use:   "get <recipient>",
 		Short: "Get messages for recipient",
 		Example: "  agentry-admin inbox get test2@localhost --key your-api-key\n" +
-			"  agentry-admin inbox get test2@localhost --key-file test2.key",
+			"  agentry-admin inbox get test2@localhost --key-file test2.key\n" +
+			"  agentry-admin inbox get test2@localhost --key-env TEST2_API_KEY\n" +
+			"  agentry-admin inbox get test2@localhost --key-file test2.key --stream",
 		Args: cobra.ExactArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			return runInboxGet(c, cmd, args)
@@ -45,6 +52,8 @@ func newInboxCmd(c *Client) *cobra.Command {
 	}
 	getCmd.Flags().String("key", "", "Agent API key for authentication")
 	getCmd.Flags().String("key-file", "", "File containing agent API key")
+	getCmd.Flags().String("key-env", "", "Environment variable holding the agent API key")
+	getCmd.Flags().Bool("stream", false, "Stream the inbox as NDJSON and print messages as they arrive, for very large inboxes")
 
 	ackCmd := &cobra.Command{
 		Use:   "ack <recipient> <message-id>",
@@ -58,17 +67,43 @@ func newInboxCmd(c *Client) *cobra.Command {
 	}
 	ackCmd.Flags().String("key", "", "Agent API key for authentication")
 	ackCmd.Flags().String("key-file", "", "File containing agent API key")
+	ackCmd.Flags().String("key-env", "", "Environment variable holding the agent API key")
 
-	inboxCmd.AddCommand(getCmd, ackCmd)
+	pendingCmd := &cobra.Command{
+		Use:   "pending",
+		Short: "List messages waiting to be acknowledged in any inbox, oldest first (requires admin key)",
+		Example: "  agentry-admin inbox pending\n" +
+			"  agentry-admin inbox pending --limit 50 --cursor 1767322800000000000:42\n" +
+			"  agentry-admin inbox pending --all --output json",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runInboxPending(c, cmd, args)
+		},
+	}
+	pendingCmd.Flags().Int("limit", 100, "Maximum number of messages per page (1-1000)")
+	pendingCmd.Flags().String("cursor", "", "Continue from the cursor printed after an earlier page")
+	pendingCmd.Flags().Bool("all", false, "Follow the cursor through every page")
+	pendingCmd.Flags().StringP("output", "o", "table", "Output format: table or json")
+
+	inboxCmd.AddCommand(getCmd, ackCmd, pendingCmd)
 	return inboxCmd
 }
 
-// resolveAPIKey returns the API key from the --key flag, or reads it from the
-// --key-file flag if provided. On failure it reports the error to stderr and
-// returns errExit.
+// resolveAPIKey returns the API key read from the --key-file flag, else the
+// --key flag, else the environment variable named by --key-env. On failure it
+// reports the error to stderr and returns errExit.
 func resolveAPIKey(cmd *cobra.Command) (string, error) {
 	apiKey, _ := cmd.Flags().GetString("key")
 	keyFile, _ := cmd.Flags().GetString("key-file")
+	keyEnv, _ := cmd.Flags().GetString("key-env")
+
+	if apiKey == "" && keyEnv != "" {
+		apiKey = strings.TrimSpace(os.Getenv(keyEnv))
+		if apiKey == "" && keyFile == "" {
+			fmt.Fprintf(cmd.ErrOrStderr(), "Error: environment variable %s is not set or empty.\n", keyEnv)
+			return "", errExit
+		}
+	}
 
 	// Get API key from file if specified
 	if keyFile != "" {
@@ -81,7 +116,7 @@ func resolveAPIKey(cmd *cobra.Command) (string, error) {
 	}
 
 	if apiKey == "" {
-		fmt.Fprintf(cmd.ErrOrStderr(), "Error: API key is required. Use --key or --key-file flag.\n")
+		fmt.Fprintf(cmd.ErrOrStderr(), "Error: API key is required. Use --key, --key-file or --key-env flag.\n")
 		_ = cmd.Usage()
 		return "", errExit
 	}
@@ -96,6 +131,10 @@ func runInboxGet(c *Client, cmd *cobra.Command, args []string) error {
 		return err
 	}
 
+	if stream, _ := cmd.Flags().GetBool("stream"); stream {
+		return runInboxStream(c, cmd, recipient, apiKey)
+	}
+
 	// Make HTTP request with authentication
 	resp, err := c.AuthenticatedRequest("GET", "/v1/inbox/"+recipient, nil, apiKey)
 	if err != nil {
@@ -117,17 +156,131 @@ func runInboxGet(c *Client, cmd *cobra.Command, args []string) error {
 	}
 
 	for i, message := range response.Messages {
-		fmt.Fprintf(out, "  Message %d:\n", i+1)
-		fmt.Fprintf(out, "    ID: %s\n", message.MessageID)
-		fmt.Fprintf(out, "    From: %s\n", message.Sender)
-		fmt.Fprintf(out, "    Subject: %s\n", message.Subject)
-		fmt.Fprintf(out, "    Timestamp: %s\n", message.Timestamp.Format(time.RFC3339))
-		if len(message.Payload) > 0 {
-			fmt.Fprintf(out, "    Payload:\n")
-			payloadJSON, _ := json.MarshalIndent(message.Payload, "      ", "  ")
-			fmt.Fprintf(out, "      %s\n", string(payloadJSON))
+		printInboxMessage(out, i+1, message)
+	}
+	return nil
+}
+
+// runInboxStream reads the inbox from the gateway's NDJSON stream and prints
+// each message as it arrives, so the inbox is never held in memory at once
+func runInboxStream(c *Client, cmd *cobra.Command, recipient, apiKey string) error {
+	out := cmd.OutOrStdout()
+	fmt.Fprintf(out, "Inbox for %s:\n\n", recipient)
+
+	count := 0
+	err := c.AuthenticatedStream("/v1/inbox/"+recipient+"?format=ndjson", apiKey, func(body io.Reader) error {
+		scanner := bufio.NewScanner(body)
+		scanner.Buffer(make([]byte, 64*1024), maxExportLineSize)
+		for scanner.Scan() {
+			var line struct {
+				Message
+				Error string `json:"error"`
+			}
+			if err := json.Unmarshal(scanner.Bytes(), &line); err != nil {
+				return fmt.Errorf("invalid inbox record: %w", err)
+			}
+			if line.Error != "" {
+				return fmt.Errorf("stream interrupted by gateway after %d message(s): %s", count, line.Error)
+			}
+			count++
+			printInboxMessage(out, count, &line.Message)
+		}
+		if err := scanner.Err(); err != nil {
+			return fmt.Errorf("failed to read inbox stream: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		fmt.Fprintf(cmd.ErrOrStderr(), "Failed to get inbox: %v\n", err)
+		return errExit
+	}
+
+	if count == 0 {
+		fmt.Fprintln(out, "  No messages")
+		return nil
+	}
+	fmt.Fprintf(out, "%d message(s)\n", count)
+	return nil
+}
+
+func printInboxMessage(out io.Writer, n int, message *Message) {
+	fmt.Fprintf(out, "  Message %d:\n", n)
+	fmt.Fprintf(out, "    ID: %s\n", message.MessageID)
+	fmt.Fprintf(out, "    From: %s\n", message.Sender)
+	fmt.Fprintf(out, "    Subject: %s\n", message.Subject)
+	fmt.Fprintf(out, "    Timestamp: %s\n", message.Timestamp.Format(time.RFC3339))
+	if len(message.Payload) > 0 {
+		fmt.Fprintf(out, "    Payload:\n")
+		payloadJSON, _ := json.MarshalIndent(message.Payload, "      ", "  ")
+		fmt.Fprintf(out, "      %s\n", string(payloadJSON))
+	}
+	fmt.Fprintln(out)
+}
+
+func runInboxPending(c *Client, cmd *cobra.Command, args []string) error {
+	limit, _ := cmd.Flags().GetInt("limit")
+	cursor, _ := cmd.Flags().GetString("cursor")
+	all, _ := cmd.Flags().GetBool("all")
+	output, _ := cmd.Flags().GetString("output")
+
+	if output != "table" && output != "json" {
+		fmt.Fprintf(cmd.ErrOrStderr(), "Error: Invalid output format %q. Must be 'table' or 'json'\n", output)
+		return errExit
+	}
+	if limit < 1 || limit > 1000 {
+		fmt.Fprintf(cmd.ErrOrStderr(), "Error: Limit must be between 1 and 1000\n")
+		return errExit
+	}
+
+	var pending []PendingInboxMessage
+	for {
+		query := url.Values{}
+		query.Set("limit", strconv.Itoa(limit))
+		if cursor != "" {
+			query.Set("cursor", cursor)
+		}
+
+		resp, err := c.AdminRequest("GET", "/v1/admin/inbox/pending?"+query.Encode(), nil)
+		if err != nil {
+			fmt.Fprintf(cmd.ErrOrStderr(), "Failed to list pending inbox messages: %v\n", err)
+			return errExit
 		}
-		fmt.Fprintln(out)
+
+		var response PendingInboxResponse
+		if err := json.Unmarshal(resp, &response); err != nil {
+			fmt.Fprintf(cmd.ErrOrStderr(), "Failed to parse response: %v\n", err)
+			return errExit
+		}
+		pending = append(pending, response.Messages...)
+		cursor = response.NextCursor
+		if !all || cursor == "" {
+			break
+		}
+	}
+
+	out := cmd.OutOrStdout()
+	if output == "json" {
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(PendingInboxResponse{Messages: pending, Total: len(pending), NextCursor: cursor})
+	}
+
+	if len(pending) == 0 {
+		fmt.Fprintln(out, "No messages waiting to be acknowledged")
+		return nil
+	}
+
+	tw := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "SINCE\tWAITING\tMESSAGE ID\tRECIPIENT")
+	for _, message := range pending {
+		waiting := time.Duration(message.WaitingSeconds) * time.Second
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", message.Since.Format(time.RFC3339), waiting, message.MessageID, message.Recipient)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	if cursor != "" {
+		fmt.Fprintf(out, "\nMore messages are waiting; continue with --cursor %s\n", cursor)
 	}
 	return nil
 }
@@ -17,7 +17,11 @@
 package main
 
 import (
+	"encoding/json"
 	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
 	"strings"
 	"testing"
 )
@@ -61,6 +65,38 @@ func TestInboxGet_KeyViaFlag(t *testing.T) {
 	}
 }
 
+func TestInboxGet_KeyViaEnv(t *testing.T) {
+	t.Setenv("TEST_AGENT_KEY", " env-key\n")
+	srv, cap := newMockGateway(t, 200, `{"recipient":"u@localhost","count":0,"messages":[]}`)
+
+	_, stderr, err := runCLI(t, srv.URL, srv.Client(),
+		"inbox", "get", "u@localhost", "--key-env", "TEST_AGENT_KEY")
+	if err != nil {
+		t.Fatalf("unexpected error: %v (stderr: %s)", err, stderr)
+	}
+	if got := cap.Header.Get("Authorization"); got != "Bearer env-key" {
+		t.Errorf("Authorization = %q", got)
+	}
+
+	// An explicit --key takes precedence over --key-env
+	if _, stderr, err := runCLI(t, srv.URL, srv.Client(),
+		"inbox", "get", "u@localhost", "--key", "raw-key", "--key-env", "TEST_AGENT_KEY"); err != nil {
+		t.Fatalf("unexpected error: %v (stderr: %s)", err, stderr)
+	}
+	if got := cap.Header.Get("Authorization"); got != "Bearer raw-key" {
+		t.Errorf("Authorization = %q, want the --key value", got)
+	}
+
+	_, stderr, err = runCLI(t, srv.URL, srv.Client(),
+		"inbox", "get", "u@localhost", "--key-env", "TEST_AGENT_KEY_UNSET")
+	if !errors.Is(err, errExit) {
+		t.Fatalf("err = %v, want errExit", err)
+	}
+	if !strings.Contains(stderr, "TEST_AGENT_KEY_UNSET is not set") {
+		t.Errorf("stderr = %q", stderr)
+	}
+}
+
 func TestInboxGet_MissingKey(t *testing.T) {
 	_, stderr, err := runCLI(t, "http://127.0.0.1:0", nil, "inbox", "get", "u@localhost")
 	if !errors.Is(err, errExit) {
@@ -85,3 +121,121 @@ func TestInboxAck_Success(t *testing.T) {
 		t.Errorf("stdout = %q", stdout)
 	}
 }
+
+func TestInboxGet_Stream(t *testing.T) {
+	stream := `{"message_id":"m1","sender":"a@b","subject":"first"}` + "\n" +
+		`{"message_id":"m2","sender":"c@d","subject":"second","payload":{"k":"v"}}` + "\n"
+	srv, cap := newMockGateway(t, 200, stream)
+
+	stdout, stderr, err := runCLI(t, srv.URL, srv.Client(),
+		"inbox", "get", "u@localhost", "--key", "raw-key", "--stream")
+	if err != nil {
+		t.Fatalf("unexpected error: %v (stderr: %s)", err, stderr)
+	}
+	if cap.Path != "/v1/inbox/u@localhost" || cap.Query != "format=ndjson" {
+		t.Errorf("request = %s?%s", cap.Path, cap.Query)
+	}
+	if got := cap.Header.Get("Authorization"); got != "Bearer raw-key" {
+		t.Errorf("Authorization = %q", got)
+	}
+	for _, want := range []string{"Message 1:", "ID: m1", "Message 2:", "ID: m2", "From: c@d", "2 message(s)"} {
+		if !strings.Contains(stdout, want) {
+			t.Errorf("stdout missing %q: %q", want, stdout)
+		}
+	}
+}
+
+func TestInboxGet_StreamInterrupted(t *testing.T) {
+	stream := `{"message_id":"m1","sender":"a@b"}` + "\n" + `{"error":"database unavailable"}` + "\n"
+	srv, _ := newMockGateway(t, 200, stream)
+
+	stdout, stderr, err := runCLI(t, srv.URL, srv.Client(),
+		"inbox", "get", "u@localhost", "--key", "raw-key", "--stream")
+	if !errors.Is(err, errExit) {
+		t.Fatalf("err = %v, want errExit", err)
+	}
+	if !strings.Contains(stdout, "ID: m1") {
+		t.Errorf("expected messages before the failure to be printed: %q", stdout)
+	}
+	if !strings.Contains(stderr, "after 1 message(s): database unavailable") {
+		t.Errorf("stderr = %q", stderr)
+	}
+}
+
+func TestInboxPending_Table(t *testing.T) {
+	resp := `{"messages":[` +
+		`{"message_id":"m1","recipient":"u@localhost","since":"2026-01-02T03:04:05Z","waiting_seconds":7200},` +
+		`{"message_id":"m2","recipient":"v@localhost","since":"2026-01-02T04:04:05Z","waiting_seconds":3600}` +
+		`],"total":2,"limit":2,"next_cursor":"1767326645000000000:9"}`
+	srv, cap := newMockGateway(t, 200, resp)
+	keyFile := writeTempFile(t, "admin-key")
+
+	stdout, stderr, err := runCLI(t, srv.URL, srv.Client(),
+		"--admin-key-file", keyFile, "inbox", "pending", "--limit", "2", "--cursor", "100:7")
+	if err != nil {
+		t.Fatalf("unexpected error: %v (stderr: %s)", err, stderr)
+	}
+	if cap.Method != "GET" || cap.Path != "/v1/admin/inbox/pending" {
+		t.Errorf("request = %s %s", cap.Method, cap.Path)
+	}
+	query, _ := url.ParseQuery(cap.Query)
+	if query.Get("limit") != "2" || query.Get("cursor") != "100:7" {
+		t.Errorf("query = %q", cap.Query)
+	}
+
+	lines := strings.Split(strings.TrimSpace(stdout), "\n")
+	if fields := strings.Fields(lines[0]); strings.Join(fields, " ") != "SINCE WAITING MESSAGE ID RECIPIENT" {
+		t.Errorf("header = %q", lines[0])
+	}
+	if !strings.Contains(lines[1], "2h0m0s") || !strings.Contains(lines[1], "u@localhost") {
+		t.Errorf("row = %q", lines[1])
+	}
+	if !strings.Contains(stdout, "--cursor 1767326645000000000:9") {
+		t.Errorf("expected the next cursor to be printed: %q", stdout)
+	}
+}
+
+func TestInboxPending_All(t *testing.T) {
+	var cursors []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cursor := r.URL.Query().Get("cursor")
+		cursors = append(cursors, cursor)
+		if cursor == "" {
+			_, _ = w.Write([]byte(`{"messages":[{"message_id":"m1","recipient":"u@localhost"}],"total":1,"limit":1,"next_cursor":"c1"}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"messages":[{"message_id":"m2","recipient":"v@localhost"}],"total":1,"limit":1}`))
+	}))
+	defer srv.Close()
+	keyFile := writeTempFile(t, "admin-key")
+
+	stdout, stderr, err := runCLI(t, srv.URL, srv.Client(),
+		"--admin-key-file", keyFile, "inbox", "pending", "--limit", "1", "--all", "-o", "json")
+	if err != nil {
+		t.Fatalf("unexpected error: %v (stderr: %s)", err, stderr)
+	}
+	if strings.Join(cursors, ",") != ",c1" {
+		t.Errorf("cursors = %q, want the first page and then c1", cursors)
+	}
+
+	var response PendingInboxResponse
+	if err := json.Unmarshal([]byte(stdout), &response); err != nil {
+		t.Fatalf("stdout is not JSON: %v (%q)", err, stdout)
+	}
+	if response.Total != 2 || response.Messages[1].MessageID != "m2" || response.NextCursor != "" {
+		t.Errorf("response = %+v", response)
+	}
+}
+
+func TestInboxPending_InvalidFlags(t *testing.T) {
+	for _, args := range [][]string{
+		{"inbox", "pending", "--limit", "0"},
+		{"inbox", "pending", "--output", "yaml"},
+	} {
+		// No server should be hit
+		_, _, err := runCLI(t, "http://127.0.0.1:0", nil, args...)
+		if !errors.Is(err, errExit) {
+			t.Errorf("%v: expected errExit, got %v", args, err)
+		}
+	}
+}
@@ -0,0 +1,347 @@
+/*
+ * Copyright 2026 Cong Wang
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+func newMessageCmd(c *Client) *cobra.Command {
+	messageCmd := &cobra.Command{
+		Use:   "message",
+		Short: "Message history commands",
+	}
+
+	listCmd := &cobra.Command{
+		Use:   "list",
+		Short: "List messages known to the gateway, newest first",
+		Example: "  agentry-admin message list --status failed\n" +
+			"  agentry-admin message list --recipient alice@localhost --since 24h\n" +
+			"  agentry-admin message list --label campaign-2024 --label eu\n" +
+			"  agentry-admin message list --since 2026-01-01T00:00:00Z --limit 50 --offset 50 --output json",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runMessageList(c, cmd, args)
+		},
+	}
+	listCmd.Flags().String("status", "", "Only messages with this delivery status (e.g. queued, delivered, failed)")
+	listCmd.Flags().String("sender", "", "Only messages from this sender")
+	listCmd.Flags().String("recipient", "", "Only messages addressed to this recipient")
+	listCmd.Flags().StringArray("label", nil, "Only messages carrying this label (repeatable; all must match)")
+	listCmd.Flags().String("since", "", "Only messages sent since an RFC3339 time or a duration ago (e.g. 24h)")
+	listCmd.Flags().Int("limit", 100, "Maximum number of messages to list (1-1000)")
+	listCmd.Flags().Int("offset", 0, "Number of messages to skip")
+	listCmd.Flags().StringP("output", "o", "table", "Output format: table or json")
+
+	resendCmd := &cobra.Command{
+		Use:   "resend <id>",
+		Short: "Submit a stored message again under a new message ID",
+		Long: "Submit a stored message again with the same sender, recipients and payload. " +
+			"The resent message gets a new message ID and idempotency key; the original is left unchanged.",
+		Example: "  agentry-admin --admin-key-file admin.key message resend 01890a5d-ac96-774b-bcce-b302099a8057",
+		Args:    cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runMessageResend(c, cmd, args)
+		},
+	}
+
+	statusCmd := &cobra.Command{
+		Use:   "status <id>",
+		Short: "Show the delivery status of a message",
+		Long: "Show the delivery status of a message and each of its recipients. " +
+			"With --watch, poll until the message is delivered or failed, printing every change; " +
+			"the command fails if the message failed or the timeout passes first.",
+		Example: "  agentry-admin message status 01890a5d-ac96-774b-bcce-b302099a8057\n" +
+			"  agentry-admin message status 01890a5d-ac96-774b-bcce-b302099a8057 --watch --interval 5s --timeout 10m",
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runMessageStatus(c, cmd, args)
+		},
+	}
+	statusCmd.Flags().Bool("watch", false, "Poll until the message is delivered or failed")
+	statusCmd.Flags().Duration("interval", 2*time.Second, "How often to poll with --watch")
+	statusCmd.Flags().Duration("timeout", 5*time.Minute, "How long to watch before giving up")
+
+	messageCmd.AddCommand(listCmd, resendCmd, statusCmd)
+	return messageCmd
+}
+
+func runMessageList(c *Client, cmd *cobra.Command, args []string) error {
+	status, _ := cmd.Flags().GetString("status")
+	sender, _ := cmd.Flags().GetString("sender")
+	recipient, _ := cmd.Flags().GetString("recipient")
+	labels, _ := cmd.Flags().GetStringArray("label")
+	since, _ := cmd.Flags().GetString("since")
+	limit, _ := cmd.Flags().GetInt("limit")
+	offset, _ := cmd.Flags().GetInt("offset")
+	output, _ := cmd.Flags().GetString("output")
+
+	if output != "table" && output != "json" {
+		fmt.Fprintf(cmd.ErrOrStderr(), "Error: Invalid output format %q. Must be 'table' or 'json'\n", output)
+		return errExit
+	}
+	if limit < 1 || limit > 1000 {
+		fmt.Fprintf(cmd.ErrOrStderr(), "Error: Limit must be between 1 and 1000\n")
+		return errExit
+	}
+	if offset < 0 {
+		fmt.Fprintf(cmd.ErrOrStderr(), "Error: Offset must be non-negative\n")
+		return errExit
+	}
+
+	query := url.Values{}
+	query.Set("limit", strconv.Itoa(limit))
+	query.Set("offset", strconv.Itoa(offset))
+	if status != "" {
+		query.Set("status", status)
+	}
+	if sender != "" {
+		query.Set("sender", sender)
+	}
+	if recipient != "" {
+		query.Set("recipient", recipient)
+	}
+	for _, label := range labels {
+		query.Add("label", label)
+	}
+	if since != "" {
+		sinceTime, err := parseSince(since, time.Now())
+		if err != nil {
+			fmt.Fprintf(cmd.ErrOrStderr(), "Error: %v\n", err)
+			return errExit
+		}
+		query.Set("since", sinceTime.UTC().Format(time.RFC3339))
+	}
+
+	resp, err := c.AdminRequest("GET", "/v1/admin/messages?"+query.Encode(), nil)
+	if err != nil {
+		fmt.Fprintf(cmd.ErrOrStderr(), "Failed to list messages: %v\n", err)
+		return errExit
+	}
+
+	var response ListMessagesResponse
+	if err := json.Unmarshal(resp, &response); err != nil {
+		fmt.Fprintf(cmd.ErrOrStderr(), "Failed to parse response: %v\n", err)
+		return errExit
+	}
+
+	out := cmd.OutOrStdout()
+	if output == "json" {
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(response)
+	}
+
+	if len(response.Messages) == 0 {
+		fmt.Fprintln(out, "No messages found")
+		return nil
+	}
+
+	tw := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "MESSAGE ID\tSTATUS\tRECIPIENTS\tTIMESTAMP")
+	for _, message := range response.Messages {
+		status := message.Status
+		if status == "" {
+			status = "-"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", message.MessageID, status,
+			strings.Join(message.Recipients, ","), message.Timestamp.Format(time.RFC3339))
+	}
+	return tw.Flush()
+}
+
+func runMessageResend(c *Client, cmd *cobra.Command, args []string) error {
+	messageID := args[0]
+
+	resp, err := c.AdminRequest("POST", "/v1/admin/messages/"+url.PathEscape(messageID)+"/resend", nil)
+	if err != nil {
+		fmt.Fprintf(cmd.ErrOrStderr(), "Failed to resend message: %v\n", err)
+		return errExit
+	}
+
+	var response ResendMessageResponse
+	if err := json.Unmarshal(resp, &response); err != nil {
+		fmt.Fprintf(cmd.ErrOrStderr(), "Failed to parse response: %v\n", err)
+		return errExit
+	}
+
+	out := cmd.OutOrStdout()
+	fmt.Fprintf(out, "Resent message %s as %s\n", response.OriginalMessageID, response.MessageID)
+	fmt.Fprintf(out, "Status: %s\n", response.Status)
+	for _, recipient := range response.Recipients {
+		if recipient.ErrorMessage != "" {
+			fmt.Fprintf(out, "  %s: %s (%s)\n", recipient.Address, recipient.Status, recipient.ErrorMessage)
+		} else {
+			fmt.Fprintf(out, "  %s: %s\n", recipient.Address, recipient.Status)
+		}
+	}
+
+	// A resend whose delivery failed is reported as a failed command
+	if response.Status == "failed" {
+		return errExit
+	}
+	return nil
+}
+
+func runMessageStatus(c *Client, cmd *cobra.Command, args []string) error {
+	messageID := args[0]
+	watch, _ := cmd.Flags().GetBool("watch")
+	interval, _ := cmd.Flags().GetDuration("interval")
+	timeout, _ := cmd.Flags().GetDuration("timeout")
+
+	if interval <= 0 || timeout <= 0 {
+		fmt.Fprintf(cmd.ErrOrStderr(), "Error: Interval and timeout must be positive\n")
+		return errExit
+	}
+
+	fetch := func() (*MessageStatusResponse, error) {
+		resp, err := c.Request("GET", "/v1/messages/"+url.PathEscape(messageID)+"/status", nil)
+		if err != nil {
+			return nil, err
+		}
+		var status MessageStatusResponse
+		if err := json.Unmarshal(resp, &status); err != nil {
+			return nil, fmt.Errorf("failed to parse response: %w", err)
+		}
+		return &status, nil
+	}
+
+	status, err := fetch()
+	if err != nil {
+		fmt.Fprintf(cmd.ErrOrStderr(), "Failed to get message status: %v\n", err)
+		return errExit
+	}
+
+	out := cmd.OutOrStdout()
+	if !watch {
+		fmt.Fprintf(out, "Message: %s\n", status.MessageID)
+		fmt.Fprintf(out, "Status: %s\n", status.Status)
+		printRecipientStatuses(out, status.Recipients, nil)
+		if status.Status == "failed" {
+			return errExit
+		}
+		return nil
+	}
+
+	ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	printStatusTransition(out, status, nil)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for !isTerminalStatus(status.Status) {
+		select {
+		case <-ctx.Done():
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				fmt.Fprintf(cmd.ErrOrStderr(), "Timed out after %s waiting for message %s; last status: %s\n", timeout, messageID, status.Status)
+			} else {
+				fmt.Fprintf(cmd.ErrOrStderr(), "Stopped watching message %s; last status: %s\n", messageID, status.Status)
+			}
+			return errExit
+		case <-ticker.C:
+		}
+
+		next, err := fetch()
+		if err != nil {
+			fmt.Fprintf(cmd.ErrOrStderr(), "Failed to get message status: %v\n", err)
+			return errExit
+		}
+		printStatusTransition(out, next, status)
+		status = next
+	}
+
+	if status.Status == "failed" {
+		return errExit
+	}
+	return nil
+}
+
+// isTerminalStatus reports whether a message status will not change again
+func isTerminalStatus(status string) bool {
+	return status == "delivered" || status == "failed"
+}
+
+// printStatusTransition prints what changed since previous, or the whole
+// status when there is no previous one
+func printStatusTransition(out io.Writer, status, previous *MessageStatusResponse) {
+	var before map[string]string
+	if previous != nil {
+		before = make(map[string]string, len(previous.Recipients))
+		for _, recipient := range previous.Recipients {
+			before[recipient.Address] = recipient.Status
+		}
+		if previous.Status == status.Status && !recipientsChanged(status.Recipients, before) {
+			return
+		}
+	}
+
+	fmt.Fprintf(out, "%s  %s\n", time.Now().Format("15:04:05"), status.Status)
+	printRecipientStatuses(out, status.Recipients, before)
+}
+
+func recipientsChanged(recipients []RecipientStatus, before map[string]string) bool {
+	for _, recipient := range recipients {
+		if status, ok := before[recipient.Address]; !ok || status != recipient.Status {
+			return true
+		}
+	}
+	return false
+}
+
+// printRecipientStatuses prints each recipient whose status differs from
+// before; all of them when before is nil
+func printRecipientStatuses(out io.Writer, recipients []RecipientStatus, before map[string]string) {
+	for _, recipient := range recipients {
+		if status, ok := before[recipient.Address]; ok && status == recipient.Status {
+			continue
+		}
+		if recipient.ErrorMessage != "" {
+			fmt.Fprintf(out, "  %s: %s (%s)\n", recipient.Address, recipient.Status, recipient.ErrorMessage)
+		} else {
+			fmt.Fprintf(out, "  %s: %s\n", recipient.Address, recipient.Status)
+		}
+	}
+}
+
+// parseSince accepts an absolute RFC3339 time or a duration counted back
+// from now, such as "90m" or "24h"
+func parseSince(since string, now time.Time) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, since); err == nil {
+		return t, nil
+	}
+	d, err := time.ParseDuration(since)
+	if err != nil || d <= 0 {
+		return time.Time{}, fmt.Errorf("invalid --since %q: use an RFC3339 time or a positive duration such as 24h", since)
+	}
+	return now.Add(-d), nil
+}
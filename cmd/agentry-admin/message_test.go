@@ -0,0 +1,353 @@
+/*
+ * Copyright 2026 Cong Wang
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+const messageListResponse = `{"messages":[` +
+	`{"message_id":"m1","sender":"a@b","recipients":["u@localhost","v@localhost"],"status":"delivered","timestamp":"2026-01-02T03:04:05Z"},` +
+	`{"message_id":"m2","sender":"a@b","recipients":["u@localhost"],"timestamp":"2026-01-02T03:00:00Z"}` +
+	`],"total":2,"limit":10,"offset":0}`
+
+func TestMessageList_Table(t *testing.T) {
+	srv, cap := newMockGateway(t, 200, messageListResponse)
+
+	stdout, stderr, err := runCLI(t, srv.URL, srv.Client(),
+		"--admin-key-file", writeTempFile(t, "admin-key"), "message", "list", "--status", "delivered", "--sender", "a@b", "--recipient", "u@localhost",
+		"--since", "2026-01-01T00:00:00Z", "--limit", "10", "--offset", "20")
+	if err != nil {
+		t.Fatalf("unexpected error: %v (stderr: %s)", err, stderr)
+	}
+	if cap.Method != "GET" || cap.Path != "/v1/admin/messages" {
+		t.Errorf("request = %s %s", cap.Method, cap.Path)
+	}
+	query, _ := url.ParseQuery(cap.Query)
+	want := map[string]string{
+		"status":    "delivered",
+		"sender":    "a@b",
+		"recipient": "u@localhost",
+		"since":     "2026-01-01T00:00:00Z",
+		"limit":     "10",
+		"offset":    "20",
+	}
+	for key, value := range want {
+		if got := query.Get(key); got != value {
+			t.Errorf("query %s = %q, want %q", key, got, value)
+		}
+	}
+
+	lines := strings.Split(strings.TrimSpace(stdout), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("expected a header and two rows, got %q", stdout)
+	}
+	if fields := strings.Fields(lines[0]); strings.Join(fields, " ") != "MESSAGE ID STATUS RECIPIENTS TIMESTAMP" {
+		t.Errorf("header = %q", lines[0])
+	}
+	if fields := strings.Fields(lines[1]); strings.Join(fields, " ") != "m1 delivered u@localhost,v@localhost 2026-01-02T03:04:05Z" {
+		t.Errorf("row = %q", lines[1])
+	}
+	if fields := strings.Fields(lines[2]); len(fields) != 4 || fields[1] != "-" {
+		t.Errorf("expected a placeholder for a missing status, got %q", lines[2])
+	}
+}
+
+func TestMessageList_JSON(t *testing.T) {
+	srv, cap := newMockGateway(t, 200, messageListResponse)
+
+	stdout, stderr, err := runCLI(t, srv.URL, srv.Client(), "--admin-key-file", writeTempFile(t, "admin-key"), "message", "list", "--output", "json")
+	if err != nil {
+		t.Fatalf("unexpected error: %v (stderr: %s)", err, stderr)
+	}
+	query, _ := url.ParseQuery(cap.Query)
+	if query.Get("limit") != "100" || query.Get("offset") != "0" || query.Has("since") {
+		t.Errorf("query = %q, want only the default paging", cap.Query)
+	}
+
+	var response ListMessagesResponse
+	if err := json.Unmarshal([]byte(stdout), &response); err != nil {
+		t.Fatalf("stdout is not JSON: %v (%q)", err, stdout)
+	}
+	if response.Total != 2 || len(response.Messages) != 2 || response.Messages[0].MessageID != "m1" {
+		t.Errorf("response = %+v", response)
+	}
+}
+
+func TestMessageList_Labels(t *testing.T) {
+	srv, cap := newMockGateway(t, 200, `{"messages":[`+
+		`{"message_id":"m1","sender":"a@b","recipients":["u@localhost"],"labels":["campaign-2024","eu"],"timestamp":"2026-01-02T03:04:05Z"}`+
+		`],"total":1,"limit":100,"offset":0}`)
+
+	stdout, stderr, err := runCLI(t, srv.URL, srv.Client(),
+		"--admin-key-file", writeTempFile(t, "admin-key"), "message", "list", "--label", "campaign-2024", "--label", "eu", "--output", "json")
+	if err != nil {
+		t.Fatalf("unexpected error: %v (stderr: %s)", err, stderr)
+	}
+	query, _ := url.ParseQuery(cap.Query)
+	if got := query["label"]; strings.Join(got, ",") != "campaign-2024,eu" {
+		t.Errorf("label query = %q, want both labels", got)
+	}
+
+	var response ListMessagesResponse
+	if err := json.Unmarshal([]byte(stdout), &response); err != nil {
+		t.Fatalf("stdout is not JSON: %v (%q)", err, stdout)
+	}
+	if len(response.Messages) != 1 || strings.Join(response.Messages[0].Labels, ",") != "campaign-2024,eu" {
+		t.Errorf("response = %+v", response)
+	}
+}
+
+func TestMessageList_Empty(t *testing.T) {
+	srv, _ := newMockGateway(t, 200, `{"messages":[],"total":0,"limit":100,"offset":0}`)
+
+	stdout, _, err := runCLI(t, srv.URL, srv.Client(), "--admin-key-file", writeTempFile(t, "admin-key"), "message", "list")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(stdout, "No messages found") {
+		t.Errorf("stdout = %q", stdout)
+	}
+}
+
+func TestMessageList_InvalidFlags(t *testing.T) {
+	srv, cap := newMockGateway(t, 200, messageListResponse)
+
+	tests := []struct {
+		name string
+		args []string
+		want string
+	}{
+		{"output", []string{"--output", "yaml"}, "Invalid output format"},
+		{"limit", []string{"--limit", "0"}, "Limit must be between 1 and 1000"},
+		{"offset", []string{"--offset", "-1"}, "Offset must be non-negative"},
+		{"since", []string{"--since", "yesterday"}, "invalid --since"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, stderr, err := runCLI(t, srv.URL, srv.Client(), append([]string{"--admin-key-file", writeTempFile(t, "admin-key"), "message", "list"}, tt.args...)...)
+			if !errors.Is(err, errExit) {
+				t.Fatalf("err = %v, want errExit", err)
+			}
+			if !strings.Contains(stderr, tt.want) {
+				t.Errorf("stderr = %q, want %q", stderr, tt.want)
+			}
+		})
+	}
+	if cap.Method != "" {
+		t.Errorf("expected no request for invalid flags, got %s %s", cap.Method, cap.Path)
+	}
+}
+
+func TestMessageList_APIError(t *testing.T) {
+	srv, _ := newMockGateway(t, 400, `{"error":{"code":"INVALID_LIMIT","message":"Limit must be between 1 and 1000"}}`)
+
+	_, stderr, err := runCLI(t, srv.URL, srv.Client(), "--admin-key-file", writeTempFile(t, "admin-key"), "message", "list")
+	if !errors.Is(err, errExit) {
+		t.Fatalf("err = %v, want errExit", err)
+	}
+	if !strings.Contains(stderr, "Failed to list messages") {
+		t.Errorf("stderr = %q", stderr)
+	}
+}
+
+func TestMessageResend(t *testing.T) {
+	srv, cap := newMockGateway(t, 200, `{"original_message_id":"m1","message_id":"m2","status":"delivered",`+
+		`"recipients":[{"address":"u@example.com","status":"delivered","attempts":1}]}`)
+	keyFile := writeTempFile(t, "admin-key")
+
+	stdout, stderr, err := runCLI(t, srv.URL, srv.Client(),
+		"--admin-key-file", keyFile, "message", "resend", "m1")
+	if err != nil {
+		t.Fatalf("unexpected error: %v (stderr: %s)", err, stderr)
+	}
+	if cap.Method != "POST" || cap.Path != "/v1/admin/messages/m1/resend" {
+		t.Errorf("request = %s %s", cap.Method, cap.Path)
+	}
+	for _, want := range []string{"Resent message m1 as m2", "Status: delivered", "u@example.com: delivered"} {
+		if !strings.Contains(stdout, want) {
+			t.Errorf("stdout missing %q: %q", want, stdout)
+		}
+	}
+}
+
+func TestMessageResend_DeliveryFailed(t *testing.T) {
+	srv, _ := newMockGateway(t, 200, `{"original_message_id":"m1","message_id":"m2","status":"failed",`+
+		`"recipients":[{"address":"u@example.com","status":"failed","error_message":"connection refused"}]}`)
+	keyFile := writeTempFile(t, "admin-key")
+
+	stdout, _, err := runCLI(t, srv.URL, srv.Client(),
+		"--admin-key-file", keyFile, "message", "resend", "m1")
+	if !errors.Is(err, errExit) {
+		t.Fatalf("err = %v, want errExit", err)
+	}
+	if !strings.Contains(stdout, "u@example.com: failed (connection refused)") {
+		t.Errorf("stdout = %q", stdout)
+	}
+}
+
+func TestMessageResend_NotFound(t *testing.T) {
+	srv, _ := newMockGateway(t, 404, `{"error":{"code":"MESSAGE_NOT_FOUND","message":"Message not found"}}`)
+	keyFile := writeTempFile(t, "admin-key")
+
+	_, stderr, err := runCLI(t, srv.URL, srv.Client(),
+		"--admin-key-file", keyFile, "message", "resend", "m1")
+	if !errors.Is(err, errExit) {
+		t.Fatalf("err = %v, want errExit", err)
+	}
+	if !strings.Contains(stderr, "Message not found") {
+		t.Errorf("stderr = %q", stderr)
+	}
+}
+
+func TestParseSince(t *testing.T) {
+	now := time.Date(2026, 1, 2, 12, 0, 0, 0, time.UTC)
+
+	got, err := parseSince("24h", now)
+	if err != nil || !got.Equal(now.Add(-24*time.Hour)) {
+		t.Errorf("parseSince(24h) = %v, %v", got, err)
+	}
+	got, err = parseSince("2026-01-01T08:00:00+02:00", now)
+	if err != nil || !got.Equal(time.Date(2026, 1, 1, 6, 0, 0, 0, time.UTC)) {
+		t.Errorf("parseSince(RFC3339) = %v, %v", got, err)
+	}
+	for _, invalid := range []string{"-1h", "0s", "2026-01-01"} {
+		if _, err := parseSince(invalid, now); err == nil {
+			t.Errorf("parseSince(%q) should fail", invalid)
+		}
+	}
+}
+
+// newStatusGateway serves the given status bodies in turn, repeating the last
+func newStatusGateway(t *testing.T, bodies ...string) (*httptest.Server, *[]string) {
+	t.Helper()
+	var mu sync.Mutex
+	var paths []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		paths = append(paths, r.URL.Path)
+		body := bodies[len(bodies)-1]
+		if len(paths) <= len(bodies) {
+			body = bodies[len(paths)-1]
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = io.WriteString(w, body)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &paths
+}
+
+func statusBody(status string, recipients ...string) string {
+	body := `{"message_id":"m1","status":"` + status + `","recipients":[`
+	for i, recipient := range recipients {
+		address, recipientStatus, _ := strings.Cut(recipient, "=")
+		if i > 0 {
+			body += ","
+		}
+		body += `{"address":"` + address + `","status":"` + recipientStatus + `"}`
+	}
+	return body + `]}`
+}
+
+func TestMessageStatus(t *testing.T) {
+	srv, paths := newStatusGateway(t, statusBody("queued", "u@localhost=queued"))
+
+	stdout, stderr, err := runCLI(t, srv.URL, srv.Client(), "message", "status", "m1")
+	if err != nil {
+		t.Fatalf("unexpected error: %v (stderr: %s)", err, stderr)
+	}
+	if len(*paths) != 1 || (*paths)[0] != "/v1/messages/m1/status" {
+		t.Errorf("requests = %v", *paths)
+	}
+	for _, want := range []string{"Message: m1", "Status: queued", "u@localhost: queued"} {
+		if !strings.Contains(stdout, want) {
+			t.Errorf("expected %q in output, got %q", want, stdout)
+		}
+	}
+}
+
+func TestMessageStatus_Watch(t *testing.T) {
+	srv, paths := newStatusGateway(t,
+		statusBody("queued", "u@localhost=queued", "v@localhost=queued"),
+		statusBody("queued", "u@localhost=queued", "v@localhost=queued"),
+		statusBody("delivering", "u@localhost=delivered", "v@localhost=queued"),
+		statusBody("delivered", "u@localhost=delivered", "v@localhost=delivered"),
+	)
+
+	stdout, stderr, err := runCLI(t, srv.URL, srv.Client(), "message", "status", "m1", "--watch", "--interval", "10ms")
+	if err != nil {
+		t.Fatalf("unexpected error: %v (stderr: %s)", err, stderr)
+	}
+	if len(*paths) != 4 {
+		t.Errorf("expected polling to stop at delivered after 4 requests, got %d", len(*paths))
+	}
+
+	// The unchanged second poll prints nothing, and only changed recipients
+	// are listed after the first
+	lines := strings.Split(strings.TrimSpace(stdout), "\n")
+	var got []string
+	for _, line := range lines {
+		got = append(got, strings.Join(strings.Fields(line)[1:], " "))
+	}
+	want := []string{"queued", "queued", "queued", "delivering", "delivered", "delivered", "delivered"}
+	if len(lines) != len(want) || !strings.HasPrefix(lines[1], "  u@localhost") {
+		t.Fatalf("unexpected transitions: %q", stdout)
+	}
+	for i := range want {
+		if !strings.HasSuffix(got[i], want[i]) {
+			t.Errorf("line %d = %q, want status %s", i, lines[i], want[i])
+		}
+	}
+}
+
+func TestMessageStatus_WatchFailed(t *testing.T) {
+	srv, _ := newStatusGateway(t,
+		statusBody("retrying", "u@localhost=retrying"),
+		statusBody("failed", "u@localhost=failed"),
+	)
+
+	stdout, _, err := runCLI(t, srv.URL, srv.Client(), "message", "status", "m1", "--watch", "--interval", "10ms")
+	if !errors.Is(err, errExit) {
+		t.Fatalf("expected errExit for a failed message, got %v", err)
+	}
+	if !strings.Contains(stdout, "u@localhost: failed") {
+		t.Errorf("expected the failure to be printed, got %q", stdout)
+	}
+}
+
+func TestMessageStatus_WatchTimeout(t *testing.T) {
+	srv, _ := newStatusGateway(t, statusBody("queued", "u@localhost=queued"))
+
+	_, stderr, err := runCLI(t, srv.URL, srv.Client(), "message", "status", "m1", "--watch", "--interval", "10ms", "--timeout", "50ms")
+	if !errors.Is(err, errExit) {
+		t.Fatalf("expected errExit on timeout, got %v", err)
+	}
+	if !strings.Contains(stderr, "Timed out after 50ms waiting for message m1; last status: queued") {
+		t.Errorf("unexpected stderr: %q", stderr)
+	}
+}
@@ -18,6 +18,7 @@ package main
 
 import (
 	"errors"
+	"os"
 
 	"github.com/spf13/cobra"
 
@@ -49,12 +50,18 @@ func buildRootCmd(c *Client) *cobra.Command {
 		SilenceErrors: true,
 	}
 
+	// Environment fallbacks become flag defaults, so explicit flags win
+	gatewayURL := os.Getenv(envGatewayURL)
+	if gatewayURL == "" {
+		gatewayURL = "http://localhost:8080"
+	}
+
 	pf := root.PersistentFlags()
-	pf.StringVar(&c.GatewayURL, "gateway-url", "http://localhost:8080", "Gateway URL")
+	pf.StringVar(&c.GatewayURL, "gateway-url", gatewayURL, "Gateway URL (env "+envGatewayURL+")")
 	pf.BoolVarP(&c.Verbose, "verbose", "v", false, "Verbose output")
-	pf.StringVar(&c.AdminKeyFile, "admin-key-file", "", "Admin API key file for administrative operations")
+	pf.StringVar(&c.AdminKeyFile, "admin-key-file", "", "Admin API key file for administrative operations (env "+envAdminKey+" or "+envAdminKeyFile+")")
 
-	root.AddCommand(newSchemaCmd(c), newAgentCmd(c), newInboxCmd(c))
+	root.AddCommand(newSchemaCmd(c), newAgentCmd(c), newInboxCmd(c), newMessageCmd(c), newExportCmd(c), newErrorsCmd(c), newDiscoveryCmd(c), newDoctorCmd(c))
 
 	return root
 }
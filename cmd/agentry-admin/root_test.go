@@ -61,6 +61,28 @@ func TestGlobalFlagsAfterSubcommand(t *testing.T) {
 	}
 }
 
+func TestGatewayURL_FromEnvironment(t *testing.T) {
+	t.Setenv(envGatewayURL, "http://gateway.internal:8080")
+
+	c := newClient()
+	root := buildRootCmd(c)
+	if err := root.ParseFlags(nil); err != nil {
+		t.Fatalf("ParseFlags failed: %v", err)
+	}
+	if c.GatewayURL != "http://gateway.internal:8080" {
+		t.Errorf("GatewayURL = %q, want the environment value", c.GatewayURL)
+	}
+
+	c = newClient()
+	root = buildRootCmd(c)
+	if err := root.ParseFlags([]string{"--gateway-url", "http://flag:9000"}); err != nil {
+		t.Fatalf("ParseFlags failed: %v", err)
+	}
+	if c.GatewayURL != "http://flag:9000" {
+		t.Errorf("GatewayURL = %q, want the flag value", c.GatewayURL)
+	}
+}
+
 func TestVersionFlag_PrintsSharedVersion(t *testing.T) {
 	stdout, _, err := runCLI(t, "http://127.0.0.1:0", nil, "--version")
 	if err != nil {
@@ -17,6 +17,7 @@
 package main
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"os"
@@ -41,6 +42,9 @@ func newSchemaCmd(c *Client) *cobra.Command {
 	}
 	registerCmd.Flags().StringP("file", "f", "", "Schema definition file (required)")
 	registerCmd.Flags().Bool("force", false, "Overwrite existing schema")
+	registerCmd.Flags().Bool("strict", false, "Reject payload fields the schema does not declare")
+	registerCmd.Flags().String("validation-mode", "", "Default validation mode: full or partial (skip required-field checks)")
+	registerCmd.Flags().String("defaults", "", "JSON file with the response_type and coordination applied to messages that leave them unset")
 
 	listCmd := &cobra.Command{
 		Use:   "list",
@@ -79,6 +83,17 @@ func newSchemaCmd(c *Client) *cobra.Command {
 	}
 	validateCmd.Flags().StringP("file", "f", "", "Payload file to validate (required)")
 
+	inferCmd := &cobra.Command{
+		Use:   "infer",
+		Short: "Suggest a schema from example payloads, to review before registering it",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runSchemaInfer(c, cmd, args)
+		},
+	}
+	inferCmd.Flags().StringP("file", "f", "", "JSON file with an array of example payloads, or a single payload (required)")
+	inferCmd.Flags().Int("max-enum-values", 0, "Most distinct values a string field may repeat to become an enum, 0 to disable enums (unset: the gateway's default of 5)")
+
 	statsCmd := &cobra.Command{
 		Use:   "stats",
 		Short: "Show schema registry statistics",
@@ -88,7 +103,16 @@ func newSchemaCmd(c *Client) *cobra.Command {
 		},
 	}
 
-	schemaCmd.AddCommand(registerCmd, listCmd, getCmd, deleteCmd, validateCmd, statsCmd)
+	reloadCmd := &cobra.Command{
+		Use:   "reload",
+		Short: "Reload schemas changed on disk into the gateway's registry",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runSchemaReload(c, cmd, args)
+		},
+	}
+
+	schemaCmd.AddCommand(registerCmd, listCmd, getCmd, deleteCmd, validateCmd, inferCmd, statsCmd, reloadCmd)
 	return schemaCmd
 }
 
@@ -96,6 +120,9 @@ func runSchemaRegister(c *Client, cmd *cobra.Command, args []string) error {
 	schemaID := args[0]
 	schemaFile, _ := cmd.Flags().GetString("file")
 	force, _ := cmd.Flags().GetBool("force")
+	strict, _ := cmd.Flags().GetBool("strict")
+	validationMode, _ := cmd.Flags().GetString("validation-mode")
+	defaultsFile, _ := cmd.Flags().GetString("defaults")
 
 	if schemaFile == "" {
 		fmt.Fprintf(cmd.ErrOrStderr(), "Error: Schema file is required (-f or --file flag)\n")
@@ -119,9 +146,24 @@ func runSchemaRegister(c *Client, cmd *cobra.Command, args []string) error {
 
 	// Create request
 	req := RegisterSchemaRequest{
-		ID:         schemaID,
-		Definition: json.RawMessage(data),
-		Force:      force,
+		ID:             schemaID,
+		Definition:     json.RawMessage(data),
+		Force:          force,
+		Strict:         strict,
+		ValidationMode: validationMode,
+	}
+
+	if defaultsFile != "" {
+		defaults, err := os.ReadFile(filepath.Clean(defaultsFile))
+		if err != nil {
+			fmt.Fprintf(cmd.ErrOrStderr(), "Failed to read defaults file: %v\n", err)
+			return errExit
+		}
+		if !json.Valid(defaults) {
+			fmt.Fprintf(cmd.ErrOrStderr(), "Invalid JSON in defaults file\n")
+			return errExit
+		}
+		req.Defaults = json.RawMessage(defaults)
 	}
 
 	// Make HTTP request with admin authentication
@@ -287,6 +329,62 @@ func runSchemaValidate(c *Client, cmd *cobra.Command, args []string) error {
 	return nil
 }
 
+// runSchemaInfer prints only the suggested schema, so it can be redirected
+// to a file, edited and passed to schema register
+func runSchemaInfer(c *Client, cmd *cobra.Command, args []string) error {
+	examplesFile, _ := cmd.Flags().GetString("file")
+	maxEnumValues, _ := cmd.Flags().GetInt("max-enum-values")
+
+	if examplesFile == "" {
+		fmt.Fprintf(cmd.ErrOrStderr(), "Error: Examples file is required (-f or --file flag)\n")
+		_ = cmd.Usage()
+		return errExit
+	}
+
+	data, err := os.ReadFile(filepath.Clean(examplesFile))
+	if err != nil {
+		fmt.Fprintf(cmd.ErrOrStderr(), "Failed to read examples file: %v\n", err)
+		return errExit
+	}
+
+	// An array holds several examples; anything else is a single one
+	var examples []json.RawMessage
+	if err := json.Unmarshal(data, &examples); err != nil {
+		var single json.RawMessage
+		if err := json.Unmarshal(data, &single); err != nil {
+			fmt.Fprintf(cmd.ErrOrStderr(), "Invalid JSON in examples file: %v\n", err)
+			return errExit
+		}
+		examples = []json.RawMessage{single}
+	}
+
+	req := InferSchemaRequest{Examples: examples}
+	if cmd.Flags().Changed("max-enum-values") {
+		req.MaxEnumValues = &maxEnumValues
+	}
+
+	resp, err := c.AdminRequest("POST", "/v1/admin/schemas/infer", req)
+	if err != nil {
+		fmt.Fprintf(cmd.ErrOrStderr(), "Failed to infer schema: %v\n", err)
+		return errExit
+	}
+
+	var response InferSchemaResponse
+	if err := json.Unmarshal(resp, &response); err != nil {
+		fmt.Fprintf(cmd.ErrOrStderr(), "Failed to parse response: %v\n", err)
+		return errExit
+	}
+
+	var prettyJSON bytes.Buffer
+	if err := json.Indent(&prettyJSON, response.Schema, "", "  "); err != nil {
+		fmt.Fprintf(cmd.ErrOrStderr(), "Failed to format schema: %v\n", err)
+		return errExit
+	}
+	fmt.Fprintln(cmd.OutOrStdout(), prettyJSON.String())
+	fmt.Fprintf(cmd.ErrOrStderr(), "Inferred from %d example(s); review it before registering\n", response.Examples)
+	return nil
+}
+
 func runSchemaStats(c *Client, cmd *cobra.Command, args []string) error {
 	// Make HTTP request with admin authentication
 	resp, err := c.AdminRequest("GET", "/v1/admin/schemas/stats", nil)
@@ -311,3 +409,31 @@ func runSchemaStats(c *Client, cmd *cobra.Command, args []string) error {
 	fmt.Fprintln(cmd.OutOrStdout(), string(prettyJSON))
 	return nil
 }
+
+func runSchemaReload(c *Client, cmd *cobra.Command, args []string) error {
+	// Make HTTP request with admin authentication
+	resp, err := c.AdminRequest("POST", "/v1/admin/schemas/reload", nil)
+	if err != nil {
+		fmt.Fprintf(cmd.ErrOrStderr(), "Failed to reload schemas: %v\n", err)
+		return errExit
+	}
+
+	var response SchemaReloadResponse
+	if err := json.Unmarshal(resp, &response); err != nil {
+		fmt.Fprintf(cmd.ErrOrStderr(), "Failed to parse response: %v\n", err)
+		return errExit
+	}
+
+	changes := response.Changes
+	fmt.Fprintf(cmd.OutOrStdout(), "Reloaded schemas: %d added, %d removed, %d updated, %d unchanged\n",
+		len(changes.Added), len(changes.Removed), len(changes.Updated), changes.Unchanged)
+	for _, group := range []struct {
+		label string
+		ids   []string
+	}{{"+", changes.Added}, {"-", changes.Removed}, {"~", changes.Updated}} {
+		for _, id := range group.ids {
+			fmt.Fprintf(cmd.OutOrStdout(), "  %s %s\n", group.label, id)
+		}
+	}
+	return nil
+}
@@ -56,6 +56,78 @@ func TestSchemaRegister_Success(t *testing.T) {
 	}
 }
 
+func TestSchemaRegister_Strict(t *testing.T) {
+	srv, cap := newMockGateway(t, 200, `{"message":"ok","schema_id":"agntcy:commerce.order.v1"}`)
+	keyFile := writeTempFile(t, "admin-key")
+	schemaFile := writeTempFile(t, `{"type":"object"}`)
+
+	_, stderr, err := runCLI(t, srv.URL, srv.Client(),
+		"--admin-key-file", keyFile,
+		"schema", "register", "agntcy:commerce.order.v1", "-f", schemaFile, "--strict")
+	if err != nil {
+		t.Fatalf("unexpected error: %v (stderr: %s)", err, stderr)
+	}
+	var req RegisterSchemaRequest
+	if e := json.Unmarshal(cap.Body, &req); e != nil {
+		t.Fatalf("decode request body: %v", e)
+	}
+	if !req.Strict {
+		t.Errorf("strict not propagated to request")
+	}
+	if req.Force {
+		t.Errorf("force should default to false")
+	}
+}
+
+func TestSchemaRegister_ValidationMode(t *testing.T) {
+	srv, cap := newMockGateway(t, 200, `{"message":"ok","schema_id":"agntcy:commerce.order.v1"}`)
+	keyFile := writeTempFile(t, "admin-key")
+	schemaFile := writeTempFile(t, `{"type":"object"}`)
+
+	_, stderr, err := runCLI(t, srv.URL, srv.Client(),
+		"--admin-key-file", keyFile,
+		"schema", "register", "agntcy:commerce.order.v1", "-f", schemaFile, "--validation-mode", "partial")
+	if err != nil {
+		t.Fatalf("unexpected error: %v (stderr: %s)", err, stderr)
+	}
+	var req RegisterSchemaRequest
+	if e := json.Unmarshal(cap.Body, &req); e != nil {
+		t.Fatalf("decode request body: %v", e)
+	}
+	if req.ValidationMode != "partial" {
+		t.Errorf("validation_mode = %q, want partial", req.ValidationMode)
+	}
+}
+
+func TestSchemaRegister_Defaults(t *testing.T) {
+	srv, cap := newMockGateway(t, 200, `{"message":"ok","schema_id":"agntcy:commerce.order.v1"}`)
+	keyFile := writeTempFile(t, "admin-key")
+	schemaFile := writeTempFile(t, `{"type":"object"}`)
+	defaultsFile := writeTempFile(t, `{"response_type":"required"}`)
+
+	_, stderr, err := runCLI(t, srv.URL, srv.Client(),
+		"--admin-key-file", keyFile,
+		"schema", "register", "agntcy:commerce.order.v1", "-f", schemaFile, "--defaults", defaultsFile)
+	if err != nil {
+		t.Fatalf("unexpected error: %v (stderr: %s)", err, stderr)
+	}
+	var req RegisterSchemaRequest
+	if e := json.Unmarshal(cap.Body, &req); e != nil {
+		t.Fatalf("decode request body: %v", e)
+	}
+	if string(req.Defaults) != `{"response_type":"required"}` {
+		t.Errorf("defaults = %s", req.Defaults)
+	}
+
+	badFile := writeTempFile(t, `{"response_type":`)
+	_, stderr, err = runCLI(t, srv.URL, srv.Client(),
+		"--admin-key-file", keyFile,
+		"schema", "register", "agntcy:commerce.order.v1", "-f", schemaFile, "--defaults", badFile)
+	if !errors.Is(err, errExit) || !strings.Contains(stderr, "Invalid JSON in defaults file") {
+		t.Errorf("err = %v, stderr = %q", err, stderr)
+	}
+}
+
 func TestSchemaRegister_MissingFileFlag(t *testing.T) {
 	keyFile := writeTempFile(t, "admin-key")
 	// No server should be hit; use an unreachable URL to prove that.
@@ -113,13 +185,92 @@ func TestSchemaValidate_InvalidPayloadExits(t *testing.T) {
 	}
 }
 
+func TestSchemaInfer(t *testing.T) {
+	resp := `{"schema":{"type":"object","required":["status"],"properties":{"status":{"type":"string","enum":["open"]}}},"examples":2}`
+
+	t.Run("array of examples", func(t *testing.T) {
+		srv, cap := newMockGateway(t, 200, resp)
+		keyFile := writeTempFile(t, "admin-key")
+		examples := writeTempFile(t, `[{"status":"open"},{"status":"open"}]`)
+
+		stdout, stderr, err := runCLI(t, srv.URL, srv.Client(),
+			"--admin-key-file", keyFile, "schema", "infer", "-f", examples, "--max-enum-values", "0")
+		if err != nil {
+			t.Fatalf("unexpected error: %v (stderr: %s)", err, stderr)
+		}
+		if cap.Method != "POST" || cap.Path != "/v1/admin/schemas/infer" {
+			t.Errorf("request = %s %s", cap.Method, cap.Path)
+		}
+		var req InferSchemaRequest
+		if err := json.Unmarshal(cap.Body, &req); err != nil {
+			t.Fatalf("bad request body: %v", err)
+		}
+		if len(req.Examples) != 2 || req.MaxEnumValues == nil || *req.MaxEnumValues != 0 {
+			t.Errorf("request body = %s", cap.Body)
+		}
+
+		// stdout holds only the schema, ready to be saved and registered
+		var schema map[string]interface{}
+		if err := json.Unmarshal([]byte(stdout), &schema); err != nil {
+			t.Fatalf("stdout is not the schema: %v: %q", err, stdout)
+		}
+		if schema["type"] != "object" || !strings.Contains(stderr, "Inferred from 2 example(s)") {
+			t.Errorf("stdout = %q, stderr = %q", stdout, stderr)
+		}
+	})
+
+	t.Run("single example", func(t *testing.T) {
+		srv, cap := newMockGateway(t, 200, resp)
+		keyFile := writeTempFile(t, "admin-key")
+		example := writeTempFile(t, `{"status":"open"}`)
+
+		if _, stderr, err := runCLI(t, srv.URL, srv.Client(),
+			"--admin-key-file", keyFile, "schema", "infer", "-f", example); err != nil {
+			t.Fatalf("unexpected error: %v (stderr: %s)", err, stderr)
+		}
+		var req InferSchemaRequest
+		if err := json.Unmarshal(cap.Body, &req); err != nil {
+			t.Fatalf("bad request body: %v", err)
+		}
+		if len(req.Examples) != 1 || req.MaxEnumValues != nil {
+			t.Errorf("request body = %s", cap.Body)
+		}
+	})
+}
+
+func TestSchemaReload_Success(t *testing.T) {
+	resp := `{"message":"Schemas reloaded successfully","changes":{"added":["agntcy:crm.lead.v1"],"removed":[],"updated":["agntcy:commerce.order.v1"],"unchanged":3}}`
+	srv, cap := newMockGateway(t, 200, resp)
+	keyFile := writeTempFile(t, "admin-key")
+
+	stdout, stderr, err := runCLI(t, srv.URL, srv.Client(),
+		"--admin-key-file", keyFile, "schema", "reload")
+	if err != nil {
+		t.Fatalf("unexpected error: %v (stderr: %s)", err, stderr)
+	}
+	if cap.Method != "POST" || cap.Path != "/v1/admin/schemas/reload" {
+		t.Errorf("request = %s %s", cap.Method, cap.Path)
+	}
+	for _, want := range []string{
+		"Reloaded schemas: 1 added, 0 removed, 1 updated, 3 unchanged",
+		"+ agntcy:crm.lead.v1",
+		"~ agntcy:commerce.order.v1",
+	} {
+		if !strings.Contains(stdout, want) {
+			t.Errorf("stdout missing %q: %q", want, stdout)
+		}
+	}
+}
+
 func TestSchemaCommand_RequiresAdminKey(t *testing.T) {
-	// No admin key file: AdminRequest fails before any network call.
+	// No admin key: AdminRequest fails before any network call.
+	t.Setenv(envAdminKey, "")
+	t.Setenv(envAdminKeyFile, "")
 	stdout, stderr, err := runCLI(t, "http://127.0.0.1:0", nil, "schema", "list")
 	if !errors.Is(err, errExit) {
 		t.Fatalf("err = %v, want errExit", err)
 	}
-	if !strings.Contains(stderr, "admin key file is required") {
+	if !strings.Contains(stderr, "admin key is required") {
 		t.Errorf("stderr = %q (stdout %q)", stderr, stdout)
 	}
 }
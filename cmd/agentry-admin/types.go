@@ -23,9 +23,12 @@ import (
 
 // API request/response structures
 type RegisterSchemaRequest struct {
-	ID         string          `json:"id"`
-	Definition json.RawMessage `json:"definition"`
-	Force      bool            `json:"force,omitempty"`
+	ID             string          `json:"id"`
+	Definition     json.RawMessage `json:"definition"`
+	Force          bool            `json:"force,omitempty"`
+	Strict         bool            `json:"strict,omitempty"`
+	ValidationMode string          `json:"validation_mode,omitempty"`
+	Defaults       json.RawMessage `json:"defaults,omitempty"`
 }
 
 type SchemaResponse struct {
@@ -52,6 +55,16 @@ type ValidatePayloadRequest struct {
 	Payload json.RawMessage `json:"payload"`
 }
 
+type InferSchemaRequest struct {
+	Examples      []json.RawMessage `json:"examples"`
+	MaxEnumValues *int              `json:"max_enum_values,omitempty"`
+}
+
+type InferSchemaResponse struct {
+	Schema   json.RawMessage `json:"schema"`
+	Examples int             `json:"examples"`
+}
+
 type ValidationResponse struct {
 	Valid     bool                     `json:"valid"`
 	Errors    []map[string]interface{} `json:"errors"`
@@ -64,17 +77,35 @@ type SchemaStatsResponse struct {
 	Timestamp time.Time              `json:"timestamp"`
 }
 
+type SchemaReloadResponse struct {
+	Message string `json:"message"`
+	Changes struct {
+		Added     []string `json:"added"`
+		Removed   []string `json:"removed"`
+		Updated   []string `json:"updated"`
+		Unchanged int      `json:"unchanged"`
+	} `json:"changes"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
 // Agent management structures
 type LocalAgent struct {
-	Address          string            `json:"address"`
-	DeliveryMode     string            `json:"delivery_mode"`
-	PushTarget       string            `json:"push_target"`
-	Headers          map[string]string `json:"headers"`
-	APIKey           string            `json:"api_key"`
-	SupportedSchemas []string          `json:"supported_schemas"`
-	RequiresSchema   bool              `json:"requires_schema"` // whether this agent requires schema validation
-	CreatedAt        time.Time         `json:"created_at"`
-	LastAccess       time.Time         `json:"last_access"`
+	Address           string            `json:"address"`
+	DeliveryMode      string            `json:"delivery_mode"`
+	PushTarget        string            `json:"push_target"`
+	PushTargets       []string          `json:"push_targets,omitempty"`
+	PushPolicy        string            `json:"push_policy,omitempty"`
+	PushStrategy      string            `json:"push_strategy,omitempty"`
+	PushTargetWeights map[string]int    `json:"push_target_weights,omitempty"`
+	Headers           map[string]string `json:"headers"`
+	APIKey            string            `json:"api_key"`
+	SupportedSchemas  []string          `json:"supported_schemas"`
+	RequiresSchema    bool              `json:"requires_schema"` // whether this agent requires schema validation
+	AllowedSenders    []string          `json:"allowed_senders,omitempty"`
+	CreatedAt         time.Time         `json:"created_at"`
+	LastAccess        time.Time         `json:"last_access"`
+
+	MaxConcurrentDeliveries int `json:"max_concurrent_deliveries,omitempty"`
 }
 
 type AgentResponse struct {
@@ -85,12 +116,53 @@ type AgentResponse struct {
 	Error     string      `json:"error,omitempty"`
 }
 
+// GetAgentResponse carries delivery counts only for push agents
+type GetAgentResponse struct {
+	Agent              *LocalAgent `json:"agent"`
+	DeliveriesInFlight *int        `json:"deliveries_in_flight,omitempty"`
+	DeliveryLimit      *int        `json:"delivery_limit,omitempty"`
+	Timestamp          time.Time   `json:"timestamp"`
+}
+
+type MintInboxTokenRequest struct {
+	TTL string `json:"ttl,omitempty"`
+}
+
+type MintInboxTokenResponse struct {
+	Token     string    `json:"token"`
+	Agent     string    `json:"agent"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
 type ListAgentsResponse struct {
 	Agents    map[string]*LocalAgent `json:"agents"`
 	Count     int                    `json:"count"`
 	Timestamp time.Time              `json:"timestamp"`
 }
 
+type IdleAgent struct {
+	Address      string    `json:"address"`
+	DeliveryMode string    `json:"delivery_mode"`
+	CreatedAt    time.Time `json:"created_at"`
+	LastActive   time.Time `json:"last_active"`
+	IdleFor      string    `json:"idle_for"`
+}
+
+type ListIdleAgentsResponse struct {
+	Agents    []IdleAgent `json:"agents"`
+	Count     int         `json:"count"`
+	IdleAfter string      `json:"idle_after"`
+	Action    string      `json:"action"`
+	Timestamp time.Time   `json:"timestamp"`
+}
+
+type DrainInboxResponse struct {
+	Message   string    `json:"message"`
+	Address   string    `json:"address"`
+	Drained   int64     `json:"drained"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
 type Message struct {
 	Version        string                 `json:"version"`
 	MessageID      string                 `json:"message_id"`
@@ -109,6 +181,107 @@ type InboxResponse struct {
 	Timestamp time.Time  `json:"timestamp"`
 }
 
+// Message history structures
+type MessageSummary struct {
+	MessageID  string    `json:"message_id"`
+	Sender     string    `json:"sender"`
+	Recipients []string  `json:"recipients"`
+	Subject    string    `json:"subject,omitempty"`
+	Status     string    `json:"status,omitempty"`
+	Labels     []string  `json:"labels,omitempty"`
+	Timestamp  time.Time `json:"timestamp"`
+}
+
+type ListMessagesResponse struct {
+	Messages []MessageSummary `json:"messages"`
+	Total    int              `json:"total"`
+	Limit    int              `json:"limit"`
+	Offset   int              `json:"offset"`
+}
+
+type RecipientStatus struct {
+	Address      string `json:"address"`
+	Status       string `json:"status"`
+	Attempts     int    `json:"attempts"`
+	ErrorMessage string `json:"error_message,omitempty"`
+}
+
+type MessageStatusResponse struct {
+	MessageID  string            `json:"message_id"`
+	Status     string            `json:"status"`
+	Recipients []RecipientStatus `json:"recipients"`
+	Attempts   int               `json:"attempts"`
+	UpdatedAt  time.Time         `json:"updated_at"`
+}
+
+type ResendMessageResponse struct {
+	OriginalMessageID string            `json:"original_message_id"`
+	MessageID         string            `json:"message_id"`
+	Status            string            `json:"status"`
+	Partial           bool              `json:"partial,omitempty"`
+	Recipients        []RecipientStatus `json:"recipients"`
+}
+
+type DeliveryError struct {
+	MessageID    string    `json:"message_id"`
+	Recipient    string    `json:"recipient"`
+	ErrorCode    string    `json:"error_code,omitempty"`
+	ErrorMessage string    `json:"error_message,omitempty"`
+	Attempts     int       `json:"attempts"`
+	DeliveryMode string    `json:"delivery_mode,omitempty"`
+	Timestamp    time.Time `json:"timestamp"`
+}
+
+type ListDeliveryErrorsResponse struct {
+	Errors []DeliveryError `json:"errors"`
+	Total  int             `json:"total"`
+	Limit  int             `json:"limit"`
+}
+
+type PendingInboxMessage struct {
+	MessageID      string    `json:"message_id"`
+	Recipient      string    `json:"recipient"`
+	Since          time.Time `json:"since"`
+	WaitingSeconds int64     `json:"waiting_seconds"`
+}
+
+type PendingInboxResponse struct {
+	Messages   []PendingInboxMessage `json:"messages"`
+	Total      int                   `json:"total"`
+	Limit      int                   `json:"limit,omitempty"`
+	NextCursor string                `json:"next_cursor,omitempty"`
+}
+
+// Discovery troubleshooting structures
+type DiscoveryCapabilities struct {
+	Version  string        `json:"version"`
+	Gateway  string        `json:"gateway"`
+	Auth     []string      `json:"auth,omitempty"`
+	MaxSize  int64         `json:"max_size,omitempty"`
+	Features []string      `json:"features,omitempty"`
+	TTL      time.Duration `json:"ttl"`
+}
+
+type DiscoveryRecord struct {
+	Record       string                 `json:"record"`
+	Capabilities *DiscoveryCapabilities `json:"capabilities,omitempty"`
+	Error        string                 `json:"error,omitempty"`
+}
+
+type DiscoveryInspection struct {
+	Domain       string                 `json:"domain"`
+	Cached       bool                   `json:"cached"`
+	Capabilities *DiscoveryCapabilities `json:"capabilities,omitempty"`
+	Records      []DiscoveryRecord      `json:"records"`
+	Error        string                 `json:"error,omitempty"`
+}
+
+type DiscoveryResponse struct {
+	Inspection   DiscoveryInspection `json:"inspection"`
+	GatewayError string              `json:"gateway_error,omitempty"`
+	Timestamp    time.Time           `json:"timestamp"`
+}
+
 type AckResponse struct {
 	Message   string    `json:"message"`
 	Recipient string    `json:"recipient"`
@@ -17,14 +17,16 @@ END $$;
 CREATE TABLE IF NOT EXISTS messages (
     id SERIAL PRIMARY KEY,
     version VARCHAR(10) NOT NULL DEFAULT '1.0',
-    message_id UUID NOT NULL UNIQUE,
+    message_id VARCHAR(64) NOT NULL UNIQUE,
     idempotency_key UUID NOT NULL UNIQUE,
     timestamp TIMESTAMPTZ NOT NULL,
     sender VARCHAR(255) NOT NULL,
     subject TEXT,
     schema TEXT,
-    in_reply_to UUID,
+    in_reply_to VARCHAR(64),
     response_type VARCHAR(50),
+    request_receipt BOOLEAN NOT NULL DEFAULT FALSE,
+    encrypted BOOLEAN NOT NULL DEFAULT FALSE,
 
     -- JSON fields
     recipients JSONB NOT NULL,
@@ -32,25 +34,53 @@ CREATE TABLE IF NOT EXISTS messages (
     headers JSONB,
     payload JSONB,
     attachments JSONB,
-    signature JSONB
+    signature JSONB,
+    labels JSONB,
+
+    -- Compressed payload and attachments, stored instead of the JSONB
+    -- columns when payload_encoding names a compression
+    payload_encoding VARCHAR(16) NOT NULL DEFAULT '',
+    payload_compressed BYTEA,
+    attachments_compressed BYTEA
 );
 
+-- Add payload compression to messages tables created by earlier releases
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS payload_encoding VARCHAR(16) NOT NULL DEFAULT '';
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS payload_compressed BYTEA;
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS attachments_compressed BYTEA;
+
+-- Add labels to messages tables created by earlier releases
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS labels JSONB;
+
+-- Add read receipt requests to messages tables created by earlier releases
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS request_receipt BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- Add end-to-end encrypted payloads to messages tables created by earlier releases
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS encrypted BOOLEAN NOT NULL DEFAULT FALSE;
+
 -- Create message status table
 CREATE TABLE IF NOT EXISTS message_statuses (
     id SERIAL PRIMARY KEY,
-    message_id UUID NOT NULL REFERENCES messages(message_id) ON DELETE CASCADE,
+    message_id VARCHAR(64) NOT NULL REFERENCES messages(message_id) ON DELETE CASCADE,
     status delivery_status NOT NULL DEFAULT 'pending',
     attempts INTEGER NOT NULL DEFAULT 0,
     next_retry TIMESTAMPTZ,
     created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
     updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
-    delivered_at TIMESTAMPTZ
+    delivered_at TIMESTAMPTZ,
+    -- Lease held by the gateway retrying the message's queued recipients
+    lease_owner VARCHAR(255),
+    lease_expires_at TIMESTAMPTZ
 );
 
+-- Add delivery leases to message status tables created by earlier releases
+ALTER TABLE message_statuses ADD COLUMN IF NOT EXISTS lease_owner VARCHAR(255);
+ALTER TABLE message_statuses ADD COLUMN IF NOT EXISTS lease_expires_at TIMESTAMPTZ;
+
 -- Create recipient status table
 CREATE TABLE IF NOT EXISTS recipient_statuses (
     id SERIAL PRIMARY KEY,
-    message_id UUID NOT NULL REFERENCES messages(message_id) ON DELETE CASCADE,
+    message_id VARCHAR(64) NOT NULL REFERENCES messages(message_id) ON DELETE CASCADE,
     address VARCHAR(255) NOT NULL,
     status delivery_status NOT NULL DEFAULT 'pending',
     timestamp TIMESTAMPTZ NOT NULL DEFAULT NOW(),
@@ -61,9 +91,51 @@ CREATE TABLE IF NOT EXISTS recipient_statuses (
     local_delivery BOOLEAN DEFAULT FALSE,
     inbox_delivered BOOLEAN DEFAULT FALSE,
     acknowledged BOOLEAN DEFAULT FALSE,
-    acknowledged_at TIMESTAMPTZ
+    acknowledged_at TIMESTAMPTZ,
+    response JSONB
 );
 
+-- Add agent responses to recipient status tables created by earlier releases
+ALTER TABLE recipient_statuses ADD COLUMN IF NOT EXISTS response JSONB;
+
+-- Create raw request table, filled only when raw request storage is enabled
+CREATE TABLE IF NOT EXISTS raw_requests (
+    id SERIAL PRIMARY KEY,
+    message_id VARCHAR(64) NOT NULL UNIQUE REFERENCES messages(message_id) ON DELETE CASCADE,
+    received_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    headers JSONB,
+    body BYTEA NOT NULL,
+    size BIGINT NOT NULL DEFAULT 0,
+    truncated BOOLEAN NOT NULL DEFAULT FALSE
+);
+
+-- Store message IDs as text in tables created by earlier releases, which
+-- kept them in UUID columns and so could not hold ULIDs or prefixed IDs.
+-- The foreign keys are recreated around the change because a text column
+-- cannot reference a UUID one.
+DO $$
+BEGIN
+    IF EXISTS (SELECT 1 FROM information_schema.columns
+               WHERE table_name = 'messages' AND column_name = 'message_id' AND data_type = 'uuid') THEN
+        ALTER TABLE message_statuses DROP CONSTRAINT IF EXISTS message_statuses_message_id_fkey;
+        ALTER TABLE recipient_statuses DROP CONSTRAINT IF EXISTS recipient_statuses_message_id_fkey;
+        ALTER TABLE raw_requests DROP CONSTRAINT IF EXISTS raw_requests_message_id_fkey;
+
+        ALTER TABLE messages ALTER COLUMN message_id TYPE VARCHAR(64);
+        ALTER TABLE messages ALTER COLUMN in_reply_to TYPE VARCHAR(64);
+        ALTER TABLE message_statuses ALTER COLUMN message_id TYPE VARCHAR(64);
+        ALTER TABLE recipient_statuses ALTER COLUMN message_id TYPE VARCHAR(64);
+        ALTER TABLE raw_requests ALTER COLUMN message_id TYPE VARCHAR(64);
+
+        ALTER TABLE message_statuses ADD CONSTRAINT message_statuses_message_id_fkey
+            FOREIGN KEY (message_id) REFERENCES messages(message_id) ON DELETE CASCADE;
+        ALTER TABLE recipient_statuses ADD CONSTRAINT recipient_statuses_message_id_fkey
+            FOREIGN KEY (message_id) REFERENCES messages(message_id) ON DELETE CASCADE;
+        ALTER TABLE raw_requests ADD CONSTRAINT raw_requests_message_id_fkey
+            FOREIGN KEY (message_id) REFERENCES messages(message_id) ON DELETE CASCADE;
+    END IF;
+END $$;
+
 -- Create indexes
 
 -- Messages table indexes
@@ -73,12 +145,15 @@ CREATE INDEX IF NOT EXISTS idx_messages_idempotency_key ON messages(idempotency_
 CREATE INDEX IF NOT EXISTS idx_messages_sender ON messages(sender);
 CREATE INDEX IF NOT EXISTS idx_messages_timestamp ON messages(timestamp);
 CREATE INDEX IF NOT EXISTS idx_messages_in_reply_to ON messages(in_reply_to);
+CREATE INDEX IF NOT EXISTS idx_messages_labels ON messages USING GIN (labels);
+CREATE INDEX IF NOT EXISTS idx_messages_schema ON messages(schema);
 
 -- Message statuses table indexes
 CREATE INDEX IF NOT EXISTS idx_message_statuses_message_id ON message_statuses(message_id);
 CREATE INDEX IF NOT EXISTS idx_message_statuses_status ON message_statuses(status);
 CREATE INDEX IF NOT EXISTS idx_message_statuses_next_retry ON message_statuses(next_retry);
 CREATE INDEX IF NOT EXISTS idx_message_statuses_updated_at ON message_statuses(updated_at DESC);
+CREATE INDEX IF NOT EXISTS idx_message_statuses_lease_expires_at ON message_statuses(lease_expires_at);
 
 -- Recipient statuses table indexes
 CREATE INDEX IF NOT EXISTS idx_recipient_statuses_message_id ON recipient_statuses(message_id);
@@ -86,3 +161,8 @@ CREATE INDEX IF NOT EXISTS idx_recipient_statuses_address ON recipient_statuses(
 CREATE INDEX IF NOT EXISTS idx_recipient_statuses_status ON recipient_statuses(status);
 CREATE INDEX IF NOT EXISTS idx_recipient_statuses_timestamp ON recipient_statuses(timestamp);
 CREATE INDEX IF NOT EXISTS idx_recipient_statuses_delivery ON recipient_statuses(local_delivery, inbox_delivered, acknowledged);
+
+-- Lowercase recipient addresses stored by releases that matched addresses
+-- case-sensitively, so lookups by the canonical address find them
+UPDATE recipient_statuses SET address = lower(address) WHERE address <> lower(address);
+UPDATE messages SET recipients = lower(recipients::text)::jsonb WHERE recipients::text <> lower(recipients::text);
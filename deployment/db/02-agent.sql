@@ -4,14 +4,48 @@ CREATE TABLE IF NOT EXISTS agents (
     address VARCHAR(255) NOT NULL UNIQUE,
     delivery_mode VARCHAR(10) DEFAULT 'push',
     push_target VARCHAR(500),
+    push_targets JSONB,
+    push_policy VARCHAR(10),
+    push_strategy VARCHAR(10),
+    push_target_weights JSONB,
     headers JSONB,
     api_key VARCHAR(255),
     supported_schemas JSONB,
     requires_schema BOOLEAN DEFAULT FALSE,
+    allowed_senders JSONB,
+    max_concurrent_deliveries INTEGER NOT NULL DEFAULT 0,
     created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
-    last_access TIMESTAMPTZ NOT NULL DEFAULT NOW()
+    last_access TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    key_rotated_at TIMESTAMPTZ
 );
 
+-- Add fan-out push columns to agents tables created by earlier releases
+ALTER TABLE agents ADD COLUMN IF NOT EXISTS push_targets JSONB;
+ALTER TABLE agents ADD COLUMN IF NOT EXISTS push_policy VARCHAR(10);
+
+-- Add balanced push columns to agents tables created by earlier releases
+ALTER TABLE agents ADD COLUMN IF NOT EXISTS push_strategy VARCHAR(10);
+ALTER TABLE agents ADD COLUMN IF NOT EXISTS push_target_weights JSONB;
+
+-- Add sender restrictions to agents tables created by earlier releases
+ALTER TABLE agents ADD COLUMN IF NOT EXISTS allowed_senders JSONB;
+
+-- Add per-agent push concurrency caps to agents tables created by earlier releases
+ALTER TABLE agents ADD COLUMN IF NOT EXISTS max_concurrent_deliveries INTEGER NOT NULL DEFAULT 0;
+
+-- Add API key rotation times to agents tables created by earlier releases
+ALTER TABLE agents ADD COLUMN IF NOT EXISTS key_rotated_at TIMESTAMPTZ;
+
+-- Lowercase agent addresses stored by releases that matched addresses
+-- case-sensitively. Addresses differing only in case are left for the
+-- operator to merge, since lowering them would collide.
+UPDATE agents SET address = lower(address)
+WHERE address <> lower(address)
+  AND (SELECT count(*) FROM agents other WHERE lower(other.address) = lower(agents.address)) = 1;
+
 -- Create index on agents address
 CREATE INDEX IF NOT EXISTS idx_agents_address ON agents(address);
 
+-- Create index on agents API key hash for resolving a key to its agent
+CREATE INDEX IF NOT EXISTS idx_agents_api_key ON agents(api_key);
+
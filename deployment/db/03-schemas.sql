@@ -9,8 +9,20 @@ CREATE TABLE IF NOT EXISTS schemas (
     signature VARCHAR(512),
     checksum VARCHAR(64),
     size BIGINT DEFAULT 0,
+    strict BOOLEAN NOT NULL DEFAULT FALSE,
+    validation_mode VARCHAR(16) NOT NULL DEFAULT '',
+    defaults JSONB,
     updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
 );
 
+-- Add strict validation flag to schemas tables created by earlier releases
+ALTER TABLE schemas ADD COLUMN IF NOT EXISTS strict BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- Add default validation mode to schemas tables created by earlier releases
+ALTER TABLE schemas ADD COLUMN IF NOT EXISTS validation_mode VARCHAR(16) NOT NULL DEFAULT '';
+
+-- Add message defaults to schemas tables created by earlier releases
+ALTER TABLE schemas ADD COLUMN IF NOT EXISTS defaults JSONB;
+
 -- Create unique index on domain, entity, and version
 CREATE UNIQUE INDEX IF NOT EXISTS idx_schema_ver ON schemas (domain, entity, version);
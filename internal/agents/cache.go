@@ -0,0 +1,133 @@
+/*
+ * Copyright 2026 Cong Wang
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package agents
+
+import (
+	"maps"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// agentCache is a read-through cache of agents keyed by address. Entries
+// expire after ttl so changes made by other gateway replicas sharing the
+// same storage become visible; changes made through this registry
+// invalidate their entry immediately.
+type agentCache struct {
+	mu      sync.RWMutex
+	ttl     time.Duration
+	entries map[string]agentCacheEntry
+
+	hits   int64
+	misses int64
+}
+
+type agentCacheEntry struct {
+	agent   *LocalAgent
+	expires time.Time
+}
+
+// newAgentCache creates a cache; a non-positive ttl disables caching.
+func newAgentCache(ttl time.Duration) *agentCache {
+	return &agentCache{
+		ttl:     ttl,
+		entries: make(map[string]agentCacheEntry),
+	}
+}
+
+// enabled reports whether lookups are cached at all
+func (c *agentCache) enabled() bool {
+	return c.ttl > 0
+}
+
+// get returns a copy of the cached agent, if present and not expired
+func (c *agentCache) get(address string) (*LocalAgent, bool) {
+	c.mu.RLock()
+	entry, ok := c.entries[address]
+	c.mu.RUnlock()
+
+	if !ok || time.Now().After(entry.expires) {
+		atomic.AddInt64(&c.misses, 1)
+		return nil, false
+	}
+	atomic.AddInt64(&c.hits, 1)
+	return cloneAgent(entry.agent), true
+}
+
+// set caches a copy of the agent under its address
+func (c *agentCache) set(agent *LocalAgent) {
+	if !c.enabled() || agent == nil {
+		return
+	}
+
+	c.mu.Lock()
+	c.entries[agent.Address] = agentCacheEntry{
+		agent:   cloneAgent(agent),
+		expires: time.Now().Add(c.ttl),
+	}
+	c.mu.Unlock()
+}
+
+// touch sets the last access time of the cached entry for address, if
+// any, without extending its expiry
+func (c *agentCache) touch(address string, at time.Time) {
+	c.mu.Lock()
+	if entry, ok := c.entries[address]; ok {
+		agent := cloneAgent(entry.agent)
+		agent.LastAccess = at
+		entry.agent = agent
+		c.entries[address] = entry
+	}
+	c.mu.Unlock()
+}
+
+// invalidate drops the cached entry for address
+func (c *agentCache) invalidate(address string) {
+	c.mu.Lock()
+	delete(c.entries, address)
+	c.mu.Unlock()
+}
+
+// stats returns the cumulative hit and miss counts
+func (c *agentCache) stats() (hits, misses int64) {
+	return atomic.LoadInt64(&c.hits), atomic.LoadInt64(&c.misses)
+}
+
+// cloneAgent copies an agent, including its slices and maps, so cached
+// entries are never shared with callers that modify them.
+func cloneAgent(agent *LocalAgent) *LocalAgent {
+	clone := *agent
+	if agent.PushTargets != nil {
+		clone.PushTargets = append([]string(nil), agent.PushTargets...)
+	}
+	if agent.PushTargetWeights != nil {
+		clone.PushTargetWeights = maps.Clone(agent.PushTargetWeights)
+	}
+	if agent.SupportedSchemas != nil {
+		clone.SupportedSchemas = append([]string(nil), agent.SupportedSchemas...)
+	}
+	if agent.AllowedSenders != nil {
+		clone.AllowedSenders = append([]string(nil), agent.AllowedSenders...)
+	}
+	if agent.Headers != nil {
+		clone.Headers = make(map[string]string, len(agent.Headers))
+		for k, v := range agent.Headers {
+			clone.Headers[k] = v
+		}
+	}
+	return &clone
+}
@@ -0,0 +1,313 @@
+/*
+ * Copyright 2026 Cong Wang
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package agents
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/amtp-protocol/agentry/internal/metrics"
+)
+
+// countingAgentStore counts storage lookups so tests can tell cache hits
+// from round-trips
+type countingAgentStore struct {
+	*inMemoryAgentStore
+	mu   sync.Mutex
+	gets int64
+}
+
+func (s *countingAgentStore) GetAgent(ctx context.Context, agentAddress string) (*LocalAgent, error) {
+	atomic.AddInt64(&s.gets, 1)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.inMemoryAgentStore.GetAgent(ctx, agentAddress)
+}
+
+func (s *countingAgentStore) GetAgentByAPIKey(ctx context.Context, hashedKey string) (*LocalAgent, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.inMemoryAgentStore.GetAgentByAPIKey(ctx, hashedKey)
+}
+
+func (s *countingAgentStore) UpdateAgent(ctx context.Context, agent *LocalAgent) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.inMemoryAgentStore.UpdateAgent(ctx, agent)
+}
+
+func (s *countingAgentStore) UpdateLastAccess(ctx context.Context, agentAddress string, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.inMemoryAgentStore.UpdateLastAccess(ctx, agentAddress, at)
+}
+
+func (s *countingAgentStore) lookups() int64 {
+	return atomic.LoadInt64(&s.gets)
+}
+
+func createCachedTestRegistry(ttl time.Duration, m metrics.MetricsProvider) (*Registry, *countingAgentStore) {
+	store := &countingAgentStore{inMemoryAgentStore: newInMemoryAgentStore()}
+	return NewRegistry(RegistryConfig{
+		LocalDomain:   "localhost",
+		SchemaManager: NewMockSchemaManager(),
+		APIKeySalt:    "test-salt",
+		CacheTTL:      ttl,
+		Metrics:       m,
+	}, store), store
+}
+
+func TestAgentCache_ReadThrough(t *testing.T) {
+	m := metrics.NewSimpleMetrics()
+	registry, store := createCachedTestRegistry(time.Minute, m)
+	ctx := context.Background()
+
+	if err := registry.RegisterAgent(ctx, &LocalAgent{Address: "alice", DeliveryMode: "pull"}); err != nil {
+		t.Fatalf("Failed to register agent: %v", err)
+	}
+
+	for i := 0; i < 3; i++ {
+		if _, err := registry.GetAgent(ctx, "alice@localhost"); err != nil {
+			t.Fatalf("Failed to get agent: %v", err)
+		}
+	}
+
+	if got := store.lookups(); got != 1 {
+		t.Errorf("Expected 1 storage lookup, got %d", got)
+	}
+
+	stats := registry.GetStats()
+	if stats["cache_hits"] != int64(2) || stats["cache_misses"] != int64(1) {
+		t.Errorf("Expected 2 hits and 1 miss, got %v hits and %v misses", stats["cache_hits"], stats["cache_misses"])
+	}
+
+	data, err := m.ToJSON()
+	if err != nil {
+		t.Fatalf("Failed to export metrics: %v", err)
+	}
+	var exported struct {
+		AgentCache struct {
+			Hits   int64 `json:"hits"`
+			Misses int64 `json:"misses"`
+		} `json:"agent_cache"`
+	}
+	if err := json.Unmarshal(data, &exported); err != nil {
+		t.Fatalf("Failed to decode metrics: %v", err)
+	}
+	if exported.AgentCache.Hits != 2 || exported.AgentCache.Misses != 1 {
+		t.Errorf("Expected metrics to record 2 hits and 1 miss, got %+v", exported.AgentCache)
+	}
+}
+
+func TestAgentCache_ReturnsCopies(t *testing.T) {
+	registry, _ := createCachedTestRegistry(time.Minute, nil)
+	ctx := context.Background()
+
+	if err := registry.RegisterAgent(ctx, &LocalAgent{
+		Address:          "alice",
+		DeliveryMode:     "pull",
+		SupportedSchemas: []string{"agntcy:commerce.*"},
+	}); err != nil {
+		t.Fatalf("Failed to register agent: %v", err)
+	}
+
+	first, _ := registry.GetAgent(ctx, "alice@localhost")
+	first.DeliveryMode = "push"
+	first.SupportedSchemas[0] = "agntcy:tampered.*"
+
+	second, _ := registry.GetAgent(ctx, "alice@localhost")
+	if second.DeliveryMode != "pull" || second.SupportedSchemas[0] != "agntcy:commerce.*" {
+		t.Errorf("Cached agent was modified through a returned copy: %+v", second)
+	}
+}
+
+func TestAgentCache_InvalidatedOnUpdate(t *testing.T) {
+	registry, store := createCachedTestRegistry(time.Minute, nil)
+	ctx := context.Background()
+
+	agent := &LocalAgent{Address: "alice", DeliveryMode: "pull"}
+	if err := registry.RegisterAgent(ctx, agent); err != nil {
+		t.Fatalf("Failed to register agent: %v", err)
+	}
+	originalKey := agent.APIKey
+
+	// Warm the cache
+	if !registry.VerifyAPIKey(ctx, agent.Address, originalKey) {
+		t.Fatal("Original API key should work")
+	}
+
+	// A rotated key takes effect immediately, not after the TTL
+	newKey, err := registry.RotateAPIKey(ctx, agent.Address)
+	if err != nil {
+		t.Fatalf("Failed to rotate API key: %v", err)
+	}
+	if registry.VerifyAPIKey(ctx, agent.Address, originalKey) {
+		t.Error("Original API key should be rejected right after rotation")
+	}
+	if !registry.VerifyAPIKey(ctx, agent.Address, newKey) {
+		t.Error("New API key should be accepted right after rotation")
+	}
+
+	// Last access updates are visible without another storage lookup
+	before, _ := registry.GetAgent(ctx, agent.Address)
+	time.Sleep(10 * time.Millisecond)
+	registry.UpdateLastAccess(ctx, agent.Address)
+	lookups := store.lookups()
+	after, _ := registry.GetAgent(ctx, agent.Address)
+	if !after.LastAccess.After(before.LastAccess) {
+		t.Error("Expected fresh last access time after update")
+	}
+	if store.lookups() != lookups {
+		t.Error("Expected updated agent to be served from the cache")
+	}
+
+	// Unregistering and re-registering returns the new registration
+	if err := registry.UnregisterAgent(ctx, "alice"); err != nil {
+		t.Fatalf("Failed to unregister agent: %v", err)
+	}
+	if _, err := registry.GetAgent(ctx, agent.Address); err == nil {
+		t.Error("Expected unregistered agent to be gone")
+	}
+	if err := registry.RegisterAgent(ctx, &LocalAgent{
+		Address:      "alice",
+		DeliveryMode: "push",
+		PushTarget:   "https://example.com/webhook",
+	}); err != nil {
+		t.Fatalf("Failed to re-register agent: %v", err)
+	}
+	fresh, err := registry.GetAgent(ctx, agent.Address)
+	if err != nil {
+		t.Fatalf("Failed to get re-registered agent: %v", err)
+	}
+	if fresh.DeliveryMode != "push" || fresh.PushTarget != "https://example.com/webhook" {
+		t.Errorf("Expected re-registered push agent, got %+v", fresh)
+	}
+}
+
+func TestAgentCache_Expiry(t *testing.T) {
+	registry, store := createCachedTestRegistry(20*time.Millisecond, nil)
+	ctx := context.Background()
+
+	if err := registry.RegisterAgent(ctx, &LocalAgent{Address: "alice", DeliveryMode: "pull"}); err != nil {
+		t.Fatalf("Failed to register agent: %v", err)
+	}
+	if _, err := registry.GetAgent(ctx, "alice@localhost"); err != nil {
+		t.Fatalf("Failed to get agent: %v", err)
+	}
+
+	// Simulate another replica changing the shared storage
+	stored, _ := store.inMemoryAgentStore.GetAgent(ctx, "alice@localhost")
+	stored.DeliveryMode = "push"
+	stored.PushTarget = "https://example.com/webhook"
+	if err := store.UpdateAgent(ctx, stored); err != nil {
+		t.Fatalf("Failed to update stored agent: %v", err)
+	}
+
+	if cached, _ := registry.GetAgent(ctx, "alice@localhost"); cached.DeliveryMode != "pull" {
+		t.Errorf("Expected cached agent before expiry, got %s", cached.DeliveryMode)
+	}
+
+	time.Sleep(30 * time.Millisecond)
+
+	if fresh, _ := registry.GetAgent(ctx, "alice@localhost"); fresh.DeliveryMode != "push" {
+		t.Errorf("Expected fresh agent after expiry, got %s", fresh.DeliveryMode)
+	}
+}
+
+func TestAgentCache_LastAccessKeepsOtherChanges(t *testing.T) {
+	registry, store := createCachedTestRegistry(time.Minute, nil)
+	ctx := context.Background()
+
+	if err := registry.RegisterAgent(ctx, &LocalAgent{Address: "alice", DeliveryMode: "pull"}); err != nil {
+		t.Fatalf("Failed to register agent: %v", err)
+	}
+	if _, err := registry.GetAgent(ctx, "alice@localhost"); err != nil {
+		t.Fatalf("Failed to get agent: %v", err)
+	}
+
+	// Another replica rotates the key and switches the agent to push
+	stored, _ := store.inMemoryAgentStore.GetAgent(ctx, "alice@localhost")
+	stored.APIKey = "rotated-elsewhere"
+	stored.DeliveryMode = "push"
+	stored.PushTarget = "https://example.com/webhook"
+	if err := store.UpdateAgent(ctx, stored); err != nil {
+		t.Fatalf("Failed to update stored agent: %v", err)
+	}
+
+	// This replica's stale cache entry must not be written back
+	registry.UpdateLastAccess(ctx, "alice@localhost")
+
+	stored, _ = store.inMemoryAgentStore.GetAgent(ctx, "alice@localhost")
+	if stored.APIKey != "rotated-elsewhere" || stored.DeliveryMode != "push" || stored.PushTarget != "https://example.com/webhook" {
+		t.Errorf("Expected the other replica's changes to survive a last access update, got %+v", stored)
+	}
+	if stored.LastAccess.IsZero() {
+		t.Error("Expected the last access time to be stored")
+	}
+}
+
+func TestAgentCache_Disabled(t *testing.T) {
+	registry, store := createCachedTestRegistry(0, nil)
+	ctx := context.Background()
+
+	if err := registry.RegisterAgent(ctx, &LocalAgent{Address: "alice", DeliveryMode: "pull"}); err != nil {
+		t.Fatalf("Failed to register agent: %v", err)
+	}
+	for i := 0; i < 3; i++ {
+		if _, err := registry.GetAgent(ctx, "alice@localhost"); err != nil {
+			t.Fatalf("Failed to get agent: %v", err)
+		}
+	}
+
+	if got := store.lookups(); got != 3 {
+		t.Errorf("Expected every lookup to hit storage with the cache disabled, got %d", got)
+	}
+}
+
+func TestAgentCache_Concurrent(t *testing.T) {
+	registry, _ := createCachedTestRegistry(time.Minute, nil)
+	ctx := context.Background()
+
+	if err := registry.RegisterAgent(ctx, &LocalAgent{Address: "alice", DeliveryMode: "pull"}); err != nil {
+		t.Fatalf("Failed to register agent: %v", err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				if _, err := registry.GetAgent(ctx, "alice@localhost"); err != nil {
+					t.Errorf("Failed to get agent: %v", err)
+					return
+				}
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				registry.UpdateLastAccess(ctx, "alice@localhost")
+			}
+		}()
+	}
+	wg.Wait()
+}
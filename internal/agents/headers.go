@@ -0,0 +1,117 @@
+/*
+ * Copyright 2026 Cong Wang
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package agents
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/amtp-protocol/agentry/internal/types"
+)
+
+// maxRenderedHeaderLength bounds a rendered header value
+const maxRenderedHeaderLength = 8 * 1024
+
+// HeaderTemplateData is the subset of message fields available to templated
+// agent headers, e.g. "X-Subject: {{.Subject}}"
+type HeaderTemplateData struct {
+	MessageID    string
+	Sender       string
+	Recipient    string
+	Subject      string
+	Schema       string
+	Timestamp    string // RFC 3339
+	InReplyTo    string
+	ResponseType string
+}
+
+// NewHeaderTemplateData returns the template data for delivering message to recipient
+func NewHeaderTemplateData(message *types.Message, recipient string) HeaderTemplateData {
+	return HeaderTemplateData{
+		MessageID:    message.MessageID,
+		Sender:       message.Sender,
+		Recipient:    recipient,
+		Subject:      message.Subject,
+		Schema:       message.Schema,
+		Timestamp:    message.Timestamp.Format(time.RFC3339),
+		InReplyTo:    message.InReplyTo,
+		ResponseType: message.ResponseType,
+	}
+}
+
+// isHeaderTemplate reports whether a header value needs rendering; other
+// values are sent unchanged
+func isHeaderTemplate(value string) bool {
+	return strings.Contains(value, "{{")
+}
+
+// RenderHeaders renders templated header values for one delivery. Line breaks
+// in rendered values are replaced with spaces so message fields cannot inject
+// additional headers.
+func RenderHeaders(headers map[string]string, data HeaderTemplateData) (map[string]string, error) {
+	rendered := make(map[string]string, len(headers))
+	for key, value := range headers {
+		if !isHeaderTemplate(value) {
+			rendered[key] = value
+			continue
+		}
+		out, err := renderHeader(key, value, data)
+		if err != nil {
+			return nil, err
+		}
+		rendered[key] = out
+	}
+	return rendered, nil
+}
+
+// validateHeaderTemplates parses every templated header value and renders it
+// against sample data, so unknown fields are rejected at registration
+func validateHeaderTemplates(headers map[string]string) error {
+	sample := HeaderTemplateData{
+		MessageID: "00000000-0000-7000-8000-000000000000",
+		Sender:    "sender@example.com",
+		Recipient: "recipient@example.com",
+		Timestamp: time.Unix(0, 0).UTC().Format(time.RFC3339),
+	}
+	for key, value := range headers {
+		if !isHeaderTemplate(value) {
+			continue
+		}
+		if _, err := renderHeader(key, value, sample); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func renderHeader(key, value string, data HeaderTemplateData) (string, error) {
+	tmpl, err := template.New(key).Option("missingkey=error").Parse(value)
+	if err != nil {
+		return "", fmt.Errorf("header %s: %w", key, err)
+	}
+
+	var out strings.Builder
+	if err := tmpl.Execute(&out, data); err != nil {
+		return "", fmt.Errorf("header %s: %w", key, err)
+	}
+	if out.Len() > maxRenderedHeaderLength {
+		return "", fmt.Errorf("header %s: rendered value exceeds %d bytes", key, maxRenderedHeaderLength)
+	}
+	return strings.NewReplacer("\r\n", " ", "\r", " ", "\n", " ").Replace(out.String()), nil
+}
@@ -0,0 +1,136 @@
+/*
+ * Copyright 2026 Cong Wang
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package agents
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/amtp-protocol/agentry/internal/types"
+)
+
+func TestRenderHeaders(t *testing.T) {
+	message := &types.Message{
+		MessageID: "01890a5d-ac96-774b-b9aa-9d3d3c3e1a2b",
+		Sender:    "sender@example.com",
+		Subject:   "Order #42",
+		Schema:    "agntcy:commerce.order.v1",
+		Timestamp: time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC),
+	}
+
+	rendered, err := RenderHeaders(map[string]string{
+		"Authorization": "Bearer static-token",
+		"X-Subject":     "{{.Subject}}",
+		"X-Trace":       "{{.MessageID}}/{{.Recipient}}",
+		"X-Sent":        "{{.Timestamp}}",
+		"X-Schema":      `{{if .Schema}}{{.Schema}}{{else}}none{{end}}`,
+	}, NewHeaderTemplateData(message, "agent@localhost"))
+	if err != nil {
+		t.Fatalf("RenderHeaders failed: %v", err)
+	}
+
+	expected := map[string]string{
+		"Authorization": "Bearer static-token",
+		"X-Subject":     "Order #42",
+		"X-Trace":       "01890a5d-ac96-774b-b9aa-9d3d3c3e1a2b/agent@localhost",
+		"X-Sent":        "2026-01-02T03:04:05Z",
+		"X-Schema":      "agntcy:commerce.order.v1",
+	}
+	for key, want := range expected {
+		if rendered[key] != want {
+			t.Errorf("Header %s = %q, want %q", key, rendered[key], want)
+		}
+	}
+}
+
+func TestRenderHeaders_StripsLineBreaks(t *testing.T) {
+	message := &types.Message{Subject: "hello\r\nX-Injected: yes\nbye"}
+
+	rendered, err := RenderHeaders(map[string]string{"X-Subject": "{{.Subject}}"}, NewHeaderTemplateData(message, "agent@localhost"))
+	if err != nil {
+		t.Fatalf("RenderHeaders failed: %v", err)
+	}
+	if rendered["X-Subject"] != "hello X-Injected: yes bye" {
+		t.Errorf("Expected line breaks to be replaced, got %q", rendered["X-Subject"])
+	}
+}
+
+func TestValidateHeaderTemplates(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		wantErr string
+	}{
+		{"plain value", "Bearer token", ""},
+		{"known field", "{{.Subject}}", ""},
+		{"braces without template", "not {a template}", ""},
+		{"unknown field", "{{.Payload}}", "can't evaluate field Payload"},
+		{"unterminated action", "{{.Subject", "unclosed action"},
+		{"undefined function", "{{env \"HOME\"}}", `function "env" not defined`},
+		{"oversized output", `{{printf "%9000s" .Subject}}`, "exceeds"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateHeaderTemplates(map[string]string{"X-Test": tt.value})
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestRegisterAgent_HeaderTemplates(t *testing.T) {
+	registry := createTestRegistry()
+	ctx := context.Background()
+
+	err := registry.RegisterAgent(ctx, &LocalAgent{
+		Address:      "bad-template",
+		DeliveryMode: "push",
+		PushTarget:   "http://example.com/webhook",
+		Headers:      map[string]string{"X-Subject": "{{.Subjet}}"},
+	})
+	if err == nil || !strings.Contains(err.Error(), "invalid header template: header X-Subject") {
+		t.Errorf("Expected invalid header template error, got %v", err)
+	}
+
+	agent := &LocalAgent{
+		Address:      "good-template",
+		DeliveryMode: "push",
+		PushTarget:   "http://example.com/webhook",
+		Headers:      map[string]string{"X-Subject": "{{.Subject}}"},
+	}
+	if err := registry.RegisterAgent(ctx, agent); err != nil {
+		t.Fatalf("Failed to register agent: %v", err)
+	}
+
+	stored, err := registry.GetAgent(ctx, agent.Address)
+	if err != nil {
+		t.Fatalf("Failed to get agent: %v", err)
+	}
+	if stored.Headers["X-Subject"] != "{{.Subject}}" {
+		t.Errorf("Expected the template to be stored unrendered, got %q", stored.Headers["X-Subject"])
+	}
+}
@@ -18,16 +18,28 @@ package agents
 
 import (
 	"context"
+	"errors"
+	"time"
 
 	"github.com/amtp-protocol/agentry/internal/types"
 )
 
+// ErrAgentNotFound is returned by agent lookups when no agent is registered
+// at the address
+var ErrAgentNotFound = errors.New("agent not found")
+
 // AgentStore defines the storage operations required by the agent registry
 type AgentStore interface {
 	CreateAgent(ctx context.Context, agent *LocalAgent) error
 	DeleteAgent(ctx context.Context, agentAddress string) error
 	GetAgent(ctx context.Context, agentAddress string) (*LocalAgent, error)
+	// GetAgentByAPIKey returns the agent whose stored API key hash is
+	// hashedKey, or an error wrapping ErrAgentNotFound
+	GetAgentByAPIKey(ctx context.Context, hashedKey string) (*LocalAgent, error)
 	UpdateAgent(ctx context.Context, agent *LocalAgent) error
+	// UpdateLastAccess sets only the agent's last access time, leaving
+	// the rest of the record as other gateways may have changed it
+	UpdateLastAccess(ctx context.Context, agentAddress string, at time.Time) error
 	ListAgents(ctx context.Context) ([]*LocalAgent, error)
 	GetSupportedSchemas(ctx context.Context) ([]string, error)
 }
@@ -38,12 +50,14 @@ type AgentRegistry interface {
 	RegisterAgent(ctx context.Context, agent *LocalAgent) error
 	UnregisterAgent(ctx context.Context, agentNameOrAddress string) error
 	GetAgent(ctx context.Context, agentAddress string) (*LocalAgent, error)
+	ResolveAgent(ctx context.Context, address string) (*LocalAgent, error)
 	GetAllAgents(ctx context.Context) map[string]*LocalAgent
 	GetSupportedSchemas(ctx context.Context) []string
 
 	// API key management
 	GenerateAPIKey() (string, error)
 	VerifyAPIKey(ctx context.Context, agentAddress, apiKey string) bool
+	ResolveAPIKey(ctx context.Context, apiKey string) (*LocalAgent, error)
 	UpdateLastAccess(ctx context.Context, agentAddress string)
 	RotateAPIKey(ctx context.Context, agentAddress string) (string, error)
 
@@ -23,26 +23,171 @@ import (
 	"crypto/subtle"
 	"encoding/base64"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"regexp"
+	"slices"
 	"strings"
 	"time"
 
+	"github.com/amtp-protocol/agentry/internal/metrics"
 	"github.com/amtp-protocol/agentry/internal/schema"
 	"github.com/amtp-protocol/agentry/internal/types"
 )
 
 // LocalAgent represents a local agent configuration
 type LocalAgent struct {
-	Address          string            `json:"address"`           // agent@domain format
-	DeliveryMode     string            `json:"delivery_mode"`     // "push" or "pull"
-	PushTarget       string            `json:"push_target"`       // webhook URL for push delivery (required for push mode)
-	Headers          map[string]string `json:"headers"`           // additional headers for push
-	APIKey           string            `json:"api_key"`           // unique API key for inbox access
-	SupportedSchemas []string          `json:"supported_schemas"` // schemas this agent can handle (e.g., ["agntcy:commerce.*", "agntcy:auth.user.*"])
-	RequiresSchema   bool              `json:"requires_schema"`   // whether this agent requires schema validation (auto-determined from SupportedSchemas)
-	CreatedAt        time.Time         `json:"created_at"`        // registration timestamp
-	LastAccess       time.Time         `json:"last_access"`       // last inbox access timestamp
+	Address           string            `json:"address"`             // agent@domain format
+	DeliveryMode      string            `json:"delivery_mode"`       // "push", "pull" or "kafka"
+	PushTarget        string            `json:"push_target"`         // webhook URL for push delivery, or topic for kafka delivery (required for both)
+	PushTargets       []string          `json:"push_targets"`        // additional webhook URLs receiving the same message (fan-out)
+	PushPolicy        string            `json:"push_policy"`         // "all" (every target must succeed) or "any" (one success is enough)
+	PushStrategy      string            `json:"push_strategy"`       // "fanout" (every target gets each message) or "balanced" (one target per message, by weight)
+	PushTargetWeights map[string]int    `json:"push_target_weights"` // balanced strategy weights by target URL; unlisted targets weigh 1
+	Headers           map[string]string `json:"headers"`             // additional headers for push
+	APIKey            string            `json:"api_key"`             // unique API key for inbox access
+	SupportedSchemas  []string          `json:"supported_schemas"`   // schemas this agent can handle (e.g., ["agntcy:commerce.*", "agntcy:auth.user.*"])
+	RequiresSchema    bool              `json:"requires_schema"`     // whether this agent requires schema validation (auto-determined from SupportedSchemas)
+	AllowedSenders    []string          `json:"allowed_senders"`     // senders delivered to this agent: addresses, domains or *.domain patterns; empty allows all
+	CreatedAt         time.Time         `json:"created_at"`          // registration timestamp
+	LastAccess        time.Time         `json:"last_access"`         // last inbox access or push delivery timestamp
+	KeyRotatedAt      time.Time         `json:"key_rotated_at"`      // last API key rotation; zero if never rotated
+
+	// MaxConcurrentDeliveries caps the push deliveries to this agent in
+	// flight at once; further deliveries wait. Zero uses the gateway default.
+	MaxConcurrentDeliveries int `json:"max_concurrent_deliveries"`
+}
+
+// CatchAllAgentName registers the catch-all agent, which receives local
+// messages addressed to agents that are not registered
+const CatchAllAgentName = "*"
+
+// Push delivery policies for agents with multiple push targets
+const (
+	PushPolicyAll = "all"
+	PushPolicyAny = "any"
+)
+
+// Push strategies for agents with multiple push targets
+const (
+	PushStrategyFanout   = "fanout"
+	PushStrategyBalanced = "balanced"
+)
+
+// kafkaTopicRegex matches a valid Kafka topic name
+var kafkaTopicRegex = regexp.MustCompile(`^[a-zA-Z0-9._-]{1,249}$`)
+
+// AllPushTargets returns the agent's push targets, the singular PushTarget
+// first, followed by any additional PushTargets with duplicates removed.
+func (a *LocalAgent) AllPushTargets() []string {
+	targets := make([]string, 0, 1+len(a.PushTargets))
+	seen := make(map[string]struct{}, 1+len(a.PushTargets))
+	for _, target := range append([]string{a.PushTarget}, a.PushTargets...) {
+		if target == "" {
+			continue
+		}
+		if _, dup := seen[target]; dup {
+			continue
+		}
+		seen[target] = struct{}{}
+		targets = append(targets, target)
+	}
+	return targets
+}
+
+// PushTargetWeight returns the share of messages target receives under the
+// balanced push strategy
+func (a *LocalAgent) PushTargetWeight(target string) int {
+	if weight, ok := a.PushTargetWeights[target]; ok {
+		return weight
+	}
+	return 1
+}
+
+// validatePushStrategy defaults the push strategy to fanout and checks that
+// weights are positive and name push targets of a balanced agent
+func validatePushStrategy(agent *LocalAgent) error {
+	switch agent.PushStrategy {
+	case "":
+		agent.PushStrategy = PushStrategyFanout
+	case PushStrategyFanout, PushStrategyBalanced:
+	default:
+		return fmt.Errorf("push strategy must be '%s' or '%s'", PushStrategyFanout, PushStrategyBalanced)
+	}
+
+	if agent.PushStrategy == PushStrategyBalanced && agent.DeliveryMode != "push" {
+		return fmt.Errorf("balanced push strategy requires push delivery mode")
+	}
+	if len(agent.PushTargetWeights) == 0 {
+		return nil
+	}
+	if agent.PushStrategy != PushStrategyBalanced {
+		return fmt.Errorf("push target weights require the balanced push strategy")
+	}
+
+	targets := agent.AllPushTargets()
+	for target, weight := range agent.PushTargetWeights {
+		if !slices.Contains(targets, target) {
+			return fmt.Errorf("push target weight given for unknown target %s", target)
+		}
+		if weight < 1 {
+			return fmt.Errorf("push target weight for %s must be positive", target)
+		}
+	}
+	return nil
+}
+
+// AllowsSender reports whether messages from sender may be delivered to the
+// agent. Each allowed sender is an address, a domain covering every address
+// in it, or a wildcard such as "*.example.com" covering exactly one extra
+// label. An agent without allowed senders accepts every sender.
+func (a *LocalAgent) AllowsSender(sender string) bool {
+	if len(a.AllowedSenders) == 0 {
+		return true
+	}
+
+	sender = types.NormalizeAddress(sender)
+	domain := sender[strings.LastIndex(sender, "@")+1:]
+	for _, allowed := range a.AllowedSenders {
+		switch {
+		case strings.Contains(allowed, "@"):
+			if allowed == sender {
+				return true
+			}
+		case strings.HasPrefix(allowed, "*."):
+			label, rest, found := strings.Cut(domain, ".")
+			if found && label != "" && rest == allowed[2:] {
+				return true
+			}
+		case allowed == domain:
+			return true
+		}
+	}
+	return false
+}
+
+// allowedSenderDomainRegex matches the domain of an allowed sender entry
+var allowedSenderDomainRegex = regexp.MustCompile(`^(\*\.)?[a-z0-9]([a-z0-9-]*[a-z0-9])?(\.[a-z0-9]([a-z0-9-]*[a-z0-9])?)*$`)
+
+// normalizeAllowedSenders validates allowed sender entries and puts them in
+// the lower-case form AllowsSender compares against
+func normalizeAllowedSenders(entries []string) ([]string, error) {
+	var normalized []string
+	for _, entry := range entries {
+		entry = strings.ToLower(strings.TrimSpace(entry))
+		if entry == "" {
+			continue
+		}
+		if local, domain, ok := strings.Cut(entry, "@"); ok {
+			if local == "" || strings.HasPrefix(domain, "*.") || !allowedSenderDomainRegex.MatchString(domain) {
+				return nil, fmt.Errorf("invalid sender address '%s'", entry)
+			}
+		} else if !allowedSenderDomainRegex.MatchString(entry) {
+			return nil, fmt.Errorf("invalid sender domain '%s'", entry)
+		}
+		normalized = append(normalized, entry)
+	}
+	return normalized, nil
 }
 
 // Registry manages local agent registrations and configurations
@@ -51,6 +196,12 @@ type Registry struct {
 	schemaManager SchemaManager
 	storage       AgentStore
 	apiKeySalt    string
+	apiKeyPrefix  string
+	apiKeyLength  int
+	cache         *agentCache
+	metrics       metrics.MetricsProvider
+	kafkaEnabled  bool
+	addresses     types.AddressSchemes
 }
 
 // SchemaManager interface for schema validation
@@ -64,15 +215,34 @@ type RegistryConfig struct {
 	LocalDomain   string
 	SchemaManager SchemaManager
 	APIKeySalt    string
+	APIKeyPrefix  string                  // Prepended to generated API keys
+	APIKeyLength  int                     // Random bytes per generated API key; zero means defaultAPIKeyLength
+	CacheTTL      time.Duration           // How long agent lookups are cached; zero disables the cache
+	Metrics       metrics.MetricsProvider // Optional; receives cache hit/miss counts
+	KafkaEnabled  bool                    // Accept agents in kafka delivery mode
+
+	// AddressSchemes are the address forms agents may be registered under
+	// besides a name in the local domain; nil means types.DefaultAddressSchemes
+	AddressSchemes types.AddressSchemes
 }
 
 // NewRegistry creates a new agent registry
 func NewRegistry(config RegistryConfig, storage AgentStore) *Registry {
+	addresses := config.AddressSchemes
+	if addresses == nil {
+		addresses = types.DefaultAddressSchemes
+	}
 	return &Registry{
 		localDomain:   config.LocalDomain,
 		schemaManager: config.SchemaManager,
 		storage:       storage,
 		apiKeySalt:    config.APIKeySalt,
+		apiKeyPrefix:  config.APIKeyPrefix,
+		apiKeyLength:  config.APIKeyLength,
+		cache:         newAgentCache(config.CacheTTL),
+		metrics:       config.Metrics,
+		kafkaEnabled:  config.KafkaEnabled,
+		addresses:     addresses,
 	}
 }
 
@@ -91,14 +261,66 @@ func (r *Registry) RegisterAgent(ctx context.Context, agent *LocalAgent) error {
 	// Update the agent with the normalized full address
 	agent.Address = fullAddress
 
-	if agent.DeliveryMode != "push" && agent.DeliveryMode != "pull" {
-		return fmt.Errorf("delivery mode must be 'push' or 'pull'")
+	switch agent.DeliveryMode {
+	case "push", "pull":
+	case "kafka":
+		if !r.kafkaEnabled {
+			return fmt.Errorf("kafka delivery mode is not enabled on this gateway")
+		}
+	default:
+		return fmt.Errorf("delivery mode must be 'push', 'pull' or 'kafka'")
 	}
 
-	if agent.DeliveryMode == "push" && agent.PushTarget == "" {
+	if agent.DeliveryMode == "push" && len(agent.AllPushTargets()) == 0 {
 		return fmt.Errorf("push target URL is required for push delivery mode")
 	}
 
+	// A kafka agent's push target is the single topic it consumes from
+	if agent.DeliveryMode == "kafka" {
+		if !kafkaTopicRegex.MatchString(agent.PushTarget) || agent.PushTarget == "." || agent.PushTarget == ".." {
+			return fmt.Errorf("push target must be a valid kafka topic for kafka delivery mode")
+		}
+		if len(agent.PushTargets) > 0 {
+			return fmt.Errorf("kafka delivery mode takes a single topic")
+		}
+	}
+
+	// Inboxes are kept per recipient address, so messages for unregistered
+	// agents can only reach the catch-all agent by push
+	if agent.Address == r.catchAllAddress() && agent.DeliveryMode != "push" {
+		return fmt.Errorf("catch-all agent must use push delivery mode")
+	}
+
+	// Default to requiring every push target to accept the message
+	switch agent.PushPolicy {
+	case "":
+		agent.PushPolicy = PushPolicyAll
+	case PushPolicyAll, PushPolicyAny:
+	default:
+		return fmt.Errorf("push policy must be '%s' or '%s'", PushPolicyAll, PushPolicyAny)
+	}
+
+	if err := validatePushStrategy(agent); err != nil {
+		return err
+	}
+
+	if agent.MaxConcurrentDeliveries < 0 {
+		return fmt.Errorf("max concurrent deliveries cannot be negative")
+	}
+	if agent.MaxConcurrentDeliveries > 0 && agent.DeliveryMode != "push" {
+		return fmt.Errorf("max concurrent deliveries requires push delivery mode")
+	}
+
+	if err := validateHeaderTemplates(agent.Headers); err != nil {
+		return fmt.Errorf("invalid header template: %w", err)
+	}
+
+	allowedSenders, err := normalizeAllowedSenders(agent.AllowedSenders)
+	if err != nil {
+		return fmt.Errorf("invalid allowed senders: %w", err)
+	}
+	agent.AllowedSenders = allowedSenders
+
 	// Validate supported schemas
 	if err := r.validateSupportedSchemas(context.Background(), agent.SupportedSchemas); err != nil {
 		return fmt.Errorf("invalid supported schemas: %w", err)
@@ -128,6 +350,7 @@ func (r *Registry) RegisterAgent(ctx context.Context, agent *LocalAgent) error {
 	agent.LastAccess = now
 
 	err = r.storage.CreateAgent(ctx, agent)
+	r.cache.invalidate(agent.Address)
 
 	// Restore plain key for the caller
 	agent.APIKey = plainAPIKey
@@ -147,6 +370,7 @@ func (r *Registry) UnregisterAgent(ctx context.Context, agentNameOrAddress strin
 	}
 
 	err = r.storage.DeleteAgent(ctx, fullAddress)
+	r.cache.invalidate(fullAddress)
 	if err != nil {
 		return fmt.Errorf("failed to unregister agent: %w", err)
 	}
@@ -167,18 +391,50 @@ func (r *Registry) GetAgent(ctx context.Context, agentAddress string) (*LocalAge
 	return &agentCopy, nil
 }
 
-// getAgentInternal returns the raw agent data including hashed API key
+// getAgentInternal returns the raw agent data including hashed API key,
+// served from the cache when possible
 func (r *Registry) getAgentInternal(ctx context.Context, agentAddress string) (*LocalAgent, error) {
+	agentAddress = types.NormalizeAddress(agentAddress)
+	if r.cache.enabled() {
+		agent, hit := r.cache.get(agentAddress)
+		if r.metrics != nil {
+			r.metrics.RecordAgentCacheLookup(hit)
+		}
+		if hit {
+			return agent, nil
+		}
+	}
+
 	agent, err := r.storage.GetAgent(ctx, agentAddress)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get agent: %w", err)
 	}
 	if agent == nil {
-		return nil, fmt.Errorf("agent not found: %s", agentAddress)
+		return nil, fmt.Errorf("%w: %s", ErrAgentNotFound, agentAddress)
 	}
+	r.cache.set(agent)
 	return agent, nil
 }
 
+// ResolveAgent returns the agent receiving messages for a local address: the
+// agent registered at the address or, if there is none, the catch-all agent.
+// Addresses in other domains never resolve to the catch-all agent.
+func (r *Registry) ResolveAgent(ctx context.Context, address string) (*LocalAgent, error) {
+	agent, err := r.GetAgent(ctx, address)
+	if err == nil || !errors.Is(err, ErrAgentNotFound) || !r.isLocalAddress(address) {
+		return agent, err
+	}
+
+	catchAll, catchAllErr := r.GetAgent(ctx, r.catchAllAddress())
+	if catchAllErr != nil {
+		if !errors.Is(catchAllErr, ErrAgentNotFound) {
+			return nil, catchAllErr
+		}
+		return nil, err
+	}
+	return catchAll, nil
+}
+
 // GetAllAgents returns all registered local agents
 func (r *Registry) GetAllAgents(ctx context.Context) map[string]*LocalAgent {
 	result := make(map[string]*LocalAgent)
@@ -208,16 +464,24 @@ func (r *Registry) GetSupportedSchemas(ctx context.Context) []string {
 	return schemas
 }
 
-// GenerateAPIKey generates a cryptographically secure API key for an agent
+// defaultAPIKeyLength is the number of random bytes (256 bits) in a generated
+// API key when no length is configured
+const defaultAPIKeyLength = 32
+
+// GenerateAPIKey generates a cryptographically secure API key for an agent,
+// starting with the configured prefix
 func (r *Registry) GenerateAPIKey() (string, error) {
-	// Generate 32 random bytes (256 bits)
-	bytes := make([]byte, 32)
+	length := r.apiKeyLength
+	if length <= 0 {
+		length = defaultAPIKeyLength
+	}
+	bytes := make([]byte, length)
 	if _, err := rand.Read(bytes); err != nil {
 		return "", fmt.Errorf("failed to generate random bytes: %w", err)
 	}
 
 	// Encode as URL-safe base64 (no padding for cleaner keys)
-	return base64.URLEncoding.WithPadding(base64.NoPadding).EncodeToString(bytes), nil
+	return r.apiKeyPrefix + base64.URLEncoding.WithPadding(base64.NoPadding).EncodeToString(bytes), nil
 }
 
 // VerifyAPIKey verifies that the provided API key belongs to the specified agent
@@ -233,18 +497,38 @@ func (r *Registry) VerifyAPIKey(ctx context.Context, agentAddress, apiKey string
 	return subtle.ConstantTimeCompare([]byte(agent.APIKey), []byte(hashedInput)) == 1
 }
 
-// UpdateLastAccess updates the last access timestamp for an agent
-func (r *Registry) UpdateLastAccess(ctx context.Context, agentAddress string) {
-	agent, err := r.getAgentInternal(ctx, agentAddress)
-	if err != nil || agent == nil {
-		return
+// ResolveAPIKey returns the agent the API key belongs to, with its key
+// redacted, or ErrAgentNotFound. The key's hash is deterministic, so the
+// agent is looked up by the stored hash.
+func (r *Registry) ResolveAPIKey(ctx context.Context, apiKey string) (*LocalAgent, error) {
+	if apiKey == "" {
+		return nil, ErrAgentNotFound
 	}
 
-	agent.LastAccess = time.Now().UTC()
-	err = r.storage.UpdateAgent(ctx, agent)
+	agent, err := r.storage.GetAgentByAPIKey(ctx, r.hashAPIKey(apiKey))
+	if errors.Is(err, ErrAgentNotFound) {
+		return nil, ErrAgentNotFound
+	}
 	if err != nil {
+		return nil, fmt.Errorf("failed to look up API key: %w", err)
+	}
+
+	agentCopy := *agent
+	agentCopy.APIKey = ""
+	return &agentCopy, nil
+}
+
+// UpdateLastAccess updates the last access timestamp for an agent. Only
+// the timestamp is written, so a cached copy of the agent can never
+// overwrite changes made by another gateway sharing the storage.
+func (r *Registry) UpdateLastAccess(ctx context.Context, agentAddress string) {
+	agentAddress = types.NormalizeAddress(agentAddress)
+	now := time.Now().UTC()
+	if err := r.storage.UpdateLastAccess(ctx, agentAddress, now); err != nil {
+		r.cache.invalidate(agentAddress)
 		return
 	}
+	r.cache.touch(agentAddress, now)
 }
 
 // RotateAPIKey generates a new API key for an existing agent
@@ -262,7 +546,9 @@ func (r *Registry) RotateAPIKey(ctx context.Context, agentAddress string) (strin
 
 	// Update agent with new key
 	agent.APIKey = r.hashAPIKey(newAPIKey)
+	agent.KeyRotatedAt = time.Now().UTC()
 	err = r.storage.UpdateAgent(ctx, agent)
+	r.cache.invalidate(agent.Address)
 	if err != nil {
 		return "", fmt.Errorf("failed to update agent with new API key: %w", err)
 	}
@@ -299,25 +585,35 @@ func (r *Registry) GetStats() map[string]interface{} {
 			"local_agents": 0,
 			"push_agents":  0,
 			"pull_agents":  0,
+			"kafka_agents": 0,
 		}
 	}
 
 	totalAgents := len(agents)
 	pushAgents := 0
 	pullAgents := 0
+	kafkaAgents := 0
 
 	for _, agent := range agents {
-		if agent.DeliveryMode == "push" {
+		switch agent.DeliveryMode {
+		case "push":
 			pushAgents++
-		} else {
+		case "kafka":
+			kafkaAgents++
+		default:
 			pullAgents++
 		}
 	}
 
+	cacheHits, cacheMisses := r.cache.stats()
+
 	return map[string]interface{}{
 		"local_agents": totalAgents,
 		"push_agents":  pushAgents,
 		"pull_agents":  pullAgents,
+		"kafka_agents": kafkaAgents,
+		"cache_hits":   cacheHits,
+		"cache_misses": cacheMisses,
 	}
 }
 
@@ -388,8 +684,13 @@ func (r *Registry) validateSchemaFormat(schemaStr string) error {
 	return nil
 }
 
-// normalizeAgentAddress processes agent name and constructs full address
+// normalizeAgentAddress processes agent name and constructs full address.
+// An address of a local-only scheme, such as a URN, is taken as it is.
 func (r *Registry) normalizeAgentAddress(agentName string) (string, error) {
+	if scheme := r.addresses.Scheme(agentName); scheme != nil && scheme.Domain(agentName) == "" {
+		return types.NormalizeAddress(agentName), nil
+	}
+
 	// Reject full addresses - only accept agent names
 	if strings.Contains(agentName, "@") {
 		return "", fmt.Errorf("only agent names are allowed, not full addresses. Use '%s' instead of '%s'",
@@ -401,6 +702,10 @@ func (r *Registry) normalizeAgentAddress(agentName string) (string, error) {
 		return "", fmt.Errorf("agent name cannot be empty")
 	}
 
+	if agentName == CatchAllAgentName {
+		return r.catchAllAddress(), nil
+	}
+
 	// Validate agent name format
 	if !isValidAgentName(agentName) {
 		return "", fmt.Errorf("invalid agent name '%s': only letters, numbers, hyphens, underscores, and dots allowed", agentName)
@@ -408,7 +713,19 @@ func (r *Registry) normalizeAgentAddress(agentName string) (string, error) {
 
 	// Construct full address with local domain
 	fullAddress := fmt.Sprintf("%s@%s", agentName, r.localDomain)
-	return fullAddress, nil
+	return types.NormalizeAddress(fullAddress), nil
+}
+
+// catchAllAddress returns the address of the catch-all agent
+func (r *Registry) catchAllAddress() string {
+	return types.NormalizeAddress(CatchAllAgentName + "@" + r.localDomain)
+}
+
+// isLocalAddress reports whether address is in the local domain or of a
+// local-only scheme
+func (r *Registry) isLocalAddress(address string) bool {
+	domain, ok := r.addresses.Domain(address, r.localDomain)
+	return ok && strings.EqualFold(domain, r.localDomain)
 }
 
 // isValidAgentName validates that an agent name follows proper naming conventions
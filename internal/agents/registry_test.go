@@ -19,7 +19,10 @@ package agents
 import (
 	"context"
 	"encoding/base64"
+	"errors"
 	"fmt"
+	"reflect"
+	"strings"
 	"testing"
 	"time"
 
@@ -79,12 +82,22 @@ func (s *inMemoryAgentStore) DeleteAgent(ctx context.Context, agentAddress strin
 func (s *inMemoryAgentStore) GetAgent(ctx context.Context, agentAddress string) (*LocalAgent, error) {
 	agent, exists := s.agents[agentAddress]
 	if !exists {
-		return nil, fmt.Errorf("agent not found: %s", agentAddress)
+		return nil, fmt.Errorf("%w: %s", ErrAgentNotFound, agentAddress)
 	}
 	agentCopy := *agent
 	return &agentCopy, nil
 }
 
+func (s *inMemoryAgentStore) GetAgentByAPIKey(ctx context.Context, hashedKey string) (*LocalAgent, error) {
+	for _, agent := range s.agents {
+		if agent.APIKey == hashedKey {
+			agentCopy := *agent
+			return &agentCopy, nil
+		}
+	}
+	return nil, ErrAgentNotFound
+}
+
 func (s *inMemoryAgentStore) UpdateAgent(ctx context.Context, agent *LocalAgent) error {
 	if agent == nil {
 		return fmt.Errorf("agent cannot be nil")
@@ -98,6 +111,18 @@ func (s *inMemoryAgentStore) UpdateAgent(ctx context.Context, agent *LocalAgent)
 	return nil
 }
 
+func (s *inMemoryAgentStore) UpdateLastAccess(ctx context.Context, agentAddress string, at time.Time) error {
+	agent, exists := s.agents[agentAddress]
+	if !exists {
+		return fmt.Errorf("agent not found: %s", agentAddress)
+	}
+
+	agentCopy := *agent
+	agentCopy.LastAccess = at
+	s.agents[agentAddress] = &agentCopy
+	return nil
+}
+
 func (s *inMemoryAgentStore) ListAgents(ctx context.Context) ([]*LocalAgent, error) {
 	var list []*LocalAgent
 	for _, agent := range s.agents {
@@ -164,6 +189,56 @@ func TestGenerateAPIKey(t *testing.T) {
 	}
 }
 
+func TestGenerateAPIKey_PrefixAndLength(t *testing.T) {
+	store := newInMemoryAgentStore()
+	ctx := context.Background()
+
+	// An agent registered before a prefix was configured
+	legacy := &LocalAgent{Address: "legacy", DeliveryMode: "pull"}
+	if err := NewRegistry(RegistryConfig{LocalDomain: "localhost", APIKeySalt: "test-salt"}, store).RegisterAgent(ctx, legacy); err != nil {
+		t.Fatalf("Failed to register agent: %v", err)
+	}
+
+	registry := NewRegistry(RegistryConfig{
+		LocalDomain:  "localhost",
+		APIKeySalt:   "test-salt",
+		APIKeyPrefix: "amtp_",
+		APIKeyLength: 48,
+	}, store)
+
+	key, err := registry.GenerateAPIKey()
+	if err != nil {
+		t.Fatalf("Failed to generate API key: %v", err)
+	}
+	if !strings.HasPrefix(key, "amtp_") {
+		t.Fatalf("Expected key to start with amtp_, got %s", key)
+	}
+	decoded, err := base64.URLEncoding.WithPadding(base64.NoPadding).DecodeString(strings.TrimPrefix(key, "amtp_"))
+	if err != nil {
+		t.Fatalf("Key material is not valid base64: %v", err)
+	}
+	if len(decoded) != 48 {
+		t.Errorf("Expected 48 random bytes, got %d", len(decoded))
+	}
+
+	agent := &LocalAgent{Address: "prefixed", DeliveryMode: "pull"}
+	if err := registry.RegisterAgent(ctx, agent); err != nil {
+		t.Fatalf("Failed to register agent: %v", err)
+	}
+	if !strings.HasPrefix(agent.APIKey, "amtp_") {
+		t.Errorf("Expected registered key to start with amtp_, got %s", agent.APIKey)
+	}
+	if !registry.VerifyAPIKey(ctx, agent.Address, agent.APIKey) {
+		t.Error("Prefixed API key verification failed")
+	}
+	if registry.VerifyAPIKey(ctx, agent.Address, strings.TrimPrefix(agent.APIKey, "amtp_")) {
+		t.Error("API key without its prefix should not verify")
+	}
+	if !registry.VerifyAPIKey(ctx, legacy.Address, legacy.APIKey) {
+		t.Error("Keys issued before the prefix was configured should still verify")
+	}
+}
+
 // Test agent API key verification
 func TestVerifyAPIKey(t *testing.T) {
 	registry := createTestRegistry()
@@ -223,6 +298,37 @@ func TestVerifyAPIKey(t *testing.T) {
 	}
 }
 
+func TestResolveAPIKey(t *testing.T) {
+	registry := createTestRegistry()
+	ctx := context.Background()
+
+	first := &LocalAgent{Address: "first", DeliveryMode: "pull"}
+	second := &LocalAgent{Address: "second", DeliveryMode: "pull"}
+	for _, agent := range []*LocalAgent{first, second} {
+		if err := registry.RegisterAgent(ctx, agent); err != nil {
+			t.Fatalf("Failed to register agent: %v", err)
+		}
+	}
+
+	agent, err := registry.ResolveAPIKey(ctx, second.APIKey)
+	if err != nil {
+		t.Fatalf("Failed to resolve API key: %v", err)
+	}
+	if agent.Address != second.Address {
+		t.Errorf("Expected %s, got %s", second.Address, agent.Address)
+	}
+	if agent.APIKey != "" {
+		t.Error("Expected the resolved agent's API key to be redacted")
+	}
+
+	if _, err := registry.ResolveAPIKey(ctx, "unknown-key"); !errors.Is(err, ErrAgentNotFound) {
+		t.Errorf("Expected ErrAgentNotFound, got %v", err)
+	}
+	if _, err := registry.ResolveAPIKey(ctx, ""); !errors.Is(err, ErrAgentNotFound) {
+		t.Errorf("Expected ErrAgentNotFound for an empty key, got %v", err)
+	}
+}
+
 // Test agent API key rotation
 func TestRotateAPIKey(t *testing.T) {
 	registry := createTestRegistry()
@@ -266,6 +372,13 @@ func TestRotateAPIKey(t *testing.T) {
 		t.Error("New API key should work after rotation")
 	}
 
+	// The rotation is recorded, so credentials derived from the old key
+	// can be refused
+	rotated, err := registry.GetAgent(ctx, agent.Address)
+	if err != nil || rotated.KeyRotatedAt.IsZero() || rotated.KeyRotatedAt.Before(rotated.CreatedAt) {
+		t.Errorf("Expected the rotation time to be recorded, got %+v (%v)", rotated, err)
+	}
+
 	// Test rotation for non-existent agent
 	_, err = registry.RotateAPIKey(ctx, "nonexistent@localhost")
 	if err == nil {
@@ -365,6 +478,118 @@ func TestRegisterAgentAPIKeyGeneration(t *testing.T) {
 			},
 			expectError: true,
 		},
+		{
+			name: "valid agent - push targets only",
+			agent: &LocalAgent{
+				Address:      "test5",
+				DeliveryMode: "push",
+				PushTargets:  []string{"http://example.com/a", "http://example.com/b"},
+				PushPolicy:   PushPolicyAny,
+			},
+			expectError: false,
+		},
+		{
+			name: "invalid agent - unknown push policy",
+			agent: &LocalAgent{
+				Address:      "test6",
+				DeliveryMode: "push",
+				PushTarget:   "http://example.com/webhook",
+				PushPolicy:   "majority",
+			},
+			expectError: true,
+		},
+		{
+			name: "valid agent - balanced with weights",
+			agent: &LocalAgent{
+				Address:           "test7",
+				DeliveryMode:      "push",
+				PushTarget:        "http://example.com/a",
+				PushTargets:       []string{"http://example.com/b"},
+				PushStrategy:      PushStrategyBalanced,
+				PushTargetWeights: map[string]int{"http://example.com/a": 3},
+			},
+			expectError: false,
+		},
+		{
+			name: "invalid agent - unknown push strategy",
+			agent: &LocalAgent{
+				Address:      "test8",
+				DeliveryMode: "push",
+				PushTarget:   "http://example.com/webhook",
+				PushStrategy: "random",
+			},
+			expectError: true,
+		},
+		{
+			name: "invalid agent - balanced pull agent",
+			agent: &LocalAgent{
+				Address:      "test9",
+				DeliveryMode: "pull",
+				PushStrategy: PushStrategyBalanced,
+			},
+			expectError: true,
+		},
+		{
+			name: "valid agent - concurrency cap",
+			agent: &LocalAgent{
+				Address:                 "test-capped",
+				DeliveryMode:            "push",
+				PushTarget:              "http://example.com/webhook",
+				MaxConcurrentDeliveries: 4,
+			},
+			expectError: false,
+		},
+		{
+			name: "invalid agent - negative concurrency cap",
+			agent: &LocalAgent{
+				Address:                 "test-negative-cap",
+				DeliveryMode:            "push",
+				PushTarget:              "http://example.com/webhook",
+				MaxConcurrentDeliveries: -1,
+			},
+			expectError: true,
+		},
+		{
+			name: "invalid agent - concurrency cap on pull agent",
+			agent: &LocalAgent{
+				Address:                 "test-pull-cap",
+				DeliveryMode:            "pull",
+				MaxConcurrentDeliveries: 2,
+			},
+			expectError: true,
+		},
+		{
+			name: "invalid agent - weights without balanced strategy",
+			agent: &LocalAgent{
+				Address:           "test10",
+				DeliveryMode:      "push",
+				PushTarget:        "http://example.com/webhook",
+				PushTargetWeights: map[string]int{"http://example.com/webhook": 2},
+			},
+			expectError: true,
+		},
+		{
+			name: "invalid agent - weight for unknown target",
+			agent: &LocalAgent{
+				Address:           "test11",
+				DeliveryMode:      "push",
+				PushTarget:        "http://example.com/webhook",
+				PushStrategy:      PushStrategyBalanced,
+				PushTargetWeights: map[string]int{"http://example.com/other": 2},
+			},
+			expectError: true,
+		},
+		{
+			name: "invalid agent - non-positive weight",
+			agent: &LocalAgent{
+				Address:           "test12",
+				DeliveryMode:      "push",
+				PushTarget:        "http://example.com/webhook",
+				PushStrategy:      PushStrategyBalanced,
+				PushTargetWeights: map[string]int{"http://example.com/webhook": 0},
+			},
+			expectError: true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -415,6 +640,21 @@ func TestRegisterAgentAPIKeyGeneration(t *testing.T) {
 	}
 }
 
+func TestAllPushTargets(t *testing.T) {
+	agent := &LocalAgent{
+		PushTarget:  "http://example.com/primary",
+		PushTargets: []string{"http://example.com/audit", "", "http://example.com/primary"},
+	}
+
+	targets := agent.AllPushTargets()
+	if len(targets) != 2 {
+		t.Fatalf("Expected 2 targets, got %d: %v", len(targets), targets)
+	}
+	if targets[0] != "http://example.com/primary" || targets[1] != "http://example.com/audit" {
+		t.Errorf("Unexpected targets order: %v", targets)
+	}
+}
+
 // Test registry statistics
 func TestGetStats(t *testing.T) {
 	registry := createTestRegistry()
@@ -576,6 +816,179 @@ func TestUnregisterAgent(t *testing.T) {
 	}
 }
 
+// Test that agent addresses are matched case-insensitively
+func TestRegisterAgent_MixedCaseName(t *testing.T) {
+	registry := createTestRegistry()
+	ctx := context.Background()
+
+	agent := &LocalAgent{Address: "SalesBot", DeliveryMode: "pull"}
+	if err := registry.RegisterAgent(ctx, agent); err != nil {
+		t.Fatalf("Failed to register agent: %v", err)
+	}
+	if agent.Address != "salesbot@localhost" {
+		t.Errorf("Expected address salesbot@localhost, got %s", agent.Address)
+	}
+
+	for _, address := range []string{"salesbot@localhost", "SalesBot@LocalHost"} {
+		if _, err := registry.GetAgent(ctx, address); err != nil {
+			t.Errorf("Expected %s to find the agent, got %v", address, err)
+		}
+	}
+
+	if err := registry.UnregisterAgent(ctx, "SALESBOT"); err != nil {
+		t.Fatalf("Failed to unregister agent: %v", err)
+	}
+	if _, err := registry.GetAgent(ctx, "salesbot@localhost"); err == nil {
+		t.Error("Agent should not exist after unregistration")
+	}
+}
+
+// Test catch-all agent registration and resolution
+func TestResolveAgent_CatchAll(t *testing.T) {
+	registry := createTestRegistry()
+	ctx := context.Background()
+
+	if err := registry.RegisterAgent(ctx, &LocalAgent{Address: "known", DeliveryMode: "pull"}); err != nil {
+		t.Fatalf("Failed to register agent: %v", err)
+	}
+
+	// Without a catch-all agent, unknown addresses are not found
+	_, err := registry.ResolveAgent(ctx, "unknown@localhost")
+	if !errors.Is(err, ErrAgentNotFound) {
+		t.Errorf("Expected ErrAgentNotFound, got %v", err)
+	}
+
+	// Catch-all agents cannot use pull delivery
+	err = registry.RegisterAgent(ctx, &LocalAgent{Address: CatchAllAgentName, DeliveryMode: "pull"})
+	if err == nil || !strings.Contains(err.Error(), "catch-all agent must use push delivery mode") {
+		t.Errorf("Expected pull catch-all to be rejected, got %v", err)
+	}
+
+	catchAll := &LocalAgent{
+		Address:      CatchAllAgentName,
+		DeliveryMode: "push",
+		PushTarget:   "http://example.com/catch-all",
+	}
+	if err := registry.RegisterAgent(ctx, catchAll); err != nil {
+		t.Fatalf("Failed to register catch-all agent: %v", err)
+	}
+	if catchAll.Address != "*@localhost" {
+		t.Errorf("Expected catch-all address *@localhost, got %s", catchAll.Address)
+	}
+
+	tests := []struct {
+		address  string
+		expected string
+	}{
+		{"known@localhost", "known@localhost"},
+		{"unknown@localhost", "*@localhost"},
+		{"*@localhost", "*@localhost"},
+	}
+	for _, tt := range tests {
+		agent, err := registry.ResolveAgent(ctx, tt.address)
+		if err != nil {
+			t.Errorf("ResolveAgent(%s) failed: %v", tt.address, err)
+			continue
+		}
+		if agent.Address != tt.expected {
+			t.Errorf("ResolveAgent(%s) = %s, want %s", tt.address, agent.Address, tt.expected)
+		}
+		if agent.APIKey != "" {
+			t.Errorf("ResolveAgent(%s) should redact the API key", tt.address)
+		}
+	}
+
+	// Remote addresses never fall back to the catch-all agent
+	for _, address := range []string{"unknown@example.com", "unknown@sub.localhost", "localhost"} {
+		if _, err := registry.ResolveAgent(ctx, address); !errors.Is(err, ErrAgentNotFound) {
+			t.Errorf("Expected ErrAgentNotFound for %s, got %v", address, err)
+		}
+	}
+
+	// Unregistering the catch-all agent restores not-found behavior
+	if err := registry.UnregisterAgent(ctx, CatchAllAgentName); err != nil {
+		t.Fatalf("Failed to unregister catch-all agent: %v", err)
+	}
+	if _, err := registry.ResolveAgent(ctx, "unknown@localhost"); !errors.Is(err, ErrAgentNotFound) {
+		t.Errorf("Expected ErrAgentNotFound after unregistering, got %v", err)
+	}
+}
+
+func TestRegisterAgent_AllowedSenders(t *testing.T) {
+	registry := createTestRegistry()
+	ctx := context.Background()
+
+	agent := &LocalAgent{
+		Address:        "orders",
+		DeliveryMode:   "pull",
+		AllowedSenders: []string{" Billing@Partner.com ", "example.org", "*.trusted.net", ""},
+	}
+	if err := registry.RegisterAgent(ctx, agent); err != nil {
+		t.Fatalf("Failed to register agent: %v", err)
+	}
+	want := []string{"billing@partner.com", "example.org", "*.trusted.net"}
+	if !reflect.DeepEqual(agent.AllowedSenders, want) {
+		t.Errorf("Expected allowed senders %v, got %v", want, agent.AllowedSenders)
+	}
+
+	tests := []struct {
+		sender  string
+		allowed bool
+	}{
+		{"billing@partner.com", true},
+		{"Billing@PARTNER.com", true},
+		{"sales@partner.com", false},
+		{"anyone@example.org", true},
+		{"anyone@sub.example.org", false},
+		{"bot@eu.trusted.net", true},
+		{"bot@trusted.net", false},
+		{"bot@a.eu.trusted.net", false},
+	}
+	for _, tt := range tests {
+		if got := agent.AllowsSender(tt.sender); got != tt.allowed {
+			t.Errorf("AllowsSender(%s) = %v, want %v", tt.sender, got, tt.allowed)
+		}
+	}
+
+	if !(&LocalAgent{}).AllowsSender("anyone@anywhere.com") {
+		t.Error("Expected an agent without allowed senders to accept every sender")
+	}
+
+	for _, invalid := range []string{"@partner.com", "bot@*.trusted.net", "not a domain", "*.", "partner..com"} {
+		err := registry.RegisterAgent(ctx, &LocalAgent{
+			Address:        "invalid",
+			DeliveryMode:   "pull",
+			AllowedSenders: []string{invalid},
+		})
+		if err == nil || !strings.Contains(err.Error(), "invalid allowed senders") {
+			t.Errorf("Expected %q to be rejected, got %v", invalid, err)
+		}
+	}
+}
+
+// unavailableAgentStore fails every agent lookup as storage would in an outage
+type unavailableAgentStore struct {
+	*inMemoryAgentStore
+}
+
+func (s unavailableAgentStore) GetAgent(ctx context.Context, agentAddress string) (*LocalAgent, error) {
+	return nil, fmt.Errorf("connection reset")
+}
+
+func TestResolveAgent_StorageError(t *testing.T) {
+	registry := NewRegistry(RegistryConfig{
+		LocalDomain:   "localhost",
+		SchemaManager: NewMockSchemaManager(),
+		APIKeySalt:    "test-salt",
+	}, unavailableAgentStore{newInMemoryAgentStore()})
+
+	// A storage failure must not be mistaken for an unknown recipient
+	_, err := registry.ResolveAgent(context.Background(), "agent@localhost")
+	if err == nil || errors.Is(err, ErrAgentNotFound) {
+		t.Errorf("Expected a storage error, got %v", err)
+	}
+}
+
 // Test getting all agents
 func TestGetAllAgents(t *testing.T) {
 	registry := createTestRegistry()
@@ -670,3 +1083,78 @@ func TestGetSupportedSchemas(t *testing.T) {
 		}
 	}
 }
+
+func TestRegisterAgent_URNAddress(t *testing.T) {
+	ctx := context.Background()
+
+	registry := createTestRegistry()
+	if err := registry.RegisterAgent(ctx, &LocalAgent{Address: "urn:agent:1234", DeliveryMode: "pull"}); err == nil {
+		t.Error("Expected a URN to be refused without the urn address scheme")
+	}
+
+	registry = NewRegistry(RegistryConfig{
+		LocalDomain:    "localhost",
+		SchemaManager:  NewMockSchemaManager(),
+		APIKeySalt:     "test-salt",
+		AddressSchemes: types.AddressSchemes{types.EmailScheme, types.URNScheme},
+	}, newInMemoryAgentStore())
+
+	agent := &LocalAgent{Address: "URN:Agent:1234", DeliveryMode: "pull"}
+	if err := registry.RegisterAgent(ctx, agent); err != nil {
+		t.Fatalf("Failed to register URN agent: %v", err)
+	}
+	if agent.Address != "urn:agent:1234" {
+		t.Errorf("Expected the URN to be normalized, got %s", agent.Address)
+	}
+	if _, err := registry.ResolveAgent(ctx, "urn:agent:1234"); err != nil {
+		t.Errorf("Expected the URN agent to resolve, got %v", err)
+	}
+	if !registry.isLocalAddress("urn:agent:5678") {
+		t.Error("Expected URN addresses to be local")
+	}
+
+	// Names still get the local domain
+	named := &LocalAgent{Address: "sales", DeliveryMode: "pull"}
+	if err := registry.RegisterAgent(ctx, named); err != nil || named.Address != "sales@localhost" {
+		t.Errorf("Expected sales@localhost, got %s (%v)", named.Address, err)
+	}
+}
+
+func TestRegisterAgent_Kafka(t *testing.T) {
+	ctx := context.Background()
+
+	registry := createTestRegistry()
+	err := registry.RegisterAgent(ctx, &LocalAgent{Address: "billing", DeliveryMode: "kafka", PushTarget: "billing-events"})
+	if err == nil || !strings.Contains(err.Error(), "not enabled") {
+		t.Errorf("Expected kafka mode to be refused when not enabled, got %v", err)
+	}
+
+	registry = NewRegistry(RegistryConfig{
+		LocalDomain:   "localhost",
+		SchemaManager: NewMockSchemaManager(),
+		APIKeySalt:    "test-salt",
+		KafkaEnabled:  true,
+	}, newInMemoryAgentStore())
+
+	if err := registry.RegisterAgent(ctx, &LocalAgent{Address: "billing", DeliveryMode: "kafka", PushTarget: "billing-events"}); err != nil {
+		t.Fatalf("Failed to register kafka agent: %v", err)
+	}
+	if stats := registry.GetStats(); stats["kafka_agents"] != 1 {
+		t.Errorf("Expected one kafka agent in stats, got %v", stats["kafka_agents"])
+	}
+
+	tests := []struct {
+		name  string
+		agent *LocalAgent
+	}{
+		{"missing topic", &LocalAgent{Address: "a", DeliveryMode: "kafka"}},
+		{"invalid topic", &LocalAgent{Address: "b", DeliveryMode: "kafka", PushTarget: "http://webhook:8080"}},
+		{"dot topic", &LocalAgent{Address: "c", DeliveryMode: "kafka", PushTarget: ".."}},
+		{"several topics", &LocalAgent{Address: "d", DeliveryMode: "kafka", PushTarget: "one", PushTargets: []string{"two"}}},
+	}
+	for _, tt := range tests {
+		if err := registry.RegisterAgent(ctx, tt.agent); err == nil {
+			t.Errorf("%s: expected registration to fail", tt.name)
+		}
+	}
+}
@@ -17,10 +17,12 @@
 package config
 
 import (
+	"crypto/tls"
 	"encoding/json"
 	"fmt"
 	"log"
 	"net"
+	"net/url"
 	"os"
 	"path/filepath"
 	"regexp"
@@ -30,37 +32,151 @@ import (
 
 	"gopkg.in/yaml.v3"
 
+	"github.com/amtp-protocol/agentry/internal/discovery"
+	"github.com/amtp-protocol/agentry/internal/features"
 	"github.com/amtp-protocol/agentry/internal/schema"
+	"github.com/amtp-protocol/agentry/internal/types"
+	"github.com/amtp-protocol/agentry/pkg/uuid"
 )
 
 // Config holds the application configuration
 type Config struct {
-	Server  ServerConfig          `yaml:"server"`
-	TLS     TLSConfig             `yaml:"tls"`
-	DNS     DNSConfig             `yaml:"dns"`
-	Message MessageConfig         `yaml:"message"`
-	Auth    AuthConfig            `yaml:"auth"`
-	Logging LoggingConfig         `yaml:"logging"`
-	Storage StorageConfig         `yaml:"storage,omitempty"`
-	Metrics *MetricsConfig        `yaml:"metrics,omitempty"`
-	Schema  *schema.ManagerConfig `yaml:"schema,omitempty"`
+	Server   ServerConfig          `yaml:"server"`
+	TLS      TLSConfig             `yaml:"tls"`
+	DNS      DNSConfig             `yaml:"dns"`
+	Message  MessageConfig         `yaml:"message"`
+	Auth     AuthConfig            `yaml:"auth"`
+	Logging  LoggingConfig         `yaml:"logging"`
+	Storage  StorageConfig         `yaml:"storage,omitempty"`
+	Agents   AgentsConfig          `yaml:"agents"`
+	Delivery DeliveryConfig        `yaml:"delivery"`
+	SMTP     SMTPBridgeConfig      `yaml:"smtp_bridge"`
+	Metrics  *MetricsConfig        `yaml:"metrics,omitempty"`
+	Schema   *schema.ManagerConfig `yaml:"schema,omitempty"`
+
+	// Features switches new behaviors on by flag name; see the features
+	// package. Unlike the rest of the configuration they are reloaded on SIGHUP.
+	Features map[string]bool `yaml:"features,omitempty"`
+
+	// Environment is the AGENTRY_ENV whose override file was loaded, and
+	// Files the configuration files in the order they were layered
+	Environment string   `yaml:"-"`
+	Files       []string `yaml:"-"`
 }
 
 // ServerConfig holds HTTP server configuration
 type ServerConfig struct {
-	Address      string        `yaml:"address"`
+	Address      string        `yaml:"address"` // host:port, or unix:/path/to.sock for a Unix domain socket
 	Domain       string        `yaml:"domain"`
 	ReadTimeout  time.Duration `yaml:"read_timeout"`
 	WriteTimeout time.Duration `yaml:"write_timeout"`
 	IdleTimeout  time.Duration `yaml:"idle_timeout"`
+
+	RateLimit RateLimitConfig `yaml:"rate_limit"`
+
+	// TrustedProxies lists the proxy IPs and CIDR ranges whose
+	// X-Forwarded-For and X-Real-IP headers name the client. Other peers are
+	// identified by their own address, so clients cannot pick the IP that
+	// rate limits and logs see. Empty trusts no proxy.
+	TrustedProxies []string `yaml:"trusted_proxies"`
+
+	// MaxConcurrentRequests caps the requests handled at once across all
+	// clients; further requests get 503 until one finishes. Health and
+	// readiness probes are not counted. 0 disables the cap.
+	MaxConcurrentRequests int `yaml:"max_concurrent_requests"`
+
+	// EventStreamMaxSubscribers caps the admins watching GET /v1/admin/events
+	// at once. 0 disables the event stream.
+	EventStreamMaxSubscribers int `yaml:"event_stream_max_subscribers"`
+}
+
+// RateLimitConfig holds per-client request rate limiting. Each client IP has
+// a token bucket holding up to Burst requests, refilled at RequestsPerMinute.
+type RateLimitConfig struct {
+	RequestsPerMinute int `yaml:"requests_per_minute"` // 0 disables rate limiting
+	Burst             int `yaml:"burst"`               // Defaults to RequestsPerMinute
+}
+
+// unixAddressPrefix marks a server address as a Unix domain socket path
+const unixAddressPrefix = "unix:"
+
+// UnixSocketPath returns the socket path if address is of the form
+// "unix:/path/to.sock"
+func UnixSocketPath(address string) (string, bool) {
+	if !strings.HasPrefix(address, unixAddressPrefix) {
+		return "", false
+	}
+	return strings.TrimPrefix(address, unixAddressPrefix), true
 }
 
 // TLSConfig holds TLS configuration
 type TLSConfig struct {
-	Enabled    bool   `yaml:"enabled"`
-	CertFile   string `yaml:"cert_file"`
-	KeyFile    string `yaml:"key_file"`
-	MinVersion string `yaml:"min_version"`
+	Enabled      bool   `yaml:"enabled"`
+	CertFile     string `yaml:"cert_file"`
+	KeyFile      string `yaml:"key_file"`
+	MinVersion   string `yaml:"min_version"`
+	ClientCAFile string `yaml:"client_ca_file"` // CA bundle for verifying optional client certificates (mTLS)
+
+	// CipherSuites restricts the TLS 1.2 cipher suites offered, by their
+	// standard names (e.g. TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256). Empty
+	// uses Go's defaults. TLS 1.3 suites are not configurable.
+	CipherSuites []string `yaml:"cipher_suites"`
+}
+
+// Minimum TLS versions
+const (
+	TLSVersion12 = "1.2"
+	TLSVersion13 = "1.3"
+)
+
+// MinTLSVersion returns the crypto/tls version for MinVersion, which
+// defaults to TLS 1.2
+func (t TLSConfig) MinTLSVersion() (uint16, error) {
+	switch t.MinVersion {
+	case "", TLSVersion12:
+		return tls.VersionTLS12, nil
+	case TLSVersion13:
+		return tls.VersionTLS13, nil
+	default:
+		return 0, fmt.Errorf("unsupported TLS min version %q, must be %s or %s", t.MinVersion, TLSVersion12, TLSVersion13)
+	}
+}
+
+// CipherSuiteIDs returns the crypto/tls IDs of the configured cipher suites,
+// or nil when none are configured. Insecure suites and suites that cannot be
+// used with TLS 1.2 are rejected.
+func (t TLSConfig) CipherSuiteIDs() ([]uint16, error) {
+	if len(t.CipherSuites) == 0 {
+		return nil, nil
+	}
+
+	known := make(map[string]*tls.CipherSuite)
+	for _, suite := range tls.CipherSuites() {
+		known[suite.Name] = suite
+	}
+
+	ids := make([]uint16, 0, len(t.CipherSuites))
+	for _, name := range t.CipherSuites {
+		name = strings.TrimSpace(name)
+		suite, ok := known[name]
+		if !ok {
+			for _, insecure := range tls.InsecureCipherSuites() {
+				if insecure.Name == name {
+					return nil, fmt.Errorf("cipher suite %s is insecure", name)
+				}
+			}
+			return nil, fmt.Errorf("unknown cipher suite %q", name)
+		}
+		tls12 := false
+		for _, version := range suite.SupportedVersions {
+			tls12 = tls12 || version == tls.VersionTLS12
+		}
+		if !tls12 {
+			return nil, fmt.Errorf("cipher suite %s is TLS 1.3 only; TLS 1.3 suites are not configurable", name)
+		}
+		ids = append(ids, suite.ID)
+	}
+	return ids, nil
 }
 
 // DNSConfig holds DNS discovery configuration
@@ -71,15 +187,80 @@ type DNSConfig struct {
 	MockMode    bool              `yaml:"mock_mode"`
 	MockRecords map[string]string `yaml:"mock_records"`
 	AllowHTTP   bool              `yaml:"allow_http"`
+	// DiscoveryOverride honors the X-AMTP-Discovery-Override request header,
+	// letting integration tests point a domain at a gateway per request.
+	// Test only; requires mock mode.
+	DiscoveryOverride bool `yaml:"discovery_override"`
 }
 
 // MessageConfig holds message processing configuration
 type MessageConfig struct {
-	MaxSize           int64         `yaml:"max_size"`
-	IdempotencyTTL    time.Duration `yaml:"idempotency_ttl"`
-	ValidationEnabled bool          `yaml:"validation_enabled"`
+	MaxSize                 int64         `yaml:"max_size"`
+	IdempotencyTTL          time.Duration `yaml:"idempotency_ttl"`
+	ValidationEnabled       bool          `yaml:"validation_enabled"`
+	MaxAttachments          int           `yaml:"max_attachments"`            // 0 means unlimited
+	MaxTotalAttachmentBytes int64         `yaml:"max_total_attachment_bytes"` // 0 means unlimited
+	MaxReplyDepth           int           `yaml:"max_reply_depth"`            // longest in_reply_to chain accepted; 0 means unlimited
+	CheckTimestamps         bool          `yaml:"check_timestamps"`           // reject relayed messages whose timestamp is further than MaxTimestampSkew from the gateway clock
+	MaxTimestampSkew        time.Duration `yaml:"max_timestamp_skew"`         // allowed timestamp difference either way with CheckTimestamps
+	MaxPayloadDepth         int           `yaml:"max_payload_depth"`          // deepest payload nesting accepted; 0 means unlimited
+	MaxPayloadElements      int           `yaml:"max_payload_elements"`       // most keys or elements in one payload object or array; 0 means unlimited
+	RequireIdempotencyKey   bool          `yaml:"require_idempotency_key"`    // reject sends without a client-supplied key
+	SchemaAutoDetect        bool          `yaml:"schema_auto_detect"`         // match schemaless payloads against registered schemas
+	BounceReports           bool          `yaml:"bounce_reports"`             // report failed recipients to the sender from postmaster@domain
+	// SchemaUnavailable decides what happens to a message that names a schema
+	// when no schema manager is configured: lenient accepts it unvalidated
+	// with a warning, strict rejects it
+	SchemaUnavailable string `yaml:"schema_unavailable"`
+
+	// IDStrategy decides how the gateway creates message IDs: uuidv7, ulid,
+	// or prefixed, which puts IDPrefix (a shard or region name) in front of
+	// a UUIDv7
+	IDStrategy string `yaml:"id_strategy"`
+	IDPrefix   string `yaml:"id_prefix"`
+
+	// AddressSchemes lists the agent address forms accepted besides the
+	// email-style local@domain, which is always accepted: urn admits opaque
+	// IDs such as urn:agent:1234 for local agents
+	AddressSchemes []string `yaml:"address_schemes"`
+
+	// StoreRawRequest keeps the body and RawRequestHeaders of every accepted
+	// send request next to its message, for reproducing client bugs. Bodies
+	// are cut at RawRequestMaxSize bytes, and the logging redaction rules
+	// are applied before anything is stored.
+	StoreRawRequest   bool     `yaml:"store_raw_request"`
+	RawRequestMaxSize int64    `yaml:"raw_request_max_size"`
+	RawRequestHeaders []string `yaml:"raw_request_headers"`
+
+	// ArchiveAddress receives a copy of every message, delivered like to any
+	// other recipient but hidden from senders, for compliance archiving
+	ArchiveAddress string `yaml:"archive_address"`
+
+	AcceptHook AcceptHookConfig `yaml:"accept_hook"`
 }
 
+// Schema unavailable modes
+const (
+	SchemaUnavailableLenient = "lenient"
+	SchemaUnavailableStrict  = "strict"
+)
+
+// AcceptHookConfig holds the optional pre-accept policy webhook. Before a
+// message is processed it is POSTed to URL, which allows, denies or modifies
+// it. OnFailure decides what happens when the hook times out, cannot be
+// reached or answers with something other than a verdict.
+type AcceptHookConfig struct {
+	URL       string        `yaml:"url"`        // Empty disables the hook
+	Timeout   time.Duration `yaml:"timeout"`    // Per message
+	OnFailure string        `yaml:"on_failure"` // open (accept) or closed (reject)
+}
+
+// Accept hook failure modes
+const (
+	AcceptHookFailOpen   = "open"
+	AcceptHookFailClosed = "closed"
+)
+
 // AuthConfig holds authentication configuration
 type AuthConfig struct {
 	RequireAuth       bool     `yaml:"require_auth"`
@@ -88,6 +269,50 @@ type AuthConfig struct {
 	AdminKeyFile      string   `yaml:"admin_key_file"`       // Path to admin API key file
 	AdminAPIKeyHeader string   `yaml:"admin_api_key_header"` // Header for admin API key
 	APIKeySalt        string   `yaml:"api_key_salt"`         // Salt for API key hashing
+	APIKeyPrefix      string   `yaml:"api_key_prefix"`       // Prepended to generated agent API keys, e.g. "amtp_"
+	APIKeyLength      int      `yaml:"api_key_length"`       // Random bytes in generated agent API keys
+	// EnforceSenderDomain rejects sends whose sender domain differs from the
+	// client's verified domain, when authentication provides one
+	EnforceSenderDomain bool `yaml:"enforce_sender_domain"`
+	// SignatureVerification checks inbound message signatures against the
+	// sender domain's published key: off, optional (verify signed messages)
+	// or required (also reject unsigned messages from other domains)
+	SignatureVerification string `yaml:"signature_verification"`
+	// SigningKeyFile holds the PEM private key relayed messages from the local
+	// domain are signed with; its public key must be published in DNS under
+	// SigningKeyID (the "default" record when empty)
+	SigningKeyFile string `yaml:"signing_key_file"`
+	SigningKeyID   string `yaml:"signing_key_id"`
+
+	Replay ReplayConfig `yaml:"replay"`
+
+	InboxTokens InboxTokenConfig `yaml:"inbox_tokens"`
+}
+
+// InboxTokenConfig enables minting short-lived tokens that grant access to
+// one agent's inbox in place of its API key. Tokens are signed with the secret
+// in KeyFile, at least 32 bytes; without it no tokens are minted or accepted.
+type InboxTokenConfig struct {
+	KeyFile    string        `yaml:"key_file"`
+	DefaultTTL time.Duration `yaml:"default_ttl"` // Lifetime of tokens minted without one
+	MaxTTL     time.Duration `yaml:"max_ttl"`     // Longest lifetime a token may be minted with
+}
+
+// Signature verification modes
+const (
+	SignatureVerificationOff      = "off"
+	SignatureVerificationOptional = "optional"
+	SignatureVerificationRequired = "required"
+)
+
+// ReplayConfig guards signed inbound requests against replay. A request
+// carrying X-AMTP-Timestamp and X-AMTP-Nonce is rejected when the timestamp
+// is further than MaxSkew from the gateway's clock or the nonce was already
+// used within that window.
+type ReplayConfig struct {
+	Enabled        bool          `yaml:"enabled"`
+	MaxSkew        time.Duration `yaml:"max_skew"`         // Allowed clock difference either way
+	NonceCacheSize int           `yaml:"nonce_cache_size"` // Most nonces remembered; the oldest are dropped first
 }
 
 // StorageConfig holds storage configuration
@@ -99,28 +324,224 @@ type StorageConfig struct {
 		MaxConnections   int    `yaml:"max_connections"`
 		MaxIdleTime      int    `yaml:"max_idle_time"`
 	} `yaml:"database,omitempty"`
+	Capacity StorageCapacityConfig `yaml:"capacity"`
+	// CompressPayloads gzips large message payloads and attachment metadata
+	// in database storage. Rows written either way can be read, so it can be
+	// turned on or off at any time.
+	CompressPayloads bool `yaml:"compress_payloads"`
+	// ReconcileInterval is how often message statuses are recomputed from
+	// their recipient statuses; 0 leaves reconciliation to the admin API
+	ReconcileInterval time.Duration            `yaml:"reconcile_interval"`
+	WriteBuffer       StorageWriteBufferConfig `yaml:"write_buffer"`
+}
+
+// StorageWriteBufferConfig holds the opt-in write buffer. While storage
+// rejects writes, sent messages are held in memory and reported as queued,
+// then stored and delivered once storage recovers. Buffered messages are
+// lost if the gateway stops before then.
+type StorageWriteBufferConfig struct {
+	Enabled       bool          `yaml:"enabled"`
+	MaxMessages   int           `yaml:"max_messages"`   // further messages are rejected while this many are buffered
+	FlushInterval time.Duration `yaml:"flush_interval"` // how often storing buffered messages is retried
+}
+
+// StorageCapacityConfig holds storage capacity monitoring configuration.
+// Exceeding any high watermark reports the gateway as degraded on /ready; a
+// zero watermark is not checked.
+type StorageCapacityConfig struct {
+	CheckInterval        time.Duration `yaml:"check_interval"`
+	MaxMessages          int64         `yaml:"max_messages"`
+	MaxInboxMessages     int64         `yaml:"max_inbox_messages"`
+	MaxUnacknowledgedAge time.Duration `yaml:"max_unacknowledged_age"`
+}
+
+// DeliveryConfig holds outbound delivery configuration. Connection failures
+// and slow responses are bounded separately so a webhook that is still
+// working is not treated like a dead one.
+type DeliveryConfig struct {
+	ConnectTimeout  time.Duration `yaml:"connect_timeout"`   // Dialing and TLS handshake
+	ResponseTimeout time.Duration `yaml:"response_timeout"`  // Grace period for response headers after the request is sent
+	Timeout         time.Duration `yaml:"timeout"`           // Caps a whole attempt including the response body (0 for no cap)
+	MaxRetries      int           `yaml:"max_retries"`       // Delivery attempts per recipient unless a message overrides it
+	RetryDelay      time.Duration `yaml:"retry_delay"`       // Base backoff between attempts unless a message overrides it
+	MaxRetriesLimit int           `yaml:"max_retries_limit"` // Upper bound for a message's max_retries
+	RetryDelayLimit time.Duration `yaml:"retry_delay_limit"` // Upper bound for a message's retry_delay
+
+	// Connection reuse. Deliveries to the same gateway or webhook share
+	// kept-alive connections; MaxConnsPerHost caps concurrent connections to
+	// one host so a burst cannot overwhelm it (0 for unlimited).
+	MaxIdleConns        int           `yaml:"max_idle_conns"`
+	MaxIdleConnsPerHost int           `yaml:"max_idle_conns_per_host"`
+	MaxConnsPerHost     int           `yaml:"max_conns_per_host"`
+	IdleConnTimeout     time.Duration `yaml:"idle_conn_timeout"`
+
+	// MaxConcurrentPerDomain caps relays in flight to one remote domain;
+	// relays beyond it queue instead of opening more connections (0 for
+	// unlimited)
+	MaxConcurrentPerDomain int `yaml:"max_concurrent_per_domain"`
+
+	// MaxConcurrentPerAgent caps push deliveries in flight to one local
+	// agent unless the agent sets its own cap, so a slow webhook cannot hold
+	// every delivery worker; pushes beyond it queue (0 for unlimited)
+	MaxConcurrentPerAgent int `yaml:"max_concurrent_per_agent"`
+
+	// UnknownRecipient decides what happens to local recipients no agent is
+	// registered for: inbox keeps the message in a pull inbox for when one
+	// is, reject fails the recipient, queue holds it for UnknownRecipientHold
+	// in case the agent registers soon, and dead-letter fails it and forwards
+	// the message to DeadLetterAddress (dead-letter@ the server domain when
+	// empty)
+	UnknownRecipient     string        `yaml:"unknown_recipient"`
+	UnknownRecipientHold time.Duration `yaml:"unknown_recipient_hold"`
+	DeadLetterAddress    string        `yaml:"dead_letter_address"`
+
+	Coordination CoordinationLimitsConfig `yaml:"coordination"`
+
+	Cluster ClusterConfig `yaml:"cluster"`
+
+	Kafka KafkaConfig `yaml:"kafka"`
+}
+
+// ClusterConfig lets several gateways share one database. Each gateway
+// leases the queued messages it retries, so no message is retried by two
+// gateways at once, and the messages of a gateway that stops are taken over
+// once their leases expire.
+type ClusterConfig struct {
+	Enabled       bool          `yaml:"enabled"`
+	WorkerID      string        `yaml:"worker_id"`      // Unique per gateway; hostname-pid when empty
+	LeaseDuration time.Duration `yaml:"lease_duration"` // How long a claimed message stays with its gateway
+}
+
+// CoordinationLimitsConfig caps what coordinated messages from local senders
+// may ask of the gateway. Each limit is checked before the message is stored
+// and 0 disables it.
+type CoordinationLimitsConfig struct {
+	MaxRecipients int           `yaml:"max_recipients"` // Distinct recipients and participants of one coordinated message
+	MaxConcurrent int           `yaml:"max_concurrent"` // Coordinations pending or in progress at once
+	MaxTimeout    time.Duration `yaml:"max_timeout"`    // Longest coordination timeout accepted
+}
+
+// KafkaConfig holds the brokers agents in kafka delivery mode are delivered
+// through. The kafka mode is available when Brokers is set and the gateway
+// was built with the kafka build tag.
+type KafkaConfig struct {
+	Brokers  []string      `yaml:"brokers"`   // host:port of the bootstrap brokers
+	ClientID string        `yaml:"client_id"` // Defaults to agentry
+	Timeout  time.Duration `yaml:"timeout"`   // Bounds producing one record, including retries
 }
 
+// Unknown local recipient modes
+const (
+	UnknownRecipientInbox      = "inbox"
+	UnknownRecipientReject     = "reject"
+	UnknownRecipientQueue      = "queue"
+	UnknownRecipientDeadLetter = "dead-letter"
+)
+
+// SMTPBridgeConfig holds the EXPERIMENTAL SMTP-to-AMTP bridge configuration.
+// The bridge accepts unauthenticated mail for the gateway's own domain only
+// and never relays, so it should listen on a trusted network.
+type SMTPBridgeConfig struct {
+	Enabled       bool          `yaml:"experimental_enabled"`
+	Address       string        `yaml:"address"`
+	MaxRecipients int           `yaml:"max_recipients"` // Per message; 0 for unlimited
+	Timeout       time.Duration `yaml:"timeout"`        // Idle time allowed for each SMTP command
+}
+
+// AgentsConfig holds agent registry configuration
+type AgentsConfig struct {
+	CacheTTL time.Duration `yaml:"cache_ttl"` // How long agent lookups are cached; 0 disables the cache
+
+	// Push targets may not resolve to loopback, private or link-local
+	// addresses, for deliveries and registration probes alike, unless they
+	// match an allowlisted host name, IP or CIDR
+	PushTargetAllowlist []string `yaml:"push_target_allowlist"`
+
+	// Reachability probe of push targets at registration
+	PushTargetCheck        string        `yaml:"push_target_check"`         // off, warn or reject
+	PushTargetCheckTimeout time.Duration `yaml:"push_target_check_timeout"` // Per target
+
+	// Agents whose last inbox access or delivery is older than IdleAfter and
+	// whose inbox is empty count as idle. They are listed by the admin API;
+	// IdleAction decides whether a periodic check also logs or unregisters
+	// them.
+	IdleAfter         time.Duration `yaml:"idle_after"`          // 0 disables the threshold
+	IdleAction        string        `yaml:"idle_action"`         // off, flag or unregister
+	IdleCheckInterval time.Duration `yaml:"idle_check_interval"` // How often the check runs
+}
+
+// Push target check modes
+const (
+	PushTargetCheckOff    = "off"
+	PushTargetCheckWarn   = "warn"
+	PushTargetCheckReject = "reject"
+)
+
+// Idle agent actions
+const (
+	IdleActionOff        = "off"
+	IdleActionFlag       = "flag"
+	IdleActionUnregister = "unregister"
+)
+
 // LoggingConfig holds logging configuration
 type LoggingConfig struct {
-	Level  string `yaml:"level"`
-	Format string `yaml:"format"`
+	Level     string          `yaml:"level"`
+	Format    string          `yaml:"format"`
+	Output    string          `yaml:"output"` // stdout (default), stderr, syslog or file:/path
+	Rotation  RotationConfig  `yaml:"rotation"`
+	Redaction RedactionConfig `yaml:"redaction"`
+}
+
+// Log outputs; file output is LogOutputFilePrefix followed by the path
+const (
+	LogOutputStdout     = "stdout"
+	LogOutputStderr     = "stderr"
+	LogOutputSyslog     = "syslog"
+	LogOutputFilePrefix = "file:"
+)
+
+// RotationConfig controls size-based rotation of file log output. The
+// current file is renamed to path.1 once it would exceed MaxSize, shifting
+// older backups up to path.MaxFiles.
+type RotationConfig struct {
+	MaxSize  int64 `yaml:"max_size"`  // Bytes; 0 disables rotation
+	MaxFiles int   `yaml:"max_files"` // Rotated files kept; 0 keeps none
+}
+
+// RedactionConfig lists values masked before they are logged. Credential
+// headers (Authorization, X-Admin-Key, X-API-Key, Cookie) are always masked
+// in addition to these.
+type RedactionConfig struct {
+	Headers []string `yaml:"headers"` // Header names, case-insensitive
+	Fields  []string `yaml:"fields"`  // Dotted JSON paths, e.g. "payload.ssn"; "*" matches any key
 }
 
 // MetricsConfig holds metrics configuration
 type MetricsConfig struct {
-	Enabled bool `yaml:"enabled"`
+	Enabled bool         `yaml:"enabled"`
+	Sink    string       `yaml:"sink"` // simple (default) or statsd
+	StatsD  StatsDConfig `yaml:"statsd"`
 }
 
-// Load loads configuration from YAML file and environment variables
+// StatsDConfig holds the StatsD metrics sink configuration
+type StatsDConfig struct {
+	Address       string        `yaml:"address"`        // UDP host:port of the StatsD or DogStatsD agent
+	Prefix        string        `yaml:"prefix"`         // Prepended to every metric name
+	Tags          bool          `yaml:"tags"`           // Send DogStatsD tags instead of name segments
+	FlushInterval time.Duration `yaml:"flush_interval"` // How often buffered metrics are sent
+}
+
+// Load loads configuration from YAML files and environment variables
 // Command line flags take precedence over environment variables
 // Environment variables take precedence over YAML file values
+// The AGENTRY_ENV override file takes precedence over the base file
 func Load(configFile, adminKeyFile string) (*Config, error) {
 	// Start with default configuration
 	cfg := getDefaultConfig()
 
-	// Load from YAML file if specified
-	if err := loadFromYAML(cfg, configFile); err != nil {
+	// Load from YAML files if specified
+	if err := loadLayers(cfg, configFile); err != nil {
 		return nil, fmt.Errorf("failed to load YAML config: %w", err)
 	}
 
@@ -133,7 +554,7 @@ func Load(configFile, adminKeyFile string) (*Config, error) {
 	}
 
 	// Validate configuration
-	if err := cfg.validate(); err != nil {
+	if err := cfg.Validate(); err != nil {
 		return nil, fmt.Errorf("invalid configuration: %w", err)
 	}
 
@@ -149,12 +570,14 @@ func getDefaultConfig() *Config {
 			ReadTimeout:  30 * time.Second,
 			WriteTimeout: 30 * time.Second,
 			IdleTimeout:  120 * time.Second,
+
+			EventStreamMaxSubscribers: 4,
 		},
 		TLS: TLSConfig{
 			Enabled:    true,
 			CertFile:   "",
 			KeyFile:    "",
-			MinVersion: "1.3",
+			MinVersion: TLSVersion12,
 		},
 		DNS: DNSConfig{
 			CacheTTL:    5 * time.Minute,
@@ -165,23 +588,110 @@ func getDefaultConfig() *Config {
 			AllowHTTP:   false,
 		},
 		Message: MessageConfig{
-			MaxSize:           10 * 1024 * 1024,   // 10MB
-			IdempotencyTTL:    7 * 24 * time.Hour, // 7 days
-			ValidationEnabled: true,
+			MaxSize:                 10 * 1024 * 1024,   // 10MB
+			IdempotencyTTL:          7 * 24 * time.Hour, // 7 days
+			ValidationEnabled:       true,
+			MaxAttachments:          100,
+			MaxTotalAttachmentBytes: 1024 * 1024 * 1024, // 1GB
+			MaxReplyDepth:           100,
+			MaxTimestampSkew:        5 * time.Minute,
+			MaxPayloadDepth:         64,
+			MaxPayloadElements:      10000,
+			SchemaUnavailable:       SchemaUnavailableLenient,
+			IDStrategy:              uuid.StrategyUUIDv7,
+			RawRequestMaxSize:       64 * 1024, // 64KB
+			RawRequestHeaders:       []string{"Content-Type", "Content-Encoding", "User-Agent", "Idempotency-Key", "Prefer", "X-Request-ID"},
+			AcceptHook: AcceptHookConfig{
+				Timeout:   5 * time.Second,
+				OnFailure: AcceptHookFailOpen,
+			},
 		},
 		Auth: AuthConfig{
-			RequireAuth:       false,
-			Methods:           []string{"domain", "apikey"},
-			APIKeyHeader:      "X-API-Key",
-			AdminKeyFile:      "",            // No admin key file by default
-			AdminAPIKeyHeader: "X-Admin-Key", // Header for admin authentication
+			RequireAuth:         false,
+			Methods:             []string{"domain", "apikey"},
+			APIKeyHeader:        "X-API-Key",
+			AdminKeyFile:        "",            // No admin key file by default
+			AdminAPIKeyHeader:   "X-Admin-Key", // Header for admin authentication
+			APIKeyLength:        32,
+			EnforceSenderDomain: true,
+			// Verify signed messages; unsigned ones are still accepted
+			SignatureVerification: SignatureVerificationOptional,
+			Replay: ReplayConfig{
+				Enabled:        false,
+				MaxSkew:        5 * time.Minute,
+				NonceCacheSize: 100000,
+			},
+			InboxTokens: InboxTokenConfig{
+				DefaultTTL: 15 * time.Minute,
+				MaxTTL:     24 * time.Hour,
+			},
 		},
 		Logging: LoggingConfig{
 			Level:  "info",
 			Format: "json",
+			Output: LogOutputStdout,
+			Rotation: RotationConfig{
+				MaxSize:  100 * 1024 * 1024, // 100MB
+				MaxFiles: 5,
+			},
 		},
 		Storage: StorageConfig{
 			Type: "memory",
+			Capacity: StorageCapacityConfig{
+				CheckInterval: 30 * time.Second,
+			},
+			WriteBuffer: StorageWriteBufferConfig{
+				MaxMessages:   1000,
+				FlushInterval: 5 * time.Second,
+			},
+		},
+		Agents: AgentsConfig{
+			CacheTTL:               30 * time.Second,
+			PushTargetCheck:        PushTargetCheckOff,
+			PushTargetCheckTimeout: 5 * time.Second,
+			IdleAction:             IdleActionOff,
+			IdleCheckInterval:      time.Hour,
+		},
+		Delivery: DeliveryConfig{
+			ConnectTimeout:  10 * time.Second,
+			ResponseTimeout: 30 * time.Second,
+			Timeout:         2 * time.Minute,
+			MaxRetries:      3,
+			RetryDelay:      1 * time.Second,
+			MaxRetriesLimit: 10,
+			RetryDelayLimit: 1 * time.Minute,
+
+			MaxIdleConns:        100,
+			MaxIdleConnsPerHost: 25,
+			MaxConnsPerHost:     0,
+			IdleConnTimeout:     90 * time.Second,
+
+			MaxConcurrentPerDomain: 10,
+			MaxConcurrentPerAgent:  10,
+
+			UnknownRecipient:     UnknownRecipientInbox,
+			UnknownRecipientHold: 5 * time.Minute,
+
+			Coordination: CoordinationLimitsConfig{
+				MaxRecipients: 100,
+				MaxConcurrent: 1000,
+				MaxTimeout:    24 * time.Hour,
+			},
+
+			Cluster: ClusterConfig{
+				LeaseDuration: 2 * time.Minute,
+			},
+
+			Kafka: KafkaConfig{
+				ClientID: "agentry",
+				Timeout:  10 * time.Second,
+			},
+		},
+		SMTP: SMTPBridgeConfig{
+			Enabled:       false,
+			Address:       "127.0.0.1:2525",
+			MaxRecipients: 100,
+			Timeout:       5 * time.Minute,
 		},
 	}
 }
@@ -226,6 +736,16 @@ func loadFromEnv(cfg *Config) {
 	if val := getDurationEnv("AMTP_IDLE_TIMEOUT", 0); val != 0 {
 		cfg.Server.IdleTimeout = val
 	}
+	cfg.Server.RateLimit.RequestsPerMinute = int(getInt64Env("AMTP_RATE_LIMIT_REQUESTS_PER_MINUTE", int64(cfg.Server.RateLimit.RequestsPerMinute)))
+	cfg.Server.RateLimit.Burst = int(getInt64Env("AMTP_RATE_LIMIT_BURST", int64(cfg.Server.RateLimit.Burst)))
+	if val := getEnv("AMTP_TRUSTED_PROXIES", ""); val != "" {
+		cfg.Server.TrustedProxies = nil
+		for _, proxy := range strings.Split(val, ",") {
+			cfg.Server.TrustedProxies = append(cfg.Server.TrustedProxies, strings.TrimSpace(proxy))
+		}
+	}
+	cfg.Server.MaxConcurrentRequests = int(getInt64Env("AMTP_MAX_CONCURRENT_REQUESTS", int64(cfg.Server.MaxConcurrentRequests)))
+	cfg.Server.EventStreamMaxSubscribers = int(getInt64Env("AMTP_EVENT_STREAM_MAX_SUBSCRIBERS", int64(cfg.Server.EventStreamMaxSubscribers)))
 
 	// TLS configuration
 	if val := getBoolEnvWithDefault("AMTP_TLS_ENABLED", cfg.TLS.Enabled); val != cfg.TLS.Enabled {
@@ -240,6 +760,12 @@ func loadFromEnv(cfg *Config) {
 	if val := getEnv("AMTP_TLS_MIN_VERSION", ""); val != "" {
 		cfg.TLS.MinVersion = val
 	}
+	if val := getEnv("AMTP_TLS_CLIENT_CA_FILE", ""); val != "" {
+		cfg.TLS.ClientCAFile = val
+	}
+	if val := getEnv("AMTP_TLS_CIPHER_SUITES", ""); val != "" {
+		cfg.TLS.CipherSuites = strings.Split(val, ",")
+	}
 
 	// DNS configuration
 	if val := getDurationEnv("AMTP_DNS_CACHE_TTL", 0); val != 0 {
@@ -257,6 +783,7 @@ func loadFromEnv(cfg *Config) {
 	if val := getBoolEnvWithDefault("AMTP_DNS_ALLOW_HTTP", cfg.DNS.AllowHTTP); val != cfg.DNS.AllowHTTP {
 		cfg.DNS.AllowHTTP = val
 	}
+	cfg.DNS.DiscoveryOverride = getBoolEnvWithDefault("AMTP_DNS_DISCOVERY_OVERRIDE", cfg.DNS.DiscoveryOverride)
 
 	// Load mock records from environment if provided
 	if mockRecords := loadMockRecords(); len(mockRecords) > 0 {
@@ -273,6 +800,79 @@ func loadFromEnv(cfg *Config) {
 	if val := getBoolEnvWithDefault("AMTP_MESSAGE_VALIDATION_ENABLED", cfg.Message.ValidationEnabled); val != cfg.Message.ValidationEnabled {
 		cfg.Message.ValidationEnabled = val
 	}
+	cfg.Message.MaxAttachments = int(getInt64Env("AMTP_MESSAGE_MAX_ATTACHMENTS", int64(cfg.Message.MaxAttachments)))
+	cfg.Message.MaxTotalAttachmentBytes = getInt64Env("AMTP_MESSAGE_MAX_TOTAL_ATTACHMENT_BYTES", cfg.Message.MaxTotalAttachmentBytes)
+	cfg.Message.MaxReplyDepth = int(getInt64Env("AMTP_MESSAGE_MAX_REPLY_DEPTH", int64(cfg.Message.MaxReplyDepth)))
+	cfg.Message.CheckTimestamps = getBoolEnvWithDefault("AMTP_MESSAGE_CHECK_TIMESTAMPS", cfg.Message.CheckTimestamps)
+	cfg.Message.MaxTimestampSkew = getDurationEnv("AMTP_MESSAGE_MAX_TIMESTAMP_SKEW", cfg.Message.MaxTimestampSkew)
+	cfg.Message.MaxPayloadDepth = int(getInt64Env("AMTP_MESSAGE_MAX_PAYLOAD_DEPTH", int64(cfg.Message.MaxPayloadDepth)))
+	cfg.Message.MaxPayloadElements = int(getInt64Env("AMTP_MESSAGE_MAX_PAYLOAD_ELEMENTS", int64(cfg.Message.MaxPayloadElements)))
+	cfg.Message.RequireIdempotencyKey = getBoolEnvWithDefault("AMTP_MESSAGE_REQUIRE_IDEMPOTENCY_KEY", cfg.Message.RequireIdempotencyKey)
+	cfg.Message.SchemaAutoDetect = getBoolEnvWithDefault("AMTP_MESSAGE_SCHEMA_AUTO_DETECT", cfg.Message.SchemaAutoDetect)
+	cfg.Message.BounceReports = getBoolEnvWithDefault("AMTP_MESSAGE_BOUNCE_REPORTS", cfg.Message.BounceReports)
+	cfg.Message.SchemaUnavailable = getEnv("AMTP_MESSAGE_SCHEMA_UNAVAILABLE", cfg.Message.SchemaUnavailable)
+	cfg.Message.IDStrategy = getEnv("AMTP_MESSAGE_ID_STRATEGY", cfg.Message.IDStrategy)
+	cfg.Message.IDPrefix = getEnv("AMTP_MESSAGE_ID_PREFIX", cfg.Message.IDPrefix)
+	if val := os.Getenv("AMTP_MESSAGE_ADDRESS_SCHEMES"); val != "" {
+		cfg.Message.AddressSchemes = strings.Split(val, ",")
+	}
+	cfg.Message.StoreRawRequest = getBoolEnvWithDefault("AMTP_MESSAGE_STORE_RAW_REQUEST", cfg.Message.StoreRawRequest)
+	cfg.Message.RawRequestMaxSize = getInt64Env("AMTP_MESSAGE_RAW_REQUEST_MAX_SIZE", cfg.Message.RawRequestMaxSize)
+	if val := os.Getenv("AMTP_MESSAGE_RAW_REQUEST_HEADERS"); val != "" {
+		cfg.Message.RawRequestHeaders = strings.Split(val, ",")
+	}
+	cfg.Message.ArchiveAddress = getEnv("AMTP_MESSAGE_ARCHIVE_ADDRESS", cfg.Message.ArchiveAddress)
+	cfg.Message.AcceptHook.URL = getEnv("AMTP_MESSAGE_ACCEPT_HOOK_URL", cfg.Message.AcceptHook.URL)
+	cfg.Message.AcceptHook.Timeout = getDurationEnv("AMTP_MESSAGE_ACCEPT_HOOK_TIMEOUT", cfg.Message.AcceptHook.Timeout)
+	cfg.Message.AcceptHook.OnFailure = getEnv("AMTP_MESSAGE_ACCEPT_HOOK_ON_FAILURE", cfg.Message.AcceptHook.OnFailure)
+
+	// Agent registry configuration
+	cfg.Agents.CacheTTL = getDurationEnv("AMTP_AGENT_CACHE_TTL", cfg.Agents.CacheTTL)
+	cfg.Agents.PushTargetCheck = getEnv("AMTP_AGENT_PUSH_TARGET_CHECK", cfg.Agents.PushTargetCheck)
+	cfg.Agents.PushTargetCheckTimeout = getDurationEnv("AMTP_AGENT_PUSH_TARGET_CHECK_TIMEOUT", cfg.Agents.PushTargetCheckTimeout)
+	cfg.Agents.IdleAfter = getDurationEnv("AMTP_AGENT_IDLE_AFTER", cfg.Agents.IdleAfter)
+	cfg.Agents.IdleAction = getEnv("AMTP_AGENT_IDLE_ACTION", cfg.Agents.IdleAction)
+	cfg.Agents.IdleCheckInterval = getDurationEnv("AMTP_AGENT_IDLE_CHECK_INTERVAL", cfg.Agents.IdleCheckInterval)
+	if val := getEnv("AMTP_AGENT_PUSH_TARGET_ALLOWLIST", ""); val != "" {
+		cfg.Agents.PushTargetAllowlist = strings.Split(val, ",")
+	}
+
+	// Delivery configuration
+	cfg.Delivery.ConnectTimeout = getDurationEnv("AMTP_DELIVERY_CONNECT_TIMEOUT", cfg.Delivery.ConnectTimeout)
+	cfg.Delivery.ResponseTimeout = getDurationEnv("AMTP_DELIVERY_RESPONSE_TIMEOUT", cfg.Delivery.ResponseTimeout)
+	cfg.Delivery.Timeout = getDurationEnv("AMTP_DELIVERY_TIMEOUT", cfg.Delivery.Timeout)
+	cfg.Delivery.MaxRetries = int(getInt64Env("AMTP_DELIVERY_MAX_RETRIES", int64(cfg.Delivery.MaxRetries)))
+	cfg.Delivery.RetryDelay = getDurationEnv("AMTP_DELIVERY_RETRY_DELAY", cfg.Delivery.RetryDelay)
+	cfg.Delivery.MaxRetriesLimit = int(getInt64Env("AMTP_DELIVERY_MAX_RETRIES_LIMIT", int64(cfg.Delivery.MaxRetriesLimit)))
+	cfg.Delivery.RetryDelayLimit = getDurationEnv("AMTP_DELIVERY_RETRY_DELAY_LIMIT", cfg.Delivery.RetryDelayLimit)
+	cfg.Delivery.MaxIdleConns = int(getInt64Env("AMTP_DELIVERY_MAX_IDLE_CONNS", int64(cfg.Delivery.MaxIdleConns)))
+	cfg.Delivery.MaxIdleConnsPerHost = int(getInt64Env("AMTP_DELIVERY_MAX_IDLE_CONNS_PER_HOST", int64(cfg.Delivery.MaxIdleConnsPerHost)))
+	cfg.Delivery.MaxConnsPerHost = int(getInt64Env("AMTP_DELIVERY_MAX_CONNS_PER_HOST", int64(cfg.Delivery.MaxConnsPerHost)))
+	cfg.Delivery.IdleConnTimeout = getDurationEnv("AMTP_DELIVERY_IDLE_CONN_TIMEOUT", cfg.Delivery.IdleConnTimeout)
+	cfg.Delivery.MaxConcurrentPerDomain = int(getInt64Env("AMTP_DELIVERY_MAX_CONCURRENT_PER_DOMAIN", int64(cfg.Delivery.MaxConcurrentPerDomain)))
+	cfg.Delivery.MaxConcurrentPerAgent = int(getInt64Env("AMTP_DELIVERY_MAX_CONCURRENT_PER_AGENT", int64(cfg.Delivery.MaxConcurrentPerAgent)))
+	cfg.Delivery.UnknownRecipient = getEnv("AMTP_DELIVERY_UNKNOWN_RECIPIENT", cfg.Delivery.UnknownRecipient)
+	cfg.Delivery.UnknownRecipientHold = getDurationEnv("AMTP_DELIVERY_UNKNOWN_RECIPIENT_HOLD", cfg.Delivery.UnknownRecipientHold)
+	cfg.Delivery.DeadLetterAddress = getEnv("AMTP_DELIVERY_DEAD_LETTER_ADDRESS", cfg.Delivery.DeadLetterAddress)
+	cfg.Delivery.Coordination.MaxRecipients = int(getInt64Env("AMTP_DELIVERY_COORDINATION_MAX_RECIPIENTS", int64(cfg.Delivery.Coordination.MaxRecipients)))
+	cfg.Delivery.Coordination.MaxConcurrent = int(getInt64Env("AMTP_DELIVERY_COORDINATION_MAX_CONCURRENT", int64(cfg.Delivery.Coordination.MaxConcurrent)))
+	cfg.Delivery.Coordination.MaxTimeout = getDurationEnv("AMTP_DELIVERY_COORDINATION_MAX_TIMEOUT", cfg.Delivery.Coordination.MaxTimeout)
+	cfg.Delivery.Cluster.Enabled = getBoolEnv("AMTP_DELIVERY_CLUSTER_ENABLED", cfg.Delivery.Cluster.Enabled)
+	cfg.Delivery.Cluster.WorkerID = getEnv("AMTP_DELIVERY_CLUSTER_WORKER_ID", cfg.Delivery.Cluster.WorkerID)
+	cfg.Delivery.Cluster.LeaseDuration = getDurationEnv("AMTP_DELIVERY_CLUSTER_LEASE_DURATION", cfg.Delivery.Cluster.LeaseDuration)
+	if val := getEnv("AMTP_DELIVERY_KAFKA_BROKERS", ""); val != "" {
+		cfg.Delivery.Kafka.Brokers = strings.Split(val, ",")
+	}
+	cfg.Delivery.Kafka.ClientID = getEnv("AMTP_DELIVERY_KAFKA_CLIENT_ID", cfg.Delivery.Kafka.ClientID)
+	cfg.Delivery.Kafka.Timeout = getDurationEnv("AMTP_DELIVERY_KAFKA_TIMEOUT", cfg.Delivery.Kafka.Timeout)
+
+	// Experimental SMTP bridge configuration
+	cfg.SMTP.Enabled = getBoolEnvWithDefault("AMTP_EXPERIMENTAL_SMTP_BRIDGE", cfg.SMTP.Enabled)
+	if val := getEnv("AMTP_SMTP_BRIDGE_ADDRESS", ""); val != "" {
+		cfg.SMTP.Address = val
+	}
+	cfg.SMTP.MaxRecipients = int(getInt64Env("AMTP_SMTP_BRIDGE_MAX_RECIPIENTS", int64(cfg.SMTP.MaxRecipients)))
+	cfg.SMTP.Timeout = getDurationEnv("AMTP_SMTP_BRIDGE_TIMEOUT", cfg.SMTP.Timeout)
 
 	// Auth configuration
 	if val := getBoolEnvWithDefault("AMTP_AUTH_REQUIRED", cfg.Auth.RequireAuth); val != cfg.Auth.RequireAuth {
@@ -284,6 +884,20 @@ func loadFromEnv(cfg *Config) {
 	if val := getEnv("AMTP_AUTH_API_KEY_SALT", ""); val != "" {
 		cfg.Auth.APIKeySalt = val
 	}
+	cfg.Auth.EnforceSenderDomain = getBoolEnvWithDefault("AMTP_AUTH_ENFORCE_SENDER_DOMAIN", cfg.Auth.EnforceSenderDomain)
+	if val := getEnv("AMTP_AUTH_API_KEY_PREFIX", ""); val != "" {
+		cfg.Auth.APIKeyPrefix = val
+	}
+	cfg.Auth.APIKeyLength = int(getInt64Env("AMTP_AUTH_API_KEY_LENGTH", int64(cfg.Auth.APIKeyLength)))
+	cfg.Auth.SignatureVerification = getEnv("AMTP_AUTH_SIGNATURE_VERIFICATION", cfg.Auth.SignatureVerification)
+	cfg.Auth.SigningKeyFile = getEnv("AMTP_AUTH_SIGNING_KEY_FILE", cfg.Auth.SigningKeyFile)
+	cfg.Auth.SigningKeyID = getEnv("AMTP_AUTH_SIGNING_KEY_ID", cfg.Auth.SigningKeyID)
+	cfg.Auth.Replay.Enabled = getBoolEnvWithDefault("AMTP_AUTH_REPLAY_PROTECTION", cfg.Auth.Replay.Enabled)
+	cfg.Auth.Replay.MaxSkew = getDurationEnv("AMTP_AUTH_REPLAY_MAX_SKEW", cfg.Auth.Replay.MaxSkew)
+	cfg.Auth.Replay.NonceCacheSize = int(getInt64Env("AMTP_AUTH_REPLAY_NONCE_CACHE_SIZE", int64(cfg.Auth.Replay.NonceCacheSize)))
+	cfg.Auth.InboxTokens.KeyFile = getEnv("AMTP_AUTH_INBOX_TOKEN_KEY_FILE", cfg.Auth.InboxTokens.KeyFile)
+	cfg.Auth.InboxTokens.DefaultTTL = getDurationEnv("AMTP_AUTH_INBOX_TOKEN_DEFAULT_TTL", cfg.Auth.InboxTokens.DefaultTTL)
+	cfg.Auth.InboxTokens.MaxTTL = getDurationEnv("AMTP_AUTH_INBOX_TOKEN_MAX_TTL", cfg.Auth.InboxTokens.MaxTTL)
 	if val := getEnv("AMTP_ADMIN_KEY_FILE", ""); val != "" {
 		cfg.Auth.AdminKeyFile = val
 	}
@@ -298,6 +912,17 @@ func loadFromEnv(cfg *Config) {
 	if val := getEnv("AMTP_LOG_FORMAT", ""); val != "" {
 		cfg.Logging.Format = val
 	}
+	if val := getEnv("AMTP_LOG_OUTPUT", ""); val != "" {
+		cfg.Logging.Output = val
+	}
+	cfg.Logging.Rotation.MaxSize = getInt64Env("AMTP_LOG_ROTATE_MAX_SIZE", cfg.Logging.Rotation.MaxSize)
+	cfg.Logging.Rotation.MaxFiles = int(getInt64Env("AMTP_LOG_ROTATE_MAX_FILES", int64(cfg.Logging.Rotation.MaxFiles)))
+	if val := getEnv("AMTP_LOG_REDACT_HEADERS", ""); val != "" {
+		cfg.Logging.Redaction.Headers = strings.Split(val, ",")
+	}
+	if val := getEnv("AMTP_LOG_REDACT_FIELDS", ""); val != "" {
+		cfg.Logging.Redaction.Fields = strings.Split(val, ",")
+	}
 
 	// Storage configuration
 	if val := getEnv("AMTP_STORAGE_TYPE", ""); val != "" {
@@ -315,42 +940,481 @@ func loadFromEnv(cfg *Config) {
 	if val := getInt64Env("AMTP_STORAGE_DATABASE_MAX_IDLE_TIME", 0); val != 0 {
 		cfg.Storage.Database.MaxIdleTime = int(val)
 	}
+	cfg.Storage.CompressPayloads = getBoolEnvWithDefault("AMTP_STORAGE_COMPRESS_PAYLOADS", cfg.Storage.CompressPayloads)
+	cfg.Storage.ReconcileInterval = getDurationEnv("AMTP_STORAGE_RECONCILE_INTERVAL", cfg.Storage.ReconcileInterval)
+	cfg.Storage.Capacity.CheckInterval = getDurationEnv("AMTP_STORAGE_CAPACITY_CHECK_INTERVAL", cfg.Storage.Capacity.CheckInterval)
+	cfg.Storage.Capacity.MaxMessages = getInt64Env("AMTP_STORAGE_MAX_MESSAGES", cfg.Storage.Capacity.MaxMessages)
+	cfg.Storage.Capacity.MaxInboxMessages = getInt64Env("AMTP_STORAGE_MAX_INBOX_MESSAGES", cfg.Storage.Capacity.MaxInboxMessages)
+	cfg.Storage.Capacity.MaxUnacknowledgedAge = getDurationEnv("AMTP_STORAGE_MAX_UNACKNOWLEDGED_AGE", cfg.Storage.Capacity.MaxUnacknowledgedAge)
+	cfg.Storage.WriteBuffer.Enabled = getBoolEnvWithDefault("AMTP_STORAGE_WRITE_BUFFER_ENABLED", cfg.Storage.WriteBuffer.Enabled)
+	cfg.Storage.WriteBuffer.MaxMessages = int(getInt64Env("AMTP_STORAGE_WRITE_BUFFER_MAX_MESSAGES", int64(cfg.Storage.WriteBuffer.MaxMessages)))
+	cfg.Storage.WriteBuffer.FlushInterval = getDurationEnv("AMTP_STORAGE_WRITE_BUFFER_FLUSH_INTERVAL", cfg.Storage.WriteBuffer.FlushInterval)
 
 	// Metrics configuration
 	loadMetricsFromEnv(cfg)
 
 	// Schema configuration
 	loadSchemaFromEnv(cfg)
+
+	// Feature flags: the listed flags are on and all others off
+	if val := os.Getenv("AMTP_FEATURES"); val != "" {
+		cfg.Features = make(map[string]bool)
+		for _, name := range strings.Split(val, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				cfg.Features[name] = true
+			}
+		}
+	}
+}
+
+// FieldError is a problem with one setting, named by its path in the YAML
+// configuration, e.g. tls.cert_file
+type FieldError struct {
+	Field string
+	Err   error
+}
+
+func (e *FieldError) Error() string {
+	return e.Field + ": " + e.Err.Error()
+}
+
+func (e *FieldError) Unwrap() error {
+	return e.Err
+}
+
+// ValidationError lists every problem Validate found, so a configuration
+// can be fixed in one pass rather than one restart per mistake
+type ValidationError struct {
+	Errors []*FieldError
 }
 
-// validate validates the configuration
-func (c *Config) validate() error {
-	// Validate server domain
+func (e *ValidationError) Error() string {
+	if len(e.Errors) == 1 {
+		return e.Errors[0].Error()
+	}
+	problems := make([]string, len(e.Errors))
+	for i, err := range e.Errors {
+		problems[i] = err.Error()
+	}
+	return fmt.Sprintf("%d problems: %s", len(e.Errors), strings.Join(problems, "; "))
+}
+
+// Unwrap lets errors.As find a particular FieldError
+func (e *ValidationError) Unwrap() []error {
+	errs := make([]error, len(e.Errors))
+	for i, err := range e.Errors {
+		errs[i] = err
+	}
+	return errs
+}
+
+// validationErrors collects the problems found by Validate
+type validationErrors []*FieldError
+
+func (v *validationErrors) add(field, format string, args ...interface{}) {
+	*v = append(*v, &FieldError{Field: field, Err: fmt.Errorf(format, args...)})
+}
+
+func (v validationErrors) err() error {
+	if len(v) == 0 {
+		return nil
+	}
+	return &ValidationError{Errors: v}
+}
+
+// Validate checks the configuration for settings that are invalid or that
+// contradict each other. Every problem is reported, each with the setting it
+// concerns, as a *ValidationError.
+func (c *Config) Validate() error {
+	var errs validationErrors
+
 	if err := c.validateDomain(); err != nil {
-		return fmt.Errorf("invalid server domain: %w", err)
+		errs.add("server.domain", "invalid server domain: %w", err)
+	}
+
+	if path, ok := UnixSocketPath(c.Server.Address); ok && path == "" {
+		errs.add("server.address", "unix socket address requires a path, e.g. unix:/run/agentry.sock")
+	}
+	if c.Server.ReadTimeout < 0 || c.Server.WriteTimeout < 0 || c.Server.IdleTimeout < 0 {
+		errs.add("server", "read, write and idle timeouts cannot be negative")
+	}
+
+	if c.TLS.Enabled {
+		if c.TLS.CertFile == "" || c.TLS.KeyFile == "" {
+			errs.add("tls", "TLS cert and key files are required when TLS is enabled")
+		}
+		files := []struct{ field, path string }{
+			{"tls.cert_file", c.TLS.CertFile},
+			{"tls.key_file", c.TLS.KeyFile},
+			{"tls.client_ca_file", c.TLS.ClientCAFile},
+		}
+		for _, file := range files {
+			if file.path == "" {
+				continue
+			}
+			if _, err := os.Stat(file.path); err != nil {
+				errs.add(file.field, "file not found: %s", file.path)
+			}
+		}
+	}
+
+	minTLSVersion, err := c.TLS.MinTLSVersion()
+	if err != nil {
+		errs.add("tls.min_version", "%w", err)
+	}
+	if _, err := c.TLS.CipherSuiteIDs(); err != nil {
+		errs.add("tls.cipher_suites", "invalid TLS cipher suites: %w", err)
+	}
+	if minTLSVersion == tls.VersionTLS13 && len(c.TLS.CipherSuites) > 0 {
+		errs.add("tls.cipher_suites", "TLS cipher suites only apply to TLS 1.2 and cannot be set with a TLS 1.3 minimum")
 	}
 
-	if c.TLS.Enabled && (c.TLS.CertFile == "" || c.TLS.KeyFile == "") {
-		return fmt.Errorf("TLS cert and key files are required when TLS is enabled")
+	if c.DNS.Timeout < 0 || c.DNS.CacheTTL < 0 {
+		errs.add("dns", "timeout and cache TTL cannot be negative")
+	}
+	for _, resolver := range c.DNS.Resolvers {
+		if !validResolver(resolver) {
+			errs.add("dns.resolvers", "invalid DNS resolver %q, expected an IP address or host name with an optional port", resolver)
+		}
 	}
 
 	if c.Message.MaxSize <= 0 {
-		return fmt.Errorf("message max size must be positive")
+		errs.add("message.max_size", "message max size must be positive")
+	}
+
+	if err := features.Validate(c.Features); err != nil {
+		errs.add("features", "%w", err)
+	}
+
+	if c.Message.MaxAttachments < 0 {
+		errs.add("message.max_attachments", "message max attachments cannot be negative")
+	}
+
+	if c.Message.MaxTotalAttachmentBytes < 0 {
+		errs.add("message.max_total_attachment_bytes", "message max total attachment bytes cannot be negative")
+	}
+
+	if c.Message.MaxReplyDepth < 0 {
+		errs.add("message.max_reply_depth", "message max reply depth cannot be negative")
+	}
+
+	if c.Message.MaxPayloadDepth < 0 {
+		errs.add("message.max_payload_depth", "message max payload depth cannot be negative")
+	}
+
+	if c.Message.MaxPayloadElements < 0 {
+		errs.add("message.max_payload_elements", "message max payload elements cannot be negative")
+	}
+
+	if c.Message.StoreRawRequest && c.Message.RawRequestMaxSize <= 0 {
+		errs.add("message.raw_request_max_size", "raw request max size must be positive when raw requests are stored")
+	}
+
+	if c.Message.ArchiveAddress != "" {
+		local, domain, ok := strings.Cut(c.Message.ArchiveAddress, "@")
+		if !ok || local == "" || domain == "" {
+			errs.add("message.archive_address", "archive address %q must be an agent address, e.g. archive@%s", c.Message.ArchiveAddress, c.Server.Domain)
+		}
+	}
+
+	switch c.Message.SchemaUnavailable {
+	case "", SchemaUnavailableLenient, SchemaUnavailableStrict:
+	default:
+		errs.add("message.schema_unavailable", "schema unavailable mode must be '%s' or '%s'", SchemaUnavailableLenient, SchemaUnavailableStrict)
+	}
+
+	if _, err := uuid.NewGenerator(c.Message.IDStrategy, c.Message.IDPrefix); err != nil {
+		errs.add("message.id_strategy", "%w", err)
+	}
+	if _, err := types.NewAddressSchemes(c.Message.AddressSchemes); err != nil {
+		errs.add("message.address_schemes", "%w", err)
+	}
+
+	if hookURL := c.Message.AcceptHook.URL; hookURL != "" {
+		u, err := url.Parse(hookURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			errs.add("message.accept_hook.url", "accept hook URL must be an absolute http or https URL")
+		}
+	}
+	if c.Message.AcceptHook.Timeout < 0 {
+		errs.add("message.accept_hook.timeout", "accept hook timeout cannot be negative")
+	}
+	switch c.Message.AcceptHook.OnFailure {
+	case "", AcceptHookFailOpen, AcceptHookFailClosed:
+	default:
+		errs.add("message.accept_hook.on_failure", "accept hook failure mode must be '%s' or '%s'", AcceptHookFailOpen, AcceptHookFailClosed)
+	}
+
+	if c.Server.RateLimit.RequestsPerMinute < 0 || c.Server.RateLimit.Burst < 0 {
+		errs.add("server.rate_limit", "rate limit requests per minute and burst cannot be negative")
+	}
+	for _, proxy := range c.Server.TrustedProxies {
+		if net.ParseIP(proxy) != nil {
+			continue
+		}
+		if _, _, err := net.ParseCIDR(proxy); err != nil {
+			errs.add("server.trusted_proxies", "invalid trusted proxy %q: must be an IP address or CIDR range", proxy)
+		}
+	}
+	if c.Server.MaxConcurrentRequests < 0 {
+		errs.add("server.max_concurrent_requests", "max concurrent requests cannot be negative")
+	}
+	if c.Server.EventStreamMaxSubscribers < 0 {
+		errs.add("server.event_stream_max_subscribers", "event stream max subscribers cannot be negative")
+	}
+
+	if c.DNS.DiscoveryOverride && !c.DNS.MockMode {
+		errs.add("dns.discovery_override", "DNS discovery override is for testing and requires DNS mock mode")
+	}
+
+	switch output := c.Logging.Output; {
+	case output == "", output == LogOutputStdout, output == LogOutputStderr, output == LogOutputSyslog:
+	case strings.HasPrefix(output, LogOutputFilePrefix) && len(output) > len(LogOutputFilePrefix):
+	default:
+		errs.add("logging.output", "log output must be '%s', '%s', '%s' or '%s/path/to/file'",
+			LogOutputStdout, LogOutputStderr, LogOutputSyslog, LogOutputFilePrefix)
+	}
+
+	if c.Logging.Rotation.MaxSize < 0 || c.Logging.Rotation.MaxFiles < 0 {
+		errs.add("logging.rotation", "log rotation max size and max files cannot be negative")
+	}
+
+	if c.Agents.CacheTTL < 0 {
+		errs.add("agents.cache_ttl", "agent cache TTL cannot be negative")
+	}
+
+	switch c.Agents.PushTargetCheck {
+	case "", PushTargetCheckOff, PushTargetCheckWarn, PushTargetCheckReject:
+	default:
+		errs.add("agents.push_target_check", "push target check must be '%s', '%s' or '%s'", PushTargetCheckOff, PushTargetCheckWarn, PushTargetCheckReject)
+	}
+
+	if c.Agents.PushTargetCheckTimeout < 0 {
+		errs.add("agents.push_target_check_timeout", "push target check timeout cannot be negative")
+	}
+
+	if c.Agents.IdleAfter < 0 || c.Agents.IdleCheckInterval < 0 {
+		errs.add("agents", "agent idle threshold and check interval cannot be negative")
+	}
+	switch c.Agents.IdleAction {
+	case "", IdleActionOff:
+	case IdleActionFlag, IdleActionUnregister:
+		if c.Agents.IdleAfter == 0 {
+			errs.add("agents.idle_after", "agent idle action %s requires an idle threshold", c.Agents.IdleAction)
+		}
+		if c.Agents.IdleCheckInterval == 0 {
+			errs.add("agents.idle_check_interval", "agent idle action %s requires a check interval", c.Agents.IdleAction)
+		}
+	default:
+		errs.add("agents.idle_action", "agent idle action must be '%s', '%s' or '%s'", IdleActionOff, IdleActionFlag, IdleActionUnregister)
+	}
+
+	for _, entry := range c.Agents.PushTargetAllowlist {
+		if strings.Contains(entry, "/") {
+			if _, _, err := net.ParseCIDR(strings.TrimSpace(entry)); err != nil {
+				errs.add("agents.push_target_allowlist", "invalid push target allowlist entry %q: %w", entry, err)
+			}
+		}
+	}
+
+	if c.Delivery.ConnectTimeout < 0 {
+		errs.add("delivery.connect_timeout", "delivery connect timeout cannot be negative")
+	}
+	if c.Delivery.ResponseTimeout < 0 {
+		errs.add("delivery.response_timeout", "delivery response timeout cannot be negative")
+	}
+	if c.Delivery.Timeout < 0 {
+		errs.add("delivery.timeout", "delivery timeout cannot be negative")
+	}
+	if c.Delivery.MaxRetries < 0 || c.Delivery.RetryDelay < 0 || c.Delivery.MaxRetriesLimit < 0 || c.Delivery.RetryDelayLimit < 0 {
+		errs.add("delivery", "delivery retry settings cannot be negative")
+	}
+	if c.Delivery.MaxRetriesLimit > 0 && c.Delivery.MaxRetries > c.Delivery.MaxRetriesLimit {
+		errs.add("delivery.max_retries", "delivery max retries %d exceeds max retries limit %d", c.Delivery.MaxRetries, c.Delivery.MaxRetriesLimit)
+	}
+	if c.Delivery.RetryDelayLimit > 0 && c.Delivery.RetryDelay > c.Delivery.RetryDelayLimit {
+		errs.add("delivery.retry_delay", "delivery retry delay %v exceeds retry delay limit %v", c.Delivery.RetryDelay, c.Delivery.RetryDelayLimit)
+	}
+	if c.Delivery.MaxIdleConns < 0 || c.Delivery.MaxIdleConnsPerHost < 0 || c.Delivery.MaxConnsPerHost < 0 ||
+		c.Delivery.IdleConnTimeout < 0 || c.Delivery.MaxConcurrentPerDomain < 0 || c.Delivery.MaxConcurrentPerAgent < 0 {
+		errs.add("delivery", "delivery connection settings cannot be negative")
+	}
+	switch c.Delivery.UnknownRecipient {
+	case "", UnknownRecipientInbox, UnknownRecipientReject, UnknownRecipientDeadLetter:
+	case UnknownRecipientQueue:
+		if c.Delivery.UnknownRecipientHold <= 0 {
+			errs.add("delivery.unknown_recipient_hold", "unknown recipient hold must be positive when unknown recipients are queued")
+		}
+	default:
+		errs.add("delivery.unknown_recipient", "unknown recipient handling must be '%s', '%s', '%s' or '%s'",
+			UnknownRecipientInbox, UnknownRecipientReject, UnknownRecipientQueue, UnknownRecipientDeadLetter)
+	}
+	if c.Delivery.DeadLetterAddress != "" {
+		_, domain, ok := strings.Cut(c.Delivery.DeadLetterAddress, "@")
+		if !ok || !strings.EqualFold(domain, c.Server.Domain) {
+			errs.add("delivery.dead_letter_address", "dead letter address %q must be an address in the server domain %s", c.Delivery.DeadLetterAddress, c.Server.Domain)
+		}
+	}
+	if c.Delivery.Coordination.MaxRecipients < 0 || c.Delivery.Coordination.MaxConcurrent < 0 || c.Delivery.Coordination.MaxTimeout < 0 {
+		errs.add("delivery.coordination", "coordination limits cannot be negative")
+	}
+	if c.Delivery.Cluster.Enabled && c.Delivery.Cluster.LeaseDuration <= 0 {
+		errs.add("delivery.cluster.lease_duration", "cluster lease duration must be positive when clustering is enabled")
+	}
+	for _, broker := range c.Delivery.Kafka.Brokers {
+		if host, port, err := net.SplitHostPort(strings.TrimSpace(broker)); err != nil || host == "" || port == "" {
+			errs.add("delivery.kafka.brokers", "invalid kafka broker %q, expected host:port", broker)
+		}
+	}
+	if len(c.Delivery.Kafka.Brokers) > 0 && c.Delivery.Kafka.Timeout <= 0 {
+		errs.add("delivery.kafka.timeout", "kafka timeout must be positive")
+	}
+
+	if c.SMTP.Enabled {
+		if c.SMTP.Address == "" {
+			errs.add("smtp_bridge.address", "SMTP bridge address is required when the bridge is enabled")
+		}
+		if c.SMTP.MaxRecipients < 0 || c.SMTP.Timeout < 0 {
+			errs.add("smtp_bridge", "SMTP bridge limits cannot be negative")
+		}
+		// The bridge has no SMTP AUTH, so it would bypass required auth
+		if c.Auth.RequireAuth {
+			errs.add("smtp_bridge.experimental_enabled", "SMTP bridge cannot be enabled when auth.require_auth is set")
+		}
+	}
+
+	switch c.Storage.Type {
+	case "", "memory", "database":
+	default:
+		errs.add("storage.type", "storage type must be 'memory' or 'database', got %q", c.Storage.Type)
+	}
+	if (c.Storage.Type == "database" || c.Storage.Database.Driver != "") && c.Storage.Database.ConnectionString == "" {
+		errs.add("storage.database.connection_string", "a connection string is required for database storage")
+	}
+
+	if c.Storage.Capacity.CheckInterval < 0 {
+		errs.add("storage.capacity.check_interval", "storage capacity check interval cannot be negative")
+	}
+
+	if c.Storage.ReconcileInterval < 0 {
+		errs.add("storage.reconcile_interval", "storage reconcile interval cannot be negative")
+	}
+
+	if c.Storage.Capacity.MaxMessages < 0 || c.Storage.Capacity.MaxInboxMessages < 0 || c.Storage.Capacity.MaxUnacknowledgedAge < 0 {
+		errs.add("storage.capacity", "storage capacity watermarks cannot be negative")
+	}
+
+	if c.Storage.WriteBuffer.Enabled {
+		if c.Storage.WriteBuffer.MaxMessages <= 0 {
+			errs.add("storage.write_buffer.max_messages", "write buffer size must be positive")
+		}
+		if c.Storage.WriteBuffer.FlushInterval <= 0 {
+			errs.add("storage.write_buffer.flush_interval", "write buffer flush interval must be positive")
+		}
+	}
+
+	if c.Auth.APIKeyPrefix != "" && !apiKeyPrefixRegex.MatchString(c.Auth.APIKeyPrefix) {
+		errs.add("auth.api_key_prefix", "API key prefix %q must be 1-15 lowercase letters or digits followed by an underscore, e.g. amtp_", c.Auth.APIKeyPrefix)
+	}
+
+	if c.Auth.APIKeyLength != 0 && c.Auth.APIKeyLength < minAPIKeyLength {
+		errs.add("auth.api_key_length", "API key length must be at least %d bytes", minAPIKeyLength)
+	}
+
+	switch c.Auth.SignatureVerification {
+	case "", SignatureVerificationOff, SignatureVerificationOptional, SignatureVerificationRequired:
+	default:
+		errs.add("auth.signature_verification", "signature verification must be '%s', '%s' or '%s'", SignatureVerificationOff, SignatureVerificationOptional, SignatureVerificationRequired)
+	}
+
+	if c.Message.CheckTimestamps && c.Message.MaxTimestampSkew <= 0 {
+		errs.add("message.max_timestamp_skew", "message timestamp checks require a positive max timestamp skew")
+	}
+	if c.Auth.Replay.Enabled {
+		if c.Auth.Replay.MaxSkew <= 0 {
+			errs.add("auth.replay.max_skew", "replay protection requires a positive max skew")
+		}
+		if c.Auth.Replay.NonceCacheSize <= 0 {
+			errs.add("auth.replay.nonce_cache_size", "replay protection requires a positive nonce cache size")
+		}
+	}
+
+	if c.Schema != nil {
+		switch c.Schema.Validation.UnknownFormats {
+		case "", schema.UnknownFormatWarn, schema.UnknownFormatError:
+		default:
+			errs.add("schema.validation.unknown_formats", "unknown schema format policy %q (expected warn or error)", c.Schema.Validation.UnknownFormats)
+		}
+	}
+
+	if c.Metrics != nil && c.Metrics.Enabled {
+		switch c.Metrics.Sink {
+		case "", "simple":
+		case "statsd":
+			if c.Metrics.StatsD.Address == "" {
+				errs.add("metrics.statsd.address", "statsd metrics sink requires an address")
+			}
+		default:
+			errs.add("metrics.sink", "unknown metrics sink %q (expected simple or statsd)", c.Metrics.Sink)
+		}
 	}
 
-	// Validate admin key file if specified
 	if c.Auth.AdminKeyFile != "" {
 		if _, err := os.Stat(c.Auth.AdminKeyFile); err != nil {
-			return fmt.Errorf("admin key file not found: %s", c.Auth.AdminKeyFile)
+			errs.add("auth.admin_key_file", "admin key file not found: %s", c.Auth.AdminKeyFile)
 		}
 	}
 
-	return nil
+	if c.Auth.SigningKeyFile != "" {
+		if _, err := os.Stat(c.Auth.SigningKeyFile); err != nil {
+			errs.add("auth.signing_key_file", "signing key file not found: %s", c.Auth.SigningKeyFile)
+		}
+		if _, err := discovery.KeyRecordName(c.Server.Domain, c.Auth.SigningKeyID); err != nil {
+			errs.add("auth.signing_key_id", "invalid signing key ID: %w", err)
+		}
+	}
+
+	if tokens := c.Auth.InboxTokens; tokens.KeyFile != "" {
+		if _, err := os.Stat(tokens.KeyFile); err != nil {
+			errs.add("auth.inbox_tokens.key_file", "inbox token key file not found: %s", tokens.KeyFile)
+		}
+		if tokens.DefaultTTL <= 0 || tokens.MaxTTL <= 0 {
+			errs.add("auth.inbox_tokens", "inbox token default and max TTL must be positive")
+		} else if tokens.DefaultTTL > tokens.MaxTTL {
+			errs.add("auth.inbox_tokens.default_ttl", "inbox token default TTL %v exceeds max TTL %v", tokens.DefaultTTL, tokens.MaxTTL)
+		}
+	}
+
+	return errs.err()
 }
 
+// apiKeyPrefixRegex restricts API key prefixes to a recognizable token such as
+// "amtp_", so key-scanning tools can match them and the CLI can tell prefix
+// from key material when masking
+var apiKeyPrefixRegex = regexp.MustCompile(`^[a-z][a-z0-9]{0,14}_$`)
+
+// minAPIKeyLength is the minimum number of random bytes in a generated API key
+const minAPIKeyLength = 16
+
 // domainRegex validates DNS domain name format.
 var domainRegex = regexp.MustCompile(`^[a-zA-Z0-9]([a-zA-Z0-9\-]{0,61}[a-zA-Z0-9])?(\.[a-zA-Z0-9]([a-zA-Z0-9\-]{0,61}[a-zA-Z0-9])?)*$`)
 
+// validResolver reports whether resolver is an IP address or host name,
+// such as kube-dns, with an optional port
+func validResolver(resolver string) bool {
+	host := strings.TrimSpace(resolver)
+	if h, port, err := net.SplitHostPort(host); err == nil {
+		if n, err := strconv.Atoi(port); err != nil || n < 1 || n > 65535 {
+			return false
+		}
+		host = h
+	}
+	if net.ParseIP(strings.Trim(host, "[]")) != nil {
+		return true
+	}
+	return len(host) <= 253 && domainRegex.MatchString(host)
+}
+
 // validateDomain validates the server domain configuration
 func (c *Config) validateDomain() error {
 	domain := strings.TrimSpace(c.Server.Domain)
@@ -504,19 +1568,22 @@ func loadSchemaFromEnv(cfg *Config) {
 		}
 
 		cfg.Schema.RegistryType = "database"
-	} else if registryType == "http" || registryURL != "" {
+	} else if registryType == "http" || registryType == "remote" || registryURL != "" {
+		if registryType == "" {
+			registryType = "http"
+		}
 		if registryURL == "" {
-			log.Printf("WARNING: Schema management enabled (http) but AMTP_SCHEMA_REGISTRY_URL not set. Schema operations will fail.")
+			log.Printf("WARNING: Schema management enabled (%s) but AMTP_SCHEMA_REGISTRY_URL not set. Schema operations will fail.", registryType)
 			return
 		}
 
-		log.Printf("INFO: Schema management enabled with HTTP registry at: %s", registryURL)
+		log.Printf("INFO: Schema management enabled with %s registry at: %s", registryType, registryURL)
 
 		if cfg.Schema == nil {
 			cfg.Schema = &schema.ManagerConfig{}
 		}
 
-		cfg.Schema.RegistryType = "http"
+		cfg.Schema.RegistryType = registryType
 		cfg.Schema.Registry.BaseURL = registryURL
 
 		// Optional additional HTTP registry settings
@@ -526,8 +1593,20 @@ func loadSchemaFromEnv(cfg *Config) {
 		if to := getDurationEnv("AMTP_SCHEMA_REGISTRY_TIMEOUT", 0); to != 0 {
 			cfg.Schema.Registry.Timeout = to
 		}
+		if ttl := getDurationEnv("AMTP_SCHEMA_REGISTRY_LIST_CACHE_TTL", 0); ttl != 0 {
+			cfg.Schema.Registry.ListCacheTTL = ttl
+		}
 	} else {
-		log.Printf("INFO: Schema management not configured. Set AMTP_SCHEMA_REGISTRY_TYPE=local (with AMTP_SCHEMA_REGISTRY_PATH) or database or http to enable.")
+		log.Printf("INFO: Schema management not configured. Set AMTP_SCHEMA_REGISTRY_TYPE=local (with AMTP_SCHEMA_REGISTRY_PATH), database, or http or remote (with AMTP_SCHEMA_REGISTRY_URL) to enable.")
+	}
+
+	if cfg.Schema != nil {
+		if val := getEnv("AMTP_SCHEMA_UNKNOWN_FORMATS", ""); val != "" {
+			cfg.Schema.Validation.UnknownFormats = val
+		}
+		if val := getEnv("AMTP_SCHEMA_SEED_DIR", ""); val != "" {
+			cfg.Schema.SeedDir = val
+		}
 	}
 }
 
@@ -544,4 +1623,19 @@ func loadMetricsFromEnv(cfg *Config) {
 	} else {
 		log.Printf("INFO: Metrics not enabled. Set AMTP_METRICS_ENABLED=true to enable metrics.")
 	}
+
+	if cfg.Metrics == nil {
+		return
+	}
+	if val := getEnv("AMTP_METRICS_SINK", ""); val != "" {
+		cfg.Metrics.Sink = val
+	}
+	if val := getEnv("AMTP_METRICS_STATSD_ADDRESS", ""); val != "" {
+		cfg.Metrics.StatsD.Address = val
+	}
+	if val := getEnv("AMTP_METRICS_STATSD_PREFIX", ""); val != "" {
+		cfg.Metrics.StatsD.Prefix = val
+	}
+	cfg.Metrics.StatsD.Tags = getBoolEnvWithDefault("AMTP_METRICS_STATSD_TAGS", cfg.Metrics.StatsD.Tags)
+	cfg.Metrics.StatsD.FlushInterval = getDurationEnv("AMTP_METRICS_STATSD_FLUSH_INTERVAL", cfg.Metrics.StatsD.FlushInterval)
 }
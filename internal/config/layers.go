@@ -0,0 +1,153 @@
+/*
+ * Copyright 2026 Cong Wang
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// EnvironmentVariable names the deployment environment, such as dev, staging
+// or prod, whose override file is layered over the base configuration file
+const EnvironmentVariable = "AGENTRY_ENV"
+
+// redactedValue replaces secrets in the effective configuration
+const redactedValue = "[REDACTED]"
+
+// environmentFile returns the override file of env for a base configuration
+// file: config.yaml becomes config.prod.yaml for prod
+func environmentFile(configFile, env string) string {
+	ext := filepath.Ext(configFile)
+	return strings.TrimSuffix(configFile, ext) + "." + env + ext
+}
+
+// loadLayers loads the base configuration file, then the override file of the
+// environment named by AGENTRY_ENV. Each file is decoded over the result of
+// the previous one, so the override only needs the settings that differ:
+// sections and maps are merged key by key, while scalars and lists are
+// replaced. Without a base file the environment is ignored.
+func loadLayers(cfg *Config, configFile string) error {
+	if err := loadFromYAML(cfg, configFile); err != nil {
+		return err
+	}
+	if configFile == "" {
+		return nil
+	}
+	cfg.Files = []string{filepath.Clean(configFile)}
+
+	env := strings.TrimSpace(os.Getenv(EnvironmentVariable))
+	if env == "" {
+		return nil
+	}
+	if strings.ContainsAny(env, `/\`) || env == "." || env == ".." {
+		return fmt.Errorf("invalid %s %q", EnvironmentVariable, env)
+	}
+
+	overrideFile := environmentFile(configFile, env)
+	if err := loadFromYAML(cfg, overrideFile); err != nil {
+		return fmt.Errorf("failed to load the %s configuration: %w", env, err)
+	}
+	cfg.Environment = env
+	cfg.Files = append(cfg.Files, filepath.Clean(overrideFile))
+	return nil
+}
+
+// connectionPasswordRegex matches the password of a key=value connection string
+var connectionPasswordRegex = regexp.MustCompile(`(?i)(password\s*=\s*)('[^']*'|\S+)`)
+
+// Redacted returns a copy of the configuration with secrets replaced: the API
+// key salt, the database password, the schema registry token and headers,
+// and credentials in the accept hook URL
+func (c *Config) Redacted() *Config {
+	redacted := *c
+
+	if redacted.Auth.APIKeySalt != "" {
+		redacted.Auth.APIKeySalt = redactedValue
+	}
+	redacted.Storage.Database.ConnectionString = redactConnectionString(c.Storage.Database.ConnectionString)
+	redacted.Message.AcceptHook.URL = redactURL(c.Message.AcceptHook.URL)
+
+	if c.Schema != nil {
+		schemaConfig := *c.Schema
+		if schemaConfig.Registry.AuthToken != "" {
+			schemaConfig.Registry.AuthToken = redactedValue
+		}
+		if len(c.Schema.Registry.Headers) > 0 {
+			schemaConfig.Registry.Headers = make(map[string]string, len(c.Schema.Registry.Headers))
+			for name := range c.Schema.Registry.Headers {
+				schemaConfig.Registry.Headers[name] = redactedValue
+			}
+		}
+		redacted.Schema = &schemaConfig
+	}
+
+	return &redacted
+}
+
+// Effective returns the redacted configuration keyed the way the YAML files
+// are, for reporting what the gateway actually runs with
+func (c *Config) Effective() (map[string]interface{}, error) {
+	data, err := yaml.Marshal(c.Redacted())
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode configuration: %w", err)
+	}
+	effective := make(map[string]interface{})
+	if err := yaml.Unmarshal(data, &effective); err != nil {
+		return nil, fmt.Errorf("failed to decode configuration: %w", err)
+	}
+	return effective, nil
+}
+
+// redactConnectionString hides the password of a URL or key=value
+// connection string
+func redactConnectionString(connection string) string {
+	if strings.Contains(connection, "://") {
+		return redactURL(connection)
+	}
+	return connectionPasswordRegex.ReplaceAllString(connection, "${1}"+redactedValue)
+}
+
+// redactURL hides the password in a URL's user info and every query value,
+// which often carry tokens
+func redactURL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil {
+		if raw == "" {
+			return raw
+		}
+		return redactedValue
+	}
+	if u.User != nil {
+		if _, ok := u.User.Password(); ok {
+			u.User = url.UserPassword(u.User.Username(), redactedValue)
+		}
+	}
+	if u.RawQuery != "" {
+		query := u.Query()
+		for key := range query {
+			query[key] = []string{redactedValue}
+		}
+		u.RawQuery = query.Encode()
+	}
+	return u.String()
+}
@@ -0,0 +1,176 @@
+/*
+ * Copyright 2026 Cong Wang
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/amtp-protocol/agentry/internal/schema"
+	"gopkg.in/yaml.v3"
+)
+
+func writeConfigFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+}
+
+func TestEnvironmentFile(t *testing.T) {
+	tests := []struct {
+		file, env, expected string
+	}{
+		{"config.yaml", "prod", "config.prod.yaml"},
+		{"/etc/agentry/gateway.yml", "staging", "/etc/agentry/gateway.staging.yml"},
+		{"config", "dev", "config.dev"},
+	}
+	for _, tt := range tests {
+		if got := environmentFile(tt.file, tt.env); got != tt.expected {
+			t.Errorf("environmentFile(%q, %q) = %q, want %q", tt.file, tt.env, got, tt.expected)
+		}
+	}
+}
+
+func TestLoad_EnvironmentLayers(t *testing.T) {
+	dir := t.TempDir()
+	base := filepath.Join(dir, "config.yaml")
+	writeConfigFile(t, base, `
+server:
+  domain: base.example.com
+  address: ":8080"
+tls:
+  enabled: false
+message:
+  max_attachments: 10
+  max_reply_depth: 20
+auth:
+  methods: ["apikey", "mtls"]
+dns:
+  mock_records:
+    base.example.com: "v=amtp1;gateway=https://base.example.com"
+`)
+	writeConfigFile(t, filepath.Join(dir, "config.prod.yaml"), `
+server:
+  domain: prod.example.com
+message:
+  max_attachments: 50
+auth:
+  methods: ["mtls"]
+dns:
+  mock_records:
+    prod.example.com: "v=amtp1;gateway=https://prod.example.com"
+`)
+
+	os.Setenv(EnvironmentVariable, "prod")
+	defer os.Unsetenv(EnvironmentVariable)
+	os.Setenv("AMTP_MESSAGE_MAX_REPLY_DEPTH", "30")
+	defer os.Unsetenv("AMTP_MESSAGE_MAX_REPLY_DEPTH")
+
+	cfg, err := Load(base, "")
+	if err != nil {
+		t.Fatalf("Failed to load layered config: %v", err)
+	}
+
+	// The override file wins over the base file, which wins over defaults
+	if cfg.Server.Domain != "prod.example.com" || cfg.Server.Address != ":8080" {
+		t.Errorf("Expected the override domain and base address, got %q and %q", cfg.Server.Domain, cfg.Server.Address)
+	}
+	if cfg.Message.MaxAttachments != 50 {
+		t.Errorf("Expected max attachments 50 from the override, got %d", cfg.Message.MaxAttachments)
+	}
+	if cfg.Message.MaxSize != 10*1024*1024 {
+		t.Errorf("Expected the default max size, got %d", cfg.Message.MaxSize)
+	}
+	// Environment variables win over both files
+	if cfg.Message.MaxReplyDepth != 30 {
+		t.Errorf("Expected max reply depth 30 from the environment, got %d", cfg.Message.MaxReplyDepth)
+	}
+	// Lists are replaced, maps are merged
+	if len(cfg.Auth.Methods) != 1 || cfg.Auth.Methods[0] != "mtls" {
+		t.Errorf("Expected the override list to replace the base list, got %v", cfg.Auth.Methods)
+	}
+	if _, ok := cfg.DNS.MockRecords["base.example.com"]; !ok {
+		t.Errorf("Expected base mock records to be kept, got %v", cfg.DNS.MockRecords)
+	}
+	if _, ok := cfg.DNS.MockRecords["prod.example.com"]; !ok {
+		t.Errorf("Expected override mock records to be added, got %v", cfg.DNS.MockRecords)
+	}
+
+	if cfg.Environment != "prod" || len(cfg.Files) != 2 || cfg.Files[1] != filepath.Join(dir, "config.prod.yaml") {
+		t.Errorf("Unexpected sources: environment %q, files %v", cfg.Environment, cfg.Files)
+	}
+
+	// A missing override file is an error, not a silent fallback to the base
+	os.Setenv(EnvironmentVariable, "staging")
+	if _, err := Load(base, ""); err == nil || !strings.Contains(err.Error(), "staging") {
+		t.Errorf("Expected a missing staging file to fail, got %v", err)
+	}
+	os.Setenv(EnvironmentVariable, "../prod")
+	if _, err := Load(base, ""); err == nil {
+		t.Error("Expected an environment with a path separator to be rejected")
+	}
+
+	// Without an environment only the base file is loaded
+	os.Unsetenv(EnvironmentVariable)
+	cfg, err = Load(base, "")
+	if err != nil {
+		t.Fatalf("Failed to load base config: %v", err)
+	}
+	if cfg.Server.Domain != "base.example.com" || cfg.Environment != "" || len(cfg.Files) != 1 {
+		t.Errorf("Expected only the base file, got domain %q and files %v", cfg.Server.Domain, cfg.Files)
+	}
+}
+
+func TestConfig_Redacted(t *testing.T) {
+	cfg := getDefaultConfig()
+	cfg.Auth.APIKeySalt = "pepper"
+	cfg.Storage.Database.ConnectionString = "postgres://agentry:hunter2@db:5432/agentry?sslmode=disable"
+	cfg.Message.AcceptHook.URL = "https://hook.example.com/check?token=abc"
+	cfg.Schema = &schema.ManagerConfig{}
+	cfg.Schema.Registry.AuthToken = "registry-token"
+	cfg.Schema.Registry.Headers = map[string]string{"Authorization": "Bearer abc"}
+
+	redacted := cfg.Redacted()
+	effective, err := redacted.Effective()
+	if err != nil {
+		t.Fatalf("Failed to build the effective config: %v", err)
+	}
+	data, err := yaml.Marshal(effective)
+	if err != nil {
+		t.Fatalf("Failed to encode the effective config: %v", err)
+	}
+	for _, secret := range []string{"pepper", "hunter2", "token=abc", "registry-token", "Bearer abc"} {
+		if strings.Contains(string(data), secret) {
+			t.Errorf("Expected %q to be redacted", secret)
+		}
+	}
+	if !strings.Contains(redacted.Storage.Database.ConnectionString, "agentry:%5BREDACTED%5D@db:5432") {
+		t.Errorf("Expected the user and host to be kept, got %q", redacted.Storage.Database.ConnectionString)
+	}
+
+	// The original is left alone
+	if cfg.Auth.APIKeySalt != "pepper" || cfg.Schema.Registry.Headers["Authorization"] != "Bearer abc" {
+		t.Error("Expected the original configuration to be unchanged")
+	}
+
+	if got := redactConnectionString("host=db user=agentry password='s3cret word' dbname=agentry"); got != "host=db user=agentry password=[REDACTED] dbname=agentry" {
+		t.Errorf("Unexpected key=value redaction: %q", got)
+	}
+}
@@ -19,6 +19,7 @@ package discovery
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net"
 	"net/http"
@@ -41,6 +42,11 @@ type AMTPCapabilities struct {
 	Domain       string        `json:"domain,omitempty"`
 	DiscoveredAt time.Time     `json:"discovered_at"`
 	TTL          time.Duration `json:"ttl"`
+
+	// Only reported by a gateway describing itself, not carried in DNS
+	Coordination       []string `json:"coordination,omitempty"`
+	MaxAttachments     int      `json:"max_attachments,omitempty"`
+	MaxAttachmentBytes int64    `json:"max_attachment_bytes,omitempty"`
 }
 
 // Agent represents an agent in the agent discovery response
@@ -64,6 +70,7 @@ type AgentDiscoveryResponse struct {
 type Discovery struct {
 	resolver   *net.Resolver
 	cache      map[string]*cacheEntry
+	keys       map[string]*keyCacheEntry
 	cacheMutex sync.RWMutex
 	timeout    time.Duration
 	defaultTTL time.Duration
@@ -74,12 +81,16 @@ type cacheEntry struct {
 	expiresAt    time.Time
 }
 
-// NewDiscovery creates a new discovery service
+// NewDiscovery creates a new discovery service. Every DNS lookup is bounded
+// by timeout, so a slow resolver fails deliveries fast instead of stalling
+// them. With resolvers, the first one is queried instead of the system's DNS
+// servers; it is host:port, or just the host for port 53.
 func NewDiscovery(timeout, defaultTTL time.Duration, resolvers []string) *Discovery {
 	var resolver *net.Resolver
 
 	if len(resolvers) > 0 {
 		// Use custom DNS resolver
+		server := resolverAddress(resolvers[0])
 		resolver = &net.Resolver{
 			PreferGo: true,
 			Dial: func(ctx context.Context, network, address string) (net.Conn, error) {
@@ -87,7 +98,7 @@ func NewDiscovery(timeout, defaultTTL time.Duration, resolvers []string) *Discov
 					Timeout: timeout,
 				}
 				// Use the first custom resolver instead of the default
-				return d.DialContext(ctx, network, resolvers[0])
+				return d.DialContext(ctx, network, server)
 			},
 		}
 	} else {
@@ -98,11 +109,29 @@ func NewDiscovery(timeout, defaultTTL time.Duration, resolvers []string) *Discov
 	return &Discovery{
 		resolver:   resolver,
 		cache:      make(map[string]*cacheEntry),
+		keys:       make(map[string]*keyCacheEntry),
 		timeout:    timeout,
 		defaultTTL: defaultTTL,
 	}
 }
 
+// resolverAddress adds the DNS port to a resolver given without one
+func resolverAddress(resolver string) string {
+	resolver = strings.TrimSpace(resolver)
+	if _, _, err := net.SplitHostPort(resolver); err == nil {
+		return resolver
+	}
+	return net.JoinHostPort(strings.Trim(resolver, "[]"), "53")
+}
+
+// lookupContext bounds a single DNS lookup by the configured timeout
+func (d *Discovery) lookupContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if d.timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, d.timeout)
+}
+
 // MockDiscovery provides a mock DNS discovery service for development/testing
 type MockDiscovery struct {
 	records    map[string]string
@@ -122,6 +151,17 @@ func NewMockDiscovery(mockRecords map[string]string, defaultTTL time.Duration) *
 
 // DiscoverCapabilities discovers AMTP capabilities using mock records
 func (m *MockDiscovery) DiscoverCapabilities(ctx context.Context, domain string) (*AMTPCapabilities, error) {
+	// A per-request override wins over the records and is never cached, so it
+	// cannot leak into other requests
+	if gateway, ok := overrideFor(ctx, domain); ok {
+		return &AMTPCapabilities{
+			Version:      "1.0",
+			Gateway:      gateway,
+			DiscoveredAt: time.Now(),
+			TTL:          m.defaultTTL,
+		}, nil
+	}
+
 	// Check cache first
 	if cached := m.getCached(domain); cached != nil {
 		return cached, nil
@@ -142,67 +182,7 @@ func (m *MockDiscovery) DiscoverCapabilities(ctx context.Context, domain string)
 
 // parseAMTPRecord parses an AMTP DNS TXT record (reused from Discovery)
 func (m *MockDiscovery) parseAMTPRecord(record string) *AMTPCapabilities {
-	// Clean up the record - remove extra quotes that may be added by DNS servers
-	record = strings.Trim(record, "\"")
-
-	// AMTP TXT record format: "v=amtp1;gateway=https://...;auth=...;max-size=..."
-	if !strings.HasPrefix(record, "v=amtp") {
-		return nil
-	}
-
-	capabilities := &AMTPCapabilities{}
-	parts := strings.Split(record, ";")
-
-	for _, part := range parts {
-		kv := strings.SplitN(part, "=", 2)
-		if len(kv) != 2 {
-			continue
-		}
-
-		key := strings.TrimSpace(kv[0])
-		value := strings.TrimSpace(kv[1])
-
-		switch key {
-		case "v":
-			if value == "amtp1" {
-				capabilities.Version = "1.0"
-			} else {
-				return nil // Unsupported version
-			}
-
-		case "gateway":
-			capabilities.Gateway = value
-
-		case "auth":
-			if value != "" {
-				capabilities.Auth = strings.Split(value, ",")
-				// Trim whitespace from each auth method
-				for i, auth := range capabilities.Auth {
-					capabilities.Auth[i] = strings.TrimSpace(auth)
-				}
-			}
-
-		case "max-size":
-			if size, err := strconv.ParseInt(value, 10, 64); err == nil {
-				capabilities.MaxSize = size
-			}
-
-		case "features":
-			if value != "" {
-				capabilities.Features = strings.Split(value, ",")
-				// Trim whitespace from each feature
-				for i, feature := range capabilities.Features {
-					capabilities.Features[i] = strings.TrimSpace(feature)
-				}
-			}
-		}
-	}
-
-	// Validate required fields
-	if capabilities.Version == "" || capabilities.Gateway == "" {
-		return nil
-	}
-
+	capabilities, _ := parseRecord(record)
 	return capabilities
 }
 
@@ -342,13 +322,20 @@ func (d *Discovery) DiscoverCapabilities(ctx context.Context, domain string) (*A
 		return capabilities, nil
 	}
 
+	// A lookup that ran out of time says nothing about the domain
+	var dnsErr *net.DNSError
+	if ctx.Err() != nil || (errors.As(err, &dnsErr) && dnsErr.IsTimeout) {
+		return nil, fmt.Errorf("DNS lookup for %s did not complete: %w", domain, err)
+	}
 	return nil, fmt.Errorf("no AMTP capabilities found for domain %s", domain)
 }
 
 // discoverViaDNS discovers capabilities via DNS TXT records
 func (d *Discovery) discoverViaDNS(ctx context.Context, domain string) (*AMTPCapabilities, error) {
 	// Query _amtp.{domain} TXT record
-	txtRecords, err := d.resolver.LookupTXT(ctx, "_amtp."+domain)
+	lookupCtx, cancel := d.lookupContext(ctx)
+	defer cancel()
+	txtRecords, err := d.resolver.LookupTXT(lookupCtx, "_amtp."+domain)
 	if err != nil {
 		return nil, fmt.Errorf("DNS TXT lookup failed: %w", err)
 	}
@@ -367,12 +354,18 @@ func (d *Discovery) discoverViaDNS(ctx context.Context, domain string) (*AMTPCap
 
 // parseAMTPRecord parses an AMTP DNS TXT record
 func (d *Discovery) parseAMTPRecord(record string) *AMTPCapabilities {
+	capabilities, _ := parseRecord(record)
+	return capabilities
+}
+
+// parseRecord parses an AMTP DNS TXT record, saying why it was rejected
+func parseRecord(record string) (*AMTPCapabilities, error) {
 	// Clean up the record - remove extra quotes that may be added by DNS servers
 	record = strings.Trim(record, "\"")
 
 	// AMTP TXT record format: "v=amtp1;gateway=https://...;auth=...;max-size=..."
 	if !strings.HasPrefix(record, "v=amtp") {
-		return nil
+		return nil, fmt.Errorf("not an AMTP record, expected it to start with v=amtp")
 	}
 
 	capabilities := &AMTPCapabilities{}
@@ -392,7 +385,7 @@ func (d *Discovery) parseAMTPRecord(record string) *AMTPCapabilities {
 			if value == "amtp1" {
 				capabilities.Version = "1.0"
 			} else {
-				return nil // Unsupported version
+				return nil, fmt.Errorf("unsupported version %q", value)
 			}
 
 		case "gateway":
@@ -424,16 +417,21 @@ func (d *Discovery) parseAMTPRecord(record string) *AMTPCapabilities {
 	}
 
 	// Validate required fields
-	if capabilities.Version == "" || capabilities.Gateway == "" {
-		return nil
+	if capabilities.Version == "" {
+		return nil, fmt.Errorf("missing version")
+	}
+	if capabilities.Gateway == "" {
+		return nil, fmt.Errorf("missing gateway")
 	}
 
-	return capabilities
+	return capabilities, nil
 }
 
 // DiscoverMXRecords discovers MX records for SMTP fallback
 func (d *Discovery) DiscoverMXRecords(ctx context.Context, domain string) ([]*net.MX, error) {
-	mxRecords, err := d.resolver.LookupMX(ctx, domain)
+	lookupCtx, cancel := d.lookupContext(ctx)
+	defer cancel()
+	mxRecords, err := d.resolver.LookupMX(lookupCtx, domain)
 	if err != nil {
 		return nil, fmt.Errorf("MX lookup failed: %w", err)
 	}
@@ -478,6 +476,7 @@ func (d *Discovery) ClearCache() {
 	defer d.cacheMutex.Unlock()
 
 	d.cache = make(map[string]*cacheEntry)
+	d.keys = make(map[string]*keyCacheEntry)
 }
 
 // ExtractDomain extracts domain from an email address
@@ -524,8 +523,13 @@ func ValidateGatewayURL(url string, allowHTTP bool) error {
 
 // HasAgentDiscovery checks if the capabilities support agent discovery
 func (c *AMTPCapabilities) HasAgentDiscovery() bool {
-	for _, feature := range c.Features {
-		if feature == "agent-discovery" {
+	return c.HasFeature("agent-discovery")
+}
+
+// HasFeature checks if the capabilities advertise a feature
+func (c *AMTPCapabilities) HasFeature(feature string) bool {
+	for _, f := range c.Features {
+		if f == feature {
 			return true
 		}
 	}
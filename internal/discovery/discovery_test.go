@@ -20,6 +20,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"net"
 	"net/http"
 	"net/http/httptest"
 	"strings"
@@ -626,3 +627,47 @@ func TestDiscoverAgents_NoCapabilities(t *testing.T) {
 		t.Errorf("Expected capabilities error, got: %v", err)
 	}
 }
+
+func TestResolverAddress(t *testing.T) {
+	tests := map[string]string{
+		"8.8.8.8":        "8.8.8.8:53",
+		" 1.1.1.1:5353 ": "1.1.1.1:5353",
+		"::1":            "[::1]:53",
+		"[::1]":          "[::1]:53",
+		"[::1]:53":       "[::1]:53",
+	}
+	for input, expected := range tests {
+		if got := resolverAddress(input); got != expected {
+			t.Errorf("resolverAddress(%q) = %q, want %q", input, got, expected)
+		}
+	}
+}
+
+func TestDiscoverCapabilities_LookupTimeout(t *testing.T) {
+	// A resolver that never answers
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer conn.Close()
+
+	discovery := NewDiscovery(200*time.Millisecond, 5*time.Minute, []string{conn.LocalAddr().String()})
+
+	start := time.Now()
+	_, err = discovery.DiscoverCapabilities(context.Background(), "example.com")
+	if err == nil {
+		t.Fatal("Expected the lookup to fail")
+	}
+	if !strings.Contains(err.Error(), "did not complete") {
+		t.Errorf("Expected a timeout error, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("Expected the lookup to stop after the timeout, took %v", elapsed)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := discovery.DiscoverCapabilities(ctx, "example.org"); err == nil || !strings.Contains(err.Error(), "did not complete") {
+		t.Errorf("Expected a cancelled lookup error, got %v", err)
+	}
+}
@@ -0,0 +1,132 @@
+/*
+ * Copyright 2026 Cong Wang
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package discovery
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// RecordResult is one TXT record published for a domain and how it parsed
+type RecordResult struct {
+	Record       string            `json:"record"`
+	Capabilities *AMTPCapabilities `json:"capabilities,omitempty"`
+	Error        string            `json:"error,omitempty"`
+}
+
+// Inspection explains a discovery lookup for troubleshooting. Capabilities
+// is what deliveries to the domain use; Records is only filled in by a live
+// lookup.
+type Inspection struct {
+	Domain       string            `json:"domain"`
+	Cached       bool              `json:"cached"`
+	Capabilities *AMTPCapabilities `json:"capabilities,omitempty"`
+	Records      []RecordResult    `json:"records"`
+	Error        string            `json:"error,omitempty"`
+}
+
+// Inspector is implemented by discovery services that can explain a lookup
+type Inspector interface {
+	// Inspect looks up domain, from the cache unless fresh is set. A live
+	// lookup that finds a valid record refreshes the cache, so a corrected
+	// record takes effect at once.
+	Inspect(ctx context.Context, domain string, fresh bool) *Inspection
+}
+
+// Ensure both discovery services can be inspected
+var (
+	_ Inspector = (*Discovery)(nil)
+	_ Inspector = (*MockDiscovery)(nil)
+)
+
+// Inspect looks up the _amtp TXT records of domain
+func (d *Discovery) Inspect(ctx context.Context, domain string, fresh bool) *Inspection {
+	if !fresh {
+		if cached := d.getCached(domain); cached != nil {
+			return &Inspection{Domain: domain, Cached: true, Capabilities: cached, Records: []RecordResult{}}
+		}
+	}
+
+	lookupCtx, cancel := d.lookupContext(ctx)
+	defer cancel()
+	txtRecords, err := d.resolver.LookupTXT(lookupCtx, "_amtp."+domain)
+	if err != nil {
+		return &Inspection{
+			Domain:  domain,
+			Records: []RecordResult{},
+			Error:   fmt.Sprintf("DNS TXT lookup failed: %v", err),
+		}
+	}
+
+	inspection := inspectRecords(domain, txtRecords, d.defaultTTL)
+	if inspection.Capabilities != nil {
+		d.cacheCapabilities(domain, inspection.Capabilities)
+	}
+	return inspection
+}
+
+// Inspect looks up the mock record of domain. Per-request overrides are
+// ignored; they are not what other requests would see.
+func (m *MockDiscovery) Inspect(ctx context.Context, domain string, fresh bool) *Inspection {
+	if !fresh {
+		if cached := m.getCached(domain); cached != nil {
+			return &Inspection{Domain: domain, Cached: true, Capabilities: cached, Records: []RecordResult{}}
+		}
+	}
+
+	record, exists := m.records[domain]
+	if !exists {
+		return &Inspection{
+			Domain:  domain,
+			Records: []RecordResult{},
+			Error:   fmt.Sprintf("no mock record for domain %s", domain),
+		}
+	}
+
+	inspection := inspectRecords(domain, []string{record}, m.defaultTTL)
+	if inspection.Capabilities != nil {
+		m.cacheCapabilities(domain, inspection.Capabilities)
+	}
+	return inspection
+}
+
+// inspectRecords parses every record; the first valid one is used, as in
+// DiscoverCapabilities
+func inspectRecords(domain string, records []string, ttl time.Duration) *Inspection {
+	inspection := &Inspection{Domain: domain, Records: make([]RecordResult, 0, len(records))}
+	for _, record := range records {
+		result := RecordResult{Record: record}
+		capabilities, err := parseRecord(record)
+		if err != nil {
+			result.Error = err.Error()
+		} else {
+			capabilities.DiscoveredAt = time.Now()
+			capabilities.TTL = ttl
+			result.Capabilities = capabilities
+			if inspection.Capabilities == nil {
+				inspection.Capabilities = capabilities
+			}
+		}
+		inspection.Records = append(inspection.Records, result)
+	}
+
+	if inspection.Capabilities == nil {
+		inspection.Error = "no valid AMTP TXT record found"
+	}
+	return inspection
+}
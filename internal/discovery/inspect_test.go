@@ -0,0 +1,96 @@
+/*
+ * Copyright 2026 Cong Wang
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package discovery
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestInspectRecords(t *testing.T) {
+	inspection := inspectRecords("example.com", []string{
+		"v=spf1 -all",
+		"v=amtp2;gateway=https://old.example.com",
+		"v=amtp1",
+		"v=amtp1;gateway=https://a.example.com",
+		"v=amtp1;gateway=https://b.example.com",
+	}, time.Minute)
+
+	if inspection.Error != "" {
+		t.Errorf("Expected no error, got %q", inspection.Error)
+	}
+	if inspection.Capabilities == nil || inspection.Capabilities.Gateway != "https://a.example.com" {
+		t.Fatalf("Expected the first valid record to be used, got %+v", inspection.Capabilities)
+	}
+	if inspection.Capabilities.TTL != time.Minute {
+		t.Errorf("Expected TTL %v, got %v", time.Minute, inspection.Capabilities.TTL)
+	}
+
+	errs := []string{"not an AMTP record", "unsupported version", "missing gateway", "", ""}
+	if len(inspection.Records) != len(errs) {
+		t.Fatalf("Expected %d records, got %d", len(errs), len(inspection.Records))
+	}
+	for i, record := range inspection.Records {
+		if errs[i] == "" {
+			if record.Error != "" || record.Capabilities == nil {
+				t.Errorf("Expected record %d to parse, got %+v", i, record)
+			}
+			continue
+		}
+		if !strings.Contains(record.Error, errs[i]) || record.Capabilities != nil {
+			t.Errorf("Expected record %d to fail with %q, got %+v", i, errs[i], record)
+		}
+	}
+
+	if inspection := inspectRecords("example.com", []string{"v=amtp1"}, time.Minute); inspection.Error == "" {
+		t.Error("Expected an error when no record is valid")
+	}
+}
+
+func TestMockDiscovery_Inspect(t *testing.T) {
+	records := map[string]string{"example.com": "v=amtp1;gateway=https://a.example.com"}
+	m := NewMockDiscovery(records, time.Minute)
+	ctx := context.Background()
+
+	inspection := m.Inspect(ctx, "example.com", false)
+	if inspection.Cached || inspection.Capabilities == nil || len(inspection.Records) != 1 {
+		t.Fatalf("Expected a live lookup, got %+v", inspection)
+	}
+
+	inspection = m.Inspect(ctx, "example.com", false)
+	if !inspection.Cached || inspection.Capabilities.Gateway != "https://a.example.com" {
+		t.Fatalf("Expected a cached result, got %+v", inspection)
+	}
+
+	// A fresh lookup sees the changed record and replaces the cached one
+	records["example.com"] = "v=amtp1;gateway=https://b.example.com"
+	inspection = m.Inspect(ctx, "example.com", true)
+	if inspection.Cached || inspection.Capabilities.Gateway != "https://b.example.com" {
+		t.Fatalf("Expected a live lookup of the new record, got %+v", inspection)
+	}
+	if capabilities, err := m.DiscoverCapabilities(ctx, "example.com"); err != nil || capabilities.Gateway != "https://b.example.com" {
+		t.Errorf("Expected the cache to be refreshed, got %+v, %v", capabilities, err)
+	}
+
+	// Overrides only apply to the request that carries them
+	overridden := WithOverrides(ctx, map[string]string{"unknown.com": "https://c.example.com"})
+	if inspection := m.Inspect(overridden, "unknown.com", true); inspection.Error == "" || inspection.Capabilities != nil {
+		t.Errorf("Expected overrides to be ignored, got %+v", inspection)
+	}
+}
@@ -0,0 +1,166 @@
+/*
+ * Copyright 2026 Cong Wang
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package discovery
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// DefaultKeyID selects a domain's signing key when a signature names none
+const DefaultKeyID = "default"
+
+// keyIDRegex keeps key IDs to a single DNS label
+var keyIDRegex = regexp.MustCompile(`^[A-Za-z0-9_-]{1,63}$`)
+
+type keyCacheEntry struct {
+	key       crypto.PublicKey
+	expiresAt time.Time
+}
+
+// KeyRecordName returns the name of the DNS TXT record publishing a domain's
+// message signing key, {keyid}._amtpkey.{domain}, in the style of DKIM
+func KeyRecordName(domain, keyID string) (string, error) {
+	if keyID == "" {
+		keyID = DefaultKeyID
+	}
+	if !keyIDRegex.MatchString(keyID) {
+		return "", fmt.Errorf("invalid key ID %q", keyID)
+	}
+	if domain == "" {
+		return "", fmt.Errorf("domain is required")
+	}
+	return keyID + "._amtpkey." + strings.ToLower(domain), nil
+}
+
+// parseKeyRecord parses a signing key TXT record of the form
+// "v=amtpkey1;k=rsa;p=<base64 DER SubjectPublicKeyInfo>". k is rsa or ec.
+func parseKeyRecord(record string) (crypto.PublicKey, error) {
+	record = strings.Trim(record, "\"")
+	if !strings.HasPrefix(record, "v=amtpkey") {
+		return nil, fmt.Errorf("not an AMTP key record")
+	}
+
+	var version, keyType, encoded string
+	for _, part := range strings.Split(record, ";") {
+		key, value, ok := strings.Cut(part, "=")
+		if !ok {
+			continue
+		}
+		switch strings.TrimSpace(key) {
+		case "v":
+			version = strings.TrimSpace(value)
+		case "k":
+			keyType = strings.TrimSpace(value)
+		case "p":
+			encoded = strings.Join(strings.Fields(value), "")
+		}
+	}
+
+	if version != "amtpkey1" {
+		return nil, fmt.Errorf("unsupported key record version %q", version)
+	}
+	if encoded == "" {
+		return nil, fmt.Errorf("key record has no public key")
+	}
+	der, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("invalid public key encoding: %w", err)
+	}
+	key, err := x509.ParsePKIXPublicKey(der)
+	if err != nil {
+		return nil, fmt.Errorf("invalid public key: %w", err)
+	}
+
+	switch key.(type) {
+	case *rsa.PublicKey:
+		if keyType != "" && keyType != "rsa" {
+			return nil, fmt.Errorf("key record type %q does not match an RSA key", keyType)
+		}
+	case *ecdsa.PublicKey:
+		if keyType != "ec" {
+			return nil, fmt.Errorf("key record type %q does not match an EC key", keyType)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported public key type %T", key)
+	}
+	return key, nil
+}
+
+// DiscoverPublicKey looks up a domain's message signing key via DNS TXT
+func (d *Discovery) DiscoverPublicKey(ctx context.Context, domain, keyID string) (crypto.PublicKey, error) {
+	name, err := KeyRecordName(domain, keyID)
+	if err != nil {
+		return nil, err
+	}
+
+	d.cacheMutex.RLock()
+	entry, exists := d.keys[name]
+	d.cacheMutex.RUnlock()
+	if exists && time.Now().Before(entry.expiresAt) {
+		return entry.key, nil
+	}
+
+	lookupCtx, cancel := d.lookupContext(ctx)
+	defer cancel()
+	txtRecords, err := d.resolver.LookupTXT(lookupCtx, name)
+	if err != nil {
+		return nil, fmt.Errorf("DNS TXT lookup failed: %w", err)
+	}
+
+	lastErr := fmt.Errorf("no AMTP key record found for %s", name)
+	for _, record := range txtRecords {
+		key, err := parseKeyRecord(record)
+		if err != nil {
+			lastErr = fmt.Errorf("invalid key record at %s: %w", name, err)
+			continue
+		}
+
+		d.cacheMutex.Lock()
+		d.keys[name] = &keyCacheEntry{key: key, expiresAt: time.Now().Add(d.defaultTTL)}
+		d.cacheMutex.Unlock()
+		return key, nil
+	}
+	return nil, lastErr
+}
+
+// DiscoverPublicKey looks up a domain's message signing key in the mock
+// records, keyed by the record name
+func (m *MockDiscovery) DiscoverPublicKey(ctx context.Context, domain, keyID string) (crypto.PublicKey, error) {
+	name, err := KeyRecordName(domain, keyID)
+	if err != nil {
+		return nil, err
+	}
+
+	record, exists := m.records[name]
+	if !exists {
+		return nil, fmt.Errorf("no AMTP key record found for %s", name)
+	}
+	key, err := parseKeyRecord(record)
+	if err != nil {
+		return nil, fmt.Errorf("invalid key record at %s: %w", name, err)
+	}
+	return key, nil
+}
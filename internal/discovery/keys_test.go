@@ -0,0 +1,121 @@
+/*
+ * Copyright 2026 Cong Wang
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package discovery
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"testing"
+	"time"
+)
+
+func keyRecord(t *testing.T, keyType string, key interface{}) string {
+	t.Helper()
+	der, err := x509.MarshalPKIXPublicKey(key)
+	if err != nil {
+		t.Fatalf("Failed to marshal public key: %v", err)
+	}
+	return "v=amtpkey1; k=" + keyType + "; p=" + base64.StdEncoding.EncodeToString(der)
+}
+
+func TestKeyRecordName(t *testing.T) {
+	tests := []struct {
+		domain, keyID, expected string
+		valid                   bool
+	}{
+		{"Example.com", "", "default._amtpkey.example.com", true},
+		{"example.com", "2026-q1", "2026-q1._amtpkey.example.com", true},
+		{"example.com", "a.b", "", false},
+		{"example.com", "../x", "", false},
+		{"", "k1", "", false},
+	}
+
+	for _, tt := range tests {
+		name, err := KeyRecordName(tt.domain, tt.keyID)
+		if (err == nil) != tt.valid || name != tt.expected {
+			t.Errorf("KeyRecordName(%q, %q) = %q, %v", tt.domain, tt.keyID, name, err)
+		}
+	}
+}
+
+func TestParseKeyRecord(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Failed to generate RSA key: %v", err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate EC key: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		record string
+		valid  bool
+	}{
+		{"rsa", keyRecord(t, "rsa", &rsaKey.PublicKey), true},
+		{"quoted ec", `"` + keyRecord(t, "ec", &ecKey.PublicKey) + `"`, true},
+		{"ec labeled rsa", keyRecord(t, "rsa", &ecKey.PublicKey), false},
+		{"wrong version", "v=amtpkey2; k=rsa; p=AAAA", false},
+		{"capabilities record", "v=amtp1;gateway=https://amtp.example.com", false},
+		{"missing key", "v=amtpkey1; k=rsa", false},
+		{"bad base64", "v=amtpkey1; k=rsa; p=@@@", false},
+		{"bad key", "v=amtpkey1; k=rsa; p=AAAA", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key, err := parseKeyRecord(tt.record)
+			if tt.valid && (err != nil || key == nil) {
+				t.Errorf("Expected a key, got %v", err)
+			}
+			if !tt.valid && err == nil {
+				t.Error("Expected an error")
+			}
+		})
+	}
+}
+
+func TestMockDiscovery_DiscoverPublicKey(t *testing.T) {
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate EC key: %v", err)
+	}
+	discovery := NewMockDiscovery(map[string]string{
+		"default._amtpkey.example.com": keyRecord(t, "ec", &ecKey.PublicKey),
+		"broken._amtpkey.example.com":  "v=amtpkey1; k=ec",
+	}, time.Minute)
+
+	key, err := discovery.DiscoverPublicKey(context.Background(), "example.com", "")
+	if err != nil {
+		t.Fatalf("Expected the default key, got %v", err)
+	}
+	if !ecKey.PublicKey.Equal(key) {
+		t.Error("Expected the published key")
+	}
+
+	for _, keyID := range []string{"missing", "broken"} {
+		if _, err := discovery.DiscoverPublicKey(context.Background(), "example.com", keyID); err == nil {
+			t.Errorf("Expected an error for key %q", keyID)
+		}
+	}
+}
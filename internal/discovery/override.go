@@ -0,0 +1,73 @@
+/*
+ * Copyright 2026 Cong Wang
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package discovery
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// OverrideHeader maps domains to gateway URLs for a single request when the
+// gateway runs with the test-only discovery override enabled. The value is a
+// comma-separated list of domain=url pairs.
+const OverrideHeader = "X-AMTP-Discovery-Override"
+
+type overridesKey struct{}
+
+// ParseOverrides parses an OverrideHeader value
+func ParseOverrides(value string) (map[string]string, error) {
+	overrides := make(map[string]string)
+	for _, pair := range strings.Split(value, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+
+		domain, gateway, ok := strings.Cut(pair, "=")
+		domain = strings.ToLower(strings.TrimSpace(domain))
+		gateway = strings.TrimSpace(gateway)
+		if !ok || domain == "" {
+			return nil, fmt.Errorf("invalid discovery override %q, expected domain=url", pair)
+		}
+
+		u, err := url.Parse(gateway)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return nil, fmt.Errorf("invalid gateway URL for %s: %q", domain, gateway)
+		}
+		overrides[domain] = gateway
+	}
+
+	if len(overrides) == 0 {
+		return nil, fmt.Errorf("discovery override is empty")
+	}
+	return overrides, nil
+}
+
+// WithOverrides returns a context whose mock discovery lookups resolve the
+// given domains to the given gateway URLs
+func WithOverrides(ctx context.Context, overrides map[string]string) context.Context {
+	return context.WithValue(ctx, overridesKey{}, overrides)
+}
+
+// overrideFor returns the gateway URL the context overrides domain with
+func overrideFor(ctx context.Context, domain string) (string, bool) {
+	overrides, _ := ctx.Value(overridesKey{}).(map[string]string)
+	gateway, ok := overrides[strings.ToLower(domain)]
+	return gateway, ok
+}
@@ -0,0 +1,66 @@
+/*
+ * Copyright 2026 Cong Wang
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package discovery
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestParseOverrides(t *testing.T) {
+	overrides, err := ParseOverrides(" Example.com = http://127.0.0.1:9000 ,other.org=https://gw.other.org/amtp,")
+	if err != nil {
+		t.Fatalf("Expected valid overrides, got %v", err)
+	}
+	if len(overrides) != 2 || overrides["example.com"] != "http://127.0.0.1:9000" || overrides["other.org"] != "https://gw.other.org/amtp" {
+		t.Errorf("Unexpected overrides: %v", overrides)
+	}
+
+	for _, invalid := range []string{"", " , ", "example.com", "=http://127.0.0.1", "example.com=", "example.com=ftp://host", "example.com=http://"} {
+		if _, err := ParseOverrides(invalid); err == nil {
+			t.Errorf("Expected %q to be rejected", invalid)
+		}
+	}
+}
+
+func TestMockDiscovery_Override(t *testing.T) {
+	d := NewMockDiscovery(map[string]string{
+		"example.com": "v=amtp1;gateway=https://amtp.example.com",
+	}, time.Minute)
+	ctx := WithOverrides(context.Background(), map[string]string{
+		"example.com":  "http://127.0.0.1:9000",
+		"unlisted.org": "http://127.0.0.1:9001",
+	})
+
+	capabilities, err := d.DiscoverCapabilities(ctx, "EXAMPLE.com")
+	if err != nil || capabilities.Gateway != "http://127.0.0.1:9000" || capabilities.Version != "1.0" {
+		t.Errorf("Expected the override to win over the mock record, got %+v, %v", capabilities, err)
+	}
+	if capabilities, err := d.DiscoverCapabilities(ctx, "unlisted.org"); err != nil || capabilities.Gateway != "http://127.0.0.1:9001" {
+		t.Errorf("Expected the override for a domain without records, got %+v, %v", capabilities, err)
+	}
+
+	// The override applies to its request only
+	capabilities, err = d.DiscoverCapabilities(context.Background(), "example.com")
+	if err != nil || capabilities.Gateway != "https://amtp.example.com" {
+		t.Errorf("Expected the mock record without an override, got %+v, %v", capabilities, err)
+	}
+	if _, err := d.DiscoverCapabilities(context.Background(), "unlisted.org"); err == nil {
+		t.Error("Expected an overridden domain not to be cached")
+	}
+}
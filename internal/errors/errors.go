@@ -34,6 +34,11 @@ const (
 	ErrInvalidMessageID        ErrorCode = "INVALID_MESSAGE_ID"
 	ErrInvalidRecipient        ErrorCode = "INVALID_RECIPIENT"
 	ErrMessageTooLarge         ErrorCode = "MESSAGE_TOO_LARGE"
+	ErrTooManyAttachments      ErrorCode = "TOO_MANY_ATTACHMENTS"
+	ErrAttachmentsTooLarge     ErrorCode = "ATTACHMENTS_TOO_LARGE"
+	ErrIdempotencyKeyRequired  ErrorCode = "IDEMPOTENCY_KEY_REQUIRED"
+	ErrInvalidIdempotencyKey   ErrorCode = "INVALID_IDEMPOTENCY_KEY"
+	ErrUnsupportedAPIVersion   ErrorCode = "UNSUPPORTED_API_VERSION"
 
 	// Processing errors
 	ErrProcessingFailed        ErrorCode = "PROCESSING_FAILED"
@@ -50,6 +55,9 @@ const (
 	// Delivery errors
 	ErrDeliveryFailed        ErrorCode = "DELIVERY_FAILED"
 	ErrHTTPRequestFailed     ErrorCode = "HTTP_REQUEST_FAILED"
+	ErrConnectionFailed      ErrorCode = "CONNECTION_FAILED"
+	ErrResponseTimeout       ErrorCode = "RESPONSE_TIMEOUT"
+	ErrTargetBlocked         ErrorCode = "TARGET_BLOCKED"
 	ErrRequestCreationFailed ErrorCode = "REQUEST_CREATION_FAILED"
 	ErrResponseReadFailed    ErrorCode = "RESPONSE_READ_FAILED"
 	ErrClientError           ErrorCode = "CLIENT_ERROR"
@@ -62,10 +70,11 @@ const (
 	ErrContextCanceled ErrorCode = "CONTEXT_CANCELED"
 
 	// Authentication and authorization errors
-	ErrUnauthorized       ErrorCode = "UNAUTHORIZED"
-	ErrForbidden          ErrorCode = "FORBIDDEN"
-	ErrInvalidCredentials ErrorCode = "INVALID_CREDENTIALS" // #nosec G101 -- false positive
-	ErrTokenExpired       ErrorCode = "TOKEN_EXPIRED"
+	ErrUnauthorized         ErrorCode = "UNAUTHORIZED"
+	ErrForbidden            ErrorCode = "FORBIDDEN"
+	ErrSenderDomainMismatch ErrorCode = "SENDER_DOMAIN_MISMATCH"
+	ErrInvalidCredentials   ErrorCode = "INVALID_CREDENTIALS" // #nosec G101 -- false positive
+	ErrTokenExpired         ErrorCode = "TOKEN_EXPIRED"
 
 	// Rate limiting errors
 	ErrRateLimitExceeded ErrorCode = "RATE_LIMIT_EXCEEDED"
@@ -169,6 +178,8 @@ func (e *AMTPError) IsRetryable() bool {
 	switch e.Code {
 	case ErrServerError, ErrTimeout, ErrServiceUnavailable:
 		return true
+	case ErrConnectionFailed, ErrResponseTimeout:
+		return true
 	case ErrRateLimitExceeded:
 		return true
 	case ErrHTTPRequestFailed:
@@ -190,15 +201,18 @@ func (e *AMTPError) IsRetryable() bool {
 func (e *AMTPError) GetHTTPStatus() int {
 	switch e.Code {
 	case ErrInvalidRequestFormat, ErrValidationFailed, ErrMessageValidationFailed,
-		ErrInvalidMessageID, ErrInvalidRecipient, ErrMessageTooLarge:
+		ErrInvalidMessageID, ErrInvalidRecipient, ErrMessageTooLarge, ErrInvalidIdempotencyKey:
 		return 400 // Bad Request
 
 	case ErrUnauthorized, ErrInvalidCredentials, ErrTokenExpired:
 		return 401 // Unauthorized
 
-	case ErrForbidden:
+	case ErrForbidden, ErrSenderDomainMismatch:
 		return 403 // Forbidden
 
+	case ErrUnsupportedAPIVersion:
+		return 406 // Not Acceptable
+
 	case ErrMessageNotFound, ErrStatusNotFound:
 		return 404 // Not Found
 
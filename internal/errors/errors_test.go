@@ -195,6 +195,9 @@ func TestIsRetryable(t *testing.T) {
 		{ErrTimeout, nil, true},
 		{ErrServiceUnavailable, nil, true},
 		{ErrRateLimitExceeded, nil, true},
+		{ErrConnectionFailed, nil, true},
+		{ErrResponseTimeout, nil, true},
+		{ErrTargetBlocked, nil, false},
 		{ErrHTTPRequestFailed, fmt.Errorf("timeout"), true},
 		{ErrHTTPRequestFailed, fmt.Errorf("connection refused"), true},
 		{ErrHTTPRequestFailed, fmt.Errorf("no such host"), true},
@@ -232,10 +235,13 @@ func TestGetHTTPStatus(t *testing.T) {
 		{ErrInvalidMessageID, 400},
 		{ErrInvalidRecipient, 400},
 		{ErrMessageTooLarge, 400},
+		{ErrInvalidIdempotencyKey, 400},
 		{ErrUnauthorized, 401},
 		{ErrInvalidCredentials, 401},
 		{ErrTokenExpired, 401},
 		{ErrForbidden, 403},
+		{ErrUnsupportedAPIVersion, 406},
+		{ErrSenderDomainMismatch, 403},
 		{ErrMessageNotFound, 404},
 		{ErrStatusNotFound, 404},
 		{ErrRateLimitExceeded, 429},
@@ -0,0 +1,201 @@
+/*
+ * Copyright 2026 Cong Wang
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package events broadcasts what the gateway does with messages to admins
+// watching it live. It is meant for debugging: events are not stored, and a
+// subscriber that falls behind is dropped instead of slowing the gateway.
+package events
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Event types
+const (
+	MessageAccepted     = "message.accepted"
+	DeliveryAttempt     = "delivery.attempt"
+	DeliveryDelivered   = "delivery.delivered"
+	DeliveryFailed      = "delivery.failed"
+	MessageAcknowledged = "message.acknowledged"
+)
+
+// Event is something that happened to a message, or to one of its
+// recipients when Recipient is set. Message events list all the message's
+// recipients in Recipients instead.
+type Event struct {
+	Type       string    `json:"type"`
+	Time       time.Time `json:"time"`
+	MessageID  string    `json:"message_id"`
+	Sender     string    `json:"sender,omitempty"`
+	Recipient  string    `json:"recipient,omitempty"`
+	Recipients []string  `json:"recipients,omitempty"`
+	Status     string    `json:"status,omitempty"`
+	Attempts   int       `json:"attempts,omitempty"`
+	ErrorCode  string    `json:"error_code,omitempty"`
+	Error      string    `json:"error,omitempty"`
+}
+
+// ErrTooManySubscribers is returned by Subscribe when the hub is full
+var ErrTooManySubscribers = errors.New("too many event subscribers")
+
+// Filter selects the events a subscriber receives. Empty fields match any
+// event; Recipient matches Recipient or any of Recipients.
+type Filter struct {
+	Recipient string
+	MessageID string
+}
+
+// Matches reports whether event passes the filter
+func (f Filter) Matches(event Event) bool {
+	if f.MessageID != "" && event.MessageID != f.MessageID {
+		return false
+	}
+	if f.Recipient == "" || strings.EqualFold(event.Recipient, f.Recipient) {
+		return true
+	}
+	for _, recipient := range event.Recipients {
+		if strings.EqualFold(recipient, f.Recipient) {
+			return true
+		}
+	}
+	return false
+}
+
+// Subscription receives the events matching its filter on Events until it
+// is closed, dropped for falling behind, or the hub is closed
+type Subscription struct {
+	Events <-chan Event
+
+	hub     *Hub
+	events  chan Event
+	filter  Filter
+	dropped bool
+}
+
+// Dropped reports whether the subscription ended because it fell behind.
+// It is only meaningful once Events is closed.
+func (s *Subscription) Dropped() bool {
+	s.hub.mu.Lock()
+	defer s.hub.mu.Unlock()
+	return s.dropped
+}
+
+// Close unsubscribes. It is safe to call more than once.
+func (s *Subscription) Close() {
+	s.hub.mu.Lock()
+	defer s.hub.mu.Unlock()
+	s.hub.remove(s)
+}
+
+// Hub fans events out to a bounded number of subscribers. Publishing never
+// blocks: a subscriber whose buffer is full is dropped. A nil *Hub accepts
+// and discards events, so producers need not check whether one is set.
+type Hub struct {
+	maxSubscribers int
+	buffer         int
+
+	mu          sync.Mutex
+	subscribers map[*Subscription]struct{}
+	closed      bool
+}
+
+// NewHub creates a hub for up to maxSubscribers subscribers, each buffering
+// up to buffer events
+func NewHub(maxSubscribers, buffer int) *Hub {
+	if buffer <= 0 {
+		buffer = 1
+	}
+	return &Hub{
+		maxSubscribers: maxSubscribers,
+		buffer:         buffer,
+		subscribers:    make(map[*Subscription]struct{}),
+	}
+}
+
+// Subscribe starts receiving the events matching filter
+func (h *Hub) Subscribe(filter Filter) (*Subscription, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.closed || len(h.subscribers) >= h.maxSubscribers {
+		return nil, ErrTooManySubscribers
+	}
+
+	events := make(chan Event, h.buffer)
+	sub := &Subscription{Events: events, hub: h, events: events, filter: filter}
+	h.subscribers[sub] = struct{}{}
+	return sub, nil
+}
+
+// Subscribers returns the number of current subscribers
+func (h *Hub) Subscribers() int {
+	if h == nil {
+		return 0
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return len(h.subscribers)
+}
+
+// Publish sends event to every matching subscriber, stamping its time when
+// unset
+func (h *Hub) Publish(event Event) {
+	if h == nil {
+		return
+	}
+	if event.Time.IsZero() {
+		event.Time = time.Now().UTC()
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for sub := range h.subscribers {
+		if !sub.filter.Matches(event) {
+			continue
+		}
+		select {
+		case sub.events <- event:
+		default:
+			sub.dropped = true
+			h.remove(sub)
+		}
+	}
+}
+
+// Close ends every subscription and refuses new ones
+func (h *Hub) Close() {
+	if h == nil {
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.closed = true
+	for sub := range h.subscribers {
+		h.remove(sub)
+	}
+}
+
+// remove must be called with mu held
+func (h *Hub) remove(sub *Subscription) {
+	if _, ok := h.subscribers[sub]; !ok {
+		return
+	}
+	delete(h.subscribers, sub)
+	close(sub.events)
+}
@@ -0,0 +1,137 @@
+/*
+ * Copyright 2026 Cong Wang
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package events
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestFilter_Matches(t *testing.T) {
+	event := Event{Type: DeliveryFailed, MessageID: "m1", Recipient: "bob@example.com"}
+	accepted := Event{Type: MessageAccepted, MessageID: "m1", Recipients: []string{"alice@example.com", "bob@example.com"}}
+
+	tests := []struct {
+		name    string
+		filter  Filter
+		event   Event
+		matches bool
+	}{
+		{"empty filter", Filter{}, event, true},
+		{"message ID", Filter{MessageID: "m1"}, event, true},
+		{"other message ID", Filter{MessageID: "m2"}, event, false},
+		{"recipient ignores case", Filter{Recipient: "Bob@Example.com"}, event, true},
+		{"other recipient", Filter{Recipient: "alice@example.com"}, event, false},
+		{"one of the recipients", Filter{Recipient: "alice@example.com"}, accepted, true},
+		{"both fields", Filter{Recipient: "bob@example.com", MessageID: "m2"}, event, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.filter.Matches(tt.event); got != tt.matches {
+				t.Errorf("Matches() = %v, want %v", got, tt.matches)
+			}
+		})
+	}
+}
+
+func TestHub_Publish(t *testing.T) {
+	hub := NewHub(2, 4)
+	all, err := hub.Subscribe(Filter{})
+	if err != nil {
+		t.Fatalf("Subscribe failed: %v", err)
+	}
+	bob, err := hub.Subscribe(Filter{Recipient: "bob@example.com"})
+	if err != nil {
+		t.Fatalf("Subscribe failed: %v", err)
+	}
+
+	hub.Publish(Event{Type: DeliveryAttempt, MessageID: "m1", Recipient: "alice@example.com"})
+	hub.Publish(Event{Type: DeliveryAttempt, MessageID: "m1", Recipient: "bob@example.com"})
+
+	if len(all.Events) != 2 {
+		t.Errorf("Expected 2 events for the unfiltered subscriber, got %d", len(all.Events))
+	}
+	if len(bob.Events) != 1 {
+		t.Fatalf("Expected 1 event for the filtered subscriber, got %d", len(bob.Events))
+	}
+	if event := <-bob.Events; event.Recipient != "bob@example.com" || event.Time.IsZero() {
+		t.Errorf("Unexpected event: %+v", event)
+	}
+
+	if _, err := hub.Subscribe(Filter{}); !errors.Is(err, ErrTooManySubscribers) {
+		t.Errorf("Expected ErrTooManySubscribers, got %v", err)
+	}
+	bob.Close()
+	bob.Close()
+	if _, ok := <-bob.Events; ok {
+		t.Error("Expected a closed subscription's channel to be closed")
+	}
+	if bob.Dropped() {
+		t.Error("Expected a closed subscription not to be reported dropped")
+	}
+	if _, err := hub.Subscribe(Filter{}); err != nil {
+		t.Errorf("Expected a freed slot to be reusable, got %v", err)
+	}
+}
+
+func TestHub_DropsSlowSubscribers(t *testing.T) {
+	hub := NewHub(1, 2)
+	sub, err := hub.Subscribe(Filter{})
+	if err != nil {
+		t.Fatalf("Subscribe failed: %v", err)
+	}
+
+	// Nobody reads, so the third event overflows the buffer
+	for i := 0; i < 3; i++ {
+		hub.Publish(Event{Type: DeliveryAttempt, MessageID: "m1"})
+	}
+
+	received := 0
+	for range sub.Events {
+		received++
+	}
+	if received != 2 {
+		t.Errorf("Expected the 2 buffered events before the drop, got %d", received)
+	}
+	if !sub.Dropped() {
+		t.Error("Expected the subscription to be reported dropped")
+	}
+	if hub.Subscribers() != 0 {
+		t.Errorf("Expected no subscribers left, got %d", hub.Subscribers())
+	}
+}
+
+func TestHub_Close(t *testing.T) {
+	hub := NewHub(1, 1)
+	sub, err := hub.Subscribe(Filter{})
+	if err != nil {
+		t.Fatalf("Subscribe failed: %v", err)
+	}
+
+	hub.Close()
+	if _, ok := <-sub.Events; ok {
+		t.Error("Expected the subscription to end when the hub closes")
+	}
+	if _, err := hub.Subscribe(Filter{}); !errors.Is(err, ErrTooManySubscribers) {
+		t.Errorf("Expected a closed hub to refuse subscribers, got %v", err)
+	}
+
+	var nilHub *Hub
+	nilHub.Publish(Event{Type: MessageAccepted})
+	nilHub.Close()
+}
@@ -0,0 +1,98 @@
+/*
+ * Copyright 2026 Cong Wang
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package features holds the flags that switch new gateway behaviors on
+// without a redeploy. The flags are read from the configuration at startup
+// and again when the gateway reloads its configuration on SIGHUP, and every
+// flag is off unless configured.
+package features
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync/atomic"
+)
+
+// Feature flags consulted by the gateway
+const (
+	// StrictSchema rejects messages naming a schema the gateway cannot
+	// validate against, as if the schema unavailable mode were strict
+	StrictSchema = "strict_schema"
+
+	// RequiredSignatures rejects unsigned messages from other domains when
+	// signature verification is on, as if it were required
+	RequiredSignatures = "required_signatures"
+
+	// CoordinationFallback delivers coordinated messages immediately, without
+	// coordination, when no workflow engine is configured instead of failing
+	// them
+	CoordinationFallback = "coordination_fallback"
+)
+
+// known lists every flag the gateway consults
+var known = map[string]bool{
+	StrictSchema:         true,
+	RequiredSignatures:   true,
+	CoordinationFallback: true,
+}
+
+var enabled atomic.Pointer[map[string]bool]
+
+// Enabled reports whether the named flag is on
+func Enabled(name string) bool {
+	flags := enabled.Load()
+	return flags != nil && (*flags)[name]
+}
+
+// Set replaces every flag. Flags not in the map are off.
+func Set(flags map[string]bool) {
+	copied := make(map[string]bool, len(flags))
+	for name, on := range flags {
+		if on {
+			copied[name] = true
+		}
+	}
+	enabled.Store(&copied)
+}
+
+// List returns the names of the flags that are on, sorted
+func List() []string {
+	names := []string{}
+	if flags := enabled.Load(); flags != nil {
+		for name := range *flags {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Validate rejects flag names the gateway does not know, which are most
+// likely typos that would otherwise leave a behavior silently off
+func Validate(flags map[string]bool) error {
+	var unknown []string
+	for name := range flags {
+		if !known[name] {
+			unknown = append(unknown, name)
+		}
+	}
+	if len(unknown) == 0 {
+		return nil
+	}
+	sort.Strings(unknown)
+	return fmt.Errorf("unknown feature flags: %s", strings.Join(unknown, ", "))
+}
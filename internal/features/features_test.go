@@ -0,0 +1,65 @@
+/*
+ * Copyright 2026 Cong Wang
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package features
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSet(t *testing.T) {
+	defer Set(nil)
+
+	if Enabled(StrictSchema) {
+		t.Error("Expected flags to be off before they are set")
+	}
+
+	flags := map[string]bool{StrictSchema: true, RequiredSignatures: false, CoordinationFallback: true}
+	Set(flags)
+	if !Enabled(StrictSchema) || Enabled(RequiredSignatures) || !Enabled(CoordinationFallback) {
+		t.Errorf("Unexpected flags after Set: %v", List())
+	}
+	if got, want := List(), []string{CoordinationFallback, StrictSchema}; !reflect.DeepEqual(got, want) {
+		t.Errorf("List() = %v, want %v", got, want)
+	}
+
+	// Later changes to the map must not leak in
+	flags[RequiredSignatures] = true
+	if Enabled(RequiredSignatures) {
+		t.Error("Expected Set to copy the flags")
+	}
+
+	Set(nil)
+	if Enabled(StrictSchema) || len(List()) != 0 {
+		t.Errorf("Expected every flag off, got %v", List())
+	}
+}
+
+func TestValidate(t *testing.T) {
+	if err := Validate(map[string]bool{StrictSchema: true, CoordinationFallback: false}); err != nil {
+		t.Errorf("Expected known flags to validate, got %v", err)
+	}
+	if err := Validate(nil); err != nil {
+		t.Errorf("Expected no flags to validate, got %v", err)
+	}
+
+	err := Validate(map[string]bool{"strict_shcema": true, StrictSchema: true, "beta": false})
+	if err == nil || !strings.Contains(err.Error(), "beta, strict_shcema") {
+		t.Errorf("Expected unknown flags to be listed, got %v", err)
+	}
+}
@@ -0,0 +1,157 @@
+/*
+ * Copyright 2026 Cong Wang
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package inboxtoken mints and verifies short-lived tokens granting access to
+// one agent's inbox, so consumers need not be given the agent's API key.
+// Tokens are JWTs signed with HMAC-SHA256 under a key only the gateway holds.
+package inboxtoken
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ScopeInbox lets the bearer read and acknowledge the agent's inbox
+const ScopeInbox = "inbox"
+
+// MinKeySize is the shortest signing key accepted, the size of the HMAC-SHA256
+// output
+const MinKeySize = 32
+
+var (
+	// ErrMalformed is returned for a token that is not a JWT this package
+	// minted
+	ErrMalformed = errors.New("malformed inbox token")
+	// ErrSignature is returned for a token not signed with the gateway's key
+	ErrSignature = errors.New("invalid inbox token signature")
+	// ErrExpired is returned for a token past its expiry
+	ErrExpired = errors.New("inbox token expired")
+)
+
+// header is the only JOSE header tokens are minted with; verification does
+// not trust the header's alg, so "none" or a different algorithm is refused
+var header = base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"HS256","typ":"JWT"}`))
+
+// Claims are what a token grants
+type Claims struct {
+	Agent     string `json:"sub"`
+	Scope     string `json:"scope"`
+	IssuedAt  int64  `json:"iat"`
+	ExpiresAt int64  `json:"exp"`
+}
+
+// Expiry returns when the token stops being accepted
+func (c *Claims) Expiry() time.Time {
+	return time.Unix(c.ExpiresAt, 0).UTC()
+}
+
+// IssuedBefore reports whether the token was minted before t. Tokens carry
+// whole seconds, so a token minted in the same second as t is not.
+func (c *Claims) IssuedBefore(t time.Time) bool {
+	return c.IssuedAt < t.Unix()
+}
+
+// Signer mints and verifies inbox tokens
+type Signer struct {
+	key []byte
+}
+
+// NewSigner creates a signer from a secret key of at least MinKeySize bytes
+func NewSigner(key []byte) (*Signer, error) {
+	if len(key) < MinKeySize {
+		return nil, fmt.Errorf("inbox token key must be at least %d bytes, got %d", MinKeySize, len(key))
+	}
+	return &Signer{key: append([]byte(nil), key...)}, nil
+}
+
+// LoadSigner reads the signing key from path. Surrounding whitespace, such as
+// a trailing newline, is not part of the key.
+func LoadSigner(path string) (*Signer, error) {
+	data, err := os.ReadFile(filepath.Clean(path))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read inbox token key file: %w", err)
+	}
+	return NewSigner(bytes.TrimSpace(data))
+}
+
+// Mint returns a token granting access to agent's inbox until now plus ttl
+func (s *Signer) Mint(agent string, now time.Time, ttl time.Duration) (string, *Claims, error) {
+	claims := &Claims{
+		Agent:     agent,
+		Scope:     ScopeInbox,
+		IssuedAt:  now.Unix(),
+		ExpiresAt: now.Add(ttl).Unix(),
+	}
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", nil, err
+	}
+
+	signed := header + "." + base64.RawURLEncoding.EncodeToString(payload)
+	return signed + "." + s.sign(signed), claims, nil
+}
+
+// Verify checks token's signature and expiry at now and returns its claims.
+// Whether the claims cover the inbox being accessed is up to the caller.
+func (s *Signer) Verify(token string, now time.Time) (*Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 || parts[0] != header {
+		return nil, ErrMalformed
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, ErrMalformed
+	}
+	expected, _ := base64.RawURLEncoding.DecodeString(s.sign(parts[0] + "." + parts[1]))
+	if !hmac.Equal(signature, expected) {
+		return nil, ErrSignature
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, ErrMalformed
+	}
+	var claims Claims
+	if err := json.Unmarshal(payload, &claims); err != nil || claims.Agent == "" || claims.Scope != ScopeInbox {
+		return nil, ErrMalformed
+	}
+	if !now.Before(claims.Expiry()) {
+		return nil, ErrExpired
+	}
+	return &claims, nil
+}
+
+// IsToken reports whether credential is shaped like a token rather than an
+// API key, so callers know which check applies
+func IsToken(credential string) bool {
+	return strings.HasPrefix(credential, header+".")
+}
+
+func (s *Signer) sign(signed string) string {
+	mac := hmac.New(sha256.New, s.key)
+	mac.Write([]byte(signed))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
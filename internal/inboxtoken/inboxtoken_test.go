@@ -0,0 +1,127 @@
+/*
+ * Copyright 2026 Cong Wang
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package inboxtoken
+
+import (
+	"encoding/base64"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+var testKey = []byte("0123456789abcdef0123456789abcdef")
+
+func TestSigner_MintAndVerify(t *testing.T) {
+	signer, err := NewSigner(testKey)
+	if err != nil {
+		t.Fatalf("NewSigner failed: %v", err)
+	}
+	now := time.Now()
+
+	token, claims, err := signer.Mint("alice@example.com", now, 15*time.Minute)
+	if err != nil {
+		t.Fatalf("Mint failed: %v", err)
+	}
+	if !IsToken(token) || IsToken("amtp_0123456789") {
+		t.Error("Expected IsToken to tell tokens from API keys")
+	}
+	if claims.Expiry().Unix() != now.Add(15*time.Minute).Unix() {
+		t.Errorf("Unexpected expiry %v", claims.Expiry())
+	}
+	if !claims.IssuedBefore(now.Add(time.Second)) || claims.IssuedBefore(now) || claims.IssuedBefore(time.Time{}) {
+		t.Errorf("Unexpected IssuedBefore for a token issued at %v", now)
+	}
+
+	verified, err := signer.Verify(token, now.Add(time.Minute))
+	if err != nil {
+		t.Fatalf("Verify failed: %v", err)
+	}
+	if verified.Agent != "alice@example.com" || verified.Scope != ScopeInbox {
+		t.Errorf("Unexpected claims: %+v", verified)
+	}
+
+	if _, err := signer.Verify(token, now.Add(15*time.Minute)); !errors.Is(err, ErrExpired) {
+		t.Errorf("Expected ErrExpired at the expiry, got %v", err)
+	}
+
+	other, _ := NewSigner([]byte(strings.Repeat("x", MinKeySize)))
+	if _, err := other.Verify(token, now); !errors.Is(err, ErrSignature) {
+		t.Errorf("Expected ErrSignature under another key, got %v", err)
+	}
+}
+
+func TestSigner_VerifyRejectsTampering(t *testing.T) {
+	signer, _ := NewSigner(testKey)
+	now := time.Now()
+	token, _, _ := signer.Mint("alice@example.com", now, time.Hour)
+	parts := strings.Split(token, ".")
+
+	forged := base64.RawURLEncoding.EncodeToString([]byte(`{"sub":"bob@example.com","scope":"inbox","exp":9999999999}`))
+	none := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"none","typ":"JWT"}`))
+
+	tests := []struct {
+		name  string
+		token string
+		err   error
+	}{
+		{"swapped claims", parts[0] + "." + forged + "." + parts[2], ErrSignature},
+		{"alg none", none + "." + parts[1] + ".", ErrMalformed},
+		{"missing signature", parts[0] + "." + parts[1], ErrMalformed},
+		{"not base64", parts[0] + "." + parts[1] + ".!!", ErrMalformed},
+		{"API key", "amtp_0123456789", ErrMalformed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := signer.Verify(tt.token, now); !errors.Is(err, tt.err) {
+				t.Errorf("Expected %v, got %v", tt.err, err)
+			}
+		})
+	}
+}
+
+func TestLoadSigner(t *testing.T) {
+	dir := t.TempDir()
+
+	path := filepath.Join(dir, "inbox-token.key")
+	if err := os.WriteFile(path, append(testKey, '\n'), 0600); err != nil {
+		t.Fatalf("Failed to write key: %v", err)
+	}
+	signer, err := LoadSigner(path)
+	if err != nil {
+		t.Fatalf("LoadSigner failed: %v", err)
+	}
+	fromKey, _ := NewSigner(testKey)
+	token, _, _ := fromKey.Mint("alice@example.com", time.Now(), time.Minute)
+	if _, err := signer.Verify(token, time.Now()); err != nil {
+		t.Errorf("Expected the trailing newline to be ignored, got %v", err)
+	}
+
+	short := filepath.Join(dir, "short.key")
+	if err := os.WriteFile(short, []byte("too short"), 0600); err != nil {
+		t.Fatalf("Failed to write key: %v", err)
+	}
+	if _, err := LoadSigner(short); err == nil {
+		t.Error("Expected a short key to be rejected")
+	}
+	if _, err := LoadSigner(filepath.Join(dir, "missing.key")); err == nil {
+		t.Error("Expected a missing key file to be rejected")
+	}
+}
@@ -0,0 +1,48 @@
+/*
+ * Copyright 2026 Cong Wang
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package kafka produces messages for agents in kafka delivery mode. The
+// Kafka client is only compiled in with the kafka build tag, so gateways
+// that do not deliver to Kafka do not carry the dependency.
+package kafka
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/amtp-protocol/agentry/internal/config"
+	"github.com/amtp-protocol/agentry/internal/processing"
+)
+
+// NewProducer returns a producer for the configured brokers, or nil when no
+// brokers are configured
+func NewProducer(cfg config.KafkaConfig) (processing.KafkaProducer, error) {
+	var brokers []string
+	for _, broker := range cfg.Brokers {
+		if broker = strings.TrimSpace(broker); broker != "" {
+			brokers = append(brokers, broker)
+		}
+	}
+	if len(brokers) == 0 {
+		return nil, nil
+	}
+
+	producer, err := newProducer(brokers, cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create kafka producer: %w", err)
+	}
+	return producer, nil
+}
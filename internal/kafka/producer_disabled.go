@@ -0,0 +1,30 @@
+//go:build !kafka
+
+/*
+ * Copyright 2026 Cong Wang
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package kafka
+
+import (
+	"errors"
+
+	"github.com/amtp-protocol/agentry/internal/config"
+	"github.com/amtp-protocol/agentry/internal/processing"
+)
+
+func newProducer([]string, config.KafkaConfig) (processing.KafkaProducer, error) {
+	return nil, errors.New("this gateway was built without kafka support; rebuild with -tags kafka")
+}
@@ -0,0 +1,33 @@
+//go:build !kafka
+
+/*
+ * Copyright 2026 Cong Wang
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package kafka
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/amtp-protocol/agentry/internal/config"
+)
+
+func TestNewProducer_NotBuilt(t *testing.T) {
+	_, err := NewProducer(config.KafkaConfig{Brokers: []string{"kafka:9092"}})
+	if err == nil || !strings.Contains(err.Error(), "-tags kafka") {
+		t.Errorf("Expected an error pointing at the kafka build tag, got %v", err)
+	}
+}
@@ -0,0 +1,82 @@
+//go:build kafka
+
+/*
+ * Copyright 2026 Cong Wang
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package kafka
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	kafkago "github.com/segmentio/kafka-go"
+
+	"github.com/amtp-protocol/agentry/internal/config"
+	"github.com/amtp-protocol/agentry/internal/processing"
+)
+
+// producer writes each record synchronously, so a delivery only succeeds
+// once every in-sync replica has the record
+type producer struct {
+	writer  *kafkago.Writer
+	timeout time.Duration
+}
+
+func newProducer(brokers []string, cfg config.KafkaConfig) (processing.KafkaProducer, error) {
+	return &producer{
+		writer: &kafkago.Writer{
+			Addr:         kafkago.TCP(brokers...),
+			Balancer:     &kafkago.Hash{},
+			RequiredAcks: kafkago.RequireAll,
+			// Deliveries write one record at a time and wait for it, so
+			// do not hold records back to fill a batch
+			BatchTimeout: 10 * time.Millisecond,
+			WriteTimeout: cfg.Timeout,
+			Transport:    &kafkago.Transport{ClientID: cfg.ClientID},
+		},
+		timeout: cfg.Timeout,
+	}, nil
+}
+
+func (p *producer) Produce(ctx context.Context, record processing.KafkaRecord) error {
+	if p.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, p.timeout)
+		defer cancel()
+	}
+
+	keys := make([]string, 0, len(record.Headers))
+	for key := range record.Headers {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	headers := make([]kafkago.Header, 0, len(keys))
+	for _, key := range keys {
+		headers = append(headers, kafkago.Header{Key: key, Value: []byte(record.Headers[key])})
+	}
+
+	return p.writer.WriteMessages(ctx, kafkago.Message{
+		Topic:   record.Topic,
+		Key:     record.Key,
+		Value:   record.Value,
+		Headers: headers,
+	})
+}
+
+func (p *producer) Close() error {
+	return p.writer.Close()
+}
@@ -0,0 +1,30 @@
+/*
+ * Copyright 2026 Cong Wang
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package kafka
+
+import (
+	"testing"
+
+	"github.com/amtp-protocol/agentry/internal/config"
+)
+
+func TestNewProducer_NoBrokers(t *testing.T) {
+	producer, err := NewProducer(config.KafkaConfig{Brokers: []string{"", " "}})
+	if err != nil || producer != nil {
+		t.Errorf("Expected no producer without brokers, got %v, %v", producer, err)
+	}
+}
@@ -67,6 +67,7 @@ type Logger struct {
 	level     LogLevel
 	component string
 	fields    map[string]interface{}
+	redactor  *Redactor
 }
 
 // contextKey is used for context keys to avoid collisions
@@ -78,30 +79,43 @@ const (
 	userIDKey    contextKey = "user_id"
 )
 
-// NewLogger creates a new logger instance
+// NewLogger creates a new logger instance writing to config.Output. If the
+// output cannot be opened, it logs the error and falls back to stderr.
 func NewLogger(config config.LoggingConfig) *Logger {
-	var writer io.Writer = os.Stdout
-
-	// In production, you might want to write to files or external systems
-	// For now, always use stdout
+	writer, err := openOutput(config)
+	if err != nil {
+		writer = os.Stderr
+	}
 
-	return &Logger{
-		writer: writer,
-		level:  LogLevel(strings.ToLower(config.Level)),
-		fields: make(map[string]interface{}),
+	logger := &Logger{
+		writer:   writer,
+		level:    LogLevel(strings.ToLower(config.Level)),
+		fields:   make(map[string]interface{}),
+		redactor: NewRedactor(config.Redaction),
+	}
+	if err != nil {
+		logger.Error("Failed to open log output, logging to stderr", err)
 	}
+	return logger
 }
 
 // NewNoopLogger creates a logger that discards all output.
 // Useful for tests or when no logger is configured.
 func NewNoopLogger() *Logger {
 	return &Logger{
-		writer: io.Discard,
-		level:  LevelDebug,
-		fields: make(map[string]interface{}),
+		writer:   io.Discard,
+		level:    LevelDebug,
+		fields:   make(map[string]interface{}),
+		redactor: NewRedactor(config.RedactionConfig{}),
 	}
 }
 
+// Writer returns the output the logger writes to, for other loggers such as
+// the HTTP access log to share
+func (l *Logger) Writer() io.Writer {
+	return l.writer
+}
+
 // WithComponent creates a new logger with a component name
 func (l *Logger) WithComponent(component string) *Logger {
 	return &Logger{
@@ -109,6 +123,7 @@ func (l *Logger) WithComponent(component string) *Logger {
 		level:     l.level,
 		component: component,
 		fields:    copyFields(l.fields),
+		redactor:  l.redactor,
 	}
 }
 
@@ -124,6 +139,7 @@ func (l *Logger) WithFields(fields map[string]interface{}) *Logger {
 		level:     l.level,
 		component: l.component,
 		fields:    newFields,
+		redactor:  l.redactor,
 	}
 }
 
@@ -137,6 +153,7 @@ func (l *Logger) WithField(key string, value interface{}) *Logger {
 		level:     l.level,
 		component: l.component,
 		fields:    fields,
+		redactor:  l.redactor,
 	}
 }
 
@@ -147,6 +164,7 @@ func (l *Logger) WithContext(ctx context.Context) *Logger {
 		level:     l.level,
 		component: l.component,
 		fields:    copyFields(l.fields),
+		redactor:  l.redactor,
 	}
 
 	// Extract context values
@@ -337,6 +355,10 @@ func (l *Logger) createEntry(level LogLevel, message string, err error) *LogEntr
 
 // writeEntry writes a log entry to the output
 func (l *Logger) writeEntry(entry *LogEntry) {
+	if l.redactor != nil {
+		entry.Fields = l.redactor.Fields(entry.Fields)
+	}
+
 	data, err := json.Marshal(entry)
 	if err != nil {
 		// Fallback to simple text output if JSON marshaling fails
@@ -0,0 +1,135 @@
+/*
+ * Copyright 2026 Cong Wang
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package logging
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/amtp-protocol/agentry/internal/config"
+)
+
+// openOutput returns the writer selected by cfg.Output
+func openOutput(cfg config.LoggingConfig) (io.Writer, error) {
+	switch output := cfg.Output; {
+	case output == "" || output == config.LogOutputStdout:
+		return os.Stdout, nil
+	case output == config.LogOutputStderr:
+		return os.Stderr, nil
+	case output == config.LogOutputSyslog:
+		return openSyslog()
+	case strings.HasPrefix(output, config.LogOutputFilePrefix):
+		return openRotatingFile(strings.TrimPrefix(output, config.LogOutputFilePrefix), cfg.Rotation)
+	default:
+		return nil, fmt.Errorf("unknown log output %q", output)
+	}
+}
+
+// rotatingFile appends to a file, renaming it to path.1 before a write would
+// grow it beyond maxSize. Older backups shift to path.2 and so on, and the
+// oldest beyond maxFiles is removed.
+type rotatingFile struct {
+	mu       sync.Mutex
+	path     string
+	maxSize  int64
+	maxFiles int
+	file     *os.File
+	size     int64
+}
+
+func openRotatingFile(path string, rotation config.RotationConfig) (*rotatingFile, error) {
+	if path == "" {
+		return nil, fmt.Errorf("log file path is empty")
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create log directory: %w", err)
+	}
+
+	f := &rotatingFile{path: path, maxSize: rotation.MaxSize, maxFiles: rotation.MaxFiles}
+	if err := f.open(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func (f *rotatingFile) open() error {
+	file, err := os.OpenFile(f.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to open log file: %w", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("failed to stat log file: %w", err)
+	}
+	f.file = file
+	f.size = info.Size()
+	return nil
+}
+
+// Write writes p whole to the current file; an entry is never split across
+// files, so a single entry larger than maxSize gets a file of its own
+func (f *rotatingFile) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.maxSize > 0 && f.size > 0 && f.size+int64(len(p)) > f.maxSize {
+		if err := f.rotate(); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := f.file.Write(p)
+	f.size += int64(n)
+	return n, err
+}
+
+func (f *rotatingFile) rotate() error {
+	if err := f.file.Close(); err != nil {
+		return fmt.Errorf("failed to close log file: %w", err)
+	}
+
+	if f.maxFiles > 0 {
+		for i := f.maxFiles - 1; i >= 1; i-- {
+			// Missing backups are expected until maxFiles rotations happened
+			_ = os.Rename(f.backup(i), f.backup(i+1))
+		}
+		if err := os.Rename(f.path, f.backup(1)); err != nil {
+			return fmt.Errorf("failed to rotate log file: %w", err)
+		}
+	} else if err := os.Remove(f.path); err != nil {
+		return fmt.Errorf("failed to rotate log file: %w", err)
+	}
+
+	return f.open()
+}
+
+func (f *rotatingFile) backup(n int) string {
+	return f.path + "." + strconv.Itoa(n)
+}
+
+// Close closes the current file
+func (f *rotatingFile) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.file.Close()
+}
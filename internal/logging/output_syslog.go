@@ -0,0 +1,35 @@
+//go:build !windows && !plan9
+
+/*
+ * Copyright 2026 Cong Wang
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package logging
+
+import (
+	"fmt"
+	"io"
+	"log/syslog"
+)
+
+// openSyslog connects to the local syslog daemon. Entries are sent at info
+// priority; their level stays in the entry itself.
+func openSyslog() (io.Writer, error) {
+	writer, err := syslog.New(syslog.LOG_INFO|syslog.LOG_DAEMON, "agentry")
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to syslog: %w", err)
+	}
+	return writer, nil
+}
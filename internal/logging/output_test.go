@@ -0,0 +1,143 @@
+/*
+ * Copyright 2026 Cong Wang
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package logging
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/amtp-protocol/agentry/internal/config"
+)
+
+func TestOpenOutput(t *testing.T) {
+	for output, want := range map[string]*os.File{"": os.Stdout, "stdout": os.Stdout, "stderr": os.Stderr} {
+		writer, err := openOutput(config.LoggingConfig{Output: output})
+		if err != nil || writer != want {
+			t.Errorf("openOutput(%q) = %v, %v", output, writer, err)
+		}
+	}
+
+	for _, output := range []string{"file:", "kafka"} {
+		if _, err := openOutput(config.LoggingConfig{Output: output}); err == nil {
+			t.Errorf("Expected openOutput(%q) to fail", output)
+		}
+	}
+
+	path := filepath.Join(t.TempDir(), "nested", "agentry.log")
+	writer, err := openOutput(config.LoggingConfig{Output: "file:" + path})
+	if err != nil {
+		t.Fatalf("Expected file output, got %v", err)
+	}
+	defer writer.(*rotatingFile).Close()
+	if _, err := os.Stat(path); err != nil {
+		t.Errorf("Expected the log file to be created: %v", err)
+	}
+}
+
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read %s: %v", path, err)
+	}
+	return string(data)
+}
+
+func TestRotatingFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "agentry.log")
+	if err := os.WriteFile(path, []byte("old\n"), 0o644); err != nil {
+		t.Fatalf("Failed to seed log file: %v", err)
+	}
+
+	f, err := openRotatingFile(path, config.RotationConfig{MaxSize: 10, MaxFiles: 2})
+	if err != nil {
+		t.Fatalf("Failed to open rotating file: %v", err)
+	}
+	defer f.Close()
+
+	// The existing size counts, so "first" no longer fits next to "old"
+	for _, line := range []string{"first\n", "second\n", "third\n", "a much longer entry\n"} {
+		if _, err := f.Write([]byte(line)); err != nil {
+			t.Fatalf("Write failed: %v", err)
+		}
+	}
+
+	want := map[string]string{
+		path:        "a much longer entry\n",
+		path + ".1": "third\n",
+		path + ".2": "second\n",
+	}
+	for file, content := range want {
+		if got := readFile(t, file); got != content {
+			t.Errorf("%s = %q, want %q", filepath.Base(file), got, content)
+		}
+	}
+	if _, err := os.Stat(path + ".3"); !os.IsNotExist(err) {
+		t.Errorf("Expected no more than 2 backups, got %v", err)
+	}
+}
+
+func TestRotatingFile_NoBackups(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "agentry.log")
+	f, err := openRotatingFile(path, config.RotationConfig{MaxSize: 8})
+	if err != nil {
+		t.Fatalf("Failed to open rotating file: %v", err)
+	}
+	defer f.Close()
+
+	f.Write([]byte("first\n"))
+	f.Write([]byte("second\n"))
+
+	if got := readFile(t, path); got != "second\n" {
+		t.Errorf("Expected the file to start over, got %q", got)
+	}
+	if _, err := os.Stat(path + ".1"); !os.IsNotExist(err) {
+		t.Errorf("Expected no backup, got %v", err)
+	}
+}
+
+func TestNewLogger_FileOutput(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "agentry.log")
+	logger := NewLogger(config.LoggingConfig{Level: "info", Output: "file:" + path})
+	defer logger.Writer().(*rotatingFile).Close()
+
+	logger.WithComponent("test").Info("hello")
+
+	var entry LogEntry
+	if err := json.Unmarshal([]byte(strings.TrimSpace(readFile(t, path))), &entry); err != nil {
+		t.Fatalf("Expected a JSON entry in the log file: %v", err)
+	}
+	if entry.Message != "hello" || entry.Component != "test" {
+		t.Errorf("Unexpected entry: %+v", entry)
+	}
+}
+
+func TestNewLogger_OutputFallback(t *testing.T) {
+	// A file where the log directory should be makes the output unusable
+	blocker := filepath.Join(t.TempDir(), "blocker")
+	if err := os.WriteFile(blocker, nil, 0o644); err != nil {
+		t.Fatalf("Failed to create file: %v", err)
+	}
+
+	logger := NewLogger(config.LoggingConfig{Level: "fatal", Output: "file:" + filepath.Join(blocker, "agentry.log")})
+	if logger.Writer() != os.Stderr {
+		t.Errorf("Expected a fallback to stderr, got %T", logger.Writer())
+	}
+}
@@ -0,0 +1,134 @@
+/*
+ * Copyright 2026 Cong Wang
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package logging
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/amtp-protocol/agentry/internal/config"
+)
+
+// RedactedValue replaces every masked value in log output
+const RedactedValue = "[REDACTED]"
+
+// DefaultRedactedHeaders are always masked, whatever the configuration, so
+// credentials never reach the logs
+var DefaultRedactedHeaders = []string{"Authorization", "X-Admin-Key", "X-API-Key", "Cookie"}
+
+// Redactor masks sensitive header values and JSON fields before they are logged
+type Redactor struct {
+	headers map[string]struct{}
+	fields  [][]string
+}
+
+// NewRedactor creates a redactor for the default headers plus the configured
+// header names and JSON field paths. A field path is dot separated from the
+// root of the logged object (e.g. "payload.ssn"); "*" matches any key, and
+// arrays are traversed transparently.
+func NewRedactor(cfg config.RedactionConfig) *Redactor {
+	r := &Redactor{headers: make(map[string]struct{})}
+	for _, name := range append(append([]string{}, DefaultRedactedHeaders...), cfg.Headers...) {
+		if name = strings.TrimSpace(name); name != "" {
+			r.headers[http.CanonicalHeaderKey(name)] = struct{}{}
+		}
+	}
+	for _, path := range cfg.Fields {
+		if path = strings.TrimSpace(path); path != "" {
+			r.fields = append(r.fields, strings.Split(path, "."))
+		}
+	}
+	return r
+}
+
+// Headers returns a flattened copy of the headers with sensitive values masked
+func (r *Redactor) Headers(h http.Header) map[string]string {
+	out := make(map[string]string, len(h))
+	for name, values := range h {
+		if _, sensitive := r.headers[http.CanonicalHeaderKey(name)]; sensitive {
+			out[name] = RedactedValue
+			continue
+		}
+		out[name] = strings.Join(values, ", ")
+	}
+	return out
+}
+
+// JSON returns the document with configured field paths masked. ok is false
+// when data is not valid JSON, in which case it must not be logged verbatim.
+func (r *Redactor) JSON(data []byte) (redacted json.RawMessage, ok bool) {
+	var doc interface{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, false
+	}
+	if len(r.fields) == 0 {
+		return data, true
+	}
+	for _, path := range r.fields {
+		doc = redactPath(doc, path)
+	}
+	out, err := json.Marshal(doc)
+	if err != nil {
+		return nil, false
+	}
+	return out, true
+}
+
+// Fields returns a copy of structured log fields with configured paths masked
+func (r *Redactor) Fields(fields map[string]interface{}) map[string]interface{} {
+	if len(r.fields) == 0 || len(fields) == 0 {
+		return fields
+	}
+
+	// Round-trip through JSON so nested structs, raw payloads and maps are
+	// all addressed by the same paths they have in the log line
+	data, err := json.Marshal(fields)
+	if err != nil {
+		return fields
+	}
+	redacted, ok := r.JSON(data)
+	if !ok {
+		return fields
+	}
+	var out map[string]interface{}
+	if err := json.Unmarshal(redacted, &out); err != nil {
+		return fields
+	}
+	return out
+}
+
+// redactPath masks the value at path within a decoded JSON document
+func redactPath(node interface{}, path []string) interface{} {
+	if len(path) == 0 {
+		return RedactedValue
+	}
+
+	switch v := node.(type) {
+	case map[string]interface{}:
+		for key, child := range v {
+			if path[0] == "*" || key == path[0] {
+				v[key] = redactPath(child, path[1:])
+			}
+		}
+	case []interface{}:
+		for i, child := range v {
+			v[i] = redactPath(child, path)
+		}
+	}
+	return node
+}
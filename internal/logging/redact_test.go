@@ -0,0 +1,103 @@
+/*
+ * Copyright 2026 Cong Wang
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/amtp-protocol/agentry/internal/config"
+)
+
+func TestRedactor_Headers(t *testing.T) {
+	r := NewRedactor(config.RedactionConfig{Headers: []string{"x-session-token"}})
+
+	h := http.Header{}
+	h.Set("Authorization", "Bearer secret")
+	h.Set("X-Admin-Key", "admin-secret")
+	h.Set("X-Session-Token", "session-secret")
+	h.Set("Content-Type", "application/json")
+
+	got := r.Headers(h)
+	for _, name := range []string{"Authorization", "X-Admin-Key", "X-Session-Token"} {
+		if got[name] != RedactedValue {
+			t.Errorf("Expected %s to be redacted, got %q", name, got[name])
+		}
+	}
+	if got["Content-Type"] != "application/json" {
+		t.Errorf("Expected Content-Type to be kept, got %q", got["Content-Type"])
+	}
+}
+
+func TestRedactor_JSON(t *testing.T) {
+	r := NewRedactor(config.RedactionConfig{
+		Fields: []string{"payload.ssn", "attachments.url", "*.token"},
+	})
+
+	in := `{"auth":{"token":"t0k"},"payload":{"ssn":"123-45-6789","name":"Alice"},"attachments":[{"url":"https://a"},{"url":"https://b","size":1}]}`
+	out, ok := r.JSON([]byte(in))
+	if !ok {
+		t.Fatal("Expected valid JSON to be redacted")
+	}
+
+	var doc struct {
+		Auth        map[string]string        `json:"auth"`
+		Payload     map[string]string        `json:"payload"`
+		Attachments []map[string]interface{} `json:"attachments"`
+	}
+	if err := json.Unmarshal(out, &doc); err != nil {
+		t.Fatalf("Failed to decode redacted JSON: %v", err)
+	}
+	if doc.Payload["ssn"] != RedactedValue || doc.Payload["name"] != "Alice" {
+		t.Errorf("Unexpected payload after redaction: %v", doc.Payload)
+	}
+	if doc.Auth["token"] != RedactedValue {
+		t.Errorf("Expected wildcard to redact auth.token, got %q", doc.Auth["token"])
+	}
+	for i, a := range doc.Attachments {
+		if a["url"] != RedactedValue {
+			t.Errorf("Expected attachments[%d].url to be redacted, got %v", i, a["url"])
+		}
+	}
+
+	if _, ok := r.JSON([]byte("not json")); ok {
+		t.Error("Expected invalid JSON to be rejected")
+	}
+}
+
+func TestLogger_RedactsFields(t *testing.T) {
+	var buf bytes.Buffer
+	logger := NewLogger(config.LoggingConfig{
+		Level:     "info",
+		Redaction: config.RedactionConfig{Fields: []string{"payload.ssn"}},
+	})
+	logger.writer = &buf
+
+	logger.WithField("payload", map[string]string{"ssn": "123-45-6789"}).
+		LogMessageProcessing("msg-1", "validate", "failed", nil, errors.New("invalid"))
+
+	if strings.Contains(buf.String(), "123-45-6789") {
+		t.Errorf("Expected payload.ssn to be redacted, got: %s", buf.String())
+	}
+	if !strings.Contains(buf.String(), RedactedValue) {
+		t.Errorf("Expected redacted marker in log, got: %s", buf.String())
+	}
+}
@@ -17,6 +17,7 @@
 package metrics
 
 import (
+	"fmt"
 	"time"
 )
 
@@ -26,19 +27,27 @@ type MetricsProvider interface {
 	RecordHTTPRequest(method, path string, statusCode int, duration time.Duration)
 	IncHTTPRequestsInFlight()
 	DecHTTPRequestsInFlight()
+	SetHTTPRequestsConcurrent(count int)
 
 	// Message processing metrics
-	RecordMessage(status, coordinationType string, duration time.Duration, sizeBytes int64, schema string)
+	RecordMessage(status, coordinationType string, duration time.Duration, size MessageSize, schema string)
 	IncMessagesInFlight()
 	DecMessagesInFlight()
 
 	// Delivery metrics
 	RecordDelivery(status, domain string, duration time.Duration, attempts int)
 	RecordDeliveryRetry(domain, reason string)
+	SetRelaysInFlight(domain string, count int)
 
 	// Discovery metrics
 	RecordDiscovery(domain, method, status string, duration time.Duration, cacheHit bool)
 
+	// Agent registry cache metrics
+	RecordAgentCacheLookup(hit bool)
+
+	// Storage capacity metrics
+	SetStorageStats(totalMessages, inboxMessages int64, oldestUnacknowledgedAge time.Duration)
+
 	// System metrics
 	SetConnectionsActive(count float64)
 	SetMemoryUsage(bytes float64)
@@ -51,12 +60,49 @@ type MetricsProvider interface {
 	ToJSON() ([]byte, error)
 }
 
-// NewMetricsProvider creates a new metrics provider instance
-// Currently returns SimpleMetrics, but can be extended to support other implementations
+// MessageSize breaks a message's size down into the parts that drive storage
+// growth
+type MessageSize struct {
+	Payload     int64 // payload bytes as received
+	Attachments int64 // bytes the attachments declare; stored behind their URLs
+	Envelope    int64 // everything else, including the attachment references
+}
+
+// Total returns the bytes storage persists for the message
+func (s MessageSize) Total() int64 {
+	return s.Payload + s.Envelope
+}
+
+// Metrics sinks selectable with Config.Sink
+const (
+	SinkSimple = "simple" // in-memory metrics served as JSON by /metrics
+	SinkStatsD = "statsd" // SimpleMetrics plus StatsD/DogStatsD over UDP
+)
+
+// Config selects and configures the metrics sink
+type Config struct {
+	Sink   string // SinkSimple when empty
+	StatsD StatsDConfig
+}
+
+// NewMetricsProvider creates the default in-memory metrics provider
 func NewMetricsProvider() MetricsProvider {
 	return NewSimpleMetrics()
 }
 
+// NewMetricsProviderWithConfig creates the metrics provider for the configured
+// sink. Providers that hold resources implement io.Closer.
+func NewMetricsProviderWithConfig(config Config) (MetricsProvider, error) {
+	switch config.Sink {
+	case "", SinkSimple:
+		return NewMetricsProvider(), nil
+	case SinkStatsD:
+		return NewStatsDMetrics(config.StatsD, NewSimpleMetrics())
+	default:
+		return nil, fmt.Errorf("unknown metrics sink: %s", config.Sink)
+	}
+}
+
 // Timer provides a convenient way to time operations
 type Timer struct {
 	start time.Time
@@ -30,27 +30,42 @@ type SimpleMetrics struct {
 	mu sync.RWMutex
 
 	// HTTP metrics
-	httpRequests  map[string]int64
-	httpDurations map[string][]float64
-	httpInFlight  int64
+	httpRequests   map[string]int64
+	httpDurations  map[string][]float64
+	httpInFlight   int64
+	httpConcurrent int64
 
 	// Message processing metrics
 	messages         map[string]int64
 	messageDurations map[string][]float64
 	messagesInFlight int64
 	messageSizes     map[string][]float64
+	payloadSizes     map[string][]float64
+	attachmentSizes  map[string][]float64
+	envelopeSizes    map[string][]float64
 
 	// Delivery metrics
 	deliveries        map[string]int64
 	deliveryDurations map[string][]float64
 	deliveryAttempts  map[string]int64
 	deliveryRetries   map[string]int64
+	relaysInFlight    map[string]int64
 
 	// Discovery metrics
 	discoveries        map[string]int64
 	discoveryDurations map[string][]float64
 	discoveryCacheHits map[string]int64
 
+	// Agent registry cache metrics
+	agentCacheHits   int64
+	agentCacheMisses int64
+
+	// Storage capacity metrics
+	storageTotalMessages    int64
+	storageInboxMessages    int64
+	storageOldestUnackedAge float64
+	storageUpdated          time.Time
+
 	// System metrics
 	connectionsActive float64
 	memoryUsageBytes  float64
@@ -72,10 +87,14 @@ func NewSimpleMetrics() *SimpleMetrics {
 		messages:           make(map[string]int64),
 		messageDurations:   make(map[string][]float64),
 		messageSizes:       make(map[string][]float64),
+		payloadSizes:       make(map[string][]float64),
+		attachmentSizes:    make(map[string][]float64),
+		envelopeSizes:      make(map[string][]float64),
 		deliveries:         make(map[string]int64),
 		deliveryDurations:  make(map[string][]float64),
 		deliveryAttempts:   make(map[string]int64),
 		deliveryRetries:    make(map[string]int64),
+		relaysInFlight:     make(map[string]int64),
 		discoveries:        make(map[string]int64),
 		discoveryDurations: make(map[string][]float64),
 		discoveryCacheHits: make(map[string]int64),
@@ -106,8 +125,14 @@ func (m *SimpleMetrics) DecHTTPRequestsInFlight() {
 	atomic.AddInt64(&m.httpInFlight, -1)
 }
 
+// SetHTTPRequestsConcurrent sets the number of requests the concurrency
+// limiter is admitting, across all routes
+func (m *SimpleMetrics) SetHTTPRequestsConcurrent(count int) {
+	atomic.StoreInt64(&m.httpConcurrent, int64(count))
+}
+
 // RecordMessage records message processing metrics
-func (m *SimpleMetrics) RecordMessage(status, coordinationType string, duration time.Duration, sizeBytes int64, schema string) {
+func (m *SimpleMetrics) RecordMessage(status, coordinationType string, duration time.Duration, size MessageSize, schema string) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
@@ -115,8 +140,11 @@ func (m *SimpleMetrics) RecordMessage(status, coordinationType string, duration
 	m.messages[key]++
 	m.messageDurations[key] = append(m.messageDurations[key], duration.Seconds())
 
-	if sizeBytes > 0 && schema != "" {
-		m.messageSizes[schema] = append(m.messageSizes[schema], float64(sizeBytes))
+	if size.Total() > 0 && schema != "" {
+		m.messageSizes[schema] = append(m.messageSizes[schema], float64(size.Total()))
+		m.payloadSizes[schema] = append(m.payloadSizes[schema], float64(size.Payload))
+		m.attachmentSizes[schema] = append(m.attachmentSizes[schema], float64(size.Attachments))
+		m.envelopeSizes[schema] = append(m.envelopeSizes[schema], float64(size.Envelope))
 	}
 	m.lastUpdate = time.Now()
 }
@@ -153,6 +181,20 @@ func (m *SimpleMetrics) RecordDeliveryRetry(domain, reason string) {
 	m.lastUpdate = time.Now()
 }
 
+// SetRelaysInFlight sets the number of relays to domain in progress; idle
+// domains are not listed
+func (m *SimpleMetrics) SetRelaysInFlight(domain string, count int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if count == 0 {
+		delete(m.relaysInFlight, domain)
+	} else {
+		m.relaysInFlight[domain] = int64(count)
+	}
+	m.lastUpdate = time.Now()
+}
+
 // RecordDiscovery records discovery metrics
 func (m *SimpleMetrics) RecordDiscovery(domain, method, status string, duration time.Duration, cacheHit bool) {
 	m.mu.Lock()
@@ -168,6 +210,26 @@ func (m *SimpleMetrics) RecordDiscovery(domain, method, status string, duration
 	m.lastUpdate = time.Now()
 }
 
+// RecordAgentCacheLookup records an agent registry cache hit or miss
+func (m *SimpleMetrics) RecordAgentCacheLookup(hit bool) {
+	if hit {
+		atomic.AddInt64(&m.agentCacheHits, 1)
+	} else {
+		atomic.AddInt64(&m.agentCacheMisses, 1)
+	}
+}
+
+// SetStorageStats sets the storage capacity gauges
+func (m *SimpleMetrics) SetStorageStats(totalMessages, inboxMessages int64, oldestUnacknowledgedAge time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.storageTotalMessages = totalMessages
+	m.storageInboxMessages = inboxMessages
+	m.storageOldestUnackedAge = oldestUnacknowledgedAge.Seconds()
+	m.storageUpdated = time.Now()
+	m.lastUpdate = m.storageUpdated
+}
+
 // SetConnectionsActive sets the number of active connections
 func (m *SimpleMetrics) SetConnectionsActive(count float64) {
 	m.mu.Lock()
@@ -215,27 +277,44 @@ func (m *SimpleMetrics) ToJSON() ([]byte, error) {
 		"timestamp":      m.lastUpdate.Unix(),
 		"uptime_seconds": time.Since(m.startTime).Seconds(),
 		"http": map[string]interface{}{
-			"requests":  m.httpRequests,
-			"durations": m.calculateStats(m.httpDurations),
-			"in_flight": atomic.LoadInt64(&m.httpInFlight),
+			"requests":   m.httpRequests,
+			"durations":  m.calculateStats(m.httpDurations),
+			"in_flight":  atomic.LoadInt64(&m.httpInFlight),
+			"concurrent": atomic.LoadInt64(&m.httpConcurrent),
 		},
 		"messages": map[string]interface{}{
 			"total":     m.messages,
 			"durations": m.calculateStats(m.messageDurations),
 			"in_flight": atomic.LoadInt64(&m.messagesInFlight),
 			"sizes":     m.calculateStats(m.messageSizes),
+			"size_components": map[string]interface{}{
+				"payload":     m.calculateStats(m.payloadSizes),
+				"attachments": m.calculateStats(m.attachmentSizes),
+				"envelope":    m.calculateStats(m.envelopeSizes),
+			},
 		},
 		"deliveries": map[string]interface{}{
-			"total":     m.deliveries,
-			"durations": m.calculateStats(m.deliveryDurations),
-			"attempts":  m.deliveryAttempts,
-			"retries":   m.deliveryRetries,
+			"total":            m.deliveries,
+			"durations":        m.calculateStats(m.deliveryDurations),
+			"attempts":         m.deliveryAttempts,
+			"retries":          m.deliveryRetries,
+			"relays_in_flight": m.relaysInFlight,
 		},
 		"discovery": map[string]interface{}{
 			"total":      m.discoveries,
 			"durations":  m.calculateStats(m.discoveryDurations),
 			"cache_hits": m.discoveryCacheHits,
 		},
+		"agent_cache": map[string]interface{}{
+			"hits":   atomic.LoadInt64(&m.agentCacheHits),
+			"misses": atomic.LoadInt64(&m.agentCacheMisses),
+		},
+		"storage": map[string]interface{}{
+			"total_messages":                    m.storageTotalMessages,
+			"inbox_unacknowledged":              m.storageInboxMessages,
+			"oldest_unacknowledged_age_seconds": m.storageOldestUnackedAge,
+			"updated":                           m.storageUpdated.Unix(),
+		},
 		"system": map[string]interface{}{
 			"connections_active": m.connectionsActive,
 			"memory_usage_bytes": memStats.Alloc,
@@ -160,16 +160,37 @@ func TestSimpleMetrics_HTTPInFlight(t *testing.T) {
 	}
 }
 
+func TestSimpleMetrics_SetHTTPRequestsConcurrent(t *testing.T) {
+	metrics := NewSimpleMetrics()
+	metrics.SetHTTPRequestsConcurrent(7)
+
+	data, err := metrics.ToJSON()
+	if err != nil {
+		t.Fatalf("Failed to export metrics: %v", err)
+	}
+	var exported struct {
+		HTTP struct {
+			Concurrent int64 `json:"concurrent"`
+		} `json:"http"`
+	}
+	if err := json.Unmarshal(data, &exported); err != nil {
+		t.Fatalf("Failed to decode metrics: %v", err)
+	}
+	if exported.HTTP.Concurrent != 7 {
+		t.Errorf("Expected 7 concurrent requests, got %d", exported.HTTP.Concurrent)
+	}
+}
+
 func TestSimpleMetrics_RecordMessage(t *testing.T) {
 	metrics := NewSimpleMetrics()
 
 	status := "success"
 	coordinationType := "direct"
 	duration := 50 * time.Millisecond
-	sizeBytes := int64(1024)
+	size := MessageSize{Payload: 768, Attachments: 4096, Envelope: 256}
 	schema := "test-schema"
 
-	metrics.RecordMessage(status, coordinationType, duration, sizeBytes, schema)
+	metrics.RecordMessage(status, coordinationType, duration, size, schema)
 
 	// Verify message was recorded
 	key := "success:direct"
@@ -186,8 +207,23 @@ func TestSimpleMetrics_RecordMessage(t *testing.T) {
 		t.Errorf("Expected 1 size entry, got %d", len(metrics.messageSizes[schema]))
 	}
 
-	if metrics.messageSizes[schema][0] != float64(sizeBytes) {
-		t.Errorf("Expected size %f, got %f", float64(sizeBytes), metrics.messageSizes[schema][0])
+	if metrics.messageSizes[schema][0] != 1024 {
+		t.Errorf("Expected size 1024, got %f", metrics.messageSizes[schema][0])
+	}
+
+	// Verify the size components were recorded separately
+	components := map[string]struct {
+		sizes    map[string][]float64
+		expected float64
+	}{
+		"payload":     {metrics.payloadSizes, 768},
+		"attachments": {metrics.attachmentSizes, 4096},
+		"envelope":    {metrics.envelopeSizes, 256},
+	}
+	for name, c := range components {
+		if len(c.sizes[schema]) != 1 || c.sizes[schema][0] != c.expected {
+			t.Errorf("Expected %s size %f, got %v", name, c.expected, c.sizes[schema])
+		}
 	}
 }
 
@@ -195,7 +231,7 @@ func TestSimpleMetrics_RecordMessage_NoSize(t *testing.T) {
 	metrics := NewSimpleMetrics()
 
 	// Record message without size/schema
-	metrics.RecordMessage("success", "direct", 50*time.Millisecond, 0, "")
+	metrics.RecordMessage("success", "direct", 50*time.Millisecond, MessageSize{}, "")
 
 	// Should not record size
 	if len(metrics.messageSizes) != 0 {
@@ -264,6 +300,61 @@ func TestSimpleMetrics_RecordDeliveryRetry(t *testing.T) {
 	}
 }
 
+func TestSimpleMetrics_SetRelaysInFlight(t *testing.T) {
+	metrics := NewSimpleMetrics()
+
+	metrics.SetRelaysInFlight("example.com", 3)
+	metrics.SetRelaysInFlight("other.com", 1)
+	if count := metrics.relaysInFlight["example.com"]; count != 3 {
+		t.Errorf("Expected 3 relays in flight, got %d", count)
+	}
+
+	metrics.SetRelaysInFlight("other.com", 0)
+	if _, ok := metrics.relaysInFlight["other.com"]; ok {
+		t.Error("Expected an idle domain not to be listed")
+	}
+}
+
+func TestSimpleMetrics_RecordAgentCacheLookup(t *testing.T) {
+	metrics := NewSimpleMetrics()
+
+	metrics.RecordAgentCacheLookup(true)
+	metrics.RecordAgentCacheLookup(true)
+	metrics.RecordAgentCacheLookup(false)
+
+	if metrics.agentCacheHits != 2 {
+		t.Errorf("Expected 2 cache hits, got %d", metrics.agentCacheHits)
+	}
+	if metrics.agentCacheMisses != 1 {
+		t.Errorf("Expected 1 cache miss, got %d", metrics.agentCacheMisses)
+	}
+}
+
+func TestSimpleMetrics_SetStorageStats(t *testing.T) {
+	metrics := NewSimpleMetrics()
+
+	metrics.SetStorageStats(120, 15, 90*time.Second)
+
+	data, err := metrics.ToJSON()
+	if err != nil {
+		t.Fatalf("Failed to export metrics: %v", err)
+	}
+
+	var exported struct {
+		Storage struct {
+			TotalMessages           int64   `json:"total_messages"`
+			InboxUnacknowledged     int64   `json:"inbox_unacknowledged"`
+			OldestUnacknowledgedAge float64 `json:"oldest_unacknowledged_age_seconds"`
+		} `json:"storage"`
+	}
+	if err := json.Unmarshal(data, &exported); err != nil {
+		t.Fatalf("Failed to decode metrics: %v", err)
+	}
+	if exported.Storage.TotalMessages != 120 || exported.Storage.InboxUnacknowledged != 15 || exported.Storage.OldestUnacknowledgedAge != 90 {
+		t.Errorf("Unexpected storage metrics: %+v", exported.Storage)
+	}
+}
+
 func TestSimpleMetrics_RecordDiscovery(t *testing.T) {
 	metrics := NewSimpleMetrics()
 
@@ -347,7 +438,7 @@ func TestSimpleMetrics_ToJSON(t *testing.T) {
 
 	// Record some test data
 	metrics.RecordHTTPRequest("GET", "/test", 200, 100*time.Millisecond)
-	metrics.RecordMessage("success", "direct", 50*time.Millisecond, 1024, "test-schema")
+	metrics.RecordMessage("success", "direct", 50*time.Millisecond, MessageSize{Payload: 1024}, "test-schema")
 	metrics.RecordDelivery("delivered", "example.com", 200*time.Millisecond, 2)
 	metrics.RecordDiscovery("example.com", "dns", "success", 30*time.Millisecond, true)
 	metrics.RecordError("server", "500", "internal")
@@ -460,7 +551,7 @@ func TestSimpleMetrics_ConcurrentAccess(t *testing.T) {
 		go func(id int) {
 			defer wg.Done()
 			for j := 0; j < numOperations; j++ {
-				metrics.RecordMessage("success", "direct", time.Millisecond, 1024, "schema")
+				metrics.RecordMessage("success", "direct", time.Millisecond, MessageSize{Payload: 1024}, "schema")
 				metrics.IncMessagesInFlight()
 				metrics.DecMessagesInFlight()
 			}
@@ -553,7 +644,7 @@ func BenchmarkSimpleMetrics_ToJSON(b *testing.B) {
 	// Add some test data
 	for i := 0; i < 100; i++ {
 		metrics.RecordHTTPRequest("GET", "/test", 200, time.Millisecond)
-		metrics.RecordMessage("success", "direct", time.Millisecond, 1024, "schema")
+		metrics.RecordMessage("success", "direct", time.Millisecond, MessageSize{Payload: 1024}, "schema")
 	}
 
 	b.ResetTimer()
@@ -0,0 +1,347 @@
+/*
+ * Copyright 2026 Cong Wang
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package metrics
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// maxStatsDPacketSize keeps a batch of metric lines within a single UDP
+// datagram on a standard 1500-byte MTU
+const maxStatsDPacketSize = 1432
+
+// defaultStatsDFlushInterval is how often buffered metric lines are sent
+const defaultStatsDFlushInterval = time.Second
+
+// StatsDConfig configures the StatsD sink
+type StatsDConfig struct {
+	Address       string        // host:port of the StatsD or DogStatsD agent (UDP)
+	Prefix        string        // Prepended to every metric name, e.g. "agentry"
+	Tags          bool          // Send dimensions as DogStatsD tags instead of name segments
+	FlushInterval time.Duration // Zero means defaultStatsDFlushInterval
+}
+
+// StatsDMetrics emits every recorded metric to a StatsD agent as counters,
+// timers and gauges. It also records into a local MetricsProvider so the
+// /metrics endpoint keeps working.
+type StatsDMetrics struct {
+	MetricsProvider // local view served by ToJSON
+
+	prefix string
+	tags   bool
+	conn   net.Conn
+
+	httpInFlight     int64
+	messagesInFlight int64
+
+	mu     sync.Mutex
+	buf    bytes.Buffer
+	stop   chan struct{}
+	done   chan struct{}
+	closed bool
+}
+
+// NewStatsDMetrics creates a StatsD sink that also records into local
+func NewStatsDMetrics(config StatsDConfig, local MetricsProvider) (*StatsDMetrics, error) {
+	if config.Address == "" {
+		return nil, fmt.Errorf("statsd address is required")
+	}
+	conn, err := net.Dial("udp", config.Address)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to statsd: %w", err)
+	}
+
+	interval := config.FlushInterval
+	if interval <= 0 {
+		interval = defaultStatsDFlushInterval
+	}
+
+	prefix := strings.TrimSuffix(config.Prefix, ".")
+	if prefix != "" {
+		prefix += "."
+	}
+
+	s := &StatsDMetrics{
+		MetricsProvider: local,
+		prefix:          prefix,
+		tags:            config.Tags,
+		conn:            conn,
+		stop:            make(chan struct{}),
+		done:            make(chan struct{}),
+	}
+	go s.flushLoop(interval)
+	return s, nil
+}
+
+// Close flushes buffered metrics and closes the connection
+func (s *StatsDMetrics) Close() error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return nil
+	}
+	s.closed = true
+	s.mu.Unlock()
+
+	close(s.stop)
+	<-s.done
+
+	s.mu.Lock()
+	s.flushLocked()
+	s.mu.Unlock()
+	return s.conn.Close()
+}
+
+// RecordHTTPRequest records HTTP request metrics
+func (s *StatsDMetrics) RecordHTTPRequest(method, path string, statusCode int, duration time.Duration) {
+	s.MetricsProvider.RecordHTTPRequest(method, path, statusCode, duration)
+	tags := []tag{{"method", method}, {"path", path}, {"status", strconv.Itoa(statusCode)}}
+	s.count("http.requests", 1, tags)
+	s.timing("http.request_duration", duration, tags)
+}
+
+// IncHTTPRequestsInFlight increments in-flight HTTP requests
+func (s *StatsDMetrics) IncHTTPRequestsInFlight() {
+	s.MetricsProvider.IncHTTPRequestsInFlight()
+	s.gauge("http.requests_in_flight", float64(atomic.AddInt64(&s.httpInFlight, 1)), nil)
+}
+
+// DecHTTPRequestsInFlight decrements in-flight HTTP requests
+func (s *StatsDMetrics) DecHTTPRequestsInFlight() {
+	s.MetricsProvider.DecHTTPRequestsInFlight()
+	s.gauge("http.requests_in_flight", float64(atomic.AddInt64(&s.httpInFlight, -1)), nil)
+}
+
+// SetHTTPRequestsConcurrent sets the number of requests the concurrency
+// limiter is admitting
+func (s *StatsDMetrics) SetHTTPRequestsConcurrent(count int) {
+	s.MetricsProvider.SetHTTPRequestsConcurrent(count)
+	s.gauge("http.requests_concurrent", float64(count), nil)
+}
+
+// RecordMessage records message processing metrics
+func (s *StatsDMetrics) RecordMessage(status, coordinationType string, duration time.Duration, size MessageSize, schema string) {
+	s.MetricsProvider.RecordMessage(status, coordinationType, duration, size, schema)
+	tags := []tag{{"status", status}, {"coordination", coordinationType}}
+	s.count("messages.processed", 1, tags)
+	s.timing("messages.duration", duration, tags)
+	if size.Total() > 0 && schema != "" {
+		schemaTags := []tag{{"schema", schema}}
+		s.send("messages.size_bytes", strconv.FormatInt(size.Total(), 10), "h", schemaTags)
+		s.send("messages.payload_bytes", strconv.FormatInt(size.Payload, 10), "h", schemaTags)
+		s.send("messages.attachment_bytes", strconv.FormatInt(size.Attachments, 10), "h", schemaTags)
+		s.send("messages.envelope_bytes", strconv.FormatInt(size.Envelope, 10), "h", schemaTags)
+	}
+}
+
+// IncMessagesInFlight increments in-flight messages
+func (s *StatsDMetrics) IncMessagesInFlight() {
+	s.MetricsProvider.IncMessagesInFlight()
+	s.gauge("messages.in_flight", float64(atomic.AddInt64(&s.messagesInFlight, 1)), nil)
+}
+
+// DecMessagesInFlight decrements in-flight messages
+func (s *StatsDMetrics) DecMessagesInFlight() {
+	s.MetricsProvider.DecMessagesInFlight()
+	s.gauge("messages.in_flight", float64(atomic.AddInt64(&s.messagesInFlight, -1)), nil)
+}
+
+// RecordDelivery records delivery metrics
+func (s *StatsDMetrics) RecordDelivery(status, domain string, duration time.Duration, attempts int) {
+	s.MetricsProvider.RecordDelivery(status, domain, duration, attempts)
+	tags := []tag{{"status", status}, {"domain", domain}}
+	s.count("deliveries", 1, tags)
+	s.timing("deliveries.duration", duration, tags)
+	s.count("deliveries.attempts", int64(attempts), []tag{{"domain", domain}})
+}
+
+// RecordDeliveryRetry records delivery retry metrics
+func (s *StatsDMetrics) RecordDeliveryRetry(domain, reason string) {
+	s.MetricsProvider.RecordDeliveryRetry(domain, reason)
+	s.count("deliveries.retries", 1, []tag{{"domain", domain}, {"reason", reason}})
+}
+
+// SetRelaysInFlight sets the number of relays to domain in progress
+func (s *StatsDMetrics) SetRelaysInFlight(domain string, count int) {
+	s.MetricsProvider.SetRelaysInFlight(domain, count)
+	s.gauge("deliveries.relays_in_flight", float64(count), []tag{{"domain", domain}})
+}
+
+// RecordDiscovery records discovery metrics
+func (s *StatsDMetrics) RecordDiscovery(domain, method, status string, duration time.Duration, cacheHit bool) {
+	s.MetricsProvider.RecordDiscovery(domain, method, status, duration, cacheHit)
+	tags := []tag{{"domain", domain}, {"method", method}, {"status", status}, {"cache_hit", strconv.FormatBool(cacheHit)}}
+	s.count("discovery.lookups", 1, tags)
+	s.timing("discovery.duration", duration, tags)
+}
+
+// RecordAgentCacheLookup records an agent registry cache hit or miss
+func (s *StatsDMetrics) RecordAgentCacheLookup(hit bool) {
+	s.MetricsProvider.RecordAgentCacheLookup(hit)
+	result := "miss"
+	if hit {
+		result = "hit"
+	}
+	s.count("agent_cache.lookups", 1, []tag{{"result", result}})
+}
+
+// SetStorageStats sets the storage capacity gauges
+func (s *StatsDMetrics) SetStorageStats(totalMessages, inboxMessages int64, oldestUnacknowledgedAge time.Duration) {
+	s.MetricsProvider.SetStorageStats(totalMessages, inboxMessages, oldestUnacknowledgedAge)
+	s.gauge("storage.total_messages", float64(totalMessages), nil)
+	s.gauge("storage.inbox_messages", float64(inboxMessages), nil)
+	s.gauge("storage.oldest_unacknowledged_age_seconds", oldestUnacknowledgedAge.Seconds(), nil)
+}
+
+// SetConnectionsActive sets the number of active connections
+func (s *StatsDMetrics) SetConnectionsActive(count float64) {
+	s.MetricsProvider.SetConnectionsActive(count)
+	s.gauge("system.connections_active", count, nil)
+}
+
+// SetMemoryUsage sets the memory usage
+func (s *StatsDMetrics) SetMemoryUsage(bytes float64) {
+	s.MetricsProvider.SetMemoryUsage(bytes)
+	s.gauge("system.memory_usage_bytes", bytes, nil)
+}
+
+// SetGoroutinesActive sets the number of active goroutines
+func (s *StatsDMetrics) SetGoroutinesActive(count float64) {
+	s.MetricsProvider.SetGoroutinesActive(count)
+	s.gauge("system.goroutines_active", count, nil)
+}
+
+// RecordError records error metrics
+func (s *StatsDMetrics) RecordError(component, errorCode, errorType string) {
+	s.MetricsProvider.RecordError(component, errorCode, errorType)
+	s.count("errors", 1, []tag{{"component", component}, {"code", errorCode}, {"type", errorType}})
+}
+
+// tag is a metric dimension
+type tag struct {
+	key, value string
+}
+
+func (s *StatsDMetrics) count(name string, value int64, tags []tag) {
+	s.send(name, strconv.FormatInt(value, 10), "c", tags)
+}
+
+func (s *StatsDMetrics) timing(name string, duration time.Duration, tags []tag) {
+	s.send(name, strconv.FormatFloat(float64(duration)/float64(time.Millisecond), 'f', -1, 64), "ms", tags)
+}
+
+func (s *StatsDMetrics) gauge(name string, value float64, tags []tag) {
+	if value < 0 {
+		// A leading minus sign would be read as a decrement
+		value = 0
+	}
+	s.send(name, strconv.FormatFloat(value, 'f', -1, 64), "g", tags)
+}
+
+// send buffers one metric line, flushing first if it would overflow a packet.
+// Plain StatsD has no tags, so tag values become name segments instead.
+func (s *StatsDMetrics) send(name, value, metricType string, tags []tag) {
+	var line strings.Builder
+	line.WriteString(s.prefix)
+	line.WriteString(name)
+	if !s.tags {
+		for _, t := range tags {
+			line.WriteByte('.')
+			line.WriteString(sanitizeStatsD(t.value, true))
+		}
+	}
+	line.WriteByte(':')
+	line.WriteString(value)
+	line.WriteByte('|')
+	line.WriteString(metricType)
+	if s.tags && len(tags) > 0 {
+		line.WriteString("|#")
+		for i, t := range tags {
+			if i > 0 {
+				line.WriteByte(',')
+			}
+			line.WriteString(t.key)
+			line.WriteByte(':')
+			line.WriteString(sanitizeStatsD(t.value, false))
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return
+	}
+	if s.buf.Len() > 0 && s.buf.Len()+1+line.Len() > maxStatsDPacketSize {
+		s.flushLocked()
+	}
+	if s.buf.Len() > 0 {
+		s.buf.WriteByte('\n')
+	}
+	s.buf.WriteString(line.String())
+}
+
+func (s *StatsDMetrics) flushLoop(interval time.Duration) {
+	defer close(s.done)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			s.mu.Lock()
+			s.flushLocked()
+			s.mu.Unlock()
+		case <-s.stop:
+			return
+		}
+	}
+}
+
+// flushLocked sends the buffered lines; metrics are best effort, so write
+// errors are dropped. Callers hold s.mu.
+func (s *StatsDMetrics) flushLocked() {
+	if s.buf.Len() == 0 {
+		return
+	}
+	_, _ = s.conn.Write(s.buf.Bytes())
+	s.buf.Reset()
+}
+
+// sanitizeStatsD replaces characters that are part of the StatsD line format.
+// A name segment also loses the dots and slashes that would nest it.
+func sanitizeStatsD(value string, nameSegment bool) string {
+	if value == "" {
+		return "none"
+	}
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case ':', '|', '@', '#', ',', '\n', '\r', ' ':
+			return '_'
+		case '.', '/':
+			if nameSegment {
+				return '_'
+			}
+		}
+		return r
+	}, value)
+}
@@ -0,0 +1,190 @@
+/*
+ * Copyright 2026 Cong Wang
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package metrics
+
+import (
+	"encoding/json"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// listenStatsD returns a UDP listener standing in for a StatsD agent
+func listenStatsD(t *testing.T) *net.UDPConn {
+	t.Helper()
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+// readStatsDLines collects metric lines until the listener goes quiet
+func readStatsDLines(t *testing.T, conn *net.UDPConn) []string {
+	t.Helper()
+	var lines []string
+	buf := make([]byte, 64*1024)
+	for {
+		conn.SetReadDeadline(time.Now().Add(500 * time.Millisecond))
+		n, err := conn.Read(buf)
+		if err != nil {
+			return lines
+		}
+		if n > maxStatsDPacketSize {
+			t.Errorf("Packet of %d bytes exceeds %d", n, maxStatsDPacketSize)
+		}
+		lines = append(lines, strings.Split(string(buf[:n]), "\n")...)
+	}
+}
+
+func containsLine(lines []string, want string) bool {
+	for _, line := range lines {
+		if line == want {
+			return true
+		}
+	}
+	return false
+}
+
+func TestStatsDMetrics_PlainStatsD(t *testing.T) {
+	listener := listenStatsD(t)
+	sink, err := NewStatsDMetrics(StatsDConfig{
+		Address: listener.LocalAddr().String(),
+		Prefix:  "agentry",
+	}, NewSimpleMetrics())
+	if err != nil {
+		t.Fatalf("NewStatsDMetrics failed: %v", err)
+	}
+
+	sink.RecordHTTPRequest("POST", "/v1/messages", 202, 1500*time.Microsecond)
+	sink.IncMessagesInFlight()
+	sink.RecordDelivery("delivered", "example.com", 20*time.Millisecond, 2)
+	sink.RecordAgentCacheLookup(true)
+	if err := sink.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	lines := readStatsDLines(t, listener)
+	for _, want := range []string{
+		"agentry.http.requests.POST._v1_messages.202:1|c",
+		"agentry.http.request_duration.POST._v1_messages.202:1.5|ms",
+		"agentry.messages.in_flight:1|g",
+		"agentry.deliveries.delivered.example_com:1|c",
+		"agentry.deliveries.attempts.example_com:2|c",
+		"agentry.agent_cache.lookups.hit:1|c",
+	} {
+		if !containsLine(lines, want) {
+			t.Errorf("Missing line %q in %q", want, lines)
+		}
+	}
+
+	// The local provider still backs /metrics
+	data, err := sink.ToJSON()
+	if err != nil {
+		t.Fatalf("ToJSON failed: %v", err)
+	}
+	var exported map[string]interface{}
+	if err := json.Unmarshal(data, &exported); err != nil {
+		t.Fatalf("Failed to parse metrics JSON: %v", err)
+	}
+	if _, ok := exported["http"]; !ok {
+		t.Errorf("Expected local HTTP metrics in %s", data)
+	}
+}
+
+func TestStatsDMetrics_DogStatsDTags(t *testing.T) {
+	listener := listenStatsD(t)
+	sink, err := NewStatsDMetrics(StatsDConfig{
+		Address: listener.LocalAddr().String(),
+		Tags:    true,
+	}, NewSimpleMetrics())
+	if err != nil {
+		t.Fatalf("NewStatsDMetrics failed: %v", err)
+	}
+
+	sink.RecordError("delivery", "TIMEOUT", "network")
+	sink.RecordMessage("delivered", "", 3*time.Millisecond, MessageSize{Payload: 384, Attachments: 2048, Envelope: 128}, "agntcy:commerce.order.v1")
+	sink.Close()
+
+	lines := readStatsDLines(t, listener)
+	for _, want := range []string{
+		"errors:1|c|#component:delivery,code:TIMEOUT,type:network",
+		"messages.processed:1|c|#status:delivered,coordination:none",
+		"messages.size_bytes:512|h|#schema:agntcy_commerce.order.v1",
+		"messages.payload_bytes:384|h|#schema:agntcy_commerce.order.v1",
+		"messages.attachment_bytes:2048|h|#schema:agntcy_commerce.order.v1",
+		"messages.envelope_bytes:128|h|#schema:agntcy_commerce.order.v1",
+	} {
+		if !containsLine(lines, want) {
+			t.Errorf("Missing line %q in %q", want, lines)
+		}
+	}
+}
+
+func TestStatsDMetrics_BatchesWithinPacketSize(t *testing.T) {
+	listener := listenStatsD(t)
+	sink, err := NewStatsDMetrics(StatsDConfig{Address: listener.LocalAddr().String()}, NewSimpleMetrics())
+	if err != nil {
+		t.Fatalf("NewStatsDMetrics failed: %v", err)
+	}
+
+	for i := 0; i < 200; i++ {
+		sink.RecordDeliveryRetry("example.com", "timeout")
+	}
+	sink.Close()
+
+	lines := readStatsDLines(t, listener)
+	if len(lines) != 200 {
+		t.Errorf("Expected 200 lines, got %d", len(lines))
+	}
+
+	// Recording after Close is dropped rather than panicking
+	sink.RecordDeliveryRetry("example.com", "timeout")
+}
+
+func TestNewMetricsProviderWithConfig(t *testing.T) {
+	provider, err := NewMetricsProviderWithConfig(Config{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, ok := provider.(*SimpleMetrics); !ok {
+		t.Errorf("Expected *SimpleMetrics by default, got %T", provider)
+	}
+
+	listener := listenStatsD(t)
+	provider, err = NewMetricsProviderWithConfig(Config{
+		Sink:   SinkStatsD,
+		StatsD: StatsDConfig{Address: listener.LocalAddr().String()},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	sink, ok := provider.(*StatsDMetrics)
+	if !ok {
+		t.Fatalf("Expected *StatsDMetrics, got %T", provider)
+	}
+	sink.Close()
+
+	if _, err := NewMetricsProviderWithConfig(Config{Sink: SinkStatsD}); err == nil {
+		t.Error("Expected error for statsd sink without an address")
+	}
+	if _, err := NewMetricsProviderWithConfig(Config{Sink: "otlp"}); err == nil {
+		t.Error("Expected error for unknown sink")
+	}
+}
@@ -0,0 +1,194 @@
+/*
+ * Copyright 2026 Cong Wang
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package middleware
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Admin key scopes. Most reads are allowed with any key, while reads of
+// message contents or the gateway configuration need a read scope, which
+// read-only grants. Each write needs the scope of the resource it changes,
+// and admin grants every scope.
+const (
+	ScopeReadOnly       = "read-only"
+	ScopeMessageRead    = "message:read"
+	ScopeConfigRead     = "config:read"
+	ScopeSchemaWrite    = "schema:write"
+	ScopeAgentWrite     = "agent:write"
+	ScopeMessageWrite   = "message:write"
+	ScopeRateLimitWrite = "ratelimit:write"
+	ScopeAdmin          = "admin"
+)
+
+var knownScopes = map[string]bool{
+	ScopeReadOnly:       true,
+	ScopeMessageRead:    true,
+	ScopeConfigRead:     true,
+	ScopeSchemaWrite:    true,
+	ScopeAgentWrite:     true,
+	ScopeMessageWrite:   true,
+	ScopeRateLimitWrite: true,
+	ScopeAdmin:          true,
+}
+
+// AdminKey is an admin API key with the identity it is logged under and the
+// scopes it grants. Keys from a flat key file have no name and every scope.
+type AdminKey struct {
+	Name   string   `json:"name" yaml:"name"`
+	Key    string   `json:"key" yaml:"key"`
+	Scopes []string `json:"scopes" yaml:"scopes"`
+}
+
+// Allows reports whether the key grants scope; an empty scope is a read
+// any key may make
+func (k *AdminKey) Allows(scope string) bool {
+	if scope == "" {
+		return true
+	}
+	for _, granted := range k.Scopes {
+		if granted == scope || granted == ScopeAdmin {
+			return true
+		}
+		if granted == ScopeReadOnly && (scope == ScopeMessageRead || scope == ScopeConfigRead) {
+			return true
+		}
+	}
+	return false
+}
+
+// adminKeysFile is the structured key file format
+type adminKeysFile struct {
+	Keys []AdminKey `json:"keys" yaml:"keys"`
+}
+
+// LoadAdminKeys reads an admin key file. Files ending in .json, .yaml or
+// .yml hold named keys with scopes; any other file lists one key per line,
+// ignoring empty lines and # comments, each with every scope.
+func LoadAdminKeys(path string) ([]AdminKey, error) {
+	data, err := os.ReadFile(filepath.Clean(path))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read admin key file: %w", err)
+	}
+
+	var file adminKeysFile
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		if err := json.Unmarshal(data, &file); err != nil {
+			return nil, fmt.Errorf("failed to parse admin key file: %w", err)
+		}
+	case ".yaml", ".yml":
+		if err := yaml.Unmarshal(data, &file); err != nil {
+			return nil, fmt.Errorf("failed to parse admin key file: %w", err)
+		}
+	default:
+		var keys []AdminKey
+		for _, line := range strings.Split(string(data), "\n") {
+			line = strings.TrimSpace(line)
+			if line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+			keys = append(keys, AdminKey{Key: line, Scopes: []string{ScopeAdmin}})
+		}
+		return keys, nil
+	}
+
+	names := make(map[string]bool, len(file.Keys))
+	for i, key := range file.Keys {
+		if key.Name == "" || key.Key == "" {
+			return nil, fmt.Errorf("admin key %d must have a name and a key", i+1)
+		}
+		if names[key.Name] {
+			return nil, fmt.Errorf("duplicate admin key name: %s", key.Name)
+		}
+		names[key.Name] = true
+		for _, scope := range key.Scopes {
+			if !knownScopes[scope] {
+				return nil, fmt.Errorf("admin key %s has unknown scope: %s", key.Name, scope)
+			}
+		}
+	}
+	return file.Keys, nil
+}
+
+// findAdminKey returns the key in keyFile matching providedKey
+func findAdminKey(providedKey, keyFile string) (*AdminKey, bool) {
+	keys, err := LoadAdminKeys(keyFile)
+	if err != nil {
+		return nil, false
+	}
+	for i := range keys {
+		// Use constant-time comparison to prevent timing attacks
+		if subtle.ConstantTimeCompare([]byte(providedKey), []byte(keys[i].Key)) == 1 {
+			return &keys[i], true
+		}
+	}
+	return nil, false
+}
+
+// adminReadScopes maps the admin routes, below /admin/, that expose message
+// contents or the gateway configuration to the read scope they need
+var adminReadScopes = map[string]string{
+	"messages":               ScopeMessageRead,
+	"messages/:id/raw":       ScopeMessageRead,
+	"messages/:id/validate":  ScopeMessageRead,
+	"inbox/pending":          ScopeMessageRead,
+	"export":                 ScopeMessageRead,
+	"events":                 ScopeMessageRead,
+	"schemas/:id/revalidate": ScopeMessageRead,
+	"config":                 ScopeConfigRead,
+}
+
+// adminCheckRoutes lists the admin POST routes that only check or suggest,
+// changing nothing, so they are scoped as reads
+var adminCheckRoutes = map[string]bool{
+	"messages/:id/validate":  true,
+	"schemas/:id/validate":   true,
+	"schemas/:id/revalidate": true,
+	"schemas/infer":          true,
+}
+
+// requiredAdminScope returns the scope a request to an admin route needs,
+// or "" for reads any key may make. Writes to routes without a resource
+// scope need admin.
+func requiredAdminScope(method, route string) string {
+	_, rest, _ := strings.Cut(route, "/admin/")
+	if isSafeMethod(method) || (method == http.MethodPost && adminCheckRoutes[rest]) {
+		return adminReadScopes[rest]
+	}
+
+	resource, _, _ := strings.Cut(rest, "/")
+	switch resource {
+	case "schemas":
+		return ScopeSchemaWrite
+	case "agents":
+		return ScopeAgentWrite
+	case "messages", "reconcile":
+		return ScopeMessageWrite
+	case "ratelimits":
+		return ScopeRateLimitWrite
+	}
+	return ScopeAdmin
+}
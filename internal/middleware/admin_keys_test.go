@@ -0,0 +1,240 @@
+/*
+ * Copyright 2026 Cong Wang
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/amtp-protocol/agentry/internal/config"
+)
+
+// writeKeyFile writes content to a key file named name in a temporary directory
+func writeKeyFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatalf("Failed to write admin keys file: %v", err)
+	}
+	return path
+}
+
+const scopedKeysYAML = `keys:
+  - name: ci
+    key: ci-key
+    scopes: [schema:write]
+  - name: dashboard
+    key: dashboard-key
+    scopes: [read-only]
+  - name: ops
+    key: ops-key
+    scopes: [admin]
+`
+
+func TestLoadAdminKeys(t *testing.T) {
+	tests := []struct {
+		name          string
+		file          string
+		content       string
+		expectedNames []string
+		errorContains string
+	}{
+		{
+			name:          "flat file",
+			file:          "admin.keys",
+			content:       "# comment\nkey-1\n\n  key-2  \n",
+			expectedNames: []string{"", ""},
+		},
+		{
+			name:          "yaml",
+			file:          "admin-keys.yaml",
+			content:       scopedKeysYAML,
+			expectedNames: []string{"ci", "dashboard", "ops"},
+		},
+		{
+			name:          "json",
+			file:          "admin-keys.json",
+			content:       `{"keys": [{"name": "ci", "key": "ci-key", "scopes": ["agent:write", "schema:write"]}]}`,
+			expectedNames: []string{"ci"},
+		},
+		{
+			name:          "unknown scope",
+			file:          "admin-keys.yml",
+			content:       "keys:\n  - name: ci\n    key: ci-key\n    scopes: [schema:delete]\n",
+			errorContains: "unknown scope",
+		},
+		{
+			name:          "missing name",
+			file:          "admin-keys.json",
+			content:       `{"keys": [{"key": "ci-key"}]}`,
+			errorContains: "must have a name",
+		},
+		{
+			name:          "duplicate name",
+			file:          "admin-keys.json",
+			content:       `{"keys": [{"name": "ci", "key": "a"}, {"name": "ci", "key": "b"}]}`,
+			errorContains: "duplicate admin key name",
+		},
+		{
+			name:          "malformed",
+			file:          "admin-keys.json",
+			content:       `{"keys": [`,
+			errorContains: "failed to parse",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keys, err := LoadAdminKeys(writeKeyFile(t, tt.file, tt.content))
+			if tt.errorContains != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errorContains) {
+					t.Fatalf("Expected error containing %q, got %v", tt.errorContains, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if len(keys) != len(tt.expectedNames) {
+				t.Fatalf("Expected %d keys, got %+v", len(tt.expectedNames), keys)
+			}
+			for i, key := range keys {
+				if key.Name != tt.expectedNames[i] || key.Key == "" {
+					t.Errorf("Unexpected key %d: %+v", i, key)
+				}
+			}
+		})
+	}
+
+	// Flat-file keys keep their old meaning: full access
+	keys, _ := LoadAdminKeys(writeKeyFile(t, "admin.keys", "key-1\n"))
+	if !keys[0].Allows(ScopeSchemaWrite) || !keys[0].Allows(ScopeAdmin) {
+		t.Error("Expected a flat-file key to grant every scope")
+	}
+}
+
+func TestAdminAuth_Scopes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.Use(AdminAuth(config.AuthConfig{
+		AdminKeyFile:      writeKeyFile(t, "admin-keys.yaml", scopedKeysYAML),
+		AdminAPIKeyHeader: "X-Admin-Key",
+	}))
+	ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+	router.GET("/v1/admin/schemas", ok)
+	router.DELETE("/v1/admin/schemas/:id", ok)
+	router.POST("/v1/admin/schemas/:id/validate", ok)
+	router.POST("/v1/admin/schemas/:id/revalidate", ok)
+	router.POST("/v1/admin/schemas/infer", ok)
+	router.GET("/v1/admin/messages/:id/raw", ok)
+	router.GET("/v1/admin/config", ok)
+	router.POST("/v1/admin/agents", ok)
+	router.POST("/v1/admin/reconcile", ok)
+	router.POST("/v1/admin/unscoped", ok)
+
+	tests := []struct {
+		key    string
+		method string
+		path   string
+		status int
+	}{
+		{"dashboard-key", "GET", "/v1/admin/schemas", http.StatusOK},
+		{"dashboard-key", "DELETE", "/v1/admin/schemas/agntcy:commerce.order.v1", http.StatusForbidden},
+		{"dashboard-key", "POST", "/v1/admin/schemas/agntcy:commerce.order.v1/validate", http.StatusOK},
+		{"dashboard-key", "POST", "/v1/admin/schemas/agntcy:commerce.order.v1/revalidate", http.StatusOK},
+		{"dashboard-key", "GET", "/v1/admin/messages/m-1/raw", http.StatusOK},
+		{"dashboard-key", "GET", "/v1/admin/config", http.StatusOK},
+		{"ci-key", "GET", "/v1/admin/schemas", http.StatusOK},
+		{"ci-key", "POST", "/v1/admin/schemas/infer", http.StatusOK},
+		{"ci-key", "POST", "/v1/admin/schemas/agntcy:commerce.order.v1/revalidate", http.StatusForbidden},
+		{"ci-key", "GET", "/v1/admin/messages/m-1/raw", http.StatusForbidden},
+		{"ci-key", "GET", "/v1/admin/config", http.StatusForbidden},
+		{"ci-key", "DELETE", "/v1/admin/schemas/agntcy:commerce.order.v1", http.StatusOK},
+		{"ci-key", "POST", "/v1/admin/agents", http.StatusForbidden},
+		{"ci-key", "POST", "/v1/admin/reconcile", http.StatusForbidden},
+		{"ops-key", "POST", "/v1/admin/agents", http.StatusOK},
+		{"ops-key", "POST", "/v1/admin/unscoped", http.StatusOK},
+		{"ci-key", "POST", "/v1/admin/unscoped", http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.key+" "+tt.method+" "+tt.path, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			req.Header.Set("X-Admin-Key", tt.key)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.status {
+				t.Fatalf("Expected status %d, got %d: %s", tt.status, w.Code, w.Body.String())
+			}
+			if tt.status == http.StatusForbidden && !strings.Contains(w.Body.String(), "ADMIN_SCOPE_DENIED") {
+				t.Errorf("Expected ADMIN_SCOPE_DENIED, got %s", w.Body.String())
+			}
+		})
+	}
+}
+
+func TestHasAdminKey_Scopes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cfg := config.AuthConfig{
+		AdminKeyFile:      writeKeyFile(t, "admin-keys.yaml", scopedKeysYAML),
+		AdminAPIKeyHeader: "X-Admin-Key",
+	}
+
+	for key, expected := range map[string]bool{"ops-key": true, "dashboard-key": false, "unknown": false} {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = httptest.NewRequest("POST", "/v1/messages", nil)
+		c.Request.Header.Set("X-Admin-Key", key)
+		if got := HasAdminKey(cfg, c); got != expected {
+			t.Errorf("HasAdminKey with %s = %v, want %v", key, got, expected)
+		}
+	}
+}
+
+func TestLogger_AdminKeyName(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	for _, format := range []string{"json", "text"} {
+		var out strings.Builder
+		router := gin.New()
+		router.Use(Logger(config.LoggingConfig{Level: "info", Format: format}, &out))
+		router.Use(AdminAuth(config.AuthConfig{
+			AdminKeyFile:      writeKeyFile(t, "admin-keys.yaml", scopedKeysYAML),
+			AdminAPIKeyHeader: "X-Admin-Key",
+		}))
+		router.DELETE("/v1/admin/schemas/:id", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+		req := httptest.NewRequest("DELETE", "/v1/admin/schemas/agntcy:commerce.order.v1", nil)
+		req.Header.Set("X-Admin-Key", "ci-key")
+		router.ServeHTTP(httptest.NewRecorder(), req)
+
+		want := `"admin_key":"ci"`
+		if format != "json" {
+			want = "admin=ci"
+		}
+		if logged := out.String(); !strings.Contains(logged, want) || strings.Contains(logged, "ci-key") {
+			t.Errorf("Expected the %s log to name the key without the secret, got: %s", format, logged)
+		}
+	}
+}
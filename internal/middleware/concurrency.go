@@ -0,0 +1,74 @@
+/*
+ * Copyright 2026 Cong Wang
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package middleware
+
+import "sync/atomic"
+
+// ConcurrencyLimiter caps the requests handled at once, whoever sends them.
+// Unlike the rate limiter it holds back no one in particular; it keeps a
+// burst of slow requests from exhausting the gateway.
+type ConcurrencyLimiter struct {
+	slots    chan struct{}
+	inFlight atomic.Int64
+	observe  func(inFlight int)
+}
+
+// NewConcurrencyLimiter returns nil when max is not positive. observe, if
+// not nil, is called with the number of admitted requests whenever it
+// changes.
+func NewConcurrencyLimiter(max int, observe func(inFlight int)) *ConcurrencyLimiter {
+	if max <= 0 {
+		return nil
+	}
+	return &ConcurrencyLimiter{
+		slots:   make(chan struct{}, max),
+		observe: observe,
+	}
+}
+
+// Limit returns the number of requests admitted at once
+func (l *ConcurrencyLimiter) Limit() int {
+	return cap(l.slots)
+}
+
+// InFlight returns the number of requests currently admitted
+func (l *ConcurrencyLimiter) InFlight() int {
+	return int(l.inFlight.Load())
+}
+
+// TryAcquire admits a request if a slot is free, without waiting
+func (l *ConcurrencyLimiter) TryAcquire() bool {
+	select {
+	case l.slots <- struct{}{}:
+		l.report(l.inFlight.Add(1))
+		return true
+	default:
+		return false
+	}
+}
+
+// Release frees the slot of an admitted request
+func (l *ConcurrencyLimiter) Release() {
+	l.report(l.inFlight.Add(-1))
+	<-l.slots
+}
+
+func (l *ConcurrencyLimiter) report(inFlight int64) {
+	if l.observe != nil {
+		l.observe(int(inFlight))
+	}
+}
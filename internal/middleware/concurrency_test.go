@@ -0,0 +1,58 @@
+/*
+ * Copyright 2026 Cong Wang
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package middleware
+
+import "testing"
+
+func TestConcurrencyLimiter(t *testing.T) {
+	if l := NewConcurrencyLimiter(0, nil); l != nil {
+		t.Error("Expected no limiter when the cap is disabled")
+	}
+
+	var observed []int
+	l := NewConcurrencyLimiter(2, func(inFlight int) { observed = append(observed, inFlight) })
+	if l.Limit() != 2 {
+		t.Errorf("Expected limit 2, got %d", l.Limit())
+	}
+
+	if !l.TryAcquire() || !l.TryAcquire() {
+		t.Fatal("Expected requests up to the limit to be admitted")
+	}
+	if l.TryAcquire() {
+		t.Error("Expected a request over the limit to be refused")
+	}
+	if l.InFlight() != 2 {
+		t.Errorf("Expected 2 requests in flight, got %d", l.InFlight())
+	}
+
+	l.Release()
+	if !l.TryAcquire() {
+		t.Error("Expected a released slot to be reusable")
+	}
+	l.Release()
+	l.Release()
+
+	want := []int{1, 2, 1, 2, 1, 0}
+	if len(observed) != len(want) {
+		t.Fatalf("Expected observations %v, got %v", want, observed)
+	}
+	for i := range want {
+		if observed[i] != want[i] {
+			t.Fatalf("Expected observations %v, got %v", want, observed)
+		}
+	}
+}
@@ -17,11 +17,15 @@
 package middleware
 
 import (
-	"crypto/subtle"
+	"bytes"
+	"crypto/tls"
+	"encoding/json"
 	"fmt"
+	"io"
+	"math"
 	"net/http"
-	"os"
-	"path/filepath"
+	"slices"
+	"strconv"
 	"strings"
 	"time"
 
@@ -29,13 +33,47 @@ import (
 	"github.com/google/uuid"
 
 	"github.com/amtp-protocol/agentry/internal/config"
+	"github.com/amtp-protocol/agentry/internal/discovery"
+	"github.com/amtp-protocol/agentry/internal/logging"
+	"github.com/amtp-protocol/agentry/internal/types"
 )
 
-// Logger creates a structured logging middleware
-func Logger(cfg config.LoggingConfig) gin.HandlerFunc {
-	return gin.LoggerWithFormatter(func(param gin.LogFormatterParams) string {
+// maxLoggedBodySize bounds how much of a request body is captured for debug
+// access logs; larger bodies are omitted rather than logged truncated.
+const maxLoggedBodySize = 64 * 1024
+
+const (
+	logHeadersKey   = "log_headers"
+	logBodyKey      = "log_body"
+	adminKeyNameKey = "admin_key_name"
+)
+
+// Logger creates a structured logging middleware writing to out, or to
+// gin.DefaultWriter when out is nil. At debug level the JSON format also
+// records request headers and body, with credentials and the configured
+// fields masked first.
+func Logger(cfg config.LoggingConfig, out io.Writer) gin.HandlerFunc {
+	redactor := logging.NewRedactor(cfg.Redaction)
+	debug := cfg.Format == "json" && strings.EqualFold(cfg.Level, string(logging.LevelDebug))
+
+	logRequest := gin.LoggerWithConfig(gin.LoggerConfig{Output: out, Formatter: func(param gin.LogFormatterParams) string {
 		if cfg.Format == "json" {
-			return fmt.Sprintf(`{"time":"%s","method":"%s","path":"%s","status":%d,"latency":"%s","ip":"%s","user_agent":"%s","request_id":"%s"}%s`,
+			var extra string
+			if headers, ok := param.Keys[logHeadersKey].(map[string]string); ok {
+				if data, err := json.Marshal(headers); err == nil {
+					extra += fmt.Sprintf(`,"headers":%s`, data)
+				}
+			}
+			if body, ok := param.Keys[logBodyKey].(json.RawMessage); ok {
+				extra += fmt.Sprintf(`,"body":%s`, body)
+			}
+			if name, ok := param.Keys[adminKeyNameKey].(string); ok {
+				if data, err := json.Marshal(name); err == nil {
+					extra += fmt.Sprintf(`,"admin_key":%s`, data)
+				}
+			}
+
+			return fmt.Sprintf(`{"time":"%s","method":"%s","path":"%s","status":%d,"latency":"%s","ip":"%s","user_agent":"%s","request_id":"%s"%s}%s`,
 				param.TimeStamp.Format(time.RFC3339),
 				param.Method,
 				param.Path,
@@ -44,20 +82,59 @@ func Logger(cfg config.LoggingConfig) gin.HandlerFunc {
 				param.ClientIP,
 				param.Request.UserAgent(),
 				param.Request.Header.Get("X-Request-ID"),
+				extra,
 				"\n",
 			)
 		}
 
 		// Default format
-		return fmt.Sprintf("[%s] %s %s %d %s %s\n",
+		var admin string
+		if name, ok := param.Keys[adminKeyNameKey].(string); ok {
+			admin = " admin=" + name
+		}
+		return fmt.Sprintf("[%s] %s %s %d %s %s%s\n",
 			param.TimeStamp.Format("2006/01/02 - 15:04:05"),
 			param.Method,
 			param.Path,
 			param.StatusCode,
 			param.Latency,
 			param.ClientIP,
+			admin,
 		)
-	})
+	}})
+
+	if !debug {
+		return logRequest
+	}
+
+	return func(c *gin.Context) {
+		c.Set(logHeadersKey, redactor.Headers(c.Request.Header))
+		if body := captureBody(c.Request); len(body) > 0 {
+			// Bodies that aren't JSON can't be redacted, so they are not logged
+			if redacted, ok := redactor.JSON(body); ok {
+				c.Set(logBodyKey, redacted)
+			}
+		}
+		logRequest(c)
+	}
+}
+
+// captureBody returns a copy of the request body, leaving it intact for the
+// handlers. It returns nil when the body is larger than maxLoggedBodySize.
+func captureBody(req *http.Request) []byte {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil
+	}
+
+	buf, err := io.ReadAll(io.LimitReader(req.Body, maxLoggedBodySize+1))
+	req.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(buf), req.Body), req.Body}
+	if err != nil || len(buf) > maxLoggedBodySize {
+		return nil
+	}
+	return buf
 }
 
 // RequestID adds a unique request ID to each request
@@ -83,7 +160,7 @@ func CORS() gin.HandlerFunc {
 		// Only allow specific origins or use a whitelist
 		c.Header("Access-Control-Allow-Origin", origin)
 		c.Header("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
-		c.Header("Access-Control-Allow-Headers", "Content-Type, Authorization, X-Request-ID, X-AMTP-Version, X-Admin-Key")
+		c.Header("Access-Control-Allow-Headers", "Content-Type, Authorization, X-Request-ID, X-AMTP-Version, X-Admin-Key, Accept-Version")
 		c.Header("Access-Control-Expose-Headers", "X-Request-ID")
 		c.Header("Access-Control-Max-Age", "86400")
 
@@ -141,6 +218,14 @@ func Auth(cfg config.AuthConfig) gin.HandlerFunc {
 			return
 		}
 
+		// Record the domains a verified client certificate vouches for, so
+		// handlers can check claimed sender addresses against them
+		if contains(cfg.Methods, "domain") {
+			if domains := verifiedDomains(c.Request.TLS); len(domains) > 0 {
+				c.Set("authenticated_domains", domains)
+			}
+		}
+
 		// NOTE: For agent-specific API key validation, use the registry directly in handlers
 		// This middleware only handles general authentication methods like domain/oauth
 		if contains(cfg.Methods, "apikey") {
@@ -217,7 +302,8 @@ func AdminAuth(cfg config.AuthConfig) gin.HandlerFunc {
 		}
 
 		// Validate admin key against file
-		if !validateAdminKey(adminKey, cfg.AdminKeyFile) {
+		key, ok := findAdminKey(adminKey, cfg.AdminKeyFile)
+		if !ok {
 			c.JSON(http.StatusForbidden, gin.H{
 				"error": gin.H{
 					"code":    "ADMIN_ACCESS_DENIED",
@@ -231,23 +317,57 @@ func AdminAuth(cfg config.AuthConfig) gin.HandlerFunc {
 			return
 		}
 
+		// Check the key may make this request
+		route := c.FullPath()
+		if route == "" {
+			route = c.Request.URL.Path
+		}
+		if scope := requiredAdminScope(c.Request.Method, route); !key.Allows(scope) {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error": gin.H{
+					"code":    "ADMIN_SCOPE_DENIED",
+					"message": "Admin API key does not grant the required scope",
+					"details": gin.H{
+						"endpoint":       c.Request.URL.Path,
+						"key_name":       key.Name,
+						"required_scope": scope,
+					},
+				},
+			})
+			c.Abort()
+			return
+		}
+
 		// Set admin authentication context
 		c.Set("admin_authenticated", true)
 		c.Set("auth_method", "admin_key")
+		if key.Name != "" {
+			c.Set(adminKeyNameKey, key.Name)
+		}
 		c.Next()
 	}
 }
 
-// RateLimit provides basic rate limiting (placeholder implementation)
-func RateLimit() gin.HandlerFunc {
-	return func(c *gin.Context) {
-		// Placeholder for rate limiting implementation
-		// In production, this would use Redis or similar for distributed rate limiting
-
-		clientIP := c.ClientIP()
+// HasAdminKey reports whether a request outside the admin routes presents a
+// valid admin API key granting message:write. Without an admin key file
+// there is no admin identity.
+func HasAdminKey(cfg config.AuthConfig, c *gin.Context) bool {
+	if cfg.AdminKeyFile == "" {
+		return false
+	}
+	adminKey := c.GetHeader(cfg.AdminAPIKeyHeader)
+	if adminKey == "" {
+		return false
+	}
+	key, ok := findAdminKey(adminKey, cfg.AdminKeyFile)
+	return ok && key.Allows(ScopeMessageWrite)
+}
 
-		// Simple in-memory rate limiting (not suitable for production)
-		if isRateLimited(clientIP) {
+// RateLimit rejects requests once the client IP's bucket in limiter is empty
+func RateLimit(limiter *RateLimiter) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if allowed, wait := limiter.Allow(c.ClientIP()); !allowed {
+			c.Header("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
 			c.JSON(http.StatusTooManyRequests, gin.H{
 				"error": gin.H{
 					"code":    "RATE_LIMIT_EXCEEDED",
@@ -262,6 +382,34 @@ func RateLimit() gin.HandlerFunc {
 	}
 }
 
+// ConcurrencyLimit rejects requests with 503 SERVER_BUSY while the limiter
+// is full. Requests for the exempt paths, such as health probes, are
+// neither counted nor rejected, so a busy gateway is not taken for a dead
+// one.
+func ConcurrencyLimit(limiter *ConcurrencyLimiter, exempt ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if slices.Contains(exempt, c.Request.URL.Path) {
+			c.Next()
+			return
+		}
+
+		if !limiter.TryAcquire() {
+			c.Header("Retry-After", "1")
+			c.JSON(http.StatusServiceUnavailable, gin.H{
+				"error": gin.H{
+					"code":    "SERVER_BUSY",
+					"message": "The server is handling too many requests. Please try again shortly.",
+				},
+			})
+			c.Abort()
+			return
+		}
+		defer limiter.Release()
+
+		c.Next()
+	}
+}
+
 // AMTPVersion validates the AMTP protocol version
 func AMTPVersion() gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -282,6 +430,76 @@ func AMTPVersion() gin.HandlerFunc {
 	}
 }
 
+// supportedAPIVersions lists the response shapes a client can select with
+// the Accept-Version header
+var supportedAPIVersions = []string{types.APIVersion}
+
+// APIVersion selects the API response version from the Accept-Version header,
+// defaulting to the current version
+func APIVersion() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		version := strings.TrimSpace(c.GetHeader("Accept-Version"))
+		if version == "" {
+			version = types.APIVersion
+		}
+		if !contains(supportedAPIVersions, version) {
+			c.JSON(http.StatusNotAcceptable, gin.H{
+				"api_version": types.APIVersion,
+				"error": gin.H{
+					"code":    "UNSUPPORTED_API_VERSION",
+					"message": fmt.Sprintf("Unsupported API version: %s", version),
+					"details": gin.H{"supported_versions": supportedAPIVersions},
+				},
+			})
+			c.Abort()
+			return
+		}
+
+		c.Set("api_version", version)
+		c.Next()
+	}
+}
+
+// DiscoveryOverride attaches the domain to gateway mappings of the
+// X-AMTP-Discovery-Override header to the request context for mock discovery.
+// Unless the test-only override is enabled, requests carrying the header are
+// rejected outright rather than silently delivered via real discovery.
+func DiscoveryOverride(enabled bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		value := c.GetHeader(discovery.OverrideHeader)
+		if value == "" {
+			c.Next()
+			return
+		}
+
+		if !enabled {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": gin.H{
+					"code":    "DISCOVERY_OVERRIDE_NOT_ALLOWED",
+					"message": fmt.Sprintf("The %s header is only accepted by gateways running in test mode", discovery.OverrideHeader),
+				},
+			})
+			c.Abort()
+			return
+		}
+
+		overrides, err := discovery.ParseOverrides(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": gin.H{
+					"code":    "INVALID_DISCOVERY_OVERRIDE",
+					"message": err.Error(),
+				},
+			})
+			c.Abort()
+			return
+		}
+
+		c.Request = c.Request.WithContext(discovery.WithOverrides(c.Request.Context(), overrides))
+		c.Next()
+	}
+}
+
 // Helper functions (placeholders for actual implementations)
 
 func contains(slice []string, item string) bool {
@@ -293,6 +511,26 @@ func contains(slice []string, item string) bool {
 	return false
 }
 
+// verifiedDomains returns the DNS names of a client certificate whose chain the
+// TLS handshake verified against the configured client CAs, or nil. The
+// subject common name is used when the certificate has no DNS names.
+func verifiedDomains(state *tls.ConnectionState) []string {
+	if state == nil || len(state.VerifiedChains) == 0 || len(state.PeerCertificates) == 0 {
+		return nil
+	}
+	cert := state.PeerCertificates[0]
+	names := cert.DNSNames
+	if len(names) == 0 && cert.Subject.CommonName != "" {
+		names = []string{cert.Subject.CommonName}
+	}
+
+	domains := make([]string, 0, len(names))
+	for _, name := range names {
+		domains = append(domains, strings.ToLower(name))
+	}
+	return domains
+}
+
 func validateClientCertificate(cert interface{}) bool {
 	// TODO: Implement proper client certificate validation
 	// This should verify the certificate chain, check revocation status,
@@ -308,35 +546,8 @@ func validateBearerToken(token string) bool {
 	return len(token) > 0
 }
 
-func isRateLimited(clientIP string) bool {
-	// TODO: Implement proper rate limiting logic
-	// This should use Redis for distributed rate limiting, or
-	// an in-memory cache with TTL for single-instance deployments
-	// Consider implementing sliding window or token bucket algorithms
-	return false
-}
-
 // validateAdminKey validates the provided admin key against the key file
 func validateAdminKey(providedKey, keyFile string) bool {
-	// Read admin keys from file
-	data, err := os.ReadFile(filepath.Clean(keyFile))
-	if err != nil {
-		return false
-	}
-
-	// Parse keys from file (one key per line, ignore empty lines and comments)
-	lines := strings.Split(string(data), "\n")
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-		if line == "" || strings.HasPrefix(line, "#") {
-			continue
-		}
-
-		// Use constant-time comparison to prevent timing attacks
-		if subtle.ConstantTimeCompare([]byte(providedKey), []byte(line)) == 1 {
-			return true
-		}
-	}
-
-	return false
+	_, ok := findAdminKey(providedKey, keyFile)
+	return ok
 }
@@ -0,0 +1,160 @@
+/*
+ * Copyright 2026 Cong Wang
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package middleware
+
+import (
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/amtp-protocol/agentry/internal/config"
+)
+
+// rateLimitSweepInterval is how often buckets that refilled completely are
+// dropped; a full bucket behaves exactly like one that was never created
+const rateLimitSweepInterval = time.Minute
+
+// RateLimiter keeps an in-memory token bucket per client key. It is local to
+// the instance; each gateway replica limits independently.
+type RateLimiter struct {
+	mu                sync.Mutex
+	requestsPerMinute int
+	burst             int
+	rate              float64 // tokens per second
+	buckets           map[string]*rateBucket
+	lastSweep         time.Time
+	now               func() time.Time
+}
+
+type rateBucket struct {
+	tokens    float64
+	updated   time.Time
+	throttled int64
+}
+
+// RateLimitBucket is a snapshot of one client's bucket
+type RateLimitBucket struct {
+	Key       string    `json:"key"`
+	Remaining int       `json:"remaining"` // whole requests allowed right now
+	Throttled int64     `json:"throttled"` // requests rejected since the bucket was created
+	LastSeen  time.Time `json:"last_seen"`
+}
+
+// NewRateLimiter returns nil when rate limiting is disabled
+func NewRateLimiter(cfg config.RateLimitConfig) *RateLimiter {
+	if cfg.RequestsPerMinute <= 0 {
+		return nil
+	}
+	burst := cfg.Burst
+	if burst <= 0 {
+		burst = cfg.RequestsPerMinute
+	}
+	return &RateLimiter{
+		requestsPerMinute: cfg.RequestsPerMinute,
+		burst:             burst,
+		rate:              float64(cfg.RequestsPerMinute) / 60,
+		buckets:           make(map[string]*rateBucket),
+		now:               time.Now,
+	}
+}
+
+// RequestsPerMinute returns the refill rate of every bucket
+func (l *RateLimiter) RequestsPerMinute() int {
+	return l.requestsPerMinute
+}
+
+// Burst returns the capacity of every bucket
+func (l *RateLimiter) Burst() int {
+	return l.burst
+}
+
+// Allow takes a token from key's bucket. When the bucket is empty it returns
+// false and how long until the next token.
+func (l *RateLimiter) Allow(key string) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	if now.Sub(l.lastSweep) >= rateLimitSweepInterval {
+		l.sweep(now)
+	}
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &rateBucket{tokens: float64(l.burst), updated: now}
+		l.buckets[key] = b
+	}
+	b.tokens = l.refill(b, now)
+	b.updated = now
+
+	if b.tokens < 1 {
+		b.throttled++
+		return false, time.Duration((1 - b.tokens) / l.rate * float64(time.Second))
+	}
+	b.tokens--
+	return true, 0
+}
+
+// Buckets returns the tracked buckets, the most throttled first
+func (l *RateLimiter) Buckets() []RateLimitBucket {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	buckets := make([]RateLimitBucket, 0, len(l.buckets))
+	for key, b := range l.buckets {
+		buckets = append(buckets, RateLimitBucket{
+			Key:       key,
+			Remaining: int(math.Floor(l.refill(b, now))),
+			Throttled: b.throttled,
+			LastSeen:  b.updated,
+		})
+	}
+	sort.Slice(buckets, func(i, j int) bool {
+		if buckets[i].Remaining != buckets[j].Remaining {
+			return buckets[i].Remaining < buckets[j].Remaining
+		}
+		return buckets[i].Key < buckets[j].Key
+	})
+	return buckets
+}
+
+// Reset forgets key's bucket, so its next request starts with a full burst.
+// It reports whether the key was tracked.
+func (l *RateLimiter) Reset(key string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	_, ok := l.buckets[key]
+	delete(l.buckets, key)
+	return ok
+}
+
+// refill returns the tokens b holds at now without updating it
+func (l *RateLimiter) refill(b *rateBucket, now time.Time) float64 {
+	return math.Min(float64(l.burst), b.tokens+now.Sub(b.updated).Seconds()*l.rate)
+}
+
+func (l *RateLimiter) sweep(now time.Time) {
+	for key, b := range l.buckets {
+		if l.refill(b, now) >= float64(l.burst) {
+			delete(l.buckets, key)
+		}
+	}
+	l.lastSweep = now
+}
@@ -0,0 +1,102 @@
+/*
+ * Copyright 2026 Cong Wang
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package middleware
+
+import (
+	"testing"
+	"time"
+
+	"github.com/amtp-protocol/agentry/internal/config"
+)
+
+// newTestRateLimiter returns a limiter on a clock the test advances
+func newTestRateLimiter(cfg config.RateLimitConfig) (*RateLimiter, *time.Time) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	l := NewRateLimiter(cfg)
+	l.now = func() time.Time { return now }
+	l.lastSweep = now
+	return l, &now
+}
+
+func TestNewRateLimiter(t *testing.T) {
+	if l := NewRateLimiter(config.RateLimitConfig{}); l != nil {
+		t.Error("Expected no limiter when rate limiting is disabled")
+	}
+	l := NewRateLimiter(config.RateLimitConfig{RequestsPerMinute: 120})
+	if l.RequestsPerMinute() != 120 || l.Burst() != 120 {
+		t.Errorf("Expected the burst to default to the rate, got %d/%d", l.RequestsPerMinute(), l.Burst())
+	}
+}
+
+func TestRateLimiter_Allow(t *testing.T) {
+	l, now := newTestRateLimiter(config.RateLimitConfig{RequestsPerMinute: 60, Burst: 3})
+
+	for i := 0; i < 3; i++ {
+		if allowed, _ := l.Allow("client"); !allowed {
+			t.Fatalf("Expected request %d within the burst to be allowed", i+1)
+		}
+	}
+	allowed, wait := l.Allow("client")
+	if allowed || wait != time.Second {
+		t.Errorf("Expected a 1s wait once the burst is spent, got %t, %v", allowed, wait)
+	}
+
+	*now = now.Add(1500 * time.Millisecond)
+	if allowed, _ := l.Allow("client"); !allowed {
+		t.Error("Expected a refilled token to be allowed")
+	}
+	if allowed, wait := l.Allow("client"); allowed || wait != 500*time.Millisecond {
+		t.Errorf("Expected a 500ms wait for the partly refilled token, got %t, %v", allowed, wait)
+	}
+}
+
+func TestRateLimiter_BucketsAndReset(t *testing.T) {
+	l, now := newTestRateLimiter(config.RateLimitConfig{RequestsPerMinute: 60, Burst: 2})
+
+	l.Allow("idle")
+	for i := 0; i < 4; i++ {
+		l.Allow("busy")
+	}
+
+	buckets := l.Buckets()
+	if len(buckets) != 2 {
+		t.Fatalf("Expected 2 buckets, got %+v", buckets)
+	}
+	if buckets[0].Key != "busy" || buckets[0].Remaining != 0 || buckets[0].Throttled != 2 || !buckets[0].LastSeen.Equal(*now) {
+		t.Errorf("Expected the throttled client first, got %+v", buckets[0])
+	}
+	if buckets[1].Key != "idle" || buckets[1].Remaining != 1 || buckets[1].Throttled != 0 {
+		t.Errorf("Unexpected bucket %+v", buckets[1])
+	}
+
+	if !l.Reset("busy") {
+		t.Error("Expected a tracked bucket to be reset")
+	}
+	if l.Reset("busy") || l.Reset("unknown") {
+		t.Error("Expected resetting an untracked key to report false")
+	}
+	if allowed, _ := l.Allow("busy"); !allowed {
+		t.Error("Expected a reset client to start with a full burst")
+	}
+
+	// Buckets that refilled completely are dropped by the next sweep
+	*now = now.Add(rateLimitSweepInterval)
+	l.Allow("new")
+	if buckets := l.Buckets(); len(buckets) != 1 || buckets[0].Key != "new" {
+		t.Errorf("Expected only the new bucket after the sweep, got %+v", buckets)
+	}
+}
@@ -0,0 +1,181 @@
+/*
+ * Copyright 2026 Cong Wang
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/amtp-protocol/agentry/internal/signing"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// TimestampHeader carries the Unix time, in seconds, a request was signed
+	TimestampHeader = signing.TimestampHeader
+	// NonceHeader carries a value the sender never reuses
+	NonceHeader = signing.NonceHeader
+
+	maxNonceLength = 128
+)
+
+// NonceCache remembers nonces until they expire, holding at most size of
+// them. When full, the oldest nonce is dropped to make room, so size should
+// comfortably exceed the signed requests expected within the expiry window.
+type NonceCache struct {
+	mu      sync.Mutex
+	size    int
+	expires map[string]time.Time
+	order   []nonceEntry // insertion order, oldest first
+	now     func() time.Time
+}
+
+type nonceEntry struct {
+	nonce   string
+	expires time.Time
+}
+
+// NewNonceCache creates a cache holding at most size nonces
+func NewNonceCache(size int) *NonceCache {
+	return &NonceCache{
+		size:    size,
+		expires: make(map[string]time.Time),
+		now:     time.Now,
+	}
+}
+
+// Add records nonce until expires. It reports false when the nonce is
+// already recorded and has not expired.
+func (c *NonceCache) Add(nonce string, expires time.Time) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now()
+	for len(c.order) > 0 && !c.order[0].expires.After(now) {
+		c.drop()
+	}
+
+	if e, ok := c.expires[nonce]; ok && e.After(now) {
+		return false
+	}
+
+	c.expires[nonce] = expires
+	c.order = append(c.order, nonceEntry{nonce: nonce, expires: expires})
+	for len(c.expires) > c.size {
+		c.drop()
+	}
+	return true
+}
+
+// Len returns the number of nonces held, including expired ones not yet
+// dropped
+func (c *NonceCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.expires)
+}
+
+// drop forgets the oldest entry, unless its nonce was recorded again since
+func (c *NonceCache) drop() {
+	e := c.order[0]
+	c.order = c.order[1:]
+	if c.expires[e.nonce].Equal(e.expires) {
+		delete(c.expires, e.nonce)
+	}
+}
+
+// ReplayProtection rejects requests that are stale or reuse a nonce. Requests
+// that change state must carry both headers; reads are checked only when
+// they carry either.
+func ReplayProtection(nonces *NonceCache, maxSkew time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		timestamp := c.GetHeader(TimestampHeader)
+		nonce := c.GetHeader(NonceHeader)
+		if timestamp == "" && nonce == "" {
+			if isSafeMethod(c.Request.Method) {
+				c.Next()
+				return
+			}
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": gin.H{
+					"code":    "INVALID_SIGNED_REQUEST",
+					"message": TimestampHeader + " and " + NonceHeader + " are required",
+				},
+			})
+			c.Abort()
+			return
+		}
+
+		if timestamp == "" || nonce == "" || len(nonce) > maxNonceLength {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": gin.H{
+					"code":    "INVALID_SIGNED_REQUEST",
+					"message": TimestampHeader + " and " + NonceHeader + " must be sent together, with a nonce of at most 128 characters",
+				},
+			})
+			c.Abort()
+			return
+		}
+
+		seconds, err := strconv.ParseInt(timestamp, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": gin.H{
+					"code":    "INVALID_SIGNED_REQUEST",
+					"message": TimestampHeader + " must be a Unix time in seconds",
+				},
+			})
+			c.Abort()
+			return
+		}
+
+		signedAt := time.Unix(seconds, 0)
+		skew := nonces.now().Sub(signedAt)
+		if skew >= maxSkew || skew <= -maxSkew {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"error": gin.H{
+					"code":    "REPLAY_DETECTED",
+					"message": "Request timestamp is outside the allowed window",
+				},
+			})
+			c.Abort()
+			return
+		}
+
+		// Past signedAt+maxSkew the timestamp check rejects the request on
+		// its own, so the nonce need not be remembered any longer
+		if !nonces.Add(nonce, signedAt.Add(maxSkew)) {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"error": gin.H{
+					"code":    "REPLAY_DETECTED",
+					"message": "Request nonce has already been used",
+				},
+			})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// isSafeMethod reports whether method only reads
+func isSafeMethod(method string) bool {
+	return method == http.MethodGet || method == http.MethodHead || method == http.MethodOptions
+}
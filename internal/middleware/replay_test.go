@@ -0,0 +1,162 @@
+/*
+ * Copyright 2026 Cong Wang
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// newTestNonceCache returns a cache on a clock the test advances
+func newTestNonceCache(size int) (*NonceCache, *time.Time) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	c := NewNonceCache(size)
+	c.now = func() time.Time { return now }
+	return c, &now
+}
+
+func TestNonceCache_Add(t *testing.T) {
+	c, now := newTestNonceCache(10)
+
+	if !c.Add("a", now.Add(time.Minute)) {
+		t.Fatal("Expected a new nonce to be accepted")
+	}
+	if c.Add("a", now.Add(time.Minute)) {
+		t.Error("Expected a repeated nonce to be refused")
+	}
+
+	*now = now.Add(time.Minute)
+	if !c.Add("a", now.Add(time.Minute)) {
+		t.Error("Expected an expired nonce to be accepted again")
+	}
+	if c.Len() != 1 {
+		t.Errorf("Expected the expired entry to be dropped, got %d entries", c.Len())
+	}
+}
+
+func TestNonceCache_ReaddedBehindLongerExpiry(t *testing.T) {
+	c, now := newTestNonceCache(2)
+
+	c.Add("long", now.Add(time.Hour))
+	c.Add("a", now.Add(time.Minute))
+
+	// "a" expired behind an entry that has not, so its old entry is still
+	// queued when it is recorded again
+	*now = now.Add(2 * time.Minute)
+	if !c.Add("a", now.Add(time.Minute)) {
+		t.Fatal("Expected an expired nonce to be accepted again")
+	}
+
+	// Evicting "long" and the stale entry must not forget the new "a"
+	c.Add("b", now.Add(time.Minute))
+	if c.Add("a", now.Add(time.Minute)) {
+		t.Error("Expected the re-recorded nonce to still be refused")
+	}
+}
+
+func TestNonceCache_Bounded(t *testing.T) {
+	c, now := newTestNonceCache(2)
+
+	c.Add("a", now.Add(time.Hour))
+	c.Add("b", now.Add(time.Hour))
+	c.Add("c", now.Add(time.Hour))
+
+	if c.Len() != 2 {
+		t.Fatalf("Expected the cache to hold 2 nonces, got %d", c.Len())
+	}
+	if c.Add("b", now.Add(time.Hour)) || c.Add("c", now.Add(time.Hour)) {
+		t.Error("Expected the newest nonces to be kept")
+	}
+	if !c.Add("a", now.Add(time.Hour)) {
+		t.Error("Expected the oldest nonce to have been dropped")
+	}
+}
+
+func TestReplayProtection(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	nonces, now := newTestNonceCache(100)
+	router := gin.New()
+	router.Use(ReplayProtection(nonces, 5*time.Minute))
+	router.Any("/test", func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	unix := func(d time.Duration) string {
+		return strconv.FormatInt(now.Add(d).Unix(), 10)
+	}
+
+	tests := []struct {
+		name           string
+		method         string
+		timestamp      string
+		nonce          string
+		expectedStatus int
+		expectedCode   string
+	}{
+		{"unsigned read", "GET", "", "", http.StatusOK, ""},
+		{"unsigned write", "POST", "", "", http.StatusBadRequest, "INVALID_SIGNED_REQUEST"},
+		{"fresh request", "POST", unix(0), "n1", http.StatusOK, ""},
+		{"read replaying a nonce", "GET", unix(0), "n1", http.StatusUnauthorized, "REPLAY_DETECTED"},
+		{"replayed nonce", "POST", unix(time.Second), "n1", http.StatusUnauthorized, "REPLAY_DETECTED"},
+		{"clock slightly ahead", "POST", unix(4 * time.Minute), "n2", http.StatusOK, ""},
+		{"stale timestamp", "POST", unix(-6 * time.Minute), "n3", http.StatusUnauthorized, "REPLAY_DETECTED"},
+		{"future timestamp", "POST", unix(6 * time.Minute), "n4", http.StatusUnauthorized, "REPLAY_DETECTED"},
+		{"timestamp without nonce", "POST", unix(0), "", http.StatusBadRequest, "INVALID_SIGNED_REQUEST"},
+		{"nonce without timestamp", "POST", "", "n5", http.StatusBadRequest, "INVALID_SIGNED_REQUEST"},
+		{"malformed timestamp", "POST", now.Format(time.RFC3339), "n6", http.StatusBadRequest, "INVALID_SIGNED_REQUEST"},
+		{"oversized nonce", "POST", unix(0), strings.Repeat("n", 129), http.StatusBadRequest, "INVALID_SIGNED_REQUEST"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "/test", nil)
+			if tt.timestamp != "" {
+				req.Header.Set(TimestampHeader, tt.timestamp)
+			}
+			if tt.nonce != "" {
+				req.Header.Set(NonceHeader, tt.nonce)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Fatalf("Expected status %d, got %d: %s", tt.expectedStatus, w.Code, w.Body.String())
+			}
+			if tt.expectedCode != "" && !strings.Contains(w.Body.String(), tt.expectedCode) {
+				t.Errorf("Expected %s, got %s", tt.expectedCode, w.Body.String())
+			}
+		})
+	}
+
+	// A nonce stays refused for as long as its timestamp is accepted
+	*now = now.Add(4*time.Minute + 59*time.Second)
+	req := httptest.NewRequest("POST", "/test", nil)
+	req.Header.Set(TimestampHeader, unix(-4*time.Minute-59*time.Second))
+	req.Header.Set(NonceHeader, "n1")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected the nonce to still be refused, got %d", w.Code)
+	}
+}
@@ -0,0 +1,136 @@
+/*
+ * Copyright 2026 Cong Wang
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package netguard keeps outbound requests to agent-supplied URLs away from
+// the gateway's internal network.
+package netguard
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"syscall"
+)
+
+// ErrBlocked is returned when a connection would reach an internal address
+// that is not allowlisted
+var ErrBlocked = errors.New("internal address not allowlisted")
+
+// internalNetworks are blocked in addition to what the net.IP predicates cover
+var internalNetworks = []*net.IPNet{
+	mustParseCIDR("0.0.0.0/8"),     // "this network"
+	mustParseCIDR("100.64.0.0/10"), // carrier-grade NAT, home of some cloud metadata services
+}
+
+func mustParseCIDR(cidr string) *net.IPNet {
+	_, network, err := net.ParseCIDR(cidr)
+	if err != nil {
+		panic(err)
+	}
+	return network
+}
+
+// Guard refuses connections to loopback, private, link-local and other
+// internal addresses unless the host name or address is allowlisted. The
+// check runs on the address actually dialed, after DNS resolution, so a host
+// name cannot be rebound to an internal address to get around it.
+type Guard struct {
+	hosts    map[string]struct{}
+	networks []*net.IPNet
+}
+
+// New creates a guard from allowlist entries, each a host name, an IP address
+// or a CIDR. Entries are expected to be validated with the configuration;
+// a malformed CIDR is ignored.
+func New(allowlist []string) *Guard {
+	g := &Guard{hosts: make(map[string]struct{})}
+	for _, entry := range allowlist {
+		entry = strings.ToLower(strings.TrimSpace(entry))
+		switch {
+		case entry == "":
+		case strings.Contains(entry, "/"):
+			if _, network, err := net.ParseCIDR(entry); err == nil {
+				g.networks = append(g.networks, network)
+			}
+		case net.ParseIP(entry) != nil:
+			ip := net.ParseIP(entry)
+			bits := 8 * net.IPv6len
+			if ip4 := ip.To4(); ip4 != nil {
+				ip, bits = ip4, 8*net.IPv4len
+			}
+			g.networks = append(g.networks, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+		default:
+			g.hosts[entry] = struct{}{}
+		}
+	}
+	return g
+}
+
+// IsInternal reports whether ip belongs to a range that is blocked unless
+// allowlisted
+func IsInternal(ip net.IP) bool {
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() {
+		return true
+	}
+	for _, network := range internalNetworks {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// AllowsIP reports whether ip may be dialed
+func (g *Guard) AllowsIP(ip net.IP) bool {
+	for _, network := range g.networks {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return !IsInternal(ip)
+}
+
+// DialContext returns a dial function for http.Transport that fails with
+// ErrBlocked instead of connecting to a disallowed address. Allowlisted host
+// names are dialed without the check. Any Control set on dialer is replaced.
+func (g *Guard) DialContext(dialer *net.Dialer) func(ctx context.Context, network, address string) (net.Conn, error) {
+	guarded := *dialer
+	guarded.Control = g.control
+	return func(ctx context.Context, network, address string) (net.Conn, error) {
+		if host, _, err := net.SplitHostPort(address); err == nil {
+			if _, ok := g.hosts[strings.ToLower(host)]; ok {
+				return dialer.DialContext(ctx, network, address)
+			}
+		}
+		return guarded.DialContext(ctx, network, address)
+	}
+}
+
+func (g *Guard) control(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || !g.AllowsIP(ip) {
+		return fmt.Errorf("%w: %s", ErrBlocked, host)
+	}
+	return nil
+}
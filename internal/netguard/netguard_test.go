@@ -0,0 +1,132 @@
+/*
+ * Copyright 2026 Cong Wang
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package netguard
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestIsInternal(t *testing.T) {
+	tests := []struct {
+		ip       string
+		internal bool
+	}{
+		{"127.0.0.1", true},
+		{"10.1.2.3", true},
+		{"172.16.0.1", true},
+		{"192.168.1.1", true},
+		{"169.254.169.254", true},
+		{"100.100.100.200", true},
+		{"0.0.0.0", true},
+		{"0.1.2.3", true},
+		{"224.0.0.1", true},
+		{"::1", true},
+		{"fd00:ec2::254", true},
+		{"fe80::1", true},
+		{"::ffff:127.0.0.1", true},
+		{"8.8.8.8", false},
+		{"172.32.0.1", false},
+		{"2001:4860:4860::8888", false},
+	}
+
+	for _, tt := range tests {
+		if got := IsInternal(net.ParseIP(tt.ip)); got != tt.internal {
+			t.Errorf("IsInternal(%s) = %v, want %v", tt.ip, got, tt.internal)
+		}
+	}
+}
+
+func TestGuard_AllowsIP(t *testing.T) {
+	g := New([]string{" 10.0.0.0/8 ", "192.168.1.5", "::1", "hooks.internal", "", "not/a/cidr"})
+
+	tests := []struct {
+		ip      string
+		allowed bool
+	}{
+		{"10.20.30.40", true},
+		{"192.168.1.5", true},
+		{"192.168.1.6", false},
+		{"::1", true},
+		{"127.0.0.1", false},
+		{"8.8.8.8", true},
+	}
+
+	for _, tt := range tests {
+		if got := g.AllowsIP(net.ParseIP(tt.ip)); got != tt.allowed {
+			t.Errorf("AllowsIP(%s) = %v, want %v", tt.ip, got, tt.allowed)
+		}
+	}
+	if _, ok := g.hosts["hooks.internal"]; !ok {
+		t.Error("Expected host names to be allowlisted by name")
+	}
+}
+
+func TestGuard_DialContext(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+	_, port, _ := net.SplitHostPort(listener.Addr().String())
+	dialer := &net.Dialer{Timeout: time.Second}
+
+	tests := []struct {
+		name      string
+		allowlist []string
+		address   string
+		blocked   bool
+	}{
+		{"loopback address", nil, "127.0.0.1:" + port, true},
+		{"host name resolving to loopback", nil, "localhost:" + port, true},
+		{"allowlisted address", []string{"127.0.0.1"}, "127.0.0.1:" + port, false},
+		{"allowlisted host name", []string{"LOCALHOST"}, "localhost:" + port, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dial := New(tt.allowlist).DialContext(dialer)
+			conn, err := dial(context.Background(), "tcp4", tt.address)
+			if tt.blocked {
+				if !errors.Is(err, ErrBlocked) {
+					t.Errorf("Expected ErrBlocked, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected the dial to succeed, got %v", err)
+			}
+			conn.Close()
+		})
+	}
+
+	if dialer.Control != nil {
+		t.Error("Expected the caller's dialer to be left unchanged")
+	}
+}
@@ -0,0 +1,140 @@
+/*
+ * Copyright 2026 Cong Wang
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package policy lets an external service decide whether the gateway
+// accepts a message.
+package policy
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/amtp-protocol/agentry/internal/types"
+)
+
+// Accept hook decisions
+const (
+	DecisionAllow  = "allow"
+	DecisionDeny   = "deny"
+	DecisionModify = "modify"
+)
+
+// maxVerdictSize bounds the response read from an accept hook; a modify
+// verdict may carry a replacement payload
+const maxVerdictSize = 16 * 1024 * 1024
+
+// Verdict is an accept hook's answer for one message. A modify verdict
+// replaces the subject, headers or payload it sets; the addressing and
+// identity of the message cannot be changed.
+type Verdict struct {
+	Decision string                 `json:"decision"`
+	Reason   string                 `json:"reason,omitempty"`
+	Subject  *string                `json:"subject,omitempty"`
+	Headers  map[string]interface{} `json:"headers,omitempty"`
+	Payload  json.RawMessage        `json:"payload,omitempty"`
+}
+
+// Apply makes the changes of a modify verdict to message
+func (v *Verdict) Apply(message *types.Message) {
+	if v.Decision != DecisionModify {
+		return
+	}
+	if v.Subject != nil {
+		message.Subject = *v.Subject
+	}
+	if v.Headers != nil {
+		message.Headers = v.Headers
+	}
+	if v.Payload != nil {
+		message.Payload = v.Payload
+	}
+}
+
+// AcceptHook decides whether a message is accepted before it is processed.
+// An error means no verdict was reached.
+type AcceptHook interface {
+	Check(ctx context.Context, message *types.Message) (*Verdict, error)
+}
+
+// HTTPAcceptHook POSTs each message as JSON to a webhook and expects a 2xx
+// response holding a Verdict
+type HTTPAcceptHook struct {
+	url     string
+	timeout time.Duration
+	client  *http.Client
+}
+
+// NewHTTPAcceptHook creates a hook calling url, giving up after timeout
+func NewHTTPAcceptHook(url string, timeout time.Duration) *HTTPAcceptHook {
+	if timeout <= 0 {
+		timeout = 5 * time.Second
+	}
+	return &HTTPAcceptHook{
+		url:     url,
+		timeout: timeout,
+		client: &http.Client{
+			// The hook is configured by the operator, but a redirect could
+			// send message contents somewhere else
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+	}
+}
+
+// Check asks the webhook for a verdict on message
+func (h *HTTPAcceptHook) Check(ctx context.Context, message *types.Message) (*Verdict, error) {
+	body, err := json.Marshal(message)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode message: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, h.timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, h.url, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create accept hook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("accept hook request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, fmt.Errorf("accept hook returned status %d", resp.StatusCode)
+	}
+
+	var verdict Verdict
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxVerdictSize)).Decode(&verdict); err != nil {
+		return nil, fmt.Errorf("invalid accept hook response: %w", err)
+	}
+	switch verdict.Decision {
+	case DecisionAllow, DecisionDeny, DecisionModify:
+	default:
+		return nil, fmt.Errorf("invalid accept hook decision %q", verdict.Decision)
+	}
+	return &verdict, nil
+}
@@ -0,0 +1,135 @@
+/*
+ * Copyright 2026 Cong Wang
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package policy
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/amtp-protocol/agentry/internal/types"
+)
+
+func newHookServer(t *testing.T, status int, body string) (*httptest.Server, *types.Message) {
+	t.Helper()
+	received := &types.Message{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("Unexpected request: %s %s", r.Method, r.Header.Get("Content-Type"))
+		}
+		if err := json.NewDecoder(r.Body).Decode(received); err != nil {
+			t.Errorf("Failed to decode message: %v", err)
+		}
+		w.WriteHeader(status)
+		w.Write([]byte(body))
+	}))
+	t.Cleanup(srv.Close)
+	return srv, received
+}
+
+func TestHTTPAcceptHook_Check(t *testing.T) {
+	message := &types.Message{
+		MessageID:  "01890a5d-ac96-774b-bcce-b302099a8057",
+		Sender:     "sender@example.com",
+		Recipients: []string{"agent@localhost"},
+		Subject:    "Hello",
+		Payload:    json.RawMessage(`{"text":"hi"}`),
+	}
+
+	tests := []struct {
+		name          string
+		status        int
+		body          string
+		decision      string
+		reason        string
+		errorContains string
+	}{
+		{"allow", http.StatusOK, `{"decision":"allow"}`, DecisionAllow, "", ""},
+		{"deny", http.StatusOK, `{"decision":"deny","reason":"spam"}`, DecisionDeny, "spam", ""},
+		{"modify", http.StatusOK, `{"decision":"modify","subject":"[filtered]"}`, DecisionModify, "", ""},
+		{"error status", http.StatusInternalServerError, `{"decision":"allow"}`, "", "", "status 500"},
+		{"unknown decision", http.StatusOK, `{"decision":"maybe"}`, "", "", "invalid accept hook decision"},
+		{"not JSON", http.StatusOK, `allow`, "", "", "invalid accept hook response"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv, received := newHookServer(t, tt.status, tt.body)
+			verdict, err := NewHTTPAcceptHook(srv.URL, time.Second).Check(context.Background(), message)
+			if received.MessageID != message.MessageID || string(received.Payload) != `{"text":"hi"}` {
+				t.Errorf("Expected the hook to receive the message, got %+v", received)
+			}
+			if tt.errorContains != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errorContains) {
+					t.Errorf("Expected error containing %q, got %v", tt.errorContains, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if verdict.Decision != tt.decision || verdict.Reason != tt.reason {
+				t.Errorf("Unexpected verdict: %+v", verdict)
+			}
+		})
+	}
+}
+
+func TestHTTPAcceptHook_Timeout(t *testing.T) {
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer srv.Close()
+	defer close(release)
+
+	start := time.Now()
+	_, err := NewHTTPAcceptHook(srv.URL, 50*time.Millisecond).Check(context.Background(), &types.Message{})
+	if err == nil {
+		t.Fatal("Expected a timeout error")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Expected the hook to give up after its timeout, took %v", elapsed)
+	}
+}
+
+func TestVerdict_Apply(t *testing.T) {
+	subject := "[filtered]"
+	message := &types.Message{
+		Sender:  "sender@example.com",
+		Subject: "Hello",
+		Headers: map[string]interface{}{"priority": "high"},
+		Payload: json.RawMessage(`{"text":"buy now"}`),
+	}
+
+	(&Verdict{Decision: DecisionAllow, Subject: &subject}).Apply(message)
+	if message.Subject != "Hello" {
+		t.Error("Expected an allow verdict to leave the message unchanged")
+	}
+
+	(&Verdict{Decision: DecisionModify, Subject: &subject, Payload: json.RawMessage(`{"text":"[removed]"}`)}).Apply(message)
+	if message.Subject != subject || string(message.Payload) != `{"text":"[removed]"}` {
+		t.Errorf("Expected the subject and payload to be replaced, got %+v", message)
+	}
+	if message.Headers["priority"] != "high" || message.Sender != "sender@example.com" {
+		t.Errorf("Expected fields the verdict does not set to be kept, got %+v", message)
+	}
+}
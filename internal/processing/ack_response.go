@@ -0,0 +1,59 @@
+/*
+ * Copyright 2026 Cong Wang
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package processing
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/amtp-protocol/agentry/internal/types"
+	"github.com/amtp-protocol/agentry/pkg/uuid"
+)
+
+// NewAckResponse builds the message carrying the response recipient
+// acknowledged message with back to its sender, under a message ID from
+// ids. Like a read receipt, it is sent from the recipient through
+// ProcessMessage.
+func NewAckResponse(ids uuid.Generator, message *types.Message, recipient string, response json.RawMessage) (*types.Message, error) {
+	messageID, err := ids.Generate()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate message ID: %w", err)
+	}
+	idempotencyKey, err := uuid.GenerateV4()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate idempotency key: %w", err)
+	}
+
+	subject := "Response"
+	if message.Subject != "" {
+		subject = "Re: " + message.Subject
+	}
+
+	return &types.Message{
+		Version:        "1.0",
+		MessageID:      messageID,
+		IdempotencyKey: idempotencyKey,
+		Timestamp:      time.Now().UTC(),
+		Sender:         recipient,
+		Recipients:     []string{message.Sender},
+		Subject:        subject,
+		Payload:        response,
+		InReplyTo:      message.MessageID,
+		ResponseType:   types.ResponseTypeAckResponse,
+	}, nil
+}
@@ -0,0 +1,134 @@
+/*
+ * Copyright 2026 Cong Wang
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package processing
+
+import (
+	"context"
+	"sync"
+
+	"github.com/amtp-protocol/agentry/internal/agents"
+)
+
+// agentLimiter caps the push deliveries in flight to each local agent, so
+// one slow webhook cannot tie up every delivery worker while other agents
+// wait. Pushes beyond an agent's cap wait for a slot. In-flight counts are
+// kept for every agent, capped or not.
+type agentLimiter struct {
+	defaultMax int // cap for agents that do not set their own; 0 for none
+
+	mu     sync.Mutex
+	agents map[string]*agentSlots
+}
+
+type agentSlots struct {
+	sem      chan struct{} // nil when the agent is not capped
+	inFlight int
+	users    int // pushes holding or waiting for a slot
+}
+
+func newAgentLimiter(defaultMax int) *agentLimiter {
+	return &agentLimiter{
+		defaultMax: defaultMax,
+		agents:     make(map[string]*agentSlots),
+	}
+}
+
+// limit returns the cap that applies to an agent with the given setting
+func (l *agentLimiter) limit(agentMax int) int {
+	if agentMax > 0 {
+		return agentMax
+	}
+	return l.defaultMax
+}
+
+// acquire waits for a slot to address, sized by limit(agentMax). A changed
+// cap takes effect once the agent's pushes in flight have drained. It fails
+// only when ctx ends first; every successful acquire must be paired with
+// release.
+func (l *agentLimiter) acquire(ctx context.Context, address string, agentMax int) error {
+	l.mu.Lock()
+	slots, ok := l.agents[address]
+	if !ok {
+		slots = &agentSlots{}
+		if max := l.limit(agentMax); max > 0 {
+			slots.sem = make(chan struct{}, max)
+		}
+		l.agents[address] = slots
+	}
+	slots.users++
+	l.mu.Unlock()
+
+	if slots.sem != nil {
+		select {
+		case slots.sem <- struct{}{}:
+		case <-ctx.Done():
+			l.mu.Lock()
+			l.leave(address, slots)
+			l.mu.Unlock()
+			return ctx.Err()
+		}
+	}
+
+	l.mu.Lock()
+	slots.inFlight++
+	l.mu.Unlock()
+	return nil
+}
+
+// release frees the slot taken by acquire
+func (l *agentLimiter) release(address string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	slots := l.agents[address]
+	if slots.sem != nil {
+		<-slots.sem
+	}
+	slots.inFlight--
+	l.leave(address, slots)
+}
+
+// leave drops the agent once no push holds or waits for its slots
+func (l *agentLimiter) leave(address string, slots *agentSlots) {
+	slots.users--
+	if slots.users == 0 {
+		delete(l.agents, address)
+	}
+}
+
+// inFlight returns the pushes to address currently holding a slot
+func (l *agentLimiter) inFlight(address string) int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if slots, ok := l.agents[address]; ok {
+		return slots.inFlight
+	}
+	return 0
+}
+
+// AgentDeliveryLimit returns the cap on push deliveries in flight to agent,
+// its own if set and the engine's default otherwise; 0 means no cap
+func (de *DeliveryEngine) AgentDeliveryLimit(agent *agents.LocalAgent) int {
+	return de.agentSlots.limit(agent.MaxConcurrentDeliveries)
+}
+
+// AgentDeliveriesInFlight returns the push deliveries to the agent at
+// address currently in progress
+func (de *DeliveryEngine) AgentDeliveriesInFlight(address string) int {
+	return de.agentSlots.inFlight(address)
+}
@@ -0,0 +1,102 @@
+/*
+ * Copyright 2026 Cong Wang
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package processing
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/amtp-protocol/agentry/internal/types"
+	"github.com/amtp-protocol/agentry/pkg/uuid"
+)
+
+// bounce sends the sender of message a non-delivery report listing the
+// recipients that failed, in the background. Delivery has already retried by
+// the time a recipient is marked failed, so the failure is final. The report
+// goes through ProcessMessage like any other message: into the sender's inbox
+// when the sender is local, relayed to the sender's gateway otherwise.
+func (mp *MessageProcessor) bounce(ctx context.Context, message *types.Message, result *ProcessingResult) {
+	if mp.bounceSender == "" || message.ResponseType == types.ResponseTypeNonDelivery {
+		return
+	}
+
+	var failed []types.FailedRecipient
+	for _, rs := range result.Recipients {
+		if rs.Status == types.StatusFailed {
+			failed = append(failed, types.FailedRecipient{
+				Address:      rs.Address,
+				ErrorCode:    rs.ErrorCode,
+				ErrorMessage: rs.ErrorMessage,
+			})
+		}
+	}
+	if len(failed) == 0 {
+		return
+	}
+
+	report, err := newNonDeliveryReport(mp.messageIDs, mp.bounceSender, message, failed)
+	if err != nil {
+		return
+	}
+
+	bgCtx := context.WithoutCancel(ctx)
+	mp.background.Add(1)
+	go func() {
+		defer mp.background.Done()
+		_, _ = mp.ProcessMessage(bgCtx, report, ProcessingOptions{ImmediatePath: true})
+	}()
+}
+
+// newNonDeliveryReport builds the report of failed recipients of message
+func newNonDeliveryReport(ids uuid.Generator, sender string, message *types.Message, failed []types.FailedRecipient) (*types.Message, error) {
+	messageID, err := ids.Generate()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate message ID: %w", err)
+	}
+	idempotencyKey, err := uuid.GenerateV4()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate idempotency key: %w", err)
+	}
+	payload, err := json.Marshal(types.NonDeliveryReport{
+		OriginalMessageID: message.MessageID,
+		OriginalSubject:   message.Subject,
+		FailedRecipients:  failed,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode non-delivery report: %w", err)
+	}
+
+	subject := "Undeliverable message"
+	if message.Subject != "" {
+		subject = "Undeliverable: " + message.Subject
+	}
+
+	return &types.Message{
+		Version:        "1.0",
+		MessageID:      messageID,
+		IdempotencyKey: idempotencyKey,
+		Timestamp:      time.Now().UTC(),
+		Sender:         sender,
+		Recipients:     []string{message.Sender},
+		Subject:        subject,
+		Payload:        payload,
+		InReplyTo:      message.MessageID,
+		ResponseType:   types.ResponseTypeNonDelivery,
+	}, nil
+}
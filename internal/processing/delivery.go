@@ -19,19 +19,29 @@ package processing
 import (
 	"bytes"
 	"context"
+	"crypto"
 	"crypto/tls"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net"
 	"net/http"
+	"net/http/httptrace"
+	"strconv"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/amtp-protocol/agentry/internal/agents"
 	"github.com/amtp-protocol/agentry/internal/discovery"
+	"github.com/amtp-protocol/agentry/internal/metrics"
+	"github.com/amtp-protocol/agentry/internal/netguard"
 	"github.com/amtp-protocol/agentry/internal/schema"
+	"github.com/amtp-protocol/agentry/internal/signing"
+	"github.com/amtp-protocol/agentry/internal/storage"
 	"github.com/amtp-protocol/agentry/internal/types"
+	"github.com/amtp-protocol/agentry/pkg/uuid"
 )
 
 // SchemaManager interface for schema validation
@@ -43,24 +53,125 @@ type SchemaManager interface {
 // DeliveryEngine handles outbound message delivery
 type DeliveryEngine struct {
 	httpClient    *http.Client
+	pushClient    *http.Client // guarded against internal addresses
 	discovery     DiscoveryService
 	agentRegistry agents.AgentRegistry // for managing local agents
 	config        DeliveryConfig
 	localDomain   string
+	relays        *relayLimiter // nil when relays are not capped
+	agentSlots    *agentLimiter // push deliveries in flight per local agent
+	balancer      *pushBalancer // targets of agents with the balanced push strategy
 }
 
+// lastAccessGranularity is how stale an agent's last access may get before
+// a delivery refreshes it
+const lastAccessGranularity = time.Minute
+
+// Defaults used when DeliveryConfig leaves a setting unset
+const (
+	defaultConnectTimeout  = 10 * time.Second
+	defaultResponseTimeout = 30 * time.Second
+	defaultMaxRetries      = 3
+	defaultRetryDelay      = 1 * time.Second
+
+	// maxRetryDelay caps both the backoff and a delay asked for by Retry-After
+	maxRetryDelay = 5 * time.Minute
+)
+
 // DeliveryConfig defines delivery engine configuration
 type DeliveryConfig struct {
-	Timeout        time.Duration
-	MaxRetries     int
-	RetryDelay     time.Duration
-	MaxConnections int
-	IdleTimeout    time.Duration
-	TLSConfig      *tls.Config
-	UserAgent      string
-	MaxMessageSize int64
-	AllowHTTP      bool
-	LocalDomain    string
+	Timeout         time.Duration // Caps a whole request including the response body; zero means no cap
+	ConnectTimeout  time.Duration // Bounds dialing and the TLS handshake
+	ResponseTimeout time.Duration // Bounds the wait for response headers; a response in progress is not cut off
+	MaxRetries      int
+	RetryDelay      time.Duration
+	MaxConnections  int           // Idle connections kept across all hosts
+	IdleTimeout     time.Duration // How long an idle connection is kept for reuse
+	TLSConfig       *tls.Config
+	UserAgent       string
+	MaxMessageSize  int64
+	AllowHTTP       bool
+	LocalDomain     string
+
+	// Connections to a single gateway or webhook: idle ones kept for reuse,
+	// defaulting to a quarter of MaxConnections, and the total allowed at
+	// once, where zero means no cap
+	MaxIdleConnsPerHost int
+	MaxConnsPerHost     int
+
+	// MaxConcurrentPerDomain caps relay attempts in flight to one remote
+	// domain; further relays wait for a slot. Zero means no cap.
+	MaxConcurrentPerDomain int
+	Metrics                metrics.MetricsProvider // Optional; receives per-domain relays in flight
+
+	// MaxConcurrentPerAgent caps push deliveries in flight to one local
+	// agent that does not set MaxConcurrentDeliveries itself; further
+	// pushes wait for a slot. Zero means no cap.
+	MaxConcurrentPerAgent int
+
+	// Push targets may only resolve to internal addresses matching these
+	// host names, IPs or CIDRs
+	PushTargetAllowlist []string
+
+	// Signer signs unsigned messages from the local domain before they are
+	// relayed, under the key published as SigningKeyID. Nil disables signing.
+	Signer       crypto.Signer
+	SigningKeyID string
+
+	// UnknownRecipient decides what happens to local recipients no agent,
+	// not even a catch-all, is registered for. DeadLetterAddress always
+	// receives into its inbox, registered or not.
+	UnknownRecipient  UnknownRecipientMode
+	DeadLetterAddress string
+
+	// KafkaProducer delivers to agents in kafka delivery mode. Nil fails
+	// their deliveries.
+	KafkaProducer KafkaProducer
+
+	// AddressSchemes are the accepted recipient address forms; addresses of
+	// local-only schemes are delivered locally. Nil means
+	// types.DefaultAddressSchemes.
+	AddressSchemes types.AddressSchemes
+}
+
+// UnknownRecipientMode is the handling of unregistered local recipients
+type UnknownRecipientMode string
+
+const (
+	// UnknownRecipientInbox delivers into a pull inbox the agent can read
+	// once registered. This is the default.
+	UnknownRecipientInbox UnknownRecipientMode = "inbox"
+	// UnknownRecipientReject fails the recipient
+	UnknownRecipientReject UnknownRecipientMode = "reject"
+	// UnknownRecipientQueue holds the recipient as queued, for the message
+	// processor to retry when an agent registers
+	UnknownRecipientQueue UnknownRecipientMode = "queue"
+	// UnknownRecipientDeadLetter fails the recipient, for the message
+	// processor to forward the message to the dead-letter address
+	UnknownRecipientDeadLetter UnknownRecipientMode = "dead-letter"
+)
+
+// Error codes of recipients no agent is registered for
+const (
+	ErrorCodeAgentNotFound      = "AGENT_NOT_FOUND"
+	ErrorCodeAgentNotRegistered = storage.ErrorCodeAgentNotRegistered // held in queue mode
+)
+
+// RetryPolicy overrides the engine's MaxRetries and RetryDelay for the
+// deliveries made with a context; zero fields keep the engine's values
+type RetryPolicy struct {
+	MaxRetries int
+	RetryDelay time.Duration
+}
+
+// contextKey is used for context keys to avoid collisions
+type contextKey string
+
+const retryPolicyKey contextKey = "retry_policy"
+
+// WithRetryPolicy returns a context whose deliveries follow policy
+func WithRetryPolicy(ctx context.Context, policy RetryPolicy) context.Context {
+	return context.WithValue(ctx, retryPolicyKey, policy)
 }
 
 // DeliveryResult represents the result of a delivery attempt
@@ -73,24 +184,44 @@ type DeliveryResult struct {
 	Timestamp     time.Time
 	Attempts      int
 	NextRetry     *time.Time
-	DeliveryMode  string // "push" or "pull"
-	LocalDelivery bool   // true if delivered locally
+	RetryAfter    time.Duration // delay asked for by a 429 or 503 response, if any
+	DeliveryMode  string        // "push", "pull" or "kafka"
+	LocalDelivery bool          // true if delivered locally
 }
 
 // NewDeliveryEngine creates a new delivery engine
 func NewDeliveryEngine(discovery DiscoveryService, agentRegistry agents.AgentRegistry, config DeliveryConfig) *DeliveryEngine {
+	if config.ConnectTimeout <= 0 {
+		config.ConnectTimeout = defaultConnectTimeout
+	}
+	if config.ResponseTimeout <= 0 {
+		config.ResponseTimeout = defaultResponseTimeout
+	}
+	if config.MaxRetries <= 0 {
+		config.MaxRetries = defaultMaxRetries
+	}
+	if config.RetryDelay <= 0 {
+		config.RetryDelay = defaultRetryDelay
+	}
+	if config.MaxIdleConnsPerHost <= 0 {
+		config.MaxIdleConnsPerHost = config.MaxConnections / 4
+	}
+
 	// Create HTTP transport with connection pooling
+	dialer := &net.Dialer{
+		Timeout:   config.ConnectTimeout,
+		KeepAlive: 30 * time.Second,
+	}
 	transport := &http.Transport{
-		DialContext: (&net.Dialer{
-			Timeout:   30 * time.Second,
-			KeepAlive: 30 * time.Second,
-		}).DialContext,
-		MaxIdleConns:        config.MaxConnections,
-		MaxIdleConnsPerHost: config.MaxConnections / 4,
-		IdleConnTimeout:     config.IdleTimeout,
-		TLSHandshakeTimeout: 10 * time.Second,
-		TLSClientConfig:     config.TLSConfig,
-		DisableCompression:  false,
+		DialContext:           dialer.DialContext,
+		MaxIdleConns:          config.MaxConnections,
+		MaxIdleConnsPerHost:   config.MaxIdleConnsPerHost,
+		MaxConnsPerHost:       config.MaxConnsPerHost,
+		IdleConnTimeout:       config.IdleTimeout,
+		TLSHandshakeTimeout:   config.ConnectTimeout,
+		ResponseHeaderTimeout: config.ResponseTimeout,
+		TLSClientConfig:       config.TLSConfig,
+		DisableCompression:    false,
 	}
 
 	// Create HTTP client
@@ -106,12 +237,23 @@ func NewDeliveryEngine(discovery DiscoveryService, agentRegistry agents.AgentReg
 		},
 	}
 
+	// Push targets are supplied by agents, so they must not be able to point
+	// deliveries at the gateway's own network, e.g. a cloud metadata service
+	pushTransport := transport.Clone()
+	pushTransport.DialContext = netguard.New(config.PushTargetAllowlist).DialContext(dialer)
+	pushClient := *httpClient
+	pushClient.Transport = pushTransport
+
 	return &DeliveryEngine{
 		httpClient:    httpClient,
+		pushClient:    &pushClient,
 		discovery:     discovery,
 		agentRegistry: agentRegistry,
 		config:        config,
 		localDomain:   config.LocalDomain,
+		relays:        newRelayLimiter(config.MaxConcurrentPerDomain, config.Metrics),
+		agentSlots:    newAgentLimiter(config.MaxConcurrentPerAgent),
+		balancer:      newPushBalancer(),
 	}
 }
 
@@ -124,12 +266,12 @@ func (de *DeliveryEngine) DeliverMessage(ctx context.Context, message *types.Mes
 	}
 
 	// Extract domain from recipient
-	domain := discovery.ExtractDomain(recipient)
-	if domain == "" {
+	domain, ok := de.recipientDomain(recipient)
+	if !ok {
 		result.Status = types.StatusFailed
 		result.ErrorCode = "INVALID_RECIPIENT"
-		result.ErrorMessage = "invalid recipient email format"
-		return result, fmt.Errorf("invalid recipient email format: %s", recipient)
+		result.ErrorMessage = "invalid recipient address format"
+		return result, fmt.Errorf("invalid recipient address format: %s", recipient)
 	}
 
 	// Check if this is a local delivery (same domain as gateway)
@@ -154,12 +296,34 @@ func (de *DeliveryEngine) DeliverMessage(ctx context.Context, message *types.Mes
 		return result, fmt.Errorf("invalid gateway URL for %s: %w", domain, err)
 	}
 
+	// Message IDs of other strategies than UUIDv7 only go to gateways that
+	// accept them
+	for _, id := range []string{message.MessageID, message.InReplyTo} {
+		strategy := uuid.Strategy(id)
+		if strategy == "" || strategy == uuid.StrategyUUIDv7 || capabilities.HasFeature(uuid.Feature(strategy)) {
+			continue
+		}
+		result.Status = types.StatusFailed
+		result.ErrorCode = "MESSAGE_ID_UNSUPPORTED"
+		result.ErrorMessage = fmt.Sprintf("%s does not accept message ID %s", domain, id)
+		return result, fmt.Errorf("message ID %s not accepted by %s", id, domain)
+	}
+
 	// Schema support is enforced authoritatively by the receiving gateway,
 	// which validates each message against its local agents' supported schemas
 	// on receipt. There is intentionally no sender-side preflight: schemas are
 	// not advertised via DNS discovery, so the sender cannot (and need not)
 	// determine a remote domain's supported schemas before delivery.
 
+	// Sign locally originated messages so the receiving gateway can verify them
+	message, err = de.sign(message, recipient)
+	if err != nil {
+		result.Status = types.StatusFailed
+		result.ErrorCode = "SIGNING_FAILED"
+		result.ErrorMessage = fmt.Sprintf("failed to sign message: %v", err)
+		return result, err
+	}
+
 	// Check message size limits
 	if capabilities.MaxSize > 0 && message.Size() > capabilities.MaxSize {
 		result.Status = types.StatusFailed
@@ -172,15 +336,84 @@ func (de *DeliveryEngine) DeliverMessage(ctx context.Context, message *types.Mes
 	return de.attemptDeliveryWithRetries(ctx, message, recipient, capabilities, result)
 }
 
+// recipientDomain returns the domain that routes recipient, the local domain
+// for addresses of local-only schemes
+func (de *DeliveryEngine) recipientDomain(recipient string) (string, bool) {
+	schemes := de.config.AddressSchemes
+	if schemes == nil {
+		schemes = types.DefaultAddressSchemes
+	}
+	domain, ok := schemes.Domain(recipient, de.localDomain)
+	return domain, ok && domain != ""
+}
+
+// sign returns the message signed for recipient with the gateway's key when
+// it originated in the local domain and carries no signature yet, so a
+// message relayed on from elsewhere keeps its original signature. The message
+// passed in is left untouched, since batch deliveries share it.
+func (de *DeliveryEngine) sign(message *types.Message, recipient string) (*types.Message, error) {
+	if de.config.Signer == nil || message.Signature != nil ||
+		!strings.EqualFold(discovery.ExtractDomain(message.Sender), de.localDomain) {
+		return message, nil
+	}
+
+	signed := *message
+	signed.Recipients = []string{recipient}
+	if err := signing.Sign(&signed, de.config.Signer, de.config.SigningKeyID); err != nil {
+		return nil, err
+	}
+	return &signed, nil
+}
+
+// stampRequest sets a relay request's timestamp and a fresh nonce, and signs
+// them with the body when the gateway has a signing key
+func (de *DeliveryEngine) stampRequest(req *http.Request, body []byte) error {
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	nonce, err := uuid.GenerateV4()
+	if err != nil {
+		return err
+	}
+	req.Header.Set(signing.TimestampHeader, timestamp)
+	req.Header.Set(signing.NonceHeader, nonce)
+
+	if de.config.Signer == nil {
+		return nil
+	}
+	signature, err := signing.SignRequest(de.config.Signer, de.localDomain, de.config.SigningKeyID,
+		req.Method, req.URL.Path, timestamp, nonce, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set(signing.RequestSignatureHeader, signature)
+	return nil
+}
+
 // attemptDeliveryWithRetries attempts delivery with retry logic
 func (de *DeliveryEngine) attemptDeliveryWithRetries(ctx context.Context, message *types.Message, recipient string, capabilities *discovery.AMTPCapabilities, result *DeliveryResult) (*DeliveryResult, error) {
 	var lastErr error
+	maxRetries, retryDelay := de.retryPolicy(ctx)
+	domain := discovery.ExtractDomain(recipient)
 
-	for attempt := 1; attempt <= de.config.MaxRetries; attempt++ {
+	for attempt := 1; attempt <= maxRetries; attempt++ {
 		result.Attempts = attempt
 
+		// Wait for a slot to the domain. Slots are taken per attempt, not
+		// held through the retry delay, so other relays proceed meanwhile.
+		if de.relays != nil {
+			if err := de.relays.acquire(ctx, domain); err != nil {
+				result.Status = types.StatusFailed
+				result.ErrorCode = "CONTEXT_CANCELED"
+				result.ErrorMessage = "delivery canceled while waiting for a relay slot"
+				return result, err
+			}
+		}
+
 		// Attempt delivery
+		result.RetryAfter = 0
 		deliveryErr := de.attemptSingleDelivery(ctx, message, recipient, capabilities, result)
+		if de.relays != nil {
+			de.relays.release(domain)
+		}
 		if deliveryErr == nil {
 			// Success
 			result.Status = types.StatusDelivered
@@ -195,13 +428,21 @@ func (de *DeliveryEngine) attemptDeliveryWithRetries(ctx context.Context, messag
 		}
 
 		// Don't retry on last attempt
-		if attempt == de.config.MaxRetries {
+		if attempt == maxRetries {
 			break
 		}
 
-		// Calculate next retry time
-		retryDelay := de.calculateRetryDelay(attempt)
-		nextRetry := time.Now().Add(retryDelay)
+		// Calculate next retry time, waiting as long as a rate-limited
+		// gateway asked instead of backing off
+		delay := retryBackoff(retryDelay, attempt)
+		if result.RetryAfter > 0 {
+			delay = min(result.RetryAfter, maxRetryDelay)
+		}
+		// A delay that would outlast the deadline is not waited out
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < delay {
+			break
+		}
+		nextRetry := time.Now().Add(delay)
 		result.NextRetry = &nextRetry
 
 		// Wait for retry delay or context cancellation
@@ -211,7 +452,7 @@ func (de *DeliveryEngine) attemptDeliveryWithRetries(ctx context.Context, messag
 			result.ErrorCode = "CONTEXT_CANCELED"
 			result.ErrorMessage = "delivery canceled"
 			return result, ctx.Err()
-		case <-time.After(retryDelay):
+		case <-time.After(delay):
 			// Continue to next attempt
 		}
 	}
@@ -248,6 +489,9 @@ func (de *DeliveryEngine) attemptSingleDelivery(ctx context.Context, message *ty
 		"in_reply_to":     message.InReplyTo,
 		"response_type":   message.ResponseType,
 	}
+	if message.Encrypted {
+		deliveryPayload["encrypted"] = true
+	}
 
 	// Marshal payload
 	payloadBytes, err := json.Marshal(deliveryPayload)
@@ -278,10 +522,21 @@ func (de *DeliveryEngine) attemptSingleDelivery(ctx context.Context, message *ty
 		req.Header.Set("X-AMTP-Version", "1.0")
 	}
 
+	// Stamp each attempt against replay, so retries are not refused
+	if err := de.stampRequest(req, payloadBytes); err != nil {
+		result.ErrorCode = "SIGNING_FAILED"
+		result.ErrorMessage = fmt.Sprintf("failed to sign request: %v", err)
+		return fmt.Errorf("failed to sign request: %w", err)
+	}
+
 	// Perform HTTP request
-	resp, err := de.httpClient.Do(req)
+	resp, err := de.doRequest(de.httpClient, req)
 	if err != nil {
 		result.ErrorCode = "HTTP_REQUEST_FAILED"
+		var transportErr *transportError
+		if errors.As(err, &transportErr) {
+			result.ErrorCode = transportErr.code
+		}
 		result.ErrorMessage = fmt.Sprintf("HTTP request failed: %v", err)
 		return fmt.Errorf("HTTP request failed: %w", err)
 	}
@@ -290,6 +545,9 @@ func (de *DeliveryEngine) attemptSingleDelivery(ctx context.Context, message *ty
 	}()
 
 	result.StatusCode = resp.StatusCode
+	if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusServiceUnavailable {
+		result.RetryAfter, _ = parseRetryAfter(resp.Header.Get("Retry-After"), time.Now())
+	}
 
 	// Read response body
 	bodyBytes, err := io.ReadAll(resp.Body)
@@ -306,6 +564,12 @@ func (de *DeliveryEngine) attemptSingleDelivery(ctx context.Context, message *ty
 		// Success
 		return nil
 
+	case resp.StatusCode == http.StatusTooManyRequests:
+		// Rate limited - retryable, after Retry-After when given
+		result.ErrorCode = "RATE_LIMITED"
+		result.ErrorMessage = fmt.Sprintf("rate limited: %s", result.ResponseBody)
+		return fmt.Errorf("rate limited: %s", result.ResponseBody)
+
 	case resp.StatusCode >= 400 && resp.StatusCode < 500:
 		// Client error - usually not retryable
 		result.ErrorCode = "CLIENT_ERROR"
@@ -326,8 +590,56 @@ func (de *DeliveryEngine) attemptSingleDelivery(ctx context.Context, message *ty
 	}
 }
 
+// transportError is a request that failed before any response arrived, with
+// the delivery error code that tells a dead endpoint from a slow one
+type transportError struct {
+	code string
+	err  error
+}
+
+func (e *transportError) Error() string {
+	return e.err.Error()
+}
+
+func (e *transportError) Unwrap() error {
+	return e.err
+}
+
+// doRequest performs req with client and classifies a failure as
+// TARGET_BLOCKED when the guard refused the address, CONNECTION_FAILED when
+// no connection was established, or RESPONSE_TIMEOUT when the endpoint was
+// reached but did not answer in time
+func (de *DeliveryEngine) doRequest(client *http.Client, req *http.Request) (*http.Response, error) {
+	var connected atomic.Bool
+	trace := &httptrace.ClientTrace{
+		GotConn: func(httptrace.GotConnInfo) { connected.Store(true) },
+	}
+
+	resp, err := client.Do(req.WithContext(httptrace.WithClientTrace(req.Context(), trace)))
+	if err == nil {
+		return resp, nil
+	}
+
+	if errors.Is(err, netguard.ErrBlocked) {
+		return nil, &transportError{code: "TARGET_BLOCKED", err: err}
+	}
+	if !connected.Load() {
+		return nil, &transportError{code: "CONNECTION_FAILED", err: err}
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return nil, &transportError{code: "RESPONSE_TIMEOUT", err: err}
+	}
+	return nil, err
+}
+
 // isRetryableError determines if an error is retryable
 func (de *DeliveryEngine) isRetryableError(statusCode int, err error) bool {
+	// A target the network guard refused stays refused
+	if errors.Is(err, netguard.ErrBlocked) {
+		return false
+	}
+
 	// Network errors are generally retryable
 	if err != nil {
 		// Check for network-related errors
@@ -362,17 +674,31 @@ func (de *DeliveryEngine) isRetryableError(statusCode int, err error) bool {
 	return false
 }
 
-// calculateRetryDelay calculates the delay before the next retry attempt
-func (de *DeliveryEngine) calculateRetryDelay(attempt int) time.Duration {
+// retryPolicy returns the attempts and base retry delay for a delivery,
+// applying any RetryPolicy carried by ctx over the engine configuration.
+// There is always at least one attempt, even with retries configured off.
+func (de *DeliveryEngine) retryPolicy(ctx context.Context) (int, time.Duration) {
+	maxRetries, retryDelay := de.config.MaxRetries, de.config.RetryDelay
+	if policy, ok := ctx.Value(retryPolicyKey).(RetryPolicy); ok {
+		if policy.MaxRetries > 0 {
+			maxRetries = policy.MaxRetries
+		}
+		if policy.RetryDelay > 0 {
+			retryDelay = policy.RetryDelay
+		}
+	}
+	return max(1, maxRetries), retryDelay
+}
+
+// retryBackoff grows baseDelay exponentially with the attempt number
+func retryBackoff(baseDelay time.Duration, attempt int) time.Duration {
 	// Exponential backoff with jitter
-	baseDelay := de.config.RetryDelay
 	// #nosec G115 -- Never overflow
 	delay := baseDelay * time.Duration(1<<uint(attempt-1)) // 2^(attempt-1)
 
 	// Cap the maximum delay
-	maxDelay := 5 * time.Minute
-	if delay > maxDelay {
-		delay = maxDelay
+	if delay > maxRetryDelay {
+		delay = maxRetryDelay
 	}
 
 	// Add jitter (±25%)
@@ -382,6 +708,32 @@ func (de *DeliveryEngine) calculateRetryDelay(attempt int) time.Duration {
 	return delay
 }
 
+// parseRetryAfter parses a Retry-After header given either as delay seconds or
+// as an HTTP date, which is taken relative to now. A date in the past asks for
+// no delay.
+func parseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.ParseInt(value, 10, 64); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		if seconds > int64(maxRetryDelay/time.Second) {
+			return maxRetryDelay, true
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		if delay := date.Sub(now); delay > 0 {
+			return delay, true
+		}
+		return 0, true
+	}
+	return 0, false
+}
+
 // DeliverBatch delivers a message to multiple recipients in parallel
 func (de *DeliveryEngine) DeliverBatch(ctx context.Context, message *types.Message, recipients []string) (map[string]*DeliveryResult, error) {
 	results := make(map[string]*DeliveryResult)
@@ -418,17 +770,33 @@ func (de *DeliveryEngine) DeliverBatch(ctx context.Context, message *types.Messa
 
 // deliverLocal handles local delivery for recipients in the same domain
 func (de *DeliveryEngine) deliverLocal(ctx context.Context, message *types.Message, recipient string, result *DeliveryResult) (*DeliveryResult, error) {
-	agent, err := de.agentRegistry.GetAgent(ctx, recipient)
+	agent, err := de.resolveLocalAgent(ctx, recipient, result)
+	if errors.Is(err, agents.ErrAgentNotFound) {
+		return de.deliverUnknownLocal(ctx, message, recipient, result)
+	}
 	if err != nil {
-		// Default to pull mode if agent is not registered
-		return de.deliverLocalPull(ctx, message, recipient, result)
+		return result, err
+	}
+
+	if !agent.AllowsSender(message.Sender) {
+		result.Status = types.StatusFailed
+		result.LocalDelivery = true
+		result.ErrorCode = "SENDER_NOT_ALLOWED"
+		result.ErrorMessage = fmt.Sprintf("agent does not accept messages from %s", message.Sender)
+		return result, fmt.Errorf("agent does not accept messages from %s", message.Sender)
 	}
 
 	switch agent.DeliveryMode {
 	case "push":
-		return de.deliverLocalPush(ctx, message, recipient, agent, result)
+		result, err = de.deliverLocalPush(ctx, message, recipient, agent, result)
+		de.touchAgent(ctx, agent, result)
+		return result, err
 	case "pull":
 		return de.deliverLocalPull(ctx, message, recipient, result)
+	case "kafka":
+		result, err = de.deliverLocalKafka(ctx, message, recipient, agent, result)
+		de.touchAgent(ctx, agent, result)
+		return result, err
 	default:
 		result.Status = types.StatusFailed
 		result.ErrorCode = "INVALID_DELIVERY_MODE"
@@ -437,17 +805,230 @@ func (de *DeliveryEngine) deliverLocal(ctx context.Context, message *types.Messa
 	}
 }
 
-// deliverLocalPush delivers a message via push (webhook) to a local agent
+// touchAgent records a push or kafka delivery as agent activity, as polling
+// the inbox does for pull agents, so agents that only receive by push are not
+// taken for idle. The refresh is coarse to spare storage a write per message.
+func (de *DeliveryEngine) touchAgent(ctx context.Context, agent *agents.LocalAgent, result *DeliveryResult) {
+	if result.Status == types.StatusDelivered && time.Since(agent.LastAccess) >= lastAccessGranularity {
+		de.agentRegistry.UpdateLastAccess(ctx, agent.Address)
+	}
+}
+
+// deliverUnknownLocal handles a recipient neither an agent nor a catch-all
+// agent is registered for, following the UnknownRecipient mode
+func (de *DeliveryEngine) deliverUnknownLocal(ctx context.Context, message *types.Message, recipient string, result *DeliveryResult) (*DeliveryResult, error) {
+	if strings.EqualFold(recipient, de.config.DeadLetterAddress) {
+		return de.deliverLocalPull(ctx, message, recipient, result)
+	}
+
+	switch de.config.UnknownRecipient {
+	case UnknownRecipientReject, UnknownRecipientDeadLetter:
+		result.Status = types.StatusFailed
+		result.LocalDelivery = true
+		result.ErrorCode = ErrorCodeAgentNotFound
+		result.ErrorMessage = fmt.Sprintf("no agent is registered for %s", recipient)
+		return result, fmt.Errorf("no agent is registered for %s", recipient)
+	case UnknownRecipientQueue:
+		result.Status = types.StatusQueued
+		result.Timestamp = time.Now().UTC()
+		result.LocalDelivery = true
+		result.ErrorCode = ErrorCodeAgentNotRegistered
+		result.ErrorMessage = fmt.Sprintf("held until an agent is registered for %s", recipient)
+		return result, nil
+	default:
+		return de.deliverLocalPull(ctx, message, recipient, result)
+	}
+}
+
+// resolveLocalAgent looks up the agent receiving recipient's messages. An
+// unknown recipient is final, but any other lookup failure comes from
+// storage and is retried with the same backoff as relay delivery, rather
+// than mistaking a storage hiccup for an unregistered agent.
+func (de *DeliveryEngine) resolveLocalAgent(ctx context.Context, recipient string, result *DeliveryResult) (*agents.LocalAgent, error) {
+	var lastErr error
+	maxRetries, retryDelay := de.retryPolicy(ctx)
+
+	for attempt := 1; attempt <= maxRetries; attempt++ {
+		result.Attempts = attempt
+
+		agent, err := de.agentRegistry.ResolveAgent(ctx, recipient)
+		if err == nil || errors.Is(err, agents.ErrAgentNotFound) {
+			result.NextRetry = nil
+			return agent, err
+		}
+		lastErr = err
+
+		if attempt == maxRetries {
+			break
+		}
+
+		delay := retryBackoff(retryDelay, attempt)
+		nextRetry := time.Now().Add(delay)
+		result.NextRetry = &nextRetry
+
+		select {
+		case <-ctx.Done():
+			result.Status = types.StatusFailed
+			result.ErrorCode = "CONTEXT_CANCELED"
+			result.ErrorMessage = "local delivery canceled"
+			return nil, ctx.Err()
+		case <-time.After(delay):
+		}
+	}
+
+	result.Status = types.StatusFailed
+	result.LocalDelivery = true
+	result.ErrorCode = "AGENT_LOOKUP_FAILED"
+	result.ErrorMessage = fmt.Sprintf("agent lookup failed after %d attempts: %v", result.Attempts, lastErr)
+	return nil, fmt.Errorf("agent lookup failed after %d attempts: %w", result.Attempts, lastErr)
+}
+
+// deliverLocalPush delivers a message via push (webhook) to a local agent.
+// Agents may configure several push targets. With the fanout strategy every
+// target receives the same payload and the overall outcome follows the
+// agent's push policy; with the balanced strategy one target receives it.
 func (de *DeliveryEngine) deliverLocalPush(ctx context.Context, message *types.Message, recipient string, agent *agents.LocalAgent, result *DeliveryResult) (*DeliveryResult, error) {
-	if agent.PushTarget == "" {
+	targets := agent.AllPushTargets()
+	if len(targets) == 0 {
 		result.Status = types.StatusFailed
 		result.ErrorCode = "MISSING_PUSH_TARGET"
 		result.ErrorMessage = "push target URL is required for push delivery mode"
 		return result, fmt.Errorf("push target URL is required for push delivery mode")
 	}
 
-	// Prepare delivery payload for local agent
-	deliveryPayload := map[string]interface{}{
+	// Marshal payload
+	payloadBytes, err := json.Marshal(localDeliveryPayload(message, recipient))
+	if err != nil {
+		result.Status = types.StatusFailed
+		result.ErrorCode = "PAYLOAD_MARSHAL_FAILED"
+		result.ErrorMessage = fmt.Sprintf("failed to marshal payload: %v", err)
+		return result, fmt.Errorf("failed to marshal payload: %w", err)
+	}
+
+	result.DeliveryMode = "push"
+	result.LocalDelivery = true
+
+	headers, err := agents.RenderHeaders(agent.Headers, agents.NewHeaderTemplateData(message, recipient))
+	if err != nil {
+		result.Status = types.StatusFailed
+		result.ErrorCode = "HEADER_TEMPLATE_FAILED"
+		result.ErrorMessage = fmt.Sprintf("failed to render push headers: %v", err)
+		return result, fmt.Errorf("failed to render push headers: %w", err)
+	}
+
+	// Wait for a slot to the agent, held for the whole push including its
+	// retries so the agent's cap bounds the requests it has to answer
+	if err := de.agentSlots.acquire(ctx, agent.Address, agent.MaxConcurrentDeliveries); err != nil {
+		result.Status = types.StatusFailed
+		result.ErrorCode = "CONTEXT_CANCELED"
+		result.ErrorMessage = "delivery canceled while waiting for an agent delivery slot"
+		return result, err
+	}
+	defer de.agentSlots.release(agent.Address)
+
+	if agent.PushStrategy == agents.PushStrategyBalanced {
+		return de.deliverBalancedPush(ctx, agent, targets, payloadBytes, headers, result)
+	}
+
+	// Fan out to every target, remembering the first success and failures
+	var failures []string
+	var transportErr *transportError
+	succeeded := 0
+	for _, target := range targets {
+		statusCode, body, err := de.pushToTarget(ctx, target, payloadBytes, headers)
+		if err != nil {
+			failures = append(failures, fmt.Sprintf("%s: %v", target, err))
+			if transportErr == nil {
+				errors.As(err, &transportErr)
+			}
+			if result.StatusCode == 0 {
+				result.StatusCode = statusCode
+				result.ResponseBody = body
+			}
+			continue
+		}
+		if succeeded == 0 {
+			result.StatusCode = statusCode
+			result.ResponseBody = body
+		}
+		succeeded++
+	}
+
+	policy := agent.PushPolicy
+	if policy == "" {
+		policy = agents.PushPolicyAll
+	}
+
+	delivered := len(failures) == 0
+	if policy == agents.PushPolicyAny {
+		delivered = succeeded > 0
+	}
+
+	if delivered {
+		result.Status = types.StatusDelivered
+		return result, nil
+	}
+
+	// Push delivery failed
+	result.Status = types.StatusFailed
+	result.ErrorCode = pushErrorCode(len(targets), result.StatusCode, transportErr)
+	result.ErrorMessage = fmt.Sprintf("push delivery failed for %d of %d target(s) (policy %s): %s",
+		len(failures), len(targets), policy, strings.Join(failures, "; "))
+	return result, fmt.Errorf("%s", result.ErrorMessage)
+}
+
+// deliverBalancedPush delivers to the push target whose turn it is, failing
+// over to the agent's other targets in turn until one accepts the message
+func (de *DeliveryEngine) deliverBalancedPush(ctx context.Context, agent *agents.LocalAgent, targets []string, payload []byte, headers map[string]string, result *DeliveryResult) (*DeliveryResult, error) {
+	var failures []string
+	var transportErr *transportError
+	for _, target := range de.balancer.order(agent, targets) {
+		statusCode, body, err := de.pushToTarget(ctx, target, payload, headers)
+		if err == nil {
+			result.StatusCode = statusCode
+			result.ResponseBody = body
+			result.Status = types.StatusDelivered
+			return result, nil
+		}
+
+		failures = append(failures, fmt.Sprintf("%s: %v", target, err))
+		if transportErr == nil {
+			errors.As(err, &transportErr)
+		}
+		if result.StatusCode == 0 {
+			result.StatusCode = statusCode
+			result.ResponseBody = body
+		}
+		if ctx.Err() != nil {
+			break
+		}
+	}
+
+	result.Status = types.StatusFailed
+	result.ErrorCode = pushErrorCode(len(targets), result.StatusCode, transportErr)
+	result.ErrorMessage = fmt.Sprintf("push delivery failed for %d of %d target(s) (strategy %s): %s",
+		len(failures), len(targets), agents.PushStrategyBalanced, strings.Join(failures, "; "))
+	return result, fmt.Errorf("%s", result.ErrorMessage)
+}
+
+// pushErrorCode classifies a failed push delivery. A single target reports
+// why it failed; several targets failing for different reasons do not.
+func pushErrorCode(targets, statusCode int, transportErr *transportError) string {
+	switch {
+	case targets == 1 && transportErr != nil:
+		return transportErr.code
+	case targets == 1 && statusCode == http.StatusTooManyRequests:
+		return "RATE_LIMITED"
+	case targets == 1 && statusCode == 0:
+		return "PUSH_REQUEST_FAILED"
+	default:
+		return "PUSH_DELIVERY_FAILED"
+	}
+}
+
+// localDeliveryPayload is what local agents receive by push or kafka
+func localDeliveryPayload(message *types.Message, recipient string) map[string]interface{} {
+	payload := map[string]interface{}{
 		"message_id":    message.MessageID,
 		"sender":        message.Sender,
 		"recipient":     recipient,
@@ -461,23 +1042,49 @@ func (de *DeliveryEngine) deliverLocalPush(ctx context.Context, message *types.M
 		"in_reply_to":   message.InReplyTo,
 		"response_type": message.ResponseType,
 	}
+	if message.Encrypted {
+		payload["encrypted"] = true
+	}
+	return payload
+}
 
-	// Marshal payload
-	payloadBytes, err := json.Marshal(deliveryPayload)
-	if err != nil {
-		result.Status = types.StatusFailed
-		result.ErrorCode = "PAYLOAD_MARSHAL_FAILED"
-		result.ErrorMessage = fmt.Sprintf("failed to marshal payload: %v", err)
-		return result, fmt.Errorf("failed to marshal payload: %w", err)
+// pushToTarget POSTs a prepared payload to a single push target and returns
+// the response status code and body. A non-2xx response is reported as an
+// error. A target answering 429 is asking the gateway to slow down, so the
+// push is retried under the delivery's retry policy, after the delay from
+// Retry-After when the target sent one. A delay that would outlast the
+// request's deadline is not waited out; the 429 is returned instead.
+func (de *DeliveryEngine) pushToTarget(ctx context.Context, target string, payload []byte, headers map[string]string) (int, string, error) {
+	maxRetries, retryDelay := de.retryPolicy(ctx)
+
+	for attempt := 1; ; attempt++ {
+		statusCode, body, retryAfter, err := de.pushOnce(ctx, target, payload, headers)
+		if statusCode != http.StatusTooManyRequests || attempt >= maxRetries {
+			return statusCode, body, err
+		}
+
+		delay := retryBackoff(retryDelay, attempt)
+		if retryAfter > 0 {
+			delay = min(retryAfter, maxRetryDelay)
+		}
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < delay {
+			return statusCode, body, err
+		}
+		select {
+		case <-ctx.Done():
+			return statusCode, body, err
+		case <-time.After(delay):
+		}
 	}
+}
 
+// pushOnce makes a single push request, also returning the delay a 429
+// response asked for
+func (de *DeliveryEngine) pushOnce(ctx context.Context, target string, payload []byte, headers map[string]string) (int, string, time.Duration, error) {
 	// Create HTTP request to agent's webhook
-	req, err := http.NewRequestWithContext(ctx, "POST", agent.PushTarget, bytes.NewReader(payloadBytes))
+	req, err := http.NewRequestWithContext(ctx, "POST", target, bytes.NewReader(payload))
 	if err != nil {
-		result.Status = types.StatusFailed
-		result.ErrorCode = "REQUEST_CREATION_FAILED"
-		result.ErrorMessage = fmt.Sprintf("failed to create request: %v", err)
-		return result, fmt.Errorf("failed to create request: %w", err)
+		return 0, "", 0, fmt.Errorf("failed to create request: %w", err)
 	}
 
 	// Set headers
@@ -486,47 +1093,32 @@ func (de *DeliveryEngine) deliverLocalPush(ctx context.Context, message *types.M
 	req.Header.Set("X-AMTP-Local-Delivery", "true")
 
 	// Add custom headers from agent configuration
-	for key, value := range agent.Headers {
+	for key, value := range headers {
 		req.Header.Set(key, value)
 	}
 
 	// Perform HTTP request
-	resp, err := de.httpClient.Do(req)
+	resp, err := de.doRequest(de.pushClient, req)
 	if err != nil {
-		result.Status = types.StatusFailed
-		result.ErrorCode = "PUSH_REQUEST_FAILED"
-		result.ErrorMessage = fmt.Sprintf("push request failed: %v", err)
-		return result, fmt.Errorf("push request failed: %w", err)
+		return 0, "", 0, fmt.Errorf("push request failed: %w", err)
 	}
 	defer resp.Body.Close()
 
-	result.StatusCode = resp.StatusCode
+	var retryAfter time.Duration
+	if resp.StatusCode == http.StatusTooManyRequests {
+		retryAfter, _ = parseRetryAfter(resp.Header.Get("Retry-After"), time.Now())
+	}
 
 	// Read response body
-	responseBody, err := io.ReadAll(resp.Body)
-	if err != nil {
-		result.ResponseBody = ""
-	} else {
-		result.ResponseBody = string(responseBody)
+	var body string
+	if responseBody, err := io.ReadAll(resp.Body); err == nil {
+		body = string(responseBody)
 	}
 
-	// Check if delivery was successful
-	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
-		result.Status = types.StatusDelivered
-		result.Attempts = 1
-		result.DeliveryMode = "push"
-		result.LocalDelivery = true
-		return result, nil
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, body, retryAfter, fmt.Errorf("push delivery failed with status %d", resp.StatusCode)
 	}
-
-	// Push delivery failed
-	result.Status = types.StatusFailed
-	result.ErrorCode = "PUSH_DELIVERY_FAILED"
-	result.ErrorMessage = fmt.Sprintf("push delivery failed with status %d", resp.StatusCode)
-	result.Attempts = 1
-	result.DeliveryMode = "push"
-	result.LocalDelivery = true
-	return result, fmt.Errorf("push delivery failed with status %d", resp.StatusCode)
+	return resp.StatusCode, body, 0, nil
 }
 
 // deliverLocalPull marks a message as delivered to local inbox
@@ -537,7 +1129,6 @@ func (de *DeliveryEngine) deliverLocalPull(ctx context.Context, message *types.M
 
 	// Mark as delivered, now available in inbox view
 	result.Status = types.StatusDelivered
-	result.Attempts = 1
 	result.Timestamp = time.Now().UTC()
 	result.DeliveryMode = "pull"
 	result.LocalDelivery = true
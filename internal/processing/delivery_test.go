@@ -18,15 +18,31 @@ package processing
 
 import (
 	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/amtp-protocol/agentry/internal/agents"
 	"github.com/amtp-protocol/agentry/internal/discovery"
+	"github.com/amtp-protocol/agentry/internal/metrics"
+	"github.com/amtp-protocol/agentry/internal/netguard"
 	"github.com/amtp-protocol/agentry/internal/schema"
+	"github.com/amtp-protocol/agentry/internal/signing"
 	"github.com/amtp-protocol/agentry/internal/types"
 )
 
@@ -50,8 +66,15 @@ func NewMockSchemaManager() *MockSchemaManager {
 
 // MockAgentRegistry for testing
 type MockAgentRegistry struct {
-	agents map[string]*agents.LocalAgent
-	inbox  map[string][]*types.Message
+	agentsMu sync.RWMutex
+	agents   map[string]*agents.LocalAgent
+	inbox    map[string][]*types.Message
+
+	// resolveErrs are returned by successive ResolveAgent calls before
+	// lookups succeed, simulating storage failures
+	resolveMu    sync.Mutex
+	resolveErrs  []error
+	resolveCalls int
 }
 
 func NewMockAgentRegistry() *MockAgentRegistry {
@@ -62,6 +85,8 @@ func NewMockAgentRegistry() *MockAgentRegistry {
 }
 
 func (m *MockAgentRegistry) RegisterAgent(ctx context.Context, agent *agents.LocalAgent) error {
+	m.agentsMu.Lock()
+	defer m.agentsMu.Unlock()
 	m.agents[agent.Address] = agent
 	return nil
 }
@@ -72,14 +97,37 @@ func (m *MockAgentRegistry) UnregisterAgent(ctx context.Context, agentNameOrAddr
 }
 
 func (m *MockAgentRegistry) GetAgent(ctx context.Context, agentAddress string) (*agents.LocalAgent, error) {
+	m.agentsMu.RLock()
+	defer m.agentsMu.RUnlock()
 	agent, exists := m.agents[agentAddress]
 	if !exists {
-		return nil, fmt.Errorf("agent not found: %s", agentAddress)
+		return nil, fmt.Errorf("%w: %s", agents.ErrAgentNotFound, agentAddress)
 	}
 	agentCopy := *agent
 	return &agentCopy, nil
 }
 
+func (m *MockAgentRegistry) ResolveAgent(ctx context.Context, address string) (*agents.LocalAgent, error) {
+	m.resolveMu.Lock()
+	m.resolveCalls++
+	if len(m.resolveErrs) > 0 {
+		err := m.resolveErrs[0]
+		m.resolveErrs = m.resolveErrs[1:]
+		m.resolveMu.Unlock()
+		return nil, err
+	}
+	m.resolveMu.Unlock()
+
+	agent, err := m.GetAgent(ctx, address)
+	if err == nil {
+		return agent, nil
+	}
+	if catchAll, catchAllErr := m.GetAgent(ctx, "*@"+discovery.ExtractDomain(address)); catchAllErr == nil {
+		return catchAll, nil
+	}
+	return nil, err
+}
+
 func (m *MockAgentRegistry) GetAllAgents(ctx context.Context) map[string]*agents.LocalAgent {
 	agents := make(map[string]*agents.LocalAgent)
 	for addr, agent := range m.agents {
@@ -114,7 +162,18 @@ func (m *MockAgentRegistry) VerifyAPIKey(ctx context.Context, agentAddress, apiK
 	return exists && agent.APIKey == apiKey
 }
 
+func (m *MockAgentRegistry) ResolveAPIKey(ctx context.Context, apiKey string) (*agents.LocalAgent, error) {
+	for _, agent := range m.agents {
+		if agent.APIKey == apiKey {
+			return agent, nil
+		}
+	}
+	return nil, agents.ErrAgentNotFound
+}
+
 func (m *MockAgentRegistry) UpdateLastAccess(ctx context.Context, agentAddress string) {
+	m.agentsMu.Lock()
+	defer m.agentsMu.Unlock()
 	if agent, exists := m.agents[agentAddress]; exists {
 		agent.LastAccess = time.Now().UTC()
 	}
@@ -195,6 +254,8 @@ func createTestDeliveryConfig() DeliveryConfig {
 		UserAgent:      "AMTP-Gateway-Test/1.0",
 		MaxMessageSize: 10485760,
 		LocalDomain:    "localhost",
+		// Push tests deliver to httptest servers on loopback
+		PushTargetAllowlist: []string{"127.0.0.1"},
 	}
 }
 
@@ -378,6 +439,33 @@ func TestDeliverMessage_InvalidRecipient(t *testing.T) {
 	}
 }
 
+func TestDeliverMessage_URNRecipient(t *testing.T) {
+	registry := NewMockAgentRegistry()
+	registry.RegisterAgent(context.Background(), &agents.LocalAgent{
+		Address:      "urn:agent:1234",
+		DeliveryMode: "pull",
+	})
+	mockDiscovery := NewMockDiscovery()
+
+	// Without the urn scheme the address is not a recipient at all
+	engine := NewDeliveryEngine(mockDiscovery, registry, createTestDeliveryConfig())
+	result, err := engine.DeliverMessage(context.Background(), createTestMessage(), "urn:agent:1234")
+	if err == nil || result.ErrorCode != "INVALID_RECIPIENT" {
+		t.Fatalf("Expected INVALID_RECIPIENT, got %v (%s)", err, result.ErrorCode)
+	}
+
+	config := createTestDeliveryConfig()
+	config.AddressSchemes = types.AddressSchemes{types.EmailScheme, types.URNScheme}
+	engine = NewDeliveryEngine(mockDiscovery, registry, config)
+	result, err = engine.DeliverMessage(context.Background(), createTestMessage(), "urn:agent:1234")
+	if err != nil {
+		t.Fatalf("Expected local delivery, got %v", err)
+	}
+	if !result.LocalDelivery || result.Status != types.StatusDelivered {
+		t.Errorf("Expected a local delivery, got %+v", result)
+	}
+}
+
 func TestDeliverMessage_MessageTooLarge(t *testing.T) {
 	mockDiscovery := NewMockDiscovery()
 	mockDiscovery.SetCapabilities("test.com", &discovery.AMTPCapabilities{
@@ -411,6 +499,56 @@ func TestDeliverMessage_MessageTooLarge(t *testing.T) {
 	}
 }
 
+func TestDeliverMessage_MessageIDStrategy(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	const ulid = "01ARZ3NDEKTSV4RRFFQ69G5FAV"
+	tests := []struct {
+		name      string
+		messageID string
+		inReplyTo string
+		features  []string
+		wantCode  string
+	}{
+		{name: "UUIDv7", messageID: "01890a5d-ac96-774b-bcce-b302099a8057"},
+		{name: "ULID not advertised", messageID: ulid, wantCode: "MESSAGE_ID_UNSUPPORTED"},
+		{name: "ULID advertised", messageID: ulid, features: []string{"message-ids-ulid"}},
+		{name: "prefixed reply", messageID: "01890a5d-ac96-774b-bcce-b302099a8057",
+			inReplyTo: "eu1-01890a5d-ac96-774b-bcce-b302099a8057", features: []string{"message-ids-ulid"}, wantCode: "MESSAGE_ID_UNSUPPORTED"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockDiscovery := NewMockDiscovery()
+			mockDiscovery.SetCapabilities("test.com", &discovery.AMTPCapabilities{
+				Version: "1.0", Gateway: server.URL, MaxSize: 10485760,
+				Features: tt.features, DiscoveredAt: time.Now(), TTL: 5 * time.Minute,
+			})
+			config := createTestDeliveryConfig()
+			config.AllowHTTP = true
+			engine := NewDeliveryEngine(mockDiscovery, NewMockAgentRegistry(), config)
+
+			message := createTestMessage()
+			message.MessageID = tt.messageID
+			message.InReplyTo = tt.inReplyTo
+			result, err := engine.DeliverMessage(context.Background(), message, "recipient@test.com")
+
+			if tt.wantCode != "" {
+				if err == nil || result.Status != types.StatusFailed || result.ErrorCode != tt.wantCode {
+					t.Errorf("Expected a %s failure, got %+v (%v)", tt.wantCode, result, err)
+				}
+				return
+			}
+			if err != nil || result.Status != types.StatusDelivered {
+				t.Errorf("Expected delivery, got %+v (%v)", result, err)
+			}
+		})
+	}
+}
+
 func TestDeliverMessage_InvalidGatewayURL(t *testing.T) {
 	mockDiscovery := NewMockDiscovery()
 	mockDiscovery.SetCapabilities("test.com", &discovery.AMTPCapabilities{
@@ -489,6 +627,7 @@ func TestIsRetryableError(t *testing.T) {
 	mockDiscovery := NewMockDiscovery()
 	config := createTestDeliveryConfig()
 	engine := NewDeliveryEngine(mockDiscovery, NewMockAgentRegistry(), config)
+	blocked := fmt.Errorf("HTTP request failed: %w", &transportError{code: "TARGET_BLOCKED", err: netguard.ErrBlocked})
 
 	tests := []struct {
 		statusCode int
@@ -509,6 +648,7 @@ func TestIsRetryableError(t *testing.T) {
 		{0, fmt.Errorf("no such host"), true},        // DNS error
 		{0, fmt.Errorf("network unreachable"), true}, // Network error
 		{400, fmt.Errorf("some other error"), false}, // Other error with 4xx status
+		{0, blocked, false},                          // Refused by the network guard
 	}
 
 	for _, test := range tests {
@@ -520,16 +660,13 @@ func TestIsRetryableError(t *testing.T) {
 	}
 }
 
-func TestCalculateRetryDelay(t *testing.T) {
-	mockDiscovery := NewMockDiscovery()
-	config := createTestDeliveryConfig()
-	config.RetryDelay = 1 * time.Second
-	engine := NewDeliveryEngine(mockDiscovery, NewMockAgentRegistry(), config)
+func TestRetryBackoff(t *testing.T) {
+	baseDelay := 1 * time.Second
 
 	// Test exponential backoff
-	delay1 := engine.calculateRetryDelay(1)
-	delay2 := engine.calculateRetryDelay(2)
-	delay3 := engine.calculateRetryDelay(3)
+	delay1 := retryBackoff(baseDelay, 1)
+	delay2 := retryBackoff(baseDelay, 2)
+	delay3 := retryBackoff(baseDelay, 3)
 
 	if delay1 >= delay2 {
 		t.Errorf("Expected delay1 (%v) < delay2 (%v)", delay1, delay2)
@@ -540,13 +677,41 @@ func TestCalculateRetryDelay(t *testing.T) {
 	}
 
 	// Test maximum delay cap
-	largeDelay := engine.calculateRetryDelay(20)
+	largeDelay := retryBackoff(baseDelay, 20)
 	maxDelay := 5 * time.Minute
 	if largeDelay > maxDelay*2 { // Allow some jitter
 		t.Errorf("Expected delay to be capped, got %v", largeDelay)
 	}
 }
 
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		value string
+		delay time.Duration
+		ok    bool
+	}{
+		{"", 0, false},
+		{"120", 2 * time.Minute, true},
+		{" 3 ", 3 * time.Second, true},
+		{"0", 0, true},
+		{"-1", 0, false},
+		{"86400", maxRetryDelay, true},
+		{"Sun, 01 Mar 2026 12:00:30 GMT", 30 * time.Second, true},
+		{"Sunday, 01-Mar-26 12:01:00 GMT", time.Minute, true},
+		{"Sun, 01 Mar 2026 11:59:00 GMT", 0, true},
+		{"soon", 0, false},
+	}
+
+	for _, tt := range tests {
+		delay, ok := parseRetryAfter(tt.value, now)
+		if delay != tt.delay || ok != tt.ok {
+			t.Errorf("parseRetryAfter(%q) = %v, %v, expected %v, %v", tt.value, delay, ok, tt.delay, tt.ok)
+		}
+	}
+}
+
 func TestDeliverMessage_ContextCancellation(t *testing.T) {
 	// Create a server that delays response
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -582,13 +747,1207 @@ func TestDeliverMessage_ContextCancellation(t *testing.T) {
 	}
 }
 
-func BenchmarkDeliverMessage(b *testing.B) {
+func TestDeliverLocalPush_FanOutPolicy(t *testing.T) {
+	var okHits, failHits int
+	okServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		okHits++
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer okServer.Close()
+	failServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		failHits++
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer failServer.Close()
+
+	tests := []struct {
+		name           string
+		policy         string
+		expectedStatus types.DeliveryStatus
+		expectError    bool
+	}{
+		{"all policy fails when one target fails", agents.PushPolicyAll, types.StatusFailed, true},
+		{"default policy behaves like all", "", types.StatusFailed, true},
+		{"any policy succeeds when one target succeeds", agents.PushPolicyAny, types.StatusDelivered, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			okHits, failHits = 0, 0
+			registry := NewMockAgentRegistry()
+			registry.RegisterAgent(context.Background(), &agents.LocalAgent{
+				Address:      "fanout@localhost",
+				DeliveryMode: "push",
+				PushTarget:   okServer.URL,
+				PushTargets:  []string{failServer.URL},
+				PushPolicy:   tt.policy,
+			})
+			engine := NewDeliveryEngine(NewMockDiscovery(), registry, createTestDeliveryConfig())
+
+			result, err := engine.DeliverMessage(context.Background(), createTestMessage(), "fanout@localhost")
+			if tt.expectError && err == nil {
+				t.Error("Expected error but got none")
+			}
+			if !tt.expectError && err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			}
+			if result.Status != tt.expectedStatus {
+				t.Errorf("Expected status %s, got %s", tt.expectedStatus, result.Status)
+			}
+			if okHits != 1 || failHits != 1 {
+				t.Errorf("Expected every target to be called once, got ok=%d fail=%d", okHits, failHits)
+			}
+		})
+	}
+}
+
+func TestDeliverLocalPush_Balanced(t *testing.T) {
+	var primaryHits, secondaryHits int32
+	var primaryDown atomic.Bool
+	primary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&primaryHits, 1)
+		if primaryDown.Load() {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer primary.Close()
+	secondary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&secondaryHits, 1)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer secondary.Close()
+
+	registry := NewMockAgentRegistry()
+	registry.RegisterAgent(context.Background(), &agents.LocalAgent{
+		Address:           "balanced@localhost",
+		DeliveryMode:      "push",
+		PushTarget:        primary.URL,
+		PushTargets:       []string{secondary.URL},
+		PushStrategy:      agents.PushStrategyBalanced,
+		PushTargetWeights: map[string]int{primary.URL: 4},
+	})
+	engine := NewDeliveryEngine(NewMockDiscovery(), registry, createTestDeliveryConfig())
+
+	deliver := func(n int) {
+		t.Helper()
+		for i := 0; i < n; i++ {
+			result, err := engine.DeliverMessage(context.Background(), createTestMessage(), "balanced@localhost")
+			if err != nil || result.Status != types.StatusDelivered {
+				t.Fatalf("Expected delivery %d to succeed, got %v", i, err)
+			}
+		}
+	}
+
+	// 80/20 by weight, one target per message
+	deliver(100)
+	if primaryHits != 80 || secondaryHits != 20 {
+		t.Errorf("Expected an 80/20 split, got %d/%d", primaryHits, secondaryHits)
+	}
+
+	// A failing target's turns fail over to the other
+	primaryDown.Store(true)
+	atomic.StoreInt32(&primaryHits, 0)
+	atomic.StoreInt32(&secondaryHits, 0)
+	deliver(10)
+	if primaryHits != 8 || secondaryHits != 10 {
+		t.Errorf("Expected 8 failed turns on the primary and every message on the secondary, got %d/%d", primaryHits, secondaryHits)
+	}
+
+	// Only when every target fails does the delivery fail
+	secondary.Close()
+	result, err := engine.DeliverMessage(context.Background(), createTestMessage(), "balanced@localhost")
+	if err == nil || result.Status != types.StatusFailed {
+		t.Fatalf("Expected failure with every target down, got %v", err)
+	}
+	if result.ErrorCode != "PUSH_DELIVERY_FAILED" || !strings.Contains(result.ErrorMessage, "2 of 2 target(s) (strategy balanced)") {
+		t.Errorf("Unexpected failure: %s %s", result.ErrorCode, result.ErrorMessage)
+	}
+}
+
+func TestDeliverLocalPush_RefreshesLastAccess(t *testing.T) {
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer webhook.Close()
+
+	stale := time.Now().UTC().Add(-48 * time.Hour)
+	registry := NewMockAgentRegistry()
+	registry.RegisterAgent(context.Background(), &agents.LocalAgent{
+		Address:      "hook@localhost",
+		DeliveryMode: "push",
+		PushTarget:   webhook.URL,
+		LastAccess:   stale,
+	})
+	engine := NewDeliveryEngine(NewMockDiscovery(), registry, createTestDeliveryConfig())
+
+	if _, err := engine.DeliverMessage(context.Background(), createTestMessage(), "hook@localhost"); err != nil {
+		t.Fatalf("Expected delivery to succeed, got %v", err)
+	}
+	agent, _ := registry.GetAgent(context.Background(), "hook@localhost")
+	if !agent.LastAccess.After(stale) {
+		t.Errorf("Expected a push delivery to refresh the agent's last access, got %v", agent.LastAccess)
+	}
+}
+
+func TestDeliverLocalPush_MaxConcurrentPerAgent(t *testing.T) {
+	// The slow agent answers only once released
+	release := make(chan struct{})
+	var active, peak int32
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&active, 1)
+		for {
+			p := atomic.LoadInt32(&peak)
+			if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+				break
+			}
+		}
+		<-release
+		atomic.AddInt32(&active, -1)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer slow.Close()
+	defer close(release)
+	fast := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer fast.Close()
+
+	registry := NewMockAgentRegistry()
+	registry.RegisterAgent(context.Background(), &agents.LocalAgent{
+		Address:                 "slow@localhost",
+		DeliveryMode:            "push",
+		PushTarget:              slow.URL,
+		MaxConcurrentDeliveries: 2,
+	})
+	registry.RegisterAgent(context.Background(), &agents.LocalAgent{
+		Address:      "fast@localhost",
+		DeliveryMode: "push",
+		PushTarget:   fast.URL,
+	})
+	config := createTestDeliveryConfig()
+	config.MaxConcurrentPerAgent = 5
+	engine := NewDeliveryEngine(NewMockDiscovery(), registry, config)
+
+	slowAgent, _ := registry.GetAgent(context.Background(), "slow@localhost")
+	fastAgent, _ := registry.GetAgent(context.Background(), "fast@localhost")
+	if engine.AgentDeliveryLimit(slowAgent) != 2 || engine.AgentDeliveryLimit(fastAgent) != 5 {
+		t.Errorf("Expected limits 2 and 5, got %d and %d", engine.AgentDeliveryLimit(slowAgent), engine.AgentDeliveryLimit(fastAgent))
+	}
+
+	var wg sync.WaitGroup
+	var failed int32
+	for i := 0; i < 6; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := engine.DeliverMessage(context.Background(), createTestMessage(), "slow@localhost"); err != nil {
+				atomic.AddInt32(&failed, 1)
+			}
+		}()
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for atomic.LoadInt32(&active) < 2 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if n := engine.AgentDeliveriesInFlight("slow@localhost"); n != 2 {
+		t.Errorf("Expected 2 deliveries in flight to the slow agent, got %d", n)
+	}
+
+	// The slow agent's queued deliveries do not hold up other agents
+	start := time.Now()
+	if _, err := engine.DeliverMessage(context.Background(), createTestMessage(), "fast@localhost"); err != nil {
+		t.Fatalf("Expected delivery to the fast agent to succeed, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Expected the fast agent to be delivered promptly, took %v", elapsed)
+	}
+
+	for i := 0; i < 6; i++ {
+		release <- struct{}{}
+	}
+	wg.Wait()
+
+	if failed != 0 {
+		t.Fatalf("Expected queued deliveries to be delivered, %d failed", failed)
+	}
+	if n := atomic.LoadInt32(&peak); n != 2 {
+		t.Errorf("Expected at most 2 concurrent deliveries to the slow agent, got %d", n)
+	}
+	if n := engine.AgentDeliveriesInFlight("slow@localhost"); n != 0 {
+		t.Errorf("Expected no deliveries in flight once done, got %d", n)
+	}
+}
+
+func TestDeliverLocalPush_Timeouts(t *testing.T) {
+	// Answers only after the response timeout has passed
+	slowHeaders := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer slowHeaders.Close()
+	// Answers at once but takes longer than the response timeout to finish
+	slowBody := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		for i := 0; i < 4; i++ {
+			w.Write([]byte(`{}`))
+			w.(http.Flusher).Flush()
+			time.Sleep(50 * time.Millisecond)
+		}
+	}))
+	defer slowBody.Close()
+	closed := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	closed.Close()
+
+	tests := []struct {
+		name           string
+		target         string
+		expectedStatus types.DeliveryStatus
+		expectedCode   string
+	}{
+		{"slow response headers", slowHeaders.URL, types.StatusFailed, "RESPONSE_TIMEOUT"},
+		{"slow but progressing body", slowBody.URL, types.StatusDelivered, ""},
+		{"connection refused", closed.URL, types.StatusFailed, "CONNECTION_FAILED"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			registry := NewMockAgentRegistry()
+			registry.RegisterAgent(context.Background(), &agents.LocalAgent{
+				Address:      "slow@localhost",
+				DeliveryMode: "push",
+				PushTarget:   tt.target,
+			})
+			config := createTestDeliveryConfig()
+			config.Timeout = 0
+			config.ResponseTimeout = 50 * time.Millisecond
+			engine := NewDeliveryEngine(NewMockDiscovery(), registry, config)
+
+			result, _ := engine.DeliverMessage(context.Background(), createTestMessage(), "slow@localhost")
+			if result.Status != tt.expectedStatus {
+				t.Errorf("Expected status %s, got %s (%s)", tt.expectedStatus, result.Status, result.ErrorMessage)
+			}
+			if result.ErrorCode != tt.expectedCode {
+				t.Errorf("Expected error code %q, got %q", tt.expectedCode, result.ErrorCode)
+			}
+		})
+	}
+}
+
+func TestDeliverLocalPush_TargetBlocked(t *testing.T) {
+	var hits int32
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer webhook.Close()
+	_, port, _ := net.SplitHostPort(webhook.Listener.Addr().String())
+
+	tests := []struct {
+		name         string
+		target       string
+		allowlist    []string
+		expectedCode string
+	}{
+		{"loopback", webhook.URL, nil, "TARGET_BLOCKED"},
+		{"host name resolving to loopback", "http://localhost:" + port, nil, "TARGET_BLOCKED"},
+		{"cloud metadata", "http://169.254.169.254/latest/meta-data/", nil, "TARGET_BLOCKED"},
+		{"private network", "http://10.0.0.1:8080/webhook", nil, "TARGET_BLOCKED"},
+		{"IPv6 loopback", "http://[::1]:" + port, nil, "TARGET_BLOCKED"},
+		{"allowlisted network", webhook.URL, []string{"127.0.0.0/8"}, ""},
+		{"allowlisted host name", "http://localhost:" + port, []string{"localhost"}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			registry := NewMockAgentRegistry()
+			registry.RegisterAgent(context.Background(), &agents.LocalAgent{
+				Address:      "hook@localhost",
+				DeliveryMode: "push",
+				PushTarget:   tt.target,
+			})
+			config := createTestDeliveryConfig()
+			config.PushTargetAllowlist = tt.allowlist
+			config.ConnectTimeout = time.Second
+			engine := NewDeliveryEngine(NewMockDiscovery(), registry, config)
+
+			before := atomic.LoadInt32(&hits)
+			result, _ := engine.DeliverMessage(context.Background(), createTestMessage(), "hook@localhost")
+			if result.ErrorCode != tt.expectedCode {
+				t.Errorf("Expected error code %q, got %q (%s)", tt.expectedCode, result.ErrorCode, result.ErrorMessage)
+			}
+			reached := atomic.LoadInt32(&hits) > before
+			if tt.expectedCode == "" && (!reached || result.Status != types.StatusDelivered) {
+				t.Errorf("Expected an allowlisted target to be delivered to, got %s", result.Status)
+			}
+			if tt.expectedCode != "" && reached {
+				t.Error("Expected a blocked target never to be contacted")
+			}
+		})
+	}
+}
+
+func TestDeliverMessage_TransportErrorCodes(t *testing.T) {
+	var hits int32
+	slowHeaders := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		time.Sleep(200 * time.Millisecond)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer slowHeaders.Close()
+	closed := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	closed.Close()
+
+	mockDiscovery := NewMockDiscovery()
+	mockDiscovery.SetCapabilities("slow.com", &discovery.AMTPCapabilities{Version: "1.0", Gateway: slowHeaders.URL})
+	mockDiscovery.SetCapabilities("down.com", &discovery.AMTPCapabilities{Version: "1.0", Gateway: closed.URL})
+
+	config := createTestDeliveryConfig()
+	config.AllowHTTP = true
+	config.Timeout = 0
+	config.MaxRetries = 2
+	config.RetryDelay = time.Millisecond
+	config.ResponseTimeout = 50 * time.Millisecond
+	engine := NewDeliveryEngine(mockDiscovery, NewMockAgentRegistry(), config)
+
+	result, err := engine.DeliverMessage(context.Background(), createTestMessage(), "agent@slow.com")
+	if err == nil {
+		t.Fatal("Expected error for a response timeout")
+	}
+	if result.ErrorCode != "RESPONSE_TIMEOUT" {
+		t.Errorf("Expected error code RESPONSE_TIMEOUT, got %q", result.ErrorCode)
+	}
+	if result.Attempts != 2 || atomic.LoadInt32(&hits) != 2 {
+		t.Errorf("Expected the response timeout to be retried, got %d attempts and %d requests", result.Attempts, hits)
+	}
+
+	result, err = engine.DeliverMessage(context.Background(), createTestMessage(), "agent@down.com")
+	if err == nil {
+		t.Fatal("Expected error for a refused connection")
+	}
+	if result.ErrorCode != "CONNECTION_FAILED" {
+		t.Errorf("Expected error code CONNECTION_FAILED, got %q", result.ErrorCode)
+	}
+	if result.Attempts != 2 {
+		t.Errorf("Expected the connection failure to be retried, got %d attempts", result.Attempts)
+	}
+}
+
+func TestDeliverMessage_RetryPolicyOverride(t *testing.T) {
+	var hits int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	mockDiscovery := NewMockDiscovery()
+	mockDiscovery.SetCapabilities("test.com", &discovery.AMTPCapabilities{Version: "1.0", Gateway: server.URL})
+
+	config := createTestDeliveryConfig()
+	config.AllowHTTP = true
+	config.MaxRetries = 2
+	config.RetryDelay = time.Minute // Would stall the test if the override were ignored
+	engine := NewDeliveryEngine(mockDiscovery, NewMockAgentRegistry(), config)
+
+	ctx := WithRetryPolicy(context.Background(), RetryPolicy{MaxRetries: 4, RetryDelay: time.Millisecond})
+	result, err := engine.DeliverMessage(ctx, createTestMessage(), "agent@test.com")
+	if err == nil {
+		t.Fatal("Expected error from an unavailable gateway")
+	}
+	if result.Attempts != 4 || atomic.LoadInt32(&hits) != 4 {
+		t.Errorf("Expected 4 attempts from the override, got %d attempts and %d requests", result.Attempts, hits)
+	}
+}
+
+// newRateLimitedServer answers 429 with retryAfter until it has been called
+// limited times, then 200, and records when each request arrived
+func newRateLimitedServer(t *testing.T, limited int, retryAfter string) (*httptest.Server, *[]time.Time) {
+	t.Helper()
+	var mu sync.Mutex
+	var hits []time.Time
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		hits = append(hits, time.Now())
+		n := len(hits)
+		mu.Unlock()
+		if n <= limited {
+			w.Header().Set("Retry-After", retryAfter)
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(server.Close)
+	return server, &hits
+}
+
+func TestDeliverMessage_RetryAfter(t *testing.T) {
+	server, hits := newRateLimitedServer(t, 1, "1")
+
+	mockDiscovery := NewMockDiscovery()
+	mockDiscovery.SetCapabilities("test.com", &discovery.AMTPCapabilities{Version: "1.0", Gateway: server.URL})
+
+	config := createTestDeliveryConfig()
+	config.AllowHTTP = true
+	config.MaxRetries = 2
+	config.RetryDelay = time.Minute // Would stall the test if Retry-After were ignored
+	engine := NewDeliveryEngine(mockDiscovery, NewMockAgentRegistry(), config)
+
+	result, err := engine.DeliverMessage(context.Background(), createTestMessage(), "agent@test.com")
+	if err != nil {
+		t.Fatalf("Expected delivery after the rate limit, got %v", err)
+	}
+	if result.Status != types.StatusDelivered || result.Attempts != 2 || len(*hits) != 2 {
+		t.Fatalf("Expected delivery on the second attempt, got %s after %d attempts", result.Status, result.Attempts)
+	}
+	if wait := (*hits)[1].Sub((*hits)[0]); wait < time.Second {
+		t.Errorf("Expected the retry to wait for Retry-After, waited %v", wait)
+	}
+
+	// A rate limit that outlasts the attempts fails as RATE_LIMITED
+	config.MaxRetries = 1
+	server, _ = newRateLimitedServer(t, 1, "1")
+	mockDiscovery.SetCapabilities("test.com", &discovery.AMTPCapabilities{Version: "1.0", Gateway: server.URL})
+	engine = NewDeliveryEngine(mockDiscovery, NewMockAgentRegistry(), config)
+
+	result, err = engine.DeliverMessage(context.Background(), createTestMessage(), "agent@test.com")
+	if err == nil {
+		t.Fatal("Expected error while rate limited")
+	}
+	if result.ErrorCode != "RATE_LIMITED" || result.RetryAfter != time.Second {
+		t.Errorf("Expected RATE_LIMITED with a 1s Retry-After, got %q and %v", result.ErrorCode, result.RetryAfter)
+	}
+
+	// A Retry-After past the deadline is not waited out
+	config.MaxRetries = 3
+	server, slowHits := newRateLimitedServer(t, 1, "60")
+	mockDiscovery.SetCapabilities("test.com", &discovery.AMTPCapabilities{Version: "1.0", Gateway: server.URL})
+	engine = NewDeliveryEngine(mockDiscovery, NewMockAgentRegistry(), config)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	start := time.Now()
+	result, err = engine.DeliverMessage(ctx, createTestMessage(), "agent@test.com")
+	if err == nil || result.ErrorCode != "RATE_LIMITED" {
+		t.Fatalf("Expected RATE_LIMITED, got %+v (%v)", result, err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second || len(*slowHits) != 1 {
+		t.Errorf("Expected a single attempt without waiting, got %d after %v", len(*slowHits), elapsed)
+	}
+}
+
+func TestDeliverLocalPush_RetryAfter(t *testing.T) {
+	retryAt := time.Now().Add(2 * time.Second).UTC().Format(http.TimeFormat)
+	server, hits := newRateLimitedServer(t, 1, retryAt)
+
+	registry := NewMockAgentRegistry()
+	registry.RegisterAgent(context.Background(), &agents.LocalAgent{
+		Address:      "limited@localhost",
+		DeliveryMode: "push",
+		PushTarget:   server.URL,
+	})
+	config := createTestDeliveryConfig()
+	config.RetryDelay = time.Minute // Would stall the test if Retry-After were ignored
+	engine := NewDeliveryEngine(NewMockDiscovery(), registry, config)
+
+	result, err := engine.DeliverMessage(context.Background(), createTestMessage(), "limited@localhost")
+	if err != nil {
+		t.Fatalf("Expected push delivery after the rate limit, got %v", err)
+	}
+	if result.Status != types.StatusDelivered || len(*hits) != 2 {
+		t.Fatalf("Expected delivery on the second push, got %s after %d requests", result.Status, len(*hits))
+	}
+	// The HTTP date has second precision, so allow for the truncated fraction
+	if wait := (*hits)[1].Sub((*hits)[0]); wait < time.Second {
+		t.Errorf("Expected the push to wait for Retry-After, waited %v", wait)
+	}
+
+	// A Retry-After past the request's deadline is not waited out
+	slow, slowHits := newRateLimitedServer(t, 1, "60")
+	registry.RegisterAgent(context.Background(), &agents.LocalAgent{
+		Address:      "slow@localhost",
+		DeliveryMode: "push",
+		PushTarget:   slow.URL,
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	start := time.Now()
+	result, err = engine.DeliverMessage(ctx, createTestMessage(), "slow@localhost")
+	if err == nil || result.ErrorCode != "RATE_LIMITED" {
+		t.Fatalf("Expected RATE_LIMITED, got %+v (%v)", result, err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second || len(*slowHits) != 1 {
+		t.Errorf("Expected a single push without waiting, got %d after %v", len(*slowHits), elapsed)
+	}
+
+	// Other failures are not retried for push targets
+	var failHits int32
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&failHits, 1)
+		w.Header().Set("Retry-After", "1")
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer failing.Close()
+	registry.RegisterAgent(context.Background(), &agents.LocalAgent{
+		Address:      "down@localhost",
+		DeliveryMode: "push",
+		PushTarget:   failing.URL,
+	})
+	if _, err := engine.DeliverMessage(context.Background(), createTestMessage(), "down@localhost"); err == nil {
+		t.Fatal("Expected error from an unavailable push target")
+	}
+	if hits := atomic.LoadInt32(&failHits); hits != 1 {
+		t.Errorf("Expected a single push to an unavailable target, got %d", hits)
+	}
+}
+
+func TestDeliverLocalPush_TemplatedHeaders(t *testing.T) {
+	var received http.Header
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r.Header.Clone()
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	registry := NewMockAgentRegistry()
+	registry.RegisterAgent(context.Background(), &agents.LocalAgent{
+		Address:      "templated@localhost",
+		DeliveryMode: "push",
+		PushTarget:   server.URL,
+		Headers: map[string]string{
+			"X-Subject":    "{{.Subject}}",
+			"X-Message-ID": "{{.MessageID}}",
+			"X-Static":     "unchanged",
+		},
+	})
+	engine := NewDeliveryEngine(NewMockDiscovery(), registry, createTestDeliveryConfig())
+
+	message := createTestMessage()
+	if _, err := engine.DeliverMessage(context.Background(), message, "templated@localhost"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if got := received.Get("X-Subject"); got != message.Subject {
+		t.Errorf("Expected X-Subject %q, got %q", message.Subject, got)
+	}
+	if got := received.Get("X-Message-ID"); got != message.MessageID {
+		t.Errorf("Expected X-Message-ID %q, got %q", message.MessageID, got)
+	}
+	if got := received.Get("X-Static"); got != "unchanged" {
+		t.Errorf("Expected X-Static to pass through, got %q", got)
+	}
+
+	// A template that cannot be rendered fails the delivery without a request
+	received = nil
+	registry.RegisterAgent(context.Background(), &agents.LocalAgent{
+		Address:      "broken@localhost",
+		DeliveryMode: "push",
+		PushTarget:   server.URL,
+		Headers:      map[string]string{"X-Bad": "{{.Missing}}"},
+	})
+	result, err := engine.DeliverMessage(context.Background(), message, "broken@localhost")
+	if err == nil || result.ErrorCode != "HEADER_TEMPLATE_FAILED" {
+		t.Errorf("Expected HEADER_TEMPLATE_FAILED, got %v (%s)", err, result.ErrorCode)
+	}
+	if received != nil {
+		t.Error("Expected no push request for an unrenderable header")
+	}
+}
+
+// mockKafkaProducer records produced records and fails with err when set
+type mockKafkaProducer struct {
+	records []KafkaRecord
+	err     error
+}
+
+func (m *mockKafkaProducer) Produce(ctx context.Context, record KafkaRecord) error {
+	if m.err != nil {
+		return m.err
+	}
+	m.records = append(m.records, record)
+	return nil
+}
+
+func (m *mockKafkaProducer) Close() error {
+	return nil
+}
+
+func TestDeliverLocalKafka(t *testing.T) {
+	registry := NewMockAgentRegistry()
+	registry.RegisterAgent(context.Background(), &agents.LocalAgent{
+		Address:      "billing@localhost",
+		DeliveryMode: "kafka",
+		PushTarget:   "billing-events",
+		Headers:      map[string]string{"x-subject": "{{.Subject}}"},
+	})
+	producer := &mockKafkaProducer{}
+	config := createTestDeliveryConfig()
+	config.KafkaProducer = producer
+	engine := NewDeliveryEngine(NewMockDiscovery(), registry, config)
+
+	message := createTestMessage()
+	message.Schema = "agntcy:commerce.order.v1"
+	result, err := engine.DeliverMessage(context.Background(), message, "billing@localhost")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if result.Status != types.StatusDelivered || result.DeliveryMode != "kafka" || !result.LocalDelivery {
+		t.Errorf("Expected local kafka delivery, got %+v", result)
+	}
+
+	if len(producer.records) != 1 {
+		t.Fatalf("Expected one record, got %d", len(producer.records))
+	}
+	record := producer.records[0]
+	if record.Topic != "billing-events" || string(record.Key) != message.MessageID {
+		t.Errorf("Unexpected topic %q or key %q", record.Topic, record.Key)
+	}
+	expectedHeaders := map[string]string{
+		KafkaHeaderMessageID: message.MessageID,
+		KafkaHeaderSender:    message.Sender,
+		KafkaHeaderRecipient: "billing@localhost",
+		KafkaHeaderSchema:    message.Schema,
+		"x-subject":          message.Subject,
+	}
+	if !reflect.DeepEqual(record.Headers, expectedHeaders) {
+		t.Errorf("Expected headers %v, got %v", expectedHeaders, record.Headers)
+	}
+	var payload map[string]interface{}
+	if err := json.Unmarshal(record.Value, &payload); err != nil {
+		t.Fatalf("Record value is not JSON: %v", err)
+	}
+	if payload["message_id"] != message.MessageID || payload["recipient"] != "billing@localhost" {
+		t.Errorf("Unexpected record value: %s", record.Value)
+	}
+
+	// A produce failure fails the recipient
+	producer.err = errors.New("leader not available")
+	result, err = engine.DeliverMessage(context.Background(), message, "billing@localhost")
+	if err == nil || result.Status != types.StatusFailed || result.ErrorCode != "KAFKA_PRODUCE_FAILED" {
+		t.Errorf("Expected KAFKA_PRODUCE_FAILED, got %v (%+v)", err, result)
+	}
+
+	// Without a producer kafka agents cannot be delivered to
+	engine = NewDeliveryEngine(NewMockDiscovery(), registry, createTestDeliveryConfig())
+	result, err = engine.DeliverMessage(context.Background(), message, "billing@localhost")
+	if err == nil || result.ErrorCode != "KAFKA_NOT_CONFIGURED" {
+		t.Errorf("Expected KAFKA_NOT_CONFIGURED, got %v (%s)", err, result.ErrorCode)
+	}
+}
+
+func TestDeliverLocal_CatchAll(t *testing.T) {
+	var pushedTo []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload struct {
+			Recipient string `json:"recipient"`
+		}
+		json.NewDecoder(r.Body).Decode(&payload)
+		pushedTo = append(pushedTo, payload.Recipient)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	registry := NewMockAgentRegistry()
+	registry.RegisterAgent(context.Background(), &agents.LocalAgent{
+		Address:      "*@localhost",
+		DeliveryMode: "push",
+		PushTarget:   server.URL,
+	})
+	registry.RegisterAgent(context.Background(), &agents.LocalAgent{
+		Address:      "known@localhost",
+		DeliveryMode: "pull",
+	})
+	engine := NewDeliveryEngine(NewMockDiscovery(), registry, createTestDeliveryConfig())
+
+	// Unregistered local recipients are pushed to the catch-all agent
+	result, err := engine.DeliverMessage(context.Background(), createTestMessage(), "unknown@localhost")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if result.Status != types.StatusDelivered || result.DeliveryMode != "push" {
+		t.Errorf("Expected push delivery, got %s via %s", result.Status, result.DeliveryMode)
+	}
+	if len(pushedTo) != 1 || pushedTo[0] != "unknown@localhost" {
+		t.Errorf("Expected catch-all push for unknown@localhost, got %v", pushedTo)
+	}
+
+	// Registered agents keep their own delivery
+	result, err = engine.DeliverMessage(context.Background(), createTestMessage(), "known@localhost")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if result.DeliveryMode != "pull" || len(pushedTo) != 1 {
+		t.Errorf("Expected pull delivery for the registered agent, got %s", result.DeliveryMode)
+	}
+}
+
+func TestDeliverLocal_UnknownRecipient(t *testing.T) {
+	tests := []struct {
+		mode        UnknownRecipientMode
+		recipient   string
+		status      types.DeliveryStatus
+		errorCode   string
+		expectError bool
+	}{
+		{"", "nobody@localhost", types.StatusDelivered, "", false},
+		{UnknownRecipientInbox, "nobody@localhost", types.StatusDelivered, "", false},
+		{UnknownRecipientReject, "nobody@localhost", types.StatusFailed, ErrorCodeAgentNotFound, true},
+		{UnknownRecipientQueue, "nobody@localhost", types.StatusQueued, ErrorCodeAgentNotRegistered, false},
+		{UnknownRecipientDeadLetter, "nobody@localhost", types.StatusFailed, ErrorCodeAgentNotFound, true},
+		{UnknownRecipientDeadLetter, "Dead-Letter@localhost", types.StatusDelivered, "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.mode)+" "+tt.recipient, func(t *testing.T) {
+			config := createTestDeliveryConfig()
+			config.UnknownRecipient = tt.mode
+			config.DeadLetterAddress = "dead-letter@localhost"
+			engine := NewDeliveryEngine(NewMockDiscovery(), NewMockAgentRegistry(), config)
+
+			result, err := engine.DeliverMessage(context.Background(), createTestMessage(), tt.recipient)
+			if (err != nil) != tt.expectError {
+				t.Fatalf("Expected error %v, got %v", tt.expectError, err)
+			}
+			if result.Status != tt.status || result.ErrorCode != tt.errorCode || !result.LocalDelivery {
+				t.Errorf("Expected local %s with %q, got %s with %q", tt.status, tt.errorCode, result.Status, result.ErrorCode)
+			}
+			if tt.status == types.StatusDelivered && result.DeliveryMode != "pull" {
+				t.Errorf("Expected pull delivery, got %q", result.DeliveryMode)
+			}
+		})
+	}
+}
+
+func TestDeliverLocal_AllowedSenders(t *testing.T) {
+	var pushes int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&pushes, 1)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	registry := NewMockAgentRegistry()
+	registry.RegisterAgent(context.Background(), &agents.LocalAgent{
+		Address:        "hook@localhost",
+		DeliveryMode:   "push",
+		PushTarget:     server.URL,
+		AllowedSenders: []string{"example.com"},
+	})
+	registry.RegisterAgent(context.Background(), &agents.LocalAgent{
+		Address:        "inbox@localhost",
+		DeliveryMode:   "pull",
+		AllowedSenders: []string{"partner@other.com"},
+	})
+	engine := NewDeliveryEngine(NewMockDiscovery(), registry, createTestDeliveryConfig())
+
+	// createTestMessage is sent from test@example.com
+	result, err := engine.DeliverMessage(context.Background(), createTestMessage(), "hook@localhost")
+	if err != nil || result.Status != types.StatusDelivered {
+		t.Fatalf("Expected delivery from an allowed sender, got %v (%v)", result.Status, err)
+	}
+	if atomic.LoadInt32(&pushes) != 1 {
+		t.Errorf("Expected one push, got %d", pushes)
+	}
+
+	result, err = engine.DeliverMessage(context.Background(), createTestMessage(), "inbox@localhost")
+	if err == nil {
+		t.Fatal("Expected pull delivery from a disallowed sender to be refused")
+	}
+	if result.Status != types.StatusFailed || result.ErrorCode != "SENDER_NOT_ALLOWED" {
+		t.Errorf("Expected SENDER_NOT_ALLOWED, got %s with %s", result.Status, result.ErrorCode)
+	}
+
+	message := createTestMessage()
+	message.Sender = "intruder@evil.com"
+	if _, err := engine.DeliverMessage(context.Background(), message, "hook@localhost"); err == nil {
+		t.Error("Expected push delivery from a disallowed sender to be refused")
+	}
+	if atomic.LoadInt32(&pushes) != 1 {
+		t.Errorf("Expected no push for a disallowed sender, got %d pushes", pushes)
+	}
+}
+
+func TestDeliverLocal_TransientLookupFailure(t *testing.T) {
+	registry := NewMockAgentRegistry()
+	registry.RegisterAgent(context.Background(), &agents.LocalAgent{
+		Address:      "known@localhost",
+		DeliveryMode: "pull",
+	})
+	config := createTestDeliveryConfig()
+	config.MaxRetries = 3
+	config.RetryDelay = time.Millisecond
+	engine := NewDeliveryEngine(NewMockDiscovery(), registry, config)
+	storageErr := errors.New("failed to get agent: connection reset")
+
+	t.Run("retried until the lookup succeeds", func(t *testing.T) {
+		registry.resolveErrs = []error{storageErr}
+		registry.resolveCalls = 0
+
+		result, err := engine.DeliverMessage(context.Background(), createTestMessage(), "known@localhost")
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if result.Status != types.StatusDelivered || result.DeliveryMode != "pull" {
+			t.Errorf("Expected pull delivery, got %s via %s", result.Status, result.DeliveryMode)
+		}
+		if result.Attempts != 2 || registry.resolveCalls != 2 {
+			t.Errorf("Expected 2 attempts, got %d attempts and %d lookups", result.Attempts, registry.resolveCalls)
+		}
+	})
+
+	t.Run("fails once retries are exhausted", func(t *testing.T) {
+		registry.resolveErrs = []error{storageErr, storageErr, storageErr}
+		registry.resolveCalls = 0
+
+		result, err := engine.DeliverMessage(context.Background(), createTestMessage(), "known@localhost")
+		if !errors.Is(err, storageErr) {
+			t.Fatalf("Expected the storage error, got %v", err)
+		}
+		if result.Status != types.StatusFailed || result.ErrorCode != "AGENT_LOOKUP_FAILED" {
+			t.Errorf("Expected AGENT_LOOKUP_FAILED, got %s with %s", result.Status, result.ErrorCode)
+		}
+		if result.Attempts != 3 || registry.resolveCalls != 3 {
+			t.Errorf("Expected 3 attempts, got %d attempts and %d lookups", result.Attempts, registry.resolveCalls)
+		}
+	})
+
+	t.Run("looked up once with retries off", func(t *testing.T) {
+		registry.resolveErrs = nil
+		registry.resolveCalls = 0
+		noRetries := createTestDeliveryConfig()
+		noRetries.MaxRetries = 0
+		engine := NewDeliveryEngine(NewMockDiscovery(), registry, noRetries)
+
+		result, err := engine.DeliverMessage(context.Background(), createTestMessage(), "known@localhost")
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if result.Status != types.StatusDelivered || result.Attempts != 1 || registry.resolveCalls != 1 {
+			t.Errorf("Expected delivery after 1 lookup, got %s after %d attempts and %d lookups", result.Status, result.Attempts, registry.resolveCalls)
+		}
+	})
+
+	t.Run("unknown recipients are not retried", func(t *testing.T) {
+		registry.resolveErrs = nil
+		registry.resolveCalls = 0
+
+		result, err := engine.DeliverMessage(context.Background(), createTestMessage(), "unknown@localhost")
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if result.DeliveryMode != "pull" || result.Attempts != 1 || registry.resolveCalls != 1 {
+			t.Errorf("Expected a single lookup and pull delivery, got %d lookups via %s", registry.resolveCalls, result.DeliveryMode)
+		}
+	})
+}
+
+// newConnCountingServer starts a gateway that counts the connections it
+// accepts and the most requests it has had in flight at once
+func newConnCountingServer(tb testing.TB, handler http.HandlerFunc) (*httptest.Server, *int32, *int32) {
+	tb.Helper()
+	var opened, inFlight, peak int32
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&inFlight, 1)
+		defer atomic.AddInt32(&inFlight, -1)
+		for {
+			p := atomic.LoadInt32(&peak)
+			if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+				break
+			}
+		}
+		handler(w, r)
+	}))
+	server.Config.ConnState = func(conn net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			atomic.AddInt32(&opened, 1)
+		}
+	}
+	server.Start()
+	tb.Cleanup(server.Close)
+	return server, &opened, &peak
+}
+
+func TestDeliverMessage_ConnectionReuse(t *testing.T) {
+	server, opened, _ := newConnCountingServer(t, func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
 		w.Write([]byte(`{"status": "accepted"}`))
+	})
+
+	mockDiscovery := NewMockDiscovery()
+	mockDiscovery.SetCapabilities("test.com", &discovery.AMTPCapabilities{Version: "1.0", Gateway: server.URL})
+
+	config := createTestDeliveryConfig()
+	config.AllowHTTP = true
+	engine := NewDeliveryEngine(mockDiscovery, NewMockAgentRegistry(), config)
+
+	for i := 0; i < 20; i++ {
+		if _, err := engine.DeliverMessage(context.Background(), createTestMessage(), "agent@test.com"); err != nil {
+			t.Fatalf("Delivery %d failed: %v", i, err)
+		}
+	}
+	if n := atomic.LoadInt32(opened); n != 1 {
+		t.Errorf("Expected sequential deliveries to share one connection, got %d", n)
+	}
+}
+
+func TestDeliverBatch_MaxConnsPerHost(t *testing.T) {
+	server, opened, peak := newConnCountingServer(t, func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(20 * time.Millisecond)
+		w.WriteHeader(http.StatusOK)
+	})
+
+	mockDiscovery := NewMockDiscovery()
+	recipients := make([]string, 10)
+	for i := range recipients {
+		domain := fmt.Sprintf("test%d.com", i)
+		mockDiscovery.SetCapabilities(domain, &discovery.AMTPCapabilities{Version: "1.0", Gateway: server.URL})
+		recipients[i] = "agent@" + domain
+	}
+
+	config := createTestDeliveryConfig()
+	config.AllowHTTP = true
+	config.MaxConnsPerHost = 2
+	engine := NewDeliveryEngine(mockDiscovery, NewMockAgentRegistry(), config)
+
+	results, err := engine.DeliverBatch(context.Background(), createTestMessage(), recipients)
+	if err != nil {
+		t.Fatalf("DeliverBatch failed: %v", err)
+	}
+	for recipient, result := range results {
+		if result.Status != types.StatusDelivered {
+			t.Errorf("Expected %s to be delivered, got %s: %s", recipient, result.Status, result.ErrorMessage)
+		}
+	}
+	if n := atomic.LoadInt32(opened); n > 2 {
+		t.Errorf("Expected at most 2 connections to the gateway, got %d", n)
+	}
+	if n := atomic.LoadInt32(peak); n > 2 {
+		t.Errorf("Expected at most 2 concurrent requests to the gateway, got %d", n)
+	}
+}
+
+// relayMetrics records the relays in flight reported for each domain
+type relayMetrics struct {
+	metrics.MetricsProvider
+	mu   sync.Mutex
+	peak map[string]int
+	last map[string]int
+}
+
+func (m *relayMetrics) SetRelaysInFlight(domain string, count int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if count > m.peak[domain] {
+		m.peak[domain] = count
+	}
+	m.last[domain] = count
+}
+
+func TestDeliverMessage_MaxConcurrentPerDomain(t *testing.T) {
+	server, _, peak := newConnCountingServer(t, func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(20 * time.Millisecond)
+		w.WriteHeader(http.StatusOK)
+	})
+
+	mockDiscovery := NewMockDiscovery()
+	mockDiscovery.SetCapabilities("test.com", &discovery.AMTPCapabilities{Version: "1.0", Gateway: server.URL})
+
+	recorder := &relayMetrics{MetricsProvider: metrics.NewSimpleMetrics(), peak: map[string]int{}, last: map[string]int{}}
+	config := createTestDeliveryConfig()
+	config.AllowHTTP = true
+	config.MaxConcurrentPerDomain = 3
+	config.Metrics = recorder
+	engine := NewDeliveryEngine(mockDiscovery, NewMockAgentRegistry(), config)
+
+	var wg sync.WaitGroup
+	var failed int32
+	for i := 0; i < 12; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := engine.DeliverMessage(context.Background(), createTestMessage(), "agent@test.com"); err != nil {
+				atomic.AddInt32(&failed, 1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if failed != 0 {
+		t.Fatalf("Expected queued relays to be delivered, %d failed", failed)
+	}
+	if n := atomic.LoadInt32(peak); n > 3 {
+		t.Errorf("Expected at most 3 concurrent relays to the domain, got %d", n)
+	}
+	if recorder.peak["test.com"] != 3 || recorder.last["test.com"] != 0 {
+		t.Errorf("Expected relays in flight to peak at 3 and return to 0, got %d and %d", recorder.peak["test.com"], recorder.last["test.com"])
+	}
+}
+
+func TestDeliverMessage_Signing(t *testing.T) {
+	var received []*types.Message
+	var mu sync.Mutex
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var message types.Message
+		if err := json.NewDecoder(r.Body).Decode(&message); err != nil {
+			t.Errorf("Failed to decode relayed message: %v", err)
+		}
+		mu.Lock()
+		received = append(received, &message)
+		mu.Unlock()
+		w.WriteHeader(http.StatusOK)
 	}))
 	defer server.Close()
 
+	mockDiscovery := NewMockDiscovery()
+	mockDiscovery.SetCapabilities("test.com", &discovery.AMTPCapabilities{Version: "1.0", Gateway: server.URL})
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Failed to generate RSA key: %v", err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate EC key: %v", err)
+	}
+
+	for _, key := range []crypto.Signer{rsaKey, ecKey} {
+		received = nil
+		config := createTestDeliveryConfig()
+		config.AllowHTTP = true
+		config.Signer = key
+		config.SigningKeyID = "k1"
+		engine := NewDeliveryEngine(mockDiscovery, NewMockAgentRegistry(), config)
+
+		local := createTestMessage()
+		local.Sender = "agent@localhost"
+		relayedOn := createTestMessage()
+		preSigned := createTestMessage()
+		preSigned.Sender = "agent@localhost"
+		preSigned.Signature = &types.MessageSignature{Algorithm: signing.AlgorithmES256, KeyID: "agent", Value: "c2lnbmVk"}
+
+		for _, message := range []*types.Message{local, relayedOn, preSigned} {
+			if _, err := engine.DeliverMessage(context.Background(), message, "agent@test.com"); err != nil {
+				t.Fatalf("Delivery failed: %v", err)
+			}
+		}
+		if len(received) != 3 {
+			t.Fatalf("Expected 3 relayed messages, got %d", len(received))
+		}
+
+		// A locally originated message is signed in transit only
+		if local.Signature != nil {
+			t.Error("Expected the caller's message to be left unsigned")
+		}
+		if sig := received[0].Signature; sig == nil || sig.KeyID != "k1" {
+			t.Fatalf("Expected the local message to be signed with k1, got %+v", sig)
+		}
+		if err := signing.VerifyWithKey(received[0], key.Public()); err != nil {
+			t.Errorf("Expected the relayed signature to verify, got %v", err)
+		}
+
+		// Messages from other domains and already signed ones are not re-signed
+		if received[1].Signature != nil {
+			t.Errorf("Expected a message from another domain to stay unsigned, got %+v", received[1].Signature)
+		}
+		if sig := received[2].Signature; sig == nil || *sig != *preSigned.Signature {
+			t.Errorf("Expected the existing signature to be kept, got %+v", sig)
+		}
+	}
+}
+
+// publicKey resolves every key ID of every domain to one key
+type publicKey struct{ key crypto.PublicKey }
+
+func (k publicKey) DiscoverPublicKey(context.Context, string, string) (crypto.PublicKey, error) {
+	return k.key, nil
+}
+
+func TestDeliverMessage_RequestSignature(t *testing.T) {
+	type request struct {
+		header http.Header
+		body   []byte
+	}
+	var requests []request
+	var mu sync.Mutex
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		requests = append(requests, request{r.Header.Clone(), body})
+		n := len(requests)
+		mu.Unlock()
+		if n == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	mockDiscovery := NewMockDiscovery()
+	mockDiscovery.SetCapabilities("test.com", &discovery.AMTPCapabilities{Version: "1.0", Gateway: server.URL})
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate EC key: %v", err)
+	}
+	config := createTestDeliveryConfig()
+	config.AllowHTTP = true
+	config.RetryDelay = time.Millisecond
+	config.Signer = key
+	config.SigningKeyID = "k1"
+	engine := NewDeliveryEngine(mockDiscovery, NewMockAgentRegistry(), config)
+
+	// Messages relayed on from other domains still get a signed request
+	if _, err := engine.DeliverMessage(context.Background(), createTestMessage(), "agent@test.com"); err != nil {
+		t.Fatalf("Delivery failed: %v", err)
+	}
+	if len(requests) != 2 {
+		t.Fatalf("Expected a retry after the first attempt, got %d requests", len(requests))
+	}
+
+	verifier := signing.NewVerifier(publicKey{key.Public()})
+	for i, req := range requests {
+		domain, err := verifier.VerifyRequest(context.Background(), req.header.Get(signing.RequestSignatureHeader), "POST",
+			"/v1/messages", req.header.Get(signing.TimestampHeader), req.header.Get(signing.NonceHeader), req.body)
+		if err != nil || domain != "localhost" {
+			t.Errorf("Expected attempt %d to be signed for localhost, got %q, %v", i+1, domain, err)
+		}
+	}
+	if requests[0].header.Get(signing.NonceHeader) == requests[1].header.Get(signing.NonceHeader) {
+		t.Error("Expected each attempt to carry a fresh nonce")
+	}
+}
+
+func TestRelayLimiter_CanceledWait(t *testing.T) {
+	l := newRelayLimiter(1, nil)
+	if err := l.acquire(context.Background(), "test.com"); err != nil {
+		t.Fatalf("Expected a free slot, got %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := l.acquire(ctx, "test.com"); err == nil {
+		t.Fatal("Expected the wait for a taken slot to end with the context")
+	}
+
+	l.release("test.com")
+	if len(l.domains) != 0 {
+		t.Errorf("Expected idle domains to be dropped, got %d", len(l.domains))
+	}
+	if newRelayLimiter(0, nil) != nil {
+		t.Error("Expected no limiter without a cap")
+	}
+}
+
+func BenchmarkDeliverMessage(b *testing.B) {
+	server, opened, _ := newConnCountingServer(b, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"status": "accepted"}`))
+	})
+
 	mockDiscovery := NewMockDiscovery()
 	mockDiscovery.SetCapabilities("test.com", &discovery.AMTPCapabilities{
 		Version: "1.0", Gateway: server.URL, MaxSize: 10485760,
@@ -610,6 +1969,7 @@ func BenchmarkDeliverMessage(b *testing.B) {
 			b.Fatal(err)
 		}
 	}
+	b.ReportMetric(float64(atomic.LoadInt32(opened))/float64(b.N), "conns/op")
 }
 
 func BenchmarkDeliverBatch(b *testing.B) {
@@ -0,0 +1,105 @@
+/*
+ * Copyright 2026 Cong Wang
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package processing
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/amtp-protocol/agentry/internal/agents"
+	"github.com/amtp-protocol/agentry/internal/types"
+)
+
+// Headers set on every record produced for kafka delivery, next to any
+// headers configured on the agent
+const (
+	KafkaHeaderMessageID = "amtp-message-id"
+	KafkaHeaderSender    = "amtp-sender"
+	KafkaHeaderRecipient = "amtp-recipient"
+	KafkaHeaderSchema    = "amtp-schema"
+)
+
+// KafkaRecord is a single record produced to a Kafka topic
+type KafkaRecord struct {
+	Topic   string
+	Key     []byte
+	Value   []byte
+	Headers map[string]string
+}
+
+// KafkaProducer produces records to Kafka. Produce returns once the brokers
+// acknowledged the record.
+type KafkaProducer interface {
+	Produce(ctx context.Context, record KafkaRecord) error
+	Close() error
+}
+
+// deliverLocalKafka produces a message to the topic the agent configured as
+// its push target. The record value is the push delivery payload and the
+// key is the message ID.
+func (de *DeliveryEngine) deliverLocalKafka(ctx context.Context, message *types.Message, recipient string, agent *agents.LocalAgent, result *DeliveryResult) (*DeliveryResult, error) {
+	result.DeliveryMode = "kafka"
+	result.LocalDelivery = true
+
+	if de.config.KafkaProducer == nil {
+		result.Status = types.StatusFailed
+		result.ErrorCode = "KAFKA_NOT_CONFIGURED"
+		result.ErrorMessage = "kafka delivery is not configured on this gateway"
+		return result, fmt.Errorf("kafka delivery is not configured on this gateway")
+	}
+
+	value, err := json.Marshal(localDeliveryPayload(message, recipient))
+	if err != nil {
+		result.Status = types.StatusFailed
+		result.ErrorCode = "PAYLOAD_MARSHAL_FAILED"
+		result.ErrorMessage = fmt.Sprintf("failed to marshal payload: %v", err)
+		return result, fmt.Errorf("failed to marshal payload: %w", err)
+	}
+
+	headers, err := agents.RenderHeaders(agent.Headers, agents.NewHeaderTemplateData(message, recipient))
+	if err != nil {
+		result.Status = types.StatusFailed
+		result.ErrorCode = "HEADER_TEMPLATE_FAILED"
+		result.ErrorMessage = fmt.Sprintf("failed to render kafka headers: %v", err)
+		return result, fmt.Errorf("failed to render kafka headers: %w", err)
+	}
+	headers[KafkaHeaderMessageID] = message.MessageID
+	headers[KafkaHeaderSender] = message.Sender
+	headers[KafkaHeaderRecipient] = recipient
+	if message.Schema != "" {
+		headers[KafkaHeaderSchema] = message.Schema
+	}
+
+	record := KafkaRecord{
+		Topic:   agent.PushTarget,
+		Key:     []byte(message.MessageID),
+		Value:   value,
+		Headers: headers,
+	}
+	if err := de.config.KafkaProducer.Produce(ctx, record); err != nil {
+		result.Status = types.StatusFailed
+		result.ErrorCode = "KAFKA_PRODUCE_FAILED"
+		result.ErrorMessage = fmt.Sprintf("failed to produce to topic %s: %v", record.Topic, err)
+		return result, fmt.Errorf("failed to produce to topic %s: %w", record.Topic, err)
+	}
+
+	result.Status = types.StatusDelivered
+	result.Timestamp = time.Now().UTC()
+	return result, nil
+}
@@ -18,15 +18,25 @@ package processing
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strings"
 	"sync"
 	"time"
 
+	"github.com/amtp-protocol/agentry/internal/events"
+	"github.com/amtp-protocol/agentry/internal/features"
 	"github.com/amtp-protocol/agentry/internal/storage"
 	"github.com/amtp-protocol/agentry/internal/types"
 	"github.com/amtp-protocol/agentry/internal/workflow"
+	"github.com/amtp-protocol/agentry/pkg/uuid"
 )
 
+// ErrMessageNotStored is returned by ProcessMessage when storage refused the
+// message itself. Nothing was delivered, so the message can be processed
+// again later.
+var ErrMessageNotStored = errors.New("failed to store message")
+
 // MessageProcessor handles message processing and routing
 type MessageProcessor struct {
 	discovery      DiscoveryService
@@ -35,6 +45,32 @@ type MessageProcessor struct {
 	workflow       workflow.Manager
 	idempotencyMap map[string]*ProcessingResult
 	idempotencyMux sync.RWMutex
+	background     sync.WaitGroup
+	bounceSender   string // sends non-delivery reports when set
+	messageIDs     uuid.Generator
+
+	// Dead letters are sent from deadLetterSender to deadLetterAddress when set
+	deadLetterSender  string
+	deadLetterAddress string
+
+	// archiveAddress silently receives a copy of every message when set
+	archiveAddress string
+
+	coordinationLimits CoordinationLimits
+
+	// held maps messages with recipients waiting for an agent to register to
+	// when they were first seen held
+	held   map[string]time.Time
+	heldMu sync.Mutex
+
+	// When claimWorker is set, held recipients are found by leasing their
+	// messages from storage instead of through held, so gateways sharing
+	// storage each retry different messages
+	claimWorker string
+	claimLease  time.Duration
+
+	// events receives what happens to messages for admins watching live
+	events *events.Hub
 }
 
 // ProcessingResult represents the result of message processing
@@ -48,11 +84,24 @@ type ProcessingResult struct {
 	ErrorMessage string
 }
 
+// clone returns a copy of r that shares no recipient statuses with it
+func (r *ProcessingResult) clone() *ProcessingResult {
+	c := *r
+	c.Recipients = append([]types.RecipientStatus(nil), r.Recipients...)
+	return &c
+}
+
 // ProcessingOptions defines options for message processing
 type ProcessingOptions struct {
 	ImmediatePath bool
 	Timeout       time.Duration
-	MaxRetries    int
+	// MaxRetries and RetryDelay override the delivery engine's retry policy
+	// for this message when set
+	MaxRetries int
+	RetryDelay time.Duration
+	// Async accepts the message as queued once it is persisted and runs
+	// delivery in the background instead of waiting for it
+	Async bool
 }
 
 // NewMessageProcessor creates a new message processor
@@ -62,19 +111,32 @@ func NewMessageProcessor(discovery DiscoveryService, deliveryEngine DeliveryServ
 		deliveryEngine: deliveryEngine,
 		storage:        storage,
 		idempotencyMap: make(map[string]*ProcessingResult),
+		held:           make(map[string]time.Time),
+		messageIDs:     uuid.V7,
 	}
 }
 
 // ProcessMessage processes an incoming message
 func (mp *MessageProcessor) ProcessMessage(ctx context.Context, message *types.Message, options ProcessingOptions) (*ProcessingResult, error) {
+	// Don't start work the caller has already given up on
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("message processing aborted: %w", err)
+	}
+
 	// Check idempotency
 	if result := mp.checkIdempotency(message.IdempotencyKey); result != nil {
 		return result, nil
 	}
 
+	if !options.ImmediatePath && message.Coordination != nil {
+		if err := mp.checkCoordinationLimits(ctx, message); err != nil {
+			return nil, err
+		}
+	}
+
 	// Store message
 	if err := mp.storage.StoreMessage(ctx, message); err != nil {
-		return nil, fmt.Errorf("failed to store message: %w", err)
+		return nil, fmt.Errorf("%w: %w", ErrMessageNotStored, err)
 	}
 
 	// Initialize processing result
@@ -112,15 +174,64 @@ func (mp *MessageProcessor) ProcessMessage(ctx context.Context, message *types.M
 	// Store idempotency result
 	mp.storeIdempotencyResult(message.IdempotencyKey, result)
 
-	// Process based on coordination type or immediate path
-	if options.ImmediatePath || message.Coordination == nil {
-		return mp.processImmediatePath(ctx, message, result, options)
+	mp.events.Publish(events.Event{
+		Type:       events.MessageAccepted,
+		MessageID:  message.MessageID,
+		Sender:     message.Sender,
+		Recipients: message.Recipients,
+		Status:     string(types.StatusQueued),
+	})
+
+	if options.Async {
+		// Hand back a snapshot so the caller never races with the background
+		// update of result
+		accepted := result.clone()
+
+		// The request context ends as soon as the caller responds, so detach
+		// from its cancellation. Failures are recorded in the stored status.
+		bgCtx := context.WithoutCancel(ctx)
+		mp.archive(bgCtx, message)
+		mp.background.Add(1)
+		go func() {
+			defer mp.background.Done()
+			_, _ = mp.dispatch(bgCtx, message, result, options)
+			mp.storeIdempotencyResult(message.IdempotencyKey, result)
+		}()
+		return accepted, nil
+	}
+
+	mp.archive(ctx, message)
+	dispatched, err := mp.dispatch(ctx, message, result, options)
+	mp.storeIdempotencyResult(message.IdempotencyKey, result)
+	return dispatched, err
+}
+
+// dispatch routes a stored message to the immediate or coordination path
+func (mp *MessageProcessor) dispatch(ctx context.Context, message *types.Message, result *ProcessingResult, options ProcessingOptions) (*ProcessingResult, error) {
+	// Process based on coordination type or immediate path. Without a
+	// workflow engine the coordination_fallback flag delivers coordinated
+	// messages as if they were not.
+	fallback := mp.workflow == nil && features.Enabled(features.CoordinationFallback)
+	if options.ImmediatePath || message.Coordination == nil || fallback {
+		result, err := mp.processImmediatePath(ctx, message, result, options)
+		if result != nil {
+			mp.trackHeld(message.MessageID, result.Recipients)
+			mp.bounce(ctx, message, result)
+			mp.deadLetter(ctx, message, result.Recipients)
+		}
+		return result, err
 	}
 
 	// Handle coordination-based processing
 	return mp.processWithCoordination(ctx, message, result, options)
 }
 
+// Wait blocks until every message accepted with ProcessingOptions.Async has
+// finished processing
+func (mp *MessageProcessor) Wait() {
+	mp.background.Wait()
+}
+
 // processImmediatePath handles immediate path message processing
 func (mp *MessageProcessor) processImmediatePath(ctx context.Context, message *types.Message, result *ProcessingResult, options ProcessingOptions) (*ProcessingResult, error) {
 	// Set timeout context
@@ -129,90 +240,36 @@ func (mp *MessageProcessor) processImmediatePath(ctx context.Context, message *t
 		ctx, cancel = context.WithTimeout(ctx, options.Timeout)
 		defer cancel()
 	}
+	if options.MaxRetries > 0 || options.RetryDelay > 0 {
+		ctx = WithRetryPolicy(ctx, RetryPolicy{MaxRetries: options.MaxRetries, RetryDelay: options.RetryDelay})
+	}
 
-	// Process recipients in parallel for immediate path
+	// Process recipients in parallel for immediate path, keeping their
+	// statuses in the order of the message's recipients
 	var wg sync.WaitGroup
-	resultChan := make(chan types.RecipientStatus, len(message.Recipients))
+	recipientResults := make([]types.RecipientStatus, len(message.Recipients))
 
 	for i, recipient := range message.Recipients {
 		wg.Add(1)
 		go func(index int, addr string) {
 			defer wg.Done()
-
-			// Update status to delivering
-			recipientStatus := types.RecipientStatus{
-				Address:   addr,
-				Status:    types.StatusDelivering,
-				Timestamp: time.Now().UTC(),
-				Attempts:  1,
-			}
-
-			// Attempt delivery
-			deliveryResult, err := mp.deliveryEngine.DeliverMessage(ctx, message, addr)
-			if err != nil {
-				recipientStatus.Status = types.StatusFailed
-				recipientStatus.ErrorCode = "DELIVERY_FAILED"
-				recipientStatus.ErrorMessage = err.Error()
-			} else {
-				recipientStatus.Status = deliveryResult.Status
-				recipientStatus.DeliveryMode = deliveryResult.DeliveryMode
-				recipientStatus.LocalDelivery = deliveryResult.LocalDelivery
-
-				// For pull mode local delivery, mark as inbox delivered
-				if deliveryResult.LocalDelivery && deliveryResult.DeliveryMode == "pull" && deliveryResult.Status == types.StatusDelivered {
-					recipientStatus.InboxDelivered = true
-				}
-
-				if deliveryResult.ErrorCode != "" {
-					recipientStatus.ErrorCode = deliveryResult.ErrorCode
-					recipientStatus.ErrorMessage = deliveryResult.ErrorMessage
-				}
-			}
-
-			recipientStatus.Timestamp = time.Now().UTC()
-			resultChan <- recipientStatus
+			recipientResults[index] = mp.deliver(ctx, message, addr, 1)
 		}(i, recipient)
 	}
 
 	// Wait for all deliveries to complete
-	go func() {
-		wg.Wait()
-		close(resultChan)
-	}()
-
-	// Collect results
-	recipientResults := make([]types.RecipientStatus, 0, len(message.Recipients))
-	for recipientStatus := range resultChan {
-		recipientResults = append(recipientResults, recipientStatus)
-	}
+	wg.Wait()
 
 	// Update result with recipient statuses
 	result.Recipients = recipientResults
 
 	// Determine overall status
-	allDelivered := true
-	anyFailed := false
-	for _, rs := range recipientResults {
-		if rs.Status != types.StatusDelivered {
-			allDelivered = false
-		}
-		if rs.Status == types.StatusFailed {
-			anyFailed = true
-		}
-	}
-
-	if allDelivered {
-		result.Status = types.StatusDelivered
-	} else if anyFailed {
-		result.Status = types.StatusFailed
-	} else {
-		result.Status = types.StatusDelivering
-	}
+	result.Status = types.AggregateStatus(recipientResults)
 
 	// Update stored status
 	err := mp.storage.UpdateStatus(ctx, message.MessageID, func(status *types.MessageStatus) error {
 		status.Status = result.Status
-		status.Recipients = result.Recipients
+		status.Recipients = mp.keepArchived(status.Recipients, result.Recipients)
 		status.UpdatedAt = time.Now().UTC()
 		if result.Status == types.StatusDelivered {
 			now := time.Now().UTC()
@@ -220,6 +277,11 @@ func (mp *MessageProcessor) processImmediatePath(ctx context.Context, message *t
 		}
 		return nil
 	})
+	// Deliveries cut short by the deadline report as failed recipients; surface
+	// the deadline itself so the caller can tell the two apart
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return nil, fmt.Errorf("message delivery aborted: %w", ctxErr)
+	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to update status: %w", err)
 	}
@@ -227,6 +289,71 @@ func (mp *MessageProcessor) processImmediatePath(ctx context.Context, message *t
 	return result, nil
 }
 
+// deliver attempts delivery of message to addr, counted as its attempts-th
+// attempt, and publishes the attempt and its outcome
+func (mp *MessageProcessor) deliver(ctx context.Context, message *types.Message, addr string, attempts int) types.RecipientStatus {
+	mp.events.Publish(events.Event{
+		Type:      events.DeliveryAttempt,
+		MessageID: message.MessageID,
+		Sender:    message.Sender,
+		Recipient: addr,
+		Attempts:  attempts,
+	})
+
+	deliveryResult, err := mp.deliveryEngine.DeliverMessage(ctx, message, addr)
+	recipientStatus := recipientStatusFor(addr, deliveryResult, err)
+	recipientStatus.Attempts = attempts
+
+	outcome := events.Event{
+		MessageID: message.MessageID,
+		Sender:    message.Sender,
+		Recipient: addr,
+		Status:    string(recipientStatus.Status),
+		Attempts:  attempts,
+		ErrorCode: recipientStatus.ErrorCode,
+		Error:     recipientStatus.ErrorMessage,
+	}
+	switch recipientStatus.Status {
+	case types.StatusDelivered:
+		outcome.Type = events.DeliveryDelivered
+		mp.events.Publish(outcome)
+	case types.StatusFailed:
+		outcome.Type = events.DeliveryFailed
+		mp.events.Publish(outcome)
+	}
+	return recipientStatus
+}
+
+// recipientStatusFor records the outcome of a delivery attempt to addr
+func recipientStatusFor(addr string, deliveryResult *DeliveryResult, err error) types.RecipientStatus {
+	recipientStatus := types.RecipientStatus{Address: addr}
+	if err != nil {
+		recipientStatus.Status = types.StatusFailed
+		recipientStatus.ErrorCode = "DELIVERY_FAILED"
+		recipientStatus.ErrorMessage = err.Error()
+		if deliveryResult != nil && deliveryResult.ErrorCode != "" {
+			recipientStatus.ErrorCode = deliveryResult.ErrorCode
+		}
+	} else {
+		recipientStatus.Status = deliveryResult.Status
+		recipientStatus.DeliveryMode = deliveryResult.DeliveryMode
+		recipientStatus.LocalDelivery = deliveryResult.LocalDelivery
+
+		// For pull mode local delivery, mark as inbox delivered
+		if deliveryResult.LocalDelivery && deliveryResult.DeliveryMode == "pull" && deliveryResult.Status == types.StatusDelivered {
+			recipientStatus.InboxDelivered = true
+		}
+
+		if deliveryResult.ErrorCode != "" {
+			recipientStatus.ErrorCode = deliveryResult.ErrorCode
+			recipientStatus.ErrorMessage = deliveryResult.ErrorMessage
+		}
+	}
+
+	recipientStatus.Timestamp = time.Now().UTC()
+	return recipientStatus
+}
+
 // checkIdempotency checks if a message has already been processed
 func (mp *MessageProcessor) checkIdempotency(idempotencyKey string) *ProcessingResult {
 	mp.idempotencyMux.RLock()
@@ -248,15 +375,17 @@ func (mp *MessageProcessor) checkIdempotency(idempotencyKey string) *ProcessingR
 		return nil
 	}
 
-	return result
+	return result.clone()
 }
 
-// storeIdempotencyResult stores the processing result for idempotency checking
+// storeIdempotencyResult stores a copy of the processing result for
+// idempotency checking. The caller keeps result and may go on updating it;
+// storing it again publishes the update.
 func (mp *MessageProcessor) storeIdempotencyResult(idempotencyKey string, result *ProcessingResult) {
 	mp.idempotencyMux.Lock()
 	defer mp.idempotencyMux.Unlock()
 
-	mp.idempotencyMap[idempotencyKey] = result
+	mp.idempotencyMap[idempotencyKey] = result.clone()
 }
 
 // CleanupExpiredEntries removes expired idempotency entries
@@ -272,6 +401,93 @@ func (mp *MessageProcessor) CleanupExpiredEntries() {
 	}
 }
 
+// SetBounceSender makes the processor report recipients that permanently
+// failed back to the message's sender, from the given address
+func (mp *MessageProcessor) SetBounceSender(sender string) {
+	mp.bounceSender = sender
+}
+
+// SetArchiveAddress makes the processor deliver a copy of every message to
+// address, in addition to its recipients. The copy is left out of the
+// processing result and the aggregate status, so senders do not see it.
+func (mp *MessageProcessor) SetArchiveAddress(address string) {
+	mp.archiveAddress = address
+}
+
+// archive delivers message's archive copy in the background, once per
+// message rather than once per coordination step. The delivery is detached
+// from ctx's cancellation and never holds up the send, even a synchronous
+// one; Wait covers it. The archive recipient is stored with the status, so
+// its inbox holds the message, but does not count towards the status.
+func (mp *MessageProcessor) archive(ctx context.Context, message *types.Message) {
+	if !mp.archives(message) {
+		return
+	}
+
+	ctx = context.WithoutCancel(ctx)
+	mp.background.Add(1)
+	go func() {
+		defer mp.background.Done()
+		archived := mp.deliver(ctx, message, mp.archiveAddress, 1)
+		// #nosec G104 - a lost archive status does not affect the recipients
+		mp.storage.UpdateStatus(ctx, message.MessageID, func(status *types.MessageStatus) error {
+			recipients := make([]types.RecipientStatus, 0, len(status.Recipients)+1)
+			for _, rs := range status.Recipients {
+				if !strings.EqualFold(rs.Address, mp.archiveAddress) {
+					recipients = append(recipients, rs)
+				}
+			}
+			status.Recipients = append(recipients, archived)
+			return nil
+		})
+	}()
+}
+
+// keepArchived returns recipients, which are to replace the stored ones,
+// followed by the archive copy's status when stored has one
+func (mp *MessageProcessor) keepArchived(stored, recipients []types.RecipientStatus) []types.RecipientStatus {
+	if mp.archiveAddress == "" {
+		return recipients
+	}
+	for _, rs := range recipients {
+		if strings.EqualFold(rs.Address, mp.archiveAddress) {
+			return recipients
+		}
+	}
+	for _, rs := range stored {
+		if strings.EqualFold(rs.Address, mp.archiveAddress) {
+			return append(append([]types.RecipientStatus(nil), recipients...), rs)
+		}
+	}
+	return recipients
+}
+
+// archives reports whether message gets an archive copy. A message already
+// addressed to the archive reaches it as a regular recipient.
+func (mp *MessageProcessor) archives(message *types.Message) bool {
+	if mp.archiveAddress == "" {
+		return false
+	}
+	for _, recipient := range message.Recipients {
+		if strings.EqualFold(recipient, mp.archiveAddress) {
+			return false
+		}
+	}
+	return true
+}
+
+// SetMessageIDGenerator sets how the processor creates the message IDs of
+// the reports and dead letters it sends
+func (mp *MessageProcessor) SetMessageIDGenerator(ids uuid.Generator) {
+	mp.messageIDs = ids
+}
+
+// SetEventHub makes the processor publish message acceptance and delivery
+// attempts to hub
+func (mp *MessageProcessor) SetEventHub(hub *events.Hub) {
+	mp.events = hub
+}
+
 // SetWorkflowManager injects the workflow manager
 func (mp *MessageProcessor) SetWorkflowManager(wm workflow.Manager) {
 	mp.workflow = wm
@@ -2,12 +2,91 @@ package processing
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 
 	"github.com/amtp-protocol/agentry/internal/types"
 )
 
+// Errors returned by ProcessMessage for a coordinated message beyond the
+// gateway's coordination limits. The message is neither stored nor delivered.
+var (
+	ErrTooManyCoordinatedRecipients = errors.New("coordinated message has too many recipients")
+	ErrCoordinationTimeoutTooLong   = errors.New("coordination timeout exceeds the gateway limit")
+	ErrTooManyCoordinations         = errors.New("too many coordinated messages in progress")
+)
+
+// CoordinationLimits caps what coordinated messages may ask of the gateway.
+// A zero field is unlimited.
+type CoordinationLimits struct {
+	MaxRecipients int           // distinct recipients and participants of one message
+	MaxConcurrent int           // workflows pending or in progress at once
+	MaxTimeout    time.Duration // longest coordination timeout
+}
+
+// SetCoordinationLimits sets the limits checked before a coordinated
+// message is accepted
+func (mp *MessageProcessor) SetCoordinationLimits(limits CoordinationLimits) {
+	mp.coordinationLimits = limits
+}
+
+// checkCoordinationLimits refuses a coordinated message before it is stored
+// when it would fan out too widely, wait too long or start a workflow the
+// gateway has no room for. The concurrency check reads the stored workflows,
+// so simultaneous sends can overshoot it by the number in flight.
+func (mp *MessageProcessor) checkCoordinationLimits(ctx context.Context, message *types.Message) error {
+	limits := mp.coordinationLimits
+	coord := message.Coordination
+
+	if limits.MaxRecipients > 0 {
+		if n := len(coordinationParticipants(message)); n > limits.MaxRecipients {
+			return fmt.Errorf("%w: %d recipients, the limit is %d", ErrTooManyCoordinatedRecipients, n, limits.MaxRecipients)
+		}
+	}
+
+	if limits.MaxTimeout > 0 {
+		if timeout := time.Duration(coord.Timeout) * time.Second; timeout > limits.MaxTimeout {
+			return fmt.Errorf("%w: %v, the limit is %v", ErrCoordinationTimeoutTooLong, timeout, limits.MaxTimeout)
+		}
+	}
+
+	// Without a workflow engine nothing is tracked to count
+	if limits.MaxConcurrent > 0 && mp.workflow != nil {
+		active, err := mp.storage.CountActiveWorkflows(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to count coordinated messages in progress: %w", err)
+		}
+		if active >= int64(limits.MaxConcurrent) {
+			return fmt.Errorf("%w: the limit is %d", ErrTooManyCoordinations, limits.MaxConcurrent)
+		}
+	}
+
+	return nil
+}
+
+// coordinationParticipants returns every distinct address a coordinated
+// message may be delivered to or wait on
+func coordinationParticipants(message *types.Message) []string {
+	coord := message.Coordination
+	groups := [][]string{message.Recipients, coord.RequiredResponses, coord.OptionalResponses, coord.Sequence}
+	for _, rule := range coord.Conditions {
+		groups = append(groups, rule.Then, rule.Else)
+	}
+
+	seen := make(map[string]bool)
+	var participants []string
+	for _, group := range groups {
+		for _, address := range group {
+			if !seen[address] {
+				seen[address] = true
+				participants = append(participants, address)
+			}
+		}
+	}
+	return participants
+}
+
 // Dispatch implements the workflow.Dispatcher interface
 func (mp *MessageProcessor) Dispatch(ctx context.Context, msg *types.Message) error {
 	recipients := make([]types.RecipientStatus, len(msg.Recipients))
@@ -50,7 +129,7 @@ func (mp *MessageProcessor) processWithCoordination(
 		// #nosec G104 - ignore err
 		mp.storage.UpdateStatus(ctx, message.MessageID, func(status *types.MessageStatus) error {
 			status.Status = result.Status
-			status.Recipients = result.Recipients
+			status.Recipients = mp.keepArchived(status.Recipients, result.Recipients)
 			status.UpdatedAt = time.Now().UTC()
 			return nil
 		})
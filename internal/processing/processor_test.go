@@ -18,11 +18,21 @@ package processing
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/amtp-protocol/agentry/internal/agents"
+	"github.com/amtp-protocol/agentry/internal/events"
+	"github.com/amtp-protocol/agentry/internal/features"
 	"github.com/amtp-protocol/agentry/internal/types"
+	"github.com/amtp-protocol/agentry/pkg/uuid"
 )
 
 func TestNewMessageProcessor(t *testing.T) {
@@ -98,6 +108,651 @@ func TestProcessMessage_ImmediatePath(t *testing.T) {
 	}
 }
 
+// blockingDeliveryEngine holds every delivery until release is closed
+type blockingDeliveryEngine struct {
+	release chan struct{}
+}
+
+func (b *blockingDeliveryEngine) DeliverMessage(ctx context.Context, message *types.Message, recipient string) (*DeliveryResult, error) {
+	<-b.release
+	return &DeliveryResult{Status: types.StatusDelivered, Timestamp: time.Now().UTC(), Attempts: 1}, nil
+}
+
+func TestProcessMessage_PublishesEvents(t *testing.T) {
+	deliveryEngine := NewMockDeliveryEngine()
+	deliveryEngine.SetDeliveryResult("down@test.com", &DeliveryResult{
+		Status:       types.StatusFailed,
+		ErrorCode:    "DELIVERY_FAILED",
+		ErrorMessage: "gateway returned 500",
+	})
+	processor := NewMessageProcessor(NewMockDiscovery(), deliveryEngine, NewMockStorage())
+	hub := events.NewHub(1, 16)
+	processor.SetEventHub(hub)
+	sub, err := hub.Subscribe(events.Filter{})
+	if err != nil {
+		t.Fatalf("Subscribe failed: %v", err)
+	}
+	defer sub.Close()
+
+	message := createTestMessage()
+	message.Recipients = []string{"down@test.com"}
+	if _, err := processor.ProcessMessage(context.Background(), message, ProcessingOptions{ImmediatePath: true}); err != nil {
+		t.Fatalf("ProcessMessage failed: %v", err)
+	}
+
+	expected := []string{events.MessageAccepted, events.DeliveryAttempt, events.DeliveryFailed}
+	for _, eventType := range expected {
+		select {
+		case event := <-sub.Events:
+			if event.Type != eventType || event.MessageID != message.MessageID {
+				t.Fatalf("Expected a %s event for %s, got %+v", eventType, message.MessageID, event)
+			}
+			if eventType == events.DeliveryFailed && (event.Recipient != "down@test.com" || event.ErrorCode != "DELIVERY_FAILED" || event.Attempts != 1) {
+				t.Errorf("Unexpected failure event: %+v", event)
+			}
+		default:
+			t.Fatalf("Expected a %s event", eventType)
+		}
+	}
+	if len(sub.Events) != 0 {
+		t.Errorf("Expected no further events, got %d", len(sub.Events))
+	}
+}
+
+func TestProcessMessage_Async(t *testing.T) {
+	deliveryEngine := &blockingDeliveryEngine{release: make(chan struct{})}
+	storage := NewMockStorage()
+	processor := NewMessageProcessor(NewMockDiscovery(), deliveryEngine, storage)
+	processor.SetWorkflowManager(&MockWorkflowManager{})
+
+	message := createTestMessage()
+	options := ProcessingOptions{
+		ImmediatePath: true,
+		Timeout:       30 * time.Second,
+		Async:         true,
+	}
+
+	// Cancel the caller's context right after acceptance, as a finished HTTP
+	// request would; background delivery must not be affected
+	ctx, cancel := context.WithCancel(context.Background())
+	result, err := processor.ProcessMessage(ctx, message, options)
+	cancel()
+	if err != nil {
+		t.Fatalf("ProcessMessage failed: %v", err)
+	}
+
+	if result.Status != types.StatusQueued {
+		t.Errorf("Expected status %s while delivery is pending, got %s", types.StatusQueued, result.Status)
+	}
+	stored, err := storage.GetStatus(context.Background(), message.MessageID)
+	if err != nil {
+		t.Fatalf("GetStatus failed: %v", err)
+	}
+	if stored.Status != types.StatusQueued {
+		t.Errorf("Expected stored status %s, got %s", types.StatusQueued, stored.Status)
+	}
+
+	// A duplicate sent while delivery runs gets its own copy of the result,
+	// which the background delivery must not write to
+	duplicate, err := processor.ProcessMessage(context.Background(), message, options)
+	if err != nil {
+		t.Fatalf("ProcessMessage of duplicate failed: %v", err)
+	}
+	close(deliveryEngine.release)
+	if duplicate.Status != types.StatusQueued || duplicate.Recipients[0].Status != types.StatusQueued {
+		t.Errorf("Expected the duplicate to see the queued result, got %s", duplicate.Status)
+	}
+	processor.Wait()
+
+	duplicate, err = processor.ProcessMessage(context.Background(), message, options)
+	if err != nil {
+		t.Fatalf("ProcessMessage of duplicate failed: %v", err)
+	}
+	if duplicate.Status != types.StatusDelivered {
+		t.Errorf("Expected a duplicate after delivery to see status %s, got %s", types.StatusDelivered, duplicate.Status)
+	}
+
+	stored, err = storage.GetStatus(context.Background(), message.MessageID)
+	if err != nil {
+		t.Fatalf("GetStatus failed: %v", err)
+	}
+	if stored.Status != types.StatusDelivered {
+		t.Errorf("Expected stored status %s after background delivery, got %s", types.StatusDelivered, stored.Status)
+	}
+	if result.Status != types.StatusQueued {
+		t.Errorf("Returned result should not change after acceptance, got %s", result.Status)
+	}
+}
+
+// recordingDeliveryEngine fails deliveries to the addresses in failing and
+// records every message it is asked to deliver
+type recordingDeliveryEngine struct {
+	mu        sync.Mutex
+	failing   map[string]bool
+	delivered []*types.Message
+}
+
+func (r *recordingDeliveryEngine) DeliverMessage(ctx context.Context, message *types.Message, recipient string) (*DeliveryResult, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.delivered = append(r.delivered, message)
+	if r.failing[recipient] {
+		return &DeliveryResult{Status: types.StatusFailed, ErrorCode: "CLIENT_ERROR", ErrorMessage: "unknown agent", Attempts: 1}, nil
+	}
+	return &DeliveryResult{Status: types.StatusDelivered, Timestamp: time.Now().UTC(), Attempts: 1}, nil
+}
+
+func TestProcessMessage_BounceReports(t *testing.T) {
+	failing := map[string]bool{"gone@test.com": true, "test@example.com": true}
+
+	tests := []struct {
+		name         string
+		bounceSender string
+		recipients   []string
+		responseType string
+		expectReport bool
+	}{
+		{"failed recipient is reported", "postmaster@localhost", []string{"recipient@test.com", "gone@test.com"}, "", true},
+		{"bounces disabled", "", []string{"gone@test.com"}, "", false},
+		{"all delivered", "postmaster@localhost", []string{"recipient@test.com"}, "", false},
+		{"reports are never bounced", "postmaster@localhost", []string{"gone@test.com"}, types.ResponseTypeNonDelivery, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine := &recordingDeliveryEngine{failing: failing}
+			storage := NewMockStorage()
+			processor := NewMessageProcessor(NewMockDiscovery(), engine, storage)
+			processor.SetBounceSender(tt.bounceSender)
+
+			message := createTestMessage()
+			message.Recipients = tt.recipients
+			message.ResponseType = tt.responseType
+			if _, err := processor.ProcessMessage(context.Background(), message, ProcessingOptions{ImmediatePath: true}); err != nil {
+				t.Fatalf("ProcessMessage failed: %v", err)
+			}
+			processor.Wait()
+
+			var reports []*types.Message
+			for _, m := range engine.delivered {
+				if m.ResponseType == types.ResponseTypeNonDelivery && m.MessageID != message.MessageID {
+					reports = append(reports, m)
+				}
+			}
+			if !tt.expectReport {
+				if len(reports) != 0 {
+					t.Errorf("Expected no report, got %d", len(reports))
+				}
+				return
+			}
+
+			// The report to test@example.com fails too, but is not reported again
+			if len(reports) != 1 {
+				t.Fatalf("Expected one report, got %d", len(reports))
+			}
+			report := reports[0]
+			if report.Sender != "postmaster@localhost" || len(report.Recipients) != 1 || report.Recipients[0] != message.Sender {
+				t.Errorf("Expected a report from postmaster to the sender, got %s to %v", report.Sender, report.Recipients)
+			}
+			if report.InReplyTo != message.MessageID || report.Subject != "Undeliverable: Test Message" {
+				t.Errorf("Unexpected report headers: in_reply_to %q, subject %q", report.InReplyTo, report.Subject)
+			}
+
+			var payload types.NonDeliveryReport
+			if err := json.Unmarshal(report.Payload, &payload); err != nil {
+				t.Fatalf("Failed to unmarshal report payload: %v", err)
+			}
+			if payload.OriginalMessageID != message.MessageID || len(payload.FailedRecipients) != 1 {
+				t.Fatalf("Unexpected report payload: %+v", payload)
+			}
+			failed := payload.FailedRecipients[0]
+			if failed.Address != "gone@test.com" || failed.ErrorCode != "CLIENT_ERROR" || failed.ErrorMessage != "unknown agent" {
+				t.Errorf("Unexpected failed recipient: %+v", failed)
+			}
+			if _, err := storage.GetStatus(context.Background(), report.MessageID); err != nil {
+				t.Errorf("Expected the report to be tracked like any message: %v", err)
+			}
+		})
+	}
+}
+
+// storedMessages returns the stored messages with the given response type
+func storedMessages(storage *MockStorage, responseType string) []*types.Message {
+	storage.mutex.RLock()
+	defer storage.mutex.RUnlock()
+	var messages []*types.Message
+	for _, m := range storage.messages {
+		if m.ResponseType == responseType {
+			messages = append(messages, m)
+		}
+	}
+	return messages
+}
+
+func TestProcessMessage_DeadLetter(t *testing.T) {
+	registry := NewMockAgentRegistry()
+	registry.RegisterAgent(context.Background(), &agents.LocalAgent{Address: "known@localhost", DeliveryMode: "pull"})
+	config := createTestDeliveryConfig()
+	config.UnknownRecipient = UnknownRecipientDeadLetter
+	config.DeadLetterAddress = "dead-letter@localhost"
+	storage := NewMockStorage()
+	processor := NewMessageProcessor(NewMockDiscovery(), NewDeliveryEngine(NewMockDiscovery(), registry, config), storage)
+	processor.SetDeadLetter("postmaster@localhost", "dead-letter@localhost")
+
+	message := createTestMessage()
+	message.Recipients = []string{"known@localhost", "nobody@localhost"}
+	result, err := processor.ProcessMessage(context.Background(), message, ProcessingOptions{ImmediatePath: true})
+	if err != nil {
+		t.Fatalf("ProcessMessage failed: %v", err)
+	}
+	processor.Wait()
+
+	if result.Status != types.StatusFailed {
+		t.Errorf("Expected the message to fail for the unknown recipient, got %s", result.Status)
+	}
+
+	letters := storedMessages(storage, types.ResponseTypeDeadLetter)
+	if len(letters) != 1 {
+		t.Fatalf("Expected one dead letter, got %d", len(letters))
+	}
+	letter := letters[0]
+	if letter.Sender != "postmaster@localhost" || len(letter.Recipients) != 1 || letter.Recipients[0] != "dead-letter@localhost" {
+		t.Errorf("Expected a dead letter from postmaster to dead-letter@localhost, got %s to %v", letter.Sender, letter.Recipients)
+	}
+	if letter.InReplyTo != message.MessageID || letter.Subject != "Dead letter: Test Message" {
+		t.Errorf("Unexpected dead letter headers: in_reply_to %q, subject %q", letter.InReplyTo, letter.Subject)
+	}
+
+	var payload types.DeadLetter
+	if err := json.Unmarshal(letter.Payload, &payload); err != nil {
+		t.Fatalf("Failed to unmarshal dead letter payload: %v", err)
+	}
+	if len(payload.Recipients) != 1 || payload.Recipients[0].Address != "nobody@localhost" || payload.Recipients[0].ErrorCode != ErrorCodeAgentNotFound {
+		t.Errorf("Unexpected dead letter recipients: %+v", payload.Recipients)
+	}
+	if payload.Message == nil || payload.Message.MessageID != message.MessageID {
+		t.Errorf("Expected the original message in the dead letter, got %+v", payload.Message)
+	}
+
+	// The dead-letter address receives into its inbox without an agent
+	status, err := storage.GetStatus(context.Background(), letter.MessageID)
+	if err != nil {
+		t.Fatalf("Expected the dead letter to be tracked: %v", err)
+	}
+	if status.Status != types.StatusDelivered || !status.Recipients[0].InboxDelivered {
+		t.Errorf("Expected the dead letter in the inbox, got %s", status.Status)
+	}
+}
+
+func TestProcessMessage_ArchiveAddress(t *testing.T) {
+	registry := NewMockAgentRegistry()
+	registry.RegisterAgent(context.Background(), &agents.LocalAgent{Address: "known@localhost", DeliveryMode: "pull"})
+	registry.RegisterAgent(context.Background(), &agents.LocalAgent{Address: "archive@localhost", DeliveryMode: "pull"})
+	config := createTestDeliveryConfig()
+	config.UnknownRecipient = UnknownRecipientReject
+	storage := NewMockStorage()
+	processor := NewMessageProcessor(NewMockDiscovery(), NewDeliveryEngine(NewMockDiscovery(), registry, config), storage)
+	processor.SetArchiveAddress("archive@localhost")
+
+	message := createTestMessage()
+	message.Recipients = []string{"known@localhost"}
+	result, err := processor.ProcessMessage(context.Background(), message, ProcessingOptions{ImmediatePath: true})
+	if err != nil {
+		t.Fatalf("ProcessMessage failed: %v", err)
+	}
+	processor.Wait()
+
+	if result.Status != types.StatusDelivered || len(result.Recipients) != 1 || result.Recipients[0].Address != "known@localhost" {
+		t.Errorf("Expected only the primary recipient in the result, got %s %+v", result.Status, result.Recipients)
+	}
+	for _, address := range []string{"known@localhost", "archive@localhost"} {
+		inbox, _ := storage.GetInboxMessages(context.Background(), address)
+		if len(inbox) != 1 || inbox[0].MessageID != message.MessageID {
+			t.Errorf("Expected the message in the inbox of %s, got %d messages", address, len(inbox))
+		}
+	}
+	if len(message.Recipients) != 1 || message.IdempotencyKey != createTestMessage().IdempotencyKey {
+		t.Errorf("Expected the message itself to be left unchanged, got %v %s", message.Recipients, message.IdempotencyKey)
+	}
+
+	// A failed archive copy does not fail the message
+	registry.UnregisterAgent(context.Background(), "archive@localhost")
+	message = createTestMessage()
+	message.MessageID = "01234567-89ab-7def-8123-456789abcdee"
+	message.IdempotencyKey = "01234567-89ab-4def-8123-456789abcdee"
+	message.Recipients = []string{"known@localhost"}
+	result, err = processor.ProcessMessage(context.Background(), message, ProcessingOptions{ImmediatePath: true})
+	if err != nil {
+		t.Fatalf("ProcessMessage failed: %v", err)
+	}
+	if result.Status != types.StatusDelivered || len(result.Recipients) != 1 {
+		t.Errorf("Expected the message to be delivered without the archive, got %s %+v", result.Status, result.Recipients)
+	}
+	processor.Wait()
+	status, _ := storage.GetStatus(context.Background(), message.MessageID)
+	if status.Status != types.StatusDelivered || len(status.Recipients) != 2 || status.Recipients[1].Status != types.StatusFailed {
+		t.Errorf("Expected the failed archive copy to be recorded apart, got %s %+v", status.Status, status.Recipients)
+	}
+}
+
+func TestProcessMessage_ArchiveOncePerMessage(t *testing.T) {
+	var archived int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&archived, 1)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	registry := NewMockAgentRegistry()
+	registry.RegisterAgent(context.Background(), &agents.LocalAgent{Address: "step@localhost", DeliveryMode: "pull"})
+	registry.RegisterAgent(context.Background(), &agents.LocalAgent{Address: "archive@localhost", DeliveryMode: "push", PushTarget: server.URL})
+	storage := NewMockStorage()
+	processor := NewMessageProcessor(NewMockDiscovery(), NewDeliveryEngine(NewMockDiscovery(), registry, createTestDeliveryConfig()), storage)
+	processor.SetArchiveAddress("archive@localhost")
+
+	message := createTestMessage()
+	message.Recipients = []string{"step@localhost"}
+	if _, err := processor.ProcessMessage(context.Background(), message, ProcessingOptions{ImmediatePath: true}); err != nil {
+		t.Fatalf("ProcessMessage failed: %v", err)
+	}
+
+	// Later coordination steps are dispatched under the same message
+	for i := 0; i < 2; i++ {
+		if err := processor.Dispatch(context.Background(), message.Clone()); err != nil {
+			t.Fatalf("Dispatch failed: %v", err)
+		}
+	}
+
+	processor.Wait()
+	if n := atomic.LoadInt32(&archived); n != 1 {
+		t.Errorf("Expected one archive copy, got %d", n)
+	}
+	status, _ := storage.GetStatus(context.Background(), message.MessageID)
+	if len(status.Recipients) != 2 || status.Recipients[1].Address != "archive@localhost" {
+		t.Errorf("Expected the archive copy to stay recorded after each step, got %+v", status.Recipients)
+	}
+}
+
+func TestProcessMessage_ArchiveDetached(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	registry := NewMockAgentRegistry()
+	registry.RegisterAgent(context.Background(), &agents.LocalAgent{Address: "known@localhost", DeliveryMode: "pull"})
+	registry.RegisterAgent(context.Background(), &agents.LocalAgent{Address: "archive@localhost", DeliveryMode: "push", PushTarget: server.URL})
+	storage := NewMockStorage()
+	processor := NewMessageProcessor(NewMockDiscovery(), NewDeliveryEngine(NewMockDiscovery(), registry, createTestDeliveryConfig()), storage)
+	processor.SetArchiveAddress("archive@localhost")
+
+	// A synchronous send returns while the archive copy is still in flight,
+	// and its cancelled request context does not cut the copy short
+	ctx, cancel := context.WithCancel(context.Background())
+	message := createTestMessage()
+	message.Recipients = []string{"known@localhost"}
+	result, err := processor.ProcessMessage(ctx, message, ProcessingOptions{ImmediatePath: true})
+	cancel()
+	if err != nil {
+		t.Fatalf("ProcessMessage failed: %v", err)
+	}
+	if result.Status != types.StatusDelivered {
+		t.Errorf("Expected the message delivered before the archive copy, got %s", result.Status)
+	}
+
+	close(release)
+	processor.Wait()
+	status, _ := storage.GetStatus(context.Background(), message.MessageID)
+	if len(status.Recipients) != 2 || status.Recipients[1].Status != types.StatusDelivered {
+		t.Errorf("Expected the archive copy delivered in the background, got %+v", status.Recipients)
+	}
+}
+
+func TestRetryHeldRecipients(t *testing.T) {
+	registry := NewMockAgentRegistry()
+	registry.RegisterAgent(context.Background(), &agents.LocalAgent{Address: "sender@localhost", DeliveryMode: "pull"})
+	config := createTestDeliveryConfig()
+	config.UnknownRecipient = UnknownRecipientQueue
+	storage := NewMockStorage()
+	processor := NewMessageProcessor(NewMockDiscovery(), NewDeliveryEngine(NewMockDiscovery(), registry, config), storage)
+	processor.SetBounceSender("postmaster@localhost")
+	ctx := context.Background()
+
+	message := createTestMessage()
+	message.Sender = "sender@localhost"
+	message.Recipients = []string{"late@localhost", "never@localhost"}
+	result, err := processor.ProcessMessage(ctx, message, ProcessingOptions{ImmediatePath: true})
+	if err != nil {
+		t.Fatalf("ProcessMessage failed: %v", err)
+	}
+	if result.Status != types.StatusDelivering {
+		t.Errorf("Expected held recipients to keep the message delivering, got %s", result.Status)
+	}
+	for _, rs := range result.Recipients {
+		if rs.Status != types.StatusQueued || rs.ErrorCode != ErrorCodeAgentNotRegistered {
+			t.Errorf("Expected %s to be held, got %s with %q", rs.Address, rs.Status, rs.ErrorCode)
+		}
+	}
+
+	recipient := func(address string) types.RecipientStatus {
+		t.Helper()
+		status, err := storage.GetStatus(ctx, message.MessageID)
+		if err != nil {
+			t.Fatalf("Failed to get status: %v", err)
+		}
+		for _, rs := range status.Recipients {
+			if rs.Address == address {
+				return rs
+			}
+		}
+		t.Fatalf("No status for %s", address)
+		return types.RecipientStatus{}
+	}
+
+	// A registration within the hold gets the message delivered
+	registry.RegisterAgent(ctx, &agents.LocalAgent{Address: "late@localhost", DeliveryMode: "pull"})
+	if err := processor.RetryHeldRecipients(ctx, time.Hour); err != nil {
+		t.Fatalf("RetryHeldRecipients failed: %v", err)
+	}
+	if rs := recipient("late@localhost"); rs.Status != types.StatusDelivered || !rs.InboxDelivered || rs.Attempts != 2 {
+		t.Errorf("Expected late@localhost delivered to its inbox on the second attempt, got %+v", rs)
+	}
+	if rs := recipient("never@localhost"); !isHeld(rs) {
+		t.Errorf("Expected never@localhost still held, got %+v", rs)
+	}
+	if len(processor.held) != 1 {
+		t.Errorf("Expected the message to stay held, got %d held", len(processor.held))
+	}
+
+	// Once the hold runs out the recipient fails and is reported
+	if err := processor.RetryHeldRecipients(ctx, 0); err != nil {
+		t.Fatalf("RetryHeldRecipients failed: %v", err)
+	}
+	processor.Wait()
+	if rs := recipient("never@localhost"); rs.Status != types.StatusFailed || rs.ErrorCode != ErrorCodeAgentNotFound {
+		t.Errorf("Expected never@localhost to fail with %s, got %+v", ErrorCodeAgentNotFound, rs)
+	}
+	if status, _ := storage.GetStatus(ctx, message.MessageID); status.Status != types.StatusFailed {
+		t.Errorf("Expected the message to fail, got %s", status.Status)
+	}
+	if len(processor.held) != 0 {
+		t.Errorf("Expected nothing held, got %d", len(processor.held))
+	}
+	reports := storedMessages(storage, types.ResponseTypeNonDelivery)
+	if len(reports) != 1 || reports[0].Recipients[0] != "sender@localhost" {
+		t.Fatalf("Expected one report to the sender, got %d", len(reports))
+	}
+
+	// Held recipients are found again after a restart
+	held := createTestMessage()
+	held.MessageID = "01234567-89ab-7def-8123-456789abcde0"
+	held.IdempotencyKey = "01234567-89ab-4def-8123-456789abcde0"
+	held.Recipients = []string{"pending@localhost"}
+	if _, err := processor.ProcessMessage(ctx, held, ProcessingOptions{ImmediatePath: true}); err != nil {
+		t.Fatalf("ProcessMessage failed: %v", err)
+	}
+	restarted := NewMessageProcessor(NewMockDiscovery(), NewDeliveryEngine(NewMockDiscovery(), registry, config), storage)
+	found, err := restarted.RecoverHeldRecipients(ctx)
+	if err != nil {
+		t.Fatalf("RecoverHeldRecipients failed: %v", err)
+	}
+	if _, ok := restarted.held[held.MessageID]; found != 1 || !ok {
+		t.Errorf("Expected the held message to be recovered, found %d", found)
+	}
+}
+
+func TestRetryHeldRecipients_Claims(t *testing.T) {
+	registry := NewMockAgentRegistry()
+	config := createTestDeliveryConfig()
+	config.UnknownRecipient = UnknownRecipientQueue
+	storage := NewMockStorage()
+	gateway := func(workerID string, lease time.Duration) *MessageProcessor {
+		processor := NewMessageProcessor(NewMockDiscovery(), NewDeliveryEngine(NewMockDiscovery(), registry, config), storage)
+		processor.SetDeliveryClaims(workerID, lease)
+		return processor
+	}
+	gatewayA := gateway("gateway-a", time.Hour)
+	gatewayB := gateway("gateway-b", time.Hour)
+	ctx := context.Background()
+
+	message := createTestMessage()
+	message.Recipients = []string{"late@localhost"}
+	if _, err := gatewayA.ProcessMessage(ctx, message, ProcessingOptions{ImmediatePath: true}); err != nil {
+		t.Fatalf("ProcessMessage failed: %v", err)
+	}
+	if len(gatewayA.held) != 0 {
+		t.Errorf("Expected held messages to be left to storage, got %d tracked", len(gatewayA.held))
+	}
+	if found, err := gatewayB.RecoverHeldRecipients(ctx); err != nil || found != 0 {
+		t.Errorf("Expected no recovery scan with claims, got %d, %v", found, err)
+	}
+
+	// Gateway A leases the message while the recipient is still held
+	if err := gatewayA.RetryHeldRecipients(ctx, time.Hour); err != nil {
+		t.Fatalf("RetryHeldRecipients failed: %v", err)
+	}
+	registry.RegisterAgent(ctx, &agents.LocalAgent{Address: "late@localhost", DeliveryMode: "pull"})
+
+	recipient := func() types.RecipientStatus {
+		t.Helper()
+		status, err := storage.GetStatus(ctx, message.MessageID)
+		if err != nil {
+			t.Fatalf("Failed to get status: %v", err)
+		}
+		return status.Recipients[0]
+	}
+
+	// Gateway B leaves it alone, gateway A delivers it
+	if err := gatewayB.RetryHeldRecipients(ctx, time.Hour); err != nil {
+		t.Fatalf("RetryHeldRecipients failed: %v", err)
+	}
+	if rs := recipient(); !isHeld(rs) || rs.Attempts != 1 {
+		t.Errorf("Expected gateway B to skip a message leased to gateway A, got %+v", rs)
+	}
+	if err := gatewayA.RetryHeldRecipients(ctx, time.Hour); err != nil {
+		t.Fatalf("RetryHeldRecipients failed: %v", err)
+	}
+	if rs := recipient(); rs.Status != types.StatusDelivered || rs.Attempts != 2 {
+		t.Errorf("Expected gateway A to deliver on the second attempt, got %+v", rs)
+	}
+
+	// The messages of a gateway that stopped are taken over once its lease expires
+	held := createTestMessage()
+	held.MessageID = "01234567-89ab-7def-8123-456789abcde1"
+	held.IdempotencyKey = "01234567-89ab-4def-8123-456789abcde1"
+	held.Recipients = []string{"later@localhost"}
+	stopped := gateway("gateway-c", time.Nanosecond)
+	if _, err := stopped.ProcessMessage(ctx, held, ProcessingOptions{ImmediatePath: true}); err != nil {
+		t.Fatalf("ProcessMessage failed: %v", err)
+	}
+	if err := stopped.RetryHeldRecipients(ctx, time.Hour); err != nil {
+		t.Fatalf("RetryHeldRecipients failed: %v", err)
+	}
+	registry.RegisterAgent(ctx, &agents.LocalAgent{Address: "later@localhost", DeliveryMode: "pull"})
+	time.Sleep(time.Millisecond)
+	if err := gatewayB.RetryHeldRecipients(ctx, time.Hour); err != nil {
+		t.Fatalf("RetryHeldRecipients failed: %v", err)
+	}
+	if status, _ := storage.GetStatus(ctx, held.MessageID); status.Recipients[0].Status != types.StatusDelivered {
+		t.Errorf("Expected gateway B to take over the expired lease, got %+v", status.Recipients[0])
+	}
+}
+
+func TestNewReadReceipt(t *testing.T) {
+	message := createTestMessage()
+	acknowledgedAt := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	receipt, err := NewReadReceipt(uuid.V7, message, "reader@localhost", acknowledgedAt)
+	if err != nil {
+		t.Fatalf("NewReadReceipt failed: %v", err)
+	}
+	if err := receipt.Validate(); err != nil {
+		t.Errorf("Expected a valid message, got %v", err)
+	}
+	if receipt.Sender != "reader@localhost" || len(receipt.Recipients) != 1 || receipt.Recipients[0] != message.Sender {
+		t.Errorf("Expected a receipt from the reader to the sender, got %s to %v", receipt.Sender, receipt.Recipients)
+	}
+	if receipt.InReplyTo != message.MessageID || receipt.ResponseType != types.ResponseTypeReadReceipt || receipt.Subject != "Read: Test Message" {
+		t.Errorf("Unexpected receipt headers: in_reply_to %q, response_type %q, subject %q", receipt.InReplyTo, receipt.ResponseType, receipt.Subject)
+	}
+	if receipt.RequestReceipt {
+		t.Error("Expected a receipt not to request a receipt")
+	}
+
+	var payload types.ReadReceipt
+	if err := json.Unmarshal(receipt.Payload, &payload); err != nil {
+		t.Fatalf("Failed to unmarshal receipt payload: %v", err)
+	}
+	if payload.OriginalMessageID != message.MessageID || payload.Recipient != "reader@localhost" || !payload.AcknowledgedAt.Equal(acknowledgedAt) {
+		t.Errorf("Unexpected receipt payload: %+v", payload)
+	}
+}
+
+// contextDeliveryEngine holds every delivery until its context ends
+type contextDeliveryEngine struct{}
+
+func (contextDeliveryEngine) DeliverMessage(ctx context.Context, message *types.Message, recipient string) (*DeliveryResult, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func TestProcessMessage_ExpiredContext(t *testing.T) {
+	storage := NewMockStorage()
+	processor := NewMessageProcessor(NewMockDiscovery(), NewMockDeliveryEngine(), storage)
+
+	message := createTestMessage()
+	ctx, cancel := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancel()
+
+	_, err := processor.ProcessMessage(ctx, message, ProcessingOptions{ImmediatePath: true})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Expected deadline error, got %v", err)
+	}
+	if _, err := storage.GetMessage(context.Background(), message.MessageID); err == nil {
+		t.Error("Expected no message to be stored after the deadline passed")
+	}
+}
+
+func TestProcessMessage_DeadlineDuringDelivery(t *testing.T) {
+	storage := NewMockStorage()
+	processor := NewMessageProcessor(NewMockDiscovery(), contextDeliveryEngine{}, storage)
+
+	message := createTestMessage()
+	start := time.Now()
+	_, err := processor.ProcessMessage(context.Background(), message, ProcessingOptions{
+		ImmediatePath: true,
+		Timeout:       50 * time.Millisecond,
+	})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Expected deadline error, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Expected processing to stop at the deadline, took %v", elapsed)
+	}
+}
+
 func TestProcessMessage_ParallelCoordination(t *testing.T) {
 	discovery := NewMockDiscovery()
 	deliveryEngine := NewMockDeliveryEngine()
@@ -130,6 +785,127 @@ func TestProcessMessage_ParallelCoordination(t *testing.T) {
 	}
 }
 
+func TestProcessMessage_CoordinationLimits(t *testing.T) {
+	tests := []struct {
+		name       string
+		recipients []string
+		coord      types.CoordinationConfig
+		active     int64
+		wantErr    error
+	}{
+		{
+			name:       "within limits",
+			recipients: []string{"a@test.com", "b@test.com"},
+			coord:      types.CoordinationConfig{Type: "parallel", Timeout: 60, RequiredResponses: []string{"a@test.com"}},
+			active:     1,
+		},
+		{
+			name:       "too many recipients",
+			recipients: []string{"a@test.com", "b@test.com"},
+			coord:      types.CoordinationConfig{Type: "parallel", Timeout: 60, OptionalResponses: []string{"c@test.com", "d@test.com"}},
+			wantErr:    ErrTooManyCoordinatedRecipients,
+		},
+		{
+			name:       "conditional branches count",
+			recipients: []string{"a@test.com"},
+			coord: types.CoordinationConfig{Type: "conditional", Timeout: 60, Conditions: []types.ConditionalRule{
+				{If: "approved == true", Then: []string{"b@test.com", "c@test.com"}, Else: []string{"d@test.com"}},
+			}},
+			wantErr: ErrTooManyCoordinatedRecipients,
+		},
+		{
+			name:       "timeout over the ceiling",
+			recipients: []string{"a@test.com"},
+			coord:      types.CoordinationConfig{Type: "parallel", Timeout: 3601},
+			wantErr:    ErrCoordinationTimeoutTooLong,
+		},
+		{
+			name:       "too many coordinations in progress",
+			recipients: []string{"a@test.com"},
+			coord:      types.CoordinationConfig{Type: "parallel", Timeout: 60},
+			active:     2,
+			wantErr:    ErrTooManyCoordinations,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			storage := NewMockStorage()
+			storage.activeWorkflows = tt.active
+			processor := NewMessageProcessor(NewMockDiscovery(), NewMockDeliveryEngine(), storage)
+			processor.SetCoordinationLimits(CoordinationLimits{MaxRecipients: 3, MaxConcurrent: 2, MaxTimeout: time.Hour})
+			initialized := false
+			processor.SetWorkflowManager(&MockWorkflowManager{
+				InitializeFunc: func(ctx context.Context, msg *types.Message) (*types.Workflow, error) {
+					initialized = true
+					return &types.Workflow{}, nil
+				},
+			})
+
+			message := createTestMessage()
+			message.Recipients = tt.recipients
+			coord := tt.coord
+			message.Coordination = &coord
+
+			_, err := processor.ProcessMessage(context.Background(), message, ProcessingOptions{Timeout: 30 * time.Second})
+			if tt.wantErr == nil {
+				if err != nil || !initialized {
+					t.Fatalf("Expected the coordination to start, got %v", err)
+				}
+				return
+			}
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Expected %v, got %v", tt.wantErr, err)
+			}
+			if initialized {
+				t.Error("Expected the coordination not to start")
+			}
+			if _, err := storage.GetMessage(context.Background(), message.MessageID); err == nil {
+				t.Error("Expected the rejected message not to be stored")
+			}
+		})
+	}
+
+	// Messages relayed on the immediate path are not coordinated here
+	processor := NewMessageProcessor(NewMockDiscovery(), NewMockDeliveryEngine(), NewMockStorage())
+	processor.SetCoordinationLimits(CoordinationLimits{MaxRecipients: 1})
+	message := createTestMessage()
+	message.Recipients = []string{"a@test.com", "b@test.com"}
+	message.Coordination = &types.CoordinationConfig{Type: "parallel", Timeout: 60}
+	if _, err := processor.ProcessMessage(context.Background(), message, ProcessingOptions{ImmediatePath: true, Timeout: 30 * time.Second}); err != nil {
+		t.Errorf("Expected the immediate path to skip the coordination limits, got %v", err)
+	}
+}
+
+func TestProcessMessage_CoordinationFallback(t *testing.T) {
+	defer features.Set(nil)
+
+	for _, fallback := range []bool{false, true} {
+		features.Set(map[string]bool{features.CoordinationFallback: fallback})
+		processor := NewMessageProcessor(NewMockDiscovery(), NewMockDeliveryEngine(), NewMockStorage())
+
+		message := createTestMessage()
+		message.Coordination = &types.CoordinationConfig{
+			Type:    "parallel",
+			Timeout: 30,
+		}
+
+		result, err := processor.ProcessMessage(context.Background(), message, ProcessingOptions{Timeout: 30 * time.Second})
+		if !fallback {
+			if err == nil {
+				t.Error("Expected coordination to fail without a workflow engine")
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("Expected the fallback to deliver immediately, got %v", err)
+		}
+		if result.Status != types.StatusDelivered {
+			t.Errorf("Expected status delivered, got %s", result.Status)
+		}
+	}
+}
+
 func TestProcessMessage_SequentialCoordination(t *testing.T) {
 	discovery := NewMockDiscovery()
 	deliveryEngine := NewMockDeliveryEngine()
@@ -0,0 +1,92 @@
+/*
+ * Copyright 2026 Cong Wang
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package processing
+
+import (
+	"slices"
+	"sync"
+
+	"github.com/amtp-protocol/agentry/internal/agents"
+)
+
+// pushBalancer picks the push target of agents using the balanced push
+// strategy. Targets take turns in proportion to their weights following
+// smooth weighted round-robin, so an 80/20 split sends four of every five
+// messages to the first target without sending them in bursts.
+type pushBalancer struct {
+	mu     sync.Mutex
+	agents map[string]*pushRotation
+}
+
+// pushRotation is the round-robin state of one agent's targets
+type pushRotation struct {
+	targets []string
+	weights []int
+	current []int
+}
+
+func newPushBalancer() *pushBalancer {
+	return &pushBalancer{agents: make(map[string]*pushRotation)}
+}
+
+// order returns targets in the order to try them for the next message: the
+// target whose turn it is, then the others by descending weight for
+// failover. The rotation starts over when the agent's targets or weights
+// change.
+func (b *pushBalancer) order(agent *agents.LocalAgent, targets []string) []string {
+	weights := make([]int, len(targets))
+	total := 0
+	for i, target := range targets {
+		weights[i] = agent.PushTargetWeight(target)
+		total += weights[i]
+	}
+
+	b.mu.Lock()
+	rotation, ok := b.agents[agent.Address]
+	if !ok || !slices.Equal(rotation.targets, targets) || !slices.Equal(rotation.weights, weights) {
+		rotation = &pushRotation{
+			targets: slices.Clone(targets),
+			weights: weights,
+			current: make([]int, len(targets)),
+		}
+		b.agents[agent.Address] = rotation
+	}
+
+	next := 0
+	for i, weight := range rotation.weights {
+		rotation.current[i] += weight
+		if rotation.current[i] > rotation.current[next] {
+			next = i
+		}
+	}
+	rotation.current[next] -= total
+	b.mu.Unlock()
+
+	ordered := make([]string, 0, len(targets))
+	ordered = append(ordered, targets[next])
+	rest := make([]int, 0, len(targets)-1)
+	for i := range targets {
+		if i != next {
+			rest = append(rest, i)
+		}
+	}
+	slices.SortStableFunc(rest, func(a, b int) int { return weights[b] - weights[a] })
+	for _, i := range rest {
+		ordered = append(ordered, targets[i])
+	}
+	return ordered
+}
@@ -0,0 +1,68 @@
+/*
+ * Copyright 2026 Cong Wang
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package processing
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/amtp-protocol/agentry/internal/types"
+	"github.com/amtp-protocol/agentry/pkg/uuid"
+)
+
+// NewReadReceipt builds the receipt telling the sender of message that
+// recipient acknowledged it, under a message ID from ids. The receipt is sent from the recipient and goes
+// through ProcessMessage like any other message: into the sender's inbox
+// when the sender is local, relayed to the sender's gateway otherwise.
+func NewReadReceipt(ids uuid.Generator, message *types.Message, recipient string, acknowledgedAt time.Time) (*types.Message, error) {
+	messageID, err := ids.Generate()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate message ID: %w", err)
+	}
+	idempotencyKey, err := uuid.GenerateV4()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate idempotency key: %w", err)
+	}
+	payload, err := json.Marshal(types.ReadReceipt{
+		OriginalMessageID: message.MessageID,
+		OriginalSubject:   message.Subject,
+		Recipient:         recipient,
+		AcknowledgedAt:    acknowledgedAt.UTC(),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode read receipt: %w", err)
+	}
+
+	subject := "Read receipt"
+	if message.Subject != "" {
+		subject = "Read: " + message.Subject
+	}
+
+	return &types.Message{
+		Version:        "1.0",
+		MessageID:      messageID,
+		IdempotencyKey: idempotencyKey,
+		Timestamp:      time.Now().UTC(),
+		Sender:         recipient,
+		Recipients:     []string{message.Sender},
+		Subject:        subject,
+		Payload:        payload,
+		InReplyTo:      message.MessageID,
+		ResponseType:   types.ResponseTypeReadReceipt,
+	}, nil
+}
@@ -0,0 +1,107 @@
+/*
+ * Copyright 2026 Cong Wang
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package processing
+
+import (
+	"context"
+	"sync"
+
+	"github.com/amtp-protocol/agentry/internal/metrics"
+)
+
+// relayLimiter caps the relays in flight to each destination domain. Relays
+// beyond the cap wait for a slot instead of opening more connections to the
+// same partner gateway.
+type relayLimiter struct {
+	max     int
+	metrics metrics.MetricsProvider // optional
+
+	mu      sync.Mutex
+	domains map[string]*relaySlots
+}
+
+type relaySlots struct {
+	sem      chan struct{}
+	inFlight int
+	users    int // relays holding or waiting for a slot
+}
+
+// newRelayLimiter returns nil when max is not positive, which means no cap
+func newRelayLimiter(max int, m metrics.MetricsProvider) *relayLimiter {
+	if max <= 0 {
+		return nil
+	}
+	return &relayLimiter{
+		max:     max,
+		metrics: m,
+		domains: make(map[string]*relaySlots),
+	}
+}
+
+// acquire waits for a slot to domain. It fails only when ctx ends first;
+// every successful acquire must be paired with release.
+func (l *relayLimiter) acquire(ctx context.Context, domain string) error {
+	l.mu.Lock()
+	slots, ok := l.domains[domain]
+	if !ok {
+		slots = &relaySlots{sem: make(chan struct{}, l.max)}
+		l.domains[domain] = slots
+	}
+	slots.users++
+	l.mu.Unlock()
+
+	select {
+	case slots.sem <- struct{}{}:
+	case <-ctx.Done():
+		l.mu.Lock()
+		l.leave(domain, slots)
+		l.mu.Unlock()
+		return ctx.Err()
+	}
+
+	l.mu.Lock()
+	slots.inFlight++
+	l.report(domain, slots.inFlight)
+	l.mu.Unlock()
+	return nil
+}
+
+// release frees the slot taken by acquire
+func (l *relayLimiter) release(domain string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	slots := l.domains[domain]
+	<-slots.sem
+	slots.inFlight--
+	l.report(domain, slots.inFlight)
+	l.leave(domain, slots)
+}
+
+// leave drops the domain once no relay holds or waits for its slots
+func (l *relayLimiter) leave(domain string, slots *relaySlots) {
+	slots.users--
+	if slots.users == 0 {
+		delete(l.domains, domain)
+	}
+}
+
+func (l *relayLimiter) report(domain string, inFlight int) {
+	if l.metrics != nil {
+		l.metrics.SetRelaysInFlight(domain, inFlight)
+	}
+}
@@ -20,6 +20,8 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"slices"
+	"sort"
 	"sync"
 	"time"
 
@@ -115,6 +117,14 @@ type MockStorage struct {
 	agents   map[string]*agents.LocalAgent
 	mutex    sync.RWMutex
 	error    error
+
+	activeWorkflows int64
+	claims          map[string]mockClaim
+}
+
+type mockClaim struct {
+	worker    string
+	expiresAt time.Time
 }
 
 func NewMockStorage() *MockStorage {
@@ -157,6 +167,17 @@ func (m *MockStorage) DeleteMessage(ctx context.Context, messageID string) error
 	return nil
 }
 
+func (m *MockStorage) StoreRawRequest(ctx context.Context, raw *types.RawRequest) error {
+	return m.error
+}
+
+func (m *MockStorage) GetRawRequest(ctx context.Context, messageID string) (*types.RawRequest, error) {
+	if m.error != nil {
+		return nil, m.error
+	}
+	return nil, fmt.Errorf("raw request not found: %s", messageID)
+}
+
 func (m *MockStorage) ListMessages(ctx context.Context, filter storage.MessageFilter) ([]*types.Message, error) {
 	if m.error != nil {
 		return nil, m.error
@@ -170,6 +191,15 @@ func (m *MockStorage) ListMessages(ctx context.Context, filter storage.MessageFi
 	return results, nil
 }
 
+func (m *MockStorage) CountMessages(ctx context.Context, filter storage.MessageFilter) (int64, error) {
+	if m.error != nil {
+		return 0, m.error
+	}
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	return int64(len(m.messages)), nil
+}
+
 func (m *MockStorage) StoreStatus(ctx context.Context, messageID string, status *types.MessageStatus) error {
 	if m.error != nil {
 		return m.error
@@ -192,6 +222,22 @@ func (m *MockStorage) GetStatus(ctx context.Context, messageID string) (*types.M
 	return nil, fmt.Errorf("message status not found: %s", messageID)
 }
 
+func (m *MockStorage) GetStatusByIdempotencyKey(ctx context.Context, idempotencyKey string) (*types.MessageStatus, error) {
+	if m.error != nil {
+		return nil, m.error
+	}
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	for id, msg := range m.messages {
+		if msg.IdempotencyKey == idempotencyKey {
+			if status, exists := m.statuses[id]; exists {
+				return status, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("message not found for idempotency key: %s", idempotencyKey)
+}
+
 func (m *MockStorage) UpdateStatus(ctx context.Context, messageID string, updater storage.StatusUpdater) error {
 	if m.error != nil {
 		return m.error
@@ -214,6 +260,43 @@ func (m *MockStorage) DeleteStatus(ctx context.Context, messageID string) error
 	return nil
 }
 
+func (m *MockStorage) GetStatuses(ctx context.Context, messageIDs []string) (map[string]*types.MessageStatus, error) {
+	if m.error != nil {
+		return nil, m.error
+	}
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	statuses := make(map[string]*types.MessageStatus, len(messageIDs))
+	for _, messageID := range messageIDs {
+		if status, exists := m.statuses[messageID]; exists {
+			statuses[messageID] = status
+		}
+	}
+	return statuses, nil
+}
+
+func (m *MockStorage) ListStatuses(ctx context.Context, cursor string, limit int) ([]*types.MessageStatus, string, error) {
+	if m.error != nil {
+		return nil, "", m.error
+	}
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	var statuses []*types.MessageStatus
+	for messageID, status := range m.statuses {
+		if messageID > cursor {
+			statuses = append(statuses, status)
+		}
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].MessageID < statuses[j].MessageID })
+	if len(statuses) > limit {
+		statuses = statuses[:limit]
+		return statuses, statuses[limit-1].MessageID, nil
+	}
+	return statuses, "", nil
+}
+
 func (m *MockStorage) GetInboxMessages(ctx context.Context, recipient string) ([]*types.Message, error) {
 	if m.error != nil {
 		return nil, m.error
@@ -241,7 +324,109 @@ func (m *MockStorage) GetInboxMessages(ctx context.Context, recipient string) ([
 	return inboxMessages, nil
 }
 
-func (m *MockStorage) AcknowledgeMessage(ctx context.Context, recipient, messageID string) error {
+func (m *MockStorage) ListInboxMessages(ctx context.Context, recipient, cursor string, limit int) ([]*types.Message, string, error) {
+	messages, err := m.GetInboxMessages(ctx, recipient)
+	if err != nil {
+		return nil, "", err
+	}
+	return messages, "", nil
+}
+
+func (m *MockStorage) ExportMessages(ctx context.Context, address, cursor string, limit int) ([]*types.Message, string, error) {
+	if m.error != nil {
+		return nil, "", m.error
+	}
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	var messages []*types.Message
+	for _, message := range m.messages {
+		if message.Sender == address {
+			messages = append(messages, message)
+			continue
+		}
+		for _, recipient := range message.Recipients {
+			if recipient == address {
+				messages = append(messages, message)
+				break
+			}
+		}
+	}
+	return messages, "", nil
+}
+
+func (m *MockStorage) ListMessagesBySchema(ctx context.Context, schemaIDs []string, cursor string, limit int) ([]*types.Message, string, error) {
+	if m.error != nil {
+		return nil, "", m.error
+	}
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	var messages []*types.Message
+	for _, message := range m.messages {
+		if slices.Contains(schemaIDs, message.Schema) {
+			messages = append(messages, message)
+		}
+	}
+	return messages, "", nil
+}
+
+func (m *MockStorage) ListPendingInbox(ctx context.Context, cursor string, limit int) ([]types.PendingInboxMessage, string, error) {
+	return nil, "", nil
+}
+
+func (m *MockStorage) DrainInbox(ctx context.Context, recipient string) (int64, error) {
+	if m.error != nil {
+		return 0, m.error
+	}
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	var drained int64
+	now := time.Now().UTC()
+	for _, status := range m.statuses {
+		for i, recipientStatus := range status.Recipients {
+			if recipientStatus.Address == recipient && recipientStatus.LocalDelivery &&
+				recipientStatus.InboxDelivered && !recipientStatus.Acknowledged {
+				status.Recipients[i].Acknowledged = true
+				status.Recipients[i].AcknowledgedAt = &now
+				drained++
+			}
+		}
+	}
+	return drained, nil
+}
+
+func (m *MockStorage) ListDeliveryErrors(ctx context.Context, since *time.Time, limit int) ([]types.DeliveryError, error) {
+	if m.error != nil {
+		return nil, m.error
+	}
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	var deliveryErrors []types.DeliveryError
+	for messageID, status := range m.statuses {
+		for _, recipientStatus := range status.Recipients {
+			if recipientStatus.Status != types.StatusFailed || (since != nil && recipientStatus.Timestamp.Before(*since)) {
+				continue
+			}
+			if len(deliveryErrors) == limit {
+				return deliveryErrors, nil
+			}
+			deliveryErrors = append(deliveryErrors, types.DeliveryError{
+				MessageID:    messageID,
+				Recipient:    recipientStatus.Address,
+				ErrorCode:    recipientStatus.ErrorCode,
+				ErrorMessage: recipientStatus.ErrorMessage,
+				Attempts:     recipientStatus.Attempts,
+				Timestamp:    recipientStatus.Timestamp,
+			})
+		}
+	}
+	return deliveryErrors, nil
+}
+
+func (m *MockStorage) AcknowledgeMessage(ctx context.Context, recipient, messageID string, response json.RawMessage) error {
 	if m.error != nil {
 		return m.error
 	}
@@ -265,6 +450,7 @@ func (m *MockStorage) AcknowledgeMessage(ctx context.Context, recipient, message
 			now := time.Now().UTC()
 			status.Recipients[i].Acknowledged = true
 			status.Recipients[i].AcknowledgedAt = &now
+			status.Recipients[i].Response = response
 			status.UpdatedAt = now
 			return nil
 		}
@@ -302,12 +488,22 @@ func (m *MockStorage) CreateAgent(ctx context.Context, agent *agents.LocalAgent)
 func (m *MockStorage) GetAgent(ctx context.Context, agentAddress string) (*agents.LocalAgent, error) {
 	agent, exists := m.agents[agentAddress]
 	if !exists {
-		return nil, fmt.Errorf("agent not found: %s", agentAddress)
+		return nil, fmt.Errorf("%w: %s", agents.ErrAgentNotFound, agentAddress)
 	}
 
 	return agent, nil
 }
 
+func (m *MockStorage) GetAgentByAPIKey(ctx context.Context, hashedKey string) (*agents.LocalAgent, error) {
+	for _, agent := range m.agents {
+		if agent.APIKey == hashedKey {
+			agentCopy := *agent
+			return &agentCopy, nil
+		}
+	}
+	return nil, agents.ErrAgentNotFound
+}
+
 func (m *MockStorage) UpdateAgent(ctx context.Context, agent *agents.LocalAgent) error {
 	if agent == nil {
 		return fmt.Errorf("agent cannot be nil")
@@ -321,6 +517,18 @@ func (m *MockStorage) UpdateAgent(ctx context.Context, agent *agents.LocalAgent)
 	return nil
 }
 
+func (m *MockStorage) UpdateLastAccess(ctx context.Context, agentAddress string, at time.Time) error {
+	agent, exists := m.agents[agentAddress]
+	if !exists {
+		return fmt.Errorf("agent not found: %s", agentAddress)
+	}
+
+	agentCopy := *agent
+	agentCopy.LastAccess = at
+	m.agents[agentAddress] = &agentCopy
+	return nil
+}
+
 func (m *MockStorage) DeleteAgent(ctx context.Context, agentAddress string) error {
 	if _, exists := m.agents[agentAddress]; !exists {
 		return fmt.Errorf("agent not found: %s", agentAddress)
@@ -433,3 +641,42 @@ func (m *MockStorage) UpdateWorkflowStatusAtomic(ctx context.Context, workflowID
 func (m *MockStorage) ListTimedOutWorkflows(ctx context.Context) ([]*types.Workflow, error) {
 	return nil, nil
 }
+
+func (m *MockStorage) CountActiveWorkflows(ctx context.Context) (int64, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	return m.activeWorkflows, nil
+}
+
+func (m *MockStorage) ClaimMessagesForDelivery(ctx context.Context, workerID string, limit int, leaseDuration time.Duration) ([]string, error) {
+	if m.error != nil {
+		return nil, m.error
+	}
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if m.claims == nil {
+		m.claims = make(map[string]mockClaim)
+	}
+
+	now := time.Now()
+	var claimed []string
+	for messageID, status := range m.statuses {
+		if claim, ok := m.claims[messageID]; ok && claim.worker != workerID && claim.expiresAt.After(now) {
+			continue
+		}
+		for _, rs := range status.Recipients {
+			if isHeld(rs) {
+				claimed = append(claimed, messageID)
+				break
+			}
+		}
+	}
+	sort.Strings(claimed)
+	if len(claimed) > limit {
+		claimed = claimed[:limit]
+	}
+	for _, messageID := range claimed {
+		m.claims[messageID] = mockClaim{worker: workerID, expiresAt: now.Add(leaseDuration)}
+	}
+	return claimed, nil
+}
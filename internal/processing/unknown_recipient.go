@@ -0,0 +1,294 @@
+/*
+ * Copyright 2026 Cong Wang
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package processing
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/amtp-protocol/agentry/internal/types"
+	"github.com/amtp-protocol/agentry/pkg/uuid"
+)
+
+// recoverBatchSize is how many message statuses are read at a time when
+// looking for held recipients
+const recoverBatchSize = 500
+
+// isHeld reports whether a recipient is waiting for its agent to register
+func isHeld(rs types.RecipientStatus) bool {
+	return rs.Status == types.StatusQueued && rs.ErrorCode == ErrorCodeAgentNotRegistered
+}
+
+// SetDeliveryClaims makes RetryHeldRecipients lease the messages it retries
+// from storage as workerID, for lease at a time. Every gateway sharing the
+// storage needs its own worker ID, and lease must outlast one retry round.
+func (mp *MessageProcessor) SetDeliveryClaims(workerID string, lease time.Duration) {
+	mp.claimWorker = workerID
+	mp.claimLease = lease
+}
+
+// trackHeld remembers messageID for RetryHeldRecipients if any of its
+// recipients is held, and reports whether one was
+func (mp *MessageProcessor) trackHeld(messageID string, recipients []types.RecipientStatus) bool {
+	for _, rs := range recipients {
+		if isHeld(rs) {
+			if mp.claimWorker != "" {
+				return true
+			}
+			mp.heldMu.Lock()
+			if _, ok := mp.held[messageID]; !ok {
+				mp.held[messageID] = time.Now()
+			}
+			mp.heldMu.Unlock()
+			return true
+		}
+	}
+	return false
+}
+
+func (mp *MessageProcessor) untrackHeld(messageID string) {
+	mp.heldMu.Lock()
+	delete(mp.held, messageID)
+	mp.heldMu.Unlock()
+}
+
+// RecoverHeldRecipients finds the messages in storage with held recipients,
+// for example after a restart, so RetryHeldRecipients picks them up again.
+// It returns how many it found.
+func (mp *MessageProcessor) RecoverHeldRecipients(ctx context.Context) (int, error) {
+	// Claims find held recipients in storage on every retry
+	if mp.claimWorker != "" {
+		return 0, nil
+	}
+
+	found := 0
+	cursor := ""
+	for {
+		statuses, next, err := mp.storage.ListStatuses(ctx, cursor, recoverBatchSize)
+		if err != nil {
+			return found, fmt.Errorf("failed to list message statuses: %w", err)
+		}
+		for _, status := range statuses {
+			if mp.trackHeld(status.MessageID, status.Recipients) {
+				found++
+			}
+		}
+		if next == "" {
+			return found, nil
+		}
+		cursor = next
+	}
+}
+
+// RetryHeldRecipients delivers again to every recipient held because no agent
+// was registered for it. A recipient still without an agent once it has been
+// held for longer than hold fails with AGENT_NOT_FOUND, and is reported like
+// any other failed recipient.
+func (mp *MessageProcessor) RetryHeldRecipients(ctx context.Context, hold time.Duration) error {
+	if mp.claimWorker != "" {
+		return mp.retryClaimed(ctx, hold)
+	}
+
+	mp.heldMu.Lock()
+	held := make(map[string]time.Time, len(mp.held))
+	for messageID, since := range mp.held {
+		held[messageID] = since
+	}
+	mp.heldMu.Unlock()
+
+	var errs []error
+	for messageID, since := range held {
+		if err := mp.retryHeld(ctx, messageID, hold); err != nil {
+			// A message that cannot be read is given up on once nothing
+			// in it could still be held
+			if time.Since(since) > hold {
+				mp.untrackHeld(messageID)
+			}
+			errs = append(errs, fmt.Errorf("message %s: %w", messageID, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// retryClaimed retries the held recipients of the messages this gateway
+// leases from storage. Messages another gateway leases are left to it until
+// its lease runs out.
+func (mp *MessageProcessor) retryClaimed(ctx context.Context, hold time.Duration) error {
+	messageIDs, err := mp.storage.ClaimMessagesForDelivery(ctx, mp.claimWorker, recoverBatchSize, mp.claimLease)
+	if err != nil {
+		return fmt.Errorf("failed to claim queued messages: %w", err)
+	}
+
+	var errs []error
+	for _, messageID := range messageIDs {
+		if err := mp.retryHeld(ctx, messageID, hold); err != nil {
+			errs = append(errs, fmt.Errorf("message %s: %w", messageID, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// retryHeld retries the held recipients of one message
+func (mp *MessageProcessor) retryHeld(ctx context.Context, messageID string, hold time.Duration) error {
+	message, err := mp.storage.GetMessage(ctx, messageID)
+	if err != nil {
+		return err
+	}
+	status, err := mp.storage.GetStatus(ctx, messageID)
+	if err != nil {
+		return err
+	}
+
+	// Deliver before taking the status update, which must stay short
+	retried := make(map[string]types.RecipientStatus)
+	for _, rs := range status.Recipients {
+		if !isHeld(rs) {
+			continue
+		}
+		updated := mp.deliver(ctx, message, rs.Address, rs.Attempts+1)
+		if isHeld(updated) {
+			if time.Since(rs.Timestamp) <= hold {
+				continue
+			}
+			updated.Status = types.StatusFailed
+			updated.ErrorCode = ErrorCodeAgentNotFound
+			updated.ErrorMessage = fmt.Sprintf("no agent was registered for %s within %v", rs.Address, hold)
+		}
+		retried[rs.Address] = updated
+	}
+
+	var failed []types.RecipientStatus
+	stillHeld := false
+	if len(retried) > 0 {
+		err = mp.storage.UpdateStatus(ctx, messageID, func(current *types.MessageStatus) error {
+			failed = failed[:0]
+			for i, rs := range current.Recipients {
+				updated, ok := retried[rs.Address]
+				if !ok || !isHeld(rs) {
+					continue
+				}
+				current.Recipients[i] = updated
+				if updated.Status == types.StatusFailed {
+					failed = append(failed, updated)
+				}
+			}
+
+			now := time.Now().UTC()
+			current.Status = types.AggregateStatus(current.Recipients)
+			current.UpdatedAt = now
+			if current.Status == types.StatusDelivered && current.DeliveredAt == nil {
+				current.DeliveredAt = &now
+			}
+			stillHeld = mp.trackHeld(messageID, current.Recipients)
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("failed to update status: %w", err)
+		}
+	} else {
+		stillHeld = mp.trackHeld(messageID, status.Recipients)
+	}
+	if !stillHeld {
+		mp.untrackHeld(messageID)
+	}
+
+	if len(failed) > 0 {
+		mp.bounce(ctx, message, &ProcessingResult{MessageID: messageID, Recipients: failed})
+		mp.deadLetter(ctx, message, failed)
+	}
+	return nil
+}
+
+// SetDeadLetter makes the processor forward messages for unregistered local
+// recipients to address, from sender
+func (mp *MessageProcessor) SetDeadLetter(sender, address string) {
+	mp.deadLetterSender = sender
+	mp.deadLetterAddress = address
+}
+
+// deadLetter forwards message to the dead-letter address in the background
+// when recipients failed because no agent is registered for them
+func (mp *MessageProcessor) deadLetter(ctx context.Context, message *types.Message, recipients []types.RecipientStatus) {
+	if mp.deadLetterAddress == "" || message.ResponseType == types.ResponseTypeDeadLetter {
+		return
+	}
+
+	var unknown []types.FailedRecipient
+	for _, rs := range recipients {
+		if rs.Status == types.StatusFailed && rs.ErrorCode == ErrorCodeAgentNotFound &&
+			!strings.EqualFold(rs.Address, mp.deadLetterAddress) {
+			unknown = append(unknown, types.FailedRecipient{
+				Address:      rs.Address,
+				ErrorCode:    rs.ErrorCode,
+				ErrorMessage: rs.ErrorMessage,
+			})
+		}
+	}
+	if len(unknown) == 0 {
+		return
+	}
+
+	letter, err := newDeadLetter(mp.messageIDs, mp.deadLetterSender, mp.deadLetterAddress, message, unknown)
+	if err != nil {
+		return
+	}
+
+	bgCtx := context.WithoutCancel(ctx)
+	mp.background.Add(1)
+	go func() {
+		defer mp.background.Done()
+		_, _ = mp.ProcessMessage(bgCtx, letter, ProcessingOptions{ImmediatePath: true})
+	}()
+}
+
+// newDeadLetter wraps message for the dead-letter address
+func newDeadLetter(ids uuid.Generator, sender, address string, message *types.Message, unknown []types.FailedRecipient) (*types.Message, error) {
+	messageID, err := ids.Generate()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate message ID: %w", err)
+	}
+	idempotencyKey, err := uuid.GenerateV4()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate idempotency key: %w", err)
+	}
+	payload, err := json.Marshal(types.DeadLetter{Recipients: unknown, Message: message})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode dead letter: %w", err)
+	}
+
+	subject := "Dead letter"
+	if message.Subject != "" {
+		subject = "Dead letter: " + message.Subject
+	}
+
+	return &types.Message{
+		Version:        "1.0",
+		MessageID:      messageID,
+		IdempotencyKey: idempotencyKey,
+		Timestamp:      time.Now().UTC(),
+		Sender:         sender,
+		Recipients:     []string{address},
+		Subject:        subject,
+		Payload:        payload,
+		InReplyTo:      message.MessageID,
+		ResponseType:   types.ResponseTypeDeadLetter,
+	}, nil
+}
@@ -59,7 +59,9 @@ func (r *DatabaseRegistry) RegisterOrUpdateSchema(ctx context.Context, s *Schema
 	return r.store.StoreSchema(ctx, s, meta)
 }
 
-// RegisterSchema registers a new schema
+// RegisterSchema registers a new schema. The store enforces unique schema
+// identifiers, so of concurrent registrations of one ID only the first
+// succeeds and the others get ErrSchemaExists.
 func (r *DatabaseRegistry) RegisterSchema(ctx context.Context, s *Schema, meta *SchemaMetadata) error {
 	return r.store.StoreSchema(ctx, s, meta)
 }
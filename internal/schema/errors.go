@@ -67,6 +67,7 @@ func (er *ErrorReporter) ReportValidationErrors(ctx context.Context, report *Val
 type ValidationReport struct {
 	MessageID        string            `json:"message_id"`
 	SchemaID         string            `json:"schema_id"`
+	ResolvedSchema   string            `json:"resolved_schema,omitempty"`
 	NegotiatedSchema string            `json:"negotiated_schema,omitempty"`
 	Valid            bool              `json:"valid"`
 	Errors           []ValidationError `json:"errors,omitempty"`
@@ -0,0 +1,109 @@
+/*
+ * Copyright 2026 Cong Wang
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package schema
+
+import (
+	"net/mail"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/amtp-protocol/agentry/internal/types"
+)
+
+// Policies for a "format" keyword with no registered checker
+const (
+	UnknownFormatWarn  = "warn"  // report a warning and accept the value
+	UnknownFormatError = "error" // reject the value
+)
+
+// FormatChecker reports whether a string value conforms to a format
+type FormatChecker func(value string) bool
+
+// FormatRegistry maps values of the JSON Schema "format" keyword to checkers.
+// It is safe for concurrent use, so formats can be registered while messages
+// are being validated.
+type FormatRegistry struct {
+	mu       sync.RWMutex
+	checkers map[string]FormatChecker
+}
+
+// NewFormatRegistry returns a registry holding the built-in formats: email,
+// uri, date, date-time and agntcy-address under the default address schemes
+func NewFormatRegistry() *FormatRegistry {
+	return &FormatRegistry{
+		checkers: map[string]FormatChecker{
+			"email":          isEmailFormat,
+			"uri":            isURIFormat,
+			"date":           isDateFormat,
+			"date-time":      isDateTimeFormat,
+			"agntcy-address": IsAgentAddress,
+		},
+	}
+}
+
+// Register adds a format, replacing any checker already registered under name
+func (r *FormatRegistry) Register(name string, checker FormatChecker) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.checkers[name] = checker
+}
+
+// Lookup returns the checker registered under name
+func (r *FormatRegistry) Lookup(name string) (FormatChecker, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	checker, ok := r.checkers[name]
+	return checker, ok
+}
+
+// AgentAddressFormat returns a checker for the agntcy-address format that
+// accepts the addresses the gateway accepts under schemes, such as
+// sales-bot@example.com, given bare rather than with a display name
+func AgentAddressFormat(schemes types.AddressSchemes) FormatChecker {
+	return func(value string) bool {
+		return schemes.IsValid(value) && strings.EqualFold(types.NormalizeAddress(value), value)
+	}
+}
+
+// IsAgentAddress reports whether value is a valid AMTP agent address under
+// the default address schemes
+func IsAgentAddress(value string) bool {
+	return AgentAddressFormat(types.DefaultAddressSchemes)(value)
+}
+
+func isEmailFormat(value string) bool {
+	addr, err := mail.ParseAddress(value)
+	return err == nil && addr.Address == value
+}
+
+// isURIFormat accepts absolute URIs, e.g. https://example.com or urn:isbn:0451450523
+func isURIFormat(value string) bool {
+	u, err := url.Parse(value)
+	return err == nil && u.Scheme != "" && (u.Host != "" || u.Opaque != "")
+}
+
+func isDateFormat(value string) bool {
+	_, err := time.Parse("2006-01-02", value)
+	return err == nil
+}
+
+func isDateTimeFormat(value string) bool {
+	_, err := time.Parse(time.RFC3339, value)
+	return err == nil
+}
@@ -0,0 +1,186 @@
+/*
+ * Copyright 2026 Cong Wang
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package schema
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// DefaultMaxEnumValues is how many distinct values a string field may take in
+// the examples to be inferred as an enum, unless InferOptions says otherwise
+const DefaultMaxEnumValues = 5
+
+// InferOptions tunes schema inference
+type InferOptions struct {
+	// MaxEnumValues is the most distinct values a string field may take to
+	// be inferred as an enum. 0 never infers enums.
+	MaxEnumValues int
+}
+
+// jsonTypes orders the types a value was seen with in inferred schemas
+var jsonTypes = []string{"object", "array", "string", "integer", "number", "boolean", "null"}
+
+// InferSchema suggests a JSON Schema that every example payload satisfies,
+// as a starting point to review before registering it. Objects declare the
+// properties seen in any example and require those present in all of them.
+// A string field is inferred as an enum when it repeats a few values, so a
+// field seen once is never pinned to its value.
+func InferSchema(examples []json.RawMessage, options InferOptions) (json.RawMessage, error) {
+	if len(examples) == 0 {
+		return nil, errors.New("at least one example payload is required")
+	}
+
+	root := &inferredNode{}
+	for i, example := range examples {
+		decoder := json.NewDecoder(bytes.NewReader(example))
+		decoder.UseNumber()
+		var value interface{}
+		if err := decoder.Decode(&value); err != nil {
+			return nil, fmt.Errorf("example %d is not valid JSON: %w", i+1, err)
+		}
+		if decoder.More() {
+			return nil, fmt.Errorf("example %d is not valid JSON: unexpected data after the payload", i+1)
+		}
+		root.observe(value, options.MaxEnumValues)
+	}
+
+	definition := root.schema(options.MaxEnumValues)
+	definition["$schema"] = "http://json-schema.org/draft-07/schema#"
+	return json.Marshal(definition)
+}
+
+// inferredNode accumulates the values seen at one place in the examples
+type inferredNode struct {
+	types map[string]bool
+
+	// Objects seen, and how many of them held each property
+	objects    int
+	properties map[string]*inferredNode
+	present    map[string]int
+
+	items *inferredNode
+
+	// Strings seen, and up to one more distinct value than an enum may hold
+	strings  int
+	distinct map[string]struct{}
+}
+
+func (n *inferredNode) observe(value interface{}, maxEnum int) {
+	if n.types == nil {
+		n.types = make(map[string]bool)
+	}
+
+	switch v := value.(type) {
+	case nil:
+		n.types["null"] = true
+	case bool:
+		n.types["boolean"] = true
+	case json.Number:
+		if strings.ContainsAny(v.String(), ".eE") {
+			n.types["number"] = true
+		} else {
+			n.types["integer"] = true
+		}
+	case string:
+		n.types["string"] = true
+		n.strings++
+		if n.distinct == nil {
+			n.distinct = make(map[string]struct{})
+		}
+		if len(n.distinct) <= maxEnum {
+			n.distinct[v] = struct{}{}
+		}
+	case []interface{}:
+		n.types["array"] = true
+		if n.items == nil {
+			n.items = &inferredNode{}
+		}
+		for _, item := range v {
+			n.items.observe(item, maxEnum)
+		}
+	case map[string]interface{}:
+		n.types["object"] = true
+		n.objects++
+		if n.properties == nil {
+			n.properties = make(map[string]*inferredNode)
+			n.present = make(map[string]int)
+		}
+		for name, property := range v {
+			if n.properties[name] == nil {
+				n.properties[name] = &inferredNode{}
+			}
+			n.properties[name].observe(property, maxEnum)
+			n.present[name]++
+		}
+	}
+}
+
+func (n *inferredNode) schema(maxEnum int) map[string]interface{} {
+	definition := make(map[string]interface{})
+
+	// Integers are numbers too, so a field holding both is a number
+	var types []string
+	for _, t := range jsonTypes {
+		if n.types[t] && !(t == "integer" && n.types["number"]) {
+			types = append(types, t)
+		}
+	}
+	switch len(types) {
+	case 0:
+		// Only empty arrays were seen here, so anything goes
+	case 1:
+		definition["type"] = types[0]
+	default:
+		definition["type"] = types
+	}
+
+	if n.types["object"] {
+		properties := make(map[string]interface{}, len(n.properties))
+		var required []string
+		for name, property := range n.properties {
+			properties[name] = property.schema(maxEnum)
+			if n.present[name] == n.objects {
+				required = append(required, name)
+			}
+		}
+		definition["properties"] = properties
+		if len(required) > 0 {
+			sort.Strings(required)
+			definition["required"] = required
+		}
+	}
+
+	if n.items != nil && n.items.types != nil {
+		definition["items"] = n.items.schema(maxEnum)
+	}
+
+	if len(types) == 1 && types[0] == "string" && len(n.distinct) <= maxEnum && len(n.distinct) < n.strings {
+		values := make([]string, 0, len(n.distinct))
+		for value := range n.distinct {
+			values = append(values, value)
+		}
+		sort.Strings(values)
+		definition["enum"] = values
+	}
+
+	return definition
+}
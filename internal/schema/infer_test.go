@@ -0,0 +1,173 @@
+/*
+ * Copyright 2026 Cong Wang
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package schema
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func rawExamples(examples ...string) []json.RawMessage {
+	raw := make([]json.RawMessage, len(examples))
+	for i, example := range examples {
+		raw[i] = json.RawMessage(example)
+	}
+	return raw
+}
+
+func TestInferSchema(t *testing.T) {
+	tests := []struct {
+		name     string
+		examples []json.RawMessage
+		maxEnum  int
+		expected string
+	}{
+		{
+			name: "orders",
+			examples: rawExamples(
+				`{"order_id": "A1", "status": "paid", "total": 12.5, "items": [{"sku": "X", "qty": 1}]}`,
+				`{"order_id": "A2", "status": "paid", "total": 3, "items": [{"sku": "Y", "qty": 2}, {"sku": "Z", "qty": 1, "gift": true}], "note": "leave at door"}`,
+				`{"order_id": "A3", "status": "shipped", "total": 7, "items": []}`,
+			),
+			maxEnum: DefaultMaxEnumValues,
+			expected: `{
+				"$schema": "http://json-schema.org/draft-07/schema#",
+				"type": "object",
+				"properties": {
+					"order_id": {"type": "string"},
+					"status": {"type": "string", "enum": ["paid", "shipped"]},
+					"total": {"type": "number"},
+					"note": {"type": "string"},
+					"items": {
+						"type": "array",
+						"items": {
+							"type": "object",
+							"properties": {
+								"sku": {"type": "string"},
+								"qty": {"type": "integer"},
+								"gift": {"type": "boolean"}
+							},
+							"required": ["qty", "sku"]
+						}
+					}
+				},
+				"required": ["items", "order_id", "status", "total"]
+			}`,
+		},
+		{
+			name: "nullable and mixed types",
+			examples: rawExamples(
+				`{"assignee": "bob", "priority": 1, "tags": []}`,
+				`{"assignee": null, "priority": "high", "tags": []}`,
+			),
+			maxEnum: DefaultMaxEnumValues,
+			expected: `{
+				"$schema": "http://json-schema.org/draft-07/schema#",
+				"type": "object",
+				"properties": {
+					"assignee": {"type": ["string", "null"]},
+					"priority": {"type": ["string", "integer"]},
+					"tags": {"type": "array"}
+				},
+				"required": ["assignee", "priority", "tags"]
+			}`,
+		},
+		{
+			name: "enums disabled",
+			examples: rawExamples(
+				`{"status": "open"}`,
+				`{"status": "open"}`,
+			),
+			maxEnum: 0,
+			expected: `{
+				"$schema": "http://json-schema.org/draft-07/schema#",
+				"type": "object",
+				"properties": {"status": {"type": "string"}},
+				"required": ["status"]
+			}`,
+		},
+		{
+			name: "too many values for an enum",
+			examples: rawExamples(
+				`{"region": "eu"}`, `{"region": "us"}`, `{"region": "ap"}`, `{"region": "eu"}`,
+			),
+			maxEnum: 2,
+			expected: `{
+				"$schema": "http://json-schema.org/draft-07/schema#",
+				"type": "object",
+				"properties": {"region": {"type": "string"}},
+				"required": ["region"]
+			}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inferred, err := InferSchema(tt.examples, InferOptions{MaxEnumValues: tt.maxEnum})
+			if err != nil {
+				t.Fatalf("InferSchema failed: %v", err)
+			}
+
+			var got, expected interface{}
+			if err := json.Unmarshal(inferred, &got); err != nil {
+				t.Fatalf("Inferred schema is not valid JSON: %v", err)
+			}
+			if err := json.Unmarshal([]byte(tt.expected), &expected); err != nil {
+				t.Fatalf("Expected schema is not valid JSON: %v", err)
+			}
+			if !reflect.DeepEqual(got, expected) {
+				t.Errorf("Unexpected schema:\n%s", inferred)
+			}
+
+			// Every example must pass the schema it was inferred from
+			validator := NewJSONSchemaValidator(nil, ValidatorConfig{})
+			for i, example := range tt.examples {
+				result, err := validator.ValidateWithSchema(context.Background(), example, &Schema{Definition: inferred})
+				if err != nil {
+					t.Fatalf("Validation failed: %v", err)
+				}
+				if !result.Valid {
+					t.Errorf("Expected example %d to satisfy the inferred schema, got %+v", i+1, result.Errors)
+				}
+			}
+		})
+	}
+}
+
+func TestInferSchema_InvalidExamples(t *testing.T) {
+	tests := []struct {
+		name     string
+		examples []json.RawMessage
+		errorMsg string
+	}{
+		{"no examples", nil, "at least one example"},
+		{"malformed example", rawExamples(`{"a": 1}`, `{"a":`), "example 2 is not valid JSON"},
+		{"trailing data", rawExamples(`{"a": 1} {"b": 2}`), "example 1 is not valid JSON"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := InferSchema(tt.examples, InferOptions{MaxEnumValues: DefaultMaxEnumValues})
+			if err == nil || !strings.Contains(err.Error(), tt.errorMsg) {
+				t.Errorf("Expected error containing %q, got %v", tt.errorMsg, err)
+			}
+		})
+	}
+}
@@ -24,8 +24,30 @@ import (
 var (
 	// ErrSchemaNotFound is returned when a requested schema is not found
 	ErrSchemaNotFound = errors.New("schema not found")
+
+	// ErrSchemaExists is returned when registering a schema whose identifier
+	// is already registered
+	ErrSchemaExists = errors.New("schema already exists")
+
+	// ErrReloadNotSupported is returned when reloading a registry that is not
+	// backed by files on disk
+	ErrReloadNotSupported = errors.New("schema registry does not support reloading from disk")
 )
 
+// Reloader is implemented by registries that can reconcile their in-memory
+// state with files changed on disk
+type Reloader interface {
+	ReloadFromDisk() (*ReloadSummary, error)
+}
+
+// ReloadSummary lists the schemas changed by a reload
+type ReloadSummary struct {
+	Added     []string `json:"added"`
+	Removed   []string `json:"removed"`
+	Updated   []string `json:"updated"`
+	Unchanged int      `json:"unchanged"`
+}
+
 // SchemaStore defines the interface for schema storage operations
 type SchemaStore interface {
 	// StoreSchema stores a schema in the registry
@@ -17,6 +17,7 @@
 package schema
 
 import (
+	"bytes"
 	"context"
 	"crypto/sha256"
 	"encoding/hex"
@@ -24,6 +25,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"reflect"
 	"sort"
 	"strings"
 	"sync"
@@ -38,7 +40,10 @@ type LocalRegistryConfig struct {
 	CreateDirs bool   `yaml:"create_dirs" json:"create_dirs"`
 }
 
-// LocalRegistry implements RegistryClient using local file system
+// LocalRegistry implements RegistryClient using local file system. It is
+// safe for concurrent use: mu guards the in-memory maps, and saveMu
+// serializes changes with the files under the base path so that the index
+// written last reflects the latest state. saveMu is always taken before mu.
 type LocalRegistry struct {
 	basePath    string
 	indexFile   string
@@ -46,6 +51,7 @@ type LocalRegistry struct {
 	schemas     map[string]*Schema
 	metadata    map[string]*SchemaMetadata
 	mu          sync.RWMutex
+	saveMu      sync.Mutex
 	initialized bool
 }
 
@@ -91,12 +97,15 @@ func NewLocalRegistry(config LocalRegistryConfig) (*LocalRegistry, error) {
 
 // RegisterSchema registers a new schema in the local registry
 func (lr *LocalRegistry) RegisterSchema(ctx context.Context, schema *Schema, metadata *SchemaMetadata) error {
+	lr.saveMu.Lock()
+	defer lr.saveMu.Unlock()
 	lr.mu.Lock()
 	defer lr.mu.Unlock()
 
-	// Check if schema already exists
+	// Check if schema already exists. Both locks are held until the schema
+	// is stored, so concurrent registrations of one ID cannot both pass.
 	if _, exists := lr.schemas[schema.ID.String()]; exists {
-		return fmt.Errorf("schema already exists: %s (use UpdateSchema to modify existing schemas)", schema.ID.String())
+		return fmt.Errorf("%w: %s (use UpdateSchema to modify existing schemas)", ErrSchemaExists, schema.ID.String())
 	}
 
 	// Use internal method to register
@@ -105,6 +114,8 @@ func (lr *LocalRegistry) RegisterSchema(ctx context.Context, schema *Schema, met
 
 // RegisterOrUpdateSchema registers a new schema or updates an existing one
 func (lr *LocalRegistry) RegisterOrUpdateSchema(ctx context.Context, schema *Schema, metadata *SchemaMetadata) error {
+	lr.saveMu.Lock()
+	defer lr.saveMu.Unlock()
 	lr.mu.Lock()
 	defer lr.mu.Unlock()
 
@@ -140,12 +151,16 @@ func (lr *LocalRegistry) registerSchemaInternal(schema *Schema, metadata *Schema
 	metadata.UpdatedAt = time.Now().UTC()
 	metadata.Size = int64(len(schema.Definition))
 	metadata.Checksum = checksum
+	metadata.Strict = schema.Strict
+	metadata.ValidationMode = schema.ValidationMode
+	metadata.Defaults = schema.Defaults
 
 	// Generate file path
 	filePath := lr.generateFilePath(schema.ID)
 	metadata.FilePath = filePath
 
-	// Store schema
+	// Store a copy, so the caller cannot change the registered schema
+	schema = cloneSchema(schema)
 	lr.schemas[schema.ID.String()] = schema
 	lr.metadata[schema.ID.String()] = metadata
 
@@ -176,6 +191,9 @@ func (lr *LocalRegistry) updateSchemaInternal(schema *Schema, metadata *SchemaMe
 	metadata.ID = schema.ID
 	metadata.UpdatedAt = time.Now().UTC()
 	metadata.Size = int64(len(schema.Definition))
+	metadata.Strict = schema.Strict
+	metadata.ValidationMode = schema.ValidationMode
+	metadata.Defaults = schema.Defaults
 
 	// Generate file path
 	filePath := lr.generateFilePath(schema.ID)
@@ -188,7 +206,8 @@ func (lr *LocalRegistry) updateSchemaInternal(schema *Schema, metadata *SchemaMe
 	}
 	metadata.Checksum = checksum
 
-	// Update schema
+	// Update schema with a copy, so the caller cannot change it afterwards
+	schema = cloneSchema(schema)
 	lr.schemas[schema.ID.String()] = schema
 	lr.metadata[schema.ID.String()] = metadata
 
@@ -212,12 +231,20 @@ func (lr *LocalRegistry) GetSchema(ctx context.Context, id SchemaIdentifier) (*S
 
 	schema, exists := lr.schemas[id.String()]
 	if !exists {
-		return nil, fmt.Errorf("schema not found: %s", id.String())
+		return nil, fmt.Errorf("%w: %s", ErrSchemaNotFound, id.String())
 	}
 
 	// Return a copy to prevent modification
-	schemaCopy := *schema
-	return &schemaCopy, nil
+	return cloneSchema(schema), nil
+}
+
+// cloneSchema returns a deep copy of schema
+func cloneSchema(schema *Schema) *Schema {
+	clone := *schema
+	if schema.Definition != nil {
+		clone.Definition = append(json.RawMessage(nil), schema.Definition...)
+	}
+	return &clone
 }
 
 // ListSchemas lists available schemas matching a pattern
@@ -268,13 +295,15 @@ func (lr *LocalRegistry) CheckCompatibility(ctx context.Context, current, new Sc
 
 // DeleteSchema removes a schema from the registry
 func (lr *LocalRegistry) DeleteSchema(ctx context.Context, id SchemaIdentifier) error {
+	lr.saveMu.Lock()
+	defer lr.saveMu.Unlock()
 	lr.mu.Lock()
 	defer lr.mu.Unlock()
 
 	// Check if schema exists
 	_, exists := lr.schemas[id.String()]
 	if !exists {
-		return fmt.Errorf("schema not found: %s", id.String())
+		return fmt.Errorf("%w: %s", ErrSchemaNotFound, id.String())
 	}
 
 	// Remove from memory
@@ -314,19 +343,22 @@ func (lr *LocalRegistry) GetSchemaMetadata(ctx context.Context, id SchemaIdentif
 
 	schema, exists := lr.schemas[id.String()]
 	if !exists {
-		return nil, fmt.Errorf("schema not found: %s", id.String())
+		return nil, fmt.Errorf("%w: %s", ErrSchemaNotFound, id.String())
 	}
 
 	// Generate metadata from schema
 	checksum, _ := lr.generateChecksum(schema.Definition)
 	metadata := &SchemaMetadata{
-		ID:        schema.ID,
-		Version:   schema.ID.Version,
-		CreatedAt: schema.PublishedAt,
-		UpdatedAt: schema.PublishedAt,
-		FilePath:  lr.generateFilePath(schema.ID),
-		Size:      int64(len(schema.Definition)),
-		Checksum:  checksum,
+		ID:             schema.ID,
+		Version:        schema.ID.Version,
+		CreatedAt:      schema.PublishedAt,
+		UpdatedAt:      schema.PublishedAt,
+		FilePath:       lr.generateFilePath(schema.ID),
+		Size:           int64(len(schema.Definition)),
+		Checksum:       checksum,
+		Strict:         schema.Strict,
+		ValidationMode: schema.ValidationMode,
+		Defaults:       schema.Defaults,
 	}
 
 	return metadata, nil
@@ -336,30 +368,109 @@ func (lr *LocalRegistry) GetSchemaMetadata(ctx context.Context, id SchemaIdentif
 func (lr *LocalRegistry) getSchemaMetadataInternal(schema *Schema) *SchemaMetadata {
 	checksum, _ := lr.generateChecksum(schema.Definition)
 	metadata := &SchemaMetadata{
-		ID:        schema.ID,
-		Version:   schema.ID.Version,
-		CreatedAt: schema.PublishedAt,
-		UpdatedAt: schema.PublishedAt,
-		FilePath:  lr.generateFilePath(schema.ID),
-		Size:      int64(len(schema.Definition)),
-		Checksum:  checksum,
+		ID:             schema.ID,
+		Version:        schema.ID.Version,
+		CreatedAt:      schema.PublishedAt,
+		UpdatedAt:      schema.PublishedAt,
+		FilePath:       lr.generateFilePath(schema.ID),
+		Size:           int64(len(schema.Definition)),
+		Checksum:       checksum,
+		Strict:         schema.Strict,
+		ValidationMode: schema.ValidationMode,
+		Defaults:       schema.Defaults,
 	}
 	return metadata
 }
 
-// SaveToDisk saves all schemas to disk
+// SaveToDisk saves all schemas to disk. It writes a snapshot taken under
+// the read lock, so lookups are not held up by the file writes.
 func (lr *LocalRegistry) SaveToDisk() error {
+	lr.saveMu.Lock()
+	defer lr.saveMu.Unlock()
+
 	lr.mu.RLock()
-	defer lr.mu.RUnlock()
+	schemas := make(map[string]*Schema, len(lr.schemas))
+	metadata := make(map[string]*SchemaMetadata, len(lr.metadata))
+	for id, schema := range lr.schemas {
+		schemas[id] = cloneSchema(schema)
+		if meta, ok := lr.metadata[id]; ok {
+			metaCopy := *meta
+			metadata[id] = &metaCopy
+		}
+	}
+	lr.mu.RUnlock()
 
-	for _, schema := range lr.schemas {
-		metadata := lr.metadata[schema.ID.String()]
-		if err := lr.saveSchema(schema, metadata); err != nil {
+	for id, schema := range schemas {
+		if err := lr.saveSchema(schema, metadata[id]); err != nil {
 			return fmt.Errorf("failed to save schema %s: %w", schema.ID.String(), err)
 		}
 	}
 
-	return lr.updateIndex()
+	return lr.writeIndex(schemas)
+}
+
+// ReloadFromDisk re-reads the base path and index and reconciles the
+// in-memory registry with them, complementing SaveToDisk. Schemas that fail
+// to load are skipped as at startup; if the registry cannot be read at all
+// it is left unchanged.
+func (lr *LocalRegistry) ReloadFromDisk() (*ReloadSummary, error) {
+	loaded := &LocalRegistry{
+		basePath:  lr.basePath,
+		indexFile: lr.indexFile,
+		schemas:   make(map[string]*Schema),
+		metadata:  make(map[string]*SchemaMetadata),
+	}
+	// Hold off writers while reading, so a half-written change is not loaded
+	lr.saveMu.Lock()
+	defer lr.saveMu.Unlock()
+	if err := loaded.loadFromDisk(); err != nil {
+		return nil, fmt.Errorf("failed to reload registry: %w", err)
+	}
+
+	lr.mu.Lock()
+	defer lr.mu.Unlock()
+
+	summary := &ReloadSummary{Added: []string{}, Removed: []string{}, Updated: []string{}}
+	for id, schema := range loaded.schemas {
+		current, exists := lr.schemas[id]
+		switch {
+		case !exists:
+			summary.Added = append(summary.Added, id)
+		case !sameSchema(current, schema):
+			summary.Updated = append(summary.Updated, id)
+		default:
+			summary.Unchanged++
+		}
+	}
+	for id := range lr.schemas {
+		if _, exists := loaded.schemas[id]; !exists {
+			summary.Removed = append(summary.Removed, id)
+		}
+	}
+	sort.Strings(summary.Added)
+	sort.Strings(summary.Removed)
+	sort.Strings(summary.Updated)
+
+	lr.schemas = loaded.schemas
+	lr.metadata = loaded.metadata
+	return summary, nil
+}
+
+// sameSchema reports whether two schemas differ only in when they were
+// published or signed
+func sameSchema(a, b *Schema) bool {
+	return sameDefinition(a.Definition, b.Definition) && a.Strict == b.Strict &&
+		a.ValidationMode == b.ValidationMode && reflect.DeepEqual(a.Defaults, b.Defaults)
+}
+
+// sameDefinition compares schema definitions ignoring formatting, since
+// definitions are re-indented when saved
+func sameDefinition(a, b json.RawMessage) bool {
+	var compactA, compactB bytes.Buffer
+	if json.Compact(&compactA, a) != nil || json.Compact(&compactB, b) != nil {
+		return bytes.Equal(a, b)
+	}
+	return bytes.Equal(compactA.Bytes(), compactB.Bytes())
 }
 
 // GetStats returns registry statistics
@@ -552,11 +663,17 @@ func (lr *LocalRegistry) loadSchema(schemaID string, metadata *SchemaMetadata) e
 	if err := json.Unmarshal(data, &schemaFile); err != nil {
 		return fmt.Errorf("failed to parse schema file: %w", err)
 	}
+	if schemaFile.Metadata == nil {
+		return fmt.Errorf("schema file has no metadata")
+	}
 
 	schema := &Schema{
-		ID:          schemaFile.Metadata.ID,
-		Definition:  schemaFile.Definition,
-		PublishedAt: schemaFile.Metadata.CreatedAt,
+		ID:             schemaFile.Metadata.ID,
+		Definition:     schemaFile.Definition,
+		PublishedAt:    schemaFile.Metadata.CreatedAt,
+		Strict:         schemaFile.Metadata.Strict,
+		ValidationMode: schemaFile.Metadata.ValidationMode,
+		Defaults:       schemaFile.Metadata.Defaults,
 	}
 
 	lr.schemas[schemaID] = schema
@@ -565,16 +682,22 @@ func (lr *LocalRegistry) loadSchema(schemaID string, metadata *SchemaMetadata) e
 	return nil
 }
 
-// updateIndex updates the index file
+// updateIndex updates the index file from the current schemas; the caller
+// holds the write lock
 func (lr *LocalRegistry) updateIndex() error {
+	return lr.writeIndex(lr.schemas)
+}
+
+// writeIndex writes the index file for schemas
+func (lr *LocalRegistry) writeIndex(schemas map[string]*Schema) error {
 	index := RegistryIndex{
 		Version:   "1.0",
 		UpdatedAt: time.Now().UTC(),
 		Schemas:   make(map[string]*SchemaMetadata),
 	}
 
-	// Build index from current schemas
-	for schemaID, schema := range lr.schemas {
+	// Build index from the given schemas
+	for schemaID, schema := range schemas {
 		metadata := lr.getSchemaMetadataInternal(schema)
 		index.Schemas[schemaID] = metadata
 	}
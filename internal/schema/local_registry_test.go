@@ -19,10 +19,16 @@ package schema
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
+	"reflect"
+	"sync"
 	"testing"
 	"time"
+
+	"github.com/amtp-protocol/agentry/internal/types"
 )
 
 func TestNewLocalRegistry(t *testing.T) {
@@ -168,6 +174,53 @@ func TestLocalRegistry_RegisterSchema(t *testing.T) {
 	}
 }
 
+func TestLocalRegistry_RegisterSchemaConcurrently(t *testing.T) {
+	config := LocalRegistryConfig{
+		BasePath:   t.TempDir(),
+		AutoSave:   true,
+		CreateDirs: true,
+	}
+	registry, err := NewLocalRegistry(config)
+	if err != nil {
+		t.Fatalf("failed to create registry: %v", err)
+	}
+
+	schemaID := SchemaIdentifier{Domain: "commerce", Entity: "order", Version: "v1"}
+	const admins = 8
+	errs := make([]error, admins)
+	start := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < admins; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			<-start
+			errs[i] = registry.RegisterSchema(context.Background(), &Schema{
+				ID:         schemaID,
+				Definition: json.RawMessage(fmt.Sprintf(`{"type": "object", "description": "admin %d"}`, i)),
+			}, nil)
+		}(i)
+	}
+	close(start)
+	wg.Wait()
+
+	succeeded := 0
+	for _, err := range errs {
+		switch {
+		case err == nil:
+			succeeded++
+		case !errors.Is(err, ErrSchemaExists):
+			t.Errorf("expected ErrSchemaExists, got %v", err)
+		}
+	}
+	if succeeded != 1 {
+		t.Errorf("expected exactly one registration to succeed, got %d", succeeded)
+	}
+	if stats := registry.GetStats(); stats.TotalSchemas != 1 {
+		t.Errorf("expected one registered schema, got %d", stats.TotalSchemas)
+	}
+}
+
 func TestLocalRegistry_RegisterOrUpdateSchema(t *testing.T) {
 	// Create temporary directory for testing
 	tempDir, err := os.MkdirTemp("", "local_registry_test")
@@ -898,6 +951,149 @@ func TestLocalRegistry_SaveToDisk(t *testing.T) {
 	}
 }
 
+func TestLocalRegistry_StrictPersisted(t *testing.T) {
+	tempDir := t.TempDir()
+	config := LocalRegistryConfig{
+		BasePath:   tempDir,
+		AutoSave:   true,
+		CreateDirs: true,
+	}
+	registry, err := NewLocalRegistry(config)
+	if err != nil {
+		t.Fatalf("failed to create registry: %v", err)
+	}
+
+	schema := &Schema{
+		ID: SchemaIdentifier{
+			Domain:  "commerce",
+			Entity:  "order",
+			Version: "v1",
+			Raw:     "agntcy:commerce.order.v1",
+		},
+		Definition:  json.RawMessage(`{"type": "object"}`),
+		PublishedAt: time.Now(),
+		Strict:      true,
+	}
+
+	ctx := context.Background()
+	if err := registry.RegisterSchema(ctx, schema, nil); err != nil {
+		t.Fatalf("unexpected error registering schema: %v", err)
+	}
+
+	metadata, err := registry.GetSchemaMetadata(ctx, schema.ID)
+	if err != nil {
+		t.Fatalf("unexpected error getting metadata: %v", err)
+	}
+	if !metadata.Strict {
+		t.Errorf("expected strict flag in schema metadata")
+	}
+
+	// Reload from disk and make sure the flag survives
+	reloaded, err := NewLocalRegistry(config)
+	if err != nil {
+		t.Fatalf("failed to reload registry: %v", err)
+	}
+	loaded, err := reloaded.GetSchema(ctx, schema.ID)
+	if err != nil {
+		t.Fatalf("unexpected error getting schema: %v", err)
+	}
+	if !loaded.Strict {
+		t.Errorf("expected strict flag to be restored from disk")
+	}
+}
+
+func TestLocalRegistry_ReloadFromDisk(t *testing.T) {
+	tempDir := t.TempDir()
+	config := LocalRegistryConfig{
+		BasePath:   tempDir,
+		AutoSave:   true,
+		CreateDirs: true,
+	}
+	registry, err := NewLocalRegistry(config)
+	if err != nil {
+		t.Fatalf("failed to create registry: %v", err)
+	}
+
+	newSchema := func(entity, definition string) *Schema {
+		return &Schema{
+			ID: SchemaIdentifier{
+				Domain:  "commerce",
+				Entity:  entity,
+				Version: "v1",
+				Raw:     "agntcy:commerce." + entity + ".v1",
+			},
+			Definition: json.RawMessage(definition),
+		}
+	}
+
+	ctx := context.Background()
+	for _, entity := range []string{"order", "invoice", "refund"} {
+		if err := registry.RegisterSchema(ctx, newSchema(entity, `{"type": "object"}`), nil); err != nil {
+			t.Fatalf("unexpected error registering schema: %v", err)
+		}
+	}
+
+	// Nothing changed on disk yet
+	summary, err := registry.ReloadFromDisk()
+	if err != nil {
+		t.Fatalf("unexpected error reloading: %v", err)
+	}
+	if len(summary.Added)+len(summary.Removed)+len(summary.Updated) != 0 || summary.Unchanged != 3 {
+		t.Errorf("expected no changes, got %+v", summary)
+	}
+
+	// Change the files out-of-band through a second registry on the same path
+	deploy, err := NewLocalRegistry(config)
+	if err != nil {
+		t.Fatalf("failed to create second registry: %v", err)
+	}
+	if err := deploy.RegisterOrUpdateSchema(ctx, newSchema("invoice", `{"type": "object", "required": ["id"]}`), nil); err != nil {
+		t.Fatalf("unexpected error updating schema: %v", err)
+	}
+	if err := deploy.DeleteSchema(ctx, newSchema("refund", `{}`).ID); err != nil {
+		t.Fatalf("unexpected error deleting schema: %v", err)
+	}
+	if err := deploy.RegisterSchema(ctx, newSchema("shipment", `{"type": "object"}`), nil); err != nil {
+		t.Fatalf("unexpected error registering schema: %v", err)
+	}
+
+	summary, err = registry.ReloadFromDisk()
+	if err != nil {
+		t.Fatalf("unexpected error reloading: %v", err)
+	}
+	expected := ReloadSummary{
+		Added:     []string{"agntcy:commerce.shipment.v1"},
+		Removed:   []string{"agntcy:commerce.refund.v1"},
+		Updated:   []string{"agntcy:commerce.invoice.v1"},
+		Unchanged: 1,
+	}
+	if !reflect.DeepEqual(*summary, expected) {
+		t.Errorf("expected %+v, got %+v", expected, *summary)
+	}
+
+	invoice, err := registry.GetSchema(ctx, newSchema("invoice", `{}`).ID)
+	if err != nil {
+		t.Fatalf("unexpected error getting schema: %v", err)
+	}
+	if !sameDefinition(invoice.Definition, json.RawMessage(`{"type": "object", "required": ["id"]}`)) {
+		t.Errorf("expected updated definition, got %s", invoice.Definition)
+	}
+	if _, err := registry.GetSchema(ctx, newSchema("refund", `{}`).ID); err == nil {
+		t.Error("expected removed schema to be gone")
+	}
+
+	// An unreadable registry leaves the loaded schemas in place
+	if err := os.RemoveAll(tempDir); err != nil {
+		t.Fatalf("failed to remove registry directory: %v", err)
+	}
+	if _, err := registry.ReloadFromDisk(); err == nil {
+		t.Error("expected reload of a missing directory to fail")
+	}
+	if stats := registry.GetStats(); stats.TotalSchemas != 3 {
+		t.Errorf("expected 3 schemas after failed reload, got %d", stats.TotalSchemas)
+	}
+}
+
 func TestLocalRegistry_generateFilePath(t *testing.T) {
 	config := LocalRegistryConfig{}
 	registry, err := NewLocalRegistry(config)
@@ -1084,3 +1280,120 @@ func TestLocalRegistry_AutoSaveDisabled(t *testing.T) {
 		t.Errorf("expected schema to be available in memory")
 	}
 }
+
+func TestLocalRegistry_ConcurrentAccess(t *testing.T) {
+	registry, err := NewLocalRegistry(LocalRegistryConfig{
+		BasePath:   t.TempDir(),
+		AutoSave:   true,
+		CreateDirs: true,
+	})
+	if err != nil {
+		t.Fatalf("failed to create registry: %v", err)
+	}
+
+	ctx := context.Background()
+	schemaID := func(i int) SchemaIdentifier {
+		version := fmt.Sprintf("v%d", i%5)
+		return SchemaIdentifier{Domain: "commerce", Entity: "order", Version: version, Raw: "agntcy:commerce.order." + version}
+	}
+
+	var wg sync.WaitGroup
+	for worker := 0; worker < 8; worker++ {
+		wg.Add(1)
+		go func(worker int) {
+			defer wg.Done()
+			for i := 0; i < 50; i++ {
+				id := schemaID(worker + i)
+				switch i % 5 {
+				case 0:
+					_ = registry.RegisterSchema(ctx, &Schema{ID: id, Definition: json.RawMessage(`{"type": "object"}`)}, nil)
+				case 1:
+					_ = registry.RegisterOrUpdateSchema(ctx, &Schema{ID: id, Definition: json.RawMessage(`{"type": "string"}`)}, nil)
+				case 2:
+					if schema, err := registry.GetSchema(ctx, id); err == nil {
+						// Returned schemas are private copies
+						schema.Definition[0] = ' '
+					}
+				case 3:
+					_ = registry.DeleteSchema(ctx, id)
+				case 4:
+					if err := registry.SaveToDisk(); err != nil {
+						t.Errorf("unexpected error saving to disk: %v", err)
+					}
+					_, _ = registry.ListSchemas(ctx, "")
+					_ = registry.GetStats()
+				}
+			}
+		}(worker)
+	}
+	wg.Wait()
+
+	// Whatever survived is intact and matches what is on disk
+	schemas, err := registry.ListSchemas(ctx, "")
+	if err != nil {
+		t.Fatalf("unexpected error listing schemas: %v", err)
+	}
+	for _, id := range schemas {
+		schema, err := registry.GetSchema(ctx, id)
+		if err != nil {
+			t.Fatalf("unexpected error getting schema %s: %v", id, err)
+		}
+		if schema.Definition[0] != '{' {
+			t.Errorf("expected schema %s to be unaffected by changes to copies, got %s", id, schema.Definition)
+		}
+	}
+	summary, err := registry.ReloadFromDisk()
+	if err != nil {
+		t.Fatalf("unexpected error reloading: %v", err)
+	}
+	if len(summary.Added) != 0 || len(summary.Removed) != 0 || summary.Unchanged != len(schemas) {
+		t.Errorf("expected the files to match the registry, got %+v", summary)
+	}
+}
+
+func TestLocalRegistry_DefaultsPersisted(t *testing.T) {
+	tempDir := t.TempDir()
+	config := LocalRegistryConfig{
+		BasePath:   tempDir,
+		AutoSave:   true,
+		CreateDirs: true,
+	}
+	registry, err := NewLocalRegistry(config)
+	if err != nil {
+		t.Fatalf("failed to create registry: %v", err)
+	}
+
+	schema := &Schema{
+		ID: SchemaIdentifier{
+			Domain:  "commerce",
+			Entity:  "order",
+			Version: "v1",
+			Raw:     "agntcy:commerce.order.v1",
+		},
+		Definition:  json.RawMessage(`{"type": "object"}`),
+		PublishedAt: time.Now(),
+		Defaults: &MessageDefaults{
+			ResponseType: "required",
+			Coordination: &types.CoordinationConfig{Type: "parallel", Timeout: 30},
+		},
+	}
+
+	ctx := context.Background()
+	if err := registry.RegisterSchema(ctx, schema, nil); err != nil {
+		t.Fatalf("unexpected error registering schema: %v", err)
+	}
+
+	// Reload from disk and make sure the defaults survive
+	reloaded, err := NewLocalRegistry(config)
+	if err != nil {
+		t.Fatalf("failed to reload registry: %v", err)
+	}
+	loaded, err := reloaded.GetSchema(ctx, schema.ID)
+	if err != nil {
+		t.Fatalf("unexpected error getting schema: %v", err)
+	}
+	if loaded.Defaults == nil || loaded.Defaults.ResponseType != "required" ||
+		loaded.Defaults.Coordination == nil || loaded.Defaults.Coordination.Timeout != 30 {
+		t.Errorf("expected defaults to be restored from disk, got %+v", loaded.Defaults)
+	}
+}
@@ -19,6 +19,7 @@ package schema
 import (
 	"context"
 	"fmt"
+	"sort"
 	"time"
 
 	"github.com/amtp-protocol/agentry/internal/types"
@@ -28,6 +29,7 @@ import (
 type Manager struct {
 	registryClient       RegistryClient
 	validator            Validator
+	formats              *FormatRegistry
 	cache                Cache
 	negotiationEngine    *NegotiationEngine
 	compatibilityChecker *CompatibilityChecker
@@ -46,7 +48,9 @@ type ManagerConfig struct {
 	Compatibility  CompatibilityConfig `yaml:"compatibility" json:"compatibility"`
 	Pipeline       PipelineConfig      `yaml:"pipeline" json:"pipeline"`
 	ErrorReporting ErrorReportConfig   `yaml:"error_reporting" json:"error_reporting"`
-	RegistryType   string              `yaml:"registry_type" json:"registry_type"` // "local", "http", or "database"
+	RegistryType   string              `yaml:"registry_type" json:"registry_type"` // "local", "database", "http" (read-only) or "remote"
+	// SeedDir holds schema files registered at startup, see SeedFromDir
+	SeedDir string `yaml:"seed_dir" json:"seed_dir"`
 }
 
 // NewManager creates a new schema manager with all components
@@ -74,6 +78,11 @@ func NewManager(config ManagerConfig, schemaStore ...SchemaStore) (*Manager, err
 		registryClient = NewDatabaseRegistry(schemaStore[0])
 	case "http":
 		registryClient = NewHTTPRegistryClient(config.Registry)
+	case "remote":
+		registryClient, err = NewRemoteRegistry(config.Registry)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create remote registry: %w", err)
+		}
 	default:
 		return nil, fmt.Errorf("unknown registry type: %s", registryType)
 	}
@@ -112,9 +121,16 @@ func NewManager(config ManagerConfig, schemaStore ...SchemaStore) (*Manager, err
 		pipeline:             pipeline,
 		errorReporter:        errorReporter,
 		config:               config,
+		formats:              validator.Formats(),
 	}, nil
 }
 
+// RegisterFormat adds a checker for a custom value of the JSON Schema
+// "format" keyword, or replaces a built-in one
+func (m *Manager) RegisterFormat(name string, checker FormatChecker) {
+	m.formats.Register(name, checker)
+}
+
 // GetRegistry returns the underlying registry client
 func (m *Manager) GetRegistry() RegistryClient {
 	return m.registryClient
@@ -158,7 +174,7 @@ func (m *Manager) ValidateMessage(ctx context.Context, message *types.Message) (
 		return report, nil
 	}
 
-	schemaID, err := ParseSchemaIdentifier(message.Schema)
+	schemaID, err := ParseSchemaReference(message.Schema)
 	if err != nil {
 		report.Valid = false
 		report.Errors = append(report.Errors, ValidationError{
@@ -170,6 +186,22 @@ func (m *Manager) ValidateMessage(ctx context.Context, message *types.Message) (
 		return report, nil
 	}
 
+	// Validate versionless references against the latest registered version
+	if schemaID.IsLatest() {
+		schemaID, err = m.ResolveLatest(ctx, schemaID.Domain, schemaID.Entity)
+		if err != nil {
+			report.Valid = false
+			report.Errors = append(report.Errors, ValidationError{
+				Field:   "schema",
+				Message: fmt.Sprintf("schema resolution failed: %s", err.Error()),
+				Code:    "SCHEMA_RESOLUTION_FAILED",
+				Value:   message.Schema,
+			})
+			return report, nil
+		}
+		report.ResolvedSchema = schemaID.String()
+	}
+
 	// Perform schema negotiation if needed
 	negotiatedSchema, err := m.negotiationEngine.NegotiateSchema(ctx, *schemaID)
 	if err != nil {
@@ -217,11 +249,129 @@ func (m *Manager) ValidateMessage(ctx context.Context, message *types.Message) (
 	return report, nil
 }
 
+// ResolveSchema parses a schema identifier, resolving a versionless or
+// @latest reference to the latest registered version
+func (m *Manager) ResolveSchema(ctx context.Context, schemaStr string) (*SchemaIdentifier, error) {
+	id, err := ParseSchemaReference(schemaStr)
+	if err != nil {
+		return nil, err
+	}
+	if !id.IsLatest() {
+		return id, nil
+	}
+	return m.ResolveLatest(ctx, id.Domain, id.Entity)
+}
+
+// ResolveLatest returns the identifier of the highest registered vN of
+// domain.entity
+func (m *Manager) ResolveLatest(ctx context.Context, domain, entity string) (*SchemaIdentifier, error) {
+	latest, _, err := m.latestVersion(ctx, domain, entity)
+	if err != nil {
+		return nil, err
+	}
+	if latest == nil {
+		return nil, fmt.Errorf("%w: no versions of agntcy:%s.%s are registered", ErrSchemaNotFound, domain, entity)
+	}
+	return latest, nil
+}
+
+// NextVersion returns the identifier for registering a new version of
+// domain.entity: one above the latest registered version, or v1
+func (m *Manager) NextVersion(ctx context.Context, domain, entity string) (*SchemaIdentifier, error) {
+	_, n, err := m.latestVersion(ctx, domain, entity)
+	if err != nil {
+		return nil, err
+	}
+	return &SchemaIdentifier{
+		Domain:  domain,
+		Entity:  entity,
+		Version: fmt.Sprintf("v%d", n+1),
+	}, nil
+}
+
+// latestVersion finds the highest registered version of domain.entity and
+// its number, or nil and 0 if none is registered
+func (m *Manager) latestVersion(ctx context.Context, domain, entity string) (*SchemaIdentifier, int, error) {
+	// Registries filter by domain; the entity is matched here
+	ids, err := m.registryClient.ListSchemas(ctx, domain)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list schemas: %w", err)
+	}
+
+	var latest *SchemaIdentifier
+	highest := 0
+	for _, id := range ids {
+		if id.Domain != domain || id.Entity != entity {
+			continue
+		}
+		if n, ok := id.VersionNumber(); ok && n > highest {
+			highest = n
+			latest = &SchemaIdentifier{Domain: id.Domain, Entity: id.Entity, Version: id.Version}
+		}
+	}
+	return latest, highest, nil
+}
+
+// DetectSchemas returns the registered schemas payload validates against,
+// trying only the latest version of each entity. It validates the payload
+// once per entity, so its cost grows with the size of the registry.
+func (m *Manager) DetectSchemas(ctx context.Context, payload []byte) ([]SchemaIdentifier, error) {
+	ids, err := m.registryClient.ListSchemas(ctx, "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list schemas: %w", err)
+	}
+
+	latest := make(map[string]SchemaIdentifier)
+	highest := make(map[string]int)
+	for _, id := range ids {
+		entity := id.Domain + "." + id.Entity
+		if n, ok := id.VersionNumber(); ok && n > highest[entity] {
+			highest[entity] = n
+			latest[entity] = SchemaIdentifier{Domain: id.Domain, Entity: id.Entity, Version: id.Version}
+		}
+	}
+
+	var matches []SchemaIdentifier
+	for _, id := range latest {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		// A schema that cannot be loaded or evaluated simply does not match
+		result, err := m.validator.ValidatePayload(ctx, payload, id)
+		if err == nil && result.Valid {
+			matches = append(matches, id)
+		}
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		return matches[i].String() < matches[j].String()
+	})
+	return matches, nil
+}
+
 // GetSchema retrieves a schema by identifier
 func (m *Manager) GetSchema(ctx context.Context, id SchemaIdentifier) (*Schema, error) {
 	return m.registryClient.GetSchema(ctx, id)
 }
 
+// ApplyDefaults fills in the settings message leaves unset from the defaults
+// of the schema it uses. A message without a schema, or naming one that
+// cannot be resolved, is left unchanged for validation to report. So is a
+// signed message, since the defaults would change what its signature covers.
+func (m *Manager) ApplyDefaults(ctx context.Context, message *types.Message) {
+	if message.Schema == "" || message.Signature != nil {
+		return
+	}
+	id, err := m.ResolveSchema(ctx, message.Schema)
+	if err != nil {
+		return
+	}
+	schema, err := m.GetSchema(ctx, *id)
+	if err != nil {
+		return
+	}
+	schema.Defaults.Apply(message)
+}
+
 // ListSchemas lists available schemas
 func (m *Manager) ListSchemas(ctx context.Context, pattern string) ([]SchemaIdentifier, error) {
 	return m.registryClient.ListSchemas(ctx, pattern)
@@ -257,8 +407,37 @@ func (m *Manager) ValidatePayload(ctx context.Context, payload []byte, schemaID
 	return m.validator.ValidatePayload(ctx, payload, schemaID)
 }
 
-// ClearCache clears the schema cache
+// ReloadSchemas reconciles a file-based registry with its files on disk and
+// clears the schema cache so no stale definition is served
+func (m *Manager) ReloadSchemas(ctx context.Context) (*ReloadSummary, error) {
+	client := m.registryClient
+	if cachedClient, ok := client.(*CachedRegistryClient); ok {
+		client = cachedClient.client
+	}
+
+	reloader, ok := client.(Reloader)
+	if !ok {
+		return nil, ErrReloadNotSupported
+	}
+
+	summary, err := reloader.ReloadFromDisk()
+	if err != nil {
+		return nil, err
+	}
+	if err := m.cache.Clear(ctx); err != nil {
+		return nil, fmt.Errorf("failed to clear schema cache: %w", err)
+	}
+	return summary, nil
+}
+
+// ClearCache clears the schema cache, including the listings cached by a
+// remote registry
 func (m *Manager) ClearCache(ctx context.Context) error {
+	if cachedClient, ok := m.registryClient.(*CachedRegistryClient); ok {
+		if remote, ok := cachedClient.client.(*RemoteRegistry); ok {
+			remote.InvalidateListCache()
+		}
+	}
 	return m.cache.Clear(ctx)
 }
 
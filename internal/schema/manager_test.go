@@ -19,7 +19,9 @@ package schema
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"os"
+	"strings"
 	"testing"
 	"time"
 
@@ -364,6 +366,222 @@ func TestManager_ValidateMessage_Success(t *testing.T) {
 	}
 }
 
+func TestManager_ResolveLatest(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "manager_latest_test")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	manager, err := NewManager(ManagerConfig{
+		RegistryType: "local",
+		LocalRegistry: LocalRegistryConfig{
+			BasePath:   tempDir,
+			CreateDirs: true,
+		},
+		Cache:      CacheConfig{Type: "memory"},
+		Validation: ValidatorConfig{Enabled: true},
+		Pipeline:   PipelineConfig{Enabled: true},
+	})
+	if err != nil {
+		t.Fatalf("failed to create manager: %v", err)
+	}
+	defer manager.Shutdown(context.Background())
+
+	ctx := context.Background()
+	definitions := map[string]string{
+		"agntcy:commerce.order.v1":    `{"type": "object"}`,
+		"agntcy:commerce.order.v2":    `{"type": "object"}`,
+		"agntcy:commerce.order.v10":   `{"type": "object", "required": ["order_id"]}`,
+		"agntcy:commerce.invoice.v99": `{"type": "object"}`,
+	}
+	for id, def := range definitions {
+		schemaID, err := ParseSchemaIdentifier(id)
+		if err != nil {
+			t.Fatalf("failed to parse %s: %v", id, err)
+		}
+		if err := manager.RegisterSchema(ctx, &Schema{ID: *schemaID, Definition: json.RawMessage(def), PublishedAt: time.Now()}, nil); err != nil {
+			t.Fatalf("failed to register %s: %v", id, err)
+		}
+	}
+
+	// Versions compare numerically, so v10 beats v2
+	latest, err := manager.ResolveLatest(ctx, "commerce", "order")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if latest.String() != "agntcy:commerce.order.v10" {
+		t.Errorf("expected agntcy:commerce.order.v10, got %s", latest.String())
+	}
+
+	for _, ref := range []string{"agntcy:commerce.order", "agntcy:commerce.order@latest", "agntcy:commerce.order.v2"} {
+		resolved, err := manager.ResolveSchema(ctx, ref)
+		if err != nil {
+			t.Errorf("ResolveSchema(%s) unexpected error: %v", ref, err)
+			continue
+		}
+		want := "agntcy:commerce.order.v10"
+		if ref == "agntcy:commerce.order.v2" {
+			want = ref
+		}
+		if resolved.String() != want {
+			t.Errorf("ResolveSchema(%s) = %s, want %s", ref, resolved.String(), want)
+		}
+	}
+
+	if _, err := manager.ResolveLatest(ctx, "commerce", "refund"); !errors.Is(err, ErrSchemaNotFound) {
+		t.Errorf("expected ErrSchemaNotFound for unregistered entity, got %v", err)
+	}
+
+	next, err := manager.NextVersion(ctx, "commerce", "order")
+	if err != nil || next.String() != "agntcy:commerce.order.v11" {
+		t.Errorf("expected next version agntcy:commerce.order.v11, got %v (err %v)", next, err)
+	}
+	next, err = manager.NextVersion(ctx, "commerce", "refund")
+	if err != nil || next.String() != "agntcy:commerce.refund.v1" {
+		t.Errorf("expected next version agntcy:commerce.refund.v1, got %v (err %v)", next, err)
+	}
+
+	// Validation runs against the resolved version
+	report, err := manager.ValidateMessage(ctx, &types.Message{
+		MessageID: "test-message-id",
+		Schema:    "agntcy:commerce.order",
+		Payload:   json.RawMessage(`{"amount": 10}`),
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if report.ResolvedSchema != "agntcy:commerce.order.v10" {
+		t.Errorf("expected resolved schema agntcy:commerce.order.v10, got %q", report.ResolvedSchema)
+	}
+	if report.Valid {
+		t.Error("expected validation against v10 to fail on missing order_id")
+	}
+
+	report, err = manager.ValidateMessage(ctx, &types.Message{
+		MessageID: "test-message-id",
+		Schema:    "agntcy:commerce.refund@latest",
+		Payload:   json.RawMessage(`{}`),
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if report.Valid || len(report.Errors) == 0 || report.Errors[0].Code != "SCHEMA_RESOLUTION_FAILED" {
+		t.Errorf("expected SCHEMA_RESOLUTION_FAILED, got %+v", report.Errors)
+	}
+}
+
+func TestManager_RegisterFormat(t *testing.T) {
+	manager, err := NewManager(ManagerConfig{
+		RegistryType: "local",
+		LocalRegistry: LocalRegistryConfig{
+			BasePath:   t.TempDir(),
+			CreateDirs: true,
+		},
+		Cache:      CacheConfig{Type: "memory"},
+		Validation: ValidatorConfig{Enabled: true},
+		Pipeline:   PipelineConfig{Enabled: true},
+	})
+	if err != nil {
+		t.Fatalf("failed to create manager: %v", err)
+	}
+	defer manager.Shutdown(context.Background())
+
+	ctx := context.Background()
+	schemaID, _ := ParseSchemaIdentifier("agntcy:commerce.order.v1")
+	definition := `{"type": "object", "properties": {"assignee": {"type": "string", "format": "agntcy-address"}, "sku": {"type": "string", "format": "sku"}}}`
+	if err := manager.RegisterSchema(ctx, &Schema{ID: *schemaID, Definition: json.RawMessage(definition), PublishedAt: time.Now()}, nil); err != nil {
+		t.Fatalf("failed to register schema: %v", err)
+	}
+	manager.RegisterFormat("sku", func(value string) bool { return strings.HasPrefix(value, "SKU-") })
+
+	validate := func(payload string) *ValidationReport {
+		t.Helper()
+		report, err := manager.ValidateMessage(ctx, &types.Message{
+			MessageID: "test-message-id",
+			Sender:    "user@example.com",
+			Schema:    "agntcy:commerce.order.v1",
+			Payload:   json.RawMessage(payload),
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return report
+	}
+
+	if report := validate(`{"assignee": "sales-bot@example.com", "sku": "SKU-1"}`); !report.Valid {
+		t.Errorf("expected valid formats to pass, got %+v", report.Errors)
+	}
+	report := validate(`{"assignee": "sales-bot", "sku": "1"}`)
+	if report.Valid || len(report.Errors) != 2 {
+		t.Fatalf("expected both fields to fail their formats, got %+v", report.Errors)
+	}
+	for _, e := range report.Errors {
+		if e.Code != "INVALID_FORMAT" {
+			t.Errorf("expected INVALID_FORMAT, got %s on %s", e.Code, e.Field)
+		}
+	}
+}
+
+func TestManager_DetectSchemas(t *testing.T) {
+	manager, err := NewManager(ManagerConfig{
+		RegistryType: "local",
+		LocalRegistry: LocalRegistryConfig{
+			BasePath:   t.TempDir(),
+			CreateDirs: true,
+		},
+		Cache:      CacheConfig{Type: "memory"},
+		Validation: ValidatorConfig{Enabled: true},
+	})
+	if err != nil {
+		t.Fatalf("failed to create manager: %v", err)
+	}
+	defer manager.Shutdown(context.Background())
+
+	ctx := context.Background()
+	definitions := map[string]string{
+		"agntcy:commerce.order.v1":   `{"type": "object", "required": ["order_id"]}`,
+		"agntcy:commerce.order.v2":   `{"type": "object", "required": ["order_id", "amount"]}`,
+		"agntcy:commerce.invoice.v1": `{"type": "object", "required": ["invoice_id"]}`,
+		"agntcy:billing.invoice.v3":  `{"type": "object", "required": ["invoice_id"]}`,
+	}
+	for id, def := range definitions {
+		schemaID, err := ParseSchemaIdentifier(id)
+		if err != nil {
+			t.Fatalf("failed to parse %s: %v", id, err)
+		}
+		if err := manager.RegisterSchema(ctx, &Schema{ID: *schemaID, Definition: json.RawMessage(def), PublishedAt: time.Now()}, nil); err != nil {
+			t.Fatalf("failed to register %s: %v", id, err)
+		}
+	}
+
+	tests := []struct {
+		payload string
+		want    []string
+	}{
+		// Only the latest version of an entity is tried
+		{`{"order_id": "o-1"}`, nil},
+		{`{"order_id": "o-1", "amount": 10}`, []string{"agntcy:commerce.order.v2"}},
+		{`{"invoice_id": "i-1"}`, []string{"agntcy:billing.invoice.v3", "agntcy:commerce.invoice.v1"}},
+		{`{"unrelated": true}`, nil},
+		{`not json`, nil},
+	}
+
+	for _, tt := range tests {
+		matches, err := manager.DetectSchemas(ctx, []byte(tt.payload))
+		if err != nil {
+			t.Fatalf("DetectSchemas(%s) unexpected error: %v", tt.payload, err)
+		}
+		got := make([]string, len(matches))
+		for i, match := range matches {
+			got[i] = match.String()
+		}
+		if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+			t.Errorf("DetectSchemas(%s) = %v, want %v", tt.payload, got, tt.want)
+		}
+	}
+}
+
 func TestManager_ValidateMessage_WithNegotiation(t *testing.T) {
 	// Create temporary directory for local registry
 	tempDir, err := os.MkdirTemp("", "manager_test")
@@ -518,6 +736,77 @@ func TestManager_GetSchema(t *testing.T) {
 	}
 }
 
+func TestManager_ReloadSchemas(t *testing.T) {
+	tempDir := t.TempDir()
+	config := ManagerConfig{
+		RegistryType: "local",
+		LocalRegistry: LocalRegistryConfig{
+			BasePath:   tempDir,
+			AutoSave:   true,
+			CreateDirs: true,
+		},
+		Cache: CacheConfig{
+			Type:       "memory",
+			DefaultTTL: time.Hour,
+		},
+	}
+
+	manager, err := NewManager(config)
+	if err != nil {
+		t.Fatalf("failed to create manager: %v", err)
+	}
+	defer manager.Shutdown(context.Background())
+
+	schemaID := SchemaIdentifier{Domain: "commerce", Entity: "order", Version: "v1", Raw: "agntcy:commerce.order.v1"}
+	ctx := context.Background()
+	if err := manager.RegisterSchema(ctx, &Schema{ID: schemaID, Definition: json.RawMessage(`{"type": "object"}`)}, nil); err != nil {
+		t.Fatalf("unexpected error registering schema: %v", err)
+	}
+	// Populate the cache
+	if _, err := manager.GetSchema(ctx, schemaID); err != nil {
+		t.Fatalf("unexpected error getting schema: %v", err)
+	}
+
+	deploy, err := NewLocalRegistry(config.LocalRegistry)
+	if err != nil {
+		t.Fatalf("failed to create second registry: %v", err)
+	}
+	if err := deploy.RegisterOrUpdateSchema(ctx, &Schema{ID: schemaID, Definition: json.RawMessage(`{"type": "string"}`)}, nil); err != nil {
+		t.Fatalf("unexpected error updating schema: %v", err)
+	}
+
+	summary, err := manager.ReloadSchemas(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error reloading: %v", err)
+	}
+	if len(summary.Updated) != 1 || summary.Updated[0] != schemaID.String() {
+		t.Errorf("expected %s to be updated, got %+v", schemaID.String(), summary)
+	}
+
+	retrieved, err := manager.GetSchema(ctx, schemaID)
+	if err != nil {
+		t.Fatalf("unexpected error getting schema: %v", err)
+	}
+	if !sameDefinition(retrieved.Definition, json.RawMessage(`{"type": "string"}`)) {
+		t.Errorf("expected reloaded definition instead of the cached one, got %s", retrieved.Definition)
+	}
+}
+
+func TestManager_ReloadSchemas_NotSupported(t *testing.T) {
+	manager, err := NewManager(ManagerConfig{
+		RegistryType: "http",
+		Cache:        CacheConfig{Type: "memory"},
+	})
+	if err != nil {
+		t.Fatalf("failed to create manager: %v", err)
+	}
+	defer manager.Shutdown(context.Background())
+
+	if _, err := manager.ReloadSchemas(context.Background()); !errors.Is(err, ErrReloadNotSupported) {
+		t.Errorf("expected ErrReloadNotSupported, got %v", err)
+	}
+}
+
 func TestManager_ListSchemas(t *testing.T) {
 	// Create temporary directory for local registry
 	tempDir, err := os.MkdirTemp("", "manager_test")
@@ -36,6 +36,9 @@ type RegistryConfig struct {
 	AuthToken  string            `yaml:"auth_token" json:"auth_token"`
 	TLSConfig  TLSConfig         `yaml:"tls" json:"tls"`
 	RetryCount int               `yaml:"retry_count" json:"retry_count"`
+
+	// ListCacheTTL is how long the remote registry reuses a schema listing
+	ListCacheTTL time.Duration `yaml:"list_cache_ttl" json:"list_cache_ttl"`
 }
 
 // TLSConfig holds TLS configuration for registry client
@@ -82,7 +85,7 @@ func (c *HTTPRegistryClient) GetSchema(ctx context.Context, id SchemaIdentifier)
 	defer resp.Body.Close()
 
 	if resp.StatusCode == http.StatusNotFound {
-		return nil, fmt.Errorf("schema not found: %s", id.String())
+		return nil, fmt.Errorf("%w: %s", ErrSchemaNotFound, id.String())
 	}
 
 	if resp.StatusCode != http.StatusOK {
@@ -264,7 +267,7 @@ func (m *MockRegistryClient) GetSchema(ctx context.Context, id SchemaIdentifier)
 
 	schema, exists := m.schemas[id.String()]
 	if !exists {
-		return nil, fmt.Errorf("schema not found: %s", id.String())
+		return nil, fmt.Errorf("%w: %s", ErrSchemaNotFound, id.String())
 	}
 
 	// Return a copy to prevent modification
@@ -301,7 +304,7 @@ func (m *MockRegistryClient) CheckCompatibility(ctx context.Context, current, ne
 func (m *MockRegistryClient) RegisterSchema(ctx context.Context, schema *Schema, metadata *SchemaMetadata) error {
 	// Check if schema already exists
 	if _, exists := m.schemas[schema.ID.String()]; exists {
-		return fmt.Errorf("schema already exists: %s", schema.ID.String())
+		return fmt.Errorf("%w: %s", ErrSchemaExists, schema.ID.String())
 	}
 
 	// Add to mock registry
@@ -319,7 +322,7 @@ func (m *MockRegistryClient) RegisterOrUpdateSchema(ctx context.Context, schema
 // DeleteSchema deletes a schema from the mock registry
 func (m *MockRegistryClient) DeleteSchema(ctx context.Context, id SchemaIdentifier) error {
 	if _, exists := m.schemas[id.String()]; !exists {
-		return fmt.Errorf("schema not found: %s", id.String())
+		return fmt.Errorf("%w: %s", ErrSchemaNotFound, id.String())
 	}
 
 	// Remove from map
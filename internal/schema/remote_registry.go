@@ -0,0 +1,183 @@
+/*
+ * Copyright 2026 Cong Wang
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package schema
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// defaultListCacheTTL is how long a remote schema listing is reused when the
+// registry configuration does not set one
+const defaultListCacheTTL = time.Minute
+
+// listCacheEntry is a cached result of listing schemas for one pattern
+type listCacheEntry struct {
+	ids     []SchemaIdentifier
+	expires time.Time
+}
+
+// RemoteRegistry is a read-write client for a central schema registry shared
+// by several gateways. Reads and validation go through HTTPRegistryClient and
+// writes are proxied to the remote. Listings are cached per pattern until the
+// TTL expires or this gateway writes to the registry; schema definitions are
+// cached by the manager's CachedRegistryClient like any other backend.
+type RemoteRegistry struct {
+	*HTTPRegistryClient
+	listTTL time.Duration
+
+	mu        sync.Mutex
+	listCache map[string]listCacheEntry
+}
+
+// NewRemoteRegistry creates a client for the registry at config.BaseURL
+func NewRemoteRegistry(config RegistryConfig) (*RemoteRegistry, error) {
+	if config.BaseURL == "" {
+		return nil, fmt.Errorf("remote registry requires a base URL")
+	}
+
+	listTTL := config.ListCacheTTL
+	if listTTL == 0 {
+		listTTL = defaultListCacheTTL
+	}
+
+	return &RemoteRegistry{
+		HTTPRegistryClient: NewHTTPRegistryClient(config),
+		listTTL:            listTTL,
+		listCache:          make(map[string]listCacheEntry),
+	}, nil
+}
+
+// ListSchemas lists schemas matching pattern, from the cache when possible
+func (r *RemoteRegistry) ListSchemas(ctx context.Context, pattern string) ([]SchemaIdentifier, error) {
+	r.mu.Lock()
+	entry, ok := r.listCache[pattern]
+	r.mu.Unlock()
+	if ok && time.Now().Before(entry.expires) {
+		return append([]SchemaIdentifier(nil), entry.ids...), nil
+	}
+
+	ids, err := r.HTTPRegistryClient.ListSchemas(ctx, pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	if r.listTTL > 0 {
+		r.mu.Lock()
+		r.listCache[pattern] = listCacheEntry{
+			ids:     append([]SchemaIdentifier(nil), ids...),
+			expires: time.Now().Add(r.listTTL),
+		}
+		r.mu.Unlock()
+	}
+	return ids, nil
+}
+
+// RegisterSchema registers a new schema with the remote registry
+func (r *RemoteRegistry) RegisterSchema(ctx context.Context, schema *Schema, metadata *SchemaMetadata) error {
+	return r.write(ctx, "POST", "/schemas", schema, metadata)
+}
+
+// RegisterOrUpdateSchema registers a schema with the remote registry,
+// replacing any existing definition
+func (r *RemoteRegistry) RegisterOrUpdateSchema(ctx context.Context, schema *Schema, metadata *SchemaMetadata) error {
+	return r.write(ctx, "PUT", "/schemas/"+url.PathEscape(schema.ID.String()), schema, metadata)
+}
+
+// DeleteSchema deletes a schema from the remote registry
+func (r *RemoteRegistry) DeleteSchema(ctx context.Context, id SchemaIdentifier) error {
+	resp, err := r.makeRequest(ctx, "DELETE", "/schemas/"+url.PathEscape(id.String()), nil)
+	if err != nil {
+		return fmt.Errorf("failed to delete schema: %w", err)
+	}
+	defer resp.Body.Close()
+	r.InvalidateListCache()
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return fmt.Errorf("%w: %s", ErrSchemaNotFound, id.String())
+	case resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent:
+		return fmt.Errorf("registry returned status %d deleting schema %s", resp.StatusCode, id.String())
+	}
+	return nil
+}
+
+// GetStats fetches statistics from the remote registry. The interface leaves
+// no room for an error, so a failed request reports an empty registry.
+func (r *RemoteRegistry) GetStats() RegistryStats {
+	ctx, cancel := context.WithTimeout(context.Background(), r.httpClient.Timeout)
+	defer cancel()
+
+	resp, err := r.makeRequest(ctx, "GET", "/schemas/stats", nil)
+	if err != nil {
+		return RegistryStats{}
+	}
+	defer resp.Body.Close()
+
+	var stats RegistryStats
+	if resp.StatusCode != http.StatusOK || json.NewDecoder(resp.Body).Decode(&stats) != nil {
+		return RegistryStats{}
+	}
+	return stats
+}
+
+// InvalidateListCache drops every cached listing
+func (r *RemoteRegistry) InvalidateListCache() {
+	r.mu.Lock()
+	r.listCache = make(map[string]listCacheEntry)
+	r.mu.Unlock()
+}
+
+// write sends a schema to the remote registry. Cached listings are dropped
+// whatever the outcome, since a failed response does not prove nothing changed.
+func (r *RemoteRegistry) write(ctx context.Context, method, path string, schema *Schema, metadata *SchemaMetadata) error {
+	body, err := json.Marshal(struct {
+		Schema   *Schema         `json:"schema"`
+		Metadata *SchemaMetadata `json:"metadata,omitempty"`
+	}{schema, metadata})
+	if err != nil {
+		return fmt.Errorf("failed to marshal schema: %w", err)
+	}
+
+	resp, err := r.makeRequest(ctx, method, path, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to register schema: %w", err)
+	}
+	defer resp.Body.Close()
+	r.InvalidateListCache()
+
+	switch {
+	case resp.StatusCode == http.StatusConflict:
+		return fmt.Errorf("%w: %s", ErrSchemaExists, schema.ID.String())
+	case resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusNoContent:
+		var errorResp struct {
+			Error string `json:"error"`
+		}
+		json.NewDecoder(resp.Body).Decode(&errorResp) // #nosec G104 -- ignore decode error
+		if errorResp.Error != "" {
+			return fmt.Errorf("registry rejected schema %s: %s", schema.ID.String(), errorResp.Error)
+		}
+		return fmt.Errorf("registry returned status %d registering schema %s", resp.StatusCode, schema.ID.String())
+	}
+	return nil
+}
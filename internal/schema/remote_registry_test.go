@@ -0,0 +1,239 @@
+/*
+ * Copyright 2026 Cong Wang
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package schema
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// mockRemoteRegistry is a minimal central registry that counts list requests
+type mockRemoteRegistry struct {
+	mu      sync.Mutex
+	schemas map[string]*Schema
+	lists   int
+}
+
+func newMockRemoteRegistry(t *testing.T) (*mockRemoteRegistry, *httptest.Server) {
+	t.Helper()
+	m := &mockRemoteRegistry{schemas: make(map[string]*Schema)}
+	srv := httptest.NewServer(http.HandlerFunc(m.serveHTTP))
+	t.Cleanup(srv.Close)
+	return m, srv
+}
+
+func (m *mockRemoteRegistry) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	id, _ := url.PathUnescape(strings.TrimPrefix(r.URL.EscapedPath(), "/schemas/"))
+	switch {
+	case r.Method == "GET" && r.URL.Path == "/schemas":
+		m.lists++
+		var ids []SchemaIdentifier
+		for _, s := range m.schemas {
+			ids = append(ids, s.ID)
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"schemas": ids})
+	case r.Method == "GET" && r.URL.Path == "/schemas/stats":
+		json.NewEncoder(w).Encode(RegistryStats{TotalSchemas: len(m.schemas)})
+	case r.Method == "GET":
+		s, ok := m.schemas[id]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		json.NewEncoder(w).Encode(s)
+	case r.Method == "POST" || r.Method == "PUT":
+		var req struct {
+			Schema *Schema `json:"schema"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Schema == nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "invalid schema"})
+			return
+		}
+		key := req.Schema.ID.String()
+		if _, exists := m.schemas[key]; exists && r.Method == "POST" {
+			w.WriteHeader(http.StatusConflict)
+			return
+		}
+		m.schemas[key] = req.Schema
+		w.WriteHeader(http.StatusCreated)
+	case r.Method == "DELETE":
+		if _, ok := m.schemas[id]; !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		delete(m.schemas, id)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func (m *mockRemoteRegistry) listRequests() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.lists
+}
+
+func (m *mockRemoteRegistry) set(s *Schema) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.schemas[s.ID.String()] = s
+}
+
+func (m *mockRemoteRegistry) remove(id SchemaIdentifier) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.schemas, id.String())
+}
+
+func testRemoteSchema(raw string) *Schema {
+	id, _ := ParseSchemaIdentifier(raw)
+	return &Schema{ID: *id, Definition: json.RawMessage(`{"type":"object"}`)}
+}
+
+func TestNewRemoteRegistry_RequiresBaseURL(t *testing.T) {
+	if _, err := NewRemoteRegistry(RegistryConfig{}); err == nil {
+		t.Error("Expected an error without a base URL")
+	}
+	if _, err := NewManager(ManagerConfig{RegistryType: "remote"}); err == nil {
+		t.Error("Expected the manager to reject a remote registry without a base URL")
+	}
+}
+
+func TestRemoteRegistry_Writes(t *testing.T) {
+	_, srv := newMockRemoteRegistry(t)
+	r, err := NewRemoteRegistry(RegistryConfig{BaseURL: srv.URL})
+	if err != nil {
+		t.Fatalf("Failed to create remote registry: %v", err)
+	}
+	ctx := context.Background()
+	order := testRemoteSchema("agntcy:commerce.order.v1")
+
+	if err := r.RegisterSchema(ctx, order, nil); err != nil {
+		t.Fatalf("RegisterSchema failed: %v", err)
+	}
+	if err := r.RegisterSchema(ctx, order, nil); err == nil || !strings.Contains(err.Error(), "already exists") {
+		t.Errorf("Expected an already exists error, got %v", err)
+	}
+	if err := r.RegisterOrUpdateSchema(ctx, order, nil); err != nil {
+		t.Errorf("RegisterOrUpdateSchema failed: %v", err)
+	}
+
+	got, err := r.GetSchema(ctx, order.ID)
+	if err != nil || got.ID.String() != order.ID.String() {
+		t.Errorf("GetSchema = %v, %v", got, err)
+	}
+	if stats := r.GetStats(); stats.TotalSchemas != 1 {
+		t.Errorf("Expected 1 schema in stats, got %d", stats.TotalSchemas)
+	}
+
+	if err := r.DeleteSchema(ctx, order.ID); err != nil {
+		t.Fatalf("DeleteSchema failed: %v", err)
+	}
+	if err := r.DeleteSchema(ctx, order.ID); !errors.Is(err, ErrSchemaNotFound) {
+		t.Errorf("Expected ErrSchemaNotFound, got %v", err)
+	}
+	if _, err := r.GetSchema(ctx, order.ID); !errors.Is(err, ErrSchemaNotFound) {
+		t.Errorf("Expected ErrSchemaNotFound, got %v", err)
+	}
+}
+
+func TestRemoteRegistry_ListCache(t *testing.T) {
+	remote, srv := newMockRemoteRegistry(t)
+	r, err := NewRemoteRegistry(RegistryConfig{BaseURL: srv.URL, ListCacheTTL: time.Hour})
+	if err != nil {
+		t.Fatalf("Failed to create remote registry: %v", err)
+	}
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		if _, err := r.ListSchemas(ctx, ""); err != nil {
+			t.Fatalf("ListSchemas failed: %v", err)
+		}
+	}
+	if remote.listRequests() != 1 {
+		t.Errorf("Expected one list request, got %d", remote.listRequests())
+	}
+
+	// A write through this gateway invalidates the cached listing
+	if err := r.RegisterSchema(ctx, testRemoteSchema("agntcy:commerce.order.v1"), nil); err != nil {
+		t.Fatalf("RegisterSchema failed: %v", err)
+	}
+	ids, err := r.ListSchemas(ctx, "")
+	if err != nil || len(ids) != 1 || remote.listRequests() != 2 {
+		t.Errorf("Expected a fresh listing with one schema, got %v (%v) after %d requests", ids, err, remote.listRequests())
+	}
+
+	// A write by another gateway is seen once the cache is invalidated
+	remote.set(testRemoteSchema("agntcy:commerce.invoice.v1"))
+	if ids, _ := r.ListSchemas(ctx, ""); len(ids) != 1 {
+		t.Errorf("Expected the cached listing, got %v", ids)
+	}
+	r.InvalidateListCache()
+	if ids, _ := r.ListSchemas(ctx, ""); len(ids) != 2 {
+		t.Errorf("Expected both schemas after invalidation, got %v", ids)
+	}
+}
+
+func TestManager_RemoteRegistry(t *testing.T) {
+	remote, srv := newMockRemoteRegistry(t)
+	m, err := NewManager(ManagerConfig{
+		RegistryType: "remote",
+		Registry:     RegistryConfig{BaseURL: srv.URL, ListCacheTTL: time.Hour},
+		Cache:        CacheConfig{Type: "memory", MaxSize: 10, DefaultTTL: time.Hour},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create manager: %v", err)
+	}
+	ctx := context.Background()
+	order := testRemoteSchema("agntcy:commerce.order.v1")
+
+	if err := m.RegisterSchema(ctx, order, nil); err != nil {
+		t.Fatalf("RegisterSchema failed: %v", err)
+	}
+
+	// Definitions are served from the manager's cache once fetched
+	remote.remove(order.ID)
+	if _, err := m.GetRegistry().GetSchema(ctx, order.ID); err != nil {
+		t.Errorf("Expected the schema from the cache, got %v", err)
+	}
+
+	if _, err := m.GetRegistry().ListSchemas(ctx, ""); err != nil {
+		t.Fatalf("ListSchemas failed: %v", err)
+	}
+	if err := m.ClearCache(ctx); err != nil {
+		t.Fatalf("ClearCache failed: %v", err)
+	}
+	if _, err := m.GetRegistry().GetSchema(ctx, order.ID); !errors.Is(err, ErrSchemaNotFound) {
+		t.Errorf("Expected ErrSchemaNotFound after clearing the cache, got %v", err)
+	}
+	if _, err := m.GetRegistry().ListSchemas(ctx, ""); err != nil || remote.listRequests() != 2 {
+		t.Errorf("Expected clearing the cache to drop the listing, got %d list requests (%v)", remote.listRequests(), err)
+	}
+}
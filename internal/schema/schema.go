@@ -21,7 +21,11 @@ import (
 	"encoding/json"
 	"fmt"
 	"regexp"
+	"strconv"
+	"strings"
 	"time"
+
+	"github.com/amtp-protocol/agentry/internal/types"
 )
 
 // SchemaIdentifier represents an AGNTCY schema identifier
@@ -38,17 +42,55 @@ type Schema struct {
 	Definition  json.RawMessage  `json:"definition"`
 	PublishedAt time.Time        `json:"published_at"`
 	Signature   string           `json:"signature,omitempty"`
+	// Strict rejects payload properties the definition does not declare,
+	// as if the schema set additionalProperties to false
+	Strict bool `json:"strict,omitempty"`
+	// ValidationMode is the default validation mode for payloads of this
+	// schema, ValidationModeFull when empty
+	ValidationMode string `json:"validation_mode,omitempty"`
+	// Defaults fill in settings that messages using this schema leave unset
+	Defaults *MessageDefaults `json:"defaults,omitempty"`
+}
+
+// MessageDefaults are message settings implied by a schema, for example an
+// order that always expects a response. A message's own values win.
+type MessageDefaults struct {
+	ResponseType string                    `json:"response_type,omitempty"`
+	Coordination *types.CoordinationConfig `json:"coordination,omitempty"`
+}
+
+// IsEmpty reports whether d sets nothing
+func (d *MessageDefaults) IsEmpty() bool {
+	return d == nil || (d.ResponseType == "" && d.Coordination == nil)
+}
+
+// Apply sets the response type and coordination of message from d where
+// the message leaves them unset
+func (d *MessageDefaults) Apply(message *types.Message) {
+	if d == nil {
+		return
+	}
+	if message.ResponseType == "" {
+		message.ResponseType = d.ResponseType
+	}
+	if message.Coordination == nil && d.Coordination != nil {
+		coordination := *d.Coordination
+		message.Coordination = &coordination
+	}
 }
 
 // SchemaMetadata contains metadata about a schema
 type SchemaMetadata struct {
-	ID        SchemaIdentifier `json:"id"`
-	Version   string           `json:"version"`
-	CreatedAt time.Time        `json:"created_at"`
-	UpdatedAt time.Time        `json:"updated_at"`
-	FilePath  string           `json:"file_path"`
-	Size      int64            `json:"size"`
-	Checksum  string           `json:"checksum"`
+	ID             SchemaIdentifier `json:"id"`
+	Version        string           `json:"version"`
+	CreatedAt      time.Time        `json:"created_at"`
+	UpdatedAt      time.Time        `json:"updated_at"`
+	FilePath       string           `json:"file_path"`
+	Size           int64            `json:"size"`
+	Checksum       string           `json:"checksum"`
+	Strict         bool             `json:"strict"`
+	ValidationMode string           `json:"validation_mode,omitempty"`
+	Defaults       *MessageDefaults `json:"defaults,omitempty"`
 }
 
 // ValidationError represents a schema validation error
@@ -141,6 +183,48 @@ func ParseSchemaIdentifier(schemaStr string) (*SchemaIdentifier, error) {
 	}, nil
 }
 
+// LatestVersion is the version of an unresolved reference to the highest
+// registered version of a schema
+const LatestVersion = "latest"
+
+// schemaReferenceRegex matches a versionless schema reference:
+// agntcy:domain.entity or agntcy:domain.entity@latest
+var schemaReferenceRegex = regexp.MustCompile(`^agntcy:([a-zA-Z0-9_-]+)\.([a-zA-Z0-9_-]+)(@latest)?$`)
+
+// ParseSchemaReference parses a schema identifier that may omit its version.
+// Concrete identifiers are parsed as by ParseSchemaIdentifier; a versionless
+// or @latest reference yields an identifier whose Version is LatestVersion,
+// to be resolved with Manager.ResolveSchema.
+func ParseSchemaReference(schemaStr string) (*SchemaIdentifier, error) {
+	if matches := schemaReferenceRegex.FindStringSubmatch(schemaStr); len(matches) == 4 {
+		return &SchemaIdentifier{
+			Domain:  matches[1],
+			Entity:  matches[2],
+			Version: LatestVersion,
+			Raw:     schemaStr,
+		}, nil
+	}
+	return ParseSchemaIdentifier(schemaStr)
+}
+
+// IsLatest reports whether the identifier is an unresolved reference to the
+// latest version
+func (si *SchemaIdentifier) IsLatest() bool {
+	return si.Version == LatestVersion
+}
+
+// VersionNumber returns N for a "vN" version
+func (si *SchemaIdentifier) VersionNumber() (int, bool) {
+	if !strings.HasPrefix(si.Version, "v") {
+		return 0, false
+	}
+	n, err := strconv.Atoi(si.Version[1:])
+	if err != nil {
+		return 0, false
+	}
+	return n, true
+}
+
 // String returns the string representation of the schema identifier
 func (si *SchemaIdentifier) String() string {
 	if si.Raw != "" {
@@ -20,8 +20,68 @@ import (
 	"encoding/json"
 	"testing"
 	"time"
+
+	"github.com/amtp-protocol/agentry/internal/types"
 )
 
+func TestParseSchemaReference(t *testing.T) {
+	tests := []struct {
+		input       string
+		version     string
+		latest      bool
+		expectError bool
+	}{
+		{input: "agntcy:commerce.order.v3", version: "v3"},
+		{input: "agntcy:commerce.order", version: LatestVersion, latest: true},
+		{input: "agntcy:commerce.order@latest", version: LatestVersion, latest: true},
+		{input: "agntcy:commerce.order@v3", expectError: true},
+		{input: "agntcy:commerce", expectError: true},
+		{input: "", expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			result, err := ParseSchemaReference(tt.input)
+			if tt.expectError {
+				if err == nil {
+					t.Errorf("expected error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if result.Domain != "commerce" || result.Entity != "order" || result.Version != tt.version {
+				t.Errorf("unexpected identifier: %+v", result)
+			}
+			if result.IsLatest() != tt.latest {
+				t.Errorf("expected IsLatest() = %v", tt.latest)
+			}
+		})
+	}
+}
+
+func TestSchemaIdentifier_VersionNumber(t *testing.T) {
+	tests := []struct {
+		version string
+		number  int
+		ok      bool
+	}{
+		{"v1", 1, true},
+		{"v10", 10, true},
+		{LatestVersion, 0, false},
+		{"v", 0, false},
+	}
+
+	for _, tt := range tests {
+		id := SchemaIdentifier{Version: tt.version}
+		n, ok := id.VersionNumber()
+		if n != tt.number || ok != tt.ok {
+			t.Errorf("VersionNumber(%q) = (%d, %v), want (%d, %v)", tt.version, n, ok, tt.number, tt.ok)
+		}
+	}
+}
+
 func TestParseSchemaIdentifier(t *testing.T) {
 	tests := []struct {
 		name        string
@@ -531,3 +591,33 @@ func TestValidationError_JSONSerialization(t *testing.T) {
 		t.Errorf("expected value %v, got %v", validationError.Value, unmarshaled.Value)
 	}
 }
+
+func TestMessageDefaults_Apply(t *testing.T) {
+	defaults := &MessageDefaults{
+		ResponseType: "required",
+		Coordination: &types.CoordinationConfig{Type: "parallel", Timeout: 30},
+	}
+
+	message := &types.Message{}
+	defaults.Apply(message)
+	if message.ResponseType != "required" || message.Coordination == nil || message.Coordination.Type != "parallel" {
+		t.Fatalf("expected defaults to be applied, got %+v", message)
+	}
+	message.Coordination.Timeout = 5
+	if defaults.Coordination.Timeout != 30 {
+		t.Error("expected the message to get its own copy of the coordination")
+	}
+
+	own := &types.CoordinationConfig{Type: "sequential", Timeout: 10}
+	message = &types.Message{ResponseType: "none", Coordination: own}
+	defaults.Apply(message)
+	if message.ResponseType != "none" || message.Coordination != own {
+		t.Errorf("expected the message's own values to win, got %+v", message)
+	}
+
+	var none *MessageDefaults
+	none.Apply(message)
+	if !none.IsEmpty() || !(&MessageDefaults{}).IsEmpty() || defaults.IsEmpty() {
+		t.Error("unexpected IsEmpty result")
+	}
+}
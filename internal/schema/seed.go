@@ -0,0 +1,158 @@
+/*
+ * Copyright 2026 Cong Wang
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package schema
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Seed actions
+const (
+	SeedRegistered = "registered"
+	SeedUpdated    = "updated"
+	SeedUnchanged  = "unchanged"
+	SeedFailed     = "failed"
+)
+
+// SeedFile is the content of a schema file in the seed directory, the same
+// shape as a schema registration request
+type SeedFile struct {
+	ID             string           `json:"id"`
+	Definition     json.RawMessage  `json:"definition"`
+	Strict         bool             `json:"strict,omitempty"`
+	ValidationMode string           `json:"validation_mode,omitempty"`
+	Defaults       *MessageDefaults `json:"defaults,omitempty"`
+}
+
+// SeedResult is the outcome of seeding one file
+type SeedResult struct {
+	File     string
+	SchemaID string
+	Action   string
+	Err      error
+}
+
+// SeedFromDir registers or updates the schema in each .json file under dir,
+// walking subdirectories in lexical order. A file that cannot be seeded is
+// reported in its result and does not stop the others; an error is only
+// returned when dir itself cannot be read. check, when set, vets each schema
+// before it is written.
+func (m *Manager) SeedFromDir(ctx context.Context, dir string, check func(*Schema) error) ([]SeedResult, error) {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read schema seed directory: %w", err)
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("schema seed path %s is not a directory", dir)
+	}
+
+	var results []SeedResult
+	err = filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			results = append(results, SeedResult{File: path, Action: SeedFailed, Err: err})
+			if d != nil && d.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() || !strings.EqualFold(filepath.Ext(path), ".json") {
+			return nil
+		}
+		results = append(results, m.seedFile(ctx, path, check))
+		return nil
+	})
+	if err != nil {
+		return results, fmt.Errorf("failed to walk schema seed directory: %w", err)
+	}
+	return results, nil
+}
+
+// seedFile registers the schema in one seed file
+func (m *Manager) seedFile(ctx context.Context, path string, check func(*Schema) error) SeedResult {
+	result := SeedResult{File: path, Action: SeedFailed}
+
+	// #nosec G304 -- seed files come from the operator's configuration
+	data, err := os.ReadFile(path)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	var file SeedFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		result.Err = fmt.Errorf("invalid schema file: %w", err)
+		return result
+	}
+	result.SchemaID = file.ID
+
+	id, err := ParseSchemaReference(file.ID)
+	if err != nil {
+		result.Err = fmt.Errorf("invalid schema identifier: %w", err)
+		return result
+	}
+	// Seeding must be repeatable, so each file pins its version
+	if id.IsLatest() {
+		result.Err = fmt.Errorf("schema identifier %q must include a version", file.ID)
+		return result
+	}
+	if len(file.Definition) == 0 || !json.Valid(file.Definition) {
+		result.Err = fmt.Errorf("schema definition is missing or not valid JSON")
+		return result
+	}
+	if !IsValidValidationMode(file.ValidationMode) {
+		result.Err = fmt.Errorf("invalid validation mode %q", file.ValidationMode)
+		return result
+	}
+	if file.Defaults.IsEmpty() {
+		file.Defaults = nil
+	}
+
+	schema := &Schema{
+		ID:             *id,
+		Definition:     file.Definition,
+		PublishedAt:    time.Now().UTC(),
+		Strict:         file.Strict,
+		ValidationMode: file.ValidationMode,
+		Defaults:       file.Defaults,
+	}
+	if check != nil {
+		if err := check(schema); err != nil {
+			result.Err = err
+			return result
+		}
+	}
+
+	result.Action = SeedRegistered
+	if current, err := m.registryClient.GetSchema(ctx, *id); err == nil {
+		if sameSchema(current, schema) {
+			result.Action = SeedUnchanged
+			return result
+		}
+		result.Action = SeedUpdated
+	}
+	if err := m.registryClient.RegisterOrUpdateSchema(ctx, schema, nil); err != nil {
+		result.Action = SeedFailed
+		result.Err = err
+	}
+	return result
+}
@@ -0,0 +1,159 @@
+/*
+ * Copyright 2026 Cong Wang
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package schema
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func writeSeedFile(t *testing.T, dir, name, content string) {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatalf("failed to create seed directory: %v", err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("failed to write seed file: %v", err)
+	}
+}
+
+func TestManager_SeedFromDir(t *testing.T) {
+	manager, err := NewManager(ManagerConfig{
+		RegistryType: "local",
+		LocalRegistry: LocalRegistryConfig{
+			BasePath:   t.TempDir(),
+			AutoSave:   true,
+			CreateDirs: true,
+		},
+		Cache: CacheConfig{Type: "memory", DefaultTTL: time.Hour},
+	})
+	if err != nil {
+		t.Fatalf("failed to create manager: %v", err)
+	}
+	defer manager.Shutdown(context.Background())
+	ctx := context.Background()
+
+	seedDir := t.TempDir()
+	writeSeedFile(t, seedDir, "commerce/order.json",
+		`{"id": "agntcy:commerce.order.v1", "definition": {"type": "object"}, "strict": true}`)
+	writeSeedFile(t, seedDir, "commerce/invoice.json",
+		`{"id": "agntcy:commerce.invoice.v1", "definition": {"type": "object"}, "defaults": {"response_type": "required"}}`)
+	writeSeedFile(t, seedDir, "bad/broken.json", `{"id": `)
+	writeSeedFile(t, seedDir, "bad/unversioned.json", `{"id": "agntcy:commerce.refund", "definition": {"type": "object"}}`)
+	writeSeedFile(t, seedDir, "bad/no-definition.json", `{"id": "agntcy:commerce.refund.v1"}`)
+	writeSeedFile(t, seedDir, "bad/mode.json",
+		`{"id": "agntcy:commerce.refund.v1", "definition": {"type": "object"}, "validation_mode": "loose"}`)
+	writeSeedFile(t, seedDir, "bad/rejected.json",
+		`{"id": "agntcy:commerce.refund.v2", "definition": {"type": "object"}, "defaults": {"response_type": "none"}}`)
+	writeSeedFile(t, seedDir, "README.md", "not a schema")
+
+	rejectNone := func(s *Schema) error {
+		if s.Defaults != nil && s.Defaults.ResponseType == "none" {
+			return errors.New("rejected by check")
+		}
+		return nil
+	}
+
+	results, err := manager.SeedFromDir(ctx, seedDir, rejectNone)
+	if err != nil {
+		t.Fatalf("unexpected error seeding: %v", err)
+	}
+	actions := make(map[string]string)
+	for _, result := range results {
+		rel, _ := filepath.Rel(seedDir, result.File)
+		actions[rel] = result.Action
+		if (result.Action == SeedFailed) != (result.Err != nil) {
+			t.Errorf("%s: action %s does not match error %v", rel, result.Action, result.Err)
+		}
+	}
+	expected := map[string]string{
+		"commerce/order.json":    SeedRegistered,
+		"commerce/invoice.json":  SeedRegistered,
+		"bad/broken.json":        SeedFailed,
+		"bad/unversioned.json":   SeedFailed,
+		"bad/no-definition.json": SeedFailed,
+		"bad/mode.json":          SeedFailed,
+		"bad/rejected.json":      SeedFailed,
+	}
+	if len(actions) != len(expected) {
+		t.Errorf("expected %d results, got %v", len(expected), actions)
+	}
+	for file, action := range expected {
+		if actions[file] != action {
+			t.Errorf("%s: expected %s, got %q", file, action, actions[file])
+		}
+	}
+
+	order, err := manager.GetSchema(ctx, SchemaIdentifier{Domain: "commerce", Entity: "order", Version: "v1"})
+	if err != nil {
+		t.Fatalf("expected seeded schema to be registered: %v", err)
+	}
+	if !order.Strict {
+		t.Error("expected seeded schema settings to be kept")
+	}
+	if ids, _ := manager.ListSchemas(ctx, "commerce.refund"); len(ids) != 0 {
+		t.Errorf("expected invalid files not to be registered, got %v", ids)
+	}
+
+	// Seeding again leaves unchanged schemas alone and overwrites changed ones
+	writeSeedFile(t, seedDir, "commerce/order.json",
+		`{"id": "agntcy:commerce.order.v1", "definition": {"type": "object", "required": ["id"]}}`)
+	results, err = manager.SeedFromDir(ctx, seedDir, rejectNone)
+	if err != nil {
+		t.Fatalf("unexpected error reseeding: %v", err)
+	}
+	for _, result := range results {
+		switch filepath.Base(result.File) {
+		case "order.json":
+			if result.Action != SeedUpdated {
+				t.Errorf("expected order to be updated, got %s", result.Action)
+			}
+		case "invoice.json":
+			if result.Action != SeedUnchanged {
+				t.Errorf("expected invoice to be unchanged, got %s", result.Action)
+			}
+		}
+	}
+	order, err = manager.GetSchema(ctx, SchemaIdentifier{Domain: "commerce", Entity: "order", Version: "v1"})
+	if err != nil {
+		t.Fatalf("unexpected error getting schema: %v", err)
+	}
+	if order.Strict || !sameDefinition(order.Definition, json.RawMessage(`{"type": "object", "required": ["id"]}`)) {
+		t.Errorf("expected the reseeded schema, got %+v", order)
+	}
+}
+
+func TestManager_SeedFromDir_MissingDir(t *testing.T) {
+	manager, err := NewManager(ManagerConfig{
+		RegistryType:  "local",
+		LocalRegistry: LocalRegistryConfig{BasePath: t.TempDir(), CreateDirs: true},
+	})
+	if err != nil {
+		t.Fatalf("failed to create manager: %v", err)
+	}
+	defer manager.Shutdown(context.Background())
+
+	if _, err := manager.SeedFromDir(context.Background(), filepath.Join(t.TempDir(), "missing"), nil); err == nil {
+		t.Error("expected an error for a missing seed directory")
+	}
+}
@@ -20,23 +20,60 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"strings"
 	"time"
 )
 
 // ValidatorConfig holds configuration for schema validation
 type ValidatorConfig struct {
 	Enabled           bool          `yaml:"enabled" json:"enabled"`
-	StrictMode        bool          `yaml:"strict_mode" json:"strict_mode"`
 	Timeout           time.Duration `yaml:"timeout" json:"timeout"`
 	MaxPayloadSize    int64         `yaml:"max_payload_size" json:"max_payload_size"`
 	AllowUnknownProps bool          `yaml:"allow_unknown_props" json:"allow_unknown_props"`
+	UnknownFormats    string        `yaml:"unknown_formats" json:"unknown_formats"` // UnknownFormatWarn (default) or UnknownFormatError
+}
+
+// Validation modes. Partial validation checks the fields a payload has but
+// does not require the fields it lacks, for rolling a schema out to senders
+// that do not fill in every required field yet.
+const (
+	ValidationModeFull    = "full"
+	ValidationModePartial = "partial"
+)
+
+// IsValidValidationMode reports whether mode is a known validation mode or
+// empty, meaning the default
+func IsValidValidationMode(mode string) bool {
+	return mode == "" || mode == ValidationModeFull || mode == ValidationModePartial
+}
+
+type validationModeKey struct{}
+
+// WithValidationMode returns a context whose payload validations use mode
+// instead of the schema's own validation mode. An empty mode leaves the
+// schema's mode in effect.
+func WithValidationMode(ctx context.Context, mode string) context.Context {
+	if mode == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, validationModeKey{}, mode)
+}
+
+// validationMode returns the mode a payload of schema is validated in
+func validationMode(ctx context.Context, schema *Schema) string {
+	if mode, _ := ctx.Value(validationModeKey{}).(string); mode != "" {
+		return mode
+	}
+	if schema.ValidationMode != "" {
+		return schema.ValidationMode
+	}
+	return ValidationModeFull
 }
 
 // JSONSchemaValidator implements Validator interface using JSON Schema validation
 type JSONSchemaValidator struct {
 	registryClient RegistryClient
 	config         ValidatorConfig
+	formats        *FormatRegistry
 }
 
 // NewJSONSchemaValidator creates a new JSON schema validator
@@ -51,9 +88,16 @@ func NewJSONSchemaValidator(registryClient RegistryClient, config ValidatorConfi
 	return &JSONSchemaValidator{
 		registryClient: registryClient,
 		config:         config,
+		formats:        NewFormatRegistry(),
 	}
 }
 
+// Formats returns the registry of "format" keyword checkers used by the
+// validator
+func (v *JSONSchemaValidator) Formats() *FormatRegistry {
+	return v.formats
+}
+
 // ValidatePayload validates a payload against a schema
 func (v *JSONSchemaValidator) ValidatePayload(ctx context.Context, payload json.RawMessage, schemaID SchemaIdentifier) (*ValidationResult, error) {
 	// Check payload size
@@ -90,15 +134,18 @@ func (v *JSONSchemaValidator) ValidateWithSchema(ctx context.Context, payload js
 	}
 
 	// Perform validation
-	if err := v.validateAgainstSchema(payloadData, schemaData, "", result); err != nil {
+	partial := validationMode(ctx, schema) == ValidationModePartial
+	if err := v.validateAgainstSchema(payloadData, schemaData, "", schema.Strict, partial, result); err != nil {
 		return nil, fmt.Errorf("validation error: %w", err)
 	}
 
 	return result, nil
 }
 
-// validateAgainstSchema performs the actual validation logic
-func (v *JSONSchemaValidator) validateAgainstSchema(data interface{}, schema map[string]interface{}, path string, result *ValidationResult) error {
+// validateAgainstSchema performs the actual validation logic. When strict is
+// set, unknown object properties are rejected at every nesting level. When
+// partial is set, missing required properties are not reported.
+func (v *JSONSchemaValidator) validateAgainstSchema(data interface{}, schema map[string]interface{}, path string, strict, partial bool, result *ValidationResult) error {
 	// This is a simplified JSON Schema validator
 	// In a production system, you would use a proper JSON Schema library like github.com/xeipuuv/gojsonschema
 
@@ -111,7 +158,7 @@ func (v *JSONSchemaValidator) validateAgainstSchema(data interface{}, schema map
 
 	// Check required properties for objects
 	if dataObj, ok := data.(map[string]interface{}); ok {
-		if required, ok := schema["required"].([]interface{}); ok {
+		if required, ok := schema["required"].([]interface{}); ok && !partial {
 			for _, reqField := range required {
 				if fieldName, ok := reqField.(string); ok {
 					if _, exists := dataObj[fieldName]; !exists {
@@ -128,6 +175,9 @@ func (v *JSONSchemaValidator) validateAgainstSchema(data interface{}, schema map
 
 		// Validate properties
 		if properties, ok := schema["properties"].(map[string]interface{}); ok {
+			additionalAllowed, declared := schema["additionalProperties"].(bool)
+			rejectUnknown := strict || (declared && !additionalAllowed)
+
 			for fieldName, fieldValue := range dataObj {
 				fieldPath := path
 				if fieldPath != "" {
@@ -136,10 +186,12 @@ func (v *JSONSchemaValidator) validateAgainstSchema(data interface{}, schema map
 				fieldPath += fieldName
 
 				if fieldSchema, ok := properties[fieldName].(map[string]interface{}); ok {
-					if err := v.validateAgainstSchema(fieldValue, fieldSchema, fieldPath, result); err != nil {
+					if err := v.validateAgainstSchema(fieldValue, fieldSchema, fieldPath, strict, partial, result); err != nil {
 						return err
 					}
-				} else if v.config.StrictMode && !v.config.AllowUnknownProps {
+				} else if rejectUnknown {
+					result.AddError(fieldPath, "unknown property", "UNKNOWN_PROPERTY", fieldValue)
+				} else if !v.config.AllowUnknownProps {
 					result.AddWarning(fieldPath, "unknown property", "UNKNOWN_PROPERTY", fieldValue)
 				}
 			}
@@ -151,7 +203,7 @@ func (v *JSONSchemaValidator) validateAgainstSchema(data interface{}, schema map
 		if items, ok := schema["items"].(map[string]interface{}); ok {
 			for i, item := range dataArray {
 				itemPath := fmt.Sprintf("%s[%d]", path, i)
-				if err := v.validateAgainstSchema(item, items, itemPath, result); err != nil {
+				if err := v.validateAgainstSchema(item, items, itemPath, strict, partial, result); err != nil {
 					return err
 				}
 			}
@@ -161,7 +213,13 @@ func (v *JSONSchemaValidator) validateAgainstSchema(data interface{}, schema map
 	// Check string format
 	if dataStr, ok := data.(string); ok {
 		if format, ok := schema["format"].(string); ok {
-			if !v.validateFormat(dataStr, format) {
+			checker, known := v.formats.Lookup(format)
+			switch {
+			case !known && v.config.UnknownFormats == UnknownFormatError:
+				result.AddError(path, fmt.Sprintf("unknown format %s", format), "UNKNOWN_FORMAT", dataStr)
+			case !known:
+				result.AddWarning(path, fmt.Sprintf("unknown format %s", format), "UNKNOWN_FORMAT", dataStr)
+			case !checker(dataStr):
 				result.AddError(path, fmt.Sprintf("invalid format %s", format), "INVALID_FORMAT", dataStr)
 			}
 		}
@@ -230,20 +288,11 @@ func (v *JSONSchemaValidator) validateType(data interface{}, expectedType string
 
 // validateFormat validates string formats
 func (v *JSONSchemaValidator) validateFormat(value, format string) bool {
-	switch format {
-	case "email":
-		return strings.Contains(value, "@") && strings.Contains(value, ".")
-	case "uri":
-		return strings.HasPrefix(value, "http://") || strings.HasPrefix(value, "https://")
-	case "date":
-		_, err := time.Parse("2006-01-02", value)
-		return err == nil
-	case "date-time":
-		_, err := time.Parse(time.RFC3339, value)
-		return err == nil
-	default:
+	checker, ok := v.formats.Lookup(format)
+	if !ok {
 		return true // Unknown format, assume valid
 	}
+	return checker(value)
 }
 
 // performBasicStructuralValidation performs basic structural validation
@@ -19,8 +19,11 @@ package schema
 import (
 	"context"
 	"encoding/json"
+	"strings"
 	"testing"
 	"time"
+
+	"github.com/amtp-protocol/agentry/internal/types"
 )
 
 func TestNewJSONSchemaValidator(t *testing.T) {
@@ -341,7 +344,6 @@ func TestJSONSchemaValidator_ValidateWithSchema_UnknownProperty(t *testing.T) {
 	mockRegistry := NewMockRegistryClient()
 	config := ValidatorConfig{
 		Enabled:           true,
-		StrictMode:        true,
 		AllowUnknownProps: false,
 	}
 	validator := NewJSONSchemaValidator(mockRegistry, config)
@@ -387,6 +389,220 @@ func TestJSONSchemaValidator_ValidateWithSchema_UnknownProperty(t *testing.T) {
 	}
 }
 
+func TestJSONSchemaValidator_ValidateWithSchema_Formats(t *testing.T) {
+	definition := json.RawMessage(`{
+		"type": "object",
+		"properties": {
+			"assignee": {"type": "string", "format": "agntcy-address"},
+			"sku": {"type": "string", "format": "sku"},
+			"region": {"type": "string", "format": "region-code"}
+		}
+	}`)
+	schema := &Schema{ID: SchemaIdentifier{Domain: "commerce", Entity: "order", Version: "v1", Raw: "agntcy:commerce.order.v1"}, Definition: definition}
+
+	tests := []struct {
+		name           string
+		unknownFormats string
+		payload        string
+		expectedErrors map[string]string
+		expectedWarn   map[string]string
+	}{
+		{
+			name:         "valid values, unknown format warns",
+			payload:      `{"assignee": "sales-bot@example.com", "sku": "SKU-123", "region": "eu"}`,
+			expectedWarn: map[string]string{"region": "UNKNOWN_FORMAT"},
+		},
+		{
+			name:           "invalid custom format values",
+			payload:        `{"assignee": "not an address", "sku": "123"}`,
+			expectedErrors: map[string]string{"assignee": "INVALID_FORMAT", "sku": "INVALID_FORMAT"},
+		},
+		{
+			name:           "unknown format rejected",
+			unknownFormats: UnknownFormatError,
+			payload:        `{"region": "eu"}`,
+			expectedErrors: map[string]string{"region": "UNKNOWN_FORMAT"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			validator := NewJSONSchemaValidator(NewMockRegistryClient(), ValidatorConfig{Enabled: true, UnknownFormats: tt.unknownFormats})
+			validator.Formats().Register("sku", func(value string) bool {
+				return strings.HasPrefix(value, "SKU-")
+			})
+
+			result, err := validator.ValidateWithSchema(context.Background(), json.RawMessage(tt.payload), schema)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if result.IsValid() != (len(tt.expectedErrors) == 0) {
+				t.Errorf("expected valid=%t, got errors %+v", len(tt.expectedErrors) == 0, result.Errors)
+			}
+			if len(result.Errors) != len(tt.expectedErrors) || len(result.Warnings) != len(tt.expectedWarn) {
+				t.Fatalf("unexpected errors %+v and warnings %+v", result.Errors, result.Warnings)
+			}
+			for _, e := range result.Errors {
+				if tt.expectedErrors[e.Field] != e.Code {
+					t.Errorf("unexpected error %s on %s", e.Code, e.Field)
+				}
+			}
+			for _, w := range result.Warnings {
+				if tt.expectedWarn[w.Field] != w.Code {
+					t.Errorf("unexpected warning %s on %s", w.Code, w.Field)
+				}
+			}
+		})
+	}
+}
+
+func TestJSONSchemaValidator_ValidateWithSchema_StrictSchema(t *testing.T) {
+	validator := NewJSONSchemaValidator(NewMockRegistryClient(), ValidatorConfig{Enabled: true})
+	payload := json.RawMessage(`{"order_id": "12345", "customer": {"name": "Ada", "nickname": "A"}, "unknown_field": "value"}`)
+	definition := json.RawMessage(`{
+		"type": "object",
+		"properties": {
+			"order_id": {"type": "string"},
+			"customer": {
+				"type": "object",
+				"properties": {"name": {"type": "string"}}
+			}
+		}
+	}`)
+
+	tests := []struct {
+		name             string
+		strict           bool
+		expectedValid    bool
+		expectedErrors   int
+		expectedWarnings int
+	}{
+		{"non-strict schema warns on unknown fields", false, true, 0, 2},
+		{"strict schema rejects unknown fields", true, false, 2, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schema := &Schema{
+				ID:         SchemaIdentifier{Domain: "commerce", Entity: "order", Version: "v1", Raw: "agntcy:commerce.order.v1"},
+				Definition: definition,
+				Strict:     tt.strict,
+			}
+
+			result, err := validator.ValidateWithSchema(context.Background(), payload, schema)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if result.IsValid() != tt.expectedValid {
+				t.Errorf("expected valid=%t, got %t", tt.expectedValid, result.IsValid())
+			}
+			if len(result.Errors) != tt.expectedErrors {
+				t.Errorf("expected %d errors, got %d", tt.expectedErrors, len(result.Errors))
+			}
+			if len(result.Warnings) != tt.expectedWarnings {
+				t.Errorf("expected %d warnings, got %d", tt.expectedWarnings, len(result.Warnings))
+			}
+			for _, e := range append(result.Errors, result.Warnings...) {
+				if e.Code != "UNKNOWN_PROPERTY" {
+					t.Errorf("expected code 'UNKNOWN_PROPERTY', got '%s'", e.Code)
+				}
+			}
+		})
+	}
+}
+
+func TestJSONSchemaValidator_ValidateWithSchema_ValidationMode(t *testing.T) {
+	validator := NewJSONSchemaValidator(NewMockRegistryClient(), ValidatorConfig{Enabled: true})
+	definition := json.RawMessage(`{
+		"type": "object",
+		"properties": {
+			"order_id": {"type": "string"},
+			"customer": {
+				"type": "object",
+				"properties": {"email": {"type": "string", "format": "email"}},
+				"required": ["email"]
+			}
+		},
+		"required": ["order_id", "total"]
+	}`)
+
+	tests := []struct {
+		name          string
+		schemaMode    string
+		requestMode   string
+		payload       string
+		expectedCodes []string
+	}{
+		{"full reports missing required fields", "", "", `{"order_id": "1", "customer": {}}`, []string{"REQUIRED_FIELD_MISSING", "REQUIRED_FIELD_MISSING"}},
+		{"partial skips missing required fields", ValidationModePartial, "", `{"order_id": "1", "customer": {}}`, nil},
+		{"partial checks types of present fields", ValidationModePartial, "", `{"order_id": 1}`, []string{"TYPE_MISMATCH"}},
+		{"partial checks formats of present fields", ValidationModePartial, "", `{"customer": {"email": "nope"}}`, []string{"INVALID_FORMAT"}},
+		{"request overrides a full schema", ValidationModeFull, ValidationModePartial, `{"order_id": "1"}`, nil},
+		{"request overrides a partial schema", ValidationModePartial, ValidationModeFull, `{"order_id": "1"}`, []string{"REQUIRED_FIELD_MISSING"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schema := &Schema{
+				ID:             SchemaIdentifier{Domain: "commerce", Entity: "order", Version: "v1", Raw: "agntcy:commerce.order.v1"},
+				Definition:     definition,
+				ValidationMode: tt.schemaMode,
+			}
+
+			ctx := WithValidationMode(context.Background(), tt.requestMode)
+			result, err := validator.ValidateWithSchema(ctx, json.RawMessage(tt.payload), schema)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if result.IsValid() != (len(tt.expectedCodes) == 0) {
+				t.Errorf("expected valid=%t, got %t: %+v", len(tt.expectedCodes) == 0, result.IsValid(), result.Errors)
+			}
+			if len(result.Errors) != len(tt.expectedCodes) {
+				t.Fatalf("expected %d errors, got %+v", len(tt.expectedCodes), result.Errors)
+			}
+			for i, e := range result.Errors {
+				if e.Code != tt.expectedCodes[i] {
+					t.Errorf("expected code %s, got %s", tt.expectedCodes[i], e.Code)
+				}
+			}
+		})
+	}
+}
+
+func TestIsValidValidationMode(t *testing.T) {
+	for _, mode := range []string{"", ValidationModeFull, ValidationModePartial} {
+		if !IsValidValidationMode(mode) {
+			t.Errorf("expected %q to be valid", mode)
+		}
+	}
+	if IsValidValidationMode("lenient") {
+		t.Error("expected an unknown mode to be invalid")
+	}
+}
+
+func TestJSONSchemaValidator_ValidateWithSchema_DeclaredAdditionalProperties(t *testing.T) {
+	validator := NewJSONSchemaValidator(NewMockRegistryClient(), ValidatorConfig{Enabled: true})
+	schema := &Schema{
+		ID: SchemaIdentifier{Domain: "commerce", Entity: "order", Version: "v1", Raw: "agntcy:commerce.order.v1"},
+		Definition: json.RawMessage(`{
+			"type": "object",
+			"properties": {"order_id": {"type": "string"}},
+			"additionalProperties": false
+		}`),
+	}
+
+	result, err := validator.ValidateWithSchema(context.Background(), json.RawMessage(`{"order_id": "12345", "extra": 1}`), schema)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.IsValid() {
+		t.Errorf("expected additionalProperties: false to reject unknown fields")
+	}
+	if len(result.Errors) != 1 || result.Errors[0].Field != "extra" {
+		t.Errorf("expected a single error for field 'extra', got %+v", result.Errors)
+	}
+}
+
 func TestJSONSchemaValidator_ValidateWithSchema_ArrayValidation(t *testing.T) {
 	mockRegistry := NewMockRegistryClient()
 	config := ValidatorConfig{
@@ -707,6 +923,15 @@ func TestJSONSchemaValidator_ValidateFormat(t *testing.T) {
 		{"invalid date", "not-a-date", "date", false},
 		{"valid date-time", "2023-01-01T12:00:00Z", "date-time", true},
 		{"invalid date-time", "not-a-datetime", "date-time", false},
+		{"valid urn", "urn:isbn:0451450523", "uri", true},
+		{"valid agent address", "sales-bot@example.com", "agntcy-address", true},
+		{"agent address with dots", "team.sales_1@mail.example.co", "agntcy-address", true},
+		{"agent address without domain", "sales-bot", "agntcy-address", false},
+		{"agent address with single-label domain", "bot@localhost", "agntcy-address", true},
+		{"agent address with plus addressing", "bot+orders@example.com", "agntcy-address", true},
+		{"agent address with display name", "Bot <bot@example.com>", "agntcy-address", false},
+		{"urn agent address by default", "urn:agent:1234", "agntcy-address", false},
+		{"email with display name", "Bot <bot@example.com>", "email", false},
 		{"unknown format", "anything", "unknown", true},
 	}
 
@@ -720,6 +945,26 @@ func TestJSONSchemaValidator_ValidateFormat(t *testing.T) {
 	}
 }
 
+func TestAgentAddressFormat(t *testing.T) {
+	schemes, err := types.NewAddressSchemes([]string{types.AddressSchemeURN})
+	if err != nil {
+		t.Fatalf("failed to create address schemes: %v", err)
+	}
+	isAddress := AgentAddressFormat(schemes)
+
+	for value, expected := range map[string]bool{
+		"urn:agent:1234":        true,
+		"alice@localhost":       true,
+		"Alice@Example.com":     true,
+		"urn:agent":             false,
+		"Alice <alice@example>": false,
+	} {
+		if got := isAddress(value); got != expected {
+			t.Errorf("AgentAddressFormat(%q) = %t, want %t", value, got, expected)
+		}
+	}
+}
+
 func TestJSONSchemaValidator_PerformBasicStructuralValidation(t *testing.T) {
 	mockRegistry := NewMockRegistryClient()
 	config := ValidatorConfig{Enabled: true}
@@ -0,0 +1,109 @@
+/*
+ * Copyright 2026 Cong Wang
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/amtp-protocol/agentry/internal/config"
+	"github.com/amtp-protocol/agentry/internal/policy"
+	"github.com/amtp-protocol/agentry/internal/types"
+)
+
+// errAcceptHookUnavailable is returned when the accept hook reached no
+// verdict and failures are closed
+var errAcceptHookUnavailable = errors.New("accept hook unavailable")
+
+// policyRejection is returned when the accept hook denies a message
+type policyRejection struct {
+	reason string
+}
+
+func (r *policyRejection) Error() string {
+	if r.reason == "" {
+		return "rejected by policy"
+	}
+	return "rejected by policy: " + r.reason
+}
+
+// newAcceptHook returns nil when no accept hook is configured
+func newAcceptHook(cfg config.AcceptHookConfig) policy.AcceptHook {
+	if cfg.URL == "" {
+		return nil
+	}
+	return policy.NewHTTPAcceptHook(cfg.URL, cfg.Timeout)
+}
+
+// applyAcceptHook asks the accept hook, if any, whether to accept message
+// and makes the changes of a modify verdict. A denial is a *policyRejection.
+func (s *Server) applyAcceptHook(ctx context.Context, message *types.Message) error {
+	if s.acceptHook == nil {
+		return nil
+	}
+
+	verdict, err := s.acceptHook.Check(ctx, message)
+	if err != nil {
+		if s.config.Message.AcceptHook.OnFailure == config.AcceptHookFailClosed {
+			return fmt.Errorf("%w: %v", errAcceptHookUnavailable, err)
+		}
+		s.logger.Warnf("Accept hook failed, accepting message %s: %v", message.MessageID, err)
+		return nil
+	}
+
+	if verdict.Decision == policy.DecisionDeny {
+		return &policyRejection{reason: verdict.Reason}
+	}
+	verdict.Apply(message)
+	return nil
+}
+
+// checkAcceptHook runs the accept hook for a send and refuses the message
+// when it is not accepted
+func (s *Server) checkAcceptHook(ctx context.Context, message *types.Message) error {
+	err := s.applyAcceptHook(ctx, message)
+	if err == nil {
+		return nil
+	}
+
+	var rejection *policyRejection
+	if errors.As(err, &rejection) {
+		details := map[string]interface{}{}
+		if rejection.reason != "" {
+			details["reason"] = rejection.reason
+		}
+		return &sendError{
+			status:  http.StatusForbidden,
+			code:    "REJECTED_BY_POLICY",
+			message: "Message rejected by policy",
+			details: details,
+			err:     err,
+		}
+	}
+
+	return &sendError{
+		status:  http.StatusServiceUnavailable,
+		code:    "POLICY_CHECK_UNAVAILABLE",
+		message: "The message policy check is unavailable, try again later",
+		details: map[string]interface{}{
+			"error": err.Error(),
+		},
+		err: err,
+	}
+}
@@ -0,0 +1,195 @@
+/*
+ * Copyright 2026 Cong Wang
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/amtp-protocol/agentry/internal/config"
+	"github.com/amtp-protocol/agentry/internal/policy"
+	"github.com/amtp-protocol/agentry/internal/types"
+)
+
+func TestNewAcceptHook_Off(t *testing.T) {
+	if hook := newAcceptHook(config.AcceptHookConfig{}); hook != nil {
+		t.Errorf("Expected no accept hook without a URL, got %v", hook)
+	}
+}
+
+func TestHandleSendMessage_AcceptHook(t *testing.T) {
+	tests := []struct {
+		name          string
+		hookStatus    int
+		hookBody      string
+		onFailure     string
+		status        int
+		code          string
+		expectSubject string
+	}{
+		{"allow", http.StatusOK, `{"decision":"allow"}`, "", http.StatusOK, "", "Hello"},
+		{"deny", http.StatusOK, `{"decision":"deny","reason":"looks like spam"}`, "", http.StatusForbidden, "REJECTED_BY_POLICY", ""},
+		{"modify", http.StatusOK, `{"decision":"modify","subject":"[checked] Hello"}`, "", http.StatusOK, "", "[checked] Hello"},
+		{"failure open", http.StatusBadGateway, ``, config.AcceptHookFailOpen, http.StatusOK, "", "Hello"},
+		{"failure closed", http.StatusBadGateway, ``, config.AcceptHookFailClosed, http.StatusServiceUnavailable, "POLICY_CHECK_UNAVAILABLE", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.hookStatus)
+				w.Write([]byte(tt.hookBody))
+			}))
+			defer hook.Close()
+
+			server := createTestServer()
+			server.acceptHook = policy.NewHTTPAcceptHook(hook.URL, time.Second)
+			server.config.Message.AcceptHook.OnFailure = tt.onFailure
+			processor := server.processor.(*MockMessageProcessor)
+
+			body, _ := json.Marshal(types.SendMessageRequest{
+				Sender:     "test@example.com",
+				Recipients: []string{"recipient@test.com"},
+				Subject:    "Hello",
+				Payload:    json.RawMessage(`{"text":"hi"}`),
+			})
+			req := httptest.NewRequest("POST", "/v1/messages", bytes.NewBuffer(body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			server.router.ServeHTTP(w, req)
+
+			if w.Code != tt.status {
+				t.Fatalf("Expected status %d, got %d: %s", tt.status, w.Code, w.Body.String())
+			}
+			if tt.code != "" {
+				if !strings.Contains(w.Body.String(), tt.code) {
+					t.Errorf("Expected %s, got %s", tt.code, w.Body.String())
+				}
+				if processor.lastMessage != nil {
+					t.Error("Expected a rejected message not to be processed")
+				}
+				return
+			}
+			if processor.lastMessage == nil || processor.lastMessage.Subject != tt.expectSubject {
+				t.Errorf("Expected the message to be processed with subject %q, got %+v", tt.expectSubject, processor.lastMessage)
+			}
+		})
+	}
+
+	t.Run("deny reason", func(t *testing.T) {
+		hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`{"decision":"deny","reason":"looks like spam"}`))
+		}))
+		defer hook.Close()
+
+		server := createTestServer()
+		server.acceptHook = policy.NewHTTPAcceptHook(hook.URL, time.Second)
+		body := `{"sender":"test@example.com","recipients":["recipient@test.com"],"payload":{}}`
+		req := httptest.NewRequest("POST", "/v1/messages", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		server.router.ServeHTTP(w, req)
+
+		if !strings.Contains(w.Body.String(), "looks like spam") {
+			t.Errorf("Expected the hook's reason in the error details, got %s", w.Body.String())
+		}
+	})
+}
+
+func TestHandleSendMessage_AcceptHookSigned(t *testing.T) {
+	for _, tt := range []struct {
+		name        string
+		hookBody    string
+		keepsSigned bool
+	}{
+		{"allow", `{"decision":"allow"}`, true},
+		{"modify", `{"decision":"modify","subject":"[checked] Hello"}`, false},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte(tt.hookBody))
+			}))
+			defer hook.Close()
+
+			server := createTestServer()
+			server.acceptHook = policy.NewHTTPAcceptHook(hook.URL, time.Second)
+			processor := server.processor.(*MockMessageProcessor)
+
+			body, _ := json.Marshal(types.SendMessageRequest{
+				Sender:     "test@example.com",
+				Recipients: []string{"recipient@test.com"},
+				Subject:    "Hello",
+				Payload:    json.RawMessage(`{"text":"hi"}`),
+				Signature:  &types.MessageSignature{Algorithm: "ES256", KeyID: "k1", Value: "c2lnbmVk"},
+			})
+			req := httptest.NewRequest("POST", "/v1/messages", bytes.NewBuffer(body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			server.router.ServeHTTP(w, req)
+
+			if w.Code != http.StatusOK || processor.lastMessage == nil {
+				t.Fatalf("Expected the message to be processed, got %d: %s", w.Code, w.Body.String())
+			}
+			// A signature the hook's changes broke is not relayed on
+			if kept := processor.lastMessage.Signature != nil; kept != tt.keepsSigned {
+				t.Errorf("Expected the signature kept to be %v, got %+v", tt.keepsSigned, processor.lastMessage.Signature)
+			}
+			if removed := strings.Contains(w.Body.String(), "SIGNATURE_REMOVED"); removed == tt.keepsSigned {
+				t.Errorf("Expected a SIGNATURE_REMOVED warning only when the signature is dropped, got %s", w.Body.String())
+			}
+		})
+	}
+}
+
+func TestSubmitBridgedMessage_AcceptHook(t *testing.T) {
+	verdict := `{"decision":"deny"}`
+	hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if verdict == "" {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Write([]byte(verdict))
+	}))
+	defer hook.Close()
+
+	server := createTestServer()
+	server.acceptHook = policy.NewHTTPAcceptHook(hook.URL, time.Second)
+	req := &types.SendMessageRequest{
+		Sender:     "mailer@example.com",
+		Recipients: []string{"agent@localhost"},
+		Payload:    json.RawMessage(`{"text":"hi"}`),
+	}
+
+	if _, err := server.submitBridgedMessage(context.Background(), req); !errors.Is(err, errMessageRejected) {
+		t.Errorf("Expected a denied message to be rejected, got %v", err)
+	}
+
+	// A closed failure is temporary, so the mail is not rejected permanently
+	verdict = ""
+	server.config.Message.AcceptHook.OnFailure = config.AcceptHookFailClosed
+	_, err := server.submitBridgedMessage(context.Background(), req)
+	if !errors.Is(err, errAcceptHookUnavailable) || errors.Is(err, errMessageRejected) {
+		t.Errorf("Expected the hook to be reported unavailable, got %v", err)
+	}
+}
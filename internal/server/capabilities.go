@@ -0,0 +1,92 @@
+/*
+ * Copyright 2026 Cong Wang
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package server
+
+import (
+	"context"
+
+	"github.com/amtp-protocol/agentry/internal/config"
+	"github.com/amtp-protocol/agentry/internal/discovery"
+	"github.com/amtp-protocol/agentry/pkg/uuid"
+)
+
+// Features this gateway can advertise in its capabilities
+const (
+	featureAttachments           = "attachments"
+	featureEncryptedPassthrough  = "encrypted-passthrough"
+	featureCoordination          = "coordination"
+	featureSchemaValidation      = "schema-validation"
+	featureSigning               = "signing"
+	featureSignatureVerification = "signature-verification"
+	featureSignaturesRequired    = "signatures-required"
+)
+
+// coordinationTypes are the coordination types the workflow manager runs
+var coordinationTypes = []string{"parallel", "sequential", "conditional"}
+
+// localCapabilities describes this gateway from its running configuration
+// rather than its DNS record, which may lag behind it. The gateway URL and
+// JWKS location can only come from DNS, so they are taken from discovered
+// when there is one.
+func (s *Server) localCapabilities(ctx context.Context, discovered *discovery.AMTPCapabilities) *discovery.AMTPCapabilities {
+	// Discovery may hand out its cached copy, which must not change
+	var capabilities discovery.AMTPCapabilities
+	if discovered != nil {
+		capabilities = *discovered
+	}
+
+	capabilities.Version = "1.0"
+	capabilities.Domain = s.config.Server.Domain
+	capabilities.MaxSize = s.config.Message.MaxSize
+	capabilities.Schemas = s.agentRegistry.GetSupportedSchemas(ctx)
+
+	capabilities.Auth = nil
+	if s.config.Auth.RequireAuth {
+		capabilities.Auth = append([]string(nil), s.config.Auth.Methods...)
+	}
+
+	features := []string{featureAttachments, featureEncryptedPassthrough}
+	capabilities.MaxAttachments = s.config.Message.MaxAttachments
+	capabilities.MaxAttachmentBytes = s.config.Message.MaxTotalAttachmentBytes
+
+	capabilities.Coordination = nil
+	if s.workflow != nil {
+		features = append(features, featureCoordination)
+		capabilities.Coordination = coordinationTypes
+	}
+	if s.schemaManager != nil {
+		features = append(features, featureSchemaValidation)
+	}
+	if s.config.Auth.SigningKeyFile != "" {
+		features = append(features, featureSigning)
+	}
+	switch s.config.Auth.SignatureVerification {
+	case config.SignatureVerificationOptional, config.SignatureVerificationRequired:
+		features = append(features, featureSignatureVerification)
+		if s.signaturesRequired() {
+			features = append(features, featureSignaturesRequired)
+		}
+	}
+	// Other gateways only relay IDs of another strategy to gateways that
+	// accept them
+	if strategy := s.config.Message.IDStrategy; strategy != "" && strategy != uuid.StrategyUUIDv7 {
+		features = append(features, uuid.Feature(strategy))
+	}
+	capabilities.Features = features
+
+	return &capabilities
+}
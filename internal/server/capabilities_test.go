@@ -0,0 +1,119 @@
+/*
+ * Copyright 2026 Cong Wang
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/amtp-protocol/agentry/internal/config"
+	"github.com/amtp-protocol/agentry/internal/discovery"
+	"github.com/amtp-protocol/agentry/internal/schema"
+	"github.com/amtp-protocol/agentry/internal/workflow"
+)
+
+func getCapabilities(t *testing.T, server *Server, domain string) discovery.AMTPCapabilities {
+	t.Helper()
+	req := httptest.NewRequest("GET", "/v1/capabilities/"+domain, nil)
+	w := httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	var capabilities discovery.AMTPCapabilities
+	if err := json.Unmarshal(w.Body.Bytes(), &capabilities); err != nil {
+		t.Fatalf("Failed to unmarshal response: %v", err)
+	}
+	return capabilities
+}
+
+func TestHandleGetCapabilities_ReflectsConfig(t *testing.T) {
+	server := createTestServerWithRealProcessor()
+
+	capabilities := getCapabilities(t, server, "localhost")
+	if capabilities.Gateway != "http://localhost:8080" || capabilities.MaxSize != 10485760 {
+		t.Errorf("Unexpected capabilities: %+v", capabilities)
+	}
+	if !reflect.DeepEqual(capabilities.Features, []string{featureAttachments, featureEncryptedPassthrough}) {
+		t.Errorf("Expected only attachments and encrypted passthrough, got %v", capabilities.Features)
+	}
+	if capabilities.Auth != nil || capabilities.Coordination != nil || capabilities.MaxAttachments != 0 {
+		t.Errorf("Expected no auth, coordination or attachment limits, got %+v", capabilities)
+	}
+
+	sm, err := schema.NewManager(schema.ManagerConfig{
+		RegistryType:  "local",
+		LocalRegistry: schema.LocalRegistryConfig{BasePath: t.TempDir(), CreateDirs: true},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create schema manager: %v", err)
+	}
+	server.schemaManager = sm
+	server.workflow = workflow.NewManager(server.storage, nil, nil)
+	server.config.Message.MaxSize = 1 << 20
+	server.config.Message.MaxAttachments = 5
+	server.config.Message.MaxTotalAttachmentBytes = 1 << 30
+	server.config.Auth.RequireAuth = true
+	server.config.Auth.Methods = []string{"apikey", "oauth"}
+	server.config.Auth.SigningKeyFile = "/etc/agentry/signing.pem"
+	server.config.Auth.SignatureVerification = config.SignatureVerificationRequired
+	server.config.Message.IDStrategy = "ulid"
+
+	capabilities = getCapabilities(t, server, "LocalHost")
+	wantFeatures := []string{
+		featureAttachments, featureEncryptedPassthrough, featureCoordination, featureSchemaValidation,
+		featureSigning, featureSignatureVerification, featureSignaturesRequired, "message-ids-ulid",
+	}
+	if !reflect.DeepEqual(capabilities.Features, wantFeatures) {
+		t.Errorf("Expected features %v, got %v", wantFeatures, capabilities.Features)
+	}
+	if !reflect.DeepEqual(capabilities.Auth, []string{"apikey", "oauth"}) {
+		t.Errorf("Expected the configured auth methods, got %v", capabilities.Auth)
+	}
+	if !reflect.DeepEqual(capabilities.Coordination, coordinationTypes) {
+		t.Errorf("Expected coordination types %v, got %v", coordinationTypes, capabilities.Coordination)
+	}
+	if capabilities.MaxSize != 1<<20 || capabilities.MaxAttachments != 5 || capabilities.MaxAttachmentBytes != 1<<30 {
+		t.Errorf("Expected the configured limits, got %+v", capabilities)
+	}
+
+	// The discovery cache keeps what DNS published
+	discovered, err := server.discovery.DiscoverCapabilities(context.Background(), "localhost")
+	if err != nil {
+		t.Fatalf("DiscoverCapabilities failed: %v", err)
+	}
+	if discovered.Features != nil || discovered.MaxSize != 0 {
+		t.Errorf("Expected the cached DNS capabilities to be left unchanged, got %+v", discovered)
+	}
+}
+
+func TestHandleGetCapabilities_OwnDomainWithoutDNSRecord(t *testing.T) {
+	server := createTestServerWithRealProcessor()
+	server.config.Server.Domain = "unpublished.example.com"
+
+	capabilities := getCapabilities(t, server, "unpublished.example.com")
+	if capabilities.Gateway != "" || capabilities.Domain != "unpublished.example.com" || capabilities.Version != "1.0" {
+		t.Errorf("Unexpected capabilities: %+v", capabilities)
+	}
+	if len(capabilities.Features) == 0 {
+		t.Error("Expected features from the configuration")
+	}
+}
@@ -0,0 +1,158 @@
+/*
+ * Copyright 2026 Cong Wang
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package server
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/amtp-protocol/agentry/internal/config"
+	"github.com/amtp-protocol/agentry/internal/logging"
+	"github.com/amtp-protocol/agentry/internal/metrics"
+	"github.com/amtp-protocol/agentry/internal/storage"
+)
+
+// defaultCapacityCheckInterval is used when no check interval is configured
+const defaultCapacityCheckInterval = 30 * time.Second
+
+// capacityMonitor periodically samples storage statistics, publishes them as
+// gauges and tracks which configured high watermarks are exceeded
+type capacityMonitor struct {
+	storage storage.Storage
+	metrics metrics.MetricsProvider
+	config  config.StorageCapacityConfig
+	logger  *logging.Logger
+
+	mu       sync.RWMutex
+	exceeded []string
+	started  bool
+
+	stopOnce sync.Once
+	stop     chan struct{}
+	done     chan struct{}
+}
+
+// newCapacityMonitor returns nil when there is nothing to monitor: metrics
+// are disabled and no watermark is configured
+func newCapacityMonitor(st storage.Storage, m metrics.MetricsProvider, cfg config.StorageCapacityConfig, logger *logging.Logger) *capacityMonitor {
+	if m == nil && cfg.MaxMessages == 0 && cfg.MaxInboxMessages == 0 && cfg.MaxUnacknowledgedAge == 0 {
+		return nil
+	}
+	if cfg.CheckInterval <= 0 {
+		cfg.CheckInterval = defaultCapacityCheckInterval
+	}
+	return &capacityMonitor{
+		storage: st,
+		metrics: m,
+		config:  cfg,
+		logger:  logger,
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+}
+
+// Start samples storage immediately and then on every check interval
+func (cm *capacityMonitor) Start(ctx context.Context) {
+	cm.mu.Lock()
+	cm.started = true
+	cm.mu.Unlock()
+
+	go func() {
+		defer close(cm.done)
+
+		ticker := time.NewTicker(cm.config.CheckInterval)
+		defer ticker.Stop()
+
+		for {
+			cm.check(ctx)
+			select {
+			case <-ticker.C:
+			case <-cm.stop:
+				return
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// Stop ends sampling and waits for an in-progress check to finish
+func (cm *capacityMonitor) Stop() {
+	cm.stopOnce.Do(func() {
+		close(cm.stop)
+
+		cm.mu.RLock()
+		started := cm.started
+		cm.mu.RUnlock()
+		if started {
+			<-cm.done
+		}
+	})
+}
+
+// Exceeded describes the high watermarks exceeded at the last check
+func (cm *capacityMonitor) Exceeded() []string {
+	cm.mu.RLock()
+	defer cm.mu.RUnlock()
+	return append([]string(nil), cm.exceeded...)
+}
+
+// check samples storage once. If the statistics cannot be read the previous
+// state is kept, since storage failures are reported by readiness already.
+func (cm *capacityMonitor) check(ctx context.Context) {
+	stats, err := cm.storage.GetStats(ctx)
+	if err != nil {
+		cm.logger.Error("Failed to read storage statistics for capacity check", err)
+		return
+	}
+
+	var oldestAge time.Duration
+	if stats.OldestUnacknowledgedAt != nil {
+		oldestAge = time.Since(*stats.OldestUnacknowledgedAt)
+	}
+
+	if cm.metrics != nil {
+		cm.metrics.SetStorageStats(stats.TotalMessages, stats.InboxMessages, oldestAge)
+	}
+
+	var exceeded []string
+	if cm.config.MaxMessages > 0 && stats.TotalMessages > cm.config.MaxMessages {
+		exceeded = append(exceeded, fmt.Sprintf("stored messages %d exceed %d", stats.TotalMessages, cm.config.MaxMessages))
+	}
+	if cm.config.MaxInboxMessages > 0 && stats.InboxMessages > cm.config.MaxInboxMessages {
+		exceeded = append(exceeded, fmt.Sprintf("unacknowledged inbox messages %d exceed %d", stats.InboxMessages, cm.config.MaxInboxMessages))
+	}
+	if cm.config.MaxUnacknowledgedAge > 0 && oldestAge > cm.config.MaxUnacknowledgedAge {
+		exceeded = append(exceeded, fmt.Sprintf("oldest unacknowledged message age %s exceeds %s",
+			oldestAge.Truncate(time.Second), cm.config.MaxUnacknowledgedAge))
+	}
+
+	cm.mu.Lock()
+	wasDegraded := len(cm.exceeded) > 0
+	cm.exceeded = exceeded
+	cm.mu.Unlock()
+
+	switch {
+	case len(exceeded) > 0 && !wasDegraded:
+		cm.logger.Warnf("Storage capacity high watermark exceeded: %s", strings.Join(exceeded, "; "))
+	case len(exceeded) == 0 && wasDegraded:
+		cm.logger.Info("Storage capacity back below high watermarks")
+	}
+}
@@ -0,0 +1,191 @@
+/*
+ * Copyright 2026 Cong Wang
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/amtp-protocol/agentry/internal/config"
+	"github.com/amtp-protocol/agentry/internal/logging"
+	"github.com/amtp-protocol/agentry/internal/metrics"
+	"github.com/amtp-protocol/agentry/internal/storage"
+	"github.com/amtp-protocol/agentry/internal/types"
+)
+
+// storeInboxMessage stores a message waiting in a local inbox since delivered
+func storeInboxMessage(t *testing.T, st storage.Storage, id string, delivered time.Time) {
+	t.Helper()
+	ctx := context.Background()
+	if err := st.StoreMessage(ctx, &types.Message{MessageID: id, Sender: "sender@example.com"}); err != nil {
+		t.Fatalf("Failed to store message: %v", err)
+	}
+	if err := st.StoreStatus(ctx, id, &types.MessageStatus{
+		MessageID: id,
+		Status:    types.StatusDelivered,
+		Recipients: []types.RecipientStatus{{
+			Address:        "agent@localhost",
+			Status:         types.StatusDelivered,
+			Timestamp:      delivered,
+			LocalDelivery:  true,
+			InboxDelivered: true,
+		}},
+	}); err != nil {
+		t.Fatalf("Failed to store status: %v", err)
+	}
+}
+
+func TestCapacityMonitor_Watermarks(t *testing.T) {
+	st := storage.NewMemoryStorage(storage.MemoryStorageConfig{})
+	m := metrics.NewSimpleMetrics()
+	cm := newCapacityMonitor(st, m, config.StorageCapacityConfig{
+		MaxMessages:          2,
+		MaxInboxMessages:     2,
+		MaxUnacknowledgedAge: time.Hour,
+	}, logging.NewNoopLogger())
+
+	storeInboxMessage(t, st, "msg-1", time.Now().Add(-time.Minute))
+	storeInboxMessage(t, st, "msg-2", time.Now())
+	cm.check(context.Background())
+
+	if exceeded := cm.Exceeded(); len(exceeded) != 0 {
+		t.Errorf("Expected no watermark exceeded at the limit, got %v", exceeded)
+	}
+
+	data, err := m.ToJSON()
+	if err != nil {
+		t.Fatalf("Failed to export metrics: %v", err)
+	}
+	var exported struct {
+		Storage struct {
+			TotalMessages           int64   `json:"total_messages"`
+			InboxUnacknowledged     int64   `json:"inbox_unacknowledged"`
+			OldestUnacknowledgedAge float64 `json:"oldest_unacknowledged_age_seconds"`
+		} `json:"storage"`
+	}
+	if err := json.Unmarshal(data, &exported); err != nil {
+		t.Fatalf("Failed to decode metrics: %v", err)
+	}
+	if exported.Storage.TotalMessages != 2 || exported.Storage.InboxUnacknowledged != 2 {
+		t.Errorf("Unexpected storage gauges: %+v", exported.Storage)
+	}
+	if exported.Storage.OldestUnacknowledgedAge < 60 {
+		t.Errorf("Expected oldest unacknowledged age of at least 60s, got %v", exported.Storage.OldestUnacknowledgedAge)
+	}
+
+	storeInboxMessage(t, st, "msg-3", time.Now().Add(-2*time.Hour))
+	cm.check(context.Background())
+
+	exceeded := cm.Exceeded()
+	if len(exceeded) != 3 {
+		t.Fatalf("Expected all three watermarks exceeded, got %v", exceeded)
+	}
+	for i, want := range []string{"stored messages 3 exceed 2", "unacknowledged inbox messages 3 exceed 2", "oldest unacknowledged message age"} {
+		if !strings.Contains(exceeded[i], want) {
+			t.Errorf("Expected %q, got %q", want, exceeded[i])
+		}
+	}
+}
+
+func TestCapacityMonitor_Disabled(t *testing.T) {
+	st := storage.NewMemoryStorage(storage.MemoryStorageConfig{})
+	if cm := newCapacityMonitor(st, nil, config.StorageCapacityConfig{}, logging.NewNoopLogger()); cm != nil {
+		t.Error("Expected no monitor without metrics or watermarks")
+	}
+}
+
+func TestCapacityMonitor_StartStop(t *testing.T) {
+	st := storage.NewMemoryStorage(storage.MemoryStorageConfig{})
+	storeInboxMessage(t, st, "msg-1", time.Now())
+
+	cm := newCapacityMonitor(st, nil, config.StorageCapacityConfig{
+		CheckInterval: 10 * time.Millisecond,
+		MaxMessages:   1,
+	}, logging.NewNoopLogger())
+	cm.Start(context.Background())
+
+	storeInboxMessage(t, st, "msg-2", time.Now())
+	deadline := time.Now().Add(time.Second)
+	for len(cm.Exceeded()) == 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if len(cm.Exceeded()) == 0 {
+		t.Error("Expected periodic check to notice the exceeded watermark")
+	}
+
+	cm.Stop()
+	cm.Stop() // idempotent
+
+	// Stopping a monitor that never started must not block
+	newCapacityMonitor(st, nil, config.StorageCapacityConfig{MaxMessages: 1}, logging.NewNoopLogger()).Stop()
+}
+
+func TestHandleReady_CapacityDegraded(t *testing.T) {
+	server := createTestServer()
+	st := storage.NewMemoryStorage(storage.MemoryStorageConfig{})
+	server.capacity = newCapacityMonitor(st, nil, config.StorageCapacityConfig{MaxInboxMessages: 1}, logging.NewNoopLogger())
+
+	getReady := func() (int, ReadinessStatus) {
+		rr := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/ready", nil)
+		server.router.ServeHTTP(rr, req)
+		var response ReadinessStatus
+		if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+			t.Fatalf("Failed to unmarshal response: %v", err)
+		}
+		return rr.Code, response
+	}
+
+	for i := 0; i < 2; i++ {
+		storeInboxMessage(t, st, fmt.Sprintf("msg-%d", i), time.Now())
+	}
+	server.capacity.check(context.Background())
+
+	code, response := getReady()
+	if code != http.StatusServiceUnavailable {
+		t.Errorf("Expected status code %d, got %d", http.StatusServiceUnavailable, code)
+	}
+	if response.Status != "degraded" || response.Ready {
+		t.Errorf("Expected degraded readiness, got %s (ready=%v)", response.Status, response.Ready)
+	}
+	if response.Dependencies["storage_capacity"] != "degraded" {
+		t.Errorf("Expected storage_capacity to be degraded, got %s", response.Dependencies["storage_capacity"])
+	}
+	if len(response.Warnings) != 1 || !strings.Contains(response.Warnings[0], "unacknowledged inbox messages 2 exceed 1") {
+		t.Errorf("Unexpected warnings: %v", response.Warnings)
+	}
+
+	// Acknowledging a message brings the gateway back
+	if err := st.AcknowledgeMessage(context.Background(), "agent@localhost", "msg-0", nil); err != nil {
+		t.Fatalf("Failed to acknowledge message: %v", err)
+	}
+	server.capacity.check(context.Background())
+
+	code, response = getReady()
+	if code != http.StatusOK || response.Status != "ready" {
+		t.Errorf("Expected ready after recovery, got %d %s", code, response.Status)
+	}
+	if response.Dependencies["storage_capacity"] != "ready" || len(response.Warnings) != 0 {
+		t.Errorf("Expected storage_capacity ready without warnings, got %s %v", response.Dependencies["storage_capacity"], response.Warnings)
+	}
+}
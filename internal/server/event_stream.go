@@ -0,0 +1,124 @@
+/*
+ * Copyright 2026 Cong Wang
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package server
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/net/websocket"
+
+	"github.com/amtp-protocol/agentry/internal/events"
+)
+
+const (
+	// eventStreamBuffer is how many events a watcher may fall behind by
+	// before it is dropped
+	eventStreamBuffer = 256
+
+	// eventStreamWriteTimeout bounds sending one event, so a stalled
+	// connection is noticed even while no events are published
+	eventStreamWriteTimeout = 10 * time.Second
+)
+
+// eventStreamDropped is the last event a watcher gets when it was dropped
+// for falling behind
+const eventStreamDropped = "stream.dropped"
+
+// handleStreamEvents handles GET /v1/admin/events, a websocket streaming what
+// the gateway does with messages as JSON events, optionally only those for a
+// recipient or message_id. It is a debugging aid: events are not stored, so
+// only those published while connected are seen, and a watcher that cannot
+// keep up is disconnected rather than slowing deliveries.
+func (s *Server) handleStreamEvents(c *gin.Context) {
+	if s.events == nil {
+		s.respondWithError(c, http.StatusServiceUnavailable, "EVENT_STREAM_DISABLED",
+			"The event stream is disabled", nil)
+		return
+	}
+
+	filter := events.Filter{
+		Recipient: c.Query("recipient"),
+		MessageID: c.Query("message_id"),
+	}
+	if filter.Recipient != "" && !s.addresses.IsValid(filter.Recipient) && !strings.Contains(filter.Recipient, "@") {
+		filter.Recipient += "@" + s.config.Server.Domain
+	}
+
+	sub, err := s.events.Subscribe(filter)
+	if errors.Is(err, events.ErrTooManySubscribers) {
+		s.respondWithError(c, http.StatusServiceUnavailable, "TOO_MANY_EVENT_SUBSCRIBERS",
+			"Too many admins are watching the event stream", map[string]interface{}{
+				"max_subscribers": s.config.Server.EventStreamMaxSubscribers,
+			})
+		return
+	}
+	defer sub.Close()
+
+	// Admin authentication comes from a header, which browsers cannot set
+	// on a websocket, so there is no cross-site request to guard against
+	// with an Origin check
+	websocket.Server{Handler: func(ws *websocket.Conn) {
+		s.streamEvents(ws, sub)
+	}}.ServeHTTP(c.Writer, c.Request)
+}
+
+// streamEvents sends sub's events to ws until either side ends the stream
+func (s *Server) streamEvents(ws *websocket.Conn, sub *events.Subscription) {
+	defer ws.Close()
+
+	// The server's read and write timeouts are meant for requests, not for
+	// a connection that stays open
+	_ = ws.SetDeadline(time.Time{})
+
+	// Nothing is expected from the watcher; reading notices it leave
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		_, _ = io.Copy(io.Discard, ws)
+	}()
+
+	for {
+		select {
+		case <-closed:
+			return
+		case event, ok := <-sub.Events:
+			if !ok {
+				if sub.Dropped() {
+					s.sendEvent(ws, events.Event{
+						Type:  eventStreamDropped,
+						Time:  time.Now().UTC(),
+						Error: "the event stream fell behind and was dropped",
+					})
+				}
+				return
+			}
+			if err := s.sendEvent(ws, event); err != nil {
+				return
+			}
+		}
+	}
+}
+
+func (s *Server) sendEvent(ws *websocket.Conn, event events.Event) error {
+	_ = ws.SetWriteDeadline(time.Now().Add(eventStreamWriteTimeout))
+	return websocket.JSON.Send(ws, event)
+}
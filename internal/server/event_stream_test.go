@@ -0,0 +1,110 @@
+/*
+ * Copyright 2026 Cong Wang
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/net/websocket"
+
+	"github.com/amtp-protocol/agentry/internal/agents"
+	"github.com/amtp-protocol/agentry/internal/events"
+	"github.com/amtp-protocol/agentry/internal/types"
+)
+
+func dialEvents(t *testing.T, srv *httptest.Server, query string) (*websocket.Conn, error) {
+	t.Helper()
+	url := "ws" + strings.TrimPrefix(srv.URL, "http") + "/v1/admin/events" + query
+	return websocket.Dial(url, "", srv.URL)
+}
+
+func TestHandleStreamEvents(t *testing.T) {
+	server := createTestServer()
+	server.events = events.NewHub(1, 16)
+	srv := httptest.NewServer(server.router)
+	defer srv.Close()
+
+	ws, err := dialEvents(t, srv, "?recipient=testuser")
+	if err != nil {
+		t.Fatalf("Failed to open the event stream: %v", err)
+	}
+	defer ws.Close()
+
+	if _, err := dialEvents(t, srv, ""); err == nil {
+		t.Error("Expected a second watcher to be refused")
+	}
+
+	// Events for other recipients are filtered out
+	server.events.Publish(events.Event{Type: events.DeliveryAttempt, MessageID: "other", Recipient: "someone@localhost"})
+
+	ctx := context.Background()
+	if err := server.agentRegistry.RegisterAgent(ctx, &agents.LocalAgent{
+		Address:      "testuser",
+		DeliveryMode: "pull",
+		APIKey:       "valid-api-key",
+	}); err != nil {
+		t.Fatalf("Failed to register agent: %v", err)
+	}
+	messageID := "test-message-123"
+	server.storage.(*MockStorage).messages[messageID] = &types.Message{
+		MessageID:  messageID,
+		Recipients: []string{"testuser@localhost"},
+	}
+	req := httptest.NewRequest("DELETE", "/v1/inbox/testuser@localhost/"+messageID, nil)
+	req.Header.Set("Authorization", "Bearer valid-api-key")
+	w := httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+
+	_ = ws.SetReadDeadline(time.Now().Add(5 * time.Second))
+	var event events.Event
+	if err := websocket.JSON.Receive(ws, &event); err != nil {
+		t.Fatalf("Failed to receive an event: %v", err)
+	}
+	if event.Type != events.MessageAcknowledged || event.MessageID != messageID || event.Recipient != "testuser@localhost" {
+		t.Errorf("Unexpected event: %+v", event)
+	}
+
+	// Leaving frees the slot
+	ws.Close()
+	deadline := time.Now().Add(5 * time.Second)
+	for server.events.Subscribers() != 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if n := server.events.Subscribers(); n != 0 {
+		t.Errorf("Expected the watcher to be unsubscribed, got %d subscribers", n)
+	}
+}
+
+func TestHandleStreamEvents_Disabled(t *testing.T) {
+	server := createTestServer()
+
+	req := httptest.NewRequest("GET", "/v1/admin/events", nil)
+	w := httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusServiceUnavailable || !strings.Contains(w.Body.String(), "EVENT_STREAM_DISABLED") {
+		t.Errorf("Expected 503 EVENT_STREAM_DISABLED, got %d: %s", w.Code, w.Body.String())
+	}
+}
@@ -17,12 +17,16 @@
 package server
 
 import (
+	"bytes"
+	"context"
 	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"net/http"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -30,13 +34,26 @@ import (
 	"github.com/gin-gonic/gin"
 
 	"github.com/amtp-protocol/agentry/internal/agents"
+	"github.com/amtp-protocol/agentry/internal/config"
+	"github.com/amtp-protocol/agentry/internal/discovery"
+	"github.com/amtp-protocol/agentry/internal/events"
+	"github.com/amtp-protocol/agentry/internal/features"
+	"github.com/amtp-protocol/agentry/internal/inboxtoken"
+	"github.com/amtp-protocol/agentry/internal/metrics"
+	"github.com/amtp-protocol/agentry/internal/middleware"
 	"github.com/amtp-protocol/agentry/internal/processing"
 	"github.com/amtp-protocol/agentry/internal/schema"
+	"github.com/amtp-protocol/agentry/internal/signing"
 	"github.com/amtp-protocol/agentry/internal/storage"
 	"github.com/amtp-protocol/agentry/internal/types"
+	"github.com/amtp-protocol/agentry/internal/validation"
 	"github.com/amtp-protocol/agentry/pkg/uuid"
 )
 
+// idempotencyKeyHeader carries a client-supplied idempotency key when the
+// request body does not
+const idempotencyKeyHeader = "Idempotency-Key"
+
 // generateIdempotencyKey creates a deterministic idempotency key based on request content
 func generateIdempotencyKey(req *types.SendMessageRequest) string {
 	// Create a canonical representation of the request for hashing
@@ -51,6 +68,9 @@ func generateIdempotencyKey(req *types.SendMessageRequest) string {
 		ResponseType string                    `json:"response_type"`
 		InReplyTo    string                    `json:"in_reply_to"`
 		Attachments  []types.Attachment        `json:"attachments"`
+		Labels       []string                  `json:"labels,omitempty"`
+		Receipt      bool                      `json:"request_receipt,omitempty"`
+		Encrypted    bool                      `json:"encrypted,omitempty"`
 	}{
 		Sender:       req.Sender,
 		Recipients:   req.Recipients,
@@ -62,6 +82,9 @@ func generateIdempotencyKey(req *types.SendMessageRequest) string {
 		ResponseType: req.ResponseType,
 		InReplyTo:    req.InReplyTo,
 		Attachments:  req.Attachments,
+		Labels:       req.Labels,
+		Receipt:      req.RequestReceipt,
+		Encrypted:    req.Encrypted,
 	}
 
 	// Marshal to JSON for consistent hashing
@@ -82,49 +105,439 @@ func generateIdempotencyKey(req *types.SendMessageRequest) string {
 		hashHex[20:32]) // 12 chars
 }
 
-// handleSendMessage handles POST /v1/messages
-func (s *Server) handleSendMessage(c *gin.Context) {
-	timer := time.Now()
-	if s.metrics != nil {
-		s.metrics.IncMessagesInFlight()
-		defer s.metrics.DecMessagesInFlight()
+// Processing preferences a client may request with the Prefer header (RFC 7240)
+const (
+	preferRespondAsync = "respond-async"
+	preferRespondSync  = "respond-sync"
+)
+
+// responsePreference returns the respond-async or respond-sync preference
+// named in the request's Prefer headers, or "" if neither is present. When a
+// client sends both, respond-sync wins and the default behavior is kept.
+func responsePreference(c *gin.Context) string {
+	preference := ""
+	for _, header := range c.Request.Header.Values("Prefer") {
+		for _, pref := range strings.Split(header, ",") {
+			// Drop any value or parameters, e.g. "respond-async; wait=10"
+			token, _, _ := strings.Cut(pref, ";")
+			token, _, _ = strings.Cut(token, "=")
+			switch strings.ToLower(strings.TrimSpace(token)) {
+			case preferRespondSync:
+				return preferRespondSync
+			case preferRespondAsync:
+				preference = preferRespondAsync
+			}
+		}
 	}
-	var req types.SendMessageRequest
+	return preference
+}
 
-	// Parse request body
-	if err := c.ShouldBindJSON(&req); err != nil {
-		s.respondWithError(c, http.StatusBadRequest, "INVALID_REQUEST_FORMAT",
-			"Invalid request format", map[string]interface{}{
-				"parse_error": err.Error(),
-			})
-		return
+// sendError is a send refused before processing, with the error response
+// the HTTP API gives it. err, if set, is the underlying cause.
+type sendError struct {
+	status  int
+	code    string
+	message string
+	details map[string]interface{}
+	err     error
+}
+
+func (e *sendError) Error() string {
+	if e.err != nil {
+		return e.message + ": " + e.err.Error()
 	}
+	return e.message
+}
 
-	// Validate request
-	if err := s.validator.ValidateSendRequest(&req); err != nil {
-		s.respondWithError(c, http.StatusBadRequest, "VALIDATION_FAILED",
-			"Request validation failed", map[string]interface{}{
-				"validation_error": err.Error(),
+func (e *sendError) Unwrap() error {
+	return e.err
+}
+
+// respondWithSendError responds to a send refused with a *sendError, and to
+// any other error as an internal failure
+func (s *Server) respondWithSendError(c *gin.Context, err error) {
+	var refused *sendError
+	if !errors.As(err, &refused) {
+		s.respondWithError(c, http.StatusInternalServerError, "PROCESSING_FAILED",
+			"Message processing failed", map[string]interface{}{
+				"processing_error": err.Error(),
 			})
 		return
 	}
+	s.respondWithError(c, refused.status, refused.code, refused.message, refused.details)
+}
+
+// sendOrigin is what an entry point knows about who handed it a message
+type sendOrigin struct {
+	authenticatedDomains []string // Domains the client verified, if any
+	requestSigner        string   // Domain that signed a relayed request, if any
+}
+
+// checkSenderDomain refuses a send whose sender domain is not one the client
+// authenticated as. Sends without a verified domain, such as API key or
+// unauthenticated ones, are not checked.
+func (s *Server) checkSenderDomain(domains []string, sender string) error {
+	if !s.config.Auth.EnforceSenderDomain || len(domains) == 0 {
+		return nil
+	}
+
+	senderDomain := s.addressDomain(sender)
+	for _, domain := range domains {
+		if domainMatches(domain, senderDomain) {
+			return nil
+		}
+	}
+
+	return &sendError{
+		status:  http.StatusForbidden,
+		code:    "SENDER_DOMAIN_MISMATCH",
+		message: "Sender domain does not match the authenticated domain",
+		details: map[string]interface{}{
+			"sender_domain":         senderDomain,
+			"authenticated_domains": domains,
+		},
+	}
+}
+
+// checkTimestamp rejects a relayed message whose timestamp is further from
+// the gateway's clock than the configured skew, so a remote sender cannot
+// backdate or postdate messages to game ordering or expiry. Local senders
+// are authenticated by this gateway and not checked. Messages without a
+// timestamp are stamped with server time and always pass.
+func (s *Server) checkTimestamp(message *types.Message) error {
+	maxSkew := s.config.Message.MaxTimestampSkew
+	if !s.config.Message.CheckTimestamps || maxSkew <= 0 || s.isLocalAddress(message.Sender) {
+		return nil
+	}
+
+	now := time.Now().UTC()
+	skew := now.Sub(message.Timestamp)
+	if skew >= -maxSkew && skew <= maxSkew {
+		return nil
+	}
+
+	return &sendError{
+		status:  http.StatusBadRequest,
+		code:    "TIMESTAMP_OUT_OF_RANGE",
+		message: "Message timestamp is too far from the gateway's clock",
+		details: map[string]interface{}{
+			"timestamp":   message.Timestamp.Format(time.RFC3339),
+			"server_time": now.Format(time.RFC3339),
+			"max_skew":    maxSkew.String(),
+		},
+	}
+}
+
+// checkReplyDepth refuses a reply that would make its in_reply_to chain
+// longer than the configured limit. The chain is followed through stored messages only as far as the limit, and
+// ends at a parent this gateway does not have, such as a workflow ID.
+func (s *Server) checkReplyDepth(ctx context.Context, inReplyTo string) error {
+	maxDepth := s.config.Message.MaxReplyDepth
+	if maxDepth <= 0 || inReplyTo == "" {
+		return nil
+	}
+
+	depth := 1
+	for parentID := inReplyTo; depth <= maxDepth; depth++ {
+		parent, err := s.storage.GetMessage(ctx, parentID)
+		if err != nil || parent.InReplyTo == "" {
+			return nil
+		}
+		parentID = parent.InReplyTo
+	}
+
+	return &sendError{
+		status:  http.StatusBadRequest,
+		code:    "THREAD_TOO_DEEP",
+		message: "Reply chain exceeds the maximum depth",
+		details: map[string]interface{}{
+			"in_reply_to": inReplyTo,
+			"max_depth":   maxDepth,
+		},
+	}
+}
+
+// normalizeRecipients puts the recipients and the addresses coordination
+// refers to in canonical form and drops repeated recipients, so each is
+// delivered to once whatever case the sender used
+func (s *Server) normalizeRecipients(req *types.SendMessageRequest) {
+	var duplicates []string
+	req.Recipients, duplicates = types.NormalizeRecipients(req.Recipients)
+	if len(duplicates) > 0 {
+		s.logger.Warnf("Dropped duplicate recipients %v from message from %s", duplicates, req.Sender)
+	}
+	if req.Coordination != nil {
+		req.Coordination.NormalizeAddresses()
+	}
+}
+
+// resolveSender fills in an omitted sender with the agent whose API key the
+// request carries, and refuses an explicit sender other than that agent
+// unless the request also presents an admin key. A bearer token that is not
+// an agent key, such as an OAuth token, leaves the sender alone.
+func (s *Server) resolveSender(c *gin.Context, req *types.SendMessageRequest) bool {
+	apiKey, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+	if !ok || apiKey == "" {
+		return true
+	}
+
+	agent, err := s.agentRegistry.ResolveAPIKey(c.Request.Context(), apiKey)
+	if errors.Is(err, agents.ErrAgentNotFound) {
+		return true
+	}
+	if err != nil {
+		s.respondWithError(c, http.StatusInternalServerError, "AGENT_LOOKUP_FAILED",
+			"Failed to look up the agent for the API key", nil)
+		return false
+	}
+
+	if req.Sender == "" {
+		req.Sender = agent.Address
+		return true
+	}
+	if strings.EqualFold(req.Sender, agent.Address) || middleware.HasAdminKey(s.config.Auth, c) {
+		return true
+	}
+	s.respondWithError(c, http.StatusForbidden, "SENDER_MISMATCH",
+		"Sender does not match the agent the API key belongs to", map[string]interface{}{
+			"sender": req.Sender,
+			"agent":  agent.Address,
+		})
+	return false
+}
+
+// signaturesRequired reports whether unsigned messages from other domains
+// are rejected, by configuration or by the required_signatures flag
+func (s *Server) signaturesRequired() bool {
+	return s.config.Auth.SignatureVerification == config.SignatureVerificationRequired ||
+		features.Enabled(features.RequiredSignatures)
+}
+
+// dropStaleSignature removes a signature that changes made since it was
+// verified have broken, since relaying it on would pass the changes off as
+// the sender's. signed is the canonical form the signature was verified
+// against.
+func dropStaleSignature(message *types.Message, signed []byte) *types.Warning {
+	if message.Signature == nil || signed == nil {
+		return nil
+	}
+	if current, err := signing.Canonicalize(message); err == nil && bytes.Equal(current, signed) {
+		return nil
+	}
+	message.Signature = nil
+	return &types.Warning{
+		Code:    "SIGNATURE_REMOVED",
+		Message: "The message was changed after its signature was verified, so the signature was removed",
+	}
+}
+
+// signatureVerificationOn reports whether message signatures are checked
+func (s *Server) signatureVerificationOn() bool {
+	mode := s.config.Auth.SignatureVerification
+	return mode == config.SignatureVerificationOptional || mode == config.SignatureVerificationRequired
+}
+
+// verifySignature checks a message's signature against the sender domain's
+// published key. Under the required mode, unsigned messages from other
+// domains are refused as well. requestSigner is the domain that signed the
+// request carrying the message, if any, which must be the sender's domain so
+// one domain cannot replay a captured relay for another.
+func (s *Server) verifySignature(ctx context.Context, message *types.Message, requestSigner string) error {
+	if senderDomain := s.addressDomain(message.Sender); requestSigner != "" && !strings.EqualFold(requestSigner, senderDomain) {
+		return &sendError{
+			status:  http.StatusUnauthorized,
+			code:    "INVALID_SIGNATURE",
+			message: "Request was not signed by the sender's domain",
+			details: map[string]interface{}{
+				"sender_domain":  senderDomain,
+				"request_signer": requestSigner,
+			},
+		}
+	}
+	if s.signatures == nil {
+		// Verification is on but keys cannot be looked up, so nothing that
+		// must be verified gets through
+		if !s.signatureVerificationOn() || !s.signaturesRequired() || s.isLocalAddress(message.Sender) {
+			return nil
+		}
+		return &sendError{
+			status:  http.StatusUnauthorized,
+			code:    "INVALID_SIGNATURE",
+			message: "Signatures from other domains cannot be verified",
+			details: map[string]interface{}{
+				"sender_domain": s.addressDomain(message.Sender),
+			},
+		}
+	}
+	if message.Signature == nil {
+		if !s.signaturesRequired() || s.isLocalAddress(message.Sender) {
+			return nil
+		}
+		return &sendError{
+			status:  http.StatusUnauthorized,
+			code:    "INVALID_SIGNATURE",
+			message: "Messages from other domains must be signed",
+			details: map[string]interface{}{
+				"sender_domain": s.addressDomain(message.Sender),
+			},
+		}
+	}
+
+	if err := s.signatures.Verify(ctx, message); err != nil {
+		return &sendError{
+			status:  http.StatusUnauthorized,
+			code:    "INVALID_SIGNATURE",
+			message: "Message signature verification failed",
+			details: map[string]interface{}{
+				"reason": err.Error(),
+			},
+		}
+	}
+
+	// A relayed request's replay headers only hold when a gateway signed
+	// them, so with both checks mandatory the request must be signed too
+	if s.nonces != nil && s.signaturesRequired() && requestSigner == "" &&
+		!s.isLocalAddress(message.Sender) {
+		return &sendError{
+			status:  http.StatusUnauthorized,
+			code:    "INVALID_SIGNATURE",
+			message: "Relayed requests must be signed",
+			details: map[string]interface{}{
+				"header": signing.RequestSignatureHeader,
+			},
+		}
+	}
+	return nil
+}
+
+// requestSignerKey is the context key of the domain that signed a request
+const requestSignerKey = "request_signer"
+
+// requestSignature checks the signature a relaying gateway puts over a
+// request's replay headers and body, and records the signing domain.
+// Requests without one pass through.
+func (s *Server) requestSignature() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		header := c.GetHeader(signing.RequestSignatureHeader)
+		if header == "" {
+			c.Next()
+			return
+		}
+
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			s.respondWithError(c, http.StatusBadRequest, "INVALID_REQUEST_FORMAT",
+				"Invalid request format", map[string]interface{}{
+					"parse_error": err.Error(),
+				})
+			c.Abort()
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		domain, err := s.signatures.VerifyRequest(c.Request.Context(), header, c.Request.Method, c.Request.URL.Path,
+			c.GetHeader(signing.TimestampHeader), c.GetHeader(signing.NonceHeader), body)
+		if err != nil {
+			s.respondWithError(c, http.StatusUnauthorized, "INVALID_SIGNATURE",
+				"Request signature verification failed", map[string]interface{}{
+					"reason": err.Error(),
+				})
+			c.Abort()
+			return
+		}
+		c.Set(requestSignerKey, domain)
+		c.Next()
+	}
+}
+
+// isLocalAddress reports whether address is in the local domain or of a
+// local-only address scheme
+func (s *Server) isLocalAddress(address string) bool {
+	domain, ok := s.addresses.Domain(address, s.config.Server.Domain)
+	return ok && strings.EqualFold(domain, s.config.Server.Domain)
+}
+
+// addressDomain returns the lowercase domain of address, the local domain
+// for addresses of local-only schemes
+func (s *Server) addressDomain(address string) string {
+	if domain, ok := s.addresses.Domain(address, s.config.Server.Domain); ok {
+		return strings.ToLower(domain)
+	}
+	return strings.ToLower(address[strings.LastIndex(address, "@")+1:])
+}
+
+// domainMatches reports whether an authenticated domain covers domain. A
+// wildcard such as "*.example.com" covers exactly one extra label.
+func domainMatches(authenticated, domain string) bool {
+	if suffix, ok := strings.CutPrefix(authenticated, "*."); ok {
+		label, rest, found := strings.Cut(domain, ".")
+		return found && label != "" && rest == suffix
+	}
+	return authenticated == domain
+}
+
+// retryPolicy resolves a send request's delivery retry settings: the
+// configured defaults, overridden by the request and clamped to the
+// configured limits. The request has already been validated.
+func (s *Server) retryPolicy(req *types.SendMessageRequest) (int, time.Duration) {
+	limits := s.config.Delivery
+
+	maxRetries := limits.MaxRetries
+	if req.MaxRetries > 0 {
+		maxRetries = req.MaxRetries
+		if limits.MaxRetriesLimit > 0 && maxRetries > limits.MaxRetriesLimit {
+			maxRetries = limits.MaxRetriesLimit
+		}
+	}
+
+	retryDelay := limits.RetryDelay
+	if delay, err := time.ParseDuration(req.RetryDelay); err == nil && delay > 0 {
+		retryDelay = delay
+		if limits.RetryDelayLimit > 0 && retryDelay > limits.RetryDelayLimit {
+			retryDelay = limits.RetryDelayLimit
+		}
+	}
+
+	return maxRetries, retryDelay
+}
+
+// defaultProcessingTimeout bounds message processing when the server has no
+// write timeout configured
+const defaultProcessingTimeout = 30 * time.Second
+
+// processingTimeout returns how long a request may spend processing: the
+// time left before the request deadline, capped by the write timeout since
+// no response can be written after it
+func (s *Server) processingTimeout(ctx context.Context) time.Duration {
+	timeout := defaultProcessingTimeout
+	if s.config.Server.WriteTimeout > 0 {
+		timeout = s.config.Server.WriteTimeout
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		if remaining := time.Until(deadline); remaining < timeout {
+			timeout = remaining
+		}
+	}
+	return timeout
+}
 
-	// Generate message ID and deterministic idempotency key
+// newMessage builds an AMTP message from a validated send request, generating
+// the message ID with ids and deriving an idempotency key when the request
+// has none
+func newMessage(ids uuid.Generator, req *types.SendMessageRequest) (*types.Message, error) {
 	messageID := req.MessageID
 	if messageID == "" {
 		var err error
-		messageID, err = uuid.GenerateV7()
+		messageID, err = ids.Generate()
 		if err != nil {
-			s.respondWithError(c, http.StatusInternalServerError, "ID_GENERATION_FAILED",
-				"Failed to generate message ID", nil)
-			return
+			return nil, fmt.Errorf("failed to generate message ID: %w", err)
 		}
 	}
 
-	// Generate deterministic idempotency key based on request content
+	// Without a client-supplied key, derive one from the request content
 	idempotencyKey := req.IdempotencyKey
 	if idempotencyKey == "" {
-		idempotencyKey = generateIdempotencyKey(&req)
+		idempotencyKey = generateIdempotencyKey(req)
 	}
 
 	timestamp := time.Now().UTC()
@@ -134,8 +547,7 @@ func (s *Server) handleSendMessage(c *gin.Context) {
 		}
 	}
 
-	// Create AMTP message
-	message := &types.Message{
+	return &types.Message{
 		Version:        "1.0",
 		MessageID:      messageID,
 		IdempotencyKey: idempotencyKey,
@@ -150,17 +562,188 @@ func (s *Server) handleSendMessage(c *gin.Context) {
 		ResponseType:   req.ResponseType,
 		InReplyTo:      req.InReplyTo,
 		Attachments:    req.Attachments,
+		Signature:      req.Signature,
+		Labels:         req.Labels,
+		RequestReceipt: req.RequestReceipt,
+		Encrypted:      req.Encrypted,
+	}, nil
+}
+
+// acceptMessage runs the checks every send goes through, whichever entry
+// point received it, and builds the message to process from req. A refused
+// send is a *sendError.
+func (s *Server) acceptMessage(ctx context.Context, req *types.SendMessageRequest, origin sendOrigin) (*types.Message, []types.Warning, error) {
+	// A signature covers the addresses as sent, so keep them to verify it
+	sentRecipients, sentCoordination := req.Recipients, req.Coordination.Clone()
+	s.normalizeRecipients(req)
+
+	// Validate request
+	if err := s.validator.ValidateSendRequest(req); err != nil {
+		code := "VALIDATION_FAILED"
+		switch {
+		case errors.Is(err, validation.ErrTooManyAttachments):
+			code = "TOO_MANY_ATTACHMENTS"
+		case errors.Is(err, validation.ErrAttachmentsTooLarge):
+			code = "ATTACHMENTS_TOO_LARGE"
+		case errors.Is(err, validation.ErrPayloadTooComplex):
+			code = "PAYLOAD_TOO_COMPLEX"
+		}
+		return nil, nil, &sendError{
+			status:  http.StatusBadRequest,
+			code:    code,
+			message: "Request validation failed",
+			details: map[string]interface{}{
+				"validation_error": err.Error(),
+			},
+			err: err,
+		}
+	}
+
+	if err := s.checkSenderDomain(origin.authenticatedDomains, req.Sender); err != nil {
+		return nil, nil, err
+	}
+
+	if err := s.checkReplyDepth(ctx, req.InReplyTo); err != nil {
+		return nil, nil, err
+	}
+
+	message, err := newMessage(s.messageIDs, req)
+	if err != nil {
+		return nil, nil, &sendError{
+			status:  http.StatusInternalServerError,
+			code:    "ID_GENERATION_FAILED",
+			message: "Failed to generate message ID",
+			err:     err,
+		}
+	}
+
+	if err := s.checkTimestamp(message); err != nil {
+		return nil, nil, err
+	}
+
+	// Verify the message as sent, before anything below changes it. A
+	// signature normalizing the addresses broke is dropped further down.
+	sent := *message
+	sent.Recipients, sent.Coordination = sentRecipients, sentCoordination
+	if err := s.verifySignature(ctx, &sent, origin.requestSigner); err != nil {
+		return nil, nil, err
+	}
+	var signed []byte
+	if message.Signature != nil {
+		signed, _ = signing.Canonicalize(&sent)
+	}
+
+	// Detect the schema of a schemaless payload before validation, so a
+	// sender refused by schema-requiring agents learns which schema to set.
+	// Encrypted payloads are never read, so neither applies to them.
+	var warnings []types.Warning
+	if !message.Encrypted {
+		warnings = s.detectSchema(ctx, message)
+		warning, err := s.checkSchemaAvailable(message)
+		if err != nil {
+			return nil, nil, err
+		}
+		if warning != nil {
+			warnings = append(warnings, *warning)
+		}
+	}
+
+	// Run the policy check before validation, so changes it makes are
+	// validated too
+	if err := s.checkAcceptHook(ctx, message); err != nil {
+		return nil, nil, err
+	}
+
+	// The schema's defaults fill in what the sender left unset, before
+	// validation so defaulted coordination is checked like the sender's own
+	if s.schemaManager != nil {
+		s.schemaManager.ApplyDefaults(ctx, message)
+	}
+
+	if warning := dropStaleSignature(message, signed); warning != nil {
+		warnings = append(warnings, *warning)
 	}
 
 	// Validate the complete message
-	if err := s.validator.ValidateMessage(message); err != nil {
-		s.respondWithError(c, http.StatusBadRequest, "MESSAGE_VALIDATION_FAILED",
-			"Message validation failed", map[string]interface{}{
-				"validation_error": err.Error(),
+	validationCtx := schema.WithValidationMode(ctx, req.ValidationMode)
+	if err := s.validator.ValidateMessageWithContext(validationCtx, message); err != nil {
+		details := map[string]interface{}{
+			"validation_error": err.Error(),
+		}
+		if len(warnings) > 0 {
+			details["warnings"] = warnings
+		}
+		code := "MESSAGE_VALIDATION_FAILED"
+		if errors.Is(err, validation.ErrPayloadTooComplex) {
+			code = "PAYLOAD_TOO_COMPLEX"
+		}
+		return nil, nil, &sendError{
+			status:  http.StatusBadRequest,
+			code:    code,
+			message: "Message validation failed",
+			details: details,
+			err:     err,
+		}
+	}
+
+	return message, warnings, nil
+}
+
+// handleSendMessage handles POST /v1/messages
+func (s *Server) handleSendMessage(c *gin.Context) {
+	timer := time.Now()
+	if s.metrics != nil {
+		s.metrics.IncMessagesInFlight()
+		defer s.metrics.DecMessagesInFlight()
+	}
+	var req types.SendMessageRequest
+
+	// Keep the body as sent before binding consumes it
+	var rawBody []byte
+	if s.rawRequests != nil {
+		var err error
+		if rawBody, err = s.rawRequests.readBody(c); err != nil {
+			s.respondWithError(c, http.StatusBadRequest, "INVALID_REQUEST_FORMAT",
+				"Invalid request format", map[string]interface{}{
+					"parse_error": err.Error(),
+				})
+			return
+		}
+	}
+
+	// Parse request body
+	if err := c.ShouldBindJSON(&req); err != nil {
+		s.respondWithError(c, http.StatusBadRequest, "INVALID_REQUEST_FORMAT",
+			"Invalid request format", map[string]interface{}{
+				"parse_error": err.Error(),
+			})
+		return
+	}
+
+	// The body field takes precedence over the Idempotency-Key header
+	if req.IdempotencyKey == "" {
+		req.IdempotencyKey = strings.TrimSpace(c.GetHeader(idempotencyKeyHeader))
+	}
+	if req.IdempotencyKey == "" && s.config.Message.RequireIdempotencyKey {
+		s.respondWithError(c, http.StatusBadRequest, "IDEMPOTENCY_KEY_REQUIRED",
+			"An idempotency key is required", map[string]interface{}{
+				"hint": "Set the idempotency_key field or the " + idempotencyKeyHeader + " header to a UUIDv4",
 			})
 		return
 	}
 
+	if !s.resolveSender(c, &req) {
+		return
+	}
+	message, warnings, err := s.acceptMessage(c.Request.Context(), &req, sendOrigin{
+		authenticatedDomains: c.GetStringSlice("authenticated_domains"),
+		requestSigner:        c.GetString(requestSignerKey),
+	})
+	if err != nil {
+		s.respondWithSendError(c, err)
+		return
+	}
+
 	// Intercept workflow responses.
 	//
 	// If this gateway created the workflow (shared-DB deployment) or is the sole
@@ -201,21 +784,39 @@ func (s *Server) handleSendMessage(c *gin.Context) {
 	}
 
 	// Is sender a local user?
-	senderDomain := ""
-	parts := strings.Split(message.Sender, "@")
-	if len(parts) == 2 {
-		senderDomain = parts[1]
-	}
-	isSenderLocal := strings.EqualFold(senderDomain, s.config.Server.Domain)
+	isSenderLocal := s.isLocalAddress(message.Sender)
 
-	// Process message using the message processor
+	// Process message using the message processor. Prefer: respond-async
+	// skips waiting for delivery, whatever the recipients' locality.
+	preference := responsePreference(c)
+	maxRetries, retryDelay := s.retryPolicy(&req)
 	processingOptions := processing.ProcessingOptions{
 		ImmediatePath: message.Coordination == nil || !isSenderLocal,
-		Timeout:       30 * time.Second,
-		MaxRetries:    3,
+		Timeout:       s.processingTimeout(c.Request.Context()),
+		MaxRetries:    maxRetries,
+		RetryDelay:    retryDelay,
+		Async:         preference == preferRespondAsync,
 	}
 
 	result, err := s.processor.ProcessMessage(c.Request.Context(), message, processingOptions)
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled) {
+		s.respondWithError(c, http.StatusGatewayTimeout, "TIMEOUT",
+			"Message processing did not finish before the request deadline", map[string]interface{}{
+				"processing_error": err.Error(),
+			})
+		return
+	}
+	if status, code, ok := coordinationLimitError(err); ok {
+		s.respondWithError(c, status, code, "Coordinated message exceeds the gateway's coordination limits",
+			map[string]interface{}{
+				"processing_error": err.Error(),
+			})
+		return
+	}
+	if errors.Is(err, processing.ErrMessageNotStored) && storage.IsUnavailable(err) && s.writeBuffer != nil {
+		s.respondBuffered(c, message, processingOptions, warnings, err)
+		return
+	}
 	if err != nil {
 		s.respondWithError(c, http.StatusInternalServerError, "PROCESSING_FAILED",
 			"Message processing failed", map[string]interface{}{
@@ -224,32 +825,24 @@ func (s *Server) handleSendMessage(c *gin.Context) {
 		return
 	}
 
-	// Determine response status based on processing result
-	var httpStatus int
-	var status string
-	switch result.Status {
-	case types.StatusDelivered:
-		httpStatus = http.StatusOK
-		status = "delivered"
-	case types.StatusDelivering:
-		httpStatus = http.StatusAccepted
-		status = "delivering"
-	case types.StatusQueued:
-		httpStatus = http.StatusAccepted
-		status = "queued"
-	case types.StatusFailed:
-		httpStatus = http.StatusBadRequest
-		status = "failed"
-	default:
-		httpStatus = http.StatusAccepted
-		status = "accepted"
+	// A repeated idempotency key returns the earlier message, which already
+	// has its raw request
+	if s.rawRequests != nil && result.MessageID == message.MessageID {
+		raw := s.rawRequests.build(message.MessageID, c.Request.Header, rawBody, timer.UTC())
+		if err := s.storage.StoreRawRequest(c.Request.Context(), raw); err != nil {
+			s.logger.Warnf("Failed to store the raw request of message %s: %v", message.MessageID, err)
+		}
 	}
 
+	httpStatus, status, partial := resultStatus(result)
+
 	// Return response
 	response := types.SendMessageResponse{
 		MessageID:  result.MessageID,
 		Status:     status,
+		Partial:    partial,
 		Recipients: result.Recipients,
+		Warnings:   warnings,
 	}
 
 	// Record message processing metrics
@@ -263,14 +856,22 @@ func (s *Server) handleSendMessage(c *gin.Context) {
 			string(result.Status),
 			coordinationType,
 			time.Since(timer),
-			message.Size(),
+			metrics.MessageSize{
+				Payload:     message.PayloadSize(),
+				Attachments: message.AttachmentsSize(),
+				Envelope:    message.EnvelopeSize(),
+			},
 			message.Schema,
 		)
 	}
 
+	if preference != "" {
+		c.Header("Preference-Applied", preference)
+	}
+
 	// Log message processing
 	s.logger.LogMessageProcessing(
-		messageID,
+		message.MessageID,
 		"send",
 		string(result.Status),
 		func() *time.Duration { d := time.Since(timer); return &d }(),
@@ -280,35 +881,196 @@ func (s *Server) handleSendMessage(c *gin.Context) {
 	s.respondWithSuccess(c, httpStatus, response)
 }
 
-// handleGetMessage handles GET /v1/messages/:id
-func (s *Server) handleGetMessage(c *gin.Context) {
-	messageID := c.Param("id")
-
-	// Validate message ID format
-	if !uuid.IsValidV7(messageID) {
-		s.respondWithError(c, http.StatusBadRequest, "INVALID_MESSAGE_ID",
-			"Invalid message ID format", nil)
+// respondBuffered accepts a message storage refused into the write buffer
+// and reports it as queued, or rejects it when the buffer is full
+func (s *Server) respondBuffered(c *gin.Context, message *types.Message, options processing.ProcessingOptions, warnings []types.Warning, storeErr error) {
+	messageID, ok := s.writeBuffer.Add(message, options)
+	if !ok {
+		c.Header("Retry-After", "1")
+		s.respondWithError(c, http.StatusServiceUnavailable, "STORAGE_UNAVAILABLE",
+			"Storage is unavailable and the write buffer is full", map[string]interface{}{
+				"processing_error": storeErr.Error(),
+			})
 		return
 	}
+	s.logger.Warnf("Buffered message %s after storage refused it: %v", messageID, storeErr)
 
-	// Retrieve message from storage
-	message, err := s.storage.GetMessage(c.Request.Context(), messageID)
-	if err != nil {
-		s.respondWithError(c, http.StatusNotFound, "MESSAGE_NOT_FOUND",
-			"Message not found", nil)
-		return
+	response := types.SendMessageResponse{
+		MessageID:  messageID,
+		Status:     "queued",
+		Recipients: make([]types.RecipientStatus, len(message.Recipients)),
+		Warnings:   warnings,
+	}
+	now := time.Now().UTC()
+	for i, recipient := range message.Recipients {
+		response.Recipients[i] = types.RecipientStatus{
+			Address:   recipient,
+			Status:    types.StatusQueued,
+			Timestamp: now,
+		}
 	}
-
-	s.respondWithSuccess(c, http.StatusOK, message)
+	s.respondWithSuccess(c, http.StatusAccepted, response)
 }
 
-// handleGetMessageStatus handles GET /v1/messages/:id/status
-func (s *Server) handleGetMessageStatus(c *gin.Context) {
-	messageID := c.Param("id")
+// coordinationLimitError maps a processing error from the coordination
+// limits to the HTTP status and error code reported to the sender
+func coordinationLimitError(err error) (int, string, bool) {
+	switch {
+	case errors.Is(err, processing.ErrTooManyCoordinatedRecipients):
+		return http.StatusBadRequest, "TOO_MANY_COORDINATED_RECIPIENTS", true
+	case errors.Is(err, processing.ErrCoordinationTimeoutTooLong):
+		return http.StatusBadRequest, "COORDINATION_TIMEOUT_TOO_LONG", true
+	case errors.Is(err, processing.ErrTooManyCoordinations):
+		return http.StatusServiceUnavailable, "COORDINATION_CAPACITY_EXCEEDED", true
+	}
+	return 0, "", false
+}
 
-	// Validate message ID format
-	if !uuid.IsValidV7(messageID) {
-		s.respondWithError(c, http.StatusBadRequest, "INVALID_MESSAGE_ID",
+// resultStatus maps a processing result to the HTTP status and status name
+// reported to the sender
+func resultStatus(result *processing.ProcessingResult) (httpStatus int, status string, partial bool) {
+	switch result.Status {
+	case types.StatusDelivered:
+		httpStatus = http.StatusOK
+		status = "delivered"
+	case types.StatusDelivering:
+		httpStatus = http.StatusAccepted
+		status = "delivering"
+	case types.StatusQueued:
+		httpStatus = http.StatusAccepted
+		status = "queued"
+	case types.StatusFailed:
+		httpStatus = http.StatusBadRequest
+		status = "failed"
+	default:
+		httpStatus = http.StatusAccepted
+		status = "accepted"
+	}
+
+	// Some recipients failed while others did not: neither a success nor a
+	// plain failure, so say so at the top level
+	partial = hasMixedOutcomes(result.Recipients)
+	if partial {
+		httpStatus = http.StatusMultiStatus
+		status = "partial"
+	}
+	return httpStatus, status, partial
+}
+
+// detectSchema annotates a schemaless message with the registered schema its
+// payload matches when schema auto-detection is enabled. The outcome is only
+// a hint: it is returned as warnings and the message's schema stays empty.
+func (s *Server) detectSchema(ctx context.Context, message *types.Message) []types.Warning {
+	if !s.config.Message.SchemaAutoDetect || s.schemaManager == nil || message.Schema != "" || len(message.Payload) == 0 {
+		return nil
+	}
+
+	matches, err := s.schemaManager.DetectSchemas(ctx, message.Payload)
+	if err != nil {
+		s.logger.WithContext(ctx).Error("Schema auto-detection failed", err)
+		return nil
+	}
+
+	switch len(matches) {
+	case 0:
+		return nil
+	case 1:
+		// Headers are signed, so a signed message is left as sent
+		detected := matches[0].String()
+		if message.Signature == nil {
+			if message.Headers == nil {
+				message.Headers = make(map[string]interface{})
+			}
+			message.Headers[types.DetectedSchemaHeader] = detected
+		}
+		return []types.Warning{{
+			Code:    "SCHEMA_DETECTED",
+			Message: "No schema was given; the payload matches " + detected,
+			Value:   detected,
+		}}
+	default:
+		candidates := make([]string, len(matches))
+		for i, match := range matches {
+			candidates[i] = match.String()
+		}
+		return []types.Warning{{
+			Code:    "SCHEMA_AMBIGUOUS",
+			Message: "No schema was given; the payload matches several registered schemas",
+			Value:   candidates,
+		}}
+	}
+}
+
+// checkSchemaAvailable applies the configured policy to a message that names
+// a schema the gateway cannot validate against because schema management is
+// not configured. Strict mode, or the strict_schema flag, refuses the
+// message; lenient mode accepts it and returns a warning saying the payload
+// went unvalidated.
+func (s *Server) checkSchemaAvailable(message *types.Message) (*types.Warning, error) {
+	if s.schemaManager != nil || message.Schema == "" {
+		return nil, nil
+	}
+
+	if s.config.Message.SchemaUnavailable == config.SchemaUnavailableStrict || features.Enabled(features.StrictSchema) {
+		return nil, &sendError{
+			status:  http.StatusServiceUnavailable,
+			code:    "SCHEMA_MANAGER_UNAVAILABLE",
+			message: "Schema management is not configured, so messages with a schema cannot be validated",
+			details: map[string]interface{}{
+				"schema": message.Schema,
+			},
+		}
+	}
+
+	return &types.Warning{
+		Code:    "SCHEMA_NOT_VALIDATED",
+		Message: "Schema management is not configured; the payload was not validated against " + message.Schema,
+		Value:   message.Schema,
+	}, nil
+}
+
+// hasMixedOutcomes reports whether some recipients failed and others did not
+func hasMixedOutcomes(recipients []types.RecipientStatus) bool {
+	var failed, other bool
+	for _, recipient := range recipients {
+		if recipient.Status == types.StatusFailed {
+			failed = true
+		} else {
+			other = true
+		}
+	}
+	return failed && other
+}
+
+// handleGetMessage handles GET /v1/messages/:id
+func (s *Server) handleGetMessage(c *gin.Context) {
+	messageID := c.Param("id")
+
+	// Validate message ID format
+	if !s.messageIDs.IsValid(messageID) {
+		s.respondWithError(c, http.StatusBadRequest, "INVALID_MESSAGE_ID",
+			"Invalid message ID format", nil)
+		return
+	}
+
+	// Retrieve message from storage
+	message, err := s.storage.GetMessage(c.Request.Context(), messageID)
+	if err != nil {
+		s.respondWithError(c, http.StatusNotFound, "MESSAGE_NOT_FOUND",
+			"Message not found", nil)
+		return
+	}
+
+	s.respondWithSuccess(c, http.StatusOK, message)
+}
+
+// handleGetMessageStatus handles GET /v1/messages/:id/status
+func (s *Server) handleGetMessageStatus(c *gin.Context) {
+	messageID := c.Param("id")
+
+	// Validate message ID format
+	if !s.messageIDs.IsValid(messageID) {
+		s.respondWithError(c, http.StatusBadRequest, "INVALID_MESSAGE_ID",
 			"Invalid message ID format", nil)
 		return
 	}
@@ -321,16 +1083,54 @@ func (s *Server) handleGetMessageStatus(c *gin.Context) {
 		return
 	}
 
-	s.respondWithSuccess(c, http.StatusOK, status)
+	s.respondWithSuccess(c, http.StatusOK, s.withLabels(c.Request.Context(), status))
+}
+
+// handleGetMessageStatusByKey handles GET /v1/messages/by-key/:key, for
+// clients that kept the idempotency key of a send but lost its message ID
+func (s *Server) handleGetMessageStatusByKey(c *gin.Context) {
+	idempotencyKey := c.Param("key")
+
+	// Client-supplied and derived keys are both UUIDv4-shaped
+	if !uuid.IsValidV4(idempotencyKey) {
+		s.respondWithError(c, http.StatusBadRequest, "INVALID_IDEMPOTENCY_KEY",
+			"Invalid idempotency key format", nil)
+		return
+	}
+
+	status, err := s.storage.GetStatusByIdempotencyKey(c.Request.Context(), idempotencyKey)
+	if err != nil {
+		s.respondWithError(c, http.StatusNotFound, "MESSAGE_NOT_FOUND",
+			"Message status not found", nil)
+		return
+	}
+
+	s.respondWithSuccess(c, http.StatusOK, s.withLabels(c.Request.Context(), status))
 }
 
-// handleListMessages handles GET /v1/messages
+// withLabels adds the labels of the message to its status, which does not
+// store them, and hides the archive copy from the recipients
+func (s *Server) withLabels(ctx context.Context, status *types.MessageStatus) *types.MessageStatus {
+	status.Recipients = withoutRecipient(status.Recipients, s.config.Message.ArchiveAddress)
+	if message, err := s.storage.GetMessage(ctx, status.MessageID); err == nil {
+		status.Labels = message.Labels
+	}
+	return status
+}
+
+// maxFullListLimit caps the page size of GET /v1/messages?include=full,
+// whose entries carry payloads and recipient statuses
+const maxFullListLimit = 100
+
+// handleListMessages handles GET /v1/admin/messages
 func (s *Server) handleListMessages(c *gin.Context) {
 	// Parse query parameters
 	status := c.Query("status")
 	sender := c.Query("sender")
 	recipient := c.Query("recipient")
+	labels := c.QueryArray("label")
 	since := c.Query("since")
+	include := c.Query("include")
 	limitStr := c.DefaultQuery("limit", "100")
 	offsetStr := c.DefaultQuery("offset", "0")
 
@@ -349,6 +1149,17 @@ func (s *Server) handleListMessages(c *gin.Context) {
 		return
 	}
 
+	// Full messages carry their payloads, so fewer fit in a page
+	full := include == "full"
+	if include != "" && !full {
+		s.respondWithError(c, http.StatusBadRequest, "INVALID_INCLUDE",
+			"Include must be 'full'", nil)
+		return
+	}
+	if full && limit > maxFullListLimit {
+		limit = maxFullListLimit
+	}
+
 	// Parse since timestamp if provided
 	var sinceTime *time.Time
 	if since != "" {
@@ -361,21 +1172,90 @@ func (s *Server) handleListMessages(c *gin.Context) {
 		sinceTime = &parsed
 	}
 
-	// For Phase 1, return empty list
-	// In later phases, this would query storage with filters
-	_ = status
-	_ = sender
-	_ = recipient
-	_ = sinceTime
+	filter := storage.MessageFilter{
+		Sender: sender,
+		Labels: labels,
+		Status: types.DeliveryStatus(status),
+		Limit:  limit,
+		Offset: offset,
+	}
+	if recipient != "" {
+		filter.Recipients = []string{recipient}
+	}
+	if sinceTime != nil {
+		unix := sinceTime.Unix()
+		filter.Since = &unix
+	}
+
+	ctx := c.Request.Context()
+	messages, err := s.storage.ListMessages(ctx, filter)
+	if err != nil {
+		s.respondWithError(c, http.StatusInternalServerError, "MESSAGE_LIST_FAILED",
+			"Failed to list messages", nil)
+		return
+	}
+
+	// A message is stored before its status, so it may briefly have none
+	messageIDs := make([]string, len(messages))
+	for i, message := range messages {
+		messageIDs[i] = message.MessageID
+	}
+	statuses, err := s.storage.GetStatuses(ctx, messageIDs)
+	if err != nil {
+		s.respondWithError(c, http.StatusInternalServerError, "MESSAGE_LIST_FAILED",
+			"Failed to list messages", nil)
+		return
+	}
+	total, err := s.storage.CountMessages(ctx, filter)
+	if err != nil {
+		s.respondWithError(c, http.StatusInternalServerError, "MESSAGE_LIST_FAILED",
+			"Failed to list messages", nil)
+		return
+	}
+
+	if full {
+		entries := make([]types.MessageWithStatus, 0, len(messages))
+		for _, message := range messages {
+			entry := types.MessageWithStatus{Message: message}
+			if messageStatus, ok := statuses[message.MessageID]; ok {
+				entry.Status = messageStatus.Status
+				entry.RecipientStatuses = withoutRecipient(messageStatus.Recipients, s.config.Message.ArchiveAddress)
+			}
+			entries = append(entries, entry)
+		}
+		s.respondWithSuccess(c, http.StatusOK, gin.H{
+			"messages": entries,
+			"total":    total,
+			"limit":    limit,
+			"offset":   offset,
+		})
+		return
+	}
+
+	summaries := make([]types.MessageSummary, 0, len(messages))
+	for _, message := range messages {
+		summary := types.MessageSummary{
+			MessageID:  message.MessageID,
+			Sender:     message.Sender,
+			Recipients: message.Recipients,
+			Subject:    message.Subject,
+			Labels:     message.Labels,
+			Timestamp:  message.Timestamp,
+		}
+		if messageStatus, ok := statuses[message.MessageID]; ok {
+			summary.Status = messageStatus.Status
+		}
+		summaries = append(summaries, summary)
+	}
 
 	response := gin.H{
-		"messages": []types.MessageStatus{},
-		"total":    0,
+		"messages": summaries,
+		"total":    total,
 		"limit":    limit,
 		"offset":   offset,
 	}
 
-	c.JSON(http.StatusOK, response)
+	s.respondWithSuccess(c, http.StatusOK, response)
 }
 
 // handleGetCapabilities handles GET /v1/capabilities/:domain
@@ -390,6 +1270,14 @@ func (s *Server) handleGetCapabilities(c *gin.Context) {
 
 	// Discover capabilities for the domain
 	capabilities, err := s.discovery.DiscoverCapabilities(c.Request.Context(), domain)
+
+	// Our own domain is described by what is actually enabled, whether or
+	// not its DNS record can be found
+	if strings.EqualFold(domain, s.config.Server.Domain) {
+		s.respondWithSuccess(c, http.StatusOK, s.localCapabilities(c.Request.Context(), capabilities))
+		return
+	}
+
 	if err != nil {
 		s.respondWithError(c, http.StatusNotFound, "CAPABILITIES_NOT_FOUND",
 			"AMTP capabilities not found for domain", map[string]interface{}{
@@ -399,12 +1287,7 @@ func (s *Server) handleGetCapabilities(c *gin.Context) {
 		return
 	}
 
-	// If this is our own domain, return schemas supported by registered agents
-	if domain == s.config.Server.Domain {
-		capabilities.Schemas = s.agentRegistry.GetSupportedSchemas(c.Request.Context())
-	}
-
-	c.JSON(http.StatusOK, capabilities)
+	s.respondWithSuccess(c, http.StatusOK, capabilities)
 }
 
 // Schema Management Handlers
@@ -421,6 +1304,11 @@ func (s *Server) handleRegisterSchema(c *gin.Context) {
 		ID         string          `json:"id" binding:"required"`
 		Definition json.RawMessage `json:"definition" binding:"required"`
 		Force      bool            `json:"force,omitempty"`
+		Strict     bool            `json:"strict,omitempty"`
+		// ValidationMode is the schema's default validation mode
+		ValidationMode string `json:"validation_mode,omitempty"`
+		// Defaults apply to messages using the schema that leave them unset
+		Defaults *schema.MessageDefaults `json:"defaults,omitempty"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -430,9 +1318,15 @@ func (s *Server) handleRegisterSchema(c *gin.Context) {
 			})
 		return
 	}
+	if !s.checkValidationMode(c, req.ValidationMode) || !s.checkSchemaDefaults(c, req.Defaults) {
+		return
+	}
+	if req.Defaults.IsEmpty() {
+		req.Defaults = nil
+	}
 
 	// Parse schema identifier
-	schemaID, err := schema.ParseSchemaIdentifier(req.ID)
+	schemaID, err := schema.ParseSchemaReference(req.ID)
 	if err != nil {
 		s.respondWithError(c, http.StatusBadRequest, "INVALID_SCHEMA_ID",
 			"Invalid schema identifier", map[string]interface{}{
@@ -442,11 +1336,28 @@ func (s *Server) handleRegisterSchema(c *gin.Context) {
 		return
 	}
 
+	// A versionless identifier registers the next version
+	if schemaID.IsLatest() {
+		schemaID, err = s.schemaManager.NextVersion(c.Request.Context(), schemaID.Domain, schemaID.Entity)
+		if err != nil {
+			s.respondWithError(c, http.StatusInternalServerError, "SCHEMA_REGISTRATION_FAILED",
+				"Failed to determine next schema version", map[string]interface{}{
+					"schema_id": req.ID,
+					"error":     err.Error(),
+				})
+			return
+		}
+		req.ID = schemaID.String()
+	}
+
 	// Create schema
 	newSchema := &schema.Schema{
-		ID:          *schemaID,
-		Definition:  req.Definition,
-		PublishedAt: time.Now().UTC(),
+		ID:             *schemaID,
+		Definition:     req.Definition,
+		PublishedAt:    time.Now().UTC(),
+		Strict:         req.Strict,
+		ValidationMode: req.ValidationMode,
+		Defaults:       req.Defaults,
 	}
 
 	// Register schema
@@ -458,7 +1369,7 @@ func (s *Server) handleRegisterSchema(c *gin.Context) {
 	}
 
 	if regErr != nil {
-		if !req.Force && regErr.Error() == "schema already exists" {
+		if !req.Force && errors.Is(regErr, schema.ErrSchemaExists) {
 			s.respondWithError(c, http.StatusConflict, "SCHEMA_ALREADY_EXISTS",
 				"Schema already exists", map[string]interface{}{
 					"schema_id": req.ID,
@@ -474,13 +1385,41 @@ func (s *Server) handleRegisterSchema(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusCreated, gin.H{
+	s.respondWithSuccess(c, http.StatusCreated, gin.H{
 		"message":   "Schema registered successfully",
 		"schema_id": req.ID,
 		"timestamp": time.Now().UTC(),
 	})
 }
 
+// checkValidationMode rejects an unknown schema validation mode
+func (s *Server) checkValidationMode(c *gin.Context, mode string) bool {
+	if schema.IsValidValidationMode(mode) {
+		return true
+	}
+	s.respondWithError(c, http.StatusBadRequest, "INVALID_VALIDATION_MODE",
+		"Invalid validation mode", map[string]interface{}{
+			"validation_mode": mode,
+			"allowed":         []string{schema.ValidationModeFull, schema.ValidationModePartial},
+		})
+	return false
+}
+
+// checkSchemaDefaults rejects schema defaults a message could not be sent with
+func (s *Server) checkSchemaDefaults(c *gin.Context, defaults *schema.MessageDefaults) bool {
+	if defaults == nil || defaults.Coordination == nil {
+		return true
+	}
+	if err := s.validator.ValidateCoordination(defaults.Coordination); err != nil {
+		s.respondWithError(c, http.StatusBadRequest, "INVALID_SCHEMA_DEFAULTS",
+			"Invalid schema defaults", map[string]interface{}{
+				"error": err.Error(),
+			})
+		return false
+	}
+	return true
+}
+
 // handleListSchemas handles GET /v1/admin/schemas
 func (s *Server) handleListSchemas(c *gin.Context) {
 	if s.schemaManager == nil {
@@ -500,7 +1439,7 @@ func (s *Server) handleListSchemas(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
+	s.respondWithSuccess(c, http.StatusOK, gin.H{
 		"schemas":   schemas,
 		"count":     len(schemas),
 		"timestamp": time.Now().UTC(),
@@ -536,7 +1475,52 @@ func (s *Server) handleGetSchema(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
+	s.respondWithSuccess(c, http.StatusOK, gin.H{
+		"schema":    schemaObj,
+		"timestamp": time.Now().UTC(),
+	})
+}
+
+// handleGetLatestSchema handles GET /v1/admin/schemas/:domain/:entity/latest.
+// The domain segment is bound as :id because gin requires wildcards at the
+// same position to share a name.
+func (s *Server) handleGetLatestSchema(c *gin.Context) {
+	if s.schemaManager == nil {
+		s.respondWithError(c, http.StatusServiceUnavailable, "SCHEMA_MANAGER_UNAVAILABLE",
+			"Schema management is not configured", nil)
+		return
+	}
+
+	domain := c.Param("id")
+	entity := c.Param("entity")
+
+	schemaID, err := s.schemaManager.ResolveLatest(c.Request.Context(), domain, entity)
+	if err != nil {
+		status, code := http.StatusInternalServerError, "SCHEMA_RESOLUTION_FAILED"
+		if errors.Is(err, schema.ErrSchemaNotFound) {
+			status, code = http.StatusNotFound, "SCHEMA_NOT_FOUND"
+		}
+		s.respondWithError(c, status, code,
+			"Failed to resolve latest schema version", map[string]interface{}{
+				"domain": domain,
+				"entity": entity,
+				"error":  err.Error(),
+			})
+		return
+	}
+
+	schemaObj, err := s.schemaManager.GetRegistry().GetSchema(c.Request.Context(), *schemaID)
+	if err != nil {
+		s.respondWithError(c, http.StatusNotFound, "SCHEMA_NOT_FOUND",
+			"Schema not found", map[string]interface{}{
+				"schema_id": schemaID.String(),
+				"error":     err.Error(),
+			})
+		return
+	}
+
+	s.respondWithSuccess(c, http.StatusOK, gin.H{
+		"schema_id": schemaID.String(),
 		"schema":    schemaObj,
 		"timestamp": time.Now().UTC(),
 	})
@@ -563,6 +1547,13 @@ func (s *Server) handleUpdateSchema(c *gin.Context) {
 
 	var req struct {
 		Definition json.RawMessage `json:"definition" binding:"required"`
+		// Strict keeps the stored setting when omitted
+		Strict *bool `json:"strict,omitempty"`
+		// ValidationMode is the schema's default validation mode
+		ValidationMode string `json:"validation_mode,omitempty"`
+		// Defaults apply to messages using the schema that leave them unset.
+		// They keep the stored defaults when omitted; {} clears them.
+		Defaults *schema.MessageDefaults `json:"defaults,omitempty"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -572,12 +1563,36 @@ func (s *Server) handleUpdateSchema(c *gin.Context) {
 			})
 		return
 	}
+	if !s.checkValidationMode(c, req.ValidationMode) || !s.checkSchemaDefaults(c, req.Defaults) {
+		return
+	}
+
+	// Settings left out of the request keep their stored values
+	var existing *schema.Schema
+	if stored, err := s.schemaManager.GetRegistry().GetSchema(c.Request.Context(), *schemaID); err == nil {
+		existing = stored
+	}
+	strict := false
+	defaults := req.Defaults
+	if req.Strict != nil {
+		strict = *req.Strict
+	} else if existing != nil {
+		strict = existing.Strict
+	}
+	if defaults == nil && existing != nil {
+		defaults = existing.Defaults
+	} else if defaults.IsEmpty() {
+		defaults = nil
+	}
 
 	// Create updated schema
 	updatedSchema := &schema.Schema{
-		ID:          *schemaID,
-		Definition:  req.Definition,
-		PublishedAt: time.Now().UTC(),
+		ID:             *schemaID,
+		Definition:     req.Definition,
+		PublishedAt:    time.Now().UTC(),
+		Strict:         strict,
+		ValidationMode: req.ValidationMode,
+		Defaults:       defaults,
 	}
 
 	// Update schema
@@ -591,7 +1606,7 @@ func (s *Server) handleUpdateSchema(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
+	s.respondWithSuccess(c, http.StatusOK, gin.H{
 		"message":   "Schema updated successfully",
 		"schema_id": schemaIDStr,
 		"timestamp": time.Now().UTC(),
@@ -628,7 +1643,7 @@ func (s *Server) handleDeleteSchema(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
+	s.respondWithSuccess(c, http.StatusOK, gin.H{
 		"message":   "Schema deleted successfully",
 		"schema_id": schemaIDStr,
 		"timestamp": time.Now().UTC(),
@@ -643,125 +1658,935 @@ func (s *Server) handleValidateSchema(c *gin.Context) {
 		return
 	}
 
-	schemaIDStr := c.Param("id")
-	_, err := schema.ParseSchemaIdentifier(schemaIDStr)
-	if err != nil {
-		s.respondWithError(c, http.StatusBadRequest, "INVALID_SCHEMA_ID",
-			"Invalid schema identifier", map[string]interface{}{
-				"schema_id": schemaIDStr,
-				"error":     err.Error(),
-			})
+	schemaIDStr := c.Param("id")
+	_, err := schema.ParseSchemaIdentifier(schemaIDStr)
+	if err != nil {
+		s.respondWithError(c, http.StatusBadRequest, "INVALID_SCHEMA_ID",
+			"Invalid schema identifier", map[string]interface{}{
+				"schema_id": schemaIDStr,
+				"error":     err.Error(),
+			})
+		return
+	}
+
+	var req struct {
+		Payload json.RawMessage `json:"payload" binding:"required"`
+		// ValidationMode overrides the schema's validation mode
+		ValidationMode string `json:"validation_mode,omitempty"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		s.respondWithError(c, http.StatusBadRequest, "INVALID_REQUEST_FORMAT",
+			"Invalid request format", map[string]interface{}{
+				"parse_error": err.Error(),
+			})
+		return
+	}
+	if !s.checkValidationMode(c, req.ValidationMode) {
+		return
+	}
+
+	// Create a temporary message for validation
+	message := &types.Message{
+		Schema:  schemaIDStr,
+		Payload: req.Payload,
+	}
+
+	// Validate payload against schema
+	ctx := schema.WithValidationMode(c.Request.Context(), req.ValidationMode)
+	report, err := s.schemaManager.ValidateMessage(ctx, message)
+	if err != nil {
+		s.respondWithError(c, http.StatusInternalServerError, "VALIDATION_FAILED",
+			"Schema validation failed", map[string]interface{}{
+				"schema_id": schemaIDStr,
+				"error":     err.Error(),
+			})
+		return
+	}
+
+	s.respondWithSuccess(c, http.StatusOK, gin.H{
+		"valid":     report.IsValid(),
+		"errors":    report.Errors,
+		"warnings":  report.Warnings,
+		"timestamp": time.Now().UTC(),
+	})
+}
+
+// handleInferSchema handles POST /v1/admin/schemas/infer, suggesting a schema
+// that fits the example payloads given. Nothing is registered: the operator
+// reviews the suggestion and registers it as usual.
+func (s *Server) handleInferSchema(c *gin.Context) {
+	var req struct {
+		Examples []json.RawMessage `json:"examples" binding:"required"`
+		// MaxEnumValues defaults to schema.DefaultMaxEnumValues; 0 disables enums
+		MaxEnumValues *int `json:"max_enum_values,omitempty"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		s.respondWithError(c, http.StatusBadRequest, "INVALID_REQUEST_FORMAT",
+			"Invalid request format", map[string]interface{}{
+				"parse_error": err.Error(),
+			})
+		return
+	}
+
+	options := schema.InferOptions{MaxEnumValues: schema.DefaultMaxEnumValues}
+	if req.MaxEnumValues != nil {
+		if *req.MaxEnumValues < 0 {
+			s.respondWithError(c, http.StatusBadRequest, "INVALID_REQUEST_FORMAT",
+				"max_enum_values cannot be negative", nil)
+			return
+		}
+		options.MaxEnumValues = *req.MaxEnumValues
+	}
+
+	definition, err := schema.InferSchema(req.Examples, options)
+	if err != nil {
+		s.respondWithError(c, http.StatusBadRequest, "INVALID_EXAMPLES",
+			"Cannot infer a schema from the examples", map[string]interface{}{
+				"error": err.Error(),
+			})
+		return
+	}
+
+	s.respondWithSuccess(c, http.StatusOK, gin.H{
+		"schema":    definition,
+		"examples":  len(req.Examples),
+		"timestamp": time.Now().UTC(),
+	})
+}
+
+// handleRevalidateSchema handles POST /v1/admin/schemas/:id/revalidate
+// Checks stored messages using the schema against its current definition,
+// e.g. to see which messages a schema fix would now reject. Messages are
+// left unchanged.
+func (s *Server) handleRevalidateSchema(c *gin.Context) {
+	if s.schemaManager == nil {
+		s.respondWithError(c, http.StatusServiceUnavailable, "SCHEMA_MANAGER_UNAVAILABLE",
+			"Schema management is not configured", nil)
+		return
+	}
+
+	schemaIDStr := c.Param("id")
+	schemaID, err := schema.ParseSchemaIdentifier(schemaIDStr)
+	if err != nil {
+		s.respondWithError(c, http.StatusBadRequest, "INVALID_SCHEMA_ID",
+			"Invalid schema identifier", map[string]interface{}{
+				"schema_id": schemaIDStr,
+				"error":     err.Error(),
+			})
+		return
+	}
+
+	ctx := c.Request.Context()
+	if _, err := s.schemaManager.GetRegistry().GetSchema(ctx, *schemaID); err != nil {
+		s.respondWithError(c, http.StatusNotFound, "SCHEMA_NOT_FOUND",
+			"Schema not found", map[string]interface{}{
+				"schema_id": schemaIDStr,
+				"error":     err.Error(),
+			})
+		return
+	}
+
+	result, err := s.revalidateStoredMessages(ctx, schemaID)
+	if err != nil {
+		if ctx.Err() != nil {
+			s.respondWithError(c, http.StatusServiceUnavailable, "REVALIDATION_CANCELED",
+				"Schema revalidation was canceled", map[string]interface{}{
+					"schema_id": schemaIDStr,
+				})
+			return
+		}
+		s.respondWithError(c, http.StatusInternalServerError, "REVALIDATION_FAILED",
+			"Failed to revalidate stored messages", map[string]interface{}{
+				"schema_id": schemaIDStr,
+				"error":     err.Error(),
+			})
+		return
+	}
+
+	s.logger.WithFields(map[string]interface{}{
+		"schema_id": result.SchemaID,
+		"checked":   result.Checked,
+		"invalid":   result.Invalid,
+		"skipped":   result.Skipped,
+	}).Info("Revalidated stored messages")
+
+	s.respondWithSuccess(c, http.StatusOK, gin.H{
+		"revalidation": result,
+		"timestamp":    time.Now().UTC(),
+	})
+}
+
+// handleSchemaStats handles GET /v1/admin/schemas/stats
+func (s *Server) handleSchemaStats(c *gin.Context) {
+	if s.schemaManager == nil {
+		s.respondWithError(c, http.StatusServiceUnavailable, "SCHEMA_MANAGER_UNAVAILABLE",
+			"Schema management is not configured", nil)
+		return
+	}
+
+	// Get schema registry statistics
+	stats := s.schemaManager.GetRegistry().GetStats()
+
+	s.respondWithSuccess(c, http.StatusOK, gin.H{
+		"stats":     stats,
+		"timestamp": time.Now().UTC(),
+	})
+}
+
+// handleReloadSchemas handles POST /v1/admin/schemas/reload
+func (s *Server) handleReloadSchemas(c *gin.Context) {
+	if s.schemaManager == nil {
+		s.respondWithError(c, http.StatusServiceUnavailable, "SCHEMA_MANAGER_UNAVAILABLE",
+			"Schema management is not configured", nil)
+		return
+	}
+
+	summary, err := s.schemaManager.ReloadSchemas(c.Request.Context())
+	if errors.Is(err, schema.ErrReloadNotSupported) {
+		s.respondWithError(c, http.StatusConflict, "SCHEMA_RELOAD_NOT_SUPPORTED",
+			"Schema registry is not backed by files on disk", nil)
+		return
+	}
+	if err != nil {
+		s.respondWithError(c, http.StatusInternalServerError, "SCHEMA_RELOAD_FAILED",
+			"Failed to reload schemas", map[string]interface{}{
+				"error": err.Error(),
+			})
+		return
+	}
+
+	s.logger.WithFields(map[string]interface{}{
+		"added":     len(summary.Added),
+		"removed":   len(summary.Removed),
+		"updated":   len(summary.Updated),
+		"unchanged": summary.Unchanged,
+	}).Info("Reloaded schemas from disk")
+
+	s.respondWithSuccess(c, http.StatusOK, gin.H{
+		"message":   "Schemas reloaded successfully",
+		"changes":   summary,
+		"timestamp": time.Now().UTC(),
+	})
+}
+
+// handleRegisterAgent handles POST /v1/admin/agents
+func (s *Server) handleRegisterAgent(c *gin.Context) {
+	var agent agents.LocalAgent
+
+	if err := c.ShouldBindJSON(&agent); err != nil {
+		s.respondWithError(c, http.StatusBadRequest, "INVALID_REQUEST_FORMAT",
+			"Invalid agent registration format", map[string]interface{}{
+				"parse_error": err.Error(),
+			})
+		return
+	}
+
+	// Catch typo'd webhook URLs now rather than at the first delivery
+	var checks []pushTargetCheck
+	if s.pushProbe != nil && agent.DeliveryMode == "push" {
+		checks = s.pushProbe.Check(c.Request.Context(), agent.AllPushTargets())
+		if !allReachable(checks) {
+			if s.pushProbe.reject {
+				s.respondWithError(c, http.StatusBadRequest, "PUSH_TARGET_UNREACHABLE",
+					"Push target is not reachable", map[string]interface{}{
+						"push_target_checks": checks,
+					})
+				return
+			}
+			s.logger.WithContext(c.Request.Context()).WithFields(map[string]interface{}{
+				"address":            agent.Address,
+				"push_target_checks": checks,
+			}).Warn("Registering agent with unreachable push target")
+		}
+	}
+
+	// Use the agent registry directly
+	if err := s.agentRegistry.RegisterAgent(c.Request.Context(), &agent); err != nil {
+		s.respondWithError(c, http.StatusBadRequest, "AGENT_REGISTRATION_FAILED",
+			"Failed to register agent", map[string]interface{}{
+				"error": err.Error(),
+			})
+		return
+	}
+
+	// Messages may be held waiting for this agent
+	if s.heldRetrier != nil {
+		s.heldRetrier.Notify()
+	}
+
+	response := gin.H{
+		"message": "Agent registered successfully",
+		"agent":   agent,
+	}
+	if checks != nil {
+		response["push_target_checks"] = checks
+	}
+	s.respondWithSuccess(c, http.StatusCreated, response)
+}
+
+// handleUnregisterAgent handles DELETE /v1/admin/agents/:address
+func (s *Server) handleUnregisterAgent(c *gin.Context) {
+	agentName := c.Param("address") // Keep param name for backward compatibility
+
+	// Use the agent registry directly
+	if err := s.agentRegistry.UnregisterAgent(c.Request.Context(), agentName); err != nil {
+		s.respondWithError(c, http.StatusBadRequest, "AGENT_UNREGISTRATION_FAILED",
+			"Failed to unregister agent", map[string]interface{}{
+				"error": err.Error(),
+			})
+		return
+	}
+
+	s.respondWithSuccess(c, http.StatusOK, gin.H{
+		"message": "Agent unregistered successfully",
+		"name":    agentName,
+	})
+}
+
+// handleDrainInbox handles DELETE /v1/admin/agents/:address/inbox
+// Acknowledges every message waiting in a local agent's inbox, for example
+// when the agent is offboarded. The agent need not still be registered.
+func (s *Server) handleDrainInbox(c *gin.Context) {
+	address := c.Param("address")
+	if !s.addresses.IsValid(address) && !strings.Contains(address, "@") {
+		address += "@" + s.config.Server.Domain
+	}
+	// Inboxes are keyed by normalized address
+	address = types.NormalizeAddress(address)
+	if !s.isLocalAddress(address) {
+		s.respondWithError(c, http.StatusBadRequest, "INVALID_AGENT_ADDRESS",
+			"Only local agents have inboxes on this gateway", map[string]interface{}{
+				"address": address,
+			})
+		return
+	}
+
+	drained, err := s.storage.DrainInbox(c.Request.Context(), address)
+	if err != nil {
+		s.respondWithError(c, http.StatusInternalServerError, "INBOX_DRAIN_FAILED",
+			"Failed to drain inbox", map[string]interface{}{
+				"error": err.Error(),
+			})
+		return
+	}
+
+	s.respondWithSuccess(c, http.StatusOK, gin.H{
+		"message": "Inbox drained",
+		"address": address,
+		"drained": drained,
+	})
+}
+
+// handleListAgents handles GET /v1/admin/agents
+func (s *Server) handleListAgents(c *gin.Context) {
+	// Use the agent registry directly
+	agents := s.agentRegistry.GetAllAgents(c.Request.Context())
+
+	s.respondWithSuccess(c, http.StatusOK, gin.H{
+		"agents": agents,
+		"count":  len(agents),
+	})
+}
+
+// handleGetAgent handles GET /v1/admin/agents/:address
+// Push agents are reported with the deliveries to them in flight and the
+// cap that applies, whether the agent's own or the gateway default.
+func (s *Server) handleGetAgent(c *gin.Context) {
+	address := c.Param("address")
+	if !s.addresses.IsValid(address) && !strings.Contains(address, "@") {
+		address += "@" + s.config.Server.Domain
+	}
+
+	agent, err := s.agentRegistry.GetAgent(c.Request.Context(), address)
+	if err != nil {
+		s.respondWithError(c, http.StatusNotFound, "AGENT_NOT_FOUND",
+			"Agent not found", map[string]interface{}{
+				"address": address,
+			})
+		return
+	}
+
+	response := gin.H{"agent": agent}
+	if agent.DeliveryMode == "push" && s.deliveries != nil {
+		response["deliveries_in_flight"] = s.deliveries.AgentDeliveriesInFlight(agent.Address)
+		response["delivery_limit"] = s.deliveries.AgentDeliveryLimit(agent)
+	}
+	s.respondWithSuccess(c, http.StatusOK, response)
+}
+
+// handleMintInboxToken handles POST /v1/admin/agents/:address/tokens,
+// minting a token that reads and acknowledges the agent's inbox until it
+// expires. The body may set a ttl such as "30m", up to the configured max.
+func (s *Server) handleMintInboxToken(c *gin.Context) {
+	if s.inboxTokens == nil {
+		s.respondWithError(c, http.StatusServiceUnavailable, "INBOX_TOKENS_DISABLED",
+			"Inbox tokens are not configured", nil)
+		return
+	}
+
+	var req struct {
+		TTL string `json:"ttl"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		s.respondWithError(c, http.StatusBadRequest, "INVALID_REQUEST_FORMAT",
+			"Invalid request format", map[string]interface{}{
+				"parse_error": err.Error(),
+			})
+		return
+	}
+
+	tokens := s.config.Auth.InboxTokens
+	ttl := tokens.DefaultTTL
+	if req.TTL != "" {
+		parsed, err := time.ParseDuration(req.TTL)
+		if err != nil || parsed <= 0 || parsed > tokens.MaxTTL {
+			s.respondWithError(c, http.StatusBadRequest, "INVALID_TTL",
+				"ttl must be a positive duration no longer than the max TTL", map[string]interface{}{
+					"ttl":     req.TTL,
+					"max_ttl": tokens.MaxTTL.String(),
+				})
+			return
+		}
+		ttl = parsed
+	}
+
+	address := c.Param("address")
+	if !s.addresses.IsValid(address) && !strings.Contains(address, "@") {
+		address += "@" + s.config.Server.Domain
+	}
+	agent, err := s.agentRegistry.GetAgent(c.Request.Context(), address)
+	if err != nil {
+		s.respondWithError(c, http.StatusNotFound, "AGENT_NOT_FOUND",
+			"Agent not found", map[string]interface{}{
+				"address": address,
+			})
+		return
+	}
+
+	token, claims, err := s.inboxTokens.Mint(agent.Address, time.Now(), ttl)
+	if err != nil {
+		s.respondWithError(c, http.StatusInternalServerError, "TOKEN_MINT_FAILED",
+			"Failed to mint inbox token", map[string]interface{}{
+				"error": err.Error(),
+			})
+		return
+	}
+
+	s.respondWithSuccess(c, http.StatusCreated, gin.H{
+		"token":      token,
+		"agent":      claims.Agent,
+		"scope":      claims.Scope,
+		"expires_at": claims.Expiry(),
+	})
+}
+
+// handleListIdleAgents handles GET /v1/admin/agents/idle
+// Lists agents that would be flagged or unregistered as idle, so operators
+// can review them before turning on an idle agent action. The threshold
+// defaults to the configured one and can be overridden with idle_after.
+func (s *Server) handleListIdleAgents(c *gin.Context) {
+	idleAfter := s.config.Agents.IdleAfter
+	if value := c.Query("idle_after"); value != "" {
+		parsed, err := time.ParseDuration(value)
+		if err != nil || parsed <= 0 {
+			s.respondWithError(c, http.StatusBadRequest, "INVALID_IDLE_AFTER",
+				"idle_after must be a positive duration such as 720h", map[string]interface{}{
+					"idle_after": value,
+				})
+			return
+		}
+		idleAfter = parsed
+	}
+	if idleAfter <= 0 {
+		s.respondWithError(c, http.StatusBadRequest, "INVALID_IDLE_AFTER",
+			"No idle threshold is configured; pass idle_after", nil)
+		return
+	}
+
+	idle, err := findIdleAgents(c.Request.Context(), s.agentRegistry, s.storage, idleAfter, time.Now().UTC())
+	if err != nil {
+		s.respondWithError(c, http.StatusInternalServerError, "IDLE_AGENT_CHECK_FAILED",
+			"Failed to check for idle agents", map[string]interface{}{
+				"error": err.Error(),
+			})
+		return
+	}
+
+	action := s.config.Agents.IdleAction
+	if action == "" {
+		action = config.IdleActionOff
+	}
+	s.respondWithSuccess(c, http.StatusOK, gin.H{
+		"agents":     idle,
+		"count":      len(idle),
+		"idle_after": idleAfter.String(),
+		"action":     action,
+		"timestamp":  time.Now().UTC(),
+	})
+}
+
+// handleListRateLimits handles GET /v1/admin/ratelimits
+func (s *Server) handleListRateLimits(c *gin.Context) {
+	if s.rateLimiter == nil {
+		s.respondWithSuccess(c, http.StatusOK, gin.H{
+			"enabled": false,
+			"buckets": []middleware.RateLimitBucket{},
+			"count":   0,
+		})
+		return
+	}
+
+	buckets := s.rateLimiter.Buckets()
+	s.respondWithSuccess(c, http.StatusOK, gin.H{
+		"enabled":             true,
+		"requests_per_minute": s.rateLimiter.RequestsPerMinute(),
+		"burst":               s.rateLimiter.Burst(),
+		"buckets":             buckets,
+		"count":               len(buckets),
+	})
+}
+
+// handleResetRateLimit handles DELETE /v1/admin/ratelimits/:key
+func (s *Server) handleResetRateLimit(c *gin.Context) {
+	key := c.Param("key")
+
+	if s.rateLimiter == nil || !s.rateLimiter.Reset(key) {
+		s.respondWithError(c, http.StatusNotFound, "RATE_LIMIT_BUCKET_NOT_FOUND",
+			"No rate limit bucket is tracked for this key", map[string]interface{}{
+				"key": key,
+			})
+		return
+	}
+
+	s.logger.WithContext(c.Request.Context()).WithFields(map[string]interface{}{
+		"key": key,
+	}).Info("Rate limit bucket reset")
+
+	s.respondWithSuccess(c, http.StatusOK, gin.H{
+		"message": "Rate limit bucket reset successfully",
+		"key":     key,
+	})
+}
+
+// exportPageSize bounds how many messages the export handler holds in memory
+const exportPageSize = 100
+
+// exportRecord is a single NDJSON line of a message history export
+type exportRecord struct {
+	Type    string               `json:"type"`
+	Message *types.Message       `json:"message,omitempty"`
+	Status  *types.MessageStatus `json:"status,omitempty"`
+	Count   *int                 `json:"count,omitempty"`
+	Error   string               `json:"error,omitempty"`
+}
+
+// handleExportMessages handles GET /v1/admin/export
+func (s *Server) handleExportMessages(c *gin.Context) {
+	address := c.Query("address")
+	if !s.addresses.IsValid(address) {
+		s.respondWithError(c, http.StatusBadRequest, "INVALID_ADDRESS",
+			"A valid address query parameter is required", map[string]interface{}{
+				"address": address,
+			})
+		return
+	}
+	// Messages are stored with normalized addresses
+	address = types.NormalizeAddress(address)
+
+	ctx := c.Request.Context()
+
+	// Fetch the first page before committing to a 200 so storage failures
+	// can still be reported as a regular error response
+	messages, cursor, err := s.storage.ExportMessages(ctx, address, "", exportPageSize)
+	if err != nil {
+		s.respondWithError(c, http.StatusInternalServerError, "EXPORT_FAILED",
+			"Failed to export messages", map[string]interface{}{
+				"address": address,
+				"error":   err.Error(),
+			})
+		return
+	}
+
+	c.Header("Content-Type", "application/x-ndjson")
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", "export-"+address+".ndjson"))
+	c.Status(http.StatusOK)
+
+	encoder := json.NewEncoder(c.Writer)
+	count := 0
+	for {
+		for _, message := range messages {
+			// A message without a stored status is still exported
+			status, _ := s.storage.GetStatus(ctx, message.MessageID)
+			if err := encoder.Encode(exportRecord{Type: "message", Message: message, Status: status}); err != nil {
+				s.logger.WithContext(ctx).Error("Export stream aborted", err)
+				return
+			}
+			count++
+		}
+		c.Writer.Flush()
+
+		if cursor == "" {
+			break
+		}
+		messages, cursor, err = s.storage.ExportMessages(ctx, address, cursor, exportPageSize)
+		if err != nil {
+			// Headers are already sent, so report the failure in-band
+			s.logger.WithContext(ctx).Error("Export failed mid-stream", err)
+			_ = encoder.Encode(exportRecord{Type: "error", Error: err.Error()})
+			return
+		}
+	}
+
+	_ = encoder.Encode(exportRecord{Type: "end", Count: &count})
+}
+
+// handleResendMessage handles POST /v1/admin/messages/:id/resend. The stored
+// message is submitted again under a fresh message ID and idempotency key, so
+// the original and its delivery history are left untouched.
+func (s *Server) handleResendMessage(c *gin.Context) {
+	messageID := c.Param("id")
+	if !s.messageIDs.IsValid(messageID) {
+		s.respondWithError(c, http.StatusBadRequest, "INVALID_MESSAGE_ID",
+			"Invalid message ID format", nil)
+		return
+	}
+
+	original, err := s.storage.GetMessage(c.Request.Context(), messageID)
+	if err != nil {
+		s.respondWithError(c, http.StatusNotFound, "MESSAGE_NOT_FOUND",
+			"Message not found", nil)
+		return
+	}
+
+	message := *original
+	message.MessageID, err = s.messageIDs.Generate()
+	if err == nil {
+		message.IdempotencyKey, err = uuid.GenerateV4()
+	}
+	if err != nil {
+		s.respondWithError(c, http.StatusInternalServerError, "ID_GENERATION_FAILED",
+			"Failed to generate message ID", nil)
+		return
+	}
+	message.Timestamp = time.Now().UTC()
+	// The signature covers the original message ID; local messages are
+	// signed again on relay
+	message.Signature = nil
+
+	isSenderLocal := s.isLocalAddress(message.Sender)
+
+	maxRetries, retryDelay := s.retryPolicy(&types.SendMessageRequest{})
+	result, err := s.processor.ProcessMessage(c.Request.Context(), &message, processing.ProcessingOptions{
+		ImmediatePath: message.Coordination == nil || !isSenderLocal,
+		Timeout:       s.processingTimeout(c.Request.Context()),
+		MaxRetries:    maxRetries,
+		RetryDelay:    retryDelay,
+	})
+	if status, code, ok := coordinationLimitError(err); ok {
+		s.respondWithError(c, status, code, "Coordinated message exceeds the gateway's coordination limits",
+			map[string]interface{}{
+				"message_id":       messageID,
+				"processing_error": err.Error(),
+			})
+		return
+	}
+	if err != nil {
+		s.respondWithError(c, http.StatusInternalServerError, "RESEND_FAILED",
+			"Failed to resend message", map[string]interface{}{
+				"message_id":       messageID,
+				"processing_error": err.Error(),
+			})
+		return
+	}
+
+	s.logger.LogMessageProcessing(message.MessageID, "resend", string(result.Status), nil, nil)
+
+	// The resend itself succeeded whatever became of the delivery, which the
+	// status reports
+	_, status, partial := resultStatus(result)
+	s.respondWithSuccess(c, http.StatusOK, gin.H{
+		"original_message_id": messageID,
+		"message_id":          result.MessageID,
+		"status":              status,
+		"partial":             partial,
+		"recipients":          result.Recipients,
+	})
+}
+
+// handleGetRawRequest returns the send request a message was created from,
+// when raw requests are stored
+func (s *Server) handleGetRawRequest(c *gin.Context) {
+	messageID := c.Param("id")
+	if !s.messageIDs.IsValid(messageID) {
+		s.respondWithError(c, http.StatusBadRequest, "INVALID_MESSAGE_ID",
+			"Invalid message ID format", nil)
+		return
+	}
+
+	raw, err := s.storage.GetRawRequest(c.Request.Context(), messageID)
+	if err != nil {
+		var details map[string]interface{}
+		if s.rawRequests == nil {
+			details = map[string]interface{}{
+				"hint": "Raw requests are only stored with message.store_raw_request enabled",
+			}
+		}
+		s.respondWithError(c, http.StatusNotFound, "RAW_REQUEST_NOT_FOUND",
+			"No raw request is stored for this message", details)
+		return
+	}
+
+	s.respondWithSuccess(c, http.StatusOK, raw)
+}
+
+// handleValidateStoredMessage handles POST /v1/admin/messages/:id/validate
+// Validates a stored message against the schema given by the schema query
+// parameter, or its own schema, to debug why an agent rejected it
+func (s *Server) handleValidateStoredMessage(c *gin.Context) {
+	if s.schemaManager == nil {
+		s.respondWithError(c, http.StatusServiceUnavailable, "SCHEMA_MANAGER_UNAVAILABLE",
+			"Schema management is not configured", nil)
+		return
+	}
+
+	messageID := c.Param("id")
+	if !s.messageIDs.IsValid(messageID) {
+		s.respondWithError(c, http.StatusBadRequest, "INVALID_MESSAGE_ID",
+			"Invalid message ID format", nil)
+		return
+	}
+
+	schemaRef := c.Query("schema")
+	if schemaRef != "" {
+		if _, err := schema.ParseSchemaReference(schemaRef); err != nil {
+			s.respondWithError(c, http.StatusBadRequest, "INVALID_SCHEMA_ID",
+				"Invalid schema identifier", map[string]interface{}{
+					"schema_id": schemaRef,
+					"error":     err.Error(),
+				})
+			return
+		}
+	}
+
+	message, err := s.storage.GetMessage(c.Request.Context(), messageID)
+	if err != nil {
+		s.respondWithError(c, http.StatusNotFound, "MESSAGE_NOT_FOUND",
+			"Message not found", nil)
+		return
+	}
+
+	// Validate a copy, so the stored message keeps its own schema
+	candidate := *message
+	if schemaRef != "" {
+		candidate.Schema = schemaRef
+	}
+	if candidate.Schema == "" {
+		s.respondWithError(c, http.StatusBadRequest, "SCHEMA_REQUIRED",
+			"The message has no schema; pass one with the schema query parameter", nil)
 		return
 	}
 
-	var req struct {
-		Payload json.RawMessage `json:"payload" binding:"required"`
+	ctx := c.Request.Context()
+	schemaID, err := s.schemaManager.ResolveSchema(ctx, candidate.Schema)
+	if err == nil {
+		_, err = s.schemaManager.GetRegistry().GetSchema(ctx, *schemaID)
 	}
-
-	if err := c.ShouldBindJSON(&req); err != nil {
-		s.respondWithError(c, http.StatusBadRequest, "INVALID_REQUEST_FORMAT",
-			"Invalid request format", map[string]interface{}{
-				"parse_error": err.Error(),
+	if errors.Is(err, schema.ErrSchemaNotFound) {
+		s.respondWithError(c, http.StatusNotFound, "SCHEMA_NOT_FOUND",
+			"Schema not found", map[string]interface{}{
+				"schema_id": candidate.Schema,
+				"error":     err.Error(),
 			})
 		return
 	}
 
-	// Create a temporary message for validation
-	message := &types.Message{
-		Schema:  schemaIDStr,
-		Payload: req.Payload,
-	}
-
-	// Validate payload against schema
-	report, err := s.schemaManager.ValidateMessage(c.Request.Context(), message)
+	report, err := s.schemaManager.ValidateMessage(ctx, &candidate)
 	if err != nil {
 		s.respondWithError(c, http.StatusInternalServerError, "VALIDATION_FAILED",
 			"Schema validation failed", map[string]interface{}{
-				"schema_id": schemaIDStr,
+				"schema_id": candidate.Schema,
 				"error":     err.Error(),
 			})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"valid":     report.IsValid(),
-		"errors":    report.Errors,
-		"warnings":  report.Warnings,
-		"timestamp": time.Now().UTC(),
-	})
+	s.respondWithSuccess(c, http.StatusOK, report)
 }
 
-// handleSchemaStats handles GET /v1/admin/schemas/stats
-func (s *Server) handleSchemaStats(c *gin.Context) {
-	if s.schemaManager == nil {
-		s.respondWithError(c, http.StatusServiceUnavailable, "SCHEMA_MANAGER_UNAVAILABLE",
-			"Schema management is not configured", nil)
+// handleListDeliveryErrors handles GET /v1/admin/errors
+// Lists the most recent failed recipient deliveries, newest first, for
+// triage. since is an RFC3339 timestamp or a duration back from now, such
+// as 1h.
+func (s *Server) handleListDeliveryErrors(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "100"))
+	if err != nil || limit < 1 || limit > 1000 {
+		s.respondWithError(c, http.StatusBadRequest, "INVALID_LIMIT",
+			"Limit must be between 1 and 1000", nil)
 		return
 	}
 
-	// Get schema registry statistics
-	stats := s.schemaManager.GetRegistry().GetStats()
+	var since *time.Time
+	if value := c.Query("since"); value != "" {
+		parsed, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			window, durationErr := time.ParseDuration(value)
+			if durationErr != nil || window <= 0 {
+				s.respondWithError(c, http.StatusBadRequest, "INVALID_SINCE_FORMAT",
+					"Since parameter must be an RFC3339 timestamp or a positive duration such as 1h", nil)
+				return
+			}
+			parsed = time.Now().UTC().Add(-window)
+		}
+		since = &parsed
+	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"stats":     stats,
-		"timestamp": time.Now().UTC(),
-	})
+	deliveryErrors, err := s.storage.ListDeliveryErrors(c.Request.Context(), since, limit)
+	if err != nil {
+		s.respondWithError(c, http.StatusInternalServerError, "DELIVERY_ERRORS_LIST_FAILED",
+			"Failed to list delivery errors", nil)
+		return
+	}
+	if deliveryErrors == nil {
+		deliveryErrors = []types.DeliveryError{}
+	}
+
+	response := gin.H{
+		"errors": deliveryErrors,
+		"total":  len(deliveryErrors),
+		"limit":  limit,
+	}
+	if since != nil {
+		response["since"] = since.UTC()
+	}
+	s.respondWithSuccess(c, http.StatusOK, response)
 }
 
-// handleRegisterAgent handles POST /v1/admin/agents
-func (s *Server) handleRegisterAgent(c *gin.Context) {
-	var agent agents.LocalAgent
+// pendingInboxEntry is a message waiting in an inbox with how long it has
+// been waiting
+type pendingInboxEntry struct {
+	types.PendingInboxMessage
+	WaitingSeconds int64 `json:"waiting_seconds"`
+}
 
-	if err := c.ShouldBindJSON(&agent); err != nil {
-		s.respondWithError(c, http.StatusBadRequest, "INVALID_REQUEST_FORMAT",
-			"Invalid agent registration format", map[string]interface{}{
-				"parse_error": err.Error(),
-			})
+// handleListPendingInbox handles GET /v1/admin/inbox/pending
+// Lists messages waiting in any local recipient's inbox, oldest first, so
+// operators can see the inbox backlog across all agents
+func (s *Server) handleListPendingInbox(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "100"))
+	if err != nil || limit < 1 || limit > 1000 {
+		s.respondWithError(c, http.StatusBadRequest, "INVALID_LIMIT",
+			"Limit must be between 1 and 1000", nil)
 		return
 	}
 
-	// Use the agent registry directly
-	if err := s.agentRegistry.RegisterAgent(c.Request.Context(), &agent); err != nil {
-		s.respondWithError(c, http.StatusBadRequest, "AGENT_REGISTRATION_FAILED",
-			"Failed to register agent", map[string]interface{}{
-				"error": err.Error(),
-			})
+	pending, cursor, err := s.storage.ListPendingInbox(c.Request.Context(), c.Query("cursor"), limit)
+	if errors.Is(err, storage.ErrInvalidCursor) {
+		s.respondWithError(c, http.StatusBadRequest, "INVALID_CURSOR",
+			"Cursor must be a next_cursor returned by an earlier page", nil)
+		return
+	}
+	if err != nil {
+		s.respondWithError(c, http.StatusInternalServerError, "PENDING_INBOX_LIST_FAILED",
+			"Failed to list pending inbox messages", nil)
 		return
 	}
 
-	s.respondWithSuccess(c, http.StatusCreated, gin.H{
-		"message": "Agent registered successfully",
-		"agent":   agent,
-	})
-}
+	now := time.Now()
+	entries := make([]pendingInboxEntry, 0, len(pending))
+	for _, message := range pending {
+		entries = append(entries, pendingInboxEntry{
+			PendingInboxMessage: message,
+			WaitingSeconds:      int64(now.Sub(message.Since) / time.Second),
+		})
+	}
 
-// handleUnregisterAgent handles DELETE /v1/admin/agents/:address
-func (s *Server) handleUnregisterAgent(c *gin.Context) {
-	agentName := c.Param("address") // Keep param name for backward compatibility
+	response := gin.H{
+		"messages": entries,
+		"total":    len(entries),
+		"limit":    limit,
+	}
+	if cursor != "" {
+		response["next_cursor"] = cursor
+	}
+	s.respondWithSuccess(c, http.StatusOK, response)
+}
 
-	// Use the agent registry directly
-	if err := s.agentRegistry.UnregisterAgent(c.Request.Context(), agentName); err != nil {
-		s.respondWithError(c, http.StatusBadRequest, "AGENT_UNREGISTRATION_FAILED",
-			"Failed to unregister agent", map[string]interface{}{
-				"error": err.Error(),
+// handleReconcileStatuses handles POST /v1/admin/reconcile
+// Recomputes every message's aggregate status from its recipient statuses
+func (s *Server) handleReconcileStatuses(c *gin.Context) {
+	report, err := reconcileStatuses(c.Request.Context(), s.storage, s.config.Message.ArchiveAddress)
+	if err != nil {
+		s.respondWithError(c, http.StatusInternalServerError, "RECONCILE_FAILED",
+			"Failed to reconcile message statuses", map[string]interface{}{
+				"error":     err.Error(),
+				"checked":   report.Checked,
+				"corrected": report.Corrected,
 			})
 		return
 	}
+	if report.Corrected > 0 {
+		s.logger.Warnf("Corrected the aggregate status of %d of %d messages", report.Corrected, report.Checked)
+	}
 
 	s.respondWithSuccess(c, http.StatusOK, gin.H{
-		"message": "Agent unregistered successfully",
-		"name":    agentName,
+		"checked":     report.Checked,
+		"corrected":   report.Corrected,
+		"corrections": report.Corrections,
+		"timestamp":   time.Now().UTC(),
 	})
 }
 
-// handleListAgents handles GET /v1/admin/agents
-func (s *Server) handleListAgents(c *gin.Context) {
-	// Use the agent registry directly
-	agents := s.agentRegistry.GetAllAgents(c.Request.Context())
+// handleInspectDiscovery handles GET /v1/admin/discovery/:domain
+// Looks up a domain the way deliveries do and explains the result, from the
+// cache unless fresh=true
+func (s *Server) handleInspectDiscovery(c *gin.Context) {
+	domain := strings.TrimSpace(c.Param("domain"))
+	inspector, ok := s.discovery.(discovery.Inspector)
+	if !ok {
+		s.respondWithError(c, http.StatusServiceUnavailable, "DISCOVERY_UNAVAILABLE",
+			"Discovery service cannot be inspected", nil)
+		return
+	}
+
+	inspection := inspector.Inspect(c.Request.Context(), domain, c.Query("fresh") == "true")
+
+	// Deliveries also refuse a gateway URL they cannot use
+	response := gin.H{
+		"inspection": inspection,
+		"timestamp":  time.Now().UTC(),
+	}
+	if inspection.Capabilities != nil {
+		if err := discovery.ValidateGatewayURL(inspection.Capabilities.Gateway, s.config.DNS.AllowHTTP); err != nil {
+			response["gateway_error"] = err.Error()
+		}
+	}
+	s.respondWithSuccess(c, http.StatusOK, response)
+}
+
+// handleGetConfig handles GET /v1/admin/config
+// Returns the configuration the gateway runs with, after layering the files
+// and environment variables, with secrets redacted
+func (s *Server) handleGetConfig(c *gin.Context) {
+	effective, err := s.config.Effective()
+	if err != nil {
+		s.respondWithError(c, http.StatusInternalServerError, "CONFIG_UNAVAILABLE",
+			"Failed to report the configuration", map[string]interface{}{
+				"error": err.Error(),
+			})
+		return
+	}
 
+	files := s.config.Files
+	if files == nil {
+		files = []string{}
+	}
 	s.respondWithSuccess(c, http.StatusOK, gin.H{
-		"agents": agents,
-		"count":  len(agents),
+		"config":      effective,
+		"environment": s.config.Environment,
+		"files":       files,
+		"timestamp":   time.Now().UTC(),
 	})
 }
 
@@ -774,6 +2599,19 @@ func (s *Server) handleGetInbox(c *gin.Context) {
 		return // verifyAgentAccess handles the error response
 	}
 
+	switch format := c.Query("format"); format {
+	case "", "json":
+	case "ndjson":
+		s.streamInbox(c, recipient)
+		return
+	default:
+		s.respondWithError(c, http.StatusBadRequest, "INVALID_FORMAT",
+			"format must be json or ndjson", map[string]interface{}{
+				"format": format,
+			})
+		return
+	}
+
 	// Get inbox messages from unified storage and update last access
 	messages, err := s.storage.GetInboxMessages(c.Request.Context(), recipient)
 	if err != nil {
@@ -790,7 +2628,55 @@ func (s *Server) handleGetInbox(c *gin.Context) {
 	})
 }
 
-// handleAcknowledgeMessage handles DELETE /v1/inbox/:recipient/:messageId
+// inboxPageSize bounds how many messages an NDJSON inbox stream holds in
+// memory at once
+const inboxPageSize = 100
+
+// streamInbox writes the recipient's inbox as NDJSON, one message per line,
+// reading it from storage a page at a time
+func (s *Server) streamInbox(c *gin.Context, recipient string) {
+	ctx := c.Request.Context()
+
+	// Fetch the first page before committing to a 200 so storage failures
+	// can still be reported as a regular error response
+	messages, cursor, err := s.storage.ListInboxMessages(ctx, recipient, "", inboxPageSize)
+	if err != nil {
+		s.respondWithError(c, http.StatusInternalServerError, "INBOX_ACCESS_FAILED",
+			"Failed to retrieve inbox messages", nil)
+		return
+	}
+	s.agentRegistry.UpdateLastAccess(ctx, recipient)
+
+	c.Header("Content-Type", "application/x-ndjson")
+	c.Status(http.StatusOK)
+
+	encoder := json.NewEncoder(c.Writer)
+	for {
+		for _, message := range messages {
+			if err := encoder.Encode(message); err != nil {
+				s.logger.WithContext(ctx).Error("Inbox stream aborted", err)
+				return
+			}
+		}
+		c.Writer.Flush()
+
+		if cursor == "" {
+			return
+		}
+		messages, cursor, err = s.storage.ListInboxMessages(ctx, recipient, cursor, inboxPageSize)
+		if err != nil {
+			// Headers are already sent, so report the failure in-band. A
+			// message never has a top-level error field.
+			s.logger.WithContext(ctx).Error("Inbox stream failed mid-stream", err)
+			_ = encoder.Encode(gin.H{"error": err.Error()})
+			return
+		}
+	}
+}
+
+// handleAcknowledgeMessage handles DELETE /v1/inbox/:recipient/:messageId.
+// The body is optional; a response in it is recorded on the recipient's
+// status and sent back to the sender when the message requires a response.
 func (s *Server) handleAcknowledgeMessage(c *gin.Context) {
 	recipient := c.Param("recipient")
 	messageID := c.Param("messageId")
@@ -800,8 +2686,22 @@ func (s *Server) handleAcknowledgeMessage(c *gin.Context) {
 		return // verifyAgentAccess handles the error response
 	}
 
+	var req struct {
+		Response json.RawMessage `json:"response"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		s.respondWithError(c, http.StatusBadRequest, "INVALID_REQUEST_FORMAT",
+			"Invalid request format", map[string]interface{}{
+				"parse_error": err.Error(),
+			})
+		return
+	}
+	if string(req.Response) == "null" {
+		req.Response = nil
+	}
+
 	// Acknowledge the message using unified storage and update last access
-	if err := s.storage.AcknowledgeMessage(c.Request.Context(), recipient, messageID); err != nil {
+	if err := s.storage.AcknowledgeMessage(c.Request.Context(), recipient, messageID, req.Response); err != nil {
 		s.respondWithError(c, http.StatusNotFound, "MESSAGE_NOT_FOUND",
 			"Message not found or already acknowledged", map[string]interface{}{
 				"error": err.Error(),
@@ -812,6 +2712,17 @@ func (s *Server) handleAcknowledgeMessage(c *gin.Context) {
 	// Update last access timestamp
 	s.agentRegistry.UpdateLastAccess(c.Request.Context(), recipient)
 
+	s.events.Publish(events.Event{
+		Type:      events.MessageAcknowledged,
+		MessageID: messageID,
+		Recipient: recipient,
+	})
+
+	s.sendReadReceipt(c.Request.Context(), recipient, messageID)
+	if len(req.Response) > 0 {
+		s.sendAckResponse(c.Request.Context(), recipient, messageID, req.Response)
+	}
+
 	s.respondWithSuccess(c, http.StatusOK, gin.H{
 		"message":    "Message acknowledged successfully",
 		"recipient":  recipient,
@@ -819,6 +2730,57 @@ func (s *Server) handleAcknowledgeMessage(c *gin.Context) {
 	})
 }
 
+// isArchiveAddress reports whether address is the archive agent, whose copy
+// of a message stays hidden from the sender
+func (s *Server) isArchiveAddress(address string) bool {
+	archive := s.config.Message.ArchiveAddress
+	return archive != "" && types.NormalizeAddress(address) == types.NormalizeAddress(archive)
+}
+
+// sendReadReceipt tells the sender of an acknowledged message that recipient
+// read it, when the sender asked for a receipt. The receipt is delivered in
+// the background; a failure to send it does not fail the acknowledgement.
+// The archive agent never sends one, which would reveal its copy.
+func (s *Server) sendReadReceipt(ctx context.Context, recipient, messageID string) {
+	if s.isArchiveAddress(recipient) {
+		return
+	}
+	message, err := s.storage.GetMessage(ctx, messageID)
+	if err != nil || !message.RequestReceipt || message.ResponseType == types.ResponseTypeReadReceipt {
+		return
+	}
+
+	receipt, err := processing.NewReadReceipt(s.messageIDs, message, recipient, time.Now())
+	if err == nil {
+		_, err = s.processor.ProcessMessage(ctx, receipt, processing.ProcessingOptions{ImmediatePath: true, Async: true})
+	}
+	if err != nil {
+		s.logger.Warnf("Failed to send read receipt for message %s to %s: %v", messageID, message.Sender, err)
+	}
+}
+
+// sendAckResponse sends the response recipient acknowledged a message with to
+// the message's sender, when the message requires a response. Like a read
+// receipt it is delivered in the background and never fails the
+// acknowledgement, and the archive agent never sends one.
+func (s *Server) sendAckResponse(ctx context.Context, recipient, messageID string, response json.RawMessage) {
+	if s.isArchiveAddress(recipient) {
+		return
+	}
+	message, err := s.storage.GetMessage(ctx, messageID)
+	if err != nil || message.ResponseType != types.ResponseTypeRequired {
+		return
+	}
+
+	reply, err := processing.NewAckResponse(s.messageIDs, message, recipient, response)
+	if err == nil {
+		_, err = s.processor.ProcessMessage(ctx, reply, processing.ProcessingOptions{ImmediatePath: true, Async: true})
+	}
+	if err != nil {
+		s.logger.Warnf("Failed to send acknowledgement response for message %s to %s: %v", messageID, message.Sender, err)
+	}
+}
+
 // verifyAgentAccess checks if the requester can access the specified agent's inbox
 func (s *Server) verifyAgentAccess(c *gin.Context, agentAddress string) bool {
 	// Extract API key from Authorization header
@@ -841,6 +2803,11 @@ func (s *Server) verifyAgentAccess(c *gin.Context, agentAddress string) bool {
 		return false
 	}
 
+	// An inbox token stands in for the API key
+	if s.inboxTokens != nil && inboxtoken.IsToken(apiKey) {
+		return s.verifyInboxToken(c, agentAddress, apiKey)
+	}
+
 	// Verify agent access
 	if !s.agentRegistry.VerifyAPIKey(c.Request.Context(), agentAddress, apiKey) {
 		s.respondWithError(c, http.StatusForbidden, "ACCESS_DENIED",
@@ -853,6 +2820,56 @@ func (s *Server) verifyAgentAccess(c *gin.Context, agentAddress string) bool {
 	return true
 }
 
+// verifyInboxToken checks that token is valid and was minted for the agent
+// whose inbox is accessed, and that the agent is still registered
+func (s *Server) verifyInboxToken(c *gin.Context, agentAddress, token string) bool {
+	claims, err := s.inboxTokens.Verify(token, time.Now())
+	if errors.Is(err, inboxtoken.ErrExpired) {
+		s.respondWithError(c, http.StatusUnauthorized, "TOKEN_EXPIRED",
+			"Inbox token has expired", map[string]interface{}{
+				"agent": agentAddress,
+			})
+		return false
+	}
+	if err != nil {
+		s.respondWithError(c, http.StatusUnauthorized, "INVALID_TOKEN",
+			"Invalid inbox token", map[string]interface{}{
+				"agent": agentAddress,
+			})
+		return false
+	}
+
+	address := agentAddress
+	if !s.addresses.IsValid(address) && !strings.Contains(address, "@") {
+		address += "@" + s.config.Server.Domain
+	}
+	if !strings.EqualFold(claims.Agent, address) {
+		s.respondWithError(c, http.StatusForbidden, "ACCESS_DENIED",
+			"Inbox token was not issued for this agent", map[string]interface{}{
+				"agent": agentAddress,
+			})
+		return false
+	}
+	agent, err := s.agentRegistry.GetAgent(c.Request.Context(), address)
+	if err != nil {
+		s.respondWithError(c, http.StatusForbidden, "ACCESS_DENIED",
+			"Agent is no longer registered", map[string]interface{}{
+				"agent": agentAddress,
+			})
+		return false
+	}
+	// A token outlives neither a re-registration of the agent nor the
+	// rotation of its API key
+	if claims.IssuedBefore(agent.CreatedAt) || claims.IssuedBefore(agent.KeyRotatedAt) {
+		s.respondWithError(c, http.StatusUnauthorized, "TOKEN_REVOKED",
+			"Inbox token was issued before the agent was registered or its key rotated", map[string]interface{}{
+				"agent": agentAddress,
+			})
+		return false
+	}
+	return true
+}
+
 // handleDiscoverAgents handles GET /v1/discovery/agents
 // Returns all agents registered on this gateway
 func (s *Server) handleDiscoverAgents(c *gin.Context) {
@@ -923,3 +2940,92 @@ func (s *Server) handleDiscoverAgentsByDomain(c *gin.Context) {
 	// Delegate to the main agent discovery handler
 	s.handleDiscoverAgents(c)
 }
+
+// agentSchema describes one schema declaration of an agent
+type agentSchema struct {
+	Schema      string   `json:"schema"`
+	Wildcard    bool     `json:"wildcard"`
+	Description string   `json:"description"`
+	Matches     []string `json:"matches,omitempty"` // registered schemas a wildcard covers
+}
+
+// handleDiscoverAgentSchemas handles GET /v1/discovery/agents/:domain/:address/schemas
+// Returns the schemas an agent accepts, with wildcard declarations expanded
+// to the registered schemas they cover
+func (s *Server) handleDiscoverAgentSchemas(c *gin.Context) {
+	domain := c.Param("domain")
+	if !strings.EqualFold(domain, s.config.Server.Domain) {
+		s.respondWithError(c, http.StatusNotFound, "DOMAIN_NOT_FOUND",
+			"Domain not served by this gateway", map[string]interface{}{
+				"requested_domain": domain,
+				"served_domain":    s.config.Server.Domain,
+			})
+		return
+	}
+
+	// The agent may be given by name or by full address
+	address := c.Param("address")
+	if name, addressDomain, ok := strings.Cut(address, "@"); ok {
+		if !strings.EqualFold(addressDomain, domain) {
+			s.respondWithError(c, http.StatusBadRequest, "INVALID_AGENT_ADDRESS",
+				"Agent address is not in the requested domain", map[string]interface{}{
+					"address": address,
+					"domain":  domain,
+				})
+			return
+		}
+		address = name
+	}
+	address = types.NormalizeAddress(address + "@" + s.config.Server.Domain)
+
+	ctx := c.Request.Context()
+	agent, err := s.agentRegistry.GetAgent(ctx, address)
+	if errors.Is(err, agents.ErrAgentNotFound) {
+		s.respondWithError(c, http.StatusNotFound, "AGENT_NOT_FOUND",
+			"Agent not found", map[string]interface{}{
+				"address": address,
+			})
+		return
+	}
+	if err != nil {
+		s.respondWithError(c, http.StatusInternalServerError, "AGENT_LOOKUP_FAILED",
+			"Failed to look up the agent", nil)
+		return
+	}
+
+	// Wildcards are expanded against the registered schemas when a schema
+	// registry is configured
+	var registered []schema.SchemaIdentifier
+	if s.schemaManager != nil {
+		registered, err = s.schemaManager.ListSchemas(ctx, "")
+		if err != nil {
+			s.logger.Warnf("Failed to list schemas for agent %s: %v", address, err)
+		}
+	}
+
+	schemas := make([]agentSchema, 0, len(agent.SupportedSchemas))
+	for _, supported := range agent.SupportedSchemas {
+		entry := agentSchema{
+			Schema:      supported,
+			Description: "accepts exactly this schema version",
+		}
+		if prefix, ok := strings.CutSuffix(supported, "*"); ok {
+			entry.Wildcard = true
+			entry.Description = fmt.Sprintf("accepts any schema whose identifier starts with %q", prefix)
+			for _, id := range registered {
+				if validation.SchemaMatches(supported, id.String()) {
+					entry.Matches = append(entry.Matches, id.String())
+				}
+			}
+			sort.Strings(entry.Matches)
+		}
+		schemas = append(schemas, entry)
+	}
+
+	s.respondWithSuccess(c, http.StatusOK, gin.H{
+		"address":         agent.Address,
+		"requires_schema": agent.RequiresSchema,
+		"schemas":         schemas,
+		"timestamp":       time.Now().UTC(),
+	})
+}
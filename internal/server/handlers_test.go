@@ -23,6 +23,13 @@ import (
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
 	"testing"
 	"time"
 
@@ -31,26 +38,37 @@ import (
 	"github.com/amtp-protocol/agentry/internal/agents"
 	"github.com/amtp-protocol/agentry/internal/config"
 	"github.com/amtp-protocol/agentry/internal/discovery"
+	"github.com/amtp-protocol/agentry/internal/features"
+	"github.com/amtp-protocol/agentry/internal/inboxtoken"
 	"github.com/amtp-protocol/agentry/internal/logging"
 	"github.com/amtp-protocol/agentry/internal/metrics"
+	"github.com/amtp-protocol/agentry/internal/middleware"
 	"github.com/amtp-protocol/agentry/internal/processing"
+	"github.com/amtp-protocol/agentry/internal/schema"
 	"github.com/amtp-protocol/agentry/internal/storage"
 	"github.com/amtp-protocol/agentry/internal/types"
 	"github.com/amtp-protocol/agentry/internal/validation"
+	"github.com/amtp-protocol/agentry/pkg/uuid"
 )
 
 // MockMessageProcessor for testing
 type MockMessageProcessor struct {
 	processResult *processing.ProcessingResult
 	processError  error
+	lastOptions   processing.ProcessingOptions
+	lastMessage   *types.Message
 	messages      map[string]*types.Message
 	statuses      map[string]*types.MessageStatus
 }
 
 type MockStorage struct {
-	messages map[string]*types.Message
-	statuses map[string]*types.MessageStatus
-	agents   map[string]*agents.LocalAgent
+	messages    map[string]*types.Message
+	statuses    map[string]*types.MessageStatus
+	agents      map[string]*agents.LocalAgent
+	rawRequests map[string]*types.RawRequest
+
+	lastFilter      storage.MessageFilter
+	lastErrorsSince *time.Time
 }
 
 func NewMockMessageProcessor() *MockMessageProcessor {
@@ -62,9 +80,10 @@ func NewMockMessageProcessor() *MockMessageProcessor {
 
 func NewMockStorage() *MockStorage {
 	return &MockStorage{
-		messages: make(map[string]*types.Message),
-		statuses: make(map[string]*types.MessageStatus),
-		agents:   make(map[string]*agents.LocalAgent),
+		messages:    make(map[string]*types.Message),
+		statuses:    make(map[string]*types.MessageStatus),
+		agents:      make(map[string]*agents.LocalAgent),
+		rawRequests: make(map[string]*types.RawRequest),
 	}
 }
 
@@ -86,7 +105,21 @@ func (m *MockStorage) DeleteMessage(ctx context.Context, messageID string) error
 	return nil
 }
 
+func (m *MockStorage) StoreRawRequest(ctx context.Context, raw *types.RawRequest) error {
+	m.rawRequests[raw.MessageID] = raw
+	return nil
+}
+
+func (m *MockStorage) GetRawRequest(ctx context.Context, messageID string) (*types.RawRequest, error) {
+	raw, exists := m.rawRequests[messageID]
+	if !exists {
+		return nil, fmt.Errorf("raw request not found: %s", messageID)
+	}
+	return raw, nil
+}
+
 func (m *MockStorage) ListMessages(ctx context.Context, filter storage.MessageFilter) ([]*types.Message, error) {
+	m.lastFilter = filter
 	var messages []*types.Message
 	for _, msg := range m.messages {
 		messages = append(messages, msg)
@@ -94,6 +127,10 @@ func (m *MockStorage) ListMessages(ctx context.Context, filter storage.MessageFi
 	return messages, nil
 }
 
+func (m *MockStorage) CountMessages(ctx context.Context, filter storage.MessageFilter) (int64, error) {
+	return int64(len(m.messages)), nil
+}
+
 func (m *MockStorage) StoreStatus(ctx context.Context, messageID string, status *types.MessageStatus) error {
 	m.statuses[messageID] = status
 	return nil
@@ -106,6 +143,15 @@ func (m *MockStorage) GetStatus(ctx context.Context, messageID string) (*types.M
 	return nil, fmt.Errorf("message status not found: %s", messageID)
 }
 
+func (m *MockStorage) GetStatusByIdempotencyKey(ctx context.Context, idempotencyKey string) (*types.MessageStatus, error) {
+	for id, msg := range m.messages {
+		if msg.IdempotencyKey == idempotencyKey {
+			return m.GetStatus(ctx, id)
+		}
+	}
+	return nil, fmt.Errorf("message not found for idempotency key: %s", idempotencyKey)
+}
+
 func (m *MockStorage) UpdateStatus(ctx context.Context, messageID string, updater storage.StatusUpdater) error {
 	if status, exists := m.statuses[messageID]; exists {
 		return updater(status)
@@ -118,6 +164,32 @@ func (m *MockStorage) DeleteStatus(ctx context.Context, messageID string) error
 	return nil
 }
 
+func (m *MockStorage) GetStatuses(ctx context.Context, messageIDs []string) (map[string]*types.MessageStatus, error) {
+	statuses := make(map[string]*types.MessageStatus, len(messageIDs))
+	for _, messageID := range messageIDs {
+		if status, exists := m.statuses[messageID]; exists {
+			statuses[messageID] = status
+		}
+	}
+	return statuses, nil
+}
+
+func (m *MockStorage) ListStatuses(ctx context.Context, cursor string, limit int) ([]*types.MessageStatus, string, error) {
+	var statuses []*types.MessageStatus
+	for messageID, status := range m.statuses {
+		if messageID > cursor {
+			statuses = append(statuses, status)
+		}
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].MessageID < statuses[j].MessageID })
+
+	if len(statuses) > limit {
+		statuses = statuses[:limit]
+		return statuses, statuses[limit-1].MessageID, nil
+	}
+	return statuses, "", nil
+}
+
 func (m *MockStorage) GetInboxMessages(ctx context.Context, recipient string) ([]*types.Message, error) {
 	var messages []*types.Message
 	for _, msg := range m.messages {
@@ -131,6 +203,23 @@ func (m *MockStorage) GetInboxMessages(ctx context.Context, recipient string) ([
 	return messages, nil
 }
 
+func (m *MockStorage) ListInboxMessages(ctx context.Context, recipient, cursor string, limit int) ([]*types.Message, string, error) {
+	messages, _ := m.GetInboxMessages(ctx, recipient)
+	var page []*types.Message
+	for _, msg := range messages {
+		if msg.MessageID > cursor {
+			page = append(page, msg)
+		}
+	}
+	sort.Slice(page, func(i, j int) bool { return page[i].MessageID < page[j].MessageID })
+
+	if len(page) > limit {
+		page = page[:limit]
+		return page, page[limit-1].MessageID, nil
+	}
+	return page, "", nil
+}
+
 func (m *MockStorage) CreateAgent(ctx context.Context, agent *agents.LocalAgent) error {
 	agentCopy := *agent
 	m.agents[agent.Address] = &agentCopy
@@ -140,13 +229,23 @@ func (m *MockStorage) CreateAgent(ctx context.Context, agent *agents.LocalAgent)
 func (m *MockStorage) GetAgent(ctx context.Context, agentAddress string) (*agents.LocalAgent, error) {
 	agent, exists := m.agents[agentAddress]
 	if !exists {
-		return nil, fmt.Errorf("agent not found: %s", agentAddress)
+		return nil, fmt.Errorf("%w: %s", agents.ErrAgentNotFound, agentAddress)
 	}
 
 	agentCopy := *agent
 	return &agentCopy, nil
 }
 
+func (m *MockStorage) GetAgentByAPIKey(ctx context.Context, hashedKey string) (*agents.LocalAgent, error) {
+	for _, agent := range m.agents {
+		if agent.APIKey == hashedKey {
+			agentCopy := *agent
+			return &agentCopy, nil
+		}
+	}
+	return nil, agents.ErrAgentNotFound
+}
+
 func (m *MockStorage) UpdateAgent(ctx context.Context, agent *agents.LocalAgent) error {
 	if agent == nil {
 		return fmt.Errorf("agent cannot be nil")
@@ -160,6 +259,18 @@ func (m *MockStorage) UpdateAgent(ctx context.Context, agent *agents.LocalAgent)
 	return nil
 }
 
+func (m *MockStorage) UpdateLastAccess(ctx context.Context, agentAddress string, at time.Time) error {
+	agent, exists := m.agents[agentAddress]
+	if !exists {
+		return fmt.Errorf("agent not found: %s", agentAddress)
+	}
+
+	agentCopy := *agent
+	agentCopy.LastAccess = at
+	m.agents[agentAddress] = &agentCopy
+	return nil
+}
+
 func (m *MockStorage) DeleteAgent(ctx context.Context, agentAddress string) error {
 	if _, exists := m.agents[agentAddress]; !exists {
 		return fmt.Errorf("agent not found: %s", agentAddress)
@@ -194,7 +305,7 @@ func (m *MockStorage) GetSupportedSchemas(ctx context.Context) ([]string, error)
 	return schemas, nil
 }
 
-func (m *MockStorage) AcknowledgeMessage(ctx context.Context, recipient, messageID string) error {
+func (m *MockStorage) AcknowledgeMessage(ctx context.Context, recipient, messageID string, response json.RawMessage) error {
 	// Check if message exists
 	if _, exists := m.messages[messageID]; !exists {
 		return fmt.Errorf("message not found: %s", messageID)
@@ -202,6 +313,78 @@ func (m *MockStorage) AcknowledgeMessage(ctx context.Context, recipient, message
 	return nil
 }
 
+func (m *MockStorage) DrainInbox(ctx context.Context, recipient string) (int64, error) {
+	var drained int64
+	for _, msg := range m.messages {
+		for _, r := range msg.Recipients {
+			if r == recipient {
+				drained++
+			}
+		}
+	}
+	return drained, nil
+}
+
+func (m *MockStorage) ListPendingInbox(ctx context.Context, cursor string, limit int) ([]types.PendingInboxMessage, string, error) {
+	return nil, "", nil
+}
+
+func (m *MockStorage) ListDeliveryErrors(ctx context.Context, since *time.Time, limit int) ([]types.DeliveryError, error) {
+	m.lastErrorsSince = since
+	var deliveryErrors []types.DeliveryError
+	for messageID, status := range m.statuses {
+		for _, recipientStatus := range status.Recipients {
+			if recipientStatus.Status == types.StatusFailed && len(deliveryErrors) < limit {
+				deliveryErrors = append(deliveryErrors, types.DeliveryError{
+					MessageID:    messageID,
+					Recipient:    recipientStatus.Address,
+					ErrorCode:    recipientStatus.ErrorCode,
+					ErrorMessage: recipientStatus.ErrorMessage,
+					Attempts:     recipientStatus.Attempts,
+					Timestamp:    recipientStatus.Timestamp,
+				})
+			}
+		}
+	}
+	return deliveryErrors, nil
+}
+
+func (m *MockStorage) ExportMessages(ctx context.Context, address, cursor string, limit int) ([]*types.Message, string, error) {
+	var messages []*types.Message
+	for _, msg := range m.messages {
+		involved := msg.Sender == address
+		for _, r := range msg.Recipients {
+			involved = involved || r == address
+		}
+		if involved && msg.MessageID > cursor {
+			messages = append(messages, msg)
+		}
+	}
+	sort.Slice(messages, func(i, j int) bool { return messages[i].MessageID < messages[j].MessageID })
+
+	if len(messages) > limit {
+		messages = messages[:limit]
+		return messages, messages[limit-1].MessageID, nil
+	}
+	return messages, "", nil
+}
+
+func (m *MockStorage) ListMessagesBySchema(ctx context.Context, schemaIDs []string, cursor string, limit int) ([]*types.Message, string, error) {
+	var messages []*types.Message
+	for _, msg := range m.messages {
+		if slices.Contains(schemaIDs, msg.Schema) && msg.MessageID > cursor {
+			messages = append(messages, msg)
+		}
+	}
+	sort.Slice(messages, func(i, j int) bool { return messages[i].MessageID < messages[j].MessageID })
+
+	if len(messages) > limit {
+		messages = messages[:limit]
+		return messages, messages[limit-1].MessageID, nil
+	}
+	return messages, "", nil
+}
+
 func (m *MockStorage) Close() error {
 	return nil
 }
@@ -215,6 +398,8 @@ func (m *MockStorage) GetStats(ctx context.Context) (storage.StorageStats, error
 }
 
 func (m *MockMessageProcessor) ProcessMessage(ctx context.Context, message *types.Message, options processing.ProcessingOptions) (*processing.ProcessingResult, error) {
+	m.lastOptions = options
+	m.lastMessage = message
 	if m.processError != nil {
 		return nil, m.processError
 	}
@@ -251,6 +436,12 @@ func (m *MockMessageProcessor) ProcessMessage(ctx context.Context, message *type
 		},
 		ProcessedAt: time.Now().UTC(),
 	}
+	if options.Async {
+		// Async acceptance reports the message as queued before delivery
+		result.Status = types.StatusQueued
+		result.Recipients[0].Status = types.StatusQueued
+		result.Recipients[0].Attempts = 0
+	}
 
 	// Store the message and status
 	m.messages[message.MessageID] = message
@@ -326,6 +517,8 @@ func createTestServer() *Server {
 
 	server := &Server{
 		config:        cfg,
+		messageIDs:    uuid.V7,
+		addresses:     types.DefaultAddressSchemes,
 		router:        router,
 		discovery:     discoveryService,
 		validator:     validator,
@@ -392,6 +585,70 @@ func TestHandleSendMessage_Success(t *testing.T) {
 	}
 }
 
+func TestHandleSendMessage_PreferHeader(t *testing.T) {
+	tests := []struct {
+		name              string
+		prefer            []string
+		expectedAsync     bool
+		expectedCode      int
+		expectedStatus    string
+		expectedPreferred string
+	}{
+		{"no preference", nil, false, http.StatusOK, "delivered", ""},
+		{"respond-async", []string{"respond-async"}, true, http.StatusAccepted, "queued", "respond-async"},
+		{"respond-async with other preferences", []string{"return=minimal, Respond-Async; wait=5"}, true, http.StatusAccepted, "queued", "respond-async"},
+		{"respond-sync", []string{"respond-sync"}, false, http.StatusOK, "delivered", "respond-sync"},
+		{"respond-sync wins over respond-async", []string{"respond-async", "respond-sync"}, false, http.StatusOK, "delivered", "respond-sync"},
+		{"unrelated preference", []string{"return=representation"}, false, http.StatusOK, "delivered", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := createTestServer()
+			processor := server.processor.(*MockMessageProcessor)
+
+			body, err := json.Marshal(types.SendMessageRequest{
+				Sender:     "test@example.com",
+				Recipients: []string{"recipient@test.com"},
+				Payload:    json.RawMessage(`{"message": "Hello, World!"}`),
+			})
+			if err != nil {
+				t.Fatalf("Failed to marshal request body: %v", err)
+			}
+
+			req := httptest.NewRequest("POST", "/v1/messages", bytes.NewBuffer(body))
+			req.Header.Set("Content-Type", "application/json")
+			for _, value := range tt.prefer {
+				req.Header.Add("Prefer", value)
+			}
+
+			rr := httptest.NewRecorder()
+			server.router.ServeHTTP(rr, req)
+
+			if rr.Code != tt.expectedCode {
+				t.Errorf("Expected status code %d, got %d", tt.expectedCode, rr.Code)
+			}
+			if processor.lastOptions.Async != tt.expectedAsync {
+				t.Errorf("Expected Async=%t, got %t", tt.expectedAsync, processor.lastOptions.Async)
+			}
+			if applied := rr.Header().Get("Preference-Applied"); applied != tt.expectedPreferred {
+				t.Errorf("Expected Preference-Applied %q, got %q", tt.expectedPreferred, applied)
+			}
+
+			var response types.SendMessageResponse
+			if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+				t.Fatalf("Failed to unmarshal response: %v", err)
+			}
+			if response.MessageID == "" {
+				t.Error("Expected message ID to be set")
+			}
+			if response.Status != tt.expectedStatus {
+				t.Errorf("Expected status %q, got %q", tt.expectedStatus, response.Status)
+			}
+		})
+	}
+}
+
 func TestHandleSendMessage_InvalidJSON(t *testing.T) {
 	server := createTestServer()
 
@@ -457,130 +714,298 @@ func TestHandleSendMessage_ValidationFailed(t *testing.T) {
 	}
 }
 
-func TestHandleSendMessage_ProcessingFailed(t *testing.T) {
+func TestHandleSendMessage_AttachmentLimits(t *testing.T) {
 	server := createTestServer()
-	mockProcessor := server.processor.(*MockMessageProcessor)
-	mockProcessor.SetProcessError(fmt.Errorf("processing failed"))
+	server.validator.SetAttachmentLimits(1, 1024)
 
-	requestBody := types.SendMessageRequest{
-		Sender:     "test@example.com",
-		Recipients: []string{"recipient@test.com"},
-		Subject:    "Test Message",
-		Payload:    json.RawMessage(`{"message": "Hello, World!"}`),
+	attachment := types.Attachment{
+		Filename:    "test.pdf",
+		ContentType: "application/pdf",
+		Size:        512,
+		Hash:        "sha256:abcdef1234567890",
+		URL:         "https://example.com/files/test.pdf",
 	}
+	large := attachment
+	large.Size = 2048
 
-	body, err := json.Marshal(requestBody)
-	if err != nil {
-		t.Fatalf("Failed to marshal request body: %v", err)
+	tests := []struct {
+		name        string
+		attachments []types.Attachment
+		wantCode    string
+	}{
+		{"too many attachments", []types.Attachment{attachment, attachment}, "TOO_MANY_ATTACHMENTS"},
+		{"attachments too large", []types.Attachment{large}, "ATTACHMENTS_TOO_LARGE"},
 	}
 
-	req, err := http.NewRequest("POST", "/v1/messages", bytes.NewBuffer(body))
-	if err != nil {
-		t.Fatalf("Failed to create request: %v", err)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, _ := json.Marshal(types.SendMessageRequest{
+				Sender:      "sender@test.com",
+				Recipients:  []string{"recipient@test.com"},
+				Subject:     "Test Message",
+				Attachments: tt.attachments,
+			})
+			req, _ := http.NewRequest("POST", "/v1/messages", bytes.NewBuffer(body))
+			req.Header.Set("Content-Type", "application/json")
+
+			rr := httptest.NewRecorder()
+			server.router.ServeHTTP(rr, req)
+
+			if rr.Code != http.StatusBadRequest {
+				t.Errorf("Expected status code %d, got %d", http.StatusBadRequest, rr.Code)
+			}
+			var errorResponse types.ErrorResponse
+			if err := json.Unmarshal(rr.Body.Bytes(), &errorResponse); err != nil {
+				t.Fatalf("Failed to unmarshal error response: %v", err)
+			}
+			if errorResponse.Error.Code != tt.wantCode {
+				t.Errorf("Expected error code %s, got %s", tt.wantCode, errorResponse.Error.Code)
+			}
+		})
+	}
+}
+
+func TestHandleSendMessage_CoordinationLimits(t *testing.T) {
+	tests := []struct {
+		err    error
+		status int
+		code   string
+	}{
+		{processing.ErrTooManyCoordinatedRecipients, http.StatusBadRequest, "TOO_MANY_COORDINATED_RECIPIENTS"},
+		{processing.ErrCoordinationTimeoutTooLong, http.StatusBadRequest, "COORDINATION_TIMEOUT_TOO_LONG"},
+		{processing.ErrTooManyCoordinations, http.StatusServiceUnavailable, "COORDINATION_CAPACITY_EXCEEDED"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.code, func(t *testing.T) {
+			server := createTestServer()
+			server.processor.(*MockMessageProcessor).processError = fmt.Errorf("%w: the limit is 1", tt.err)
+
+			body, _ := json.Marshal(types.SendMessageRequest{
+				Sender:       "test@localhost",
+				Recipients:   []string{"a@localhost", "b@localhost"},
+				Payload:      json.RawMessage(`{"task":"review"}`),
+				Coordination: &types.CoordinationConfig{Type: "parallel", Timeout: 60},
+			})
+			req := httptest.NewRequest("POST", "/v1/messages", bytes.NewBuffer(body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			server.router.ServeHTTP(w, req)
+
+			if w.Code != tt.status || !strings.Contains(w.Body.String(), tt.code) {
+				t.Errorf("Expected %d %s, got %d: %s", tt.status, tt.code, w.Code, w.Body.String())
+			}
+		})
 	}
+}
+
+func TestHandleSendMessage_PayloadTooComplex(t *testing.T) {
+	server := createTestServer()
+	server.validator.SetPayloadLimits(8, 100)
+
+	body, _ := json.Marshal(types.SendMessageRequest{
+		Sender:     "sender@test.com",
+		Recipients: []string{"recipient@test.com"},
+		Subject:    "Test Message",
+		Payload:    json.RawMessage(strings.Repeat(`{"a":`, 9) + "1" + strings.Repeat("}", 9)),
+	})
+	req, _ := http.NewRequest("POST", "/v1/messages", bytes.NewBuffer(body))
 	req.Header.Set("Content-Type", "application/json")
 
 	rr := httptest.NewRecorder()
 	server.router.ServeHTTP(rr, req)
 
-	if rr.Code != http.StatusInternalServerError {
-		t.Errorf("Expected status code %d, got %d", http.StatusInternalServerError, rr.Code)
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("Expected status code %d, got %d", http.StatusBadRequest, rr.Code)
 	}
-
 	var errorResponse types.ErrorResponse
-	err = json.Unmarshal(rr.Body.Bytes(), &errorResponse)
-	if err != nil {
+	if err := json.Unmarshal(rr.Body.Bytes(), &errorResponse); err != nil {
 		t.Fatalf("Failed to unmarshal error response: %v", err)
 	}
-
-	if errorResponse.Error.Code != "PROCESSING_FAILED" {
-		t.Errorf("Expected error code 'PROCESSING_FAILED', got %s", errorResponse.Error.Code)
+	if errorResponse.Error.Code != "PAYLOAD_TOO_COMPLEX" {
+		t.Errorf("Expected error code PAYLOAD_TOO_COMPLEX, got %s", errorResponse.Error.Code)
 	}
 }
 
-func TestHandleGetMessage_Success(t *testing.T) {
+func TestHandleSendMessage_EncryptedPassthrough(t *testing.T) {
+	sm, err := schema.NewManager(schema.ManagerConfig{
+		RegistryType:  "local",
+		LocalRegistry: schema.LocalRegistryConfig{BasePath: t.TempDir(), CreateDirs: true},
+		Validation:    schema.ValidatorConfig{Enabled: true, MaxPayloadSize: 1 << 20},
+		Pipeline:      schema.PipelineConfig{Enabled: true},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create schema manager: %v", err)
+	}
 	server := createTestServer()
-	mockStorage := server.storage.(*MockStorage)
+	server.schemaManager = sm
+	server.validator = validation.NewWithSchemaManager(server.config.Message.MaxSize, sm)
+	server.validator.SetPayloadLimits(2, 2)
+	server.config.Message.SchemaAutoDetect = true
 
-	// First, send a message to store it
-	message := &types.Message{
-		Version:        "1.0",
-		MessageID:      "01234567-89ab-7def-8123-456789abcdef",
-		IdempotencyKey: "01234567-89ab-4def-8123-456789abcdef",
-		Timestamp:      time.Now().UTC(),
-		Sender:         "test@example.com",
-		Recipients:     []string{"recipient@test.com"},
-		Subject:        "Test Message",
-		Payload:        json.RawMessage(`{"message": "Hello, World!"}`),
+	definition := `{"type":"object","required":["order_id"],"properties":{"order_id":{"type":"string"}}}`
+	req := httptest.NewRequest("POST", "/v1/admin/schemas", bytes.NewBufferString(`{"id":"agntcy:commerce.order.v1","definition":`+definition+`}`))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Failed to register schema: %d %s", w.Code, w.Body.String())
 	}
-	mockStorage.messages[message.MessageID] = message
 
-	req, err := http.NewRequest("GET", "/v1/messages/"+message.MessageID, nil)
-	if err != nil {
-		t.Fatalf("Failed to create request: %v", err)
+	// Ciphertext is a JSON string, so neither the schema nor the payload
+	// structure limits apply to it
+	ciphertext := json.RawMessage(`"eyJ7Ijp7Ijp7Ijp7fX19fQ.{[[[[]]]]}.bm90IHJlYWQ"`)
+
+	send := func(schemaID string, encrypted bool, payload json.RawMessage) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(types.SendMessageRequest{
+			Sender:     "test@example.com",
+			Recipients: []string{"recipient@test.com"},
+			Schema:     schemaID,
+			Payload:    payload,
+			Encrypted:  encrypted,
+		})
+		req := httptest.NewRequest("POST", "/v1/messages", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		server.router.ServeHTTP(w, req)
+		return w
 	}
 
-	rr := httptest.NewRecorder()
-	server.router.ServeHTTP(rr, req)
+	for _, schemaID := range []string{"agntcy:commerce.order.v1", ""} {
+		mockProcessor := server.processor.(*MockMessageProcessor)
+		mockProcessor.lastMessage = nil
 
-	if rr.Code != http.StatusOK {
-		t.Errorf("Expected status code %d, got %d", http.StatusOK, rr.Code)
+		w := send(schemaID, true, ciphertext)
+		if w.Code != http.StatusOK && w.Code != http.StatusAccepted {
+			t.Fatalf("Expected the encrypted message with schema %q to be accepted, got %d: %s", schemaID, w.Code, w.Body.String())
+		}
+		message := mockProcessor.lastMessage
+		if message == nil || !message.Encrypted || !bytes.Equal(message.Payload, ciphertext) {
+			t.Fatalf("Expected the ciphertext to be passed on untouched, got %+v", message)
+		}
+		if _, detected := message.Headers[types.DetectedSchemaHeader]; detected {
+			t.Error("Expected schema auto-detection to skip an encrypted payload")
+		}
 	}
 
-	var response types.Message
-	err = json.Unmarshal(rr.Body.Bytes(), &response)
-	if err != nil {
-		t.Fatalf("Failed to unmarshal response: %v", err)
+	// Plaintext is still validated against the schema
+	if w := send("agntcy:commerce.order.v1", false, json.RawMessage(`{"sku":"x"}`)); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected the plaintext payload to fail schema validation, got %d: %s", w.Code, w.Body.String())
 	}
-
-	if response.MessageID != message.MessageID {
-		t.Errorf("Expected message ID %s, got %s", message.MessageID, response.MessageID)
+	// An encrypted payload must still be ciphertext
+	for _, payload := range []string{`{"order_id":"o-1"}`, `""`} {
+		if w := send("", true, json.RawMessage(payload)); w.Code != http.StatusBadRequest {
+			t.Errorf("Expected encrypted payload %s to be rejected, got %d: %s", payload, w.Code, w.Body.String())
+		}
 	}
-
-	if response.Sender != message.Sender {
-		t.Errorf("Expected sender %s, got %s", message.Sender, response.Sender)
+	if w := send("", true, nil); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected an encrypted message without a payload to be rejected, got %d: %s", w.Code, w.Body.String())
 	}
 }
 
-func TestHandleGetMessage_InvalidID(t *testing.T) {
-	server := createTestServer()
+func TestHandleSendMessage_IdempotencyKey(t *testing.T) {
+	const (
+		bodyKey   = "01234567-89ab-4def-8123-456789abcdef"
+		headerKey = "fedcba98-7654-4321-8fed-cba987654321"
+	)
 
-	req, err := http.NewRequest("GET", "/v1/messages/invalid-id", nil)
-	if err != nil {
-		t.Fatalf("Failed to create request: %v", err)
+	tests := []struct {
+		name         string
+		require      bool
+		bodyKey      string
+		headerKey    string
+		expectedCode int
+		expectedKey  string // empty means a derived key
+	}{
+		{"lenient without key derives one", false, "", "", http.StatusOK, ""},
+		{"lenient with body key", false, bodyKey, "", http.StatusOK, bodyKey},
+		{"lenient with header key", false, "", headerKey, http.StatusOK, headerKey},
+		{"body key wins over header", false, bodyKey, headerKey, http.StatusOK, bodyKey},
+		{"strict without key", true, "", "", http.StatusBadRequest, ""},
+		{"strict with blank header", true, "", "  ", http.StatusBadRequest, ""},
+		{"strict with body key", true, bodyKey, "", http.StatusOK, bodyKey},
+		{"strict with header key", true, "", headerKey, http.StatusOK, headerKey},
 	}
 
-	rr := httptest.NewRecorder()
-	server.router.ServeHTTP(rr, req)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := createTestServer()
+			server.config.Message.RequireIdempotencyKey = tt.require
+
+			body, _ := json.Marshal(types.SendMessageRequest{
+				Sender:         "test@example.com",
+				Recipients:     []string{"recipient@test.com"},
+				Subject:        "Test Message",
+				IdempotencyKey: tt.bodyKey,
+				Payload:        json.RawMessage(`{"message": "Hello, World!"}`),
+			})
+			req, _ := http.NewRequest("POST", "/v1/messages", bytes.NewBuffer(body))
+			req.Header.Set("Content-Type", "application/json")
+			if tt.headerKey != "" {
+				req.Header.Set("Idempotency-Key", tt.headerKey)
+			}
 
-	if rr.Code != http.StatusBadRequest {
-		t.Errorf("Expected status code %d, got %d", http.StatusBadRequest, rr.Code)
-	}
+			rr := httptest.NewRecorder()
+			server.router.ServeHTTP(rr, req)
 
-	var errorResponse types.ErrorResponse
-	err = json.Unmarshal(rr.Body.Bytes(), &errorResponse)
-	if err != nil {
-		t.Fatalf("Failed to unmarshal error response: %v", err)
-	}
+			if rr.Code != tt.expectedCode {
+				t.Fatalf("Expected status code %d, got %d: %s", tt.expectedCode, rr.Code, rr.Body.String())
+			}
 
-	if errorResponse.Error.Code != "INVALID_MESSAGE_ID" {
-		t.Errorf("Expected error code 'INVALID_MESSAGE_ID', got %s", errorResponse.Error.Code)
+			processor := server.processor.(*MockMessageProcessor)
+			if rr.Code != http.StatusOK {
+				var errorResponse types.ErrorResponse
+				if err := json.Unmarshal(rr.Body.Bytes(), &errorResponse); err != nil {
+					t.Fatalf("Failed to unmarshal error response: %v", err)
+				}
+				if errorResponse.Error.Code != "IDEMPOTENCY_KEY_REQUIRED" {
+					t.Errorf("Expected error code 'IDEMPOTENCY_KEY_REQUIRED', got %s", errorResponse.Error.Code)
+				}
+				if processor.lastMessage != nil {
+					t.Error("Rejected send should not reach the processor")
+				}
+				return
+			}
+
+			key := processor.lastMessage.IdempotencyKey
+			if tt.expectedKey != "" && key != tt.expectedKey {
+				t.Errorf("Expected idempotency key %s, got %s", tt.expectedKey, key)
+			}
+			if tt.expectedKey == "" && (key == bodyKey || key == headerKey || key == "") {
+				t.Errorf("Expected a derived idempotency key, got %q", key)
+			}
+		})
 	}
 }
 
-func TestHandleGetMessage_NotFound(t *testing.T) {
+func TestHandleSendMessage_ProcessingFailed(t *testing.T) {
 	server := createTestServer()
+	mockProcessor := server.processor.(*MockMessageProcessor)
+	mockProcessor.SetProcessError(fmt.Errorf("processing failed"))
 
-	req, err := http.NewRequest("GET", "/v1/messages/01234567-89ab-7def-8123-456789abcdef", nil)
+	requestBody := types.SendMessageRequest{
+		Sender:     "test@example.com",
+		Recipients: []string{"recipient@test.com"},
+		Subject:    "Test Message",
+		Payload:    json.RawMessage(`{"message": "Hello, World!"}`),
+	}
+
+	body, err := json.Marshal(requestBody)
+	if err != nil {
+		t.Fatalf("Failed to marshal request body: %v", err)
+	}
+
+	req, err := http.NewRequest("POST", "/v1/messages", bytes.NewBuffer(body))
 	if err != nil {
 		t.Fatalf("Failed to create request: %v", err)
 	}
+	req.Header.Set("Content-Type", "application/json")
 
 	rr := httptest.NewRecorder()
 	server.router.ServeHTTP(rr, req)
 
-	if rr.Code != http.StatusNotFound {
-		t.Errorf("Expected status code %d, got %d", http.StatusNotFound, rr.Code)
+	if rr.Code != http.StatusInternalServerError {
+		t.Errorf("Expected status code %d, got %d", http.StatusInternalServerError, rr.Code)
 	}
 
 	var errorResponse types.ErrorResponse
@@ -589,8 +1014,621 @@ func TestHandleGetMessage_NotFound(t *testing.T) {
 		t.Fatalf("Failed to unmarshal error response: %v", err)
 	}
 
-	if errorResponse.Error.Code != "MESSAGE_NOT_FOUND" {
-		t.Errorf("Expected error code 'MESSAGE_NOT_FOUND', got %s", errorResponse.Error.Code)
+	if errorResponse.Error.Code != "PROCESSING_FAILED" {
+		t.Errorf("Expected error code 'PROCESSING_FAILED', got %s", errorResponse.Error.Code)
+	}
+}
+
+func TestHandleSendMessage_RecipientOutcomes(t *testing.T) {
+	delivered := types.RecipientStatus{Address: "a@test.com", Status: types.StatusDelivered, Attempts: 1}
+	failed := types.RecipientStatus{Address: "b@test.com", Status: types.StatusFailed, Attempts: 3, ErrorCode: "DELIVERY_FAILED"}
+	delivering := types.RecipientStatus{Address: "c@test.com", Status: types.StatusDelivering, Attempts: 1}
+
+	tests := []struct {
+		name            string
+		overall         types.DeliveryStatus
+		recipients      []types.RecipientStatus
+		expectedCode    int
+		expectedStatus  string
+		expectedPartial bool
+	}{
+		{"all delivered", types.StatusDelivered, []types.RecipientStatus{delivered, delivered}, http.StatusOK, "delivered", false},
+		{"all failed", types.StatusFailed, []types.RecipientStatus{failed, failed}, http.StatusBadRequest, "failed", false},
+		{"some failed", types.StatusFailed, []types.RecipientStatus{delivered, failed}, http.StatusMultiStatus, "partial", true},
+		{"some failed, some pending", types.StatusFailed, []types.RecipientStatus{failed, delivering}, http.StatusMultiStatus, "partial", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := createTestServer()
+			server.processor.(*MockMessageProcessor).SetProcessResult(&processing.ProcessingResult{
+				MessageID:  "msg-1",
+				Status:     tt.overall,
+				Recipients: tt.recipients,
+			})
+
+			body, err := json.Marshal(types.SendMessageRequest{
+				Sender:     "test@example.com",
+				Recipients: []string{"a@test.com", "b@test.com"},
+				Payload:    json.RawMessage(`{"message": "Hello"}`),
+			})
+			if err != nil {
+				t.Fatalf("Failed to marshal request body: %v", err)
+			}
+			req := httptest.NewRequest("POST", "/v1/messages", bytes.NewBuffer(body))
+			req.Header.Set("Content-Type", "application/json")
+			rr := httptest.NewRecorder()
+			server.router.ServeHTTP(rr, req)
+
+			if rr.Code != tt.expectedCode {
+				t.Fatalf("Expected status code %d, got %d: %s", tt.expectedCode, rr.Code, rr.Body.String())
+			}
+			var response types.SendMessageResponse
+			if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+				t.Fatalf("Failed to unmarshal response: %v", err)
+			}
+			if response.Status != tt.expectedStatus || response.Partial != tt.expectedPartial {
+				t.Errorf("Expected status %q with partial=%t, got %q with partial=%t",
+					tt.expectedStatus, tt.expectedPartial, response.Status, response.Partial)
+			}
+			if len(response.Recipients) != len(tt.recipients) {
+				t.Errorf("Expected %d recipient statuses, got %d", len(tt.recipients), len(response.Recipients))
+			}
+			if !tt.expectedPartial && strings.Contains(rr.Body.String(), `"partial"`) {
+				t.Errorf("Expected no partial field, got %s", rr.Body.String())
+			}
+		})
+	}
+}
+
+func TestHandleSendMessage_RequestDeadline(t *testing.T) {
+	server := createTestServer()
+	mockProcessor := server.processor.(*MockMessageProcessor)
+
+	body, err := json.Marshal(types.SendMessageRequest{
+		Sender:     "test@example.com",
+		Recipients: []string{"recipient@test.com"},
+		Payload:    json.RawMessage(`{"message": "Hello, World!"}`),
+	})
+	if err != nil {
+		t.Fatalf("Failed to marshal request body: %v", err)
+	}
+
+	send := func(ctx context.Context) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/v1/messages", bytes.NewReader(body)).WithContext(ctx)
+		req.Header.Set("Content-Type", "application/json")
+		rr := httptest.NewRecorder()
+		server.router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	// Without a deadline the default bound applies
+	send(context.Background())
+	if mockProcessor.lastOptions.Timeout != defaultProcessingTimeout {
+		t.Errorf("Expected timeout %v, got %v", defaultProcessingTimeout, mockProcessor.lastOptions.Timeout)
+	}
+
+	// A shorter request deadline bounds processing
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	send(ctx)
+	if timeout := mockProcessor.lastOptions.Timeout; timeout <= 0 || timeout > 2*time.Second {
+		t.Errorf("Expected timeout within the request deadline, got %v", timeout)
+	}
+
+	// Processing aborted by the deadline is reported as a timeout
+	mockProcessor.SetProcessError(fmt.Errorf("message processing aborted: %w", context.DeadlineExceeded))
+	rr := send(context.Background())
+	if rr.Code != http.StatusGatewayTimeout {
+		t.Errorf("Expected status code %d, got %d", http.StatusGatewayTimeout, rr.Code)
+	}
+	var errorResponse types.ErrorResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &errorResponse); err != nil {
+		t.Fatalf("Failed to unmarshal error response: %v", err)
+	}
+	if errorResponse.Error.Code != "TIMEOUT" {
+		t.Errorf("Expected error code 'TIMEOUT', got %s", errorResponse.Error.Code)
+	}
+}
+
+func TestHandleSendMessage_SenderDomain(t *testing.T) {
+	server := createTestServer()
+	server.config.Auth.EnforceSenderDomain = true
+	schemes, err := types.NewAddressSchemes([]string{types.AddressSchemeURN})
+	if err != nil {
+		t.Fatalf("Failed to create address schemes: %v", err)
+	}
+	server.addresses = schemes
+	server.validator.SetAddressSchemes(schemes)
+
+	// Stand in for the auth middleware's record of a verified client certificate
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		if domains := c.GetHeader("X-Test-Domains"); domains != "" {
+			c.Set("authenticated_domains", strings.Split(domains, ","))
+		}
+		c.Next()
+	})
+	router.POST("/v1/messages", server.handleSendMessage)
+
+	send := func(sender, domains string) *httptest.ResponseRecorder {
+		body, err := json.Marshal(types.SendMessageRequest{
+			Sender:     sender,
+			Recipients: []string{"recipient@test.com"},
+			Payload:    json.RawMessage(`{"message": "Hello, World!"}`),
+		})
+		if err != nil {
+			t.Fatalf("Failed to marshal request body: %v", err)
+		}
+		req := httptest.NewRequest("POST", "/v1/messages", bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Test-Domains", domains)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	tests := []struct {
+		name     string
+		sender   string
+		domains  string
+		expected int
+	}{
+		{"no verified domain", "agent@other.com", "", http.StatusOK},
+		{"matching domain", "agent@Example.com", "example.com", http.StatusOK},
+		{"one of several domains", "agent@b.com", "a.com,b.com", http.StatusOK},
+		{"wildcard domain", "agent@mail.example.com", "*.example.com", http.StatusOK},
+		{"mismatched domain", "agent@other.com", "example.com", http.StatusForbidden},
+		{"wildcard does not cover apex", "agent@example.com", "*.example.com", http.StatusForbidden},
+		{"local URN sender", "urn:agent:1234", "localhost", http.StatusOK},
+		{"local URN sender from another domain", "urn:agent:1234", "example.com", http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := send(tt.sender, tt.domains)
+			if rr.Code != tt.expected {
+				t.Fatalf("Expected status code %d, got %d: %s", tt.expected, rr.Code, rr.Body.String())
+			}
+			if tt.expected != http.StatusForbidden {
+				return
+			}
+			var errorResponse types.ErrorResponse
+			if err := json.Unmarshal(rr.Body.Bytes(), &errorResponse); err != nil {
+				t.Fatalf("Failed to unmarshal error response: %v", err)
+			}
+			if errorResponse.Error.Code != "SENDER_DOMAIN_MISMATCH" {
+				t.Errorf("Expected error code 'SENDER_DOMAIN_MISMATCH', got %s", errorResponse.Error.Code)
+			}
+		})
+	}
+
+	// Trusted internal deployments can turn enforcement off
+	server.config.Auth.EnforceSenderDomain = false
+	if rr := send("agent@other.com", "example.com"); rr.Code != http.StatusOK {
+		t.Errorf("Expected status code %d with enforcement off, got %d", http.StatusOK, rr.Code)
+	}
+}
+
+func TestHandleSendMessage_DefaultSender(t *testing.T) {
+	server := createTestServer()
+	processor := server.processor.(*MockMessageProcessor)
+
+	agent := &agents.LocalAgent{Address: "sender", DeliveryMode: "pull"}
+	if err := server.agentRegistry.RegisterAgent(context.Background(), agent); err != nil {
+		t.Fatalf("Failed to register agent: %v", err)
+	}
+	adminKeyFile := filepath.Join(t.TempDir(), "admin.keys")
+	if err := os.WriteFile(adminKeyFile, []byte("admin-secret\n"), 0600); err != nil {
+		t.Fatalf("Failed to write admin key file: %v", err)
+	}
+	server.config.Auth.AdminKeyFile = adminKeyFile
+	server.config.Auth.AdminAPIKeyHeader = "X-Admin-Key"
+
+	send := func(sender, apiKey, adminKey string) *httptest.ResponseRecorder {
+		body, err := json.Marshal(types.SendMessageRequest{
+			Sender:     sender,
+			Recipients: []string{"recipient@test.com"},
+			Payload:    json.RawMessage(`{"message": "Hello, World!"}`),
+		})
+		if err != nil {
+			t.Fatalf("Failed to marshal request body: %v", err)
+		}
+		req := httptest.NewRequest("POST", "/v1/messages", bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		if apiKey != "" {
+			req.Header.Set("Authorization", "Bearer "+apiKey)
+		}
+		if adminKey != "" {
+			req.Header.Set("X-Admin-Key", adminKey)
+		}
+		rr := httptest.NewRecorder()
+		server.router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	tests := []struct {
+		name     string
+		sender   string
+		apiKey   string
+		adminKey string
+		status   int
+		code     string
+		expected string
+	}{
+		{"omitted sender defaults to the agent", "", agent.APIKey, "", http.StatusOK, "", "sender@localhost"},
+		{"matching sender", "Sender@localhost", agent.APIKey, "", http.StatusOK, "", "Sender@localhost"},
+		{"mismatched sender", "other@localhost", agent.APIKey, "", http.StatusForbidden, "SENDER_MISMATCH", ""},
+		{"mismatched sender with an admin key", "other@localhost", agent.APIKey, "admin-secret", http.StatusOK, "", "other@localhost"},
+		{"mismatched sender with a wrong admin key", "other@localhost", agent.APIKey, "guess", http.StatusForbidden, "SENDER_MISMATCH", ""},
+		{"bearer token that is not an agent key", "other@localhost", "oauth-token", "", http.StatusOK, "", "other@localhost"},
+		{"omitted sender without a key", "", "", "", http.StatusBadRequest, "VALIDATION_FAILED", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			processor.lastMessage = nil
+			rr := send(tt.sender, tt.apiKey, tt.adminKey)
+			if rr.Code != tt.status {
+				t.Fatalf("Expected status code %d, got %d: %s", tt.status, rr.Code, rr.Body.String())
+			}
+			if tt.code != "" && !strings.Contains(rr.Body.String(), tt.code) {
+				t.Errorf("Expected error code %s, got %s", tt.code, rr.Body.String())
+			}
+			if tt.expected != "" && (processor.lastMessage == nil || processor.lastMessage.Sender != tt.expected) {
+				t.Errorf("Expected sender %s, got %+v", tt.expected, processor.lastMessage)
+			}
+		})
+	}
+}
+
+func TestDomainMatches(t *testing.T) {
+	tests := []struct {
+		authenticated, domain string
+		expected              bool
+	}{
+		{"example.com", "example.com", true},
+		{"example.com", "mail.example.com", false},
+		{"*.example.com", "mail.example.com", true},
+		{"*.example.com", "a.mail.example.com", false},
+		{"*.example.com", "example.com", false},
+		{"*.example.com", ".example.com", false},
+	}
+
+	for _, tt := range tests {
+		if got := domainMatches(tt.authenticated, tt.domain); got != tt.expected {
+			t.Errorf("domainMatches(%q, %q) = %v, want %v", tt.authenticated, tt.domain, got, tt.expected)
+		}
+	}
+}
+
+func TestHandleSendMessage_DuplicateRecipients(t *testing.T) {
+	server := createTestServerWithRealProcessor()
+	ctx := context.Background()
+	for _, name := range []string{"bob", "alice"} {
+		if err := server.agentRegistry.RegisterAgent(ctx, &agents.LocalAgent{Address: name, DeliveryMode: "pull"}); err != nil {
+			t.Fatalf("Failed to register %s: %v", name, err)
+		}
+	}
+
+	body, _ := json.Marshal(types.SendMessageRequest{
+		Sender:     "test@example.com",
+		Recipients: []string{"bob@localhost", "Bob@LocalHost", "bob@localhost", "Alice <ALICE@localhost>"},
+		Payload:    json.RawMessage(`{"message": "hello"}`),
+	})
+	req := httptest.NewRequest("POST", "/v1/messages", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	var response types.SendMessageResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to unmarshal response: %v", err)
+	}
+	if len(response.Recipients) != 2 || response.Recipients[0].Address != "bob@localhost" || response.Recipients[1].Address != "alice@localhost" {
+		t.Fatalf("Expected the canonical recipients bob@localhost and alice@localhost, got %+v", response.Recipients)
+	}
+
+	for _, recipient := range []string{"bob@localhost", "alice@localhost"} {
+		inbox, err := server.storage.GetInboxMessages(ctx, recipient)
+		if err != nil {
+			t.Fatalf("Failed to get inbox of %s: %v", recipient, err)
+		}
+		if len(inbox) != 1 {
+			t.Errorf("Expected one delivery to %s, got %d", recipient, len(inbox))
+		}
+	}
+}
+
+func TestHandleSendMessage_SchemaUnavailable(t *testing.T) {
+	tests := []struct {
+		name        string
+		mode        string
+		schema      string
+		status      int
+		errorCode   string
+		warningCode string
+		flags       map[string]bool
+	}{
+		{"lenient accepts with a warning", config.SchemaUnavailableLenient, "agntcy:commerce.order.v1", http.StatusOK, "", "SCHEMA_NOT_VALIDATED", nil},
+		{"strict rejects", config.SchemaUnavailableStrict, "agntcy:commerce.order.v1", http.StatusServiceUnavailable, "SCHEMA_MANAGER_UNAVAILABLE", "", nil},
+		{"strict accepts schemaless messages", config.SchemaUnavailableStrict, "", http.StatusOK, "", "", nil},
+		{"strict_schema flag overrides lenient", config.SchemaUnavailableLenient, "agntcy:commerce.order.v1", http.StatusServiceUnavailable, "SCHEMA_MANAGER_UNAVAILABLE", "", map[string]bool{features.StrictSchema: true}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			features.Set(tt.flags)
+			defer features.Set(nil)
+			server := createTestServer()
+			server.config.Message.SchemaUnavailable = tt.mode
+			processor := server.processor.(*MockMessageProcessor)
+
+			body, _ := json.Marshal(types.SendMessageRequest{
+				Sender:     "test@example.com",
+				Recipients: []string{"recipient@test.com"},
+				Schema:     tt.schema,
+				Payload:    json.RawMessage(`{"order_id": "1"}`),
+			})
+			req := httptest.NewRequest("POST", "/v1/messages", bytes.NewBuffer(body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			server.router.ServeHTTP(w, req)
+
+			if w.Code != tt.status {
+				t.Fatalf("Expected status %d, got %d: %s", tt.status, w.Code, w.Body.String())
+			}
+
+			if tt.errorCode != "" {
+				var errorResponse types.ErrorResponse
+				if err := json.Unmarshal(w.Body.Bytes(), &errorResponse); err != nil {
+					t.Fatalf("Failed to unmarshal error response: %v", err)
+				}
+				if errorResponse.Error.Code != tt.errorCode {
+					t.Errorf("Expected error code %s, got %s", tt.errorCode, errorResponse.Error.Code)
+				}
+				if processor.lastMessage != nil {
+					t.Error("Rejected send should not reach the processor")
+				}
+				return
+			}
+
+			var response types.SendMessageResponse
+			if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+				t.Fatalf("Failed to unmarshal response: %v", err)
+			}
+			if tt.warningCode == "" {
+				if len(response.Warnings) != 0 {
+					t.Errorf("Expected no warnings, got %+v", response.Warnings)
+				}
+				return
+			}
+			if len(response.Warnings) != 1 || response.Warnings[0].Code != tt.warningCode || response.Warnings[0].Value != tt.schema {
+				t.Errorf("Expected a %s warning, got %+v", tt.warningCode, response.Warnings)
+			}
+		})
+	}
+}
+
+func TestHandleSendMessage_RetryPolicy(t *testing.T) {
+	server := createTestServer()
+	server.config.Delivery = config.DeliveryConfig{
+		MaxRetries:      3,
+		RetryDelay:      time.Second,
+		MaxRetriesLimit: 10,
+		RetryDelayLimit: time.Minute,
+	}
+	mockProcessor := server.processor.(*MockMessageProcessor)
+
+	send := func(maxRetries int, retryDelay string) *httptest.ResponseRecorder {
+		body, err := json.Marshal(types.SendMessageRequest{
+			Sender:     "test@example.com",
+			Recipients: []string{"recipient@test.com"},
+			Payload:    json.RawMessage(`{"message": "Hello, World!"}`),
+			MaxRetries: maxRetries,
+			RetryDelay: retryDelay,
+		})
+		if err != nil {
+			t.Fatalf("Failed to marshal request body: %v", err)
+		}
+		req := httptest.NewRequest("POST", "/v1/messages", bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		rr := httptest.NewRecorder()
+		server.router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	tests := []struct {
+		name          string
+		maxRetries    int
+		retryDelay    string
+		expectedMax   int
+		expectedDelay time.Duration
+	}{
+		{"defaults", 0, "", 3, time.Second},
+		{"override applied", 6, "250ms", 6, 250 * time.Millisecond},
+		{"override clamped", 50, "10m", 10, time.Minute},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if rr := send(tt.maxRetries, tt.retryDelay); rr.Code != http.StatusOK {
+				t.Fatalf("Expected status code %d, got %d: %s", http.StatusOK, rr.Code, rr.Body.String())
+			}
+			if mockProcessor.lastOptions.MaxRetries != tt.expectedMax {
+				t.Errorf("Expected max retries %d, got %d", tt.expectedMax, mockProcessor.lastOptions.MaxRetries)
+			}
+			if mockProcessor.lastOptions.RetryDelay != tt.expectedDelay {
+				t.Errorf("Expected retry delay %v, got %v", tt.expectedDelay, mockProcessor.lastOptions.RetryDelay)
+			}
+		})
+	}
+
+	if rr := send(-1, ""); rr.Code != http.StatusBadRequest {
+		t.Errorf("Expected status code %d for negative max retries, got %d", http.StatusBadRequest, rr.Code)
+	}
+}
+
+func TestProcessingTimeout(t *testing.T) {
+	server := createTestServer()
+	server.config.Server.WriteTimeout = 10 * time.Second
+
+	if timeout := server.processingTimeout(context.Background()); timeout != 10*time.Second {
+		t.Errorf("Expected the write timeout to cap processing, got %v", timeout)
+	}
+
+	ctx, cancel := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancel()
+	if timeout := server.processingTimeout(ctx); timeout > 0 {
+		t.Errorf("Expected no time left after the deadline, got %v", timeout)
+	}
+}
+
+func TestHandleGetMessage_Success(t *testing.T) {
+	server := createTestServer()
+	mockStorage := server.storage.(*MockStorage)
+
+	// First, send a message to store it
+	message := &types.Message{
+		Version:        "1.0",
+		MessageID:      "01234567-89ab-7def-8123-456789abcdef",
+		IdempotencyKey: "01234567-89ab-4def-8123-456789abcdef",
+		Timestamp:      time.Now().UTC(),
+		Sender:         "test@example.com",
+		Recipients:     []string{"recipient@test.com"},
+		Subject:        "Test Message",
+		Payload:        json.RawMessage(`{"message": "Hello, World!"}`),
+	}
+	mockStorage.messages[message.MessageID] = message
+
+	req, err := http.NewRequest("GET", "/v1/messages/"+message.MessageID, nil)
+	if err != nil {
+		t.Fatalf("Failed to create request: %v", err)
+	}
+
+	rr := httptest.NewRecorder()
+	server.router.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Errorf("Expected status code %d, got %d", http.StatusOK, rr.Code)
+	}
+
+	var response types.Message
+	err = json.Unmarshal(rr.Body.Bytes(), &response)
+	if err != nil {
+		t.Fatalf("Failed to unmarshal response: %v", err)
+	}
+
+	if response.MessageID != message.MessageID {
+		t.Errorf("Expected message ID %s, got %s", message.MessageID, response.MessageID)
+	}
+
+	if response.Sender != message.Sender {
+		t.Errorf("Expected sender %s, got %s", message.Sender, response.Sender)
+	}
+}
+
+func TestHandleGetMessage_MessageIDStrategy(t *testing.T) {
+	server := createTestServer()
+	ids, err := uuid.NewGenerator(uuid.StrategyPrefixed, "eu1")
+	if err != nil {
+		t.Fatalf("NewGenerator failed: %v", err)
+	}
+	server.messageIDs = ids
+	server.validator.SetMessageIDGenerator(ids)
+
+	body, _ := json.Marshal(types.SendMessageRequest{
+		Sender:     "test@example.com",
+		Recipients: []string{"recipient@test.com"},
+		Payload:    json.RawMessage(`{"message": "Hello, World!"}`),
+	})
+	req := httptest.NewRequest("POST", "/v1/messages", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	rr := httptest.NewRecorder()
+	server.router.ServeHTTP(rr, req)
+
+	var response types.SendMessageResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to unmarshal response: %v", err)
+	}
+	if !strings.HasPrefix(response.MessageID, "eu1-") || !ids.IsValid(response.MessageID) {
+		t.Fatalf("Expected an eu1-prefixed message ID, got %q", response.MessageID)
+	}
+
+	mockStorage := server.storage.(*MockStorage)
+	mockStorage.messages[response.MessageID] = server.processor.(*MockMessageProcessor).lastMessage
+
+	tests := []struct {
+		id     string
+		status int
+	}{
+		{response.MessageID, http.StatusOK},
+		{"01234567-89ab-7def-8123-456789abcdef", http.StatusNotFound},
+		{"us2-01234567-89ab-7def-8123-456789abcdef", http.StatusNotFound},
+		{"US2-01234567-89ab-7def-8123-456789abcdef", http.StatusBadRequest},
+		{"01ARZ3NDEKTSV4RRFFQ69G5FAV", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		rr := httptest.NewRecorder()
+		server.router.ServeHTTP(rr, httptest.NewRequest("GET", "/v1/messages/"+tt.id, nil))
+		if rr.Code != tt.status {
+			t.Errorf("GET %s: expected status %d, got %d", tt.id, tt.status, rr.Code)
+		}
+	}
+}
+
+func TestHandleGetMessage_InvalidID(t *testing.T) {
+	server := createTestServer()
+
+	req, err := http.NewRequest("GET", "/v1/messages/invalid-id", nil)
+	if err != nil {
+		t.Fatalf("Failed to create request: %v", err)
+	}
+
+	rr := httptest.NewRecorder()
+	server.router.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("Expected status code %d, got %d", http.StatusBadRequest, rr.Code)
+	}
+
+	var errorResponse types.ErrorResponse
+	err = json.Unmarshal(rr.Body.Bytes(), &errorResponse)
+	if err != nil {
+		t.Fatalf("Failed to unmarshal error response: %v", err)
+	}
+
+	if errorResponse.Error.Code != "INVALID_MESSAGE_ID" {
+		t.Errorf("Expected error code 'INVALID_MESSAGE_ID', got %s", errorResponse.Error.Code)
+	}
+}
+
+func TestHandleGetMessage_NotFound(t *testing.T) {
+	server := createTestServer()
+
+	req, err := http.NewRequest("GET", "/v1/messages/01234567-89ab-7def-8123-456789abcdef", nil)
+	if err != nil {
+		t.Fatalf("Failed to create request: %v", err)
+	}
+
+	rr := httptest.NewRecorder()
+	server.router.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("Expected status code %d, got %d", http.StatusNotFound, rr.Code)
+	}
+
+	var errorResponse types.ErrorResponse
+	err = json.Unmarshal(rr.Body.Bytes(), &errorResponse)
+	if err != nil {
+		t.Fatalf("Failed to unmarshal error response: %v", err)
+	}
+
+	if errorResponse.Error.Code != "MESSAGE_NOT_FOUND" {
+		t.Errorf("Expected error code 'MESSAGE_NOT_FOUND', got %s", errorResponse.Error.Code)
 	}
 }
 
@@ -647,6 +1685,277 @@ func TestHandleGetMessageStatus_Success(t *testing.T) {
 	}
 }
 
+func TestHandleGetMessageStatus_HidesArchive(t *testing.T) {
+	server := createTestServer()
+	server.config.Message.ArchiveAddress = "archive@localhost"
+	mockStorage := server.storage.(*MockStorage)
+
+	messageID := "01234567-89ab-7def-8123-456789abcdef"
+	mockStorage.statuses[messageID] = &types.MessageStatus{
+		MessageID: messageID,
+		Status:    types.StatusDelivered,
+		Recipients: []types.RecipientStatus{
+			{Address: "recipient@test.com", Status: types.StatusDelivered},
+			{Address: "archive@localhost", Status: types.StatusFailed},
+		},
+	}
+
+	rr := httptest.NewRecorder()
+	server.router.ServeHTTP(rr, httptest.NewRequest("GET", "/v1/messages/"+messageID+"/status", nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d", http.StatusOK, rr.Code)
+	}
+
+	var response types.MessageStatus
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to unmarshal response: %v", err)
+	}
+	if len(response.Recipients) != 1 || response.Recipients[0].Address != "recipient@test.com" {
+		t.Errorf("Expected the archive recipient to be hidden, got %+v", response.Recipients)
+	}
+}
+
+func TestHandleGetMessageStatusByKey(t *testing.T) {
+	server := createTestServer()
+	mockStorage := server.storage.(*MockStorage)
+
+	messageID := "01234567-89ab-7def-8123-456789abcdef"
+	idempotencyKey := "6f1c2a9e-3b4d-4e5f-8a7b-9c0d1e2f3a4b"
+	mockStorage.messages[messageID] = &types.Message{MessageID: messageID, IdempotencyKey: idempotencyKey}
+	mockStorage.statuses[messageID] = &types.MessageStatus{MessageID: messageID, Status: types.StatusDelivered}
+
+	tests := []struct {
+		name         string
+		key          string
+		expectedCode int
+		errorCode    string
+	}{
+		{"known key", idempotencyKey, http.StatusOK, ""},
+		{"unknown key", "0a1b2c3d-4e5f-4a6b-9c7d-8e9f0a1b2c3d", http.StatusNotFound, "MESSAGE_NOT_FOUND"},
+		{"invalid key", "not-a-uuid", http.StatusBadRequest, "INVALID_IDEMPOTENCY_KEY"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/v1/messages/by-key/"+tt.key, nil)
+			rr := httptest.NewRecorder()
+			server.router.ServeHTTP(rr, req)
+
+			if rr.Code != tt.expectedCode {
+				t.Fatalf("Expected status code %d, got %d", tt.expectedCode, rr.Code)
+			}
+			if tt.errorCode != "" {
+				var errorResponse types.ErrorResponse
+				if err := json.Unmarshal(rr.Body.Bytes(), &errorResponse); err != nil {
+					t.Fatalf("Failed to unmarshal error response: %v", err)
+				}
+				if errorResponse.Error.Code != tt.errorCode {
+					t.Errorf("Expected error code %s, got %s", tt.errorCode, errorResponse.Error.Code)
+				}
+				return
+			}
+
+			var response types.MessageStatus
+			if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+				t.Fatalf("Failed to unmarshal response: %v", err)
+			}
+			if response.MessageID != messageID || response.Status != types.StatusDelivered {
+				t.Errorf("Unexpected status: %+v", response)
+			}
+		})
+	}
+
+	// The message routes still resolve alongside the by-key route
+	req := httptest.NewRequest("GET", "/v1/messages/"+messageID+"/status", nil)
+	rr := httptest.NewRecorder()
+	server.router.ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Errorf("Expected status code %d for status by ID, got %d", http.StatusOK, rr.Code)
+	}
+}
+
+func TestHandleSendMessage_TimestampSkew(t *testing.T) {
+	server := createTestServerWithRealProcessor()
+	server.config.Message.CheckTimestamps = true
+	server.config.Message.MaxTimestampSkew = time.Hour
+	if err := server.agentRegistry.RegisterAgent(context.Background(), &agents.LocalAgent{Address: "bob", DeliveryMode: "pull"}); err != nil {
+		t.Fatalf("Failed to register agent: %v", err)
+	}
+
+	send := func(sender string, timestamp time.Time) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(types.SendMessageRequest{
+			Sender:     sender,
+			Recipients: []string{"bob@localhost"},
+			Payload:    json.RawMessage(`{"message": "hello"}`),
+			Timestamp:  timestamp.Format(time.RFC3339),
+		})
+		req := httptest.NewRequest("POST", "/v1/messages", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		server.router.ServeHTTP(w, req)
+		return w
+	}
+
+	now := time.Now()
+	tests := []struct {
+		name     string
+		sender   string
+		offset   time.Duration
+		accepted bool
+	}{
+		{"relayed just inside the past", "test@example.com", -59 * time.Minute, true},
+		{"relayed just inside the future", "test@example.com", 59 * time.Minute, true},
+		{"relayed just outside the past", "test@example.com", -61 * time.Minute, false},
+		{"relayed just outside the future", "test@example.com", 61 * time.Minute, false},
+		{"local sender inside", "alice@localhost", -59 * time.Minute, true},
+		{"local sender outside", "alice@localhost", -61 * time.Minute, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := send(tt.sender, now.Add(tt.offset))
+			if tt.accepted {
+				if w.Code != http.StatusOK {
+					t.Errorf("Expected the message to be accepted, got %d: %s", w.Code, w.Body.String())
+				}
+				return
+			}
+			if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "TIMESTAMP_OUT_OF_RANGE") {
+				t.Errorf("Expected TIMESTAMP_OUT_OF_RANGE, got %d: %s", w.Code, w.Body.String())
+			}
+		})
+	}
+
+	// Without the check, any timestamp is accepted
+	server.config.Message.CheckTimestamps = false
+	if w := send("test@example.com", now.Add(-48*time.Hour)); w.Code != http.StatusOK {
+		t.Errorf("Expected an unchecked timestamp to be accepted, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestHandleSendMessage_ReplyDepth(t *testing.T) {
+	server := createTestServerWithRealProcessor()
+	server.config.Message.MaxReplyDepth = 3
+	if err := server.agentRegistry.RegisterAgent(context.Background(), &agents.LocalAgent{Address: "bob", DeliveryMode: "pull"}); err != nil {
+		t.Fatalf("Failed to register agent: %v", err)
+	}
+
+	reply := func(inReplyTo string) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(types.SendMessageRequest{
+			Sender:     "test@example.com",
+			Recipients: []string{"bob@localhost"},
+			Payload:    json.RawMessage(`{"message": "hello"}`),
+			InReplyTo:  inReplyTo,
+		})
+		req := httptest.NewRequest("POST", "/v1/messages", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		server.router.ServeHTTP(w, req)
+		return w
+	}
+
+	// A root message followed by replies up to the limit
+	parent := ""
+	for depth := 0; depth <= 3; depth++ {
+		w := reply(parent)
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected a reply at depth %d to be accepted, got %d: %s", depth, w.Code, w.Body.String())
+		}
+		var sent types.SendMessageResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &sent); err != nil {
+			t.Fatalf("Failed to unmarshal response: %v", err)
+		}
+		parent = sent.MessageID
+	}
+
+	w := reply(parent)
+	if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "THREAD_TOO_DEEP") {
+		t.Errorf("Expected a reply past the limit to be rejected with THREAD_TOO_DEEP, got %d: %s", w.Code, w.Body.String())
+	}
+
+	// A parent the gateway does not have ends the chain
+	unknown, _ := uuid.GenerateV7()
+	if w := reply(unknown); w.Code != http.StatusOK {
+		t.Errorf("Expected a reply to an unknown message to be accepted, got %d: %s", w.Code, w.Body.String())
+	}
+
+	server.config.Message.MaxReplyDepth = 0
+	if w := reply(parent); w.Code != http.StatusOK {
+		t.Errorf("Expected no limit with a max depth of 0, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestHandleMessageLabels(t *testing.T) {
+	server := createTestServerWithRealProcessor()
+	ctx := context.Background()
+	if err := server.agentRegistry.RegisterAgent(ctx, &agents.LocalAgent{Address: "bob", DeliveryMode: "pull"}); err != nil {
+		t.Fatalf("Failed to register agent: %v", err)
+	}
+
+	send := func(labels []string) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(types.SendMessageRequest{
+			Sender:     "test@example.com",
+			Recipients: []string{"bob@localhost"},
+			Payload:    json.RawMessage(`{"message": "hello"}`),
+			Labels:     labels,
+		})
+		req := httptest.NewRequest("POST", "/v1/messages", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		server.router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := send([]string{"campaign-2024", "eu"})
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	var sent types.SendMessageResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &sent); err != nil {
+		t.Fatalf("Failed to unmarshal response: %v", err)
+	}
+	if w := send([]string{"us"}); w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d", http.StatusOK, w.Code)
+	}
+	if w := send([]string{"not a label"}); w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "invalid label") {
+		t.Errorf("Expected an invalid label to be rejected, got %d: %s", w.Code, w.Body.String())
+	}
+
+	get := func(path string, target interface{}) {
+		t.Helper()
+		req := httptest.NewRequest("GET", path, nil)
+		w := httptest.NewRecorder()
+		server.router.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("GET %s: expected status %d, got %d", path, http.StatusOK, w.Code)
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), target); err != nil {
+			t.Fatalf("GET %s: failed to unmarshal response: %v", path, err)
+		}
+	}
+
+	var message types.Message
+	get("/v1/messages/"+sent.MessageID, &message)
+	if strings.Join(message.Labels, ",") != "campaign-2024,eu" {
+		t.Errorf("Expected the message's labels, got %v", message.Labels)
+	}
+
+	var status types.MessageStatus
+	get("/v1/messages/"+sent.MessageID+"/status", &status)
+	if strings.Join(status.Labels, ",") != "campaign-2024,eu" {
+		t.Errorf("Expected the status to carry the labels, got %v", status.Labels)
+	}
+
+	var list struct {
+		Messages []types.MessageSummary `json:"messages"`
+	}
+	get("/v1/admin/messages?label=campaign-2024&label=eu", &list)
+	if len(list.Messages) != 1 || list.Messages[0].MessageID != sent.MessageID ||
+		strings.Join(list.Messages[0].Labels, ",") != "campaign-2024,eu" {
+		t.Errorf("Expected only the labelled message, got %+v", list.Messages)
+	}
+}
+
 func TestHandleGetMessageStatus_InvalidID(t *testing.T) {
 	server := createTestServer()
 
@@ -792,8 +2101,9 @@ func TestHandleReady(t *testing.T) {
 func TestHandleHealth_UnhealthyComponents(t *testing.T) {
 	// Create server with nil components to test unhealthy state
 	server := &Server{
-		config: &config.Config{},
-		router: nil, // This will make it unhealthy
+		config:     &config.Config{},
+		messageIDs: uuid.V7,
+		router:     nil, // This will make it unhealthy
 	}
 
 	health := server.checkHealth()
@@ -815,6 +2125,7 @@ func TestHandleReady_NotReadyDependencies(t *testing.T) {
 	// Create server with nil dependencies to test not ready state
 	server := &Server{
 		config:        &config.Config{},
+		messageIDs:    uuid.V7,
 		agentRegistry: nil, // This will make it not ready
 	}
 
@@ -894,6 +2205,8 @@ func createTestServerWithRealProcessor() *Server {
 
 	server := &Server{
 		config:        cfg,
+		messageIDs:    uuid.V7,
+		addresses:     types.DefaultAddressSchemes,
 		discovery:     discoveryService,
 		validator:     validator,
 		processor:     processor,
@@ -1100,7 +2413,7 @@ func BenchmarkHandleGetMessage(b *testing.B) {
 func TestHandleListMessages_Success(t *testing.T) {
 	server := createTestServer()
 
-	req := httptest.NewRequest("GET", "/v1/messages", nil)
+	req := httptest.NewRequest("GET", "/v1/admin/messages", nil)
 	w := httptest.NewRecorder()
 	server.router.ServeHTTP(w, req)
 
@@ -1122,6 +2435,13 @@ func TestHandleListMessages_Success(t *testing.T) {
 		t.Errorf("Expected limit 100, got %v", response["limit"])
 	}
 
+	// The listing exposes every sender and recipient, so it is admin only
+	w = httptest.NewRecorder()
+	server.router.ServeHTTP(w, httptest.NewRequest("GET", "/v1/messages", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected no public message listing, got %d", w.Code)
+	}
+
 	if response["offset"].(float64) != 0 {
 		t.Errorf("Expected offset 0, got %v", response["offset"])
 	}
@@ -1130,7 +2450,7 @@ func TestHandleListMessages_Success(t *testing.T) {
 func TestHandleListMessages_WithParameters(t *testing.T) {
 	server := createTestServer()
 
-	req := httptest.NewRequest("GET", "/v1/messages?limit=50&offset=10&status=delivered&sender=test@example.com&recipient=user@example.com&since=2023-01-01T00:00:00Z", nil)
+	req := httptest.NewRequest("GET", "/v1/admin/messages?limit=50&offset=10&status=delivered&sender=test@example.com&recipient=user@example.com&since=2023-01-01T00:00:00Z", nil)
 	w := httptest.NewRecorder()
 	server.router.ServeHTTP(w, req)
 
@@ -1153,6 +2473,112 @@ func TestHandleListMessages_WithParameters(t *testing.T) {
 	}
 }
 
+func TestHandleListMessages_ReturnsMessages(t *testing.T) {
+	server := createTestServer()
+	mockStorage := server.storage.(*MockStorage)
+
+	timestamp := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	mockStorage.messages["msg-1"] = &types.Message{
+		MessageID:  "msg-1",
+		Sender:     "sender@example.com",
+		Recipients: []string{"user@example.com"},
+		Subject:    "Hello",
+		Timestamp:  timestamp,
+	}
+	mockStorage.statuses["msg-1"] = &types.MessageStatus{MessageID: "msg-1", Status: types.StatusDelivered}
+
+	req := httptest.NewRequest("GET", "/v1/admin/messages?status=delivered&sender=sender@example.com&recipient=user@example.com&since=2026-01-01T00:00:00Z&limit=10&offset=5", nil)
+	w := httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d", http.StatusOK, w.Code)
+	}
+
+	filter := mockStorage.lastFilter
+	if filter.Sender != "sender@example.com" || filter.Status != types.StatusDelivered ||
+		len(filter.Recipients) != 1 || filter.Recipients[0] != "user@example.com" ||
+		filter.Limit != 10 || filter.Offset != 5 {
+		t.Errorf("Unexpected storage filter: %+v", filter)
+	}
+	if filter.Since == nil || *filter.Since != time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC).Unix() {
+		t.Errorf("Expected since to be passed as a Unix timestamp, got %v", filter.Since)
+	}
+
+	var response struct {
+		Messages []types.MessageSummary `json:"messages"`
+		Total    int                    `json:"total"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to unmarshal response: %v", err)
+	}
+	if response.Total != 1 || len(response.Messages) != 1 {
+		t.Fatalf("Expected one message, got %+v", response)
+	}
+	summary := response.Messages[0]
+	if summary.MessageID != "msg-1" || summary.Status != types.StatusDelivered ||
+		summary.Subject != "Hello" || !summary.Timestamp.Equal(timestamp) {
+		t.Errorf("Unexpected message summary: %+v", summary)
+	}
+}
+
+func TestHandleListMessages_Full(t *testing.T) {
+	server := createTestServer()
+	mockStorage := server.storage.(*MockStorage)
+
+	mockStorage.messages["msg-1"] = &types.Message{
+		MessageID:  "msg-1",
+		Sender:     "sender@example.com",
+		Recipients: []string{"user@example.com"},
+		Subject:    "Hello",
+		Payload:    json.RawMessage(`{"text":"hi"}`),
+		Labels:     []string{"campaign"},
+	}
+	mockStorage.statuses["msg-1"] = &types.MessageStatus{
+		MessageID:  "msg-1",
+		Status:     types.StatusDelivered,
+		Recipients: []types.RecipientStatus{{Address: "user@example.com", Status: types.StatusDelivered}},
+	}
+
+	// The limit is capped for full messages
+	req := httptest.NewRequest("GET", "/v1/admin/messages?include=full&limit=500", nil)
+	w := httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	if mockStorage.lastFilter.Limit != maxFullListLimit {
+		t.Errorf("Expected limit %d, got %d", maxFullListLimit, mockStorage.lastFilter.Limit)
+	}
+
+	var response struct {
+		Messages []types.MessageWithStatus `json:"messages"`
+		Total    int                       `json:"total"`
+		Limit    int                       `json:"limit"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to unmarshal response: %v", err)
+	}
+	if response.Total != 1 || response.Limit != maxFullListLimit || len(response.Messages) != 1 {
+		t.Fatalf("Unexpected response: %s", w.Body.String())
+	}
+	entry := response.Messages[0]
+	if entry.Message == nil || entry.MessageID != "msg-1" || string(entry.Payload) != `{"text":"hi"}` || len(entry.Labels) != 1 {
+		t.Errorf("Expected the complete message, got %s", w.Body.String())
+	}
+	if entry.Status != types.StatusDelivered || len(entry.RecipientStatuses) != 1 || entry.RecipientStatuses[0].Address != "user@example.com" {
+		t.Errorf("Expected the message's statuses, got %s", w.Body.String())
+	}
+
+	req = httptest.NewRequest("GET", "/v1/admin/messages?include=everything", nil)
+	w = httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+	if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "INVALID_INCLUDE") {
+		t.Errorf("Expected INVALID_INCLUDE, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
 func TestHandleListMessages_InvalidLimit(t *testing.T) {
 	server := createTestServer()
 
@@ -1168,7 +2594,7 @@ func TestHandleListMessages_InvalidLimit(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			req := httptest.NewRequest("GET", "/v1/messages?limit="+tt.limit, nil)
+			req := httptest.NewRequest("GET", "/v1/admin/messages?limit="+tt.limit, nil)
 			w := httptest.NewRecorder()
 			server.router.ServeHTTP(w, req)
 
@@ -1202,7 +2628,7 @@ func TestHandleListMessages_InvalidOffset(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			req := httptest.NewRequest("GET", "/v1/messages?offset="+tt.offset, nil)
+			req := httptest.NewRequest("GET", "/v1/admin/messages?offset="+tt.offset, nil)
 			w := httptest.NewRecorder()
 			server.router.ServeHTTP(w, req)
 
@@ -1226,7 +2652,7 @@ func TestHandleListMessages_InvalidOffset(t *testing.T) {
 func TestHandleListMessages_InvalidSince(t *testing.T) {
 	server := createTestServer()
 
-	req := httptest.NewRequest("GET", "/v1/messages?since=invalid-date", nil)
+	req := httptest.NewRequest("GET", "/v1/admin/messages?since=invalid-date", nil)
 	w := httptest.NewRecorder()
 	server.router.ServeHTTP(w, req)
 
@@ -1370,8 +2796,92 @@ func TestHandleDiscoverAgentsByDomain_NotFound(t *testing.T) {
 		t.Fatalf("Failed to unmarshal error response: %v", err)
 	}
 
-	if errorResponse.Error.Code != "DOMAIN_NOT_FOUND" {
-		t.Errorf("Expected error code 'DOMAIN_NOT_FOUND', got %s", errorResponse.Error.Code)
+	if errorResponse.Error.Code != "DOMAIN_NOT_FOUND" {
+		t.Errorf("Expected error code 'DOMAIN_NOT_FOUND', got %s", errorResponse.Error.Code)
+	}
+}
+
+func TestHandleDiscoverAgentSchemas(t *testing.T) {
+	server := createTestServerWithRealProcessor()
+	ctx := context.Background()
+
+	sm, err := schema.NewManager(schema.ManagerConfig{
+		RegistryType:  "local",
+		LocalRegistry: schema.LocalRegistryConfig{BasePath: t.TempDir(), CreateDirs: true},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create schema manager: %v", err)
+	}
+	for _, id := range []string{"agntcy:commerce.order.v1", "agntcy:commerce.invoice.v1", "agntcy:billing.invoice.v1"} {
+		schemaID, _ := schema.ParseSchemaIdentifier(id)
+		if err := sm.RegisterSchema(ctx, &schema.Schema{ID: *schemaID, Definition: json.RawMessage(`{"type":"object"}`)}, nil); err != nil {
+			t.Fatalf("Failed to register %s: %v", id, err)
+		}
+	}
+	server.schemaManager = sm
+
+	err = server.agentRegistry.RegisterAgent(ctx, &agents.LocalAgent{
+		Address:          "sales",
+		DeliveryMode:     "push",
+		PushTarget:       "http://example.com/webhook",
+		SupportedSchemas: []string{"agntcy:commerce.*", "agntcy:billing.invoice.v1"},
+	})
+	if err != nil {
+		t.Fatalf("Failed to register agent: %v", err)
+	}
+
+	for _, path := range []string{
+		"/v1/discovery/agents/localhost/sales/schemas",
+		"/v1/discovery/agents/localhost/Sales@LocalHost/schemas",
+	} {
+		req := httptest.NewRequest("GET", path, nil)
+		w := httptest.NewRecorder()
+		server.router.ServeHTTP(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("GET %s: expected status %d, got %d: %s", path, http.StatusOK, w.Code, w.Body.String())
+		}
+		if strings.Contains(w.Body.String(), "example.com/webhook") || strings.Contains(w.Body.String(), "api_key") {
+			t.Errorf("Expected no push target or API key, got %s", w.Body.String())
+		}
+
+		var response struct {
+			Address        string        `json:"address"`
+			RequiresSchema bool          `json:"requires_schema"`
+			Schemas        []agentSchema `json:"schemas"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+			t.Fatalf("Failed to unmarshal response: %v", err)
+		}
+		if response.Address != "sales@localhost" || !response.RequiresSchema || len(response.Schemas) != 2 {
+			t.Fatalf("Unexpected response: %+v", response)
+		}
+		wildcard, exact := response.Schemas[0], response.Schemas[1]
+		wantMatches := []string{"agntcy:commerce.invoice.v1", "agntcy:commerce.order.v1"}
+		if !wildcard.Wildcard || !reflect.DeepEqual(wildcard.Matches, wantMatches) {
+			t.Errorf("Expected the wildcard to match %v, got %+v", wantMatches, wildcard)
+		}
+		if exact.Wildcard || exact.Schema != "agntcy:billing.invoice.v1" || exact.Matches != nil {
+			t.Errorf("Unexpected exact schema entry: %+v", exact)
+		}
+	}
+
+	tests := []struct {
+		path   string
+		status int
+		code   string
+	}{
+		{"/v1/discovery/agents/localhost/nobody/schemas", http.StatusNotFound, "AGENT_NOT_FOUND"},
+		{"/v1/discovery/agents/localhost/sales@other.com/schemas", http.StatusBadRequest, "INVALID_AGENT_ADDRESS"},
+		{"/v1/discovery/agents/other.com/sales/schemas", http.StatusNotFound, "DOMAIN_NOT_FOUND"},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest("GET", tt.path, nil)
+		w := httptest.NewRecorder()
+		server.router.ServeHTTP(w, req)
+		if w.Code != tt.status || !strings.Contains(w.Body.String(), tt.code) {
+			t.Errorf("GET %s: expected %d %s, got %d: %s", tt.path, tt.status, tt.code, w.Code, w.Body.String())
+		}
 	}
 }
 
@@ -1465,6 +2975,184 @@ func TestHandleUnregisterAgent_Success(t *testing.T) {
 	}
 }
 
+func TestHandleDrainInbox(t *testing.T) {
+	server := createTestServer()
+	mockStorage := server.storage.(*MockStorage)
+	mockStorage.messages["m1"] = &types.Message{MessageID: "m1", Recipients: []string{"leaver@localhost", "other@localhost"}}
+	mockStorage.messages["m2"] = &types.Message{MessageID: "m2", Recipients: []string{"leaver@localhost"}}
+	mockStorage.messages["m3"] = &types.Message{MessageID: "m3", Recipients: []string{"third@localhost"}}
+
+	tests := []struct {
+		name    string
+		path    string
+		status  int
+		address string
+		drained float64
+	}{
+		{"agent name", "/v1/admin/agents/leaver/inbox", http.StatusOK, "leaver@localhost", 2},
+		{"full address", "/v1/admin/agents/other@localhost/inbox", http.StatusOK, "other@localhost", 1},
+		{"mixed case address", "/v1/admin/agents/Third@LocalHost/inbox", http.StatusOK, "third@localhost", 1},
+		{"remote address", "/v1/admin/agents/leaver@remote.com/inbox", http.StatusBadRequest, "", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("DELETE", tt.path, nil)
+			w := httptest.NewRecorder()
+			server.router.ServeHTTP(w, req)
+
+			if w.Code != tt.status {
+				t.Fatalf("Expected status %d, got %d: %s", tt.status, w.Code, w.Body.String())
+			}
+			if tt.status != http.StatusOK {
+				return
+			}
+			var response map[string]interface{}
+			if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+				t.Fatalf("Failed to unmarshal response: %v", err)
+			}
+			if response["address"] != tt.address || response["drained"] != tt.drained {
+				t.Errorf("Unexpected response: %v", response)
+			}
+		})
+	}
+}
+
+func TestHandleListDeliveryErrors(t *testing.T) {
+	server := createTestServer()
+	mockStorage := server.storage.(*MockStorage)
+	mockStorage.statuses["m1"] = &types.MessageStatus{
+		MessageID: "m1",
+		Recipients: []types.RecipientStatus{
+			{Address: "a@remote.com", Status: types.StatusFailed, Attempts: 3, ErrorCode: "DELIVERY_FAILED", ErrorMessage: "connection refused"},
+			{Address: "b@remote.com", Status: types.StatusDelivered},
+		},
+	}
+
+	req := httptest.NewRequest("GET", "/v1/admin/errors?since=1h", nil)
+	w := httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	var response struct {
+		Errors []types.DeliveryError `json:"errors"`
+		Total  int                   `json:"total"`
+		Limit  int                   `json:"limit"`
+		Since  time.Time             `json:"since"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to unmarshal response: %v", err)
+	}
+	if response.Total != 1 || response.Limit != 100 || len(response.Errors) != 1 {
+		t.Fatalf("Unexpected response: %s", w.Body.String())
+	}
+	if e := response.Errors[0]; e.MessageID != "m1" || e.Recipient != "a@remote.com" || e.ErrorCode != "DELIVERY_FAILED" {
+		t.Errorf("Unexpected delivery error: %+v", e)
+	}
+	if mockStorage.lastErrorsSince == nil || time.Since(*mockStorage.lastErrorsSince) < time.Hour-time.Minute {
+		t.Errorf("Expected since to be an hour ago, got %v", mockStorage.lastErrorsSince)
+	}
+
+	since := "2026-01-02T03:04:05Z"
+	req = httptest.NewRequest("GET", "/v1/admin/errors?since="+since, nil)
+	w = httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK || mockStorage.lastErrorsSince.Format(time.RFC3339) != since {
+		t.Errorf("Expected an RFC3339 since to be passed through, got %d %v", w.Code, mockStorage.lastErrorsSince)
+	}
+
+	for _, query := range []string{"since=yesterday", "since=-1h", "limit=0", "limit=1001", "limit=abc"} {
+		req := httptest.NewRequest("GET", "/v1/admin/errors?"+query, nil)
+		w := httptest.NewRecorder()
+		server.router.ServeHTTP(w, req)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected status %d, got %d", query, http.StatusBadRequest, w.Code)
+		}
+	}
+}
+
+func TestHandleListPendingInbox(t *testing.T) {
+	server := createTestServer()
+	st := storage.NewMemoryStorage(storage.MemoryStorageConfig{})
+	server.storage = st
+	ctx := context.Background()
+
+	now := time.Now().UTC()
+	inbox := func(address string, age time.Duration, acknowledged bool) types.RecipientStatus {
+		return types.RecipientStatus{
+			Address:        address,
+			Status:         types.StatusDelivered,
+			Timestamp:      now.Add(-age),
+			LocalDelivery:  true,
+			InboxDelivered: true,
+			Acknowledged:   acknowledged,
+		}
+	}
+	statuses := map[string][]types.RecipientStatus{
+		"m1": {inbox("a@localhost", time.Hour, false), inbox("b@localhost", 2*time.Hour, false)},
+		"m2": {inbox("a@localhost", 3*time.Hour, true), {Address: "c@remote.com", Status: types.StatusDelivered, Timestamp: now}},
+		"m3": {inbox("c@localhost", time.Minute, false)},
+	}
+	for messageID, recipients := range statuses {
+		if err := st.StoreStatus(ctx, messageID, &types.MessageStatus{MessageID: messageID, Recipients: recipients}); err != nil {
+			t.Fatalf("Failed to store status: %v", err)
+		}
+	}
+
+	type page struct {
+		Messages []struct {
+			MessageID      string `json:"message_id"`
+			Recipient      string `json:"recipient"`
+			WaitingSeconds int64  `json:"waiting_seconds"`
+		} `json:"messages"`
+		NextCursor string `json:"next_cursor"`
+	}
+	get := func(query string) (*httptest.ResponseRecorder, page) {
+		req := httptest.NewRequest("GET", "/v1/admin/inbox/pending?"+query, nil)
+		w := httptest.NewRecorder()
+		server.router.ServeHTTP(w, req)
+		var response page
+		if w.Code == http.StatusOK {
+			if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+				t.Fatalf("Failed to unmarshal response: %v", err)
+			}
+		}
+		return w, response
+	}
+
+	var seen []string
+	cursor := ""
+	for i := 0; i < 3; i++ {
+		w, response := get("limit=2&cursor=" + url.QueryEscape(cursor))
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+		}
+		for _, message := range response.Messages {
+			seen = append(seen, message.MessageID+" "+message.Recipient)
+		}
+		if i == 0 && response.Messages[0].WaitingSeconds < 7190 {
+			t.Errorf("Expected the oldest message to have waited two hours, got %ds", response.Messages[0].WaitingSeconds)
+		}
+		cursor = response.NextCursor
+		if cursor == "" {
+			break
+		}
+	}
+
+	expected := []string{"m1 b@localhost", "m1 a@localhost", "m3 c@localhost"}
+	if strings.Join(seen, ",") != strings.Join(expected, ",") {
+		t.Errorf("Expected %v, got %v", expected, seen)
+	}
+
+	for _, query := range []string{"limit=0", "limit=1001", "cursor=bogus"} {
+		if w, _ := get(query); w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected status %d, got %d", query, http.StatusBadRequest, w.Code)
+		}
+	}
+}
+
 func TestHandleUnregisterAgent_NotFound(t *testing.T) {
 	server := createTestServer()
 
@@ -1487,6 +3175,60 @@ func TestHandleUnregisterAgent_NotFound(t *testing.T) {
 	}
 }
 
+func TestHandleRateLimits(t *testing.T) {
+	server := createTestServer()
+
+	do := func(method, path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		w := httptest.NewRecorder()
+		server.router.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := do("GET", "/v1/admin/ratelimits"); w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"enabled":false`) {
+		t.Errorf("Expected rate limiting to be reported disabled, got %d: %s", w.Code, w.Body.String())
+	}
+	if w := do("DELETE", "/v1/admin/ratelimits/192.0.2.1"); w.Code != http.StatusNotFound {
+		t.Errorf("Expected status %d while disabled, got %d", http.StatusNotFound, w.Code)
+	}
+
+	server.rateLimiter = middleware.NewRateLimiter(config.RateLimitConfig{RequestsPerMinute: 60, Burst: 1})
+	server.rateLimiter.Allow("192.0.2.1")
+	server.rateLimiter.Allow("192.0.2.1")
+
+	w := do("GET", "/v1/admin/ratelimits")
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d", http.StatusOK, w.Code)
+	}
+	var response struct {
+		Enabled           bool                         `json:"enabled"`
+		RequestsPerMinute int                          `json:"requests_per_minute"`
+		Burst             int                          `json:"burst"`
+		Buckets           []middleware.RateLimitBucket `json:"buckets"`
+		Count             int                          `json:"count"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to unmarshal response: %v", err)
+	}
+	if !response.Enabled || response.RequestsPerMinute != 60 || response.Burst != 1 || response.Count != 1 {
+		t.Errorf("Unexpected response: %+v", response)
+	}
+	if len(response.Buckets) != 1 || response.Buckets[0].Key != "192.0.2.1" || response.Buckets[0].Remaining != 0 || response.Buckets[0].Throttled != 1 {
+		t.Errorf("Unexpected buckets: %+v", response.Buckets)
+	}
+
+	if w := do("DELETE", "/v1/admin/ratelimits/192.0.2.1"); w.Code != http.StatusOK {
+		t.Errorf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	if allowed, _ := server.rateLimiter.Allow("192.0.2.1"); !allowed {
+		t.Error("Expected the reset client to be allowed again")
+	}
+	w = do("DELETE", "/v1/admin/ratelimits/192.0.2.9")
+	if w.Code != http.StatusNotFound || !strings.Contains(w.Body.String(), "RATE_LIMIT_BUCKET_NOT_FOUND") {
+		t.Errorf("Expected RATE_LIMIT_BUCKET_NOT_FOUND, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
 func TestHandleListAgents_Success(t *testing.T) {
 	server := createTestServer()
 	ctx := context.Background()
@@ -1502,81 +3244,427 @@ func TestHandleListAgents_Success(t *testing.T) {
 		PushTarget:   "https://example.com/webhook",
 	}
 
-	err := server.agentRegistry.RegisterAgent(ctx, agent1)
-	if err != nil {
-		t.Fatalf("Failed to register agent1: %v", err)
-	}
+	err := server.agentRegistry.RegisterAgent(ctx, agent1)
+	if err != nil {
+		t.Fatalf("Failed to register agent1: %v", err)
+	}
+
+	err = server.agentRegistry.RegisterAgent(ctx, agent2)
+	if err != nil {
+		t.Fatalf("Failed to register agent2: %v", err)
+	}
+
+	req := httptest.NewRequest("GET", "/v1/admin/agents", nil)
+	w := httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status %d, got %d", http.StatusOK, w.Code)
+	}
+
+	var response map[string]interface{}
+	err = json.Unmarshal(w.Body.Bytes(), &response)
+	if err != nil {
+		t.Fatalf("Failed to unmarshal response: %v", err)
+	}
+
+	if response["count"].(float64) != 2 {
+		t.Errorf("Expected count 2, got %v", response["count"])
+	}
+
+	agents, ok := response["agents"].(map[string]interface{})
+	if !ok {
+		t.Fatalf("Expected agents to be a map, got %T", response["agents"])
+	}
+
+	if len(agents) != 2 {
+		t.Errorf("Expected 2 agents, got %d", len(agents))
+	}
+}
+
+func TestHandleGetAgent(t *testing.T) {
+	server := createTestServer()
+	server.deliveries = processing.NewDeliveryEngine(server.discovery, server.agentRegistry, processing.DeliveryConfig{MaxConcurrentPerAgent: 4})
+	ctx := context.Background()
+
+	if err := server.agentRegistry.RegisterAgent(ctx, &agents.LocalAgent{
+		Address:                 "hook",
+		DeliveryMode:            "push",
+		PushTarget:              "https://example.com/webhook",
+		MaxConcurrentDeliveries: 2,
+	}); err != nil {
+		t.Fatalf("Failed to register agent: %v", err)
+	}
+	if err := server.agentRegistry.RegisterAgent(ctx, &agents.LocalAgent{Address: "puller", DeliveryMode: "pull"}); err != nil {
+		t.Fatalf("Failed to register agent: %v", err)
+	}
+
+	get := func(address string) (int, map[string]interface{}) {
+		t.Helper()
+		w := httptest.NewRecorder()
+		server.router.ServeHTTP(w, httptest.NewRequest("GET", "/v1/admin/agents/"+address, nil))
+		var response map[string]interface{}
+		if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+			t.Fatalf("Failed to unmarshal response: %v", err)
+		}
+		return w.Code, response
+	}
+
+	code, response := get("hook")
+	if code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d", http.StatusOK, code)
+	}
+	agent, _ := response["agent"].(map[string]interface{})
+	if agent["address"] != "hook@localhost" || agent["api_key"] != "" {
+		t.Errorf("Expected the agent with its API key redacted, got %v", agent)
+	}
+	if response["deliveries_in_flight"] != float64(0) || response["delivery_limit"] != float64(2) {
+		t.Errorf("Expected 0 deliveries in flight under a limit of 2, got %v and %v", response["deliveries_in_flight"], response["delivery_limit"])
+	}
+
+	code, response = get("puller@localhost")
+	if code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d", http.StatusOK, code)
+	}
+	if _, ok := response["deliveries_in_flight"]; ok {
+		t.Errorf("Expected no delivery counts for a pull agent, got %v", response)
+	}
+
+	if code, _ := get("missing"); code != http.StatusNotFound {
+		t.Errorf("Expected status %d for an unknown agent, got %d", http.StatusNotFound, code)
+	}
+}
+
+// Test inbox handlers
+func TestHandleInspectDiscovery(t *testing.T) {
+	server := createTestServer()
+	records := map[string]string{
+		"partner.com": "v=amtp1;gateway=http://gw.partner.com",
+		"broken.com":  "v=amtp1;auth=cert",
+	}
+	server.discovery = discovery.NewMockDiscovery(records, time.Minute)
+
+	get := func(path string) (int, map[string]interface{}) {
+		w := httptest.NewRecorder()
+		server.router.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		var response map[string]interface{}
+		if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+			t.Fatalf("Failed to unmarshal response: %v", err)
+		}
+		return w.Code, response
+	}
+
+	code, response := get("/v1/admin/discovery/partner.com")
+	if code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d: %v", http.StatusOK, code, response)
+	}
+	inspection := response["inspection"].(map[string]interface{})
+	if inspection["cached"] != false || inspection["capabilities"].(map[string]interface{})["gateway"] != "http://gw.partner.com" {
+		t.Errorf("Unexpected inspection: %v", inspection)
+	}
+	// Deliveries refuse plain HTTP gateways unless allowed
+	if response["gateway_error"] == nil {
+		t.Errorf("Expected a gateway error for an http gateway, got %v", response)
+	}
+
+	_, response = get("/v1/admin/discovery/partner.com")
+	if response["inspection"].(map[string]interface{})["cached"] != true {
+		t.Errorf("Expected a cached result, got %v", response)
+	}
+	_, response = get("/v1/admin/discovery/partner.com?fresh=true")
+	if response["inspection"].(map[string]interface{})["cached"] != false {
+		t.Errorf("Expected fresh=true to bypass the cache, got %v", response)
+	}
+
+	code, response = get("/v1/admin/discovery/broken.com")
+	inspection = response["inspection"].(map[string]interface{})
+	record := inspection["records"].([]interface{})[0].(map[string]interface{})
+	if code != http.StatusOK || inspection["error"] == nil || !strings.Contains(record["error"].(string), "missing gateway") {
+		t.Errorf("Expected the parse error to be reported, got %d: %v", code, response)
+	}
+}
+
+func TestHandleGetConfig(t *testing.T) {
+	server := createTestServer()
+	server.config.Auth.APIKeySalt = "pepper"
+	server.config.Environment = "prod"
+	server.config.Files = []string{"config.yaml", "config.prod.yaml"}
+
+	w := httptest.NewRecorder()
+	server.router.ServeHTTP(w, httptest.NewRequest("GET", "/v1/admin/config", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	if strings.Contains(w.Body.String(), "pepper") {
+		t.Errorf("Expected the API key salt to be redacted, got %s", w.Body.String())
+	}
+
+	var response struct {
+		Config      map[string]interface{} `json:"config"`
+		Environment string                 `json:"environment"`
+		Files       []string               `json:"files"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to unmarshal response: %v", err)
+	}
+	if response.Environment != "prod" || len(response.Files) != 2 {
+		t.Errorf("Unexpected sources: %q, %v", response.Environment, response.Files)
+	}
+	serverConfig, _ := response.Config["server"].(map[string]interface{})
+	if serverConfig["domain"] != server.config.Server.Domain {
+		t.Errorf("Expected the config keyed like the YAML files, got %v", response.Config["server"])
+	}
+}
+
+func TestHandleGetInbox_Success(t *testing.T) {
+	server := createTestServer()
+	ctx := context.Background()
+
+	// Register agent with API key
+	agent := &agents.LocalAgent{
+		Address:      "testuser",
+		DeliveryMode: "pull",
+		APIKey:       "valid-api-key",
+	}
+	err := server.agentRegistry.RegisterAgent(ctx, agent)
+	if err != nil {
+		t.Fatalf("Failed to register agent: %v", err)
+	}
+
+	req := httptest.NewRequest("GET", "/v1/inbox/testuser@localhost", nil)
+	req.Header.Set("Authorization", "Bearer valid-api-key")
+	w := httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status %d, got %d", http.StatusOK, w.Code)
+	}
+
+	var response map[string]interface{}
+	err = json.Unmarshal(w.Body.Bytes(), &response)
+	if err != nil {
+		t.Fatalf("Failed to unmarshal response: %v", err)
+	}
+
+	if response["recipient"] != "testuser@localhost" {
+		t.Errorf("Expected recipient 'testuser@localhost', got %v", response["recipient"])
+	}
+
+	if response["count"].(float64) != 0 {
+		t.Errorf("Expected count 0, got %v", response["count"])
+	}
+}
+
+func TestInboxTokens(t *testing.T) {
+	server := createTestServer()
+	server.config.Auth.InboxTokens = config.InboxTokenConfig{DefaultTTL: 15 * time.Minute, MaxTTL: time.Hour}
+	signer, err := inboxtoken.NewSigner([]byte("0123456789abcdef0123456789abcdef"))
+	if err != nil {
+		t.Fatalf("Failed to create signer: %v", err)
+	}
+	server.inboxTokens = signer
+
+	ctx := context.Background()
+	for _, name := range []string{"testuser", "otheruser"} {
+		if err := server.agentRegistry.RegisterAgent(ctx, &agents.LocalAgent{Address: name, DeliveryMode: "pull", APIKey: name + "-key"}); err != nil {
+			t.Fatalf("Failed to register agent: %v", err)
+		}
+	}
+
+	mint := func(t *testing.T, address, body string) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest("POST", "/v1/admin/agents/"+address+"/tokens", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		server.router.ServeHTTP(w, req)
+		return w
+	}
+	getInbox := func(address, credential string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/v1/inbox/"+address, nil)
+		req.Header.Set("Authorization", "Bearer "+credential)
+		w := httptest.NewRecorder()
+		server.router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := mint(t, "testuser", `{"ttl": "30m"}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusCreated, w.Code, w.Body.String())
+	}
+	var minted struct {
+		Token     string    `json:"token"`
+		Agent     string    `json:"agent"`
+		ExpiresAt time.Time `json:"expires_at"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &minted); err != nil {
+		t.Fatalf("Failed to unmarshal response: %v", err)
+	}
+	if minted.Agent != "testuser@localhost" || time.Until(minted.ExpiresAt) < 29*time.Minute || time.Until(minted.ExpiresAt) > 31*time.Minute {
+		t.Errorf("Unexpected token: %s", w.Body.String())
+	}
+
+	t.Run("valid token reads and acknowledges", func(t *testing.T) {
+		if w := getInbox("testuser@localhost", minted.Token); w.Code != http.StatusOK {
+			t.Errorf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+		}
+
+		messageID := "token-message"
+		server.storage.(*MockStorage).messages[messageID] = &types.Message{MessageID: messageID, Recipients: []string{"testuser@localhost"}}
+		req := httptest.NewRequest("DELETE", "/v1/inbox/testuser@localhost/"+messageID, nil)
+		req.Header.Set("Authorization", "Bearer "+minted.Token)
+		w := httptest.NewRecorder()
+		server.router.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			t.Errorf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+		}
+	})
+
+	t.Run("API key still works", func(t *testing.T) {
+		if w := getInbox("testuser@localhost", "testuser-key"); w.Code != http.StatusOK {
+			t.Errorf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+		}
+	})
+
+	t.Run("expired token", func(t *testing.T) {
+		expired, _, _ := signer.Mint("testuser@localhost", time.Now().Add(-2*time.Hour), time.Hour)
+		w := getInbox("testuser@localhost", expired)
+		if w.Code != http.StatusUnauthorized || !strings.Contains(w.Body.String(), "TOKEN_EXPIRED") {
+			t.Errorf("Expected 401 TOKEN_EXPIRED, got %d: %s", w.Code, w.Body.String())
+		}
+	})
+
+	t.Run("wrong agent", func(t *testing.T) {
+		w := getInbox("otheruser@localhost", minted.Token)
+		if w.Code != http.StatusForbidden || !strings.Contains(w.Body.String(), "ACCESS_DENIED") {
+			t.Errorf("Expected 403 ACCESS_DENIED, got %d: %s", w.Code, w.Body.String())
+		}
+	})
 
-	err = server.agentRegistry.RegisterAgent(ctx, agent2)
-	if err != nil {
-		t.Fatalf("Failed to register agent2: %v", err)
-	}
+	t.Run("token from another key", func(t *testing.T) {
+		other, _ := inboxtoken.NewSigner([]byte(strings.Repeat("k", inboxtoken.MinKeySize)))
+		forged, _, _ := other.Mint("testuser@localhost", time.Now(), time.Hour)
+		w := getInbox("testuser@localhost", forged)
+		if w.Code != http.StatusUnauthorized || !strings.Contains(w.Body.String(), "INVALID_TOKEN") {
+			t.Errorf("Expected 401 INVALID_TOKEN, got %d: %s", w.Code, w.Body.String())
+		}
+	})
 
-	req := httptest.NewRequest("GET", "/v1/admin/agents", nil)
-	w := httptest.NewRecorder()
-	server.router.ServeHTTP(w, req)
+	t.Run("token issued before registration", func(t *testing.T) {
+		early, _, _ := signer.Mint("otheruser@localhost", time.Now().Add(-time.Hour), 2*time.Hour)
+		w := getInbox("otheruser@localhost", early)
+		if w.Code != http.StatusUnauthorized || !strings.Contains(w.Body.String(), "TOKEN_REVOKED") {
+			t.Errorf("Expected 401 TOKEN_REVOKED, got %d: %s", w.Code, w.Body.String())
+		}
+	})
 
-	if w.Code != http.StatusOK {
-		t.Errorf("Expected status %d, got %d", http.StatusOK, w.Code)
-	}
+	t.Run("token issued before key rotation", func(t *testing.T) {
+		// Backdate the registration so the stale token falls between it
+		// and the rotation
+		server.storage.(*MockStorage).agents["otheruser@localhost"].CreatedAt = time.Now().Add(-time.Hour)
+		stale, _, _ := signer.Mint("otheruser@localhost", time.Now().Add(-30*time.Minute), time.Hour)
+		if w := getInbox("otheruser@localhost", stale); w.Code != http.StatusOK {
+			t.Fatalf("Expected status %d before the rotation, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+		}
 
-	var response map[string]interface{}
-	err = json.Unmarshal(w.Body.Bytes(), &response)
-	if err != nil {
-		t.Fatalf("Failed to unmarshal response: %v", err)
-	}
+		if _, err := server.agentRegistry.RotateAPIKey(ctx, "otheruser@localhost"); err != nil {
+			t.Fatalf("Failed to rotate API key: %v", err)
+		}
+		w := getInbox("otheruser@localhost", stale)
+		if w.Code != http.StatusUnauthorized || !strings.Contains(w.Body.String(), "TOKEN_REVOKED") {
+			t.Errorf("Expected 401 TOKEN_REVOKED, got %d: %s", w.Code, w.Body.String())
+		}
 
-	if response["count"].(float64) != 2 {
-		t.Errorf("Expected count 2, got %v", response["count"])
-	}
+		fresh, _, _ := signer.Mint("otheruser@localhost", time.Now(), time.Hour)
+		if w := getInbox("otheruser@localhost", fresh); w.Code != http.StatusOK {
+			t.Errorf("Expected a token minted after the rotation to work, got %d: %s", w.Code, w.Body.String())
+		}
+	})
 
-	agents, ok := response["agents"].(map[string]interface{})
-	if !ok {
-		t.Fatalf("Expected agents to be a map, got %T", response["agents"])
-	}
+	t.Run("unregistered agent", func(t *testing.T) {
+		orphan, _, _ := signer.Mint("gone@localhost", time.Now(), time.Hour)
+		if w := getInbox("gone@localhost", orphan); w.Code != http.StatusForbidden {
+			t.Errorf("Expected status %d, got %d: %s", http.StatusForbidden, w.Code, w.Body.String())
+		}
+	})
 
-	if len(agents) != 2 {
-		t.Errorf("Expected 2 agents, got %d", len(agents))
-	}
+	t.Run("mint errors", func(t *testing.T) {
+		if w := mint(t, "testuser", `{"ttl": "2h"}`); w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "INVALID_TTL") {
+			t.Errorf("Expected 400 INVALID_TTL, got %d: %s", w.Code, w.Body.String())
+		}
+		if w := mint(t, "nobody", ""); w.Code != http.StatusNotFound {
+			t.Errorf("Expected status %d, got %d", http.StatusNotFound, w.Code)
+		}
+
+		server.inboxTokens = nil
+		defer func() { server.inboxTokens = signer }()
+		if w := mint(t, "testuser", ""); w.Code != http.StatusServiceUnavailable {
+			t.Errorf("Expected status %d, got %d", http.StatusServiceUnavailable, w.Code)
+		}
+	})
 }
 
-// Test inbox handlers
-func TestHandleGetInbox_Success(t *testing.T) {
+func TestHandleGetInbox_NDJSON(t *testing.T) {
 	server := createTestServer()
+	mockStorage := server.storage.(*MockStorage)
 	ctx := context.Background()
 
-	// Register agent with API key
 	agent := &agents.LocalAgent{
 		Address:      "testuser",
 		DeliveryMode: "pull",
 		APIKey:       "valid-api-key",
 	}
-	err := server.agentRegistry.RegisterAgent(ctx, agent)
-	if err != nil {
+	if err := server.agentRegistry.RegisterAgent(ctx, agent); err != nil {
 		t.Fatalf("Failed to register agent: %v", err)
 	}
 
-	req := httptest.NewRequest("GET", "/v1/inbox/testuser@localhost", nil)
+	// Enough messages to span more than one storage page
+	total := inboxPageSize + inboxPageSize/2
+	for i := 0; i < total; i++ {
+		msg := &types.Message{
+			MessageID:  fmt.Sprintf("msg-%04d", i),
+			Sender:     "sender@example.com",
+			Recipients: []string{"testuser@localhost"},
+		}
+		mockStorage.messages[msg.MessageID] = msg
+	}
+	mockStorage.messages["other"] = &types.Message{
+		MessageID:  "other",
+		Sender:     "sender@example.com",
+		Recipients: []string{"someone@localhost"},
+	}
+
+	req := httptest.NewRequest("GET", "/v1/inbox/testuser@localhost?format=ndjson", nil)
 	req.Header.Set("Authorization", "Bearer valid-api-key")
 	w := httptest.NewRecorder()
 	server.router.ServeHTTP(w, req)
 
 	if w.Code != http.StatusOK {
-		t.Errorf("Expected status %d, got %d", http.StatusOK, w.Code)
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
 	}
-
-	var response map[string]interface{}
-	err = json.Unmarshal(w.Body.Bytes(), &response)
-	if err != nil {
-		t.Fatalf("Failed to unmarshal response: %v", err)
+	if ct := w.Header().Get("Content-Type"); ct != "application/x-ndjson" {
+		t.Errorf("Expected NDJSON content type, got %s", ct)
 	}
 
-	if response["recipient"] != "testuser@localhost" {
-		t.Errorf("Expected recipient 'testuser@localhost', got %v", response["recipient"])
+	lines := bytes.Split(bytes.TrimSpace(w.Body.Bytes()), []byte("\n"))
+	if len(lines) != total {
+		t.Fatalf("Expected %d lines, got %d", total, len(lines))
+	}
+	for i, line := range lines {
+		var message types.Message
+		if err := json.Unmarshal(line, &message); err != nil {
+			t.Fatalf("Line %d is not valid JSON: %v", i, err)
+		}
+		if expected := fmt.Sprintf("msg-%04d", i); message.MessageID != expected {
+			t.Errorf("Line %d: expected %s, got %s", i, expected, message.MessageID)
+		}
 	}
 
-	if response["count"].(float64) != 0 {
-		t.Errorf("Expected count 0, got %v", response["count"])
+	req = httptest.NewRequest("GET", "/v1/inbox/testuser@localhost?format=xml", nil)
+	req.Header.Set("Authorization", "Bearer valid-api-key")
+	w = httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+	if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "INVALID_FORMAT") {
+		t.Errorf("Expected INVALID_FORMAT, got %d: %s", w.Code, w.Body.String())
 	}
 }
 
@@ -1716,6 +3804,257 @@ func TestHandleAcknowledgeMessage_NotFound(t *testing.T) {
 	}
 }
 
+func TestHandleAcknowledgeMessage_ReadReceipt(t *testing.T) {
+	server := createTestServerWithRealProcessor()
+	ctx := context.Background()
+	for _, address := range []string{"alice", "bob"} {
+		if err := server.agentRegistry.RegisterAgent(ctx, &agents.LocalAgent{
+			Address:      address,
+			DeliveryMode: "pull",
+			APIKey:       address + "-key",
+		}); err != nil {
+			t.Fatalf("Failed to register agent: %v", err)
+		}
+	}
+
+	send := func(requestReceipt bool) string {
+		body, _ := json.Marshal(types.SendMessageRequest{
+			Sender:         "alice@localhost",
+			Recipients:     []string{"bob@localhost"},
+			Subject:        "Quarterly report",
+			Payload:        json.RawMessage(`{"message": "hello"}`),
+			RequestReceipt: requestReceipt,
+		})
+		req := httptest.NewRequest("POST", "/v1/messages", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer alice-key")
+		w := httptest.NewRecorder()
+		server.router.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected the message to be sent, got %d: %s", w.Code, w.Body.String())
+		}
+		var sent types.SendMessageResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &sent); err != nil {
+			t.Fatalf("Failed to unmarshal response: %v", err)
+		}
+		return sent.MessageID
+	}
+	acknowledge := func(recipient, messageID string) {
+		req := httptest.NewRequest("DELETE", "/v1/inbox/"+recipient+"@localhost/"+messageID, nil)
+		req.Header.Set("Authorization", "Bearer "+recipient+"-key")
+		w := httptest.NewRecorder()
+		server.router.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected the message to be acknowledged, got %d: %s", w.Code, w.Body.String())
+		}
+		server.processor.(*processing.MessageProcessor).Wait()
+	}
+
+	acknowledge("bob", send(false))
+	if inbox, _ := server.storage.GetInboxMessages(ctx, "alice@localhost"); len(inbox) != 0 {
+		t.Fatalf("Expected no receipt without a request, got %d messages", len(inbox))
+	}
+
+	messageID := send(true)
+	acknowledge("bob", messageID)
+
+	// The sender is local, so the receipt lands in its inbox
+	inbox, err := server.storage.GetInboxMessages(ctx, "alice@localhost")
+	if err != nil {
+		t.Fatalf("Failed to get inbox: %v", err)
+	}
+	if len(inbox) != 1 {
+		t.Fatalf("Expected one receipt in the sender's inbox, got %d messages", len(inbox))
+	}
+	receipt := inbox[0]
+	if receipt.Sender != "bob@localhost" || receipt.InReplyTo != messageID || receipt.ResponseType != types.ResponseTypeReadReceipt {
+		t.Errorf("Unexpected receipt: sender %s, in_reply_to %s, response_type %s", receipt.Sender, receipt.InReplyTo, receipt.ResponseType)
+	}
+	var payload types.ReadReceipt
+	if err := json.Unmarshal(receipt.Payload, &payload); err != nil {
+		t.Fatalf("Failed to unmarshal receipt payload: %v", err)
+	}
+	if payload.OriginalMessageID != messageID || payload.Recipient != "bob@localhost" || payload.AcknowledgedAt.IsZero() {
+		t.Errorf("Unexpected receipt payload: %+v", payload)
+	}
+
+	// Receipts never ask for receipts of their own
+	acknowledge("alice", receipt.MessageID)
+	if inbox, _ := server.storage.GetInboxMessages(ctx, "bob@localhost"); len(inbox) != 0 {
+		t.Errorf("Expected no receipt for a receipt, got %d messages", len(inbox))
+	}
+}
+
+func TestHandleAcknowledgeMessage_Response(t *testing.T) {
+	server := createTestServerWithRealProcessor()
+	ctx := context.Background()
+	for _, address := range []string{"alice", "bob"} {
+		if err := server.agentRegistry.RegisterAgent(ctx, &agents.LocalAgent{
+			Address:      address,
+			DeliveryMode: "pull",
+			APIKey:       address + "-key",
+		}); err != nil {
+			t.Fatalf("Failed to register agent: %v", err)
+		}
+	}
+
+	send := func(responseType string) string {
+		body, _ := json.Marshal(types.SendMessageRequest{
+			Sender:       "alice@localhost",
+			Recipients:   []string{"bob@localhost"},
+			Subject:      "Approve order",
+			Payload:      json.RawMessage(`{"order_id": "A-1"}`),
+			ResponseType: responseType,
+		})
+		req := httptest.NewRequest("POST", "/v1/messages", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer alice-key")
+		w := httptest.NewRecorder()
+		server.router.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected the message to be sent, got %d: %s", w.Code, w.Body.String())
+		}
+		var sent types.SendMessageResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &sent); err != nil {
+			t.Fatalf("Failed to unmarshal response: %v", err)
+		}
+		return sent.MessageID
+	}
+	acknowledge := func(messageID, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("DELETE", "/v1/inbox/bob@localhost/"+messageID, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer bob-key")
+		w := httptest.NewRecorder()
+		server.router.ServeHTTP(w, req)
+		server.processor.(*processing.MessageProcessor).Wait()
+		return w
+	}
+	response := `{"approved":true}`
+
+	// A malformed body leaves the message in the inbox
+	messageID := send(types.ResponseTypeRequired)
+	if w := acknowledge(messageID, `{"response":`); w.Code != http.StatusBadRequest {
+		t.Fatalf("Expected status %d, got %d", http.StatusBadRequest, w.Code)
+	}
+
+	if w := acknowledge(messageID, `{"response": `+response+`}`); w.Code != http.StatusOK {
+		t.Fatalf("Expected the message to be acknowledged, got %d: %s", w.Code, w.Body.String())
+	}
+
+	status, err := server.storage.GetStatus(ctx, messageID)
+	if err != nil {
+		t.Fatalf("Failed to get status: %v", err)
+	}
+	if len(status.Recipients) != 1 || string(status.Recipients[0].Response) != response {
+		t.Errorf("Expected the response on the recipient status, got %+v", status.Recipients)
+	}
+
+	inbox, err := server.storage.GetInboxMessages(ctx, "alice@localhost")
+	if err != nil {
+		t.Fatalf("Failed to get inbox: %v", err)
+	}
+	if len(inbox) != 1 {
+		t.Fatalf("Expected one reply in the sender's inbox, got %d messages", len(inbox))
+	}
+	reply := inbox[0]
+	if reply.Sender != "bob@localhost" || reply.InReplyTo != messageID || reply.ResponseType != types.ResponseTypeAckResponse {
+		t.Errorf("Unexpected reply: sender %s, in_reply_to %s, response_type %s", reply.Sender, reply.InReplyTo, reply.ResponseType)
+	}
+	if string(reply.Payload) != response || reply.Subject != "Re: Approve order" {
+		t.Errorf("Unexpected reply: subject %q, payload %s", reply.Subject, reply.Payload)
+	}
+
+	// Without a required response the payload is only recorded
+	messageID = send("")
+	if w := acknowledge(messageID, `{"response": `+response+`}`); w.Code != http.StatusOK {
+		t.Fatalf("Expected the message to be acknowledged, got %d: %s", w.Code, w.Body.String())
+	}
+	if inbox, _ := server.storage.GetInboxMessages(ctx, "alice@localhost"); len(inbox) != 1 {
+		t.Errorf("Expected no reply for a message not requiring one, got %d messages", len(inbox))
+	}
+	if status, _ := server.storage.GetStatus(ctx, messageID); status == nil || string(status.Recipients[0].Response) != response {
+		t.Errorf("Expected the response on the recipient status, got %+v", status)
+	}
+}
+
+func TestHandleAcknowledgeMessage_ReadReceiptRemoteSender(t *testing.T) {
+	server := createTestServer()
+	mockStorage := server.storage.(*MockStorage)
+	mockProcessor := server.processor.(*MockMessageProcessor)
+	if err := server.agentRegistry.RegisterAgent(context.Background(), &agents.LocalAgent{
+		Address:      "testuser",
+		DeliveryMode: "pull",
+		APIKey:       "valid-api-key",
+	}); err != nil {
+		t.Fatalf("Failed to register agent: %v", err)
+	}
+
+	messageID := "test-message-123"
+	mockStorage.messages[messageID] = &types.Message{
+		MessageID:      messageID,
+		Sender:         "sender@example.com",
+		Recipients:     []string{"testuser@localhost"},
+		RequestReceipt: true,
+	}
+
+	req := httptest.NewRequest("DELETE", "/v1/inbox/testuser@localhost/"+messageID, nil)
+	req.Header.Set("Authorization", "Bearer valid-api-key")
+	w := httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+
+	// The receipt is handed to the processor to relay to the sender's gateway
+	receipt := mockProcessor.lastMessage
+	if receipt == nil || receipt.ResponseType != types.ResponseTypeReadReceipt {
+		t.Fatalf("Expected a read receipt to be processed, got %+v", receipt)
+	}
+	if len(receipt.Recipients) != 1 || receipt.Recipients[0] != "sender@example.com" || receipt.Sender != "testuser@localhost" {
+		t.Errorf("Expected a receipt from the recipient to the sender, got %s to %v", receipt.Sender, receipt.Recipients)
+	}
+	if !mockProcessor.lastOptions.Async {
+		t.Error("Expected the receipt to be delivered in the background")
+	}
+}
+
+func TestHandleAcknowledgeMessage_ArchiveSendsNothing(t *testing.T) {
+	server := createTestServer()
+	server.config.Message.ArchiveAddress = "archive@localhost"
+	mockStorage := server.storage.(*MockStorage)
+	mockProcessor := server.processor.(*MockMessageProcessor)
+	if err := server.agentRegistry.RegisterAgent(context.Background(), &agents.LocalAgent{
+		Address:      "archive",
+		DeliveryMode: "pull",
+		APIKey:       "archive-key",
+	}); err != nil {
+		t.Fatalf("Failed to register agent: %v", err)
+	}
+
+	messageID := "test-message-123"
+	mockStorage.messages[messageID] = &types.Message{
+		MessageID:      messageID,
+		Sender:         "sender@example.com",
+		Recipients:     []string{"testuser@localhost"},
+		RequestReceipt: true,
+		ResponseType:   types.ResponseTypeRequired,
+	}
+
+	req := httptest.NewRequest("DELETE", "/v1/inbox/Archive@localhost/"+messageID, strings.NewReader(`{"response": {"ok": true}}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer archive-key")
+	w := httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+
+	// Neither a receipt nor a reply reveals the archive copy to the sender
+	if mockProcessor.lastMessage != nil {
+		t.Errorf("Expected nothing sent on behalf of the archive agent, got %+v", mockProcessor.lastMessage)
+	}
+}
+
 // Test verifyAgentAccess function
 func TestVerifyAgentAccess_Success(t *testing.T) {
 	server := createTestServer()
@@ -1781,6 +4120,186 @@ func TestVerifyAgentAccess_EmptyAPIKey(t *testing.T) {
 	}
 }
 
+func TestHandleExportMessages_StreamsAllPages(t *testing.T) {
+	server := createTestServer()
+	mockStorage := server.storage.(*MockStorage)
+
+	// Enough messages to span more than one export page
+	total := exportPageSize + exportPageSize/2
+	for i := 0; i < total; i++ {
+		msg := &types.Message{
+			MessageID:  fmt.Sprintf("msg-%04d", i),
+			Sender:     "sender@example.com",
+			Recipients: []string{"subject@localhost"},
+		}
+		if i%2 == 1 {
+			// Messages sent by the subject are exported too
+			msg.Sender = "subject@localhost"
+			msg.Recipients = []string{"other@example.com"}
+		}
+		mockStorage.messages[msg.MessageID] = msg
+	}
+	mockStorage.messages["unrelated"] = &types.Message{
+		MessageID:  "unrelated",
+		Sender:     "a@example.com",
+		Recipients: []string{"b@example.com"},
+	}
+	mockStorage.statuses["msg-0000"] = &types.MessageStatus{MessageID: "msg-0000", Status: types.StatusDelivered}
+
+	// The address matches stored messages whatever its case
+	req := httptest.NewRequest("GET", "/v1/admin/export?address=Subject@LocalHost", nil)
+	w := httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d", http.StatusOK, w.Code)
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "application/x-ndjson" {
+		t.Errorf("Expected NDJSON content type, got %s", ct)
+	}
+
+	lines := bytes.Split(bytes.TrimSpace(w.Body.Bytes()), []byte("\n"))
+	if len(lines) != total+1 {
+		t.Fatalf("Expected %d lines, got %d", total+1, len(lines))
+	}
+
+	seen := make(map[string]bool)
+	for i, line := range lines[:total] {
+		var record exportRecord
+		if err := json.Unmarshal(line, &record); err != nil {
+			t.Fatalf("Line %d is not valid JSON: %v", i, err)
+		}
+		if record.Type != "message" || record.Message == nil {
+			t.Fatalf("Line %d: expected message record, got %s", i, line)
+		}
+		if seen[record.Message.MessageID] {
+			t.Errorf("Message %s exported twice", record.Message.MessageID)
+		}
+		seen[record.Message.MessageID] = true
+		if record.Message.MessageID == "msg-0000" && (record.Status == nil || record.Status.Status != types.StatusDelivered) {
+			t.Errorf("Expected status to be exported with msg-0000")
+		}
+	}
+	if seen["unrelated"] {
+		t.Errorf("Unrelated message should not be exported")
+	}
+
+	var end exportRecord
+	if err := json.Unmarshal(lines[total], &end); err != nil {
+		t.Fatalf("Trailer is not valid JSON: %v", err)
+	}
+	if end.Type != "end" || end.Count == nil || *end.Count != total {
+		t.Errorf("Expected end record with count %d, got %s", total, lines[total])
+	}
+}
+
+func TestHandleExportMessages_InvalidAddress(t *testing.T) {
+	server := createTestServer()
+
+	for _, query := range []string{"", "?address=", "?address=not-an-address"} {
+		req := httptest.NewRequest("GET", "/v1/admin/export"+query, nil)
+		w := httptest.NewRecorder()
+		server.router.ServeHTTP(w, req)
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%q: expected status %d, got %d", query, http.StatusBadRequest, w.Code)
+		}
+
+		var errorResponse types.ErrorResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &errorResponse); err != nil {
+			t.Fatalf("Failed to unmarshal error response: %v", err)
+		}
+		if errorResponse.Error.Code != "INVALID_ADDRESS" {
+			t.Errorf("Expected error code 'INVALID_ADDRESS', got %s", errorResponse.Error.Code)
+		}
+	}
+}
+
+func TestHandleResendMessage(t *testing.T) {
+	server := createTestServer()
+	mockStorage := server.storage.(*MockStorage)
+	processor := server.processor.(*MockMessageProcessor)
+
+	originalID, _ := uuid.GenerateV7()
+	original := &types.Message{
+		Version:        "1.0",
+		MessageID:      originalID,
+		IdempotencyKey: "01234567-89ab-4def-8123-456789abcdef",
+		Timestamp:      time.Now().Add(-time.Hour).UTC(),
+		Sender:         "sender@localhost",
+		Recipients:     []string{"recipient@example.com"},
+		Subject:        "Retry me",
+		Payload:        json.RawMessage(`{"n":1}`),
+		Signature:      &types.MessageSignature{Algorithm: "RS256", KeyID: "default", Value: "sig"},
+	}
+	mockStorage.messages[originalID] = original
+
+	req := httptest.NewRequest("POST", "/v1/admin/messages/"+originalID+"/resend", nil)
+	w := httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	var response map[string]interface{}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to unmarshal response: %v", err)
+	}
+
+	resent := processor.lastMessage
+	if resent == nil {
+		t.Fatal("Expected the message to be resubmitted")
+	}
+	if resent.MessageID == originalID || resent.IdempotencyKey == original.IdempotencyKey {
+		t.Errorf("Expected a fresh message ID and idempotency key, got %s and %s", resent.MessageID, resent.IdempotencyKey)
+	}
+	if resent.Sender != original.Sender || resent.Subject != original.Subject ||
+		len(resent.Recipients) != 1 || resent.Recipients[0] != original.Recipients[0] ||
+		string(resent.Payload) != string(original.Payload) {
+		t.Errorf("Expected the original content to be preserved, got %+v", resent)
+	}
+	if resent.Signature != nil {
+		t.Error("Expected the stale signature to be dropped")
+	}
+	if original.MessageID != originalID || original.Signature == nil {
+		t.Error("Expected the stored original to be left unchanged")
+	}
+	if response["original_message_id"] != originalID || response["message_id"] != resent.MessageID || response["status"] != "delivered" {
+		t.Errorf("Unexpected response: %v", response)
+	}
+}
+
+func TestHandleResendMessage_Errors(t *testing.T) {
+	server := createTestServer()
+	missingID, _ := uuid.GenerateV7()
+
+	tests := []struct {
+		id     string
+		status int
+		code   string
+	}{
+		{"not-a-uuid", http.StatusBadRequest, "INVALID_MESSAGE_ID"},
+		{missingID, http.StatusNotFound, "MESSAGE_NOT_FOUND"},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest("POST", "/v1/admin/messages/"+tt.id+"/resend", nil)
+		w := httptest.NewRecorder()
+		server.router.ServeHTTP(w, req)
+
+		if w.Code != tt.status {
+			t.Errorf("%s: expected status %d, got %d", tt.id, tt.status, w.Code)
+		}
+		var errorResponse types.ErrorResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &errorResponse); err != nil {
+			t.Fatalf("Failed to unmarshal error response: %v", err)
+		}
+		if errorResponse.Error.Code != tt.code {
+			t.Errorf("%s: expected error code %s, got %s", tt.id, tt.code, errorResponse.Error.Code)
+		}
+	}
+}
+
 func (m *MockStorage) StoreWorkflow(ctx context.Context, state *types.Workflow) error {
 	return nil
 }
@@ -1802,3 +4321,11 @@ func (m *MockStorage) UpdateWorkflowStatusAtomic(ctx context.Context, workflowID
 func (m *MockStorage) ListTimedOutWorkflows(ctx context.Context) ([]*types.Workflow, error) {
 	return nil, nil
 }
+
+func (m *MockStorage) CountActiveWorkflows(ctx context.Context) (int64, error) {
+	return 0, nil
+}
+
+func (m *MockStorage) ClaimMessagesForDelivery(ctx context.Context, workerID string, limit int, leaseDuration time.Duration) ([]string, error) {
+	return nil, nil
+}
@@ -0,0 +1,137 @@
+/*
+ * Copyright 2026 Cong Wang
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package server
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/amtp-protocol/agentry/internal/config"
+	"github.com/amtp-protocol/agentry/internal/logging"
+	"github.com/amtp-protocol/agentry/internal/processing"
+)
+
+// maxHeldRetryInterval bounds how long a held recipient waits for a retry
+// when no registration triggers one
+const maxHeldRetryInterval = 30 * time.Second
+
+// heldRecipientRetrier delivers to recipients held because no agent was
+// registered for them, whenever an agent registers and periodically, so a
+// hold that runs out is failed on time
+type heldRecipientRetrier struct {
+	processor *processing.MessageProcessor
+	hold      time.Duration
+	interval  time.Duration
+	logger    *logging.Logger
+
+	mu      sync.Mutex
+	started bool
+
+	notify   chan struct{}
+	stopOnce sync.Once
+	stop     chan struct{}
+	done     chan struct{}
+}
+
+// newHeldRecipientRetrier returns nil unless unknown recipients are queued
+func newHeldRecipientRetrier(processor *processing.MessageProcessor, cfg config.DeliveryConfig, logger *logging.Logger) *heldRecipientRetrier {
+	if cfg.UnknownRecipient != config.UnknownRecipientQueue || cfg.UnknownRecipientHold <= 0 {
+		return nil
+	}
+
+	interval := cfg.UnknownRecipientHold / 4
+	if interval > maxHeldRetryInterval {
+		interval = maxHeldRetryInterval
+	}
+	if interval < time.Second {
+		interval = time.Second
+	}
+
+	return &heldRecipientRetrier{
+		processor: processor,
+		hold:      cfg.UnknownRecipientHold,
+		interval:  interval,
+		logger:    logger,
+		notify:    make(chan struct{}, 1),
+		stop:      make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+}
+
+// Start picks up recipients held before a restart, then retries on every
+// interval and after every Notify
+func (hr *heldRecipientRetrier) Start(ctx context.Context) {
+	hr.mu.Lock()
+	hr.started = true
+	hr.mu.Unlock()
+
+	go func() {
+		defer close(hr.done)
+
+		if found, err := hr.processor.RecoverHeldRecipients(ctx); err != nil {
+			hr.logger.Error("Failed to recover held recipients", err)
+		} else if found > 0 {
+			hr.logger.Infof("Recovered %d messages with recipients waiting for their agent to register", found)
+		}
+
+		ticker := time.NewTicker(hr.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				hr.run(ctx)
+			case <-hr.notify:
+				hr.run(ctx)
+			case <-hr.stop:
+				return
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// Notify asks for a retry soon, typically because an agent registered. It
+// never blocks; notifications arriving during a retry are coalesced.
+func (hr *heldRecipientRetrier) Notify() {
+	select {
+	case hr.notify <- struct{}{}:
+	default:
+	}
+}
+
+// Stop ends retrying and waits for an in-progress retry to finish
+func (hr *heldRecipientRetrier) Stop() {
+	hr.stopOnce.Do(func() {
+		close(hr.stop)
+
+		hr.mu.Lock()
+		started := hr.started
+		hr.mu.Unlock()
+		if started {
+			<-hr.done
+		}
+	})
+}
+
+func (hr *heldRecipientRetrier) run(ctx context.Context) {
+	if err := hr.processor.RetryHeldRecipients(ctx, hr.hold); err != nil {
+		hr.logger.Warnf("Retrying held recipients failed: %v", err)
+	}
+}
@@ -0,0 +1,101 @@
+/*
+ * Copyright 2026 Cong Wang
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/amtp-protocol/agentry/internal/agents"
+	"github.com/amtp-protocol/agentry/internal/config"
+	"github.com/amtp-protocol/agentry/internal/logging"
+	"github.com/amtp-protocol/agentry/internal/processing"
+	"github.com/amtp-protocol/agentry/internal/types"
+)
+
+func TestHeldRecipientRetrier_Disabled(t *testing.T) {
+	for _, mode := range []string{"", config.UnknownRecipientInbox, config.UnknownRecipientReject, config.UnknownRecipientDeadLetter} {
+		cfg := config.DeliveryConfig{UnknownRecipient: mode, UnknownRecipientHold: time.Minute}
+		if hr := newHeldRecipientRetrier(nil, cfg, logging.NewNoopLogger()); hr != nil {
+			t.Errorf("Expected no retrier for mode %q", mode)
+		}
+	}
+}
+
+func TestHeldRecipientRetrier_RetriesOnRegistration(t *testing.T) {
+	server := createTestServerWithRealProcessor()
+	engine := processing.NewDeliveryEngine(server.discovery, server.agentRegistry, processing.DeliveryConfig{
+		MaxRetries:       1,
+		LocalDomain:      "localhost",
+		UnknownRecipient: processing.UnknownRecipientQueue,
+	})
+	processor := processing.NewMessageProcessor(server.discovery, engine, server.storage)
+	server.processor = processor
+
+	// The hold is long enough that only the registration triggers a retry
+	server.heldRetrier = newHeldRecipientRetrier(processor, config.DeliveryConfig{
+		UnknownRecipient:     config.UnknownRecipientQueue,
+		UnknownRecipientHold: time.Hour,
+	}, logging.NewNoopLogger())
+	server.heldRetrier.Start(context.Background())
+	defer server.heldRetrier.Stop()
+
+	message := &types.Message{
+		Version:        "1.0",
+		MessageID:      "01234567-89ab-7def-8123-456789abcdef",
+		IdempotencyKey: "01234567-89ab-4def-8123-456789abcdef",
+		Timestamp:      time.Now().UTC(),
+		Sender:         "sender@example.com",
+		Recipients:     []string{"late@localhost"},
+		Payload:        json.RawMessage(`{"hello":"world"}`),
+	}
+	if _, err := processor.ProcessMessage(context.Background(), message, processing.ProcessingOptions{ImmediatePath: true}); err != nil {
+		t.Fatalf("ProcessMessage failed: %v", err)
+	}
+
+	body, _ := json.Marshal(agents.LocalAgent{Address: "late", DeliveryMode: "pull"})
+	req := httptest.NewRequest("POST", "/v1/admin/agents", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusCreated, w.Code, w.Body.String())
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		status, err := server.storage.GetStatus(context.Background(), message.MessageID)
+		if err != nil {
+			t.Fatalf("Failed to get status: %v", err)
+		}
+		if status.Status == types.StatusDelivered {
+			if !status.Recipients[0].InboxDelivered {
+				t.Error("Expected the held message in the new agent's inbox")
+			}
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected the held message to be delivered after registration, got %s", status.Status)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
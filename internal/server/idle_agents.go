@@ -0,0 +1,196 @@
+/*
+ * Copyright 2026 Cong Wang
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package server
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/amtp-protocol/agentry/internal/agents"
+	"github.com/amtp-protocol/agentry/internal/config"
+	"github.com/amtp-protocol/agentry/internal/logging"
+	"github.com/amtp-protocol/agentry/internal/storage"
+)
+
+// idleAgent is an agent that has neither polled its inbox nor received a
+// delivery since the idle threshold
+type idleAgent struct {
+	Address      string    `json:"address"`
+	DeliveryMode string    `json:"delivery_mode"`
+	CreatedAt    time.Time `json:"created_at"`
+	LastActive   time.Time `json:"last_active"` // registration time if never active
+	IdleFor      string    `json:"idle_for"`
+}
+
+// findIdleAgents lists agents last active before now minus idleAfter, the
+// longest idle first. Agents with messages waiting in their inbox are never
+// idle, and neither is the catch-all agent, which stands in for agents that
+// are not registered rather than being one.
+func findIdleAgents(ctx context.Context, registry agents.AgentRegistry, st storage.Storage, idleAfter time.Duration, now time.Time) ([]idleAgent, error) {
+	cutoff := now.Add(-idleAfter)
+	idle := make([]idleAgent, 0)
+	for address, agent := range registry.GetAllAgents(ctx) {
+		if strings.HasPrefix(address, agents.CatchAllAgentName+"@") {
+			continue
+		}
+		lastActive := agent.LastAccess
+		if lastActive.IsZero() {
+			lastActive = agent.CreatedAt
+		}
+		if lastActive.After(cutoff) {
+			continue
+		}
+
+		empty, err := inboxEmpty(ctx, st, address)
+		if err != nil {
+			return nil, err
+		}
+		if !empty {
+			continue
+		}
+
+		idle = append(idle, idleAgent{
+			Address:      address,
+			DeliveryMode: agent.DeliveryMode,
+			CreatedAt:    agent.CreatedAt,
+			LastActive:   lastActive,
+			IdleFor:      now.Sub(lastActive).Truncate(time.Second).String(),
+		})
+	}
+
+	sort.Slice(idle, func(i, j int) bool {
+		return idle[i].LastActive.Before(idle[j].LastActive)
+	})
+	return idle, nil
+}
+
+// inboxEmpty reports whether address has no unacknowledged inbox messages
+func inboxEmpty(ctx context.Context, st storage.Storage, address string) (bool, error) {
+	messages, _, err := st.ListInboxMessages(ctx, address, "", 1)
+	if err != nil {
+		return false, fmt.Errorf("failed to check the inbox of %s: %w", address, err)
+	}
+	return len(messages) == 0, nil
+}
+
+// idleAgentPruner periodically finds idle agents and, depending on the
+// configured action, logs or unregisters them
+type idleAgentPruner struct {
+	registry   agents.AgentRegistry
+	storage    storage.Storage
+	idleAfter  time.Duration
+	unregister bool
+	interval   time.Duration
+	logger     *logging.Logger
+
+	mu      sync.Mutex
+	started bool
+
+	stopOnce sync.Once
+	stop     chan struct{}
+	done     chan struct{}
+}
+
+// newIdleAgentPruner returns nil when the idle agent action is off
+func newIdleAgentPruner(registry agents.AgentRegistry, st storage.Storage, cfg config.AgentsConfig, logger *logging.Logger) *idleAgentPruner {
+	if cfg.IdleAction != config.IdleActionFlag && cfg.IdleAction != config.IdleActionUnregister {
+		return nil
+	}
+	return &idleAgentPruner{
+		registry:   registry,
+		storage:    st,
+		idleAfter:  cfg.IdleAfter,
+		unregister: cfg.IdleAction == config.IdleActionUnregister,
+		interval:   cfg.IdleCheckInterval,
+		logger:     logger,
+		stop:       make(chan struct{}),
+		done:       make(chan struct{}),
+	}
+}
+
+// Start checks on every interval, the first time one interval after start
+func (ip *idleAgentPruner) Start(ctx context.Context) {
+	ip.mu.Lock()
+	ip.started = true
+	ip.mu.Unlock()
+
+	go func() {
+		defer close(ip.done)
+
+		ticker := time.NewTicker(ip.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				ip.run(ctx)
+			case <-ip.stop:
+				return
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// Stop ends the checks and waits for an in-progress check to finish
+func (ip *idleAgentPruner) Stop() {
+	ip.stopOnce.Do(func() {
+		close(ip.stop)
+
+		ip.mu.Lock()
+		started := ip.started
+		ip.mu.Unlock()
+		if started {
+			<-ip.done
+		}
+	})
+}
+
+func (ip *idleAgentPruner) run(ctx context.Context) {
+	idle, err := findIdleAgents(ctx, ip.registry, ip.storage, ip.idleAfter, time.Now().UTC())
+	if err != nil {
+		ip.logger.Error("Idle agent check failed", err)
+		return
+	}
+
+	for _, agent := range idle {
+		if !ip.unregister {
+			ip.logger.Warnf("Agent %s has been idle for %s", agent.Address, agent.IdleFor)
+			continue
+		}
+
+		// A message may have arrived since the agent was found idle
+		if empty, err := inboxEmpty(ctx, ip.storage, agent.Address); err != nil || !empty {
+			continue
+		}
+		// The registry takes agent names; local addresses only differ by domain
+		name := agent.Address
+		if i := strings.LastIndex(name, "@"); i >= 0 {
+			name = name[:i]
+		}
+		if err := ip.registry.UnregisterAgent(ctx, name); err != nil {
+			ip.logger.Warnf("Failed to unregister idle agent %s: %v", agent.Address, err)
+			continue
+		}
+		ip.logger.Warnf("Unregistered agent %s after being idle for %s", agent.Address, agent.IdleFor)
+	}
+}
@@ -0,0 +1,176 @@
+/*
+ * Copyright 2026 Cong Wang
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/amtp-protocol/agentry/internal/agents"
+	"github.com/amtp-protocol/agentry/internal/config"
+	"github.com/amtp-protocol/agentry/internal/logging"
+	"github.com/amtp-protocol/agentry/internal/storage"
+)
+
+// registerAgentActiveAt registers a pull agent, or a push agent when given
+// a target, last active at lastAccess
+func registerAgentActiveAt(t *testing.T, registry agents.AgentRegistry, st storage.Storage, name, target string, lastAccess time.Time) {
+	t.Helper()
+	ctx := context.Background()
+	agent := &agents.LocalAgent{Address: name, DeliveryMode: "pull"}
+	if target != "" {
+		agent.DeliveryMode = "push"
+		agent.PushTarget = target
+	}
+	if err := registry.RegisterAgent(ctx, agent); err != nil {
+		t.Fatalf("Failed to register %s: %v", name, err)
+	}
+
+	stored, err := st.GetAgent(ctx, agent.Address)
+	if err != nil {
+		t.Fatalf("Failed to get %s: %v", agent.Address, err)
+	}
+	stored.LastAccess = lastAccess
+	if err := st.UpdateAgent(ctx, stored); err != nil {
+		t.Fatalf("Failed to update %s: %v", agent.Address, err)
+	}
+}
+
+// newIdleAgentsFixture registers agents of which stale@localhost and
+// hook@localhost are idle for a day-long threshold
+func newIdleAgentsFixture(t *testing.T) (agents.AgentRegistry, storage.Storage) {
+	t.Helper()
+	st := storage.NewMemoryStorage(storage.MemoryStorageConfig{})
+	registry := agents.NewRegistry(agents.RegistryConfig{LocalDomain: "localhost", APIKeySalt: "test-salt"}, st)
+
+	now := time.Now().UTC()
+	registerAgentActiveAt(t, registry, st, "agent", "", now.Add(-72*time.Hour))
+	registerAgentActiveAt(t, registry, st, "stale", "", now.Add(-48*time.Hour))
+	registerAgentActiveAt(t, registry, st, "fresh", "", now.Add(-time.Hour))
+	registerAgentActiveAt(t, registry, st, "hook", "http://example.com/webhook", now.Add(-96*time.Hour))
+	registerAgentActiveAt(t, registry, st, agents.CatchAllAgentName, "http://example.com/fallback", now.Add(-96*time.Hour))
+
+	// agent@localhost still has a message to pick up
+	storeInboxMessage(t, st, "msg-1", now.Add(-72*time.Hour))
+	return registry, st
+}
+
+func TestFindIdleAgents(t *testing.T) {
+	registry, st := newIdleAgentsFixture(t)
+
+	idle, err := findIdleAgents(context.Background(), registry, st, 24*time.Hour, time.Now().UTC())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(idle) != 2 || idle[0].Address != "hook@localhost" || idle[1].Address != "stale@localhost" {
+		t.Fatalf("Expected hook and stale to be idle, longest first, got %+v", idle)
+	}
+	if idle[0].DeliveryMode != "push" || !strings.HasPrefix(idle[0].IdleFor, "96h") {
+		t.Errorf("Unexpected idle agent: %+v", idle[0])
+	}
+}
+
+func TestIdleAgentPruner(t *testing.T) {
+	ctx := context.Background()
+	cfg := config.AgentsConfig{IdleAfter: 24 * time.Hour, IdleAction: config.IdleActionFlag, IdleCheckInterval: time.Hour}
+
+	for _, action := range []string{"", config.IdleActionOff} {
+		cfg := cfg
+		cfg.IdleAction = action
+		if p := newIdleAgentPruner(nil, nil, cfg, logging.NewNoopLogger()); p != nil {
+			t.Errorf("Expected no pruner for action %q", action)
+		}
+	}
+
+	t.Run("flag keeps agents", func(t *testing.T) {
+		registry, st := newIdleAgentsFixture(t)
+		newIdleAgentPruner(registry, st, cfg, logging.NewNoopLogger()).run(ctx)
+		if n := len(registry.GetAllAgents(ctx)); n != 5 {
+			t.Errorf("Expected every agent to stay registered, got %d", n)
+		}
+	})
+
+	t.Run("unregister removes idle agents", func(t *testing.T) {
+		registry, st := newIdleAgentsFixture(t)
+		cfg := cfg
+		cfg.IdleAction = config.IdleActionUnregister
+		newIdleAgentPruner(registry, st, cfg, logging.NewNoopLogger()).run(ctx)
+
+		remaining := registry.GetAllAgents(ctx)
+		for _, address := range []string{"agent@localhost", "fresh@localhost", "*@localhost"} {
+			if _, ok := remaining[address]; !ok {
+				t.Errorf("Expected %s to stay registered", address)
+			}
+		}
+		if len(remaining) != 3 {
+			t.Errorf("Expected the idle agents to be unregistered, got %d agents", len(remaining))
+		}
+	})
+}
+
+func TestHandleListIdleAgents(t *testing.T) {
+	server := createTestServer()
+	registerAgentActiveAt(t, server.agentRegistry, server.storage, "stale", "", time.Now().UTC().Add(-48*time.Hour))
+	registerAgentActiveAt(t, server.agentRegistry, server.storage, "fresh", "", time.Now().UTC())
+
+	get := func(query string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		server.router.ServeHTTP(w, httptest.NewRequest("GET", "/v1/admin/agents/idle"+query, nil))
+		return w
+	}
+
+	// Without a configured threshold one must be given
+	if w := get(""); w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "INVALID_IDLE_AFTER") {
+		t.Errorf("Expected INVALID_IDLE_AFTER, got %d: %s", w.Code, w.Body.String())
+	}
+	if w := get("?idle_after=soon"); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status %d for an invalid duration, got %d", http.StatusBadRequest, w.Code)
+	}
+
+	server.config.Agents.IdleAfter = 24 * time.Hour
+	for _, tt := range []struct {
+		query string
+		count int
+	}{
+		{"", 1},
+		{"?idle_after=72h", 0},
+	} {
+		w := get(tt.query)
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+		}
+		var response struct {
+			Agents []idleAgent `json:"agents"`
+			Count  int         `json:"count"`
+			Action string      `json:"action"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+			t.Fatalf("Failed to unmarshal response: %v", err)
+		}
+		if response.Count != tt.count || len(response.Agents) != tt.count || response.Action != config.IdleActionOff {
+			t.Errorf("%q: unexpected response %s", tt.query, w.Body.String())
+		}
+		if tt.count == 1 && response.Agents[0].Address != "stale@localhost" {
+			t.Errorf("Expected stale@localhost to be idle, got %+v", response.Agents)
+		}
+	}
+}
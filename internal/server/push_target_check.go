@@ -0,0 +1,150 @@
+/*
+ * Copyright 2026 Cong Wang
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package server
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/amtp-protocol/agentry/internal/config"
+	"github.com/amtp-protocol/agentry/internal/netguard"
+)
+
+// pushTargetCheck is the outcome of probing one push target. Any HTTP
+// response counts as reachable; only the status code hints at a wrong path.
+type pushTargetCheck struct {
+	Target     string `json:"target"`
+	Reachable  bool   `json:"reachable"`
+	StatusCode int    `json:"status_code,omitempty"`
+	Error      string `json:"error,omitempty"`
+}
+
+// pushTargetProber checks that push targets answer HTTP at registration.
+// Probes go through the same guard as push deliveries, so the admin API
+// cannot be used to scan the gateway's network.
+type pushTargetProber struct {
+	reject  bool
+	timeout time.Duration
+	client  *http.Client
+}
+
+// newPushTargetProber returns nil when the check is off
+func newPushTargetProber(cfg config.AgentsConfig) *pushTargetProber {
+	if cfg.PushTargetCheck != config.PushTargetCheckWarn && cfg.PushTargetCheck != config.PushTargetCheckReject {
+		return nil
+	}
+
+	timeout := cfg.PushTargetCheckTimeout
+	if timeout <= 0 {
+		timeout = 5 * time.Second
+	}
+	guard := netguard.New(cfg.PushTargetAllowlist)
+
+	return &pushTargetProber{
+		reject:  cfg.PushTargetCheck == config.PushTargetCheckReject,
+		timeout: timeout,
+		client: &http.Client{
+			Transport: &http.Transport{
+				// No proxy: the guard must see the address actually dialed
+				Proxy:               nil,
+				DialContext:         guard.DialContext(&net.Dialer{Timeout: timeout}),
+				TLSHandshakeTimeout: timeout,
+				DisableKeepAlives:   true,
+			},
+			// A redirect already proves the target answers, and following it
+			// could lead the probe somewhere the guard did not approve
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+	}
+}
+
+// Check probes every target concurrently and returns the results in order
+func (p *pushTargetProber) Check(ctx context.Context, targets []string) []pushTargetCheck {
+	checks := make([]pushTargetCheck, len(targets))
+	var wg sync.WaitGroup
+	for i, target := range targets {
+		wg.Add(1)
+		go func(i int, target string) {
+			defer wg.Done()
+			checks[i] = p.probe(ctx, target)
+		}(i, target)
+	}
+	wg.Wait()
+	return checks
+}
+
+// probe sends HEAD, falling back to OPTIONS for targets that only accept
+// other methods
+func (p *pushTargetProber) probe(ctx context.Context, target string) pushTargetCheck {
+	check := pushTargetCheck{Target: target}
+
+	u, err := url.Parse(target)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" {
+		check.Error = "push target must be an absolute http or https URL"
+		return check
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+
+	status, err := p.request(ctx, http.MethodHead, target)
+	if err == nil && (status == http.StatusMethodNotAllowed || status == http.StatusNotImplemented) {
+		status, err = p.request(ctx, http.MethodOptions, target)
+	}
+	if err != nil {
+		if errors.Is(err, netguard.ErrBlocked) {
+			check.Error = "push target resolves to an internal address; add it to the push target allowlist to probe it"
+		} else {
+			check.Error = err.Error()
+		}
+		return check
+	}
+
+	check.Reachable = true
+	check.StatusCode = status
+	return check
+}
+
+func (p *pushTargetProber) request(ctx context.Context, method, target string) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, method, target, nil)
+	if err != nil {
+		return 0, err
+	}
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	resp.Body.Close()
+	return resp.StatusCode, nil
+}
+
+// allReachable reports whether every probed target answered
+func allReachable(checks []pushTargetCheck) bool {
+	for _, check := range checks {
+		if !check.Reachable {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,240 @@
+/*
+ * Copyright 2026 Cong Wang
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/amtp-protocol/agentry/internal/agents"
+	"github.com/amtp-protocol/agentry/internal/config"
+)
+
+// newWebhookServer answers HEAD with headStatus and records each method seen
+func newWebhookServer(t *testing.T, headStatus int) (*httptest.Server, *[]string) {
+	t.Helper()
+	var methods []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		methods = append(methods, r.Method)
+		if r.Method == http.MethodHead {
+			w.WriteHeader(headStatus)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &methods
+}
+
+// closedPortURL returns a loopback URL nothing is listening on
+func closedPortURL(t *testing.T) string {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	addr := listener.Addr().String()
+	listener.Close()
+	return "http://" + addr + "/webhook"
+}
+
+func TestNewPushTargetProber_Off(t *testing.T) {
+	for _, mode := range []string{"", config.PushTargetCheckOff} {
+		if p := newPushTargetProber(config.AgentsConfig{PushTargetCheck: mode}); p != nil {
+			t.Errorf("Expected no prober for mode %q", mode)
+		}
+	}
+}
+
+func TestPushTargetProber_Check(t *testing.T) {
+	webhook, methods := newWebhookServer(t, http.StatusMethodNotAllowed)
+	webhookURL := webhook.URL + "/webhook"
+
+	tests := []struct {
+		name          string
+		allowlist     []string
+		target        string
+		reachable     bool
+		status        int
+		errorContains string
+	}{
+		{
+			name:          "loopback address is blocked",
+			target:        webhookURL,
+			errorContains: "internal address",
+		},
+		{
+			name:          "host name resolving to loopback is blocked",
+			target:        strings.Replace(webhookURL, "127.0.0.1", "localhost", 1),
+			errorContains: "internal address",
+		},
+		{
+			name:      "allowlisted network",
+			allowlist: []string{"127.0.0.0/8"},
+			target:    webhookURL,
+			reachable: true,
+			status:    http.StatusNoContent,
+		},
+		{
+			name:      "allowlisted address",
+			allowlist: []string{"127.0.0.1"},
+			target:    webhookURL,
+			reachable: true,
+			status:    http.StatusNoContent,
+		},
+		{
+			name:      "allowlisted host name",
+			allowlist: []string{"LocalHost"},
+			target:    strings.Replace(webhookURL, "127.0.0.1", "localhost", 1),
+			reachable: true,
+			status:    http.StatusNoContent,
+		},
+		{
+			name:      "nothing listening",
+			allowlist: []string{"127.0.0.1/32"},
+			target:    closedPortURL(t),
+		},
+		{
+			name:          "not an http URL",
+			target:        "ftp://example.com/webhook",
+			errorContains: "http or https URL",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := newPushTargetProber(config.AgentsConfig{
+				PushTargetCheck:        config.PushTargetCheckWarn,
+				PushTargetCheckTimeout: 2 * time.Second,
+				PushTargetAllowlist:    tt.allowlist,
+			})
+			checks := p.Check(context.Background(), []string{tt.target})
+			if len(checks) != 1 {
+				t.Fatalf("Expected one check, got %d", len(checks))
+			}
+			check := checks[0]
+			if check.Target != tt.target || check.Reachable != tt.reachable || check.StatusCode != tt.status {
+				t.Errorf("Unexpected check: %+v", check)
+			}
+			if !tt.reachable && check.Error == "" {
+				t.Error("Expected an error for an unreachable target")
+			}
+			if !strings.Contains(check.Error, tt.errorContains) {
+				t.Errorf("Expected error containing %q, got %q", tt.errorContains, check.Error)
+			}
+		})
+	}
+
+	// HEAD was refused, so the reachable probes fell back to OPTIONS
+	for i, method := range *methods {
+		expected := http.MethodHead
+		if i%2 == 1 {
+			expected = http.MethodOptions
+		}
+		if method != expected {
+			t.Errorf("Expected request %d to be %s, got %s", i, expected, method)
+		}
+	}
+}
+
+func registerPushAgent(t *testing.T, server *Server, targets ...string) *httptest.ResponseRecorder {
+	t.Helper()
+	body, err := json.Marshal(agents.LocalAgent{
+		Address:      "hook",
+		DeliveryMode: "push",
+		PushTarget:   targets[0],
+		PushTargets:  targets[1:],
+	})
+	if err != nil {
+		t.Fatalf("Failed to marshal agent: %v", err)
+	}
+	req := httptest.NewRequest("POST", "/v1/admin/agents", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+	return w
+}
+
+func TestHandleRegisterAgent_PushTargetCheck(t *testing.T) {
+	webhook, _ := newWebhookServer(t, http.StatusOK)
+	unreachable := closedPortURL(t)
+
+	agentsConfig := config.AgentsConfig{
+		PushTargetCheck:        config.PushTargetCheckWarn,
+		PushTargetCheckTimeout: 2 * time.Second,
+		PushTargetAllowlist:    []string{"127.0.0.1"},
+	}
+
+	t.Run("warn registers and reports", func(t *testing.T) {
+		server := createTestServer()
+		server.pushProbe = newPushTargetProber(agentsConfig)
+
+		w := registerPushAgent(t, server, webhook.URL, unreachable)
+		if w.Code != http.StatusCreated {
+			t.Fatalf("Expected status %d, got %d: %s", http.StatusCreated, w.Code, w.Body.String())
+		}
+		var response struct {
+			Checks []pushTargetCheck `json:"push_target_checks"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+			t.Fatalf("Failed to unmarshal response: %v", err)
+		}
+		if len(response.Checks) != 2 || !response.Checks[0].Reachable || response.Checks[0].StatusCode != http.StatusOK || response.Checks[1].Reachable {
+			t.Errorf("Unexpected push target checks: %+v", response.Checks)
+		}
+	})
+
+	t.Run("reject refuses unreachable targets", func(t *testing.T) {
+		server := createTestServer()
+		rejectConfig := agentsConfig
+		rejectConfig.PushTargetCheck = config.PushTargetCheckReject
+		server.pushProbe = newPushTargetProber(rejectConfig)
+
+		w := registerPushAgent(t, server, webhook.URL, unreachable)
+		if w.Code != http.StatusBadRequest {
+			t.Fatalf("Expected status %d, got %d", http.StatusBadRequest, w.Code)
+		}
+		if !strings.Contains(w.Body.String(), "PUSH_TARGET_UNREACHABLE") {
+			t.Errorf("Expected PUSH_TARGET_UNREACHABLE, got %s", w.Body.String())
+		}
+		if _, err := server.agentRegistry.GetAgent(context.Background(), "hook@localhost"); err == nil {
+			t.Error("Expected the agent not to be registered")
+		}
+
+		if w := registerPushAgent(t, server, webhook.URL); w.Code != http.StatusCreated {
+			t.Errorf("Expected a reachable target to be accepted, got %d: %s", w.Code, w.Body.String())
+		}
+	})
+
+	t.Run("off skips the probe", func(t *testing.T) {
+		server := createTestServer()
+		w := registerPushAgent(t, server, unreachable)
+		if w.Code != http.StatusCreated {
+			t.Fatalf("Expected status %d, got %d", http.StatusCreated, w.Code)
+		}
+		if strings.Contains(w.Body.String(), "push_target_checks") {
+			t.Errorf("Expected no push target checks, got %s", w.Body.String())
+		}
+	})
+}
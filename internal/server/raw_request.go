@@ -0,0 +1,97 @@
+/*
+ * Copyright 2026 Cong Wang
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package server
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"time"
+	"unicode/utf8"
+
+	"github.com/amtp-protocol/agentry/internal/config"
+	"github.com/amtp-protocol/agentry/internal/logging"
+	"github.com/amtp-protocol/agentry/internal/types"
+	"github.com/gin-gonic/gin"
+)
+
+// rawRequestRecorder keeps send requests as clients sent them, redacted with
+// the logging rules, so protocol problems can be reproduced from the bytes
+// that caused them
+type rawRequestRecorder struct {
+	maxSize  int64
+	headers  []string
+	redactor *logging.Redactor
+}
+
+// newRawRequestRecorder returns nil when raw requests are not stored
+func newRawRequestRecorder(cfg *config.Config) *rawRequestRecorder {
+	if !cfg.Message.StoreRawRequest {
+		return nil
+	}
+	return &rawRequestRecorder{
+		maxSize:  cfg.Message.RawRequestMaxSize,
+		headers:  cfg.Message.RawRequestHeaders,
+		redactor: logging.NewRedactor(redactionConfig(cfg)),
+	}
+}
+
+// readBody reads the request body and puts it back for binding
+func (r *rawRequestRecorder) readBody(c *gin.Context) ([]byte, error) {
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		return nil, err
+	}
+	c.Request.Body = io.NopCloser(bytes.NewReader(body))
+	return body, nil
+}
+
+// build returns the raw request to keep for a message. The body is redacted
+// before it is cut, since redaction needs the whole document.
+func (r *rawRequestRecorder) build(messageID string, header http.Header, body []byte, receivedAt time.Time) *types.RawRequest {
+	selected := make(http.Header)
+	for _, name := range r.headers {
+		if values := header.Values(name); len(values) > 0 {
+			selected[http.CanonicalHeaderKey(name)] = values
+		}
+	}
+
+	raw := &types.RawRequest{
+		MessageID:  messageID,
+		ReceivedAt: receivedAt,
+		Headers:    r.redactor.Headers(selected),
+		Size:       int64(len(body)),
+	}
+
+	redacted, ok := r.redactor.JSON(body)
+	if !ok {
+		// Not JSON, so the field rules cannot be applied
+		raw.Body = logging.RedactedValue
+		return raw
+	}
+	if int64(len(redacted)) > r.maxSize {
+		// Cut at a character boundary, not halfway through one
+		cut := int(r.maxSize)
+		for cut > 0 && !utf8.RuneStart(redacted[cut]) {
+			cut--
+		}
+		redacted = redacted[:cut]
+		raw.Truncated = true
+	}
+	raw.Body = string(redacted)
+	return raw
+}
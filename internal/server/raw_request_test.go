@@ -0,0 +1,144 @@
+/*
+ * Copyright 2026 Cong Wang
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/amtp-protocol/agentry/internal/config"
+	"github.com/amtp-protocol/agentry/internal/logging"
+	"github.com/amtp-protocol/agentry/internal/types"
+)
+
+func TestNewRawRequestRecorder_Off(t *testing.T) {
+	if r := newRawRequestRecorder(&config.Config{}); r != nil {
+		t.Error("Expected no recorder when raw requests are not stored")
+	}
+}
+
+func TestRawRequestRecorder_Build(t *testing.T) {
+	cfg := &config.Config{
+		Message: config.MessageConfig{
+			StoreRawRequest:   true,
+			RawRequestMaxSize: 1024,
+			RawRequestHeaders: []string{"user-agent", "X-API-Key", "X-Missing"},
+		},
+		Logging: config.LoggingConfig{
+			Redaction: config.RedactionConfig{Fields: []string{"payload.ssn"}},
+		},
+	}
+	header := http.Header{}
+	header.Set("User-Agent", "client/1.0")
+	header.Set("X-API-Key", "secret")
+	header.Set("Cookie", "session=1")
+	receivedAt := time.Now().UTC()
+
+	body := []byte(`{"sender":"a@localhost","payload":{"ssn":"123-45-6789"}}`)
+	raw := newRawRequestRecorder(cfg).build("id", header, body, receivedAt)
+
+	if raw.MessageID != "id" || !raw.ReceivedAt.Equal(receivedAt) || raw.Size != int64(len(body)) || raw.Truncated {
+		t.Errorf("Unexpected raw request: %+v", raw)
+	}
+	expectedHeaders := map[string]string{"User-Agent": "client/1.0", "X-Api-Key": logging.RedactedValue}
+	if len(raw.Headers) != len(expectedHeaders) {
+		t.Errorf("Expected headers %v, got %v", expectedHeaders, raw.Headers)
+	}
+	for name, value := range expectedHeaders {
+		if raw.Headers[name] != value {
+			t.Errorf("Expected header %s to be %q, got %q", name, value, raw.Headers[name])
+		}
+	}
+	if strings.Contains(raw.Body, "123-45-6789") || !strings.Contains(raw.Body, logging.RedactedValue) {
+		t.Errorf("Expected the configured field to be redacted, got %s", raw.Body)
+	}
+
+	// Cut at the size limit without splitting a character
+	cfg.Message.RawRequestMaxSize = 14
+	cfg.Logging.Redaction.Fields = nil
+	raw = newRawRequestRecorder(cfg).build("id", header, []byte(`{"subject":"héllo"}`), receivedAt)
+	if raw.Body != `{"subject":"h` || !raw.Truncated || raw.Size != 20 {
+		t.Errorf("Expected a truncated body, got %q (truncated %v, size %d)", raw.Body, raw.Truncated, raw.Size)
+	}
+}
+
+func TestHandleGetRawRequest(t *testing.T) {
+	server := createTestServer()
+	body := `{"sender": "test@example.com",  "recipients": ["recipient@test.com"], "payload": {}}`
+
+	send := func() types.SendMessageResponse {
+		t.Helper()
+		req := httptest.NewRequest("POST", "/v1/messages", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("User-Agent", "client/1.0")
+		w := httptest.NewRecorder()
+		server.router.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+		}
+		var response types.SendMessageResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+			t.Fatalf("Failed to unmarshal response: %v", err)
+		}
+		return response
+	}
+	getRaw := func(messageID string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/v1/admin/messages/"+messageID+"/raw", nil)
+		w := httptest.NewRecorder()
+		server.router.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("off", func(t *testing.T) {
+		w := getRaw(send().MessageID)
+		if w.Code != http.StatusNotFound || !strings.Contains(w.Body.String(), "store_raw_request") {
+			t.Errorf("Expected RAW_REQUEST_NOT_FOUND with a hint, got %d: %s", w.Code, w.Body.String())
+		}
+	})
+
+	t.Run("on", func(t *testing.T) {
+		server.config.Message.StoreRawRequest = true
+		server.config.Message.RawRequestMaxSize = 1024
+		server.config.Message.RawRequestHeaders = []string{"User-Agent"}
+		server.rawRequests = newRawRequestRecorder(server.config)
+
+		w := getRaw(send().MessageID)
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+		}
+		var raw types.RawRequest
+		if err := json.Unmarshal(w.Body.Bytes(), &raw); err != nil {
+			t.Fatalf("Failed to unmarshal raw request: %v", err)
+		}
+		if raw.Body != body {
+			t.Errorf("Expected the body byte for byte, got %q", raw.Body)
+		}
+		if len(raw.Headers) != 1 || raw.Headers["User-Agent"] != "client/1.0" {
+			t.Errorf("Expected only the selected headers, got %v", raw.Headers)
+		}
+	})
+
+	t.Run("invalid ID", func(t *testing.T) {
+		if w := getRaw("not-an-id"); w.Code != http.StatusBadRequest {
+			t.Errorf("Expected status %d, got %d", http.StatusBadRequest, w.Code)
+		}
+	})
+}
@@ -0,0 +1,219 @@
+/*
+ * Copyright 2026 Cong Wang
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package server
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/amtp-protocol/agentry/internal/logging"
+	"github.com/amtp-protocol/agentry/internal/storage"
+	"github.com/amtp-protocol/agentry/internal/types"
+)
+
+// reconcileBatchSize is how many message statuses are read at a time
+const reconcileBatchSize = 500
+
+// maxReportedCorrections bounds the corrections listed in a report; the
+// count covers all of them
+const maxReportedCorrections = 100
+
+// statusCorrection is one message whose aggregate status was fixed
+type statusCorrection struct {
+	MessageID string               `json:"message_id"`
+	From      types.DeliveryStatus `json:"from"`
+	To        types.DeliveryStatus `json:"to"`
+}
+
+// reconcileReport summarizes a reconciliation pass
+type reconcileReport struct {
+	Checked     int                `json:"checked"`
+	Corrected   int                `json:"corrected"`
+	Corrections []statusCorrection `json:"corrections,omitempty"`
+}
+
+// withoutRecipient returns recipients without the status of address, such
+// as the archive copy's, which senders do not see and which does not count
+// towards the aggregate status
+func withoutRecipient(recipients []types.RecipientStatus, address string) []types.RecipientStatus {
+	if address == "" {
+		return recipients
+	}
+	kept := make([]types.RecipientStatus, 0, len(recipients))
+	for _, rs := range recipients {
+		if !strings.EqualFold(rs.Address, address) {
+			kept = append(kept, rs)
+		}
+	}
+	return kept
+}
+
+// reconciledStatus returns the aggregate status a message should have given
+// its recipients, other than the archive, and whether the stored status
+// disagrees. A message still in progress is left alone while its recipients
+// are, and a message without recipient statuses has nothing to reconcile
+// against.
+func reconciledStatus(status *types.MessageStatus, archive string) (types.DeliveryStatus, bool) {
+	recipients := withoutRecipient(status.Recipients, archive)
+	if len(recipients) == 0 {
+		return status.Status, false
+	}
+	want := types.AggregateStatus(recipients)
+	if want == types.StatusDelivering && !status.Status.IsFinal() {
+		return status.Status, false
+	}
+	return want, want != status.Status
+}
+
+// reconcileStatuses walks every message status and recomputes its aggregate
+// status from its recipient statuses, correcting those that drifted, for
+// example after a crash between recipient and message status writes. Each
+// correction is decided again inside the update, so a delivery finishing
+// meanwhile is not overwritten.
+func reconcileStatuses(ctx context.Context, st storage.Storage, archive string) (*reconcileReport, error) {
+	report := &reconcileReport{}
+	cursor := ""
+	for {
+		statuses, next, err := st.ListStatuses(ctx, cursor, reconcileBatchSize)
+		if err != nil {
+			return report, fmt.Errorf("failed to list message statuses: %w", err)
+		}
+
+		for _, status := range statuses {
+			report.Checked++
+			if _, drifted := reconciledStatus(status, archive); !drifted {
+				continue
+			}
+
+			var correction *statusCorrection
+			err := st.UpdateStatus(ctx, status.MessageID, func(current *types.MessageStatus) error {
+				want, drifted := reconciledStatus(current, archive)
+				if !drifted {
+					return nil
+				}
+				correction = &statusCorrection{MessageID: current.MessageID, From: current.Status, To: want}
+
+				now := time.Now().UTC()
+				current.Status = want
+				current.UpdatedAt = now
+				if want == types.StatusDelivered && current.DeliveredAt == nil {
+					current.DeliveredAt = &now
+				} else if want != types.StatusDelivered {
+					current.DeliveredAt = nil
+				}
+				return nil
+			})
+			if err != nil {
+				return report, fmt.Errorf("failed to correct status of message %s: %w", status.MessageID, err)
+			}
+			if correction != nil {
+				report.Corrected++
+				if len(report.Corrections) < maxReportedCorrections {
+					report.Corrections = append(report.Corrections, *correction)
+				}
+			}
+		}
+
+		if next == "" {
+			return report, nil
+		}
+		cursor = next
+	}
+}
+
+// statusReconciler runs reconcileStatuses periodically
+type statusReconciler struct {
+	storage  storage.Storage
+	interval time.Duration
+	archive  string
+	logger   *logging.Logger
+
+	mu      sync.Mutex
+	started bool
+
+	stopOnce sync.Once
+	stop     chan struct{}
+	done     chan struct{}
+}
+
+// newStatusReconciler returns nil when periodic reconciliation is off
+func newStatusReconciler(st storage.Storage, interval time.Duration, archive string, logger *logging.Logger) *statusReconciler {
+	if interval <= 0 {
+		return nil
+	}
+	return &statusReconciler{
+		storage:  st,
+		interval: interval,
+		archive:  archive,
+		logger:   logger,
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+}
+
+// Start reconciles on every interval, the first time one interval after start
+func (sr *statusReconciler) Start(ctx context.Context) {
+	sr.mu.Lock()
+	sr.started = true
+	sr.mu.Unlock()
+
+	go func() {
+		defer close(sr.done)
+
+		ticker := time.NewTicker(sr.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				sr.run(ctx)
+			case <-sr.stop:
+				return
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// Stop ends reconciliation and waits for an in-progress pass to finish
+func (sr *statusReconciler) Stop() {
+	sr.stopOnce.Do(func() {
+		close(sr.stop)
+
+		sr.mu.Lock()
+		started := sr.started
+		sr.mu.Unlock()
+		if started {
+			<-sr.done
+		}
+	})
+}
+
+func (sr *statusReconciler) run(ctx context.Context) {
+	report, err := reconcileStatuses(ctx, sr.storage, sr.archive)
+	if err != nil {
+		sr.logger.Error("Message status reconciliation failed", err)
+		return
+	}
+	if report.Corrected > 0 {
+		sr.logger.Warnf("Corrected the aggregate status of %d of %d messages", report.Corrected, report.Checked)
+	}
+}
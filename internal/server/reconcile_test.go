@@ -0,0 +1,152 @@
+/*
+ * Copyright 2026 Cong Wang
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/amtp-protocol/agentry/internal/logging"
+	"github.com/amtp-protocol/agentry/internal/storage"
+	"github.com/amtp-protocol/agentry/internal/types"
+)
+
+// storeDriftStatus stores a message status with the given aggregate and
+// recipient statuses
+func storeDriftStatus(t *testing.T, st storage.Storage, id string, aggregate types.DeliveryStatus, recipients ...types.DeliveryStatus) {
+	t.Helper()
+	status := &types.MessageStatus{MessageID: id, Status: aggregate}
+	for _, recipient := range recipients {
+		status.Recipients = append(status.Recipients, types.RecipientStatus{
+			Address: "agent@localhost",
+			Status:  recipient,
+		})
+	}
+	if err := st.StoreStatus(context.Background(), id, status); err != nil {
+		t.Fatalf("Failed to store status: %v", err)
+	}
+}
+
+func TestHandleReconcileStatuses(t *testing.T) {
+	server := createTestServerWithRealProcessor()
+	st := server.storage
+
+	storeDriftStatus(t, st, "m1", types.StatusDelivered, types.StatusDelivered, types.StatusFailed)
+	storeDriftStatus(t, st, "m2", types.StatusFailed, types.StatusDelivered, types.StatusDelivered)
+	storeDriftStatus(t, st, "m3", types.StatusDelivered, types.StatusDelivered, types.StatusQueued)
+	storeDriftStatus(t, st, "m4", types.StatusQueued, types.StatusDelivered, types.StatusQueued)
+	storeDriftStatus(t, st, "m5", types.StatusDelivered, types.StatusDelivered)
+	storeDriftStatus(t, st, "m6", types.StatusQueued)
+
+	req := httptest.NewRequest("POST", "/v1/admin/reconcile", nil)
+	w := httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	var response reconcileReport
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to unmarshal response: %v", err)
+	}
+	if response.Checked != 6 || response.Corrected != 3 {
+		t.Errorf("Expected 3 of 6 statuses corrected, got %d of %d", response.Corrected, response.Checked)
+	}
+
+	expected := map[string]types.DeliveryStatus{
+		"m1": types.StatusFailed,
+		"m2": types.StatusDelivered,
+		"m3": types.StatusDelivering,
+		"m4": types.StatusQueued,
+		"m5": types.StatusDelivered,
+		"m6": types.StatusQueued,
+	}
+	for id, want := range expected {
+		status, err := st.GetStatus(context.Background(), id)
+		if err != nil {
+			t.Fatalf("Failed to get status of %s: %v", id, err)
+		}
+		if status.Status != want {
+			t.Errorf("Expected %s to be %s, got %s", id, want, status.Status)
+		}
+		if (status.DeliveredAt != nil) != (id == "m2") {
+			t.Errorf("Unexpected delivered_at for %s: %v", id, status.DeliveredAt)
+		}
+	}
+
+	// A second pass finds nothing left to correct
+	w = httptest.NewRecorder()
+	server.router.ServeHTTP(w, httptest.NewRequest("POST", "/v1/admin/reconcile", nil))
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to unmarshal response: %v", err)
+	}
+	if response.Corrected != 0 {
+		t.Errorf("Expected no corrections on a second pass, got %+v", response.Corrections)
+	}
+}
+
+func TestStatusReconciler_Disabled(t *testing.T) {
+	st := storage.NewMemoryStorage(storage.MemoryStorageConfig{})
+	if sr := newStatusReconciler(st, 0, "", logging.NewNoopLogger()); sr != nil {
+		t.Error("Expected no reconciler without an interval")
+	}
+}
+
+func TestStatusReconciler_StartStop(t *testing.T) {
+	st := storage.NewMemoryStorage(storage.MemoryStorageConfig{})
+	storeDriftStatus(t, st, "m1", types.StatusDelivered, types.StatusFailed)
+
+	sr := newStatusReconciler(st, 10*time.Millisecond, "", logging.NewNoopLogger())
+	sr.Start(context.Background())
+
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		status, _ := st.GetStatus(context.Background(), "m1")
+		if status.Status == types.StatusFailed {
+			break
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	sr.Stop()
+	sr.Stop()
+
+	if status, _ := st.GetStatus(context.Background(), "m1"); status.Status != types.StatusFailed {
+		t.Errorf("Expected the periodic pass to correct the status, got %s", status.Status)
+	}
+}
+
+func TestReconciledStatus_IgnoresArchive(t *testing.T) {
+	status := &types.MessageStatus{
+		MessageID: "m1",
+		Status:    types.StatusDelivered,
+		Recipients: []types.RecipientStatus{
+			{Address: "agent@localhost", Status: types.StatusDelivered},
+			{Address: "archive@localhost", Status: types.StatusFailed},
+		},
+	}
+
+	if want, drifted := reconciledStatus(status, "Archive@localhost"); drifted {
+		t.Errorf("Expected the failed archive copy to be ignored, got %s", want)
+	}
+	if want, drifted := reconciledStatus(status, ""); !drifted || want != types.StatusFailed {
+		t.Errorf("Expected a failed recipient without an archive to fail the message, got %s", want)
+	}
+}
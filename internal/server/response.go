@@ -17,9 +17,12 @@
 package server
 
 import (
+	"encoding/binary"
+	"encoding/json"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/ugorji/go/codec"
 
 	"github.com/amtp-protocol/agentry/internal/errors"
 	"github.com/amtp-protocol/agentry/internal/types"
@@ -30,6 +33,7 @@ func (s *Server) respondWithError(c *gin.Context, statusCode int, code, message
 	requestID := c.GetString("request_id")
 
 	errorResponse := types.ErrorResponse{
+		APIVersion: apiVersion(c),
 		Error: types.ErrorDetail{
 			Code:      code,
 			Message:   message,
@@ -69,6 +73,7 @@ func (s *Server) respondWithAMTPError(c *gin.Context, err *errors.AMTPError) {
 
 	statusCode := err.GetHTTPStatus()
 	errorResponse := err.ToErrorResponse()
+	errorResponse.APIVersion = apiVersion(c)
 
 	// Log the error
 	logger := s.logger.WithContext(c.Request.Context()).WithFields(map[string]interface{}{
@@ -105,7 +110,29 @@ func getErrorType(statusCode int) string {
 	}
 }
 
-// respondWithSuccess sends a successful response with metrics
+// Response media types negotiated with the Accept header; JSON is the default
+const (
+	mimeMsgpack  = "application/msgpack"
+	mimeXMsgpack = "application/x-msgpack"
+)
+
+// msgpackHandle encodes msgpack responses. Struct fields keep their JSON
+// names, times use the msgpack timestamp extension, and raw JSON payloads
+// are sent as binary fields holding the JSON text.
+var msgpackHandle = &codec.MsgpackHandle{WriteExt: true}
+
+// acceptsMsgpack reports whether the client prefers msgpack over JSON
+func acceptsMsgpack(c *gin.Context) bool {
+	switch c.NegotiateFormat(gin.MIMEJSON, mimeMsgpack, mimeXMsgpack) {
+	case mimeMsgpack, mimeXMsgpack:
+		return true
+	}
+	return false
+}
+
+// respondWithSuccess sends a successful response with metrics, encoded as
+// msgpack when the client asks for it and as JSON otherwise. Error responses
+// are always JSON.
 func (s *Server) respondWithSuccess(c *gin.Context, statusCode int, data interface{}) {
 	// Record success metrics
 	if s.metrics != nil {
@@ -117,7 +144,93 @@ func (s *Server) respondWithSuccess(c *gin.Context, statusCode int, data interfa
 		)
 	}
 
-	c.JSON(statusCode, data)
+	writeResponse(c, statusCode, data)
+}
+
+// writeResponse sends data with the request's api_version, encoded as msgpack
+// when the client asks for it and as JSON otherwise, without recording
+// metrics. Probes use it directly.
+func writeResponse(c *gin.Context, statusCode int, data interface{}) {
+	c.Header("Vary", "Accept")
+	if acceptsMsgpack(c) {
+		var body []byte
+		if err := codec.NewEncoderBytes(&body, msgpackHandle).Encode(data); err == nil {
+			c.Data(statusCode, mimeMsgpack, withAPIVersionMsgpack(body, apiVersion(c)))
+			return
+		}
+	}
+
+	body, err := json.Marshal(data)
+	if err != nil {
+		c.JSON(statusCode, data)
+		return
+	}
+	c.Data(statusCode, "application/json; charset=utf-8", withAPIVersion(body, apiVersion(c)))
+}
+
+// apiVersion returns the response version selected for the request
+func apiVersion(c *gin.Context) string {
+	if version := c.GetString("api_version"); version != "" {
+		return version
+	}
+	return types.APIVersion
+}
+
+// withAPIVersion adds api_version as the first field of a JSON object.
+// Other JSON values, such as arrays, are returned unchanged.
+func withAPIVersion(body []byte, version string) []byte {
+	if len(body) < 2 || body[0] != '{' {
+		return body
+	}
+	field, _ := json.Marshal(version)
+
+	out := make([]byte, 0, len(body)+len(field)+16)
+	out = append(out, `{"api_version":`...)
+	out = append(out, field...)
+	if rest := body[1:]; rest[0] != '}' {
+		out = append(out, ',')
+		out = append(out, rest...)
+	} else {
+		out = append(out, '}')
+	}
+	return out
+}
+
+// withAPIVersionMsgpack adds api_version as the first entry of a msgpack map.
+// Other values are returned unchanged.
+func withAPIVersionMsgpack(body []byte, version string) []byte {
+	if len(body) == 0 {
+		return body
+	}
+	var size, header int
+	switch b := body[0]; {
+	case b&0xf0 == 0x80: // fixmap
+		size, header = int(b&0x0f), 1
+	case b == 0xde && len(body) >= 3: // map 16
+		size, header = int(binary.BigEndian.Uint16(body[1:3])), 3
+	case b == 0xdf && len(body) >= 5: // map 32
+		size, header = int(binary.BigEndian.Uint32(body[1:5])), 5
+	default:
+		return body
+	}
+
+	// Encode the entry as a one-entry map and drop its fixmap header
+	var field []byte
+	codec.NewEncoderBytes(&field, msgpackHandle).MustEncode(map[string]string{"api_version": version})
+
+	out := make([]byte, 0, len(body)+len(field)+4)
+	switch size++; {
+	case size < 16:
+		out = append(out, 0x80|byte(size))
+	case size <= 0xffff:
+		out = append(out, 0xde)
+		out = binary.BigEndian.AppendUint16(out, uint16(size))
+	default:
+		out = append(out, 0xdf)
+		out = binary.BigEndian.AppendUint32(out, uint32(size))
+	}
+	out = append(out, field[1:]...)
+	return append(out, body[header:]...)
 }
 
 // withRequestMetrics wraps a handler with request metrics
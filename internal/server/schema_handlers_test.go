@@ -18,15 +18,19 @@ package server
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"os"
 
 	"github.com/amtp-protocol/agentry/internal/schema"
 	"github.com/amtp-protocol/agentry/internal/types"
+	"github.com/amtp-protocol/agentry/internal/validation"
 )
 
 // Test schema management handlers when schema manager is not configured
@@ -44,7 +48,10 @@ func TestSchemaHandlers_NoSchemaManager(t *testing.T) {
 		{"PUT", "/v1/admin/schemas/agntcy:example.test.v1", `{"definition": {}}`},
 		{"DELETE", "/v1/admin/schemas/agntcy:example.test.v1", ""},
 		{"POST", "/v1/admin/schemas/test.v1/validate", `{"payload": {}}`},
+		{"POST", "/v1/admin/schemas/agntcy:example.test.v1/revalidate", ""},
 		{"GET", "/v1/admin/schemas/stats", ""},
+		{"POST", "/v1/admin/schemas/reload", ""},
+		{"GET", "/v1/admin/schemas/example/test/latest", ""},
 	}
 
 	for _, endpoint := range endpoints {
@@ -113,6 +120,18 @@ func TestSchemaHandlers_WithSchemaManager(t *testing.T) {
 		}
 	})
 
+	t.Run("POST /v1/admin/schemas - Duplicate Schema", func(t *testing.T) {
+		body := `{"id":"agntcy:test.domain.v1","definition":{"type":"object"}}`
+		req := httptest.NewRequest("POST", "/v1/admin/schemas", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		server.router.ServeHTTP(w, req)
+
+		if w.Code != http.StatusConflict || !strings.Contains(w.Body.String(), "SCHEMA_ALREADY_EXISTS") {
+			t.Errorf("Expected status %d with SCHEMA_ALREADY_EXISTS, got %d: %s", http.StatusConflict, w.Code, w.Body.String())
+		}
+	})
+
 	t.Run("GET /v1/admin/schemas - List Schemas", func(t *testing.T) {
 		req := httptest.NewRequest("GET", "/v1/admin/schemas?domain=test.domain", nil)
 		w := httptest.NewRecorder()
@@ -176,4 +195,707 @@ func TestSchemaHandlers_WithSchemaManager(t *testing.T) {
 			t.Errorf("Expected status %d, got %d", http.StatusOK, w.Code)
 		}
 	})
+
+	t.Run("POST /v1/admin/schemas/reload - Reload Schemas", func(t *testing.T) {
+		// Add a schema file out-of-band
+		deploy, err := schema.NewLocalRegistry(schema.LocalRegistryConfig{BasePath: tempDir, AutoSave: true})
+		if err != nil {
+			t.Fatalf("failed to create registry: %v", err)
+		}
+		id, _ := schema.ParseSchemaIdentifier("agntcy:test.reload.v1")
+		if err := deploy.RegisterSchema(context.Background(), &schema.Schema{ID: *id, Definition: json.RawMessage(`{"type":"object"}`)}, nil); err != nil {
+			t.Fatalf("failed to write schema: %v", err)
+		}
+
+		req := httptest.NewRequest("POST", "/v1/admin/schemas/reload", nil)
+		w := httptest.NewRecorder()
+		server.router.ServeHTTP(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+		}
+
+		var response struct {
+			Changes schema.ReloadSummary `json:"changes"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+			t.Fatalf("Failed to unmarshal response: %v", err)
+		}
+		if len(response.Changes.Added) != 1 || response.Changes.Added[0] != "agntcy:test.reload.v1" {
+			t.Errorf("Expected the new schema to be added, got %+v", response.Changes)
+		}
+
+		req = httptest.NewRequest("GET", "/v1/admin/schemas/agntcy:test.reload.v1", nil)
+		w = httptest.NewRecorder()
+		server.router.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			t.Errorf("Expected reloaded schema to be served, got %d", w.Code)
+		}
+	})
+}
+
+func TestSchemaHandlers_LatestVersion(t *testing.T) {
+	sm, err := schema.NewManager(schema.ManagerConfig{
+		RegistryType: "local",
+		LocalRegistry: schema.LocalRegistryConfig{
+			BasePath:   t.TempDir(),
+			CreateDirs: true,
+		},
+	})
+	if err != nil {
+		t.Fatalf("failed to create schema manager: %v", err)
+	}
+
+	server := createTestServer()
+	server.schemaManager = sm
+
+	// Versionless registrations are assigned the next version
+	for _, want := range []string{"agntcy:commerce.order.v1", "agntcy:commerce.order.v2"} {
+		body := `{"id":"agntcy:commerce.order","definition":{"type":"object"}}`
+		req := httptest.NewRequest("POST", "/v1/admin/schemas", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		server.router.ServeHTTP(w, req)
+
+		if w.Code != http.StatusCreated {
+			t.Fatalf("Expected status %d, got %d: %s", http.StatusCreated, w.Code, w.Body.String())
+		}
+		var resp map[string]interface{}
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("Failed to unmarshal response: %v", err)
+		}
+		if resp["schema_id"] != want {
+			t.Errorf("Expected schema_id %s, got %v", want, resp["schema_id"])
+		}
+	}
+
+	req := httptest.NewRequest("GET", "/v1/admin/schemas/commerce/order/latest", nil)
+	w := httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	var resp struct {
+		SchemaID string         `json:"schema_id"`
+		Schema   *schema.Schema `json:"schema"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Failed to unmarshal response: %v", err)
+	}
+	if resp.SchemaID != "agntcy:commerce.order.v2" || resp.Schema == nil || resp.Schema.ID.Version != "v2" {
+		t.Errorf("Expected latest agntcy:commerce.order.v2, got %s (%+v)", resp.SchemaID, resp.Schema)
+	}
+
+	req = httptest.NewRequest("GET", "/v1/admin/schemas/commerce/refund/latest", nil)
+	w = httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected status %d, got %d", http.StatusNotFound, w.Code)
+	}
+}
+
+func TestHandleSendMessage_SchemaAutoDetect(t *testing.T) {
+	sm, err := schema.NewManager(schema.ManagerConfig{
+		RegistryType: "local",
+		LocalRegistry: schema.LocalRegistryConfig{
+			BasePath:   t.TempDir(),
+			CreateDirs: true,
+		},
+	})
+	if err != nil {
+		t.Fatalf("failed to create schema manager: %v", err)
+	}
+	for id, def := range map[string]string{
+		"agntcy:commerce.order.v1":   `{"type":"object","required":["order_id"]}`,
+		"agntcy:commerce.invoice.v1": `{"type":"object","required":["invoice_id"]}`,
+		"agntcy:billing.invoice.v1":  `{"type":"object","required":["invoice_id"]}`,
+	} {
+		schemaID, err := schema.ParseSchemaIdentifier(id)
+		if err != nil {
+			t.Fatalf("failed to parse %s: %v", id, err)
+		}
+		if err := sm.RegisterSchema(context.Background(), &schema.Schema{ID: *schemaID, Definition: json.RawMessage(def)}, nil); err != nil {
+			t.Fatalf("failed to register %s: %v", id, err)
+		}
+	}
+
+	tests := []struct {
+		name        string
+		autoDetect  bool
+		schema      string
+		payload     string
+		warningCode string
+		detected    string
+	}{
+		{"single match", true, "", `{"order_id":"o-1"}`, "SCHEMA_DETECTED", "agntcy:commerce.order.v1"},
+		{"several matches", true, "", `{"invoice_id":"i-1"}`, "SCHEMA_AMBIGUOUS", ""},
+		{"no match", true, "", `{"note":"hi"}`, "", ""},
+		{"schema given", true, "agntcy:commerce.order.v1", `{"order_id":"o-1"}`, "", ""},
+		{"disabled", false, "", `{"order_id":"o-1"}`, "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := createTestServer()
+			server.schemaManager = sm
+			server.config.Message.SchemaAutoDetect = tt.autoDetect
+
+			body, _ := json.Marshal(types.SendMessageRequest{
+				Sender:     "test@example.com",
+				Recipients: []string{"recipient@test.com"},
+				Schema:     tt.schema,
+				Payload:    json.RawMessage(tt.payload),
+			})
+			req := httptest.NewRequest("POST", "/v1/messages", bytes.NewBuffer(body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			server.router.ServeHTTP(w, req)
+
+			if w.Code != http.StatusOK {
+				t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+			}
+			var response types.SendMessageResponse
+			if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+				t.Fatalf("Failed to unmarshal response: %v", err)
+			}
+
+			if tt.warningCode == "" {
+				if len(response.Warnings) != 0 {
+					t.Errorf("Expected no warnings, got %+v", response.Warnings)
+				}
+			} else if len(response.Warnings) != 1 || response.Warnings[0].Code != tt.warningCode {
+				t.Errorf("Expected a %s warning, got %+v", tt.warningCode, response.Warnings)
+			}
+
+			sent := server.processor.(*MockMessageProcessor).lastMessage
+			if sent.Schema != tt.schema {
+				t.Errorf("Expected the schema to stay %q, got %q", tt.schema, sent.Schema)
+			}
+			if got, _ := sent.Headers[types.DetectedSchemaHeader].(string); got != tt.detected {
+				t.Errorf("Expected detected schema header %q, got %q", tt.detected, got)
+			}
+		})
+	}
+}
+
+func TestSchemaHandlers_ValidationMode(t *testing.T) {
+	sm, err := schema.NewManager(schema.ManagerConfig{
+		RegistryType: "local",
+		LocalRegistry: schema.LocalRegistryConfig{
+			BasePath:   t.TempDir(),
+			CreateDirs: true,
+		},
+		Validation: schema.ValidatorConfig{Enabled: true, MaxPayloadSize: 1 << 20},
+		Pipeline:   schema.PipelineConfig{Enabled: true},
+	})
+	if err != nil {
+		t.Fatalf("failed to create schema manager: %v", err)
+	}
+	server := createTestServer()
+	server.schemaManager = sm
+	server.validator = validation.NewWithSchemaManager(server.config.Message.MaxSize, sm)
+
+	post := func(path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", path, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		server.router.ServeHTTP(w, req)
+		return w
+	}
+
+	definition := `{"type":"object","properties":{"order_id":{"type":"string"},"total":{"type":"number"}},"required":["order_id","total"]}`
+	if w := post("/v1/admin/schemas", `{"id":"agntcy:commerce.order.v1","definition":`+definition+`,"validation_mode":"lenient"}`); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected an unknown validation mode to be rejected, got %d", w.Code)
+	}
+	if w := post("/v1/admin/schemas", `{"id":"agntcy:commerce.order.v1","definition":`+definition+`}`); w.Code != http.StatusCreated {
+		t.Fatalf("Failed to register schema: %d %s", w.Code, w.Body.String())
+	}
+
+	t.Run("validate endpoint", func(t *testing.T) {
+		tests := []struct {
+			mode    string
+			payload string
+			valid   bool
+		}{
+			{"", `{"order_id":"o-1"}`, false},
+			{"full", `{"order_id":"o-1"}`, false},
+			{"partial", `{"order_id":"o-1"}`, true},
+			{"partial", `{"order_id":1}`, false},
+		}
+		for _, tt := range tests {
+			body, _ := json.Marshal(map[string]interface{}{"payload": json.RawMessage(tt.payload), "validation_mode": tt.mode})
+			w := post("/v1/admin/schemas/agntcy:commerce.order.v1/validate", string(body))
+			if w.Code != http.StatusOK {
+				t.Fatalf("mode %q: expected status %d, got %d: %s", tt.mode, http.StatusOK, w.Code, w.Body.String())
+			}
+			var response struct {
+				Valid bool `json:"valid"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+				t.Fatalf("Failed to unmarshal response: %v", err)
+			}
+			if response.Valid != tt.valid {
+				t.Errorf("mode %q, payload %s: expected valid=%t, got %t", tt.mode, tt.payload, tt.valid, response.Valid)
+			}
+		}
+	})
+
+	t.Run("send", func(t *testing.T) {
+		tests := []struct {
+			mode   string
+			status int
+		}{
+			{"full", http.StatusBadRequest},
+			{"partial", http.StatusOK},
+		}
+		for _, tt := range tests {
+			body, _ := json.Marshal(types.SendMessageRequest{
+				Sender:         "test@example.com",
+				Recipients:     []string{"recipient@test.com"},
+				Schema:         "agntcy:commerce.order.v1",
+				Payload:        json.RawMessage(`{"order_id":"o-1"}`),
+				ValidationMode: tt.mode,
+			})
+			if w := post("/v1/messages", string(body)); w.Code != tt.status {
+				t.Errorf("mode %q: expected status %d, got %d: %s", tt.mode, tt.status, w.Code, w.Body.String())
+			}
+		}
+	})
+
+	t.Run("schema default", func(t *testing.T) {
+		req := httptest.NewRequest("PUT", "/v1/admin/schemas/agntcy:commerce.order.v1",
+			bytes.NewBufferString(`{"definition":`+definition+`,"validation_mode":"partial"}`))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		server.router.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("Failed to update schema: %d %s", w.Code, w.Body.String())
+		}
+
+		w = post("/v1/admin/schemas/agntcy:commerce.order.v1/validate", `{"payload":{"order_id":"o-1"}}`)
+		if !bytes.Contains(w.Body.Bytes(), []byte(`"valid":true`)) {
+			t.Errorf("Expected the schema's partial mode to apply, got %s", w.Body.String())
+		}
+		w = post("/v1/admin/schemas/agntcy:commerce.order.v1/validate", `{"payload":{"order_id":"o-1"},"validation_mode":"full"}`)
+		if !bytes.Contains(w.Body.Bytes(), []byte(`"valid":false`)) {
+			t.Errorf("Expected the request's full mode to override the schema, got %s", w.Body.String())
+		}
+	})
+}
+
+func TestSchemaHandlers_UpdateKeepsStrict(t *testing.T) {
+	sm, err := schema.NewManager(schema.ManagerConfig{
+		RegistryType: "local",
+		LocalRegistry: schema.LocalRegistryConfig{
+			BasePath:   t.TempDir(),
+			CreateDirs: true,
+		},
+		Validation: schema.ValidatorConfig{Enabled: true, MaxPayloadSize: 1 << 20},
+		Pipeline:   schema.PipelineConfig{Enabled: true},
+	})
+	if err != nil {
+		t.Fatalf("failed to create schema manager: %v", err)
+	}
+	server := createTestServer()
+	server.schemaManager = sm
+
+	send := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		server.router.ServeHTTP(w, req)
+		return w
+	}
+	strict := func() bool {
+		schemaID, _ := schema.ParseSchemaIdentifier("agntcy:commerce.order.v1")
+		stored, err := sm.GetSchema(context.Background(), *schemaID)
+		if err != nil {
+			t.Fatalf("failed to get schema: %v", err)
+		}
+		return stored.Strict
+	}
+
+	definition := `{"type":"object","properties":{"order_id":{"type":"string"}}}`
+	if w := send("POST", "/v1/admin/schemas", `{"id":"agntcy:commerce.order.v1","definition":`+definition+`,"strict":true}`); w.Code != http.StatusCreated {
+		t.Fatalf("Failed to register schema: %d %s", w.Code, w.Body.String())
+	}
+
+	if w := send("PUT", "/v1/admin/schemas/agntcy:commerce.order.v1", `{"definition":`+definition+`}`); w.Code != http.StatusOK {
+		t.Fatalf("Failed to update schema: %d %s", w.Code, w.Body.String())
+	}
+	if !strict() {
+		t.Error("Expected an update without strict to keep the schema strict")
+	}
+
+	if w := send("PUT", "/v1/admin/schemas/agntcy:commerce.order.v1", `{"definition":`+definition+`,"strict":false}`); w.Code != http.StatusOK {
+		t.Fatalf("Failed to update schema: %d %s", w.Code, w.Body.String())
+	}
+	if strict() {
+		t.Error("Expected an explicit strict=false to clear strict")
+	}
+}
+
+func TestHandleValidateStoredMessage(t *testing.T) {
+	sm, err := schema.NewManager(schema.ManagerConfig{
+		RegistryType: "local",
+		LocalRegistry: schema.LocalRegistryConfig{
+			BasePath:   t.TempDir(),
+			CreateDirs: true,
+		},
+		Validation: schema.ValidatorConfig{Enabled: true, MaxPayloadSize: 1 << 20},
+		Pipeline:   schema.PipelineConfig{Enabled: true},
+	})
+	if err != nil {
+		t.Fatalf("failed to create schema manager: %v", err)
+	}
+
+	server := createTestServer()
+	mockStorage := server.storage.(*MockStorage)
+	ctx := context.Background()
+	for id, definition := range map[string]string{
+		"agntcy:commerce.order.v1":  `{"type":"object","properties":{"order_id":{"type":"string"}},"required":["order_id"]}`,
+		"agntcy:commerce.refund.v1": `{"type":"object","properties":{"refund_id":{"type":"string"}},"required":["refund_id"]}`,
+	} {
+		schemaID, _ := schema.ParseSchemaIdentifier(id)
+		if err := sm.RegisterSchema(ctx, &schema.Schema{ID: *schemaID, Definition: json.RawMessage(definition)}, nil); err != nil {
+			t.Fatalf("failed to register %s: %v", id, err)
+		}
+	}
+
+	orderID := "01234567-89ab-7def-8123-456789abcdef"
+	mockStorage.messages[orderID] = &types.Message{
+		MessageID: orderID,
+		Schema:    "agntcy:commerce.order.v1",
+		Payload:   json.RawMessage(`{"order_id":"o-1"}`),
+	}
+	plainID := "01234567-89ab-7def-8123-456789abcdee"
+	mockStorage.messages[plainID] = &types.Message{
+		MessageID: plainID,
+		Payload:   json.RawMessage(`{"order_id":"o-2"}`),
+	}
+
+	validate := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		server.router.ServeHTTP(w, httptest.NewRequest("POST", path, nil))
+		return w
+	}
+
+	if w := validate("/v1/admin/messages/" + orderID + "/validate"); w.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected status %d without a schema manager, got %d", http.StatusServiceUnavailable, w.Code)
+	}
+	server.schemaManager = sm
+
+	tests := []struct {
+		name      string
+		path      string
+		code      int
+		valid     bool
+		errorCode string
+	}{
+		{"own schema", "/v1/admin/messages/" + orderID + "/validate", http.StatusOK, true, ""},
+		{"other schema", "/v1/admin/messages/" + orderID + "/validate?schema=agntcy:commerce.refund.v1", http.StatusOK, false, ""},
+		{"latest version", "/v1/admin/messages/" + plainID + "/validate?schema=agntcy:commerce.order", http.StatusOK, true, ""},
+		{"no schema", "/v1/admin/messages/" + plainID + "/validate", http.StatusBadRequest, false, "SCHEMA_REQUIRED"},
+		{"invalid schema", "/v1/admin/messages/" + orderID + "/validate?schema=not-a-schema", http.StatusBadRequest, false, "INVALID_SCHEMA_ID"},
+		{"unknown schema", "/v1/admin/messages/" + orderID + "/validate?schema=agntcy:commerce.invoice.v1", http.StatusNotFound, false, "SCHEMA_NOT_FOUND"},
+		{"unknown latest schema", "/v1/admin/messages/" + orderID + "/validate?schema=agntcy:commerce.invoice", http.StatusNotFound, false, "SCHEMA_NOT_FOUND"},
+		{"unknown message", "/v1/admin/messages/01234567-89ab-7def-8123-456789abcdea/validate", http.StatusNotFound, false, "MESSAGE_NOT_FOUND"},
+		{"invalid message ID", "/v1/admin/messages/nope/validate", http.StatusBadRequest, false, "INVALID_MESSAGE_ID"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := validate(tt.path)
+			if w.Code != tt.code {
+				t.Fatalf("Expected status %d, got %d: %s", tt.code, w.Code, w.Body.String())
+			}
+			if tt.errorCode != "" {
+				if !strings.Contains(w.Body.String(), tt.errorCode) {
+					t.Errorf("Expected error %s, got %s", tt.errorCode, w.Body.String())
+				}
+				return
+			}
+
+			var report schema.ValidationReport
+			if err := json.Unmarshal(w.Body.Bytes(), &report); err != nil {
+				t.Fatalf("Failed to unmarshal response: %v", err)
+			}
+			if report.Valid != tt.valid {
+				t.Errorf("Expected valid %v, got %+v", tt.valid, report)
+			}
+			if !tt.valid && len(report.Errors) == 0 {
+				t.Error("Expected the report to list the validation errors")
+			}
+		})
+	}
+
+	// The stored message keeps its own schema
+	if stored := mockStorage.messages[orderID]; stored.Schema != "agntcy:commerce.order.v1" {
+		t.Errorf("Expected the stored message to be unchanged, got schema %s", stored.Schema)
+	}
+}
+
+func TestHandleRevalidateSchema(t *testing.T) {
+	sm, err := schema.NewManager(schema.ManagerConfig{
+		RegistryType: "local",
+		LocalRegistry: schema.LocalRegistryConfig{
+			BasePath:   t.TempDir(),
+			CreateDirs: true,
+		},
+		Validation: schema.ValidatorConfig{Enabled: true, MaxPayloadSize: 1 << 20},
+		Pipeline:   schema.PipelineConfig{Enabled: true},
+	})
+	if err != nil {
+		t.Fatalf("failed to create schema manager: %v", err)
+	}
+	schemaID, _ := schema.ParseSchemaIdentifier("agntcy:commerce.order.v1")
+	definition := `{"type":"object","properties":{"order_id":{"type":"string"}},"required":["order_id"]}`
+	if err := sm.RegisterSchema(context.Background(), &schema.Schema{ID: *schemaID, Definition: json.RawMessage(definition)}, nil); err != nil {
+		t.Fatalf("failed to register schema: %v", err)
+	}
+
+	server := createTestServer()
+	server.schemaManager = sm
+	mockStorage := server.storage.(*MockStorage)
+
+	// More messages than fit in one page; every tenth lacks the order ID
+	for i := 0; i < 2*revalidatePageSize+10; i++ {
+		payload := fmt.Sprintf(`{"order_id":"o-%d"}`, i)
+		if i%10 == 0 {
+			payload = `{"amount":1}`
+		}
+		id := fmt.Sprintf("m-%04d", i)
+		mockStorage.messages[id] = &types.Message{MessageID: id, Schema: "agntcy:commerce.order.v1", Payload: json.RawMessage(payload)}
+	}
+	mockStorage.messages["sealed"] = &types.Message{MessageID: "sealed", Schema: "agntcy:commerce.order.v1", Encrypted: true, Payload: json.RawMessage(`"c2VhbGVk"`)}
+	mockStorage.messages["other"] = &types.Message{MessageID: "other", Schema: "agntcy:commerce.refund.v1", Payload: json.RawMessage(`{}`)}
+	// References that resolve to v1 while it is the latest version
+	mockStorage.messages["z-latest"] = &types.Message{MessageID: "z-latest", Schema: "agntcy:commerce.order@latest", Payload: json.RawMessage(`{"amount":1}`)}
+	mockStorage.messages["z-versionless"] = &types.Message{MessageID: "z-versionless", Schema: "agntcy:commerce.order", Payload: json.RawMessage(`{"order_id":"o-z"}`)}
+
+	revalidate := func(ctx context.Context, id string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest("POST", "/v1/admin/schemas/"+id+"/revalidate", nil).WithContext(ctx)
+		server.router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := revalidate(context.Background(), "agntcy:commerce.order.v1")
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	var response struct {
+		Revalidation schemaRevalidation `json:"revalidation"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to unmarshal response: %v", err)
+	}
+	result := response.Revalidation
+	if result.Checked != 212 || result.Valid != 190 || result.Invalid != 22 || result.Skipped != 1 {
+		t.Errorf("Unexpected counts: %+v", result)
+	}
+	if len(result.InvalidSample) != revalidateSampleSize || result.InvalidSample[0].MessageID != "m-0000" || len(result.InvalidSample[0].Errors) == 0 {
+		t.Errorf("Unexpected sample: %+v", result.InvalidSample)
+	}
+	if string(mockStorage.messages["m-0001"].Payload) != `{"order_id":"o-1"}` {
+		t.Error("Expected stored messages to be left unchanged")
+	}
+
+	// Once v2 is registered, versionless references resolve to it instead
+	v2, _ := schema.ParseSchemaIdentifier("agntcy:commerce.order.v2")
+	if err := sm.RegisterSchema(context.Background(), &schema.Schema{ID: *v2, Definition: json.RawMessage(definition)}, nil); err != nil {
+		t.Fatalf("failed to register schema: %v", err)
+	}
+	w = revalidate(context.Background(), "agntcy:commerce.order.v1")
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to unmarshal response: %v", err)
+	}
+	if result := response.Revalidation; result.Checked != 210 || result.Invalid != 21 {
+		t.Errorf("Expected only v1 messages once v2 is latest, got %+v", result)
+	}
+	w = revalidate(context.Background(), "agntcy:commerce.order.v2")
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to unmarshal response: %v", err)
+	}
+	if result := response.Revalidation; result.Checked != 2 || result.Valid != 1 || result.Invalid != 1 {
+		t.Errorf("Expected the versionless messages under v2, got %+v", result)
+	}
+
+	tests := []struct {
+		name      string
+		id        string
+		code      int
+		errorCode string
+	}{
+		{"invalid schema", "not-a-schema", http.StatusBadRequest, "INVALID_SCHEMA_ID"},
+		{"unknown schema", "agntcy:commerce.order.v9", http.StatusNotFound, "SCHEMA_NOT_FOUND"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := revalidate(context.Background(), tt.id)
+			if w.Code != tt.code || !strings.Contains(w.Body.String(), tt.errorCode) {
+				t.Errorf("Expected status %d with %s, got %d: %s", tt.code, tt.errorCode, w.Code, w.Body.String())
+			}
+		})
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if w := revalidate(ctx, "agntcy:commerce.order.v1"); w.Code != http.StatusServiceUnavailable || !strings.Contains(w.Body.String(), "REVALIDATION_CANCELED") {
+		t.Errorf("Expected a canceled revalidation to stop, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestHandleInferSchema(t *testing.T) {
+	// Inference needs no schema manager
+	server := createTestServer()
+
+	tests := []struct {
+		name           string
+		body           string
+		expectedStatus int
+		expectedCode   string
+	}{
+		{"examples", `{"examples": [{"status": "open", "id": 1}, {"status": "open", "id": 2}]}`, http.StatusOK, ""},
+		{"enums disabled", `{"examples": [{"status": "open"}, {"status": "open"}], "max_enum_values": 0}`, http.StatusOK, ""},
+		{"no examples", `{"examples": []}`, http.StatusBadRequest, "INVALID_EXAMPLES"},
+		{"missing examples", `{}`, http.StatusBadRequest, "INVALID_REQUEST_FORMAT"},
+		{"negative enum limit", `{"examples": [{}], "max_enum_values": -1}`, http.StatusBadRequest, "INVALID_REQUEST_FORMAT"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/v1/admin/schemas/infer", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			server.router.ServeHTTP(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Fatalf("Expected status %d, got %d: %s", tt.expectedStatus, w.Code, w.Body.String())
+			}
+			if tt.expectedCode != "" {
+				if !strings.Contains(w.Body.String(), tt.expectedCode) {
+					t.Errorf("Expected %s, got %s", tt.expectedCode, w.Body.String())
+				}
+				return
+			}
+
+			var response struct {
+				Schema struct {
+					Type       string                     `json:"type"`
+					Required   []string                   `json:"required"`
+					Properties map[string]json.RawMessage `json:"properties"`
+				} `json:"schema"`
+				Examples int `json:"examples"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+				t.Fatalf("Failed to unmarshal response: %v", err)
+			}
+			if response.Examples != 2 || response.Schema.Type != "object" || len(response.Schema.Required) == 0 {
+				t.Errorf("Unexpected response: %s", w.Body.String())
+			}
+			hasEnum := strings.Contains(string(response.Schema.Properties["status"]), "enum")
+			if hasEnum != (tt.name == "examples") {
+				t.Errorf("Unexpected status property: %s", response.Schema.Properties["status"])
+			}
+		})
+	}
+}
+
+func TestSchemaHandlers_Defaults(t *testing.T) {
+	sm, err := schema.NewManager(schema.ManagerConfig{
+		RegistryType: "local",
+		LocalRegistry: schema.LocalRegistryConfig{
+			BasePath:   t.TempDir(),
+			CreateDirs: true,
+		},
+	})
+	if err != nil {
+		t.Fatalf("failed to create schema manager: %v", err)
+	}
+	server := createTestServer()
+	server.schemaManager = sm
+
+	post := func(path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", path, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		server.router.ServeHTTP(w, req)
+		return w
+	}
+
+	definition := `{"type":"object"}`
+	if w := post("/v1/admin/schemas", `{"id":"agntcy:commerce.order.v1","definition":`+definition+`,"defaults":{"coordination":{"type":"broadcast","timeout":30}}}`); w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "INVALID_SCHEMA_DEFAULTS") {
+		t.Errorf("Expected invalid defaults to be rejected, got %d: %s", w.Code, w.Body.String())
+	}
+	defaults := `{"response_type":"required","coordination":{"type":"parallel","timeout":30}}`
+	if w := post("/v1/admin/schemas", `{"id":"agntcy:commerce.order.v1","definition":`+definition+`,"defaults":`+defaults+`}`); w.Code != http.StatusCreated {
+		t.Fatalf("Failed to register schema: %d %s", w.Code, w.Body.String())
+	}
+
+	req := httptest.NewRequest("GET", "/v1/admin/schemas/agntcy:commerce.order.v1", nil)
+	w := httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+	if !strings.Contains(w.Body.String(), `"response_type":"required"`) {
+		t.Errorf("Expected the defaults in the schema, got %s", w.Body.String())
+	}
+
+	// An update that leaves the defaults out keeps them
+	req = httptest.NewRequest("PUT", "/v1/admin/schemas/agntcy:commerce.order.v1", bytes.NewBufferString(`{"definition":`+definition+`}`))
+	req.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Failed to update schema: %d %s", w.Code, w.Body.String())
+	}
+
+	signature := &types.MessageSignature{Algorithm: "ES256", KeyID: "k1", Value: "c2lnbmVk"}
+	tests := []struct {
+		name         string
+		schema       string
+		responseType string
+		coordination *types.CoordinationConfig
+		signature    *types.MessageSignature
+		wantResponse string
+		wantCoord    string
+	}{
+		{"defaults applied", "agntcy:commerce.order.v1", "", nil, nil, "required", "parallel"},
+		{"message overrides", "agntcy:commerce.order.v1", "none", &types.CoordinationConfig{Type: "parallel", Timeout: 5}, nil, "none", "parallel"},
+		{"no schema", "", "", nil, nil, "", ""},
+		{"signed message", "agntcy:commerce.order.v1", "", nil, signature, "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, _ := json.Marshal(types.SendMessageRequest{
+				Sender:       "test@example.com",
+				Recipients:   []string{"recipient@test.com"},
+				Schema:       tt.schema,
+				Payload:      json.RawMessage(`{"order_id":"o-1"}`),
+				ResponseType: tt.responseType,
+				Coordination: tt.coordination,
+				Signature:    tt.signature,
+			})
+			if w := post("/v1/messages", string(body)); w.Code != http.StatusOK {
+				t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+			}
+
+			sent := server.processor.(*MockMessageProcessor).lastMessage
+			if sent.ResponseType != tt.wantResponse {
+				t.Errorf("Expected response type %q, got %q", tt.wantResponse, sent.ResponseType)
+			}
+			coordination := ""
+			if sent.Coordination != nil {
+				coordination = sent.Coordination.Type
+			}
+			if coordination != tt.wantCoord {
+				t.Errorf("Expected coordination %q, got %q", tt.wantCoord, coordination)
+			}
+			if tt.coordination != nil && sent.Coordination.Timeout != tt.coordination.Timeout {
+				t.Errorf("Expected the message's own coordination, got %+v", sent.Coordination)
+			}
+		})
+	}
 }
@@ -0,0 +1,127 @@
+/*
+ * Copyright 2026 Cong Wang
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/amtp-protocol/agentry/internal/schema"
+)
+
+const (
+	// revalidatePageSize is how many stored messages are read per batch
+	revalidatePageSize = 100
+	// revalidateSampleSize caps the failing messages reported by ID
+	revalidateSampleSize = 20
+)
+
+// schemaRevalidation summarizes stored messages checked against the current
+// version of their schema. Every stored message was accepted, so a message
+// that fails now is one the schema change would have rejected.
+type schemaRevalidation struct {
+	SchemaID string `json:"schema_id"`
+	Checked  int    `json:"checked"`
+	Valid    int    `json:"valid"`
+	Invalid  int    `json:"invalid"`
+	// Skipped counts encrypted messages, whose payload the gateway cannot read
+	Skipped       int                   `json:"skipped"`
+	InvalidSample []revalidationFailure `json:"invalid_sample"`
+}
+
+type revalidationFailure struct {
+	MessageID string                   `json:"message_id"`
+	Errors    []schema.ValidationError `json:"errors"`
+}
+
+// revalidateStoredMessages runs every stored message using schemaID through
+// the schema manager, a page at a time. Messages are only read. It stops
+// with ctx's error when ctx ends.
+func (s *Server) revalidateStoredMessages(ctx context.Context, schemaID *schema.SchemaIdentifier) (*schemaRevalidation, error) {
+	result := &schemaRevalidation{SchemaID: schemaID.String(), InvalidSample: []revalidationFailure{}}
+
+	references, err := s.schemaReferences(ctx, schemaID)
+	if err != nil {
+		return nil, err
+	}
+
+	cursor := ""
+	for {
+		messages, next, err := s.storage.ListMessagesBySchema(ctx, references, cursor, revalidatePageSize)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, message := range messages {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+			if message.Encrypted {
+				result.Skipped++
+				continue
+			}
+
+			// Validate a copy, so nothing the validator does reaches storage
+			candidate := *message
+			result.Checked++
+			report, err := s.schemaManager.ValidateMessage(ctx, &candidate)
+			if err != nil {
+				return nil, err
+			}
+			if report.IsValid() {
+				result.Valid++
+				continue
+			}
+
+			result.Invalid++
+			if len(result.InvalidSample) < revalidateSampleSize {
+				result.InvalidSample = append(result.InvalidSample, revalidationFailure{
+					MessageID: message.MessageID,
+					Errors:    report.Errors,
+				})
+			}
+		}
+
+		if next == "" {
+			return result, nil
+		}
+		cursor = next
+	}
+}
+
+// schemaReferences returns the schema values stored messages validated
+// against schemaID carry. Messages keep the reference they were sent with,
+// so while schemaID is the latest version of its entity, versionless and
+// @latest references resolve to it too.
+func (s *Server) schemaReferences(ctx context.Context, schemaID *schema.SchemaIdentifier) ([]string, error) {
+	references := []string{schemaID.String()}
+
+	// Versions not numbered vN are never the latest
+	latest, err := s.schemaManager.ResolveLatest(ctx, schemaID.Domain, schemaID.Entity)
+	if errors.Is(err, schema.ErrSchemaNotFound) {
+		return references, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if latest.Version == schemaID.Version {
+		versionless := fmt.Sprintf("agntcy:%s.%s", schemaID.Domain, schemaID.Entity)
+		references = append(references, versionless, versionless+"@"+schema.LatestVersion)
+	}
+	return references, nil
+}
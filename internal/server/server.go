@@ -19,8 +19,12 @@ package server
 import (
 	"context"
 	"crypto/tls"
+	"crypto/x509"
 	"fmt"
+	"io"
+	"net"
 	"net/http"
+	"os"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -28,14 +32,22 @@ import (
 	"github.com/amtp-protocol/agentry/internal/agents"
 	"github.com/amtp-protocol/agentry/internal/config"
 	"github.com/amtp-protocol/agentry/internal/discovery"
+	"github.com/amtp-protocol/agentry/internal/events"
+	"github.com/amtp-protocol/agentry/internal/features"
+	"github.com/amtp-protocol/agentry/internal/inboxtoken"
+	"github.com/amtp-protocol/agentry/internal/kafka"
 	"github.com/amtp-protocol/agentry/internal/logging"
 	"github.com/amtp-protocol/agentry/internal/metrics"
 	"github.com/amtp-protocol/agentry/internal/middleware"
+	"github.com/amtp-protocol/agentry/internal/policy"
 	"github.com/amtp-protocol/agentry/internal/processing"
 	"github.com/amtp-protocol/agentry/internal/schema"
+	"github.com/amtp-protocol/agentry/internal/signing"
 	"github.com/amtp-protocol/agentry/internal/storage"
+	"github.com/amtp-protocol/agentry/internal/types"
 	"github.com/amtp-protocol/agentry/internal/validation"
 	"github.com/amtp-protocol/agentry/internal/workflow"
+	"github.com/amtp-protocol/agentry/pkg/uuid"
 )
 
 // AgentManagerAdapter adapts agents.Registry to validation.AgentManager
@@ -66,16 +78,43 @@ type Server struct {
 	discovery     processing.DiscoveryService
 	validator     *validation.Validator
 	processor     processing.MessageProcessorService
+	deliveries    *processing.DeliveryEngine // nil in tests; reports push deliveries in flight
 	storage       storage.Storage
 	agentRegistry agents.AgentRegistry
 	schemaManager *schema.Manager
 	logger        *logging.Logger
 	metrics       metrics.MetricsProvider
 	workflow      workflow.Manager
+	capacity      *capacityMonitor
+	reconciler    *statusReconciler
+	writeBuffer   *writeBuffer
+	heldRetrier   *heldRecipientRetrier
+	idleAgents    *idleAgentPruner
+	messageIDs    uuid.Generator
+	addresses     types.AddressSchemes
+	smtp          *smtpBridge
+	pushProbe     *pushTargetProber
+	rawRequests   *rawRequestRecorder
+	rateLimiter   *middleware.RateLimiter
+	concurrency   *middleware.ConcurrencyLimiter
+	nonces        *middleware.NonceCache
+	signatures    *signing.Verifier
+	acceptHook    policy.AcceptHook
+	kafka         processing.KafkaProducer
+	events        *events.Hub // nil when the event stream is disabled
+	inboxTokens   *inboxtoken.Signer
 }
 
 // New creates a new AMTP server
 func New(cfg *config.Config) (*Server, error) {
+	// The key file is read again on every admin request so keys can be
+	// rotated without a restart, but it must be valid to start with
+	if cfg.Auth.AdminKeyFile != "" {
+		if _, err := middleware.LoadAdminKeys(cfg.Auth.AdminKeyFile); err != nil {
+			return nil, fmt.Errorf("invalid admin key file: %w", err)
+		}
+	}
+
 	// Create discovery service
 	var discoveryService processing.DiscoveryService
 	if cfg.DNS.MockMode {
@@ -91,10 +130,24 @@ func New(cfg *config.Config) (*Server, error) {
 	// Create logger
 	logger := logging.NewLogger(cfg.Logging).WithComponent("server")
 
+	features.Set(cfg.Features)
+
 	// Create metrics if enabled
 	var metricsInstance metrics.MetricsProvider
 	if cfg.Metrics != nil && cfg.Metrics.Enabled {
-		metricsInstance = metrics.NewMetricsProvider()
+		var err error
+		metricsInstance, err = metrics.NewMetricsProviderWithConfig(metrics.Config{
+			Sink: cfg.Metrics.Sink,
+			StatsD: metrics.StatsDConfig{
+				Address:       cfg.Metrics.StatsD.Address,
+				Prefix:        cfg.Metrics.StatsD.Prefix,
+				Tags:          cfg.Metrics.StatsD.Tags,
+				FlushInterval: cfg.Metrics.StatsD.FlushInterval,
+			},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to create metrics provider: %w", err)
+		}
 	}
 
 	// Create storage
@@ -107,6 +160,7 @@ func New(cfg *config.Config) (*Server, error) {
 				ConnectionString: cfg.Storage.Database.ConnectionString,
 				MaxConnections:   cfg.Storage.Database.MaxConnections,
 				MaxIdleTime:      cfg.Storage.Database.MaxIdleTime,
+				CompressPayloads: cfg.Storage.CompressPayloads,
 			},
 		}
 	} else {
@@ -132,25 +186,69 @@ func New(cfg *config.Config) (*Server, error) {
 		}
 	}
 
+	// Agents in kafka delivery mode are only accepted when brokers are set
+	kafkaProducer, err := kafka.NewProducer(cfg.Delivery.Kafka)
+	if err != nil {
+		return nil, err
+	}
+
+	addressSchemes, err := types.NewAddressSchemes(cfg.Message.AddressSchemes)
+	if err != nil {
+		return nil, fmt.Errorf("invalid address schemes: %w", err)
+	}
+
 	// Create agent registry first
 	agentRegistryConfig := agents.RegistryConfig{
 		LocalDomain:   cfg.Server.Domain,
 		SchemaManager: schemaManager,
 		APIKeySalt:    cfg.Auth.APIKeySalt,
+		APIKeyPrefix:  cfg.Auth.APIKeyPrefix,
+		APIKeyLength:  cfg.Auth.APIKeyLength,
+		CacheTTL:      cfg.Agents.CacheTTL,
+		Metrics:       metricsInstance,
+		KafkaEnabled:  kafkaProducer != nil,
+
+		AddressSchemes: addressSchemes,
 	}
 	agentRegistry := agents.NewRegistry(agentRegistryConfig, storage)
 
 	// Create delivery engine with agent registry
 	deliveryConfig := processing.DeliveryConfig{
-		Timeout:        30 * time.Second,
-		MaxRetries:     3,
-		RetryDelay:     1 * time.Second,
-		MaxConnections: 100,
-		IdleTimeout:    90 * time.Second,
-		UserAgent:      "AMTP-Gateway/1.0",
-		MaxMessageSize: cfg.Message.MaxSize,
-		AllowHTTP:      cfg.DNS.AllowHTTP,
-		LocalDomain:    cfg.Server.Domain,
+		ConnectTimeout:  cfg.Delivery.ConnectTimeout,
+		ResponseTimeout: cfg.Delivery.ResponseTimeout,
+		Timeout:         cfg.Delivery.Timeout,
+		MaxRetries:      cfg.Delivery.MaxRetries,
+		RetryDelay:      cfg.Delivery.RetryDelay,
+		MaxConnections:  cfg.Delivery.MaxIdleConns,
+		IdleTimeout:     cfg.Delivery.IdleConnTimeout,
+		UserAgent:       "AMTP-Gateway/1.0",
+		MaxMessageSize:  cfg.Message.MaxSize,
+		AllowHTTP:       cfg.DNS.AllowHTTP,
+		LocalDomain:     cfg.Server.Domain,
+
+		MaxIdleConnsPerHost:    cfg.Delivery.MaxIdleConnsPerHost,
+		MaxConnsPerHost:        cfg.Delivery.MaxConnsPerHost,
+		MaxConcurrentPerDomain: cfg.Delivery.MaxConcurrentPerDomain,
+		MaxConcurrentPerAgent:  cfg.Delivery.MaxConcurrentPerAgent,
+		Metrics:                metricsInstance,
+		PushTargetAllowlist:    cfg.Agents.PushTargetAllowlist,
+		SigningKeyID:           cfg.Auth.SigningKeyID,
+		UnknownRecipient:       processing.UnknownRecipientMode(cfg.Delivery.UnknownRecipient),
+		KafkaProducer:          kafkaProducer,
+		AddressSchemes:         addressSchemes,
+	}
+	if cfg.Delivery.UnknownRecipient == config.UnknownRecipientDeadLetter {
+		deliveryConfig.DeadLetterAddress = cfg.Delivery.DeadLetterAddress
+		if deliveryConfig.DeadLetterAddress == "" {
+			deliveryConfig.DeadLetterAddress = "dead-letter@" + cfg.Server.Domain
+		}
+	}
+	if cfg.Auth.SigningKeyFile != "" {
+		signer, err := signing.LoadPrivateKey(cfg.Auth.SigningKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load signing key: %w", err)
+		}
+		deliveryConfig.Signer = signer
 	}
 	deliveryEngine := processing.NewDeliveryEngine(discoveryService, agentRegistry, deliveryConfig)
 
@@ -162,10 +260,54 @@ func New(cfg *config.Config) (*Server, error) {
 	} else {
 		validator = validation.NewWithAgentManager(cfg.Message.MaxSize, nil, agentManagerAdapter)
 	}
+	validator.SetAttachmentLimits(cfg.Message.MaxAttachments, cfg.Message.MaxTotalAttachmentBytes)
+	validator.SetPayloadLimits(cfg.Message.MaxPayloadDepth, cfg.Message.MaxPayloadElements)
+
+	// Payload addresses are valid when the gateway would accept them
+	if schemaManager != nil {
+		schemaManager.RegisterFormat("agntcy-address", schema.AgentAddressFormat(addressSchemes))
+	}
+	if schemaManager != nil && cfg.Schema.SeedDir != "" {
+		seedSchemas(schemaManager, cfg.Schema.SeedDir, validator, logger)
+	}
+
+	messageIDs, err := uuid.NewGenerator(cfg.Message.IDStrategy, cfg.Message.IDPrefix)
+	if err != nil {
+		return nil, fmt.Errorf("invalid message ID strategy: %w", err)
+	}
+	validator.SetMessageIDGenerator(messageIDs)
+	validator.SetAddressSchemes(addressSchemes)
 
 	// Create message processor
 	processor := processing.NewMessageProcessor(discoveryService, deliveryEngine, storage)
+	processor.SetMessageIDGenerator(messageIDs)
+	if cfg.Message.BounceReports {
+		processor.SetBounceSender("postmaster@" + cfg.Server.Domain)
+	}
+	if deliveryConfig.DeadLetterAddress != "" {
+		processor.SetDeadLetter("postmaster@"+cfg.Server.Domain, deliveryConfig.DeadLetterAddress)
+	}
+	if cfg.Message.ArchiveAddress != "" {
+		processor.SetArchiveAddress(cfg.Message.ArchiveAddress)
+	}
+	processor.SetCoordinationLimits(processing.CoordinationLimits{
+		MaxRecipients: cfg.Delivery.Coordination.MaxRecipients,
+		MaxConcurrent: cfg.Delivery.Coordination.MaxConcurrent,
+		MaxTimeout:    cfg.Delivery.Coordination.MaxTimeout,
+	})
+	if cfg.Delivery.Cluster.Enabled {
+		workerID := clusterWorkerID(cfg.Delivery.Cluster)
+		processor.SetDeliveryClaims(workerID, cfg.Delivery.Cluster.LeaseDuration)
+		logger.WithField("worker_id", workerID).Info("Clustering enabled; queued messages are leased through storage")
+	}
 	// Create workflow manager
+	// Admins watching GET /v1/admin/events see what the processor does
+	var eventHub *events.Hub
+	if cfg.Server.EventStreamMaxSubscribers > 0 {
+		eventHub = events.NewHub(cfg.Server.EventStreamMaxSubscribers, eventStreamBuffer)
+		processor.SetEventHub(eventHub)
+	}
+
 	workflowManager := workflow.NewManager(storage, processor, logger)
 	processor.SetWorkflowManager(workflowManager)
 
@@ -176,8 +318,12 @@ func New(cfg *config.Config) (*Server, error) {
 		gin.SetMode(gin.ReleaseMode)
 	}
 
-	// Create router
+	// Create router. Forwarding headers are only believed from the
+	// configured proxies, since client IPs key rate limits.
 	router := gin.New()
+	if err := router.SetTrustedProxies(cfg.Server.TrustedProxies); err != nil {
+		return nil, fmt.Errorf("invalid trusted proxies: %w", err)
+	}
 
 	// Create server
 	server := &Server{
@@ -186,14 +332,54 @@ func New(cfg *config.Config) (*Server, error) {
 		discovery:     discoveryService,
 		validator:     validator,
 		processor:     processor,
+		deliveries:    deliveryEngine,
 		storage:       storage,
 		agentRegistry: agentRegistry,
 		schemaManager: schemaManager,
 		logger:        logger,
 		metrics:       metricsInstance,
 		workflow:      workflowManager,
+		capacity:      newCapacityMonitor(storage, metricsInstance, cfg.Storage.Capacity, logger),
+		reconciler:    newStatusReconciler(storage, cfg.Storage.ReconcileInterval, cfg.Message.ArchiveAddress, logger),
+		writeBuffer:   newWriteBuffer(processor, cfg.Storage.WriteBuffer, logger),
+		heldRetrier:   newHeldRecipientRetrier(processor, cfg.Delivery, logger),
+		idleAgents:    newIdleAgentPruner(agentRegistry, storage, cfg.Agents, logger),
+		messageIDs:    messageIDs,
+		addresses:     addressSchemes,
+		pushProbe:     newPushTargetProber(cfg.Agents),
+		rawRequests:   newRawRequestRecorder(cfg),
+		rateLimiter:   middleware.NewRateLimiter(cfg.Server.RateLimit),
+		acceptHook:    newAcceptHook(cfg.Message.AcceptHook),
+		kafka:         kafkaProducer,
+		events:        eventHub,
+	}
+	var observeConcurrency func(int)
+	if metricsInstance != nil {
+		observeConcurrency = metricsInstance.SetHTTPRequestsConcurrent
+	}
+	server.concurrency = middleware.NewConcurrencyLimiter(cfg.Server.MaxConcurrentRequests, observeConcurrency)
+	if cfg.Auth.Replay.Enabled {
+		server.nonces = middleware.NewNonceCache(cfg.Auth.Replay.NonceCacheSize)
+	}
+	switch cfg.Auth.SignatureVerification {
+	case config.SignatureVerificationOptional, config.SignatureVerificationRequired:
+		if keys, ok := discoveryService.(signing.KeyResolver); ok {
+			server.signatures = signing.NewVerifier(keys)
+		} else if cfg.Auth.SignatureVerification == config.SignatureVerificationRequired {
+			return nil, fmt.Errorf("signature verification is required but discovery cannot look up signing keys")
+		}
 	}
 
+	if cfg.Auth.InboxTokens.KeyFile != "" {
+		signer, err := inboxtoken.LoadSigner(cfg.Auth.InboxTokens.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load inbox token key: %w", err)
+		}
+		server.inboxTokens = signer
+	}
+
+	server.smtp = newSMTPBridge(server)
+
 	// Setup middleware
 	server.setupMiddleware()
 
@@ -228,12 +414,88 @@ func (s *Server) Start() error {
 		s.workflow.Start(context.Background())
 	}
 
+	// Start sampling storage capacity
+	if s.capacity != nil {
+		s.capacity.Start(context.Background())
+	}
+
+	// Start reconciling message statuses
+	if s.reconciler != nil {
+		s.reconciler.Start(context.Background())
+	}
+
+	// Start storing messages buffered while storage was unavailable
+	if s.writeBuffer != nil {
+		s.writeBuffer.Start(context.Background())
+	}
+
+	// Start retrying recipients held until their agent registers
+	if s.heldRetrier != nil {
+		s.heldRetrier.Start(context.Background())
+	}
+
+	// Start checking for idle agents
+	if s.idleAgents != nil {
+		s.idleAgents.Start(context.Background())
+	}
+
+	// Start the experimental SMTP bridge
+	if s.smtp != nil {
+		if err := s.smtp.Start(); err != nil {
+			return err
+		}
+	}
+
+	if path, ok := config.UnixSocketPath(s.config.Server.Address); ok {
+		listener, err := listenUnix(path)
+		if err != nil {
+			return err
+		}
+		if s.config.TLS.Enabled {
+			return s.httpServer.ServeTLS(listener, s.config.TLS.CertFile, s.config.TLS.KeyFile)
+		}
+		return s.httpServer.Serve(listener)
+	}
+
 	if s.config.TLS.Enabled {
 		return s.httpServer.ListenAndServeTLS(s.config.TLS.CertFile, s.config.TLS.KeyFile)
 	}
 	return s.httpServer.ListenAndServe()
 }
 
+// unixSocketMode restricts the socket to the gateway's user and group, so
+// sidecars share access through group membership
+const unixSocketMode = 0660
+
+// listenUnix listens on a Unix domain socket at path. A stale socket left by
+// an unclean exit is replaced, but a socket another process still serves on
+// or a non-socket file is an error. The listener unlinks the socket when it
+// is closed, which Shutdown does.
+func listenUnix(path string) (net.Listener, error) {
+	if info, err := os.Lstat(path); err == nil {
+		if info.Mode()&os.ModeSocket == 0 {
+			return nil, fmt.Errorf("cannot listen on %s: file exists and is not a socket", path)
+		}
+		if conn, err := net.Dial("unix", path); err == nil {
+			conn.Close()
+			return nil, fmt.Errorf("cannot listen on %s: socket is in use", path)
+		}
+		if err := os.Remove(path); err != nil {
+			return nil, fmt.Errorf("failed to remove stale socket %s: %w", path, err)
+		}
+	}
+
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %s: %w", path, err)
+	}
+	if err := os.Chmod(path, unixSocketMode); err != nil {
+		listener.Close()
+		return nil, fmt.Errorf("failed to set permissions on %s: %w", path, err)
+	}
+	return listener, nil
+}
+
 // Shutdown gracefully shuts down the server
 func (s *Server) Shutdown(ctx context.Context) error {
 	// Stop workflow manager sweeper
@@ -241,7 +503,115 @@ func (s *Server) Shutdown(ctx context.Context) error {
 		s.workflow.Stop()
 	}
 
-	return s.httpServer.Shutdown(ctx)
+	// Stop sampling storage capacity
+	if s.capacity != nil {
+		s.capacity.Stop()
+	}
+
+	// Stop reconciling message statuses
+	if s.reconciler != nil {
+		s.reconciler.Stop()
+	}
+
+	// Stop retrying held recipients
+	if s.heldRetrier != nil {
+		s.heldRetrier.Stop()
+	}
+
+	// Stop checking for idle agents
+	if s.idleAgents != nil {
+		s.idleAgents.Stop()
+	}
+
+	// End event streams, whose hijacked connections Shutdown does not wait for
+	s.events.Close()
+
+	// Stop accepting mail; messages already accepted finish with the others below
+	if s.smtp != nil {
+		s.smtp.Stop()
+	}
+
+	if err := s.httpServer.Shutdown(ctx); err != nil {
+		return err
+	}
+
+	// No request adds to the write buffer anymore; store what storage takes
+	if s.writeBuffer != nil {
+		s.writeBuffer.Stop()
+	}
+
+	// Let messages accepted with Prefer: respond-async finish delivering
+	if waiter, ok := s.processor.(interface{ Wait() }); ok {
+		done := make(chan struct{})
+		go func() {
+			waiter.Wait()
+			close(done)
+		}()
+		select {
+		case <-done:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	// Deliveries are done, so nothing produces to Kafka anymore
+	if s.kafka != nil {
+		if err := s.kafka.Close(); err != nil {
+			return fmt.Errorf("failed to close kafka producer: %w", err)
+		}
+	}
+
+	// Flush metrics sinks that buffer, such as StatsD
+	if closer, ok := s.metrics.(io.Closer); ok {
+		if err := closer.Close(); err != nil {
+			return fmt.Errorf("failed to close metrics: %w", err)
+		}
+	}
+	return nil
+}
+
+// clusterWorkerID names this gateway in delivery leases, from the
+// configuration or else the host name and process ID
+func clusterWorkerID(cfg config.ClusterConfig) string {
+	if cfg.WorkerID != "" {
+		return cfg.WorkerID
+	}
+	host, err := os.Hostname()
+	if err != nil || host == "" {
+		host = "agentry"
+	}
+	return fmt.Sprintf("%s-%d", host, os.Getpid())
+}
+
+// seedSchemas registers the schemas in dir. A schema that cannot be seeded
+// is logged and skipped rather than stopping the gateway from starting.
+func seedSchemas(manager *schema.Manager, dir string, validator *validation.Validator, logger *logging.Logger) {
+	checkDefaults := func(sch *schema.Schema) error {
+		if sch.Defaults == nil || sch.Defaults.Coordination == nil {
+			return nil
+		}
+		if err := validator.ValidateCoordination(sch.Defaults.Coordination); err != nil {
+			return fmt.Errorf("invalid schema defaults: %w", err)
+		}
+		return nil
+	}
+
+	results, err := manager.SeedFromDir(context.Background(), dir, checkDefaults)
+	if err != nil {
+		logger.WithField("seed_dir", dir).Error("Failed to seed schemas", err)
+	}
+	for _, result := range results {
+		entry := logger.WithFields(map[string]interface{}{
+			"file":      result.File,
+			"schema_id": result.SchemaID,
+			"action":    result.Action,
+		})
+		if result.Err != nil {
+			entry.Error("Failed to seed schema", result.Err)
+			continue
+		}
+		entry.Info("Seeded schema")
+	}
 }
 
 // GetRouter returns the Gin router for testing purposes
@@ -249,13 +619,25 @@ func (s *Server) GetRouter() *gin.Engine {
 	return s.router
 }
 
+// redactionConfig returns the logging redaction rules with the configured
+// API key headers added; they are credentials too, even when renamed from
+// the defaults
+func redactionConfig(cfg *config.Config) config.RedactionConfig {
+	redaction := cfg.Logging.Redaction
+	redaction.Headers = append(append([]string{}, redaction.Headers...),
+		cfg.Auth.APIKeyHeader, cfg.Auth.AdminAPIKeyHeader)
+	return redaction
+}
+
 // setupMiddleware configures middleware for the server
 func (s *Server) setupMiddleware() {
 	// Recovery middleware
 	s.router.Use(gin.Recovery())
 
 	// Logging middleware
-	s.router.Use(middleware.Logger(s.config.Logging))
+	logCfg := s.config.Logging
+	logCfg.Redaction = redactionConfig(s.config)
+	s.router.Use(middleware.Logger(logCfg, s.logger.Writer()))
 
 	// CORS middleware
 	s.router.Use(middleware.CORS())
@@ -263,9 +645,18 @@ func (s *Server) setupMiddleware() {
 	// Request ID middleware
 	s.router.Use(middleware.RequestID())
 
+	// API version negotiation middleware
+	s.router.Use(middleware.APIVersion())
+
+	// Concurrency limiting middleware (if configured); probes stay answerable,
+	// and event streams are capped by their own subscriber limit
+	if s.concurrency != nil {
+		s.router.Use(middleware.ConcurrencyLimit(s.concurrency, "/health", "/ready", "/v1/admin/events"))
+	}
+
 	// Rate limiting middleware (if configured)
-	if s.config.Auth.RequireAuth {
-		s.router.Use(middleware.RateLimit())
+	if s.rateLimiter != nil {
+		s.router.Use(middleware.RateLimit(s.rateLimiter))
 	}
 
 	// Authentication middleware (if required)
@@ -273,9 +664,22 @@ func (s *Server) setupMiddleware() {
 		s.router.Use(middleware.Auth(s.config.Auth))
 	}
 
+	// Replay protection for signed requests (if enabled)
+	if s.nonces != nil {
+		s.router.Use(middleware.ReplayProtection(s.nonces, s.config.Auth.Replay.MaxSkew))
+	}
+
+	// Test-only discovery override; rejects the header when disabled
+	s.router.Use(middleware.DiscoveryOverride(s.config.DNS.DiscoveryOverride))
+
 	// Request size limit middleware
 	s.router.Use(middleware.RequestSizeLimit(s.config.Message.MaxSize))
 
+	// Gateway request signatures, binding replay headers to the body
+	if s.signatures != nil {
+		s.router.Use(s.requestSignature())
+	}
+
 	// Security headers middleware
 	s.router.Use(middleware.SecurityHeaders())
 }
@@ -296,7 +700,7 @@ func (s *Server) setupRoutes() {
 		v1.POST("/messages", server.withRequestMetrics(func(c *gin.Context) { server.handleSendMessage(c) }))
 		v1.GET("/messages/:id", server.withRequestMetrics(func(c *gin.Context) { server.handleGetMessage(c) }))
 		v1.GET("/messages/:id/status", server.withRequestMetrics(func(c *gin.Context) { server.handleGetMessageStatus(c) }))
-		v1.GET("/messages", server.withRequestMetrics(func(c *gin.Context) { server.handleListMessages(c) }))
+		v1.GET("/messages/by-key/:key", server.withRequestMetrics(func(c *gin.Context) { server.handleGetMessageStatusByKey(c) }))
 
 		// Discovery endpoints (public)
 		v1.GET("/capabilities/:domain", server.withRequestMetrics(func(c *gin.Context) { server.handleGetCapabilities(c) }))
@@ -306,6 +710,7 @@ func (s *Server) setupRoutes() {
 		{
 			discoveryGroup.GET("/agents", server.withRequestMetrics(func(c *gin.Context) { server.handleDiscoverAgents(c) }))
 			discoveryGroup.GET("/agents/:domain", server.withRequestMetrics(func(c *gin.Context) { server.handleDiscoverAgentsByDomain(c) }))
+			discoveryGroup.GET("/agents/:domain/:address/schemas", server.withRequestMetrics(func(c *gin.Context) { server.handleDiscoverAgentSchemas(c) }))
 		}
 
 		// Inbox endpoints (agent protected - these use agent API keys, not admin keys)
@@ -319,16 +724,44 @@ func (s *Server) setupRoutes() {
 			// Agent management endpoints
 			admin.POST("/agents", server.withRequestMetrics(func(c *gin.Context) { server.handleRegisterAgent(c) }))
 			admin.DELETE("/agents/:address", server.withRequestMetrics(func(c *gin.Context) { server.handleUnregisterAgent(c) }))
+			admin.DELETE("/agents/:address/inbox", server.withRequestMetrics(func(c *gin.Context) { server.handleDrainInbox(c) }))
 			admin.GET("/agents", server.withRequestMetrics(func(c *gin.Context) { server.handleListAgents(c) }))
+			admin.GET("/agents/idle", server.withRequestMetrics(func(c *gin.Context) { server.handleListIdleAgents(c) }))
+			admin.GET("/agents/:address", server.withRequestMetrics(func(c *gin.Context) { server.handleGetAgent(c) }))
+			admin.POST("/agents/:address/tokens", server.withRequestMetrics(func(c *gin.Context) { server.handleMintInboxToken(c) }))
+
+			// Data export endpoints
+			admin.GET("/export", server.withRequestMetrics(func(c *gin.Context) { server.handleExportMessages(c) }))
+
+			// Message endpoints
+			admin.GET("/messages", server.withRequestMetrics(func(c *gin.Context) { server.handleListMessages(c) }))
+			admin.POST("/messages/:id/resend", server.withRequestMetrics(func(c *gin.Context) { server.handleResendMessage(c) }))
+			admin.GET("/messages/:id/raw", server.withRequestMetrics(func(c *gin.Context) { server.handleGetRawRequest(c) }))
+			admin.POST("/messages/:id/validate", server.withRequestMetrics(func(c *gin.Context) { server.handleValidateStoredMessage(c) }))
+			admin.GET("/errors", server.withRequestMetrics(func(c *gin.Context) { server.handleListDeliveryErrors(c) }))
+			admin.GET("/inbox/pending", server.withRequestMetrics(func(c *gin.Context) { server.handleListPendingInbox(c) }))
+			admin.POST("/reconcile", server.withRequestMetrics(func(c *gin.Context) { server.handleReconcileStatuses(c) }))
+			admin.GET("/discovery/:domain", server.withRequestMetrics(func(c *gin.Context) { server.handleInspectDiscovery(c) }))
+			admin.GET("/config", server.withRequestMetrics(func(c *gin.Context) { server.handleGetConfig(c) }))
+			// Streams run until the admin disconnects, so request metrics would only skew latencies
+			admin.GET("/events", func(c *gin.Context) { server.handleStreamEvents(c) })
+
+			// Rate limit buckets
+			admin.GET("/ratelimits", server.withRequestMetrics(func(c *gin.Context) { server.handleListRateLimits(c) }))
+			admin.DELETE("/ratelimits/:key", server.withRequestMetrics(func(c *gin.Context) { server.handleResetRateLimit(c) }))
 
 			// Schema management endpoints
 			admin.POST("/schemas", server.withRequestMetrics(func(c *gin.Context) { server.handleRegisterSchema(c) }))
 			admin.GET("/schemas", server.withRequestMetrics(func(c *gin.Context) { server.handleListSchemas(c) }))
 			admin.GET("/schemas/:id", server.withRequestMetrics(func(c *gin.Context) { server.handleGetSchema(c) }))
+			admin.GET("/schemas/:id/:entity/latest", server.withRequestMetrics(func(c *gin.Context) { server.handleGetLatestSchema(c) }))
 			admin.PUT("/schemas/:id", server.withRequestMetrics(func(c *gin.Context) { server.handleUpdateSchema(c) }))
 			admin.DELETE("/schemas/:id", server.withRequestMetrics(func(c *gin.Context) { server.handleDeleteSchema(c) }))
 			admin.POST("/schemas/:id/validate", server.withRequestMetrics(func(c *gin.Context) { server.handleValidateSchema(c) }))
+			admin.POST("/schemas/:id/revalidate", server.withRequestMetrics(func(c *gin.Context) { server.handleRevalidateSchema(c) }))
 			admin.GET("/schemas/stats", server.withRequestMetrics(func(c *gin.Context) { server.handleSchemaStats(c) }))
+			admin.POST("/schemas/infer", server.withRequestMetrics(func(c *gin.Context) { server.handleInferSchema(c) }))
+			admin.POST("/schemas/reload", server.withRequestMetrics(func(c *gin.Context) { server.handleReloadSchemas(c) }))
 		}
 	}
 
@@ -339,24 +772,34 @@ func (s *Server) setupRoutes() {
 
 // createTLSConfig creates TLS configuration
 func (s *Server) createTLSConfig() (*tls.Config, error) {
+	minVersion, err := s.config.TLS.MinTLSVersion()
+	if err != nil {
+		return nil, err
+	}
+	// Nil leaves Go's secure defaults in place
+	cipherSuites, err := s.config.TLS.CipherSuiteIDs()
+	if err != nil {
+		return nil, fmt.Errorf("invalid TLS cipher suites: %w", err)
+	}
+
 	tlsConfig := &tls.Config{
-		MinVersion: tls.VersionTLS13, // Default to TLS 1.3
-		CipherSuites: []uint16{
-			tls.TLS_AES_256_GCM_SHA384,
-			tls.TLS_AES_128_GCM_SHA256,
-			tls.TLS_CHACHA20_POLY1305_SHA256,
-		},
-		PreferServerCipherSuites: true,
+		MinVersion:   minVersion,
+		CipherSuites: cipherSuites,
 	}
 
-	// Set minimum TLS version based on configuration
-	switch s.config.TLS.MinVersion {
-	case "1.2":
-		tlsConfig.MinVersion = tls.VersionTLS12
-	case "1.3":
-		tlsConfig.MinVersion = tls.VersionTLS13
-	default:
-		tlsConfig.MinVersion = tls.VersionTLS13
+	// Client certificates are optional, but when presented they must chain to
+	// the configured CA so the domain auth method can trust them
+	if s.config.TLS.ClientCAFile != "" {
+		caPEM, err := os.ReadFile(s.config.TLS.ClientCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read client CA file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(caPEM) {
+			return nil, fmt.Errorf("no certificates found in client CA file: %s", s.config.TLS.ClientCAFile)
+		}
+		tlsConfig.ClientCAs = pool
+		tlsConfig.ClientAuth = tls.VerifyClientCertIfGiven
 	}
 
 	return tlsConfig, nil
@@ -371,7 +814,7 @@ func (s *Server) handleHealth(c *gin.Context) {
 		statusCode = http.StatusServiceUnavailable
 	}
 
-	c.JSON(statusCode, health)
+	writeResponse(c, statusCode, health)
 }
 
 // handleReady handles readiness check requests (readiness probe)
@@ -383,7 +826,7 @@ func (s *Server) handleReady(c *gin.Context) {
 		statusCode = http.StatusServiceUnavailable
 	}
 
-	c.JSON(statusCode, readiness)
+	writeResponse(c, statusCode, readiness)
 }
 
 // handleMetrics handles metrics requests
@@ -420,6 +863,7 @@ type ReadinessStatus struct {
 	Timestamp    time.Time         `json:"timestamp"`
 	Version      string            `json:"version"`
 	Dependencies map[string]string `json:"dependencies"`
+	Warnings     []string          `json:"warnings,omitempty"`
 }
 
 // checkHealth performs basic health checks (liveness)
@@ -541,9 +985,24 @@ func (s *Server) checkReadiness() ReadinessStatus {
 		dependencies["validator"] = "not_initialized"
 	}
 
+	// Check storage capacity against the configured high watermarks
+	var warnings []string
+	if s.capacity != nil {
+		warnings = s.capacity.Exceeded()
+		if len(warnings) > 0 {
+			dependencies["storage_capacity"] = "degraded"
+		} else {
+			dependencies["storage_capacity"] = "ready"
+		}
+	}
+
 	status := "ready"
 	if !ready {
 		status = "not_ready"
+	} else if len(warnings) > 0 {
+		// Fail the probe so load balancers shed load before storage is full
+		status = "degraded"
+		ready = false
 	}
 
 	return ReadinessStatus{
@@ -552,5 +1011,6 @@ func (s *Server) checkReadiness() ReadinessStatus {
 		Timestamp:    time.Now().UTC(),
 		Version:      "1.0",
 		Dependencies: dependencies,
+		Warnings:     warnings,
 	}
 }
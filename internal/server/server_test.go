@@ -17,24 +17,33 @@
 package server
 
 import (
+	"bytes"
 	"context"
+	"crypto/tls"
 	"encoding/json"
 	"fmt"
+	"net"
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"path/filepath"
+	"reflect"
 	"strings"
 	"testing"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/ugorji/go/codec"
 
 	"github.com/amtp-protocol/agentry/internal/agents"
 	"github.com/amtp-protocol/agentry/internal/config"
 	"github.com/amtp-protocol/agentry/internal/errors"
+	"github.com/amtp-protocol/agentry/internal/logging"
+	"github.com/amtp-protocol/agentry/internal/middleware"
 	"github.com/amtp-protocol/agentry/internal/schema"
 	"github.com/amtp-protocol/agentry/internal/storage"
 	"github.com/amtp-protocol/agentry/internal/types"
+	"github.com/amtp-protocol/agentry/pkg/uuid"
 )
 
 // Test Server creation with different configurations
@@ -123,6 +132,115 @@ func TestNew_Success(t *testing.T) {
 	}
 }
 
+func TestStart_UnixSocket(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	socketPath := filepath.Join(t.TempDir(), "agentry.sock")
+	cfg := &config.Config{
+		Server: config.ServerConfig{
+			Address: "unix:" + socketPath,
+			Domain:  "test.example.com",
+		},
+		Message: config.MessageConfig{
+			MaxSize: 10485760,
+		},
+		DNS: config.DNSConfig{
+			MockMode: true,
+			CacheTTL: 5 * time.Minute,
+		},
+	}
+
+	server, err := New(cfg)
+	if err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+
+	errCh := make(chan error, 1)
+	go func() { errCh <- server.Start() }()
+
+	client := &http.Client{
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				var d net.Dialer
+				return d.DialContext(ctx, "unix", socketPath)
+			},
+		},
+	}
+
+	var resp *http.Response
+	for i := 0; i < 50; i++ {
+		if resp, err = client.Get("http://localhost/health"); err == nil {
+			break
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	if err != nil {
+		t.Fatalf("Failed to reach server over unix socket: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected status %d, got %d", http.StatusOK, resp.StatusCode)
+	}
+
+	info, err := os.Stat(socketPath)
+	if err != nil {
+		t.Fatalf("Expected socket file to exist: %v", err)
+	}
+	if perm := info.Mode().Perm(); perm != unixSocketMode {
+		t.Errorf("Expected socket permissions %o, got %o", unixSocketMode, perm)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := server.Shutdown(ctx); err != nil {
+		t.Fatalf("Shutdown failed: %v", err)
+	}
+	if err := <-errCh; err != http.ErrServerClosed {
+		t.Errorf("Expected ErrServerClosed, got %v", err)
+	}
+	if _, err := os.Stat(socketPath); !os.IsNotExist(err) {
+		t.Errorf("Expected socket file to be removed on shutdown, got %v", err)
+	}
+}
+
+func TestListenUnix_ExistingFile(t *testing.T) {
+	dir := t.TempDir()
+
+	// A regular file must never be removed to make way for the socket
+	regular := filepath.Join(dir, "not-a-socket")
+	if err := os.WriteFile(regular, []byte("data"), 0600); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	if _, err := listenUnix(regular); err == nil {
+		t.Error("Expected error when path is a regular file")
+	}
+
+	// A live socket belongs to another process
+	live := filepath.Join(dir, "live.sock")
+	listener, err := listenUnix(live)
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer listener.Close()
+	if _, err := listenUnix(live); err == nil {
+		t.Error("Expected error when socket is in use")
+	}
+
+	// A stale socket is replaced
+	stale := filepath.Join(dir, "stale.sock")
+	staleListener, err := net.Listen("unix", stale)
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	staleListener.(*net.UnixListener).SetUnlinkOnClose(false)
+	staleListener.Close()
+	replaced, err := listenUnix(stale)
+	if err != nil {
+		t.Fatalf("Expected stale socket to be replaced, got %v", err)
+	}
+	replaced.Close()
+}
+
 // Test server creation with metrics enabled
 func TestNew_WithMetrics(t *testing.T) {
 	gin.SetMode(gin.TestMode)
@@ -172,6 +290,22 @@ func TestNew_WithMetrics(t *testing.T) {
 	}
 }
 
+func TestNew_InvalidAdminKeyFile(t *testing.T) {
+	keyFile := filepath.Join(t.TempDir(), "admin-keys.yaml")
+	if err := os.WriteFile(keyFile, []byte("keys:\n  - name: ci\n    key: ci-key\n    scopes: [everything]\n"), 0600); err != nil {
+		t.Fatalf("Failed to write admin key file: %v", err)
+	}
+
+	cfg := &config.Config{
+		Server: config.ServerConfig{Address: ":8080", Domain: "test.example.com"},
+		DNS:    config.DNSConfig{MockMode: true},
+		Auth:   config.AuthConfig{AdminKeyFile: keyFile},
+	}
+	if _, err := New(cfg); err == nil || !strings.Contains(err.Error(), "invalid admin key file") {
+		t.Errorf("Expected an invalid admin key file error, got %v", err)
+	}
+}
+
 // Test server creation with schema configuration
 func TestNew_WithSchema(t *testing.T) {
 	gin.SetMode(gin.TestMode)
@@ -543,6 +677,10 @@ func TestRespondWithError(t *testing.T) {
 	if response.Error.Details["field"] != "value" {
 		t.Errorf("Expected details field 'value', got %v", response.Error.Details["field"])
 	}
+
+	if response.APIVersion != types.APIVersion {
+		t.Errorf("Expected API version %s, got %s", types.APIVersion, response.APIVersion)
+	}
 }
 
 func TestRespondWithAMTPError(t *testing.T) {
@@ -623,6 +761,249 @@ func TestRespondWithSuccess(t *testing.T) {
 	if response["data"] != "test_data" {
 		t.Errorf("Expected data 'test_data', got %v", response["data"])
 	}
+
+	if response["api_version"] != types.APIVersion {
+		t.Errorf("Expected api_version %s, got %v", types.APIVersion, response["api_version"])
+	}
+}
+
+func TestResponsesCarryAPIVersion(t *testing.T) {
+	server := createTestServer()
+
+	tests := []struct {
+		method, path, body string
+	}{
+		{"GET", "/health", ""},
+		{"GET", "/ready", ""},
+		{"GET", "/v1/capabilities/localhost", ""},
+		{"POST", "/v1/admin/schemas/infer", `{"examples":[{"order_id":"o-1"}]}`},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		server.router.ServeHTTP(w, req)
+
+		if !strings.HasPrefix(w.Body.String(), `{"api_version":"1",`) {
+			t.Errorf("%s %s: expected api_version in response, got %d %s", tt.method, tt.path, w.Code, w.Body.String())
+		}
+	}
+}
+
+func TestWithAPIVersion(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		expected string
+	}{
+		{"object", `{"message_id":"m1","status":"queued"}`, `{"api_version":"1","message_id":"m1","status":"queued"}`},
+		{"empty object", `{}`, `{"api_version":"1"}`},
+		{"array", `[{"id":1}]`, `[{"id":1}]`},
+		{"null", `null`, `null`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := string(withAPIVersion([]byte(tt.body), "1")); got != tt.expected {
+				t.Errorf("Expected %s, got %s", tt.expected, got)
+			}
+		})
+	}
+}
+
+// decodeMsgpack decodes a msgpack response body with string keys and values
+func decodeMsgpack(t testing.TB, body []byte) map[string]interface{} {
+	t.Helper()
+	handle := &codec.MsgpackHandle{}
+	handle.RawToString = true
+	handle.MapType = reflect.TypeOf(map[string]interface{}(nil))
+	var out map[string]interface{}
+	if err := codec.NewDecoderBytes(body, handle).Decode(&out); err != nil {
+		t.Fatalf("Failed to decode msgpack: %v", err)
+	}
+	return out
+}
+
+func TestRespondWithSuccess_Msgpack(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	server := createTestServer()
+	router := gin.New()
+	timestamp := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	router.GET("/test", func(c *gin.Context) {
+		c.Set("start_time", time.Now())
+		server.respondWithSuccess(c, http.StatusOK, gin.H{
+			"recipient": "agent@localhost",
+			"messages": []*types.Message{{
+				MessageID:  "m1",
+				Sender:     "alice@example.com",
+				Recipients: []string{"agent@localhost"},
+				Timestamp:  timestamp,
+				Payload:    json.RawMessage(`{"order":42}`),
+			}},
+			"count": 1,
+		})
+	})
+
+	tests := []struct {
+		accept  string
+		msgpack bool
+	}{
+		{"application/msgpack", true},
+		{"application/x-msgpack", true},
+		{"application/msgpack, application/json", true},
+		{"application/json, application/msgpack", false},
+		{"*/*", false},
+		{"", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.accept, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/test", nil)
+			if tt.accept != "" {
+				req.Header.Set("Accept", tt.accept)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Header().Get("Vary") != "Accept" {
+				t.Errorf("Expected Vary: Accept, got %q", w.Header().Get("Vary"))
+			}
+			contentType := w.Header().Get("Content-Type")
+			if !tt.msgpack {
+				if !strings.HasPrefix(contentType, "application/json") {
+					t.Errorf("Expected JSON, got %s", contentType)
+				}
+				return
+			}
+			if contentType != "application/msgpack" {
+				t.Fatalf("Expected msgpack, got %s", contentType)
+			}
+
+			response := decodeMsgpack(t, w.Body.Bytes())
+			if response["api_version"] != types.APIVersion || response["recipient"] != "agent@localhost" {
+				t.Errorf("Unexpected response: %v", response)
+			}
+			messages, ok := response["messages"].([]interface{})
+			if !ok || len(messages) != 1 {
+				t.Fatalf("Expected one message, got %v", response["messages"])
+			}
+			message := messages[0].(map[string]interface{})
+			if message["message_id"] != "m1" || message["sender"] != "alice@example.com" {
+				t.Errorf("Expected JSON field names, got %v", message)
+			}
+			if _, ok := message["subject"]; ok {
+				t.Error("Expected omitempty fields to be left out")
+			}
+			if ts, ok := message["timestamp"].(time.Time); !ok || !ts.Equal(timestamp) {
+				t.Errorf("Expected a msgpack timestamp, got %#v", message["timestamp"])
+			}
+			if payload := fmt.Sprint(message["payload"]); payload != `{"order":42}` {
+				t.Errorf("Expected the payload as JSON text, got %s", payload)
+			}
+		})
+	}
+}
+
+func TestWithAPIVersionMsgpack(t *testing.T) {
+	encode := func(v interface{}) []byte {
+		var out []byte
+		codec.NewEncoderBytes(&out, msgpackHandle).MustEncode(v)
+		return out
+	}
+	entries := func(n int) map[string]int {
+		m := make(map[string]int, n)
+		for i := 0; i < n; i++ {
+			m[fmt.Sprintf("k%d", i)] = i
+		}
+		return m
+	}
+
+	for _, n := range []int{0, 1, 14, 15, 16, 70000} {
+		t.Run(fmt.Sprintf("%d entries", n), func(t *testing.T) {
+			body := withAPIVersionMsgpack(encode(entries(n)), "1")
+			decoded := decodeMsgpack(t, body)
+			if len(decoded) != n+1 || decoded["api_version"] != "1" {
+				t.Errorf("Expected %d entries with api_version, got %d (%v)", n+1, len(decoded), decoded["api_version"])
+			}
+		})
+	}
+
+	array := encode([]int{1, 2})
+	if got := withAPIVersionMsgpack(array, "1"); !bytes.Equal(got, array) {
+		t.Errorf("Expected arrays to be unchanged, got %x", got)
+	}
+}
+
+// BenchmarkInboxEncoding compares response encoding cost for a large inbox
+func BenchmarkInboxEncoding(b *testing.B) {
+	gin.SetMode(gin.TestMode)
+	server := createTestServer()
+
+	messages := make([]*types.Message, 500)
+	for i := range messages {
+		messages[i] = &types.Message{
+			Version:        "1.0",
+			MessageID:      fmt.Sprintf("01890a5d-ac96-774b-bcce-b302099a%04d", i),
+			IdempotencyKey: fmt.Sprintf("01890a5d-ac96-774b-bcce-b302099b%04d", i),
+			Timestamp:      time.Now().UTC(),
+			Sender:         "orders@example.com",
+			Recipients:     []string{"agent@localhost"},
+			Subject:        "Order update",
+			Schema:         "agntcy:commerce.order.v1",
+			Headers:        map[string]interface{}{"priority": "high", "trace_id": fmt.Sprint(i)},
+			Payload:        json.RawMessage(`{"order_id":"ORD-12345","status":"shipped","items":[{"sku":"A1","qty":2},{"sku":"B2","qty":1}],"total":129.99}`),
+		}
+	}
+	data := gin.H{"recipient": "agent@localhost", "messages": messages, "count": len(messages)}
+
+	for _, accept := range []string{"application/json", "application/msgpack"} {
+		b.Run(accept, func(b *testing.B) {
+			req := httptest.NewRequest("GET", "/v1/inbox/agent@localhost", nil)
+			req.Header.Set("Accept", accept)
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				w := httptest.NewRecorder()
+				c, _ := gin.CreateTestContext(w)
+				c.Request = req
+				server.respondWithSuccess(c, http.StatusOK, data)
+				b.SetBytes(int64(w.Body.Len()))
+			}
+		})
+	}
+}
+
+func TestRespondWithSuccess_AcceptVersion(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	server := createTestServer()
+	router := gin.New()
+	router.Use(middleware.APIVersion())
+	router.GET("/test", func(c *gin.Context) {
+		c.Set("start_time", time.Now())
+		server.respondWithSuccess(c, http.StatusOK, types.SendMessageResponse{MessageID: "m1", Status: "queued"})
+	})
+
+	req := httptest.NewRequest("GET", "/test", nil)
+	req.Header.Set("Accept-Version", types.APIVersion)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d", http.StatusOK, w.Code)
+	}
+
+	// Typed clients ignore the added field
+	var response types.SendMessageResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to unmarshal response: %v", err)
+	}
+	if response.MessageID != "m1" || response.Status != "queued" {
+		t.Errorf("Unexpected response: %+v", response)
+	}
+	if !strings.HasPrefix(w.Body.String(), `{"api_version":"1",`) {
+		t.Errorf("Expected api_version in response, got %s", w.Body.String())
+	}
 }
 
 func TestGetErrorType(t *testing.T) {
@@ -706,8 +1087,10 @@ func TestSetupMiddleware(t *testing.T) {
 	}
 
 	server := &Server{
-		config: cfg,
-		router: gin.New(),
+		config:     cfg,
+		messageIDs: uuid.V7,
+		router:     gin.New(),
+		logger:     logging.NewNoopLogger(),
 	}
 
 	// This should not panic
@@ -741,7 +1124,7 @@ func TestSetupRoutes(t *testing.T) {
 		{"POST", "/v1/messages"},
 		{"GET", "/v1/messages/:id"},
 		{"GET", "/v1/messages/:id/status"},
-		{"GET", "/v1/messages"},
+		{"GET", "/v1/admin/messages"},
 		{"GET", "/v1/capabilities/:domain"},
 		{"GET", "/v1/discovery/agents"},
 		{"GET", "/v1/discovery/agents/:domain"},
@@ -783,3 +1166,26 @@ func TestSetupRoutes(t *testing.T) {
 		})
 	}
 }
+
+func TestCreateTLSConfig(t *testing.T) {
+	s := &Server{config: &config.Config{TLS: config.TLSConfig{
+		MinVersion:   config.TLSVersion12,
+		CipherSuites: []string{"TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256"},
+	}}}
+
+	tlsConfig, err := s.createTLSConfig()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if tlsConfig.MinVersion != tls.VersionTLS12 {
+		t.Errorf("Expected TLS 1.2 minimum, got %x", tlsConfig.MinVersion)
+	}
+	if len(tlsConfig.CipherSuites) != 1 || tlsConfig.CipherSuites[0] != tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256 {
+		t.Errorf("Unexpected cipher suites: %v", tlsConfig.CipherSuites)
+	}
+
+	s.config.TLS.CipherSuites = []string{"TLS_MADE_UP"}
+	if _, err := s.createTLSConfig(); err == nil {
+		t.Error("Expected an error for an unknown cipher suite")
+	}
+}
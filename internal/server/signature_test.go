@@ -0,0 +1,242 @@
+/*
+ * Copyright 2026 Cong Wang
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package server
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/amtp-protocol/agentry/internal/config"
+	"github.com/amtp-protocol/agentry/internal/discovery"
+	"github.com/amtp-protocol/agentry/internal/middleware"
+	"github.com/amtp-protocol/agentry/internal/signing"
+	"github.com/amtp-protocol/agentry/internal/types"
+	"github.com/amtp-protocol/agentry/pkg/uuid"
+)
+
+// relayBody builds the body a remote gateway relays message with
+func relayBody(t *testing.T, message *types.Message) []byte {
+	t.Helper()
+	body, err := json.Marshal(map[string]interface{}{
+		"version":         message.Version,
+		"message_id":      message.MessageID,
+		"idempotency_key": message.IdempotencyKey,
+		"timestamp":       message.Timestamp.Format(time.RFC3339),
+		"sender":          message.Sender,
+		"recipients":      message.Recipients,
+		"subject":         message.Subject,
+		"headers":         message.Headers,
+		"payload":         message.Payload,
+		"signature":       message.Signature,
+	})
+	if err != nil {
+		t.Fatalf("Failed to marshal relayed message: %v", err)
+	}
+	return body
+}
+
+func TestHandleSendMessage_Signature(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatalf("Failed to marshal public key: %v", err)
+	}
+	keys := discovery.NewMockDiscovery(map[string]string{
+		"k1._amtpkey.remote.example": "v=amtpkey1; k=ec; p=" + base64.StdEncoding.EncodeToString(der),
+	}, time.Minute)
+
+	newRemoteMessage := func(sender string) *types.Message {
+		messageID, _ := uuid.GenerateV7()
+		idempotencyKey, _ := uuid.GenerateV4()
+		return &types.Message{
+			Version:        "1.0",
+			MessageID:      messageID,
+			IdempotencyKey: idempotencyKey,
+			Timestamp:      time.Now(),
+			Sender:         sender,
+			Recipients:     []string{"agent@localhost"},
+			Subject:        "Signed",
+			Headers:        map[string]interface{}{"priority": "high"},
+			Payload:        json.RawMessage(`{"message": "Hello"}`),
+		}
+	}
+	signed := func() *types.Message {
+		message := newRemoteMessage("agent@remote.example")
+		if err := signing.Sign(message, key, "k1"); err != nil {
+			t.Fatalf("Failed to sign: %v", err)
+		}
+		return message
+	}
+	tampered := signed()
+	tampered.Subject = "Tampered"
+	mixedCase := newRemoteMessage("agent@remote.example")
+	mixedCase.Recipients = []string{"Agent@LocalHost", "agent@localhost"}
+	if err := signing.Sign(mixedCase, key, "k1"); err != nil {
+		t.Fatalf("Failed to sign: %v", err)
+	}
+	unknownKey := signed()
+	unknownKey.Signature.KeyID = "k2"
+	schemes, err := types.NewAddressSchemes([]string{types.AddressSchemeURN})
+	if err != nil {
+		t.Fatalf("Failed to create address schemes: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		mode    string
+		noKeys  bool
+		message *types.Message
+		status  int
+	}{
+		{"valid signature", config.SignatureVerificationOptional, false, signed(), http.StatusOK},
+		{"valid signature over mixed-case and repeated recipients", config.SignatureVerificationOptional, false, mixedCase, http.StatusOK},
+		{"tampered message", config.SignatureVerificationOptional, false, tampered, http.StatusUnauthorized},
+		{"unknown key", config.SignatureVerificationOptional, false, unknownKey, http.StatusUnauthorized},
+		{"unsigned when optional", config.SignatureVerificationOptional, false, newRemoteMessage("agent@remote.example"), http.StatusOK},
+		{"unsigned when required", config.SignatureVerificationRequired, false, newRemoteMessage("agent@remote.example"), http.StatusUnauthorized},
+		{"unsigned local sender when required", config.SignatureVerificationRequired, false, newRemoteMessage("agent@localhost"), http.StatusOK},
+		{"unsigned local URN sender when required", config.SignatureVerificationRequired, false, newRemoteMessage("urn:agent:1234"), http.StatusOK},
+		{"tampered message when off", config.SignatureVerificationOff, false, tampered, http.StatusOK},
+		{"signed without key lookup when required", config.SignatureVerificationRequired, true, signed(), http.StatusUnauthorized},
+		{"local sender without key lookup when required", config.SignatureVerificationRequired, true, newRemoteMessage("agent@localhost"), http.StatusOK},
+		{"local URN sender without key lookup when required", config.SignatureVerificationRequired, true, newRemoteMessage("urn:agent:1234"), http.StatusOK},
+		{"signed without key lookup when optional", config.SignatureVerificationOptional, true, signed(), http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := createTestServer()
+			server.config.Auth.SignatureVerification = tt.mode
+			server.addresses = schemes
+			server.validator.SetAddressSchemes(schemes)
+			if tt.mode != config.SignatureVerificationOff && !tt.noKeys {
+				server.signatures = signing.NewVerifier(keys)
+			}
+
+			req := httptest.NewRequest("POST", "/v1/messages", bytes.NewReader(relayBody(t, tt.message)))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			server.router.ServeHTTP(w, req)
+
+			if w.Code != tt.status {
+				t.Fatalf("Expected status %d, got %d: %s", tt.status, w.Code, w.Body.String())
+			}
+			if tt.status == http.StatusUnauthorized && !strings.Contains(w.Body.String(), "INVALID_SIGNATURE") {
+				t.Errorf("Expected INVALID_SIGNATURE, got %s", w.Body.String())
+			}
+			if tt.message == mixedCase && !strings.Contains(w.Body.String(), "SIGNATURE_REMOVED") {
+				t.Errorf("Expected the signature over the addresses as sent to be removed, got %s", w.Body.String())
+			}
+		})
+	}
+}
+
+func TestHandleSendMessage_RequestSignature(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatalf("Failed to marshal public key: %v", err)
+	}
+	keys := discovery.NewMockDiscovery(map[string]string{
+		"k1._amtpkey.remote.example": "v=amtpkey1; k=ec; p=" + base64.StdEncoding.EncodeToString(der),
+		"k1._amtpkey.other.example":  "v=amtpkey1; k=ec; p=" + base64.StdEncoding.EncodeToString(der),
+	}, time.Minute)
+
+	server := createTestServer()
+	server.config.Auth.SignatureVerification = config.SignatureVerificationRequired
+	server.config.Auth.Replay = config.ReplayConfig{Enabled: true, MaxSkew: 5 * time.Minute, NonceCacheSize: 100}
+	server.signatures = signing.NewVerifier(keys)
+	server.nonces = middleware.NewNonceCache(100)
+	server.router = gin.New()
+	server.setupMiddleware()
+	server.setupRoutes()
+
+	signer := "remote.example"
+	send := func(body []byte, nonce, signedNonce string, sign bool) *httptest.ResponseRecorder {
+		timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+		req := httptest.NewRequest("POST", "/v1/messages", bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set(signing.TimestampHeader, timestamp)
+		req.Header.Set(signing.NonceHeader, nonce)
+		if sign {
+			header, err := signing.SignRequest(key, signer, "k1", "POST", "/v1/messages", timestamp, signedNonce, body)
+			if err != nil {
+				t.Fatalf("Failed to sign request: %v", err)
+			}
+			req.Header.Set(signing.RequestSignatureHeader, header)
+		}
+		w := httptest.NewRecorder()
+		server.router.ServeHTTP(w, req)
+		return w
+	}
+	message := func() []byte {
+		messageID, _ := uuid.GenerateV7()
+		idempotencyKey, _ := uuid.GenerateV4()
+		m := &types.Message{
+			Version:        "1.0",
+			MessageID:      messageID,
+			IdempotencyKey: idempotencyKey,
+			Timestamp:      time.Now(),
+			Sender:         "agent@remote.example",
+			Recipients:     []string{"agent@localhost"},
+			Payload:        json.RawMessage(`{"message": "Hello"}`),
+		}
+		if err := signing.Sign(m, key, "k1"); err != nil {
+			t.Fatalf("Failed to sign: %v", err)
+		}
+		return relayBody(t, m)
+	}
+
+	if w := send(message(), "n1", "n1", true); w.Code != http.StatusOK {
+		t.Fatalf("Expected a signed request to be accepted, got %d: %s", w.Code, w.Body.String())
+	}
+
+	// Replaying a captured request with a fresh nonce breaks its signature,
+	// and leaving the signature off is refused outright
+	body := message()
+	if w := send(body, "n2-replayed", "n2", true); w.Code != http.StatusUnauthorized || !strings.Contains(w.Body.String(), "INVALID_SIGNATURE") {
+		t.Errorf("Expected a swapped nonce to fail the request signature, got %d: %s", w.Code, w.Body.String())
+	}
+	if w := send(body, "n3", "", false); w.Code != http.StatusUnauthorized || !strings.Contains(w.Body.String(), "INVALID_SIGNATURE") {
+		t.Errorf("Expected an unsigned relayed request to be refused, got %d: %s", w.Code, w.Body.String())
+	}
+
+	// Another domain cannot re-sign a captured relay as its own
+	signer = "other.example"
+	if w := send(body, "n4", "n4", true); w.Code != http.StatusUnauthorized || !strings.Contains(w.Body.String(), "not signed by the sender's domain") {
+		t.Errorf("Expected a request signed by another domain to be refused, got %d: %s", w.Code, w.Body.String())
+	}
+}
@@ -0,0 +1,556 @@
+/*
+ * Copyright 2026 Cong Wang
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/http"
+	"net/mail"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/amtp-protocol/agentry/internal/config"
+	"github.com/amtp-protocol/agentry/internal/logging"
+	"github.com/amtp-protocol/agentry/internal/processing"
+	"github.com/amtp-protocol/agentry/internal/types"
+)
+
+// maxSMTPLineLength is the longest command line accepted, per RFC 5321
+const maxSMTPLineLength = 1000
+
+// errMessageRejected marks a bridged message that can never be accepted, as
+// opposed to a temporary processing failure the SMTP client should retry
+var errMessageRejected = errors.New("message rejected")
+
+// smtpBridge is an EXPERIMENTAL minimal SMTP receiver that turns inbound mail
+// for the gateway's domain into AMTP messages. It implements only what a
+// sending MTA needs to hand over mail: no AUTH, no STARTTLS and no relaying.
+type smtpBridge struct {
+	config  config.SMTPBridgeConfig
+	domain  string
+	maxSize int64
+	submit  func(ctx context.Context, req *types.SendMessageRequest) (string, error)
+	logger  *logging.Logger
+
+	mu       sync.Mutex
+	listener net.Listener
+	conns    map[net.Conn]struct{}
+	closed   bool
+	wg       sync.WaitGroup
+}
+
+// newSMTPBridge returns nil unless the bridge is enabled
+func newSMTPBridge(s *Server) *smtpBridge {
+	if !s.config.SMTP.Enabled {
+		return nil
+	}
+	return &smtpBridge{
+		config:  s.config.SMTP,
+		domain:  s.config.Server.Domain,
+		maxSize: s.config.Message.MaxSize,
+		submit:  s.submitBridgedMessage,
+		logger:  s.logger.WithComponent("smtp_bridge"),
+		conns:   make(map[net.Conn]struct{}),
+	}
+}
+
+// Start listens on the configured address and serves connections in the
+// background
+func (b *smtpBridge) Start() error {
+	listener, err := net.Listen("tcp", b.config.Address)
+	if err != nil {
+		return fmt.Errorf("failed to start SMTP bridge: %w", err)
+	}
+
+	b.mu.Lock()
+	b.listener = listener
+	b.mu.Unlock()
+
+	b.logger.Warnf("EXPERIMENTAL SMTP bridge listening on %s", listener.Addr())
+	b.wg.Add(1)
+	go b.acceptLoop(listener)
+	return nil
+}
+
+// Addr returns the listening address, or nil before Start
+func (b *smtpBridge) Addr() net.Addr {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.listener == nil {
+		return nil
+	}
+	return b.listener.Addr()
+}
+
+// Stop closes the listener and every open session and waits for them to end
+func (b *smtpBridge) Stop() {
+	b.mu.Lock()
+	b.closed = true
+	if b.listener != nil {
+		b.listener.Close()
+	}
+	for conn := range b.conns {
+		conn.Close()
+	}
+	b.mu.Unlock()
+
+	b.wg.Wait()
+}
+
+func (b *smtpBridge) acceptLoop(listener net.Listener) {
+	defer b.wg.Done()
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			b.mu.Lock()
+			closed := b.closed
+			b.mu.Unlock()
+			if !closed {
+				b.logger.Error("SMTP bridge stopped accepting connections", err)
+			}
+			return
+		}
+
+		b.mu.Lock()
+		if b.closed {
+			b.mu.Unlock()
+			conn.Close()
+			return
+		}
+		b.conns[conn] = struct{}{}
+		b.wg.Add(1)
+		b.mu.Unlock()
+
+		go func() {
+			defer b.wg.Done()
+			defer func() {
+				b.mu.Lock()
+				delete(b.conns, conn)
+				b.mu.Unlock()
+				conn.Close()
+			}()
+			b.serve(conn)
+		}()
+	}
+}
+
+// smtpSession is the state of one mail transaction
+type smtpSession struct {
+	greeted    bool
+	from       string
+	hasFrom    bool
+	recipients []string
+}
+
+func (ss *smtpSession) reset() {
+	ss.from, ss.hasFrom, ss.recipients = "", false, nil
+}
+
+// serve runs the SMTP dialogue on conn until the client quits or errs
+func (b *smtpBridge) serve(conn net.Conn) {
+	r := bufio.NewReaderSize(conn, maxSMTPLineLength)
+	w := bufio.NewWriter(conn)
+	reply := func(lines ...string) bool {
+		for _, line := range lines {
+			w.WriteString(line + "\r\n")
+		}
+		return w.Flush() == nil
+	}
+
+	session := &smtpSession{}
+	if !reply("220 " + b.domain + " ESMTP AMTP bridge (experimental)") {
+		return
+	}
+
+	for {
+		b.setDeadline(conn)
+		line, err := readSMTPLine(r)
+		if errors.Is(err, bufio.ErrBufferFull) {
+			if !reply("500 5.5.2 Line too long") {
+				return
+			}
+			continue
+		}
+		if err != nil {
+			return
+		}
+
+		verb, arg, _ := strings.Cut(line, " ")
+		arg = strings.TrimSpace(arg)
+		switch strings.ToUpper(verb) {
+		case "HELO":
+			session.reset()
+			session.greeted = true
+			if !reply("250 " + b.domain) {
+				return
+			}
+		case "EHLO":
+			session.reset()
+			session.greeted = true
+			if !reply("250-"+b.domain, "250-8BITMIME", "250 SIZE "+strconv.FormatInt(b.maxSize, 10)) {
+				return
+			}
+		case "MAIL":
+			if !reply(b.mail(session, arg)) {
+				return
+			}
+		case "RCPT":
+			if !reply(b.rcpt(session, arg)) {
+				return
+			}
+		case "DATA":
+			if len(session.recipients) == 0 {
+				if !reply("503 5.5.1 Need RCPT command first") {
+					return
+				}
+				continue
+			}
+			if !reply("354 End data with <CR><LF>.<CR><LF>") {
+				return
+			}
+			status, ok := b.data(conn, r, session)
+			session.reset()
+			if !ok || !reply(status) {
+				return
+			}
+		case "RSET":
+			session.reset()
+			if !reply("250 2.0.0 OK") {
+				return
+			}
+		case "NOOP":
+			if !reply("250 2.0.0 OK") {
+				return
+			}
+		case "VRFY":
+			if !reply("252 2.5.0 Cannot verify user") {
+				return
+			}
+		case "QUIT":
+			reply("221 2.0.0 Bye")
+			return
+		default:
+			if !reply("502 5.5.2 Command not recognized") {
+				return
+			}
+		}
+	}
+}
+
+func (b *smtpBridge) setDeadline(conn net.Conn) {
+	if b.config.Timeout > 0 {
+		conn.SetDeadline(time.Now().Add(b.config.Timeout))
+	}
+}
+
+// mail handles MAIL FROM and returns the reply. The null reverse path is
+// accepted; the From header then names the sender.
+func (b *smtpBridge) mail(session *smtpSession, arg string) string {
+	if !session.greeted {
+		return "503 5.5.1 Send HELO or EHLO first"
+	}
+	if session.hasFrom {
+		return "503 5.5.1 Sender already specified"
+	}
+	from, params, ok := parseSMTPPath(arg, "FROM:")
+	if !ok {
+		return "501 5.5.4 Syntax: MAIL FROM:<address>"
+	}
+	for _, param := range strings.Fields(params) {
+		if value, found := strings.CutPrefix(strings.ToUpper(param), "SIZE="); found {
+			if size, err := strconv.ParseInt(value, 10, 64); err == nil && size > b.maxSize {
+				return "552 5.3.4 Message size exceeds fixed limit"
+			}
+		}
+	}
+	session.from, session.hasFrom = from, true
+	return "250 2.1.0 OK"
+}
+
+// rcpt handles RCPT TO and returns the reply. Only the gateway's own domain
+// is accepted since the bridge is not a relay.
+func (b *smtpBridge) rcpt(session *smtpSession, arg string) string {
+	if !session.hasFrom {
+		return "503 5.5.1 Need MAIL command first"
+	}
+	to, _, ok := parseSMTPPath(arg, "TO:")
+	if !ok || to == "" {
+		return "501 5.5.4 Syntax: RCPT TO:<address>"
+	}
+	at := strings.LastIndex(to, "@")
+	if at < 1 || !strings.EqualFold(to[at+1:], b.domain) {
+		return "550 5.7.1 Relaying not permitted"
+	}
+	if b.config.MaxRecipients > 0 && len(session.recipients) >= b.config.MaxRecipients {
+		return "452 4.5.3 Too many recipients"
+	}
+	session.recipients = append(session.recipients, to)
+	return "250 2.1.5 OK"
+}
+
+// data reads the message content and submits it, returning the reply and
+// whether the connection is still usable
+func (b *smtpBridge) data(conn net.Conn, r *bufio.Reader, session *smtpSession) (string, bool) {
+	b.setDeadline(conn)
+	dot := textproto.NewReader(r).DotReader()
+	content, err := io.ReadAll(io.LimitReader(dot, b.maxSize+1))
+	if err != nil {
+		return "", false
+	}
+	if int64(len(content)) > b.maxSize {
+		if _, err := io.Copy(io.Discard, dot); err != nil {
+			return "", false
+		}
+		return "552 5.3.4 Message size exceeds fixed limit", true
+	}
+
+	req, err := mailToSendRequest(session.from, session.recipients, content)
+	if err != nil {
+		return "554 5.6.0 " + smtpReplyText(err), true
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), defaultProcessingTimeout)
+	defer cancel()
+	messageID, err := b.submit(ctx, req)
+	if errors.Is(err, errMessageRejected) {
+		b.logger.Warnf("SMTP bridge rejected message from %s: %v", req.Sender, err)
+		return "554 5.6.0 " + smtpReplyText(err), true
+	}
+	if err != nil {
+		b.logger.Error("SMTP bridge failed to process message", err)
+		return "451 4.3.0 Message processing failed, try again later", true
+	}
+	return "250 2.0.0 OK queued as " + messageID, true
+}
+
+// smtpReplyText keeps an error on the single line an SMTP reply allows
+func smtpReplyText(err error) string {
+	return strings.NewReplacer("\r", " ", "\n", " ").Replace(err.Error())
+}
+
+// readSMTPLine reads one CRLF or LF terminated line. A line longer than the
+// reader's buffer is discarded and reported as bufio.ErrBufferFull.
+func readSMTPLine(r *bufio.Reader) (string, error) {
+	line, err := r.ReadSlice('\n')
+	if errors.Is(err, bufio.ErrBufferFull) {
+		for errors.Is(err, bufio.ErrBufferFull) {
+			_, err = r.ReadSlice('\n')
+		}
+		if err != nil {
+			return "", err
+		}
+		return "", bufio.ErrBufferFull
+	}
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(line), "\r\n"), nil
+}
+
+// parseSMTPPath parses "FROM:<address> params" style arguments
+func parseSMTPPath(arg, prefix string) (string, string, bool) {
+	if len(arg) < len(prefix) || !strings.EqualFold(arg[:len(prefix)], prefix) {
+		return "", "", false
+	}
+	rest := strings.TrimSpace(arg[len(prefix):])
+	if !strings.HasPrefix(rest, "<") {
+		return "", "", false
+	}
+	end := strings.Index(rest, ">")
+	if end < 0 {
+		return "", "", false
+	}
+	return rest[1:end], strings.TrimSpace(rest[end+1:]), true
+}
+
+// mailToSendRequest maps a received mail onto a send request. The sender is
+// the From header, falling back to the envelope sender; the recipients are
+// the envelope recipients. The decoded body, with LF line endings, becomes the
+// payload: as JSON if it is a JSON document or as a JSON string otherwise.
+func mailToSendRequest(envelopeFrom string, recipients []string, content []byte) (*types.SendMessageRequest, error) {
+	msg, err := mail.ReadMessage(bytes.NewReader(content))
+	if err != nil {
+		return nil, fmt.Errorf("malformed message: %w", err)
+	}
+
+	sender := envelopeFrom
+	if from := msg.Header.Get("From"); from != "" {
+		addr, err := mail.ParseAddress(from)
+		if err != nil {
+			return nil, fmt.Errorf("invalid From header: %w", err)
+		}
+		sender = addr.Address
+	}
+	if sender == "" {
+		return nil, fmt.Errorf("message has no sender")
+	}
+
+	subject, err := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+	if err != nil {
+		subject = msg.Header.Get("Subject")
+	}
+
+	body, err := decodeMailBody(textproto.MIMEHeader(msg.Header), msg.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode body: %w", err)
+	}
+	body = bytes.ReplaceAll(body, []byte("\r\n"), []byte("\n"))
+
+	payload := json.RawMessage(bytes.TrimSpace(body))
+	if len(payload) == 0 || (payload[0] != '{' && payload[0] != '[') || !json.Valid(payload) {
+		payload, err = json.Marshal(string(body))
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode body: %w", err)
+		}
+	}
+
+	req := &types.SendMessageRequest{
+		Sender:     sender,
+		Recipients: recipients,
+		Subject:    subject,
+		Payload:    payload,
+	}
+	if messageID := msg.Header.Get("Message-Id"); messageID != "" {
+		// Kept so a retransmitted mail derives the same idempotency key
+		req.Headers = map[string]interface{}{"smtp_message_id": messageID}
+	}
+	return req, nil
+}
+
+// decodeMailBody returns the text of a mail body, undoing its transfer
+// encoding. For multipart mail the first text/plain part is used, or the
+// first text part if there is no plain one.
+func decodeMailBody(header textproto.MIMEHeader, body io.Reader) ([]byte, error) {
+	mediaType, params, err := mime.ParseMediaType(header.Get("Content-Type"))
+	if err != nil {
+		mediaType = "text/plain"
+	}
+
+	if !strings.HasPrefix(mediaType, "multipart/") {
+		return io.ReadAll(transferDecoder(header.Get("Content-Transfer-Encoding"), body))
+	}
+
+	text, _, err := decodeMultipart(body, params["boundary"])
+	if err != nil {
+		return nil, err
+	}
+	if text == nil {
+		return nil, fmt.Errorf("no text part in multipart message")
+	}
+	return text, nil
+}
+
+// decodeMultipart returns the first text/plain part of a multipart body,
+// descending into nested multipart parts such as a multipart/alternative
+// inside multipart/mixed, or else its first other text part. plain reports
+// whether the text came from a text/plain part.
+func decodeMultipart(body io.Reader, boundary string) (text []byte, plain bool, err error) {
+	parts := multipart.NewReader(body, boundary)
+	var fallback []byte
+	for {
+		part, err := parts.NextPart()
+		if err == io.EOF {
+			return fallback, false, nil
+		}
+		if err != nil {
+			return nil, false, err
+		}
+		partType, partParams, err := mime.ParseMediaType(part.Header.Get("Content-Type"))
+		if err != nil {
+			partType = "text/plain"
+		}
+		encoding := part.Header.Get("Content-Transfer-Encoding")
+
+		var partText []byte
+		switch {
+		case strings.HasPrefix(partType, "multipart/"):
+			nested, nestedPlain, err := decodeMultipart(transferDecoder(encoding, part), partParams["boundary"])
+			if err != nil {
+				return nil, false, err
+			}
+			if nestedPlain {
+				return nested, true, nil
+			}
+			partText = nested
+		case strings.HasPrefix(partType, "text/"):
+			partText, err = io.ReadAll(transferDecoder(encoding, part))
+			if err != nil {
+				return nil, false, err
+			}
+			if partType == "text/plain" {
+				return partText, true, nil
+			}
+		}
+		if fallback == nil {
+			fallback = partText
+		}
+	}
+}
+
+// transferDecoder undoes a Content-Transfer-Encoding. The multipart reader
+// already decodes quoted-printable parts and drops their header.
+func transferDecoder(encoding string, r io.Reader) io.Reader {
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case "base64":
+		return base64.NewDecoder(base64.StdEncoding, r)
+	case "quoted-printable":
+		return quotedprintable.NewReader(r)
+	default:
+		return r
+	}
+}
+
+// submitBridgedMessage runs a message received by the SMTP bridge through
+// the checks of an HTTP send and hands it to the processor like a Prefer:
+// respond-async send. Sends refused for good wrap errMessageRejected.
+func (s *Server) submitBridgedMessage(ctx context.Context, req *types.SendMessageRequest) (string, error) {
+	message, _, err := s.acceptMessage(ctx, req, sendOrigin{})
+	if err != nil {
+		var refused *sendError
+		if errors.As(err, &refused) && refused.status < http.StatusInternalServerError {
+			return "", fmt.Errorf("%w: %v", errMessageRejected, err)
+		}
+		return "", err
+	}
+
+	maxRetries, retryDelay := s.retryPolicy(req)
+	result, err := s.processor.ProcessMessage(ctx, message, processing.ProcessingOptions{
+		ImmediatePath: true,
+		Timeout:       s.processingTimeout(ctx),
+		MaxRetries:    maxRetries,
+		RetryDelay:    retryDelay,
+		Async:         true,
+	})
+	if err != nil {
+		return "", err
+	}
+	return result.MessageID, nil
+}
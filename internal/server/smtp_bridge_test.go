@@ -0,0 +1,311 @@
+/*
+ * Copyright 2026 Cong Wang
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/smtp"
+	"net/textproto"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/amtp-protocol/agentry/internal/config"
+	"github.com/amtp-protocol/agentry/internal/processing"
+	"github.com/amtp-protocol/agentry/internal/types"
+)
+
+// recordingProcessor hands every processed message to a channel
+type recordingProcessor struct {
+	messages chan *types.Message
+	options  chan processing.ProcessingOptions
+}
+
+func (p *recordingProcessor) ProcessMessage(ctx context.Context, message *types.Message, options processing.ProcessingOptions) (*processing.ProcessingResult, error) {
+	p.messages <- message
+	p.options <- options
+	return &processing.ProcessingResult{MessageID: message.MessageID, Status: types.StatusQueued}, nil
+}
+
+func startTestSMTPBridge(t *testing.T) (*smtpBridge, *recordingProcessor) {
+	t.Helper()
+	server := createTestServer()
+	server.config.SMTP = config.SMTPBridgeConfig{
+		Enabled:       true,
+		Address:       "127.0.0.1:0",
+		MaxRecipients: 2,
+		Timeout:       5 * time.Second,
+	}
+	processor := &recordingProcessor{
+		messages: make(chan *types.Message, 1),
+		options:  make(chan processing.ProcessingOptions, 1),
+	}
+	server.processor = processor
+
+	bridge := newSMTPBridge(server)
+	if err := bridge.Start(); err != nil {
+		t.Fatalf("Failed to start SMTP bridge: %v", err)
+	}
+	t.Cleanup(bridge.Stop)
+	return bridge, processor
+}
+
+func TestSMTPBridge_Disabled(t *testing.T) {
+	if bridge := newSMTPBridge(createTestServer()); bridge != nil {
+		t.Error("Expected no SMTP bridge unless it is enabled")
+	}
+}
+
+func TestSMTPBridge_Session(t *testing.T) {
+	bridge, processor := startTestSMTPBridge(t)
+
+	client, err := smtp.Dial(bridge.Addr().String())
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer client.Close()
+
+	if err := client.Hello("mta.example.com"); err != nil {
+		t.Fatalf("EHLO failed: %v", err)
+	}
+	if err := client.Mail("bounce@example.com"); err != nil {
+		t.Fatalf("MAIL failed: %v", err)
+	}
+	if err := client.Rcpt("agent@elsewhere.com"); err == nil || !strings.Contains(err.Error(), "550") {
+		t.Errorf("Expected relaying to be refused, got %v", err)
+	}
+	for _, rcpt := range []string{"agent@localhost", "other@LOCALHOST"} {
+		if err := client.Rcpt(rcpt); err != nil {
+			t.Fatalf("RCPT %s failed: %v", rcpt, err)
+		}
+	}
+	if err := client.Rcpt("third@localhost"); err == nil || !strings.Contains(err.Error(), "452") {
+		t.Errorf("Expected the recipient limit to apply, got %v", err)
+	}
+
+	w, err := client.Data()
+	if err != nil {
+		t.Fatalf("DATA failed: %v", err)
+	}
+	w.Write([]byte("From: Alice <alice@example.com>\r\n" +
+		"Subject: =?utf-8?q?Caf=C3=A9_order?=\r\n" +
+		"Message-ID: <1234@example.com>\r\n" +
+		"\r\n" +
+		".starts with a dot\r\n"))
+	if err := w.Close(); err != nil {
+		t.Fatalf("Expected the message to be accepted: %v", err)
+	}
+
+	message := <-processor.messages
+	options := <-processor.options
+	if message.Sender != "alice@example.com" {
+		t.Errorf("Expected the From header as sender, got %s", message.Sender)
+	}
+	if len(message.Recipients) != 2 || message.Recipients[0] != "agent@localhost" {
+		t.Errorf("Expected the envelope recipients, got %v", message.Recipients)
+	}
+	if message.Subject != "Café order" {
+		t.Errorf("Expected the decoded subject, got %q", message.Subject)
+	}
+	if string(message.Payload) != `".starts with a dot\n"` {
+		t.Errorf("Expected the unstuffed body as a string payload, got %s", message.Payload)
+	}
+	if message.Headers["smtp_message_id"] != "<1234@example.com>" {
+		t.Errorf("Expected the SMTP message ID header, got %v", message.Headers)
+	}
+	if !options.Async || !options.ImmediatePath {
+		t.Errorf("Expected asynchronous immediate processing, got %+v", options)
+	}
+
+	// A mail without any sender can never be accepted
+	if err := client.Mail(""); err != nil {
+		t.Fatalf("MAIL with null sender failed: %v", err)
+	}
+	if err := client.Rcpt("agent@localhost"); err != nil {
+		t.Fatalf("RCPT failed: %v", err)
+	}
+	w, err = client.Data()
+	if err != nil {
+		t.Fatalf("DATA failed: %v", err)
+	}
+	w.Write([]byte("Subject: no sender\r\n\r\nhello\r\n"))
+	if err := w.Close(); err == nil || !strings.Contains(err.Error(), "554") {
+		t.Errorf("Expected a message without sender to be rejected, got %v", err)
+	}
+
+	if err := client.Quit(); err != nil {
+		t.Errorf("QUIT failed: %v", err)
+	}
+}
+
+func TestSubmitBridgedMessage_SendChecks(t *testing.T) {
+	server := createTestServer()
+	server.config.Auth.SignatureVerification = config.SignatureVerificationRequired
+	newRequest := func(sender string) *types.SendMessageRequest {
+		return &types.SendMessageRequest{
+			Sender:     sender,
+			Recipients: []string{"Agent@LocalHost"},
+			Payload:    json.RawMessage(`{"text":"hi"}`),
+		}
+	}
+
+	// Mail cannot carry a signature, so other domains are refused for good
+	_, err := server.submitBridgedMessage(context.Background(), newRequest("mailer@example.com"))
+	if !errors.Is(err, errMessageRejected) || !strings.Contains(err.Error(), "Signatures from other domains") {
+		t.Errorf("Expected unsigned mail from another domain to be rejected, got %v", err)
+	}
+	if _, err := server.submitBridgedMessage(context.Background(), newRequest("mailer@localhost")); err != nil {
+		t.Errorf("Expected mail from the local domain to be accepted, got %v", err)
+	}
+
+	// A schema that cannot be validated yet is a temporary failure
+	server.config.Message.SchemaUnavailable = config.SchemaUnavailableStrict
+	req := newRequest("mailer@localhost")
+	req.Schema = "agntcy:commerce.order.v1"
+	_, err = server.submitBridgedMessage(context.Background(), req)
+	if err == nil || errors.Is(err, errMessageRejected) {
+		t.Errorf("Expected an unavailable schema manager to be a temporary failure, got %v", err)
+	}
+}
+
+func TestSMTPBridge_CommandErrors(t *testing.T) {
+	bridge, _ := startTestSMTPBridge(t)
+
+	conn, err := textproto.Dial("tcp", bridge.Addr().String())
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer conn.Close()
+	if _, _, err := conn.ReadResponse(220); err != nil {
+		t.Fatalf("Expected greeting: %v", err)
+	}
+
+	tests := []struct {
+		command string
+		code    int
+	}{
+		{"MAIL FROM:<alice@example.com>", 503},
+		{"HELO mta.example.com", 250},
+		{"RCPT TO:<agent@localhost>", 503},
+		{"MAIL FROM:alice@example.com", 501},
+		{"MAIL FROM:<alice@example.com> SIZE=999999999999", 552},
+		{"MAIL FROM:<alice@example.com>", 250},
+		{"MAIL FROM:<alice@example.com>", 503},
+		{"DATA", 503},
+		{"RCPT TO:<" + strings.Repeat("a", maxSMTPLineLength) + "@localhost>", 500},
+		{"TURN", 502},
+		{"RSET", 250},
+		{"NOOP", 250},
+	}
+
+	for _, tt := range tests {
+		id, err := conn.Cmd("%s", tt.command)
+		if err != nil {
+			t.Fatalf("Failed to send %q: %v", tt.command, err)
+		}
+		conn.StartResponse(id)
+		code, _, _ := conn.ReadResponse(0)
+		conn.EndResponse(id)
+		if code != tt.code {
+			t.Errorf("%.40s: expected reply %d, got %d", tt.command, tt.code, code)
+		}
+	}
+}
+
+func TestMailToSendRequest(t *testing.T) {
+	tests := []struct {
+		name            string
+		content         string
+		expectedSender  string
+		expectedSubject string
+		expectedPayload string
+	}{
+		{
+			name:            "plain text",
+			content:         "From: bob@example.com\r\nSubject: Hi\r\n\r\nHello\r\n",
+			expectedSender:  "bob@example.com",
+			expectedSubject: "Hi",
+			expectedPayload: `"Hello\n"`,
+		},
+		{
+			name:            "JSON body",
+			content:         "From: bob@example.com\r\n\r\n{\"order\": 42}\r\n",
+			expectedSender:  "bob@example.com",
+			expectedPayload: `{"order": 42}`,
+		},
+		{
+			name:            "envelope sender without From header",
+			content:         "Content-Transfer-Encoding: base64\r\n\r\nSGVsbG8s\r\nIHdvcmxk\r\n",
+			expectedSender:  "envelope@example.com",
+			expectedPayload: `"Hello, world"`,
+		},
+		{
+			name: "multipart prefers text/plain",
+			content: "From: bob@example.com\r\n" +
+				"Content-Type: multipart/alternative; boundary=b1\r\n\r\n" +
+				"--b1\r\nContent-Type: text/html\r\n\r\n<p>Hi</p>\r\n" +
+				"--b1\r\nContent-Type: text/plain; charset=utf-8\r\nContent-Transfer-Encoding: quoted-printable\r\n\r\nCaf=C3=A9\r\n" +
+				"--b1--\r\n",
+			expectedSender:  "bob@example.com",
+			expectedPayload: `"Café"`,
+		},
+		{
+			name: "nested multipart with attachment",
+			content: "From: bob@example.com\r\n" +
+				"Content-Type: multipart/mixed; boundary=outer\r\n\r\n" +
+				"--outer\r\nContent-Type: multipart/alternative; boundary=inner\r\n\r\n" +
+				"--inner\r\nContent-Type: text/html\r\n\r\n<p>Hi</p>\r\n" +
+				"--inner\r\nContent-Type: text/plain\r\n\r\nHi\r\n" +
+				"--inner--\r\n" +
+				"--outer\r\nContent-Type: application/pdf\r\nContent-Transfer-Encoding: base64\r\n\r\nJVBERi0=\r\n" +
+				"--outer--\r\n",
+			expectedSender:  "bob@example.com",
+			expectedPayload: `"Hi"`,
+		},
+		{
+			name: "nested multipart without text/plain",
+			content: "From: bob@example.com\r\n" +
+				"Content-Type: multipart/mixed; boundary=outer\r\n\r\n" +
+				"--outer\r\nContent-Type: multipart/related; boundary=inner\r\n\r\n" +
+				"--inner\r\nContent-Type: text/html\r\n\r\n<p>Hi</p>\r\n" +
+				"--inner--\r\n" +
+				"--outer--\r\n",
+			expectedSender:  "bob@example.com",
+			expectedPayload: `"\u003cp\u003eHi\u003c/p\u003e"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := mailToSendRequest("envelope@example.com", []string{"agent@localhost"}, []byte(tt.content))
+			if err != nil {
+				t.Fatalf("mailToSendRequest failed: %v", err)
+			}
+			if req.Sender != tt.expectedSender {
+				t.Errorf("Expected sender %s, got %s", tt.expectedSender, req.Sender)
+			}
+			if req.Subject != tt.expectedSubject {
+				t.Errorf("Expected subject %q, got %q", tt.expectedSubject, req.Subject)
+			}
+			if string(req.Payload) != tt.expectedPayload {
+				t.Errorf("Expected payload %s, got %s", tt.expectedPayload, req.Payload)
+			}
+		})
+	}
+}
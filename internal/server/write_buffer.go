@@ -0,0 +1,191 @@
+/*
+ * Copyright 2026 Cong Wang
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package server
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/amtp-protocol/agentry/internal/config"
+	"github.com/amtp-protocol/agentry/internal/logging"
+	"github.com/amtp-protocol/agentry/internal/processing"
+	"github.com/amtp-protocol/agentry/internal/storage"
+	"github.com/amtp-protocol/agentry/internal/types"
+)
+
+// bufferedMessage is a sent message storage refused, with the options it
+// was sent with
+type bufferedMessage struct {
+	message *types.Message
+	options processing.ProcessingOptions
+}
+
+// writeBuffer holds sent messages in memory while storage refuses them and
+// processes them again, in the order they were sent, once it recovers. It
+// trades durability for availability: buffered messages are lost if the
+// gateway stops before storage recovers.
+type writeBuffer struct {
+	processor processing.MessageProcessorService
+	max       int
+	interval  time.Duration
+	logger    *logging.Logger
+
+	mu      sync.Mutex
+	pending []bufferedMessage
+	started bool
+
+	stopOnce sync.Once
+	stop     chan struct{}
+	done     chan struct{}
+}
+
+// newWriteBuffer returns nil unless the write buffer is enabled
+func newWriteBuffer(processor processing.MessageProcessorService, cfg config.StorageWriteBufferConfig, logger *logging.Logger) *writeBuffer {
+	if !cfg.Enabled || cfg.MaxMessages <= 0 || cfg.FlushInterval <= 0 {
+		return nil
+	}
+	return &writeBuffer{
+		processor: processor,
+		max:       cfg.MaxMessages,
+		interval:  cfg.FlushInterval,
+		logger:    logger,
+		stop:      make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+}
+
+// Add buffers message and returns the ID it is accepted under, or false
+// when the buffer is full. A message repeating the idempotency key of one
+// already buffered is not buffered twice; the earlier message's ID is
+// returned instead.
+func (wb *writeBuffer) Add(message *types.Message, options processing.ProcessingOptions) (string, bool) {
+	wb.mu.Lock()
+	defer wb.mu.Unlock()
+
+	if message.IdempotencyKey != "" {
+		for _, buffered := range wb.pending {
+			if buffered.message.IdempotencyKey == message.IdempotencyKey {
+				return buffered.message.MessageID, true
+			}
+		}
+	}
+	if len(wb.pending) >= wb.max {
+		return "", false
+	}
+
+	// Nobody waits for delivery once the message is flushed, and the
+	// request's deadline will be long gone
+	options.Async = true
+	options.Timeout = 0
+	wb.pending = append(wb.pending, bufferedMessage{message: message, options: options})
+	return message.MessageID, true
+}
+
+// Len returns the number of buffered messages
+func (wb *writeBuffer) Len() int {
+	wb.mu.Lock()
+	defer wb.mu.Unlock()
+	return len(wb.pending)
+}
+
+// Start flushes on every interval, and once more when stopped
+func (wb *writeBuffer) Start(ctx context.Context) {
+	wb.mu.Lock()
+	wb.started = true
+	wb.mu.Unlock()
+
+	go func() {
+		defer close(wb.done)
+
+		ticker := time.NewTicker(wb.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				wb.flush(ctx)
+			case <-wb.stop:
+				wb.flush(ctx)
+				return
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// Stop ends flushing after a last attempt and waits for it to finish.
+// Whatever storage still refuses then is lost.
+func (wb *writeBuffer) Stop() {
+	wb.stopOnce.Do(func() {
+		close(wb.stop)
+
+		wb.mu.Lock()
+		started := wb.started
+		wb.mu.Unlock()
+		if started {
+			<-wb.done
+		}
+
+		if n := wb.Len(); n > 0 {
+			wb.logger.Warnf("Dropping %d buffered messages that could not be stored", n)
+		}
+	})
+}
+
+// flush processes buffered messages in order, stopping at the first one
+// storage still cannot be reached for. A message that fails otherwise would
+// fail on every flush, so it is dropped. Only flush removes messages, so the
+// oldest one stays first while it is processed without the lock.
+func (wb *writeBuffer) flush(ctx context.Context) {
+	flushed := 0
+	for {
+		wb.mu.Lock()
+		if len(wb.pending) == 0 {
+			wb.mu.Unlock()
+			break
+		}
+		next := wb.pending[0]
+		wb.mu.Unlock()
+
+		_, err := wb.processor.ProcessMessage(ctx, next.message, next.options)
+		notStored := errors.Is(err, processing.ErrMessageNotStored)
+		if notStored && storage.IsUnavailable(err) {
+			break
+		}
+		switch {
+		case notStored:
+			wb.logger.Warnf("Dropping buffered message %s that storage refused: %v", next.message.MessageID, err)
+		case err != nil:
+			wb.logger.Warnf("Failed to process buffered message %s: %v", next.message.MessageID, err)
+		}
+
+		wb.mu.Lock()
+		wb.pending[0] = bufferedMessage{}
+		wb.pending = wb.pending[1:]
+		wb.mu.Unlock()
+		if !notStored {
+			flushed++
+		}
+	}
+
+	if flushed > 0 {
+		wb.logger.Infof("Stored %d buffered messages", flushed)
+	}
+}
@@ -0,0 +1,231 @@
+/*
+ * Copyright 2026 Cong Wang
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+
+	"github.com/amtp-protocol/agentry/internal/config"
+	"github.com/amtp-protocol/agentry/internal/processing"
+	"github.com/amtp-protocol/agentry/internal/types"
+)
+
+// errConnectionRefused is how storage fails while the database is down
+var errConnectionRefused = &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}
+
+// errUniqueViolation is how storage refuses a message it already has
+var errUniqueViolation = &pgconn.PgError{Code: "23505", Message: "duplicate key value violates unique constraint"}
+
+// failingProcessor fails the messages in errs and processes the others
+type failingProcessor struct {
+	*MockMessageProcessor
+	errs map[string]error
+}
+
+func (p *failingProcessor) ProcessMessage(ctx context.Context, message *types.Message, options processing.ProcessingOptions) (*processing.ProcessingResult, error) {
+	if err, ok := p.errs[message.MessageID]; ok {
+		return nil, err
+	}
+	return p.MockMessageProcessor.ProcessMessage(ctx, message, options)
+}
+
+func TestNewWriteBuffer_Off(t *testing.T) {
+	if wb := newWriteBuffer(NewMockMessageProcessor(), config.StorageWriteBufferConfig{MaxMessages: 10, FlushInterval: time.Second}, nil); wb != nil {
+		t.Error("Expected no write buffer unless enabled")
+	}
+}
+
+func TestWriteBuffer_StorageOutage(t *testing.T) {
+	server := createTestServer()
+	mockProcessor := server.processor.(*MockMessageProcessor)
+	server.writeBuffer = newWriteBuffer(mockProcessor, config.StorageWriteBufferConfig{
+		Enabled:       true,
+		MaxMessages:   2,
+		FlushInterval: time.Hour,
+	}, server.logger)
+
+	send := func(idempotencyKey string) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(types.SendMessageRequest{
+			Sender:         "test@example.com",
+			Recipients:     []string{"recipient@test.com"},
+			Payload:        json.RawMessage(`{"message": "hello"}`),
+			IdempotencyKey: idempotencyKey,
+		})
+		req := httptest.NewRequest("POST", "/v1/messages", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		server.router.ServeHTTP(w, req)
+		return w
+	}
+
+	// The database goes away
+	mockProcessor.processError = fmt.Errorf("%w: %w", processing.ErrMessageNotStored, errConnectionRefused)
+
+	var accepted []string
+	for _, key := range []string{"0b8f8a3e-4c4d-4b8e-9f6a-1d2c3b4a5e6f", "1c9e7b2d-5a6b-4c7d-8e9f-2a3b4c5d6e7f"} {
+		w := send(key)
+		if w.Code != http.StatusAccepted {
+			t.Fatalf("Expected status %d, got %d: %s", http.StatusAccepted, w.Code, w.Body.String())
+		}
+		var response types.SendMessageResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+			t.Fatalf("Failed to unmarshal response: %v", err)
+		}
+		if response.Status != "queued" || len(response.Recipients) != 1 || response.Recipients[0].Status != types.StatusQueued {
+			t.Errorf("Expected the message to be reported queued, got %+v", response)
+		}
+		accepted = append(accepted, response.MessageID)
+	}
+
+	// A retry of a buffered message takes no room
+	w := send("1c9e7b2d-5a6b-4c7d-8e9f-2a3b4c5d6e7f")
+	var retried types.SendMessageResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &retried); err != nil || w.Code != http.StatusAccepted || retried.MessageID != accepted[1] {
+		t.Errorf("Expected the retry to return message %s, got %d: %s", accepted[1], w.Code, w.Body.String())
+	}
+
+	if w := send(""); w.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected status %d with the buffer full, got %d", http.StatusServiceUnavailable, w.Code)
+	} else if w.Header().Get("Retry-After") == "" {
+		t.Error("Expected a Retry-After header")
+	}
+
+	// Flushing while storage is still down keeps everything
+	server.writeBuffer.flush(context.Background())
+	if n := server.writeBuffer.Len(); n != 2 {
+		t.Fatalf("Expected 2 buffered messages, got %d", n)
+	}
+
+	// The database recovers
+	mockProcessor.processError = nil
+	server.writeBuffer.flush(context.Background())
+	if n := server.writeBuffer.Len(); n != 0 {
+		t.Errorf("Expected the buffer to be empty, got %d messages", n)
+	}
+	for _, id := range accepted {
+		if _, ok := mockProcessor.messages[id]; !ok {
+			t.Errorf("Expected buffered message %s to be processed", id)
+		}
+	}
+	if mockProcessor.lastMessage.MessageID != accepted[1] {
+		t.Errorf("Expected buffered messages to be processed in order, last was %s", mockProcessor.lastMessage.MessageID)
+	}
+	if !mockProcessor.lastOptions.Async || mockProcessor.lastOptions.Timeout != 0 {
+		t.Errorf("Expected buffered messages to be delivered in the background, got %+v", mockProcessor.lastOptions)
+	}
+
+	if w := send(""); w.Code != http.StatusOK {
+		t.Errorf("Expected messages to be processed directly again, got %d", w.Code)
+	}
+}
+
+func TestHandleSendMessage_StorageOutageWithoutBuffer(t *testing.T) {
+	server := createTestServer()
+	server.processor.(*MockMessageProcessor).processError = fmt.Errorf("%w: %w", processing.ErrMessageNotStored, errConnectionRefused)
+
+	body, _ := json.Marshal(types.SendMessageRequest{
+		Sender:     "test@example.com",
+		Recipients: []string{"recipient@test.com"},
+		Payload:    json.RawMessage(`{"message": "hello"}`),
+	})
+	req := httptest.NewRequest("POST", "/v1/messages", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("Expected status %d, got %d", http.StatusInternalServerError, w.Code)
+	}
+}
+
+func TestHandleSendMessage_StorageRefusal(t *testing.T) {
+	server := createTestServer()
+	server.processor.(*MockMessageProcessor).processError = fmt.Errorf("%w: %w", processing.ErrMessageNotStored, errUniqueViolation)
+	server.writeBuffer = newWriteBuffer(server.processor, config.StorageWriteBufferConfig{
+		Enabled:       true,
+		MaxMessages:   2,
+		FlushInterval: time.Hour,
+	}, server.logger)
+
+	body, _ := json.Marshal(types.SendMessageRequest{
+		Sender:     "test@example.com",
+		Recipients: []string{"recipient@test.com"},
+		Payload:    json.RawMessage(`{"message": "hello"}`),
+	})
+	req := httptest.NewRequest("POST", "/v1/messages", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+
+	// Storage is up but refuses the message, which buffering cannot fix
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("Expected status %d, got %d", http.StatusInternalServerError, w.Code)
+	}
+	if n := server.writeBuffer.Len(); n != 0 {
+		t.Errorf("Expected nothing to be buffered, got %d messages", n)
+	}
+}
+
+func TestWriteBuffer_FlushDropsRefusedMessages(t *testing.T) {
+	server := createTestServer()
+	processor := &failingProcessor{MockMessageProcessor: server.processor.(*MockMessageProcessor), errs: map[string]error{}}
+	wb := newWriteBuffer(processor, config.StorageWriteBufferConfig{
+		Enabled:       true,
+		MaxMessages:   3,
+		FlushInterval: time.Hour,
+	}, server.logger)
+
+	ids := []string{
+		"01234567-89ab-7def-8123-456789abcde1",
+		"01234567-89ab-7def-8123-456789abcde2",
+		"01234567-89ab-7def-8123-456789abcde3",
+	}
+	for _, id := range ids {
+		if _, ok := wb.Add(&types.Message{MessageID: id, Recipients: []string{"recipient@test.com"}}, processing.ProcessingOptions{}); !ok {
+			t.Fatalf("Failed to buffer message %s", id)
+		}
+	}
+
+	// The first message is refused for good, the third while storage is down
+	processor.errs[ids[0]] = fmt.Errorf("%w: %w", processing.ErrMessageNotStored, errUniqueViolation)
+	processor.errs[ids[2]] = fmt.Errorf("%w: %w", processing.ErrMessageNotStored, errConnectionRefused)
+	wb.flush(context.Background())
+
+	if n := wb.Len(); n != 1 {
+		t.Fatalf("Expected only the message waiting for storage to stay buffered, got %d", n)
+	}
+	if _, ok := processor.messages[ids[1]]; !ok {
+		t.Errorf("Expected message %s after the refused one to be processed", ids[1])
+	}
+
+	delete(processor.errs, ids[2])
+	wb.flush(context.Background())
+	if n := wb.Len(); n != 0 {
+		t.Errorf("Expected the buffer to be empty, got %d messages", n)
+	}
+}
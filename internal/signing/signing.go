@@ -0,0 +1,334 @@
+/*
+ * Copyright 2026 Cong Wang
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package signing signs AMTP messages and verifies their signatures against
+// the sender domain's published key.
+package signing
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/amtp-protocol/agentry/internal/types"
+)
+
+// Signature algorithms, named as in JWS
+const (
+	AlgorithmRS256 = "RS256"
+	AlgorithmES256 = "ES256"
+)
+
+var (
+	// ErrUnsigned is returned when verifying a message without a signature
+	ErrUnsigned = errors.New("message is not signed")
+	// ErrInvalidSignature is returned when a signature does not verify
+	ErrInvalidSignature = errors.New("invalid signature")
+)
+
+// KeyResolver finds the public key a domain signs its messages with
+type KeyResolver interface {
+	DiscoverPublicKey(ctx context.Context, domain, keyID string) (crypto.PublicKey, error)
+}
+
+// canonicalMessage is the signed form of a message. Gateways relay a message
+// to each recipient separately, so a relayed message is signed for the one
+// recipient it carries; the timestamp is kept to the second precision it is
+// relayed with.
+type canonicalMessage struct {
+	Version        string                    `json:"version"`
+	MessageID      string                    `json:"message_id"`
+	IdempotencyKey string                    `json:"idempotency_key"`
+	Timestamp      string                    `json:"timestamp"`
+	Sender         string                    `json:"sender"`
+	Recipients     []string                  `json:"recipients"`
+	Subject        string                    `json:"subject,omitempty"`
+	Schema         string                    `json:"schema,omitempty"`
+	Coordination   *types.CoordinationConfig `json:"coordination,omitempty"`
+	Headers        map[string]interface{}    `json:"headers,omitempty"`
+	Payload        json.RawMessage           `json:"payload,omitempty"`
+	Attachments    []types.Attachment        `json:"attachments,omitempty"`
+	InReplyTo      string                    `json:"in_reply_to,omitempty"`
+	ResponseType   string                    `json:"response_type,omitempty"`
+	Encrypted      bool                      `json:"encrypted,omitempty"`
+}
+
+// Canonicalize returns the bytes a message signature covers: compact JSON of
+// the message without its signature, with object keys in a fixed order and
+// the timestamp in UTC RFC 3339 to the second
+func Canonicalize(message *types.Message) ([]byte, error) {
+	payload := message.Payload
+	if strings.TrimSpace(string(payload)) == "null" {
+		payload = nil
+	}
+
+	return json.Marshal(canonicalMessage{
+		Version:        message.Version,
+		MessageID:      message.MessageID,
+		IdempotencyKey: message.IdempotencyKey,
+		Timestamp:      message.Timestamp.UTC().Format(time.RFC3339),
+		Sender:         message.Sender,
+		Recipients:     message.Recipients,
+		Subject:        message.Subject,
+		Schema:         message.Schema,
+		Coordination:   message.Coordination,
+		Headers:        message.Headers,
+		Payload:        payload,
+		Attachments:    message.Attachments,
+		InReplyTo:      message.InReplyTo,
+		ResponseType:   message.ResponseType,
+		Encrypted:      message.Encrypted,
+	})
+}
+
+// Sign signs the canonical form of a message with key, which must be an RSA
+// or P-256 ECDSA private key, and sets the message's signature
+func Sign(message *types.Message, key crypto.Signer, keyID string) error {
+	canonical, err := Canonicalize(message)
+	if err != nil {
+		return fmt.Errorf("failed to canonicalize message: %w", err)
+	}
+	algorithm, value, err := signDigest(key, sha256.Sum256(canonical))
+	if err != nil {
+		return fmt.Errorf("failed to sign message: %w", err)
+	}
+
+	message.Signature = &types.MessageSignature{
+		Algorithm: algorithm,
+		KeyID:     keyID,
+		Value:     base64.RawURLEncoding.EncodeToString(value),
+	}
+	return nil
+}
+
+// signDigest signs a SHA-256 digest with key, which must be an RSA or P-256
+// ECDSA private key, and returns the JWS algorithm name with the signature
+func signDigest(key crypto.Signer, digest [sha256.Size]byte) (string, []byte, error) {
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		value, err := rsa.SignPKCS1v15(rand.Reader, k, crypto.SHA256, digest[:])
+		return AlgorithmRS256, value, err
+	case *ecdsa.PrivateKey:
+		if k.Curve != elliptic.P256() {
+			return "", nil, fmt.Errorf("ES256 requires a P-256 key")
+		}
+		r, s, err := ecdsa.Sign(rand.Reader, k, digest[:])
+		if err != nil {
+			return "", nil, err
+		}
+		// JWS encodes ES256 signatures as fixed-size R || S
+		value := make([]byte, 64)
+		r.FillBytes(value[:32])
+		s.FillBytes(value[32:])
+		return AlgorithmES256, value, nil
+	default:
+		return "", nil, fmt.Errorf("unsupported signing key type %T", key)
+	}
+}
+
+// LoadPrivateKey reads a PEM signing key: PKCS #8, PKCS #1 RSA or SEC 1 EC
+func LoadPrivateKey(path string) (crypto.Signer, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read signing key: %w", err)
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM block in %s", path)
+	}
+
+	var key interface{}
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		key, err = x509.ParseECPrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("unsupported PEM block %q in %s", block.Type, path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid signing key: %w", err)
+	}
+
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		return k, nil
+	case *ecdsa.PrivateKey:
+		if k.Curve != elliptic.P256() {
+			return nil, fmt.Errorf("EC signing keys must use P-256")
+		}
+		return k, nil
+	default:
+		return nil, fmt.Errorf("unsupported signing key type %T", key)
+	}
+}
+
+// VerifyWithKey checks a message's signature against key
+func VerifyWithKey(message *types.Message, key crypto.PublicKey) error {
+	sig := message.Signature
+	if sig == nil {
+		return ErrUnsigned
+	}
+
+	value, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(sig.Value, "="))
+	if err != nil {
+		return fmt.Errorf("%w: value is not base64url", ErrInvalidSignature)
+	}
+	canonical, err := Canonicalize(message)
+	if err != nil {
+		return fmt.Errorf("failed to canonicalize message: %w", err)
+	}
+	return verifyDigest(sig.Algorithm, value, key, sha256.Sum256(canonical), "message")
+}
+
+// verifyDigest checks a signature made with algorithm over the SHA-256
+// digest of what, naming it when the signature does not match
+func verifyDigest(algorithm string, value []byte, key crypto.PublicKey, digest [sha256.Size]byte, what string) error {
+	switch algorithm {
+	case AlgorithmRS256:
+		pub, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return fmt.Errorf("%w: RS256 requires an RSA key", ErrInvalidSignature)
+		}
+		if rsa.VerifyPKCS1v15(pub, crypto.SHA256, digest[:], value) != nil {
+			return fmt.Errorf("%w: signature does not match the %s", ErrInvalidSignature, what)
+		}
+	case AlgorithmES256:
+		pub, ok := key.(*ecdsa.PublicKey)
+		if !ok || pub.Curve != elliptic.P256() {
+			return fmt.Errorf("%w: ES256 requires a P-256 key", ErrInvalidSignature)
+		}
+		if len(value) != 64 {
+			return fmt.Errorf("%w: ES256 signature must be 64 bytes", ErrInvalidSignature)
+		}
+		r := new(big.Int).SetBytes(value[:32])
+		s := new(big.Int).SetBytes(value[32:])
+		if !ecdsa.Verify(pub, digest[:], r, s) {
+			return fmt.Errorf("%w: signature does not match the %s", ErrInvalidSignature, what)
+		}
+	default:
+		return fmt.Errorf("%w: unsupported algorithm %q", ErrInvalidSignature, algorithm)
+	}
+	return nil
+}
+
+// Verifier checks message signatures against keys published by the sender's
+// domain
+type Verifier struct {
+	keys KeyResolver
+}
+
+// NewVerifier creates a verifier that looks keys up with keys
+func NewVerifier(keys KeyResolver) *Verifier {
+	return &Verifier{keys: keys}
+}
+
+// Verify checks a message's signature against the sender domain's key named
+// by the signature
+func (v *Verifier) Verify(ctx context.Context, message *types.Message) error {
+	if message.Signature == nil {
+		return ErrUnsigned
+	}
+
+	at := strings.LastIndex(message.Sender, "@")
+	if at < 0 {
+		return fmt.Errorf("%w: sender has no domain", ErrInvalidSignature)
+	}
+	domain := message.Sender[at+1:]
+
+	key, err := v.keys.DiscoverPublicKey(ctx, domain, message.Signature.KeyID)
+	if err != nil {
+		return fmt.Errorf("%w: no signing key for %s: %v", ErrInvalidSignature, domain, err)
+	}
+	return VerifyWithKey(message, key)
+}
+
+// Request replay headers. A gateway relaying a message stamps the request
+// with the Unix time, in seconds, and a nonce it never reuses, and signs both
+// together with the request line and body so neither can be swapped out.
+const (
+	TimestampHeader        = "X-AMTP-Timestamp"
+	NonceHeader            = "X-AMTP-Nonce"
+	RequestSignatureHeader = "X-AMTP-Request-Signature"
+)
+
+// CanonicalizeRequest returns the bytes a request signature covers: the
+// method, path, timestamp, nonce and base64url SHA-256 digest of the body,
+// one per line
+func CanonicalizeRequest(method, path, timestamp, nonce string, body []byte) []byte {
+	digest := sha256.Sum256(body)
+	return []byte(strings.Join([]string{
+		method, path, timestamp, nonce, base64.RawURLEncoding.EncodeToString(digest[:]),
+	}, "\n"))
+}
+
+// SignRequest signs a request on behalf of domain under the key published as
+// keyID, and returns the RequestSignatureHeader value
+func SignRequest(key crypto.Signer, domain, keyID, method, path, timestamp, nonce string, body []byte) (string, error) {
+	algorithm, value, err := signDigest(key, sha256.Sum256(CanonicalizeRequest(method, path, timestamp, nonce, body)))
+	if err != nil {
+		return "", fmt.Errorf("failed to sign request: %w", err)
+	}
+	return fmt.Sprintf("domain=%s; key_id=%s; algorithm=%s; value=%s",
+		domain, keyID, algorithm, base64.RawURLEncoding.EncodeToString(value)), nil
+}
+
+// VerifyRequest checks a RequestSignatureHeader value against the key its
+// domain publishes, and returns that domain
+func (v *Verifier) VerifyRequest(ctx context.Context, header, method, path, timestamp, nonce string, body []byte) (string, error) {
+	params := make(map[string]string)
+	for _, part := range strings.Split(header, ";") {
+		name, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			return "", fmt.Errorf("%w: malformed %s", ErrInvalidSignature, RequestSignatureHeader)
+		}
+		params[name] = value
+	}
+	domain := params["domain"]
+	if domain == "" || params["algorithm"] == "" || params["value"] == "" {
+		return "", fmt.Errorf("%w: %s needs domain, algorithm and value", ErrInvalidSignature, RequestSignatureHeader)
+	}
+
+	value, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(params["value"], "="))
+	if err != nil {
+		return "", fmt.Errorf("%w: value is not base64url", ErrInvalidSignature)
+	}
+	key, err := v.keys.DiscoverPublicKey(ctx, domain, params["key_id"])
+	if err != nil {
+		return "", fmt.Errorf("%w: no signing key for %s: %v", ErrInvalidSignature, domain, err)
+	}
+	digest := sha256.Sum256(CanonicalizeRequest(method, path, timestamp, nonce, body))
+	if err := verifyDigest(params["algorithm"], value, key, digest, "request"); err != nil {
+		return "", err
+	}
+	return domain, nil
+}
@@ -0,0 +1,316 @@
+/*
+ * Copyright 2026 Cong Wang
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package signing
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/amtp-protocol/agentry/internal/types"
+)
+
+func newTestMessage() *types.Message {
+	return &types.Message{
+		Version:        "1.0",
+		MessageID:      "01890a5d-ac96-774b-bcce-b302099a8057",
+		IdempotencyKey: "f47ac10b-58cc-4372-a567-0e02b2c3d479",
+		Timestamp:      time.Date(2026, 3, 1, 12, 30, 45, 123456789, time.FixedZone("CET", 3600)),
+		Sender:         "agent@remote.example",
+		Recipients:     []string{"a@localhost", "b@localhost"},
+		Subject:        "Order <1> & more",
+		Headers:        map[string]interface{}{"priority": "high", "count": 3},
+		Payload:        json.RawMessage(`{ "item": "widget",  "qty": 2 }`),
+	}
+}
+
+// relay round-trips a message through the JSON a gateway relays for one
+// recipient
+func relay(t *testing.T, message *types.Message, recipient string) *types.Message {
+	t.Helper()
+	body, err := json.Marshal(map[string]interface{}{
+		"version":         message.Version,
+		"message_id":      message.MessageID,
+		"idempotency_key": message.IdempotencyKey,
+		"timestamp":       message.Timestamp.Format(time.RFC3339),
+		"sender":          message.Sender,
+		"recipients":      []string{recipient},
+		"subject":         message.Subject,
+		"schema":          message.Schema,
+		"coordination":    message.Coordination,
+		"headers":         message.Headers,
+		"payload":         message.Payload,
+		"attachments":     message.Attachments,
+		"signature":       message.Signature,
+		"in_reply_to":     message.InReplyTo,
+		"response_type":   message.ResponseType,
+	})
+	if err != nil {
+		t.Fatalf("Failed to marshal relayed message: %v", err)
+	}
+	var relayed types.Message
+	if err := json.Unmarshal(body, &relayed); err != nil {
+		t.Fatalf("Failed to unmarshal relayed message: %v", err)
+	}
+	return &relayed
+}
+
+func testKeys(t *testing.T) map[string]crypto.Signer {
+	t.Helper()
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Failed to generate RSA key: %v", err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate EC key: %v", err)
+	}
+	return map[string]crypto.Signer{AlgorithmRS256: rsaKey, AlgorithmES256: ecKey}
+}
+
+func TestSignAndVerify(t *testing.T) {
+	for algorithm, key := range testKeys(t) {
+		t.Run(algorithm, func(t *testing.T) {
+			// Gateways sign a message for the one recipient they relay it to
+			message := newTestMessage()
+			message.Recipients = []string{"a@localhost"}
+			if err := Sign(message, key, "k1"); err != nil {
+				t.Fatalf("Failed to sign: %v", err)
+			}
+			if message.Signature.Algorithm != algorithm || message.Signature.KeyID != "k1" {
+				t.Fatalf("Unexpected signature: %+v", message.Signature)
+			}
+
+			// Relaying drops sub-second precision
+			relayed := relay(t, message, "a@localhost")
+			if err := VerifyWithKey(relayed, key.Public()); err != nil {
+				t.Errorf("Expected the relayed message to verify, got %v", err)
+			}
+
+			// Re-addressing the message breaks its signature
+			if err := VerifyWithKey(relay(t, message, "b@localhost"), key.Public()); !errors.Is(err, ErrInvalidSignature) {
+				t.Errorf("Expected ErrInvalidSignature for a re-addressed message, got %v", err)
+			}
+
+			relayed.Subject = "Tampered"
+			if err := VerifyWithKey(relayed, key.Public()); !errors.Is(err, ErrInvalidSignature) {
+				t.Errorf("Expected ErrInvalidSignature for a tampered message, got %v", err)
+			}
+		})
+	}
+}
+
+func TestCanonicalize_NullPayload(t *testing.T) {
+	message := newTestMessage()
+	message.Payload = nil
+	withNull := newTestMessage()
+	withNull.Payload = json.RawMessage("null")
+
+	a, err := Canonicalize(message)
+	if err != nil {
+		t.Fatalf("Failed to canonicalize: %v", err)
+	}
+	b, err := Canonicalize(withNull)
+	if err != nil {
+		t.Fatalf("Failed to canonicalize: %v", err)
+	}
+	if string(a) != string(b) {
+		t.Errorf("Expected a null payload to canonicalize like no payload:\n%s\n%s", a, b)
+	}
+}
+
+func TestVerifyWithKey_Errors(t *testing.T) {
+	keys := testKeys(t)
+	signed := newTestMessage()
+	if err := Sign(signed, keys[AlgorithmRS256], ""); err != nil {
+		t.Fatalf("Failed to sign: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		modify func(*types.Message)
+		key    crypto.PublicKey
+		want   error
+	}{
+		{"unsigned", func(m *types.Message) { m.Signature = nil }, keys[AlgorithmRS256].Public(), ErrUnsigned},
+		{"wrong key type", func(*types.Message) {}, keys[AlgorithmES256].Public(), ErrInvalidSignature},
+		{"unsupported algorithm", func(m *types.Message) { m.Signature.Algorithm = "HS256" }, keys[AlgorithmRS256].Public(), ErrInvalidSignature},
+		{"not base64url", func(m *types.Message) { m.Signature.Value = "not base64!" }, keys[AlgorithmRS256].Public(), ErrInvalidSignature},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			message := *signed
+			sig := *signed.Signature
+			message.Signature = &sig
+			tt.modify(&message)
+			if err := VerifyWithKey(&message, tt.key); !errors.Is(err, tt.want) {
+				t.Errorf("Expected %v, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+type staticKeys map[string]crypto.PublicKey
+
+func (k staticKeys) DiscoverPublicKey(ctx context.Context, domain, keyID string) (crypto.PublicKey, error) {
+	if key, ok := k[keyID+"@"+domain]; ok {
+		return key, nil
+	}
+	return nil, fmt.Errorf("no key %s for %s", keyID, domain)
+}
+
+func TestVerifier_Verify(t *testing.T) {
+	keys := testKeys(t)
+	verifier := NewVerifier(staticKeys{"k1@remote.example": keys[AlgorithmES256].Public()})
+
+	message := newTestMessage()
+	if err := verifier.Verify(context.Background(), message); !errors.Is(err, ErrUnsigned) {
+		t.Errorf("Expected ErrUnsigned, got %v", err)
+	}
+
+	if err := Sign(message, keys[AlgorithmES256], "k1"); err != nil {
+		t.Fatalf("Failed to sign: %v", err)
+	}
+	if err := verifier.Verify(context.Background(), message); err != nil {
+		t.Errorf("Expected the message to verify, got %v", err)
+	}
+
+	// The key must come from the sender's own domain
+	message.Sender = "agent@other.example"
+	if err := verifier.Verify(context.Background(), message); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("Expected ErrInvalidSignature for an unknown key, got %v", err)
+	}
+}
+
+func TestVerifier_VerifyRequest(t *testing.T) {
+	keys := testKeys(t)
+	verifier := NewVerifier(staticKeys{"k1@remote.example": keys[AlgorithmRS256].Public()})
+	body := []byte(`{"message_id":"01890a5d-ac96-774b-bcce-b302099a8057"}`)
+
+	header, err := SignRequest(keys[AlgorithmRS256], "remote.example", "k1", "POST", "/v1/messages", "1772368245", "n-1", body)
+	if err != nil {
+		t.Fatalf("Failed to sign request: %v", err)
+	}
+	domain, err := verifier.VerifyRequest(context.Background(), header, "POST", "/v1/messages", "1772368245", "n-1", body)
+	if err != nil || domain != "remote.example" {
+		t.Errorf("Expected the request to verify for remote.example, got %q, %v", domain, err)
+	}
+
+	// Swapping the timestamp, nonce or body breaks the signature
+	for name, verify := range map[string]func() (string, error){
+		"timestamp": func() (string, error) {
+			return verifier.VerifyRequest(context.Background(), header, "POST", "/v1/messages", "1772368300", "n-1", body)
+		},
+		"nonce": func() (string, error) {
+			return verifier.VerifyRequest(context.Background(), header, "POST", "/v1/messages", "1772368245", "n-2", body)
+		},
+		"body": func() (string, error) {
+			return verifier.VerifyRequest(context.Background(), header, "POST", "/v1/messages", "1772368245", "n-1", []byte(`{}`))
+		},
+		"malformed": func() (string, error) {
+			return verifier.VerifyRequest(context.Background(), "garbage", "POST", "/v1/messages", "1772368245", "n-1", body)
+		},
+	} {
+		if _, err := verify(); !errors.Is(err, ErrInvalidSignature) {
+			t.Errorf("Expected ErrInvalidSignature with a different %s, got %v", name, err)
+		}
+	}
+}
+
+func TestLoadPrivateKey(t *testing.T) {
+	keys := testKeys(t)
+	rsaKey := keys[AlgorithmRS256].(*rsa.PrivateKey)
+	ecKey := keys[AlgorithmES256].(*ecdsa.PrivateKey)
+	p384Key, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate EC key: %v", err)
+	}
+
+	pkcs8 := func(key interface{}) []byte {
+		der, err := x509.MarshalPKCS8PrivateKey(key)
+		if err != nil {
+			t.Fatalf("Failed to marshal key: %v", err)
+		}
+		return der
+	}
+	sec1, err := x509.MarshalECPrivateKey(ecKey)
+	if err != nil {
+		t.Fatalf("Failed to marshal key: %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		blockType string
+		der       []byte
+		valid     bool
+	}{
+		{"pkcs8 rsa", "PRIVATE KEY", pkcs8(rsaKey), true},
+		{"pkcs8 ec", "PRIVATE KEY", pkcs8(ecKey), true},
+		{"pkcs1 rsa", "RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(rsaKey), true},
+		{"sec1 ec", "EC PRIVATE KEY", sec1, true},
+		{"p-384", "PRIVATE KEY", pkcs8(p384Key), false},
+		{"certificate", "CERTIFICATE", []byte("cert"), false},
+		{"corrupt", "PRIVATE KEY", []byte("corrupt"), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "signing.pem")
+			data := pem.EncodeToMemory(&pem.Block{Type: tt.blockType, Bytes: tt.der})
+			if err := os.WriteFile(path, data, 0600); err != nil {
+				t.Fatalf("Failed to write key: %v", err)
+			}
+
+			key, err := LoadPrivateKey(path)
+			if !tt.valid {
+				if err == nil {
+					t.Error("Expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Failed to load key: %v", err)
+			}
+
+			// The loaded key signs messages its public key verifies
+			message := newTestMessage()
+			if err := Sign(message, key, ""); err != nil {
+				t.Fatalf("Failed to sign: %v", err)
+			}
+			if err := VerifyWithKey(message, key.Public()); err != nil {
+				t.Errorf("Expected the signature to verify, got %v", err)
+			}
+		})
+	}
+
+	if _, err := LoadPrivateKey(filepath.Join(t.TempDir(), "missing.pem")); err == nil {
+		t.Error("Expected an error for a missing file")
+	}
+}
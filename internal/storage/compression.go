@@ -0,0 +1,125 @@
+/*
+ * Copyright 2026 Cong Wang
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package storage
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// PayloadEncodingGzip marks a message row whose payload and attachments are
+// stored gzip-compressed
+const PayloadEncodingGzip = "gzip"
+
+// minCompressSize is the smallest payload plus attachments worth
+// compressing; below it the gzip framing can outweigh the savings
+const minCompressSize = 1024
+
+// gzipWriters and gzipReaders reuse writers and readers, whose allocation
+// dominates the cost of (de)compressing a typical payload
+var (
+	gzipWriters = sync.Pool{New: func() any { return gzip.NewWriter(nil) }}
+	gzipReaders sync.Pool
+)
+
+// compressBytes gzips data; empty data stays empty
+func compressBytes(data []byte) ([]byte, error) {
+	if len(data) == 0 {
+		return nil, nil
+	}
+	var buf bytes.Buffer
+	writer := gzipWriters.Get().(*gzip.Writer)
+	defer gzipWriters.Put(writer)
+	writer.Reset(&buf)
+	if _, err := writer.Write(data); err != nil {
+		return nil, err
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// decompressBytes undoes the given payload encoding; empty data stays empty
+func decompressBytes(encoding string, data []byte) ([]byte, error) {
+	if len(data) == 0 {
+		return nil, nil
+	}
+	if encoding != PayloadEncodingGzip {
+		return nil, fmt.Errorf("unsupported payload encoding %q", encoding)
+	}
+
+	var err error
+	reader, ok := gzipReaders.Get().(*gzip.Reader)
+	if ok {
+		err = reader.Reset(bytes.NewReader(data))
+	} else {
+		reader, err = gzip.NewReader(bytes.NewReader(data))
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer gzipReaders.Put(reader)
+	return io.ReadAll(reader)
+}
+
+// compressMessagePayload moves a large payload and attachment list of
+// dbMessage into the compressed columns
+func compressMessagePayload(dbMessage *Message) error {
+	if len(dbMessage.Payload)+len(dbMessage.Attachments) < minCompressSize {
+		return nil
+	}
+
+	payload, err := compressBytes(dbMessage.Payload)
+	if err != nil {
+		return fmt.Errorf("failed to compress payload: %w", err)
+	}
+	attachments, err := compressBytes(dbMessage.Attachments)
+	if err != nil {
+		return fmt.Errorf("failed to compress attachments: %w", err)
+	}
+
+	dbMessage.PayloadEncoding = PayloadEncodingGzip
+	dbMessage.PayloadCompressed = payload
+	dbMessage.AttachmentsCompressed = attachments
+	dbMessage.Payload = nil
+	dbMessage.Attachments = nil
+	return nil
+}
+
+// decompressMessagePayload restores the JSON columns of a compressed row
+func decompressMessagePayload(dbMessage *Message) error {
+	if dbMessage.PayloadEncoding == "" {
+		return nil
+	}
+
+	payload, err := decompressBytes(dbMessage.PayloadEncoding, dbMessage.PayloadCompressed)
+	if err != nil {
+		return fmt.Errorf("failed to decompress payload: %w", err)
+	}
+	attachments, err := decompressBytes(dbMessage.PayloadEncoding, dbMessage.AttachmentsCompressed)
+	if err != nil {
+		return fmt.Errorf("failed to decompress attachments: %w", err)
+	}
+
+	dbMessage.Payload = payload
+	dbMessage.Attachments = attachments
+	return nil
+}
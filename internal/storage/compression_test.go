@@ -0,0 +1,171 @@
+/*
+ * Copyright 2026 Cong Wang
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/amtp-protocol/agentry/internal/types"
+)
+
+// largeOrderPayload builds a JSON payload of n order lines, typical of the
+// structured documents agents exchange
+func largeOrderPayload(n int) json.RawMessage {
+	lines := make([]string, n)
+	for i := range lines {
+		lines[i] = fmt.Sprintf(`{"sku":"SKU-%05d","description":"Widget, standard finish","quantity":%d,"unit_price":19.99,"currency":"USD"}`, i, i%7+1)
+	}
+	return json.RawMessage(`{"order_id":"o-1","customer":{"name":"Example Corp","email":"buyer@example.com"},"lines":[` +
+		strings.Join(lines, ",") + `]}`)
+}
+
+func compressionTestMessage(payload json.RawMessage) *types.Message {
+	return &types.Message{
+		Version:        "1.0",
+		MessageID:      "01890a5d-ac96-774b-bcce-b302099a8057",
+		IdempotencyKey: "6f1c1f0e-0d3a-4f47-9d59-0b1c2a3d4e5f",
+		Timestamp:      time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC),
+		Sender:         "sender@example.com",
+		Recipients:     []string{"agent@localhost"},
+		Payload:        payload,
+		Attachments: []types.Attachment{
+			{Filename: "invoice.pdf", ContentType: "application/pdf", Size: 12345, Hash: "sha256:abc", URL: "https://files.example.com/invoice.pdf"},
+		},
+	}
+}
+
+func TestDatabaseStorage_CompressPayloads(t *testing.T) {
+	compressing := &DatabaseStorage{config: DatabaseStorageConfig{CompressPayloads: true}}
+	plain := &DatabaseStorage{}
+	message := compressionTestMessage(largeOrderPayload(50))
+
+	dbMessage, err := compressing.convertToDBMessage(message)
+	if err != nil {
+		t.Fatalf("convertToDBMessage failed: %v", err)
+	}
+	if dbMessage.PayloadEncoding != PayloadEncodingGzip || dbMessage.Payload != nil || dbMessage.Attachments != nil {
+		t.Fatalf("Expected the payload and attachments to be stored compressed, got encoding %q", dbMessage.PayloadEncoding)
+	}
+	if len(dbMessage.PayloadCompressed) >= len(message.Payload) {
+		t.Errorf("Expected compression to save space, got %d bytes for %d", len(dbMessage.PayloadCompressed), len(message.Payload))
+	}
+
+	// Compressed rows are readable whether or not compression is enabled
+	for name, ds := range map[string]*DatabaseStorage{"compressing": compressing, "plain": plain} {
+		row := *dbMessage
+		restored, err := ds.convertToTypesMessage(&row)
+		if err != nil {
+			t.Fatalf("%s: convertToTypesMessage failed: %v", name, err)
+		}
+		if string(restored.Payload) != string(message.Payload) {
+			t.Errorf("%s: payload did not survive compression", name)
+		}
+		if len(restored.Attachments) != 1 || restored.Attachments[0] != message.Attachments[0] {
+			t.Errorf("%s: attachments did not survive compression: %+v", name, restored.Attachments)
+		}
+	}
+
+	// So are rows written before compression was enabled
+	uncompressed, err := plain.convertToDBMessage(message)
+	if err != nil {
+		t.Fatalf("convertToDBMessage failed: %v", err)
+	}
+	if uncompressed.PayloadEncoding != "" || uncompressed.PayloadCompressed != nil {
+		t.Fatalf("Expected no compression when disabled, got encoding %q", uncompressed.PayloadEncoding)
+	}
+	restored, err := compressing.convertToTypesMessage(uncompressed)
+	if err != nil || string(restored.Payload) != string(message.Payload) {
+		t.Errorf("Expected an uncompressed row to be read unchanged, got %v", err)
+	}
+}
+
+func TestDatabaseStorage_CompressPayloads_SmallPayload(t *testing.T) {
+	ds := &DatabaseStorage{config: DatabaseStorageConfig{CompressPayloads: true}}
+	message := compressionTestMessage(json.RawMessage(`{"text":"hi"}`))
+
+	dbMessage, err := ds.convertToDBMessage(message)
+	if err != nil {
+		t.Fatalf("convertToDBMessage failed: %v", err)
+	}
+	if dbMessage.PayloadEncoding != "" || string(dbMessage.Payload) != `{"text":"hi"}` {
+		t.Errorf("Expected a small payload to be stored as is, got encoding %q", dbMessage.PayloadEncoding)
+	}
+}
+
+func TestDatabaseStorage_UnknownPayloadEncoding(t *testing.T) {
+	ds := &DatabaseStorage{}
+	message := compressionTestMessage(largeOrderPayload(50))
+	dbMessage, err := (&DatabaseStorage{config: DatabaseStorageConfig{CompressPayloads: true}}).convertToDBMessage(message)
+	if err != nil {
+		t.Fatalf("convertToDBMessage failed: %v", err)
+	}
+
+	dbMessage.PayloadEncoding = "zstd"
+	if _, err := ds.convertToTypesMessage(dbMessage); err == nil || !strings.Contains(err.Error(), "unsupported payload encoding") {
+		t.Errorf("Expected an unsupported encoding error, got %v", err)
+	}
+}
+
+// BenchmarkConvertToDBMessage measures the CPU cost of compressing payloads
+// on write and reports the stored size per message
+func BenchmarkConvertToDBMessage(b *testing.B) {
+	for _, lines := range []int{10, 100, 1000} {
+		message := compressionTestMessage(largeOrderPayload(lines))
+		for _, compress := range []bool{false, true} {
+			ds := &DatabaseStorage{config: DatabaseStorageConfig{CompressPayloads: compress}}
+			b.Run(fmt.Sprintf("payload=%dB/compress=%t", len(message.Payload), compress), func(b *testing.B) {
+				var stored int
+				for i := 0; i < b.N; i++ {
+					dbMessage, err := ds.convertToDBMessage(message)
+					if err != nil {
+						b.Fatal(err)
+					}
+					stored = len(dbMessage.Payload) + len(dbMessage.Attachments) +
+						len(dbMessage.PayloadCompressed) + len(dbMessage.AttachmentsCompressed)
+				}
+				b.ReportMetric(float64(stored), "stored-bytes")
+			})
+		}
+	}
+}
+
+// BenchmarkConvertToTypesMessage measures the CPU cost of decompressing
+// payloads on read
+func BenchmarkConvertToTypesMessage(b *testing.B) {
+	for _, lines := range []int{10, 100, 1000} {
+		message := compressionTestMessage(largeOrderPayload(lines))
+		for _, compress := range []bool{false, true} {
+			ds := &DatabaseStorage{config: DatabaseStorageConfig{CompressPayloads: compress}}
+			dbMessage, err := ds.convertToDBMessage(message)
+			if err != nil {
+				b.Fatal(err)
+			}
+			b.Run(fmt.Sprintf("payload=%dB/compress=%t", len(message.Payload), compress), func(b *testing.B) {
+				for i := 0; i < b.N; i++ {
+					row := *dbMessage
+					if _, err := ds.convertToTypesMessage(&row); err != nil {
+						b.Fatal(err)
+					}
+				}
+			})
+		}
+	}
+}
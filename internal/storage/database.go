@@ -21,6 +21,8 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/amtp-protocol/agentry/internal/agents"
@@ -29,6 +31,7 @@ import (
 	"gorm.io/datatypes"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type DatabaseStorage struct {
@@ -48,7 +51,9 @@ func NewDatabaseStorage(config DatabaseStorageConfig, dbOverride ...*gorm.DB) (*
 				DriverName: config.Driver,
 				DSN:        config.ConnectionString,
 			}),
-			&gorm.Config{},
+			// Report unique violations as gorm.ErrDuplicatedKey, which
+			// agent and schema creation rely on to detect duplicates
+			&gorm.Config{TranslateError: true},
 		)
 		if err != nil {
 			return nil, err
@@ -181,6 +186,12 @@ func (ds *DatabaseStorage) DeleteMessage(ctx context.Context, messageID string)
 			return fmt.Errorf("failed to delete message status: %w", err)
 		}
 
+		// Delete the raw request, if one was kept
+		if err := tx.Where("message_id = ?", messageID).
+			Delete(&RawRequest{}).Error; err != nil {
+			return fmt.Errorf("failed to delete raw request: %w", err)
+		}
+
 		// Delete the message
 		if err := tx.Where("message_id = ?", messageID).
 			Delete(&Message{}).Error; err != nil {
@@ -191,36 +202,74 @@ func (ds *DatabaseStorage) DeleteMessage(ctx context.Context, messageID string)
 	})
 }
 
-// ListMessages returns messages matching the filter criteria
-func (ds *DatabaseStorage) ListMessages(ctx context.Context, filter MessageFilter) ([]*types.Message, error) {
-	query := ds.db.WithContext(ctx).Model(&Message{})
+// StoreRawRequest stores the raw request of a stored message
+func (ds *DatabaseStorage) StoreRawRequest(ctx context.Context, raw *types.RawRequest) error {
+	if raw == nil {
+		return fmt.Errorf("raw request cannot be nil")
+	}
+	if raw.MessageID == "" {
+		return fmt.Errorf("message ID cannot be empty")
+	}
 
-	// Apply filters
-	if filter.Sender != "" {
-		query = query.Where("sender = ?", filter.Sender)
+	headers, err := json.Marshal(raw.Headers)
+	if err != nil {
+		return fmt.Errorf("failed to marshal raw request headers: %w", err)
 	}
 
-	if len(filter.Recipients) > 0 {
-		// Use JSONB containment operator to check if recipients array contains any of the filter recipients
-		recipientsJSON, err := json.Marshal(filter.Recipients)
-		if err != nil {
-			return nil, fmt.Errorf("failed to marshal recipients filter: %w", err)
+	dbRaw := &RawRequest{
+		MessageID:  raw.MessageID,
+		ReceivedAt: raw.ReceivedAt,
+		Headers:    datatypes.JSON(headers),
+		Body:       []byte(raw.Body),
+		Size:       raw.Size,
+		Truncated:  raw.Truncated,
+	}
+	if err := ds.db.WithContext(ctx).Create(dbRaw).Error; err != nil {
+		return fmt.Errorf("failed to store raw request: %w", err)
+	}
+	return nil
+}
+
+// GetRawRequest retrieves the raw request of a message
+func (ds *DatabaseStorage) GetRawRequest(ctx context.Context, messageID string) (*types.RawRequest, error) {
+	if messageID == "" {
+		return nil, fmt.Errorf("message ID cannot be empty")
+	}
+
+	var dbRaw RawRequest
+	if err := ds.db.WithContext(ctx).
+		Where("message_id = ?", messageID).
+		First(&dbRaw).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("raw request not found: %s", messageID)
 		}
-		query = query.Where("recipients @> ?", string(recipientsJSON))
+		return nil, fmt.Errorf("failed to get raw request: %w", err)
 	}
 
-	if filter.Status != "" {
-		// Join with message_statuses table to filter by status
-		query = query.Joins("JOIN message_statuses ON messages.message_id = message_statuses.message_id").
-			Where("message_statuses.status = ?", filter.Status)
+	raw := &types.RawRequest{
+		MessageID:  dbRaw.MessageID,
+		ReceivedAt: dbRaw.ReceivedAt,
+		Body:       string(dbRaw.Body),
+		Size:       dbRaw.Size,
+		Truncated:  dbRaw.Truncated,
 	}
+	if len(dbRaw.Headers) > 0 {
+		if err := json.Unmarshal(dbRaw.Headers, &raw.Headers); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal raw request headers: %w", err)
+		}
+	}
+	return raw, nil
+}
 
-	if filter.Since != nil {
-		query = query.Where("timestamp >= ?", time.Unix(*filter.Since, 0))
+// ListMessages returns messages matching the filter criteria
+func (ds *DatabaseStorage) ListMessages(ctx context.Context, filter MessageFilter) ([]*types.Message, error) {
+	query, err := ds.messageQuery(ctx, filter)
+	if err != nil {
+		return nil, err
 	}
 
-	// Apply ordering and pagination
-	query = query.Order("created_at DESC")
+	// Apply ordering and pagination, newest first as in MemoryStorage
+	query = query.Order("messages.timestamp DESC, messages.id DESC")
 
 	if filter.Offset > 0 {
 		query = query.Offset(filter.Offset)
@@ -248,6 +297,60 @@ func (ds *DatabaseStorage) ListMessages(ctx context.Context, filter MessageFilte
 	return messages, nil
 }
 
+// CountMessages returns how many messages match the filter criteria
+func (ds *DatabaseStorage) CountMessages(ctx context.Context, filter MessageFilter) (int64, error) {
+	query, err := ds.messageQuery(ctx, filter)
+	if err != nil {
+		return 0, err
+	}
+
+	var count int64
+	if err := query.Count(&count).Error; err != nil {
+		return 0, fmt.Errorf("failed to count messages: %w", err)
+	}
+	return count, nil
+}
+
+// messageQuery selects the messages matching the filter criteria, without
+// ordering or pagination
+func (ds *DatabaseStorage) messageQuery(ctx context.Context, filter MessageFilter) (*gorm.DB, error) {
+	query := ds.db.WithContext(ctx).Model(&Message{})
+
+	// Apply filters
+	if filter.Sender != "" {
+		query = query.Where("sender = ?", filter.Sender)
+	}
+
+	if len(filter.Recipients) > 0 {
+		// Use JSONB containment operator to check if recipients array contains any of the filter recipients
+		recipientsJSON, err := json.Marshal(filter.Recipients)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal recipients filter: %w", err)
+		}
+		query = query.Where("recipients @> ?", string(recipientsJSON))
+	}
+
+	if len(filter.Labels) > 0 {
+		labelsJSON, err := json.Marshal(filter.Labels)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal labels filter: %w", err)
+		}
+		query = query.Where("labels @> ?", string(labelsJSON))
+	}
+
+	if filter.Status != "" {
+		// Join with message_statuses table to filter by status
+		query = query.Joins("JOIN message_statuses ON messages.message_id = message_statuses.message_id").
+			Where("message_statuses.status = ?", filter.Status)
+	}
+
+	if filter.Since != nil {
+		query = query.Where("timestamp >= ?", time.Unix(*filter.Since, 0))
+	}
+
+	return query, nil
+}
+
 // StoreStatus stores message status
 func (ds *DatabaseStorage) StoreStatus(ctx context.Context, messageID string, status *types.MessageStatus) error {
 	if messageID == "" {
@@ -289,6 +392,7 @@ func (ds *DatabaseStorage) StoreStatus(ctx context.Context, messageID string, st
 				InboxDelivered: recipientStatus.InboxDelivered,
 				Acknowledged:   recipientStatus.Acknowledged,
 				AcknowledgedAt: recipientStatus.AcknowledgedAt,
+				Response:       datatypes.JSON(recipientStatus.Response),
 			}
 
 			if err := tx.Where("message_id = ? AND address = ?", messageID, recipientStatus.Address).
@@ -330,6 +434,63 @@ func (ds *DatabaseStorage) GetStatus(ctx context.Context, messageID string) (*ty
 	return ds.convertToTypesMessageStatus(&messageStatus, recipientStatuses)
 }
 
+// GetStatuses retrieves the statuses of several messages with one query for
+// the message statuses and one for their recipients
+func (ds *DatabaseStorage) GetStatuses(ctx context.Context, messageIDs []string) (map[string]*types.MessageStatus, error) {
+	statuses := make(map[string]*types.MessageStatus, len(messageIDs))
+	if len(messageIDs) == 0 {
+		return statuses, nil
+	}
+
+	var messageStatuses []MessageStatus
+	if err := ds.db.WithContext(ctx).
+		Where("message_id IN ?", messageIDs).
+		Find(&messageStatuses).Error; err != nil {
+		return nil, fmt.Errorf("failed to get message statuses: %w", err)
+	}
+
+	var recipientStatuses []RecipientStatus
+	if err := ds.db.WithContext(ctx).
+		Where("message_id IN ?", messageIDs).
+		Find(&recipientStatuses).Error; err != nil {
+		return nil, fmt.Errorf("failed to get recipient statuses: %w", err)
+	}
+	byMessage := make(map[string][]RecipientStatus, len(messageStatuses))
+	for _, recipientStatus := range recipientStatuses {
+		byMessage[recipientStatus.MessageID] = append(byMessage[recipientStatus.MessageID], recipientStatus)
+	}
+
+	for i := range messageStatuses {
+		status, err := ds.convertToTypesMessageStatus(&messageStatuses[i], byMessage[messageStatuses[i].MessageID])
+		if err != nil {
+			return nil, fmt.Errorf("failed to convert message status: %w", err)
+		}
+		statuses[status.MessageID] = status
+	}
+	return statuses, nil
+}
+
+// GetStatusByIdempotencyKey retrieves the status of the message stored with
+// the given idempotency key, using the unique index on messages.idempotency_key
+func (ds *DatabaseStorage) GetStatusByIdempotencyKey(ctx context.Context, idempotencyKey string) (*types.MessageStatus, error) {
+	if idempotencyKey == "" {
+		return nil, fmt.Errorf("idempotency key cannot be empty")
+	}
+
+	var message Message
+	if err := ds.db.WithContext(ctx).
+		Select("message_id").
+		Where("idempotency_key = ?", idempotencyKey).
+		First(&message).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, fmt.Errorf("message not found for idempotency key: %s", idempotencyKey)
+		}
+		return nil, fmt.Errorf("failed to look up idempotency key: %w", err)
+	}
+
+	return ds.GetStatus(ctx, message.MessageID)
+}
+
 // UpdateStatus updates message status using the provided updater function
 func (ds *DatabaseStorage) UpdateStatus(ctx context.Context, messageID string, updater StatusUpdater) error {
 	if messageID == "" {
@@ -381,6 +542,124 @@ func (ds *DatabaseStorage) DeleteStatus(ctx context.Context, messageID string) e
 	})
 }
 
+// ListStatuses returns a page of message statuses in insertion order. The
+// cursor is the row ID of the last status returned.
+func (ds *DatabaseStorage) ListStatuses(ctx context.Context, cursor string, limit int) ([]*types.MessageStatus, string, error) {
+	if limit <= 0 {
+		return nil, "", fmt.Errorf("limit must be positive")
+	}
+
+	var afterID uint64
+	if cursor != "" {
+		parsed, err := strconv.ParseUint(cursor, 10, 64)
+		if err != nil {
+			return nil, "", fmt.Errorf("invalid status cursor: %s", cursor)
+		}
+		afterID = parsed
+	}
+
+	// Fetch one extra row to learn whether another page exists
+	var messageStatuses []MessageStatus
+	if err := ds.db.WithContext(ctx).
+		Where("id > ?", afterID).
+		Order("id ASC").
+		Limit(limit + 1).
+		Find(&messageStatuses).Error; err != nil {
+		return nil, "", fmt.Errorf("failed to list message statuses: %w", err)
+	}
+
+	nextCursor := ""
+	if len(messageStatuses) > limit {
+		messageStatuses = messageStatuses[:limit]
+		nextCursor = strconv.FormatUint(uint64(messageStatuses[limit-1].ID), 10)
+	}
+	if len(messageStatuses) == 0 {
+		return nil, "", nil
+	}
+
+	messageIDs := make([]string, len(messageStatuses))
+	for i := range messageStatuses {
+		messageIDs[i] = messageStatuses[i].MessageID
+	}
+
+	var recipientStatuses []RecipientStatus
+	if err := ds.db.WithContext(ctx).
+		Where("message_id IN ?", messageIDs).
+		Find(&recipientStatuses).Error; err != nil {
+		return nil, "", fmt.Errorf("failed to get recipient statuses: %w", err)
+	}
+	byMessage := make(map[string][]RecipientStatus, len(messageStatuses))
+	for _, recipientStatus := range recipientStatuses {
+		byMessage[recipientStatus.MessageID] = append(byMessage[recipientStatus.MessageID], recipientStatus)
+	}
+
+	statuses := make([]*types.MessageStatus, 0, len(messageStatuses))
+	for i := range messageStatuses {
+		status, err := ds.convertToTypesMessageStatus(&messageStatuses[i], byMessage[messageStatuses[i].MessageID])
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to convert message status: %w", err)
+		}
+		statuses = append(statuses, status)
+	}
+
+	return statuses, nextCursor, nil
+}
+
+// ClaimMessagesForDelivery leases message statuses with held recipients.
+// Candidate rows are locked with FOR UPDATE SKIP LOCKED, so gateways
+// claiming at the same moment each take different messages instead of
+// waiting on one another.
+func (ds *DatabaseStorage) ClaimMessagesForDelivery(ctx context.Context, workerID string, limit int, leaseDuration time.Duration) ([]string, error) {
+	if workerID == "" {
+		return nil, fmt.Errorf("worker ID cannot be empty")
+	}
+	if limit <= 0 {
+		return nil, fmt.Errorf("limit must be positive")
+	}
+	if leaseDuration <= 0 {
+		return nil, fmt.Errorf("lease duration must be positive")
+	}
+
+	var messageIDs []string
+	err := ds.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		now := time.Now().UTC()
+
+		var claimable []MessageStatus
+		if err := tx.Select("id", "message_id").
+			Where("EXISTS (SELECT 1 FROM recipient_statuses WHERE recipient_statuses.message_id = message_statuses.message_id AND recipient_statuses.status = ? AND recipient_statuses.error_code = ?)", StatusQueued, ErrorCodeAgentNotRegistered).
+			Where("lease_expires_at IS NULL OR lease_expires_at < ? OR lease_owner = ?", now, workerID).
+			Order("lease_expires_at ASC NULLS FIRST, id ASC").
+			Limit(limit).
+			Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Find(&claimable).Error; err != nil {
+			return fmt.Errorf("failed to find claimable messages: %w", err)
+		}
+		if len(claimable) == 0 {
+			return nil
+		}
+
+		ids := make([]uint, len(claimable))
+		for i := range claimable {
+			ids[i] = claimable[i].ID
+			messageIDs = append(messageIDs, claimable[i].MessageID)
+		}
+		// A claim is not a status change, so updated_at is left alone
+		if err := tx.Model(&MessageStatus{}).
+			Where("id IN ?", ids).
+			UpdateColumns(map[string]interface{}{
+				"lease_owner":      workerID,
+				"lease_expires_at": now.Add(leaseDuration),
+			}).Error; err != nil {
+			return fmt.Errorf("failed to claim messages: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return messageIDs, nil
+}
+
 // GetInboxMessages retrieves messages for a recipient from the database
 func (ds *DatabaseStorage) GetInboxMessages(ctx context.Context, recipient string) ([]*types.Message, error) {
 	if recipient == "" {
@@ -413,8 +692,143 @@ func (ds *DatabaseStorage) GetInboxMessages(ctx context.Context, recipient strin
 	return messages, nil
 }
 
+// ListInboxMessages returns a page of the recipient's inbox using keyset
+// pagination on the message primary key.
+func (ds *DatabaseStorage) ListInboxMessages(ctx context.Context, recipient, cursor string, limit int) ([]*types.Message, string, error) {
+	if recipient == "" {
+		return nil, "", fmt.Errorf("recipient cannot be empty")
+	}
+	if limit <= 0 {
+		return nil, "", fmt.Errorf("limit must be positive")
+	}
+
+	var afterID uint64
+	if cursor != "" {
+		parsed, err := strconv.ParseUint(cursor, 10, 64)
+		if err != nil {
+			return nil, "", fmt.Errorf("invalid inbox cursor: %s", cursor)
+		}
+		afterID = parsed
+	}
+
+	// Fetch one extra row to learn whether another page exists
+	var dbMessages []Message
+	err := ds.db.WithContext(ctx).
+		Joins("JOIN recipient_statuses ON messages.message_id = recipient_statuses.message_id").
+		Where("messages.id > ?", afterID).
+		Where("recipient_statuses.address = ?", recipient).
+		Where("recipient_statuses.local_delivery = ?", true).
+		Where("recipient_statuses.inbox_delivered = ?", true).
+		Where("recipient_statuses.acknowledged = ?", false).
+		Order("messages.id ASC").
+		Limit(limit + 1).
+		Find(&dbMessages).Error
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to list inbox messages: %w", err)
+	}
+
+	nextCursor := ""
+	if len(dbMessages) > limit {
+		dbMessages = dbMessages[:limit]
+		nextCursor = strconv.FormatUint(uint64(dbMessages[limit-1].ID), 10)
+	}
+
+	messages := make([]*types.Message, 0, len(dbMessages))
+	for i := range dbMessages {
+		message, err := ds.convertToTypesMessage(&dbMessages[i])
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to convert message: %w", err)
+		}
+		messages = append(messages, message)
+	}
+
+	return messages, nextCursor, nil
+}
+
+// ExportMessages returns a page of messages involving the address using
+// keyset pagination on the primary key, so each call touches at most limit
+// rows no matter how large the history is.
+func (ds *DatabaseStorage) ExportMessages(ctx context.Context, address, cursor string, limit int) ([]*types.Message, string, error) {
+	if address == "" {
+		return nil, "", fmt.Errorf("address cannot be empty")
+	}
+
+	recipientJSON, err := json.Marshal([]string{address})
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to marshal address filter: %w", err)
+	}
+
+	messages, nextCursor, err := ds.pageMessages(ctx, cursor, limit, func(db *gorm.DB) *gorm.DB {
+		return db.Where("sender = ? OR recipients @> ?", address, string(recipientJSON))
+	})
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to export messages: %w", err)
+	}
+	return messages, nextCursor, nil
+}
+
+// ListMessagesBySchema returns a page of messages using one of the schemas,
+// with the same keyset pagination as ExportMessages
+func (ds *DatabaseStorage) ListMessagesBySchema(ctx context.Context, schemaIDs []string, cursor string, limit int) ([]*types.Message, string, error) {
+	if len(schemaIDs) == 0 {
+		return nil, "", fmt.Errorf("schema ID cannot be empty")
+	}
+
+	messages, nextCursor, err := ds.pageMessages(ctx, cursor, limit, func(db *gorm.DB) *gorm.DB {
+		return db.Where("schema IN ?", schemaIDs)
+	})
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to list messages by schema: %w", err)
+	}
+	return messages, nextCursor, nil
+}
+
+// pageMessages returns up to limit messages matching scope after cursor, in
+// primary key order, and the cursor of the next page
+func (ds *DatabaseStorage) pageMessages(ctx context.Context, cursor string, limit int, scope func(*gorm.DB) *gorm.DB) ([]*types.Message, string, error) {
+	if limit <= 0 {
+		return nil, "", fmt.Errorf("limit must be positive")
+	}
+
+	var afterID uint64
+	if cursor != "" {
+		parsed, err := strconv.ParseUint(cursor, 10, 64)
+		if err != nil {
+			return nil, "", fmt.Errorf("%w: %s", ErrInvalidCursor, cursor)
+		}
+		afterID = parsed
+	}
+
+	// Fetch one extra row to learn whether another page exists
+	var dbMessages []Message
+	err := scope(ds.db.WithContext(ctx).Where("id > ?", afterID)).
+		Order("id ASC").
+		Limit(limit + 1).
+		Find(&dbMessages).Error
+	if err != nil {
+		return nil, "", err
+	}
+
+	nextCursor := ""
+	if len(dbMessages) > limit {
+		dbMessages = dbMessages[:limit]
+		nextCursor = strconv.FormatUint(uint64(dbMessages[limit-1].ID), 10)
+	}
+
+	messages := make([]*types.Message, 0, len(dbMessages))
+	for i := range dbMessages {
+		message, err := ds.convertToTypesMessage(&dbMessages[i])
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to convert message: %w", err)
+		}
+		messages = append(messages, message)
+	}
+
+	return messages, nextCursor, nil
+}
+
 // AcknowledgeMessage marks a message as acknowledged for a specific recipient
-func (ds *DatabaseStorage) AcknowledgeMessage(ctx context.Context, recipient, messageID string) error {
+func (ds *DatabaseStorage) AcknowledgeMessage(ctx context.Context, recipient, messageID string, response json.RawMessage) error {
 	if recipient == "" {
 		return fmt.Errorf("recipient cannot be empty")
 	}
@@ -443,12 +857,16 @@ func (ds *DatabaseStorage) AcknowledgeMessage(ctx context.Context, recipient, me
 
 		// Update acknowledgment
 		now := time.Now().UTC()
+		updates := map[string]interface{}{
+			"acknowledged":    true,
+			"acknowledged_at": now,
+		}
+		if len(response) > 0 {
+			updates["response"] = datatypes.JSON(response)
+		}
 		if err := tx.Model(&RecipientStatus{}).
 			Where("message_id = ? AND address = ?", messageID, recipient).
-			Updates(map[string]interface{}{
-				"acknowledged":    true,
-				"acknowledged_at": now,
-			}).Error; err != nil {
+			Updates(updates).Error; err != nil {
 			return fmt.Errorf("failed to acknowledge message: %w", err)
 		}
 
@@ -463,6 +881,126 @@ func (ds *DatabaseStorage) AcknowledgeMessage(ctx context.Context, recipient, me
 	})
 }
 
+// DrainInbox acknowledges all of a recipient's inbox messages in one
+// transaction
+func (ds *DatabaseStorage) DrainInbox(ctx context.Context, recipient string) (int64, error) {
+	if recipient == "" {
+		return 0, fmt.Errorf("recipient cannot be empty")
+	}
+
+	var drained int64
+	err := ds.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var messageIDs []string
+		if err := tx.Model(&RecipientStatus{}).
+			Where("address = ? AND local_delivery = ? AND inbox_delivered = ? AND acknowledged = ?",
+				recipient, true, true, false).
+			Pluck("message_id", &messageIDs).Error; err != nil {
+			return fmt.Errorf("failed to list inbox messages: %w", err)
+		}
+		if len(messageIDs) == 0 {
+			return nil
+		}
+
+		now := time.Now().UTC()
+		result := tx.Model(&RecipientStatus{}).
+			Where("address = ? AND message_id IN ? AND acknowledged = ?", recipient, messageIDs, false).
+			Updates(map[string]interface{}{
+				"acknowledged":    true,
+				"acknowledged_at": now,
+			})
+		if result.Error != nil {
+			return fmt.Errorf("failed to acknowledge inbox messages: %w", result.Error)
+		}
+		drained = result.RowsAffected
+
+		if err := tx.Model(&MessageStatus{}).
+			Where("message_id IN ?", messageIDs).
+			Update("updated_at", now).Error; err != nil {
+			return fmt.Errorf("failed to update message statuses: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return drained, nil
+}
+
+// ListPendingInbox returns a page of unacknowledged inbox deliveries using
+// keyset pagination on the recipient status timestamp and primary key
+func (ds *DatabaseStorage) ListPendingInbox(ctx context.Context, cursor string, limit int) ([]types.PendingInboxMessage, string, error) {
+	if limit <= 0 {
+		return nil, "", fmt.Errorf("limit must be positive")
+	}
+
+	query := ds.db.WithContext(ctx).
+		Where("local_delivery = ? AND inbox_delivered = ? AND acknowledged = ?", true, true, false)
+	if cursor != "" {
+		nanos, id, ok := strings.Cut(cursor, ":")
+		afterNanos, nanosErr := strconv.ParseInt(nanos, 10, 64)
+		afterID, idErr := strconv.ParseUint(id, 10, 64)
+		if !ok || nanosErr != nil || idErr != nil {
+			return nil, "", fmt.Errorf("%w: %s", ErrInvalidCursor, cursor)
+		}
+		after := time.Unix(0, afterNanos).UTC()
+		query = query.Where("timestamp > ? OR (timestamp = ? AND id > ?)", after, after, afterID)
+	}
+
+	// Fetch one extra row to learn whether another page exists
+	var statuses []RecipientStatus
+	if err := query.Order("timestamp ASC").Order("id ASC").Limit(limit + 1).Find(&statuses).Error; err != nil {
+		return nil, "", fmt.Errorf("failed to list pending inbox messages: %w", err)
+	}
+
+	nextCursor := ""
+	if len(statuses) > limit {
+		statuses = statuses[:limit]
+		last := statuses[limit-1]
+		nextCursor = fmt.Sprintf("%d:%d", last.Timestamp.UnixNano(), last.ID)
+	}
+
+	pending := make([]types.PendingInboxMessage, 0, len(statuses))
+	for _, status := range statuses {
+		pending = append(pending, types.PendingInboxMessage{
+			MessageID: status.MessageID,
+			Recipient: status.Address,
+			Since:     status.Timestamp,
+		})
+	}
+	return pending, nextCursor, nil
+}
+
+// ListDeliveryErrors returns failed recipient deliveries, newest first
+func (ds *DatabaseStorage) ListDeliveryErrors(ctx context.Context, since *time.Time, limit int) ([]types.DeliveryError, error) {
+	if limit <= 0 {
+		return nil, fmt.Errorf("limit must be positive")
+	}
+
+	query := ds.db.WithContext(ctx).Where("status = ?", StatusFailed)
+	if since != nil {
+		query = query.Where("timestamp >= ?", *since)
+	}
+
+	var statuses []RecipientStatus
+	if err := query.Order("timestamp DESC").Order("id DESC").Limit(limit).Find(&statuses).Error; err != nil {
+		return nil, fmt.Errorf("failed to list delivery errors: %w", err)
+	}
+
+	deliveryErrors := make([]types.DeliveryError, 0, len(statuses))
+	for _, status := range statuses {
+		deliveryErrors = append(deliveryErrors, types.DeliveryError{
+			MessageID:    status.MessageID,
+			Recipient:    status.Address,
+			ErrorCode:    status.ErrorCode,
+			ErrorMessage: status.ErrorMessage,
+			Attempts:     status.Attempts,
+			DeliveryMode: status.DeliveryMode,
+			Timestamp:    status.Timestamp,
+		})
+	}
+	return deliveryErrors, nil
+}
+
 // Close closes the database connection
 func (ds *DatabaseStorage) Close() error {
 	if ds.db == nil {
@@ -534,9 +1072,10 @@ func (ds *DatabaseStorage) GetStats(ctx context.Context) (StorageStats, error) {
 		InboxDelivered bool
 		Acknowledged   bool
 		Count          int64
+		Oldest         *time.Time
 	}
 	if err := ds.db.WithContext(ctx).Model(&RecipientStatus{}).
-		Select("inbox_delivered, acknowledged, COUNT(*) as count").
+		Select("inbox_delivered, acknowledged, COUNT(*) as count, MIN(timestamp) as oldest").
 		Where("local_delivery = ?", true).
 		Group("inbox_delivered, acknowledged").
 		Find(&inboxStats).Error; err != nil {
@@ -549,6 +1088,7 @@ func (ds *DatabaseStorage) GetStats(ctx context.Context) (StorageStats, error) {
 				stats.AcknowledgedMessages += is.Count
 			} else {
 				stats.InboxMessages += is.Count
+				stats.OldestUnacknowledgedAt = is.Oldest
 			}
 		}
 	}
@@ -590,7 +1130,7 @@ func (ds *DatabaseStorage) GetAgent(ctx context.Context, agentAddress string) (*
 		Where("address = ?", agentAddress).
 		First(&dbAgent).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, fmt.Errorf("agent not found: %s", agentAddress)
+			return nil, fmt.Errorf("%w: %s", agents.ErrAgentNotFound, agentAddress)
 		}
 		return nil, fmt.Errorf("failed to get agent: %w", err)
 	}
@@ -603,6 +1143,31 @@ func (ds *DatabaseStorage) GetAgent(ctx context.Context, agentAddress string) (*
 	return agent, nil
 }
 
+// GetAgentByAPIKey retrieves the agent with the hashed API key from the
+// database
+func (ds *DatabaseStorage) GetAgentByAPIKey(ctx context.Context, hashedKey string) (*agents.LocalAgent, error) {
+	if hashedKey == "" {
+		return nil, fmt.Errorf("API key cannot be empty")
+	}
+
+	var dbAgent Agent
+	if err := ds.db.WithContext(ctx).
+		Where("api_key = ?", hashedKey).
+		First(&dbAgent).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, agents.ErrAgentNotFound
+		}
+		return nil, fmt.Errorf("failed to get agent by API key: %w", err)
+	}
+
+	agent, err := ds.convertToLocalAgent(&dbAgent)
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert agent: %w", err)
+	}
+
+	return agent, nil
+}
+
 // UpdateAgent updates an existing agent in the database
 func (ds *DatabaseStorage) UpdateAgent(ctx context.Context, agent *agents.LocalAgent) error {
 	if agent == nil {
@@ -630,6 +1195,28 @@ func (ds *DatabaseStorage) UpdateAgent(ctx context.Context, agent *agents.LocalA
 	return nil
 }
 
+// UpdateLastAccess sets an agent's last access time
+func (ds *DatabaseStorage) UpdateLastAccess(ctx context.Context, agentAddress string, at time.Time) error {
+	if agentAddress == "" {
+		return fmt.Errorf("agent address cannot be empty")
+	}
+
+	result := ds.db.WithContext(ctx).
+		Model(&Agent{}).
+		Where("address = ?", agentAddress).
+		UpdateColumn("last_access", at)
+
+	if result.Error != nil {
+		return fmt.Errorf("failed to update agent last access: %w", result.Error)
+	}
+
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("agent not found: %s", agentAddress)
+	}
+
+	return nil
+}
+
 // DeleteAgent deletes an agent from the database
 func (ds *DatabaseStorage) DeleteAgent(ctx context.Context, agentAddress string) error {
 	if agentAddress == "" {
@@ -722,6 +1309,8 @@ func (ds *DatabaseStorage) convertToDBMessage(message *types.Message) (*Message,
 		Schema:         message.Schema,
 		InReplyTo:      inReplyToStr,
 		ResponseType:   message.ResponseType,
+		RequestReceipt: message.RequestReceipt,
+		Encrypted:      message.Encrypted,
 	}
 
 	// Convert recipients
@@ -754,6 +1343,15 @@ func (ds *DatabaseStorage) convertToDBMessage(message *types.Message) (*Message,
 		}
 	}
 
+	// Convert labels
+	if len(message.Labels) > 0 {
+		labelsJSON, err := json.Marshal(message.Labels)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal labels: %w", err)
+		}
+		dbMessage.Labels = datatypes.JSON(labelsJSON)
+	}
+
 	// Convert headers
 	if message.Headers != nil {
 		headersJSON, err := json.Marshal(message.Headers)
@@ -801,6 +1399,12 @@ func (ds *DatabaseStorage) convertToDBMessage(message *types.Message) (*Message,
 		dbMessage.Signature = datatypes.JSON(signatureJSON)
 	}
 
+	if ds.config.CompressPayloads {
+		if err := compressMessagePayload(dbMessage); err != nil {
+			return nil, err
+		}
+	}
+
 	return dbMessage, nil
 }
 
@@ -820,6 +1424,8 @@ func (ds *DatabaseStorage) convertToTypesMessage(dbMessage *Message) (*types.Mes
 		Schema:         dbMessage.Schema,
 		InReplyTo:      inReplyToStr,
 		ResponseType:   dbMessage.ResponseType,
+		RequestReceipt: dbMessage.RequestReceipt,
+		Encrypted:      dbMessage.Encrypted,
 	}
 
 	// Convert recipients
@@ -853,6 +1459,13 @@ func (ds *DatabaseStorage) convertToTypesMessage(dbMessage *Message) (*types.Mes
 		}
 	}
 
+	// Convert labels
+	if len(dbMessage.Labels) > 0 {
+		if err := json.Unmarshal(dbMessage.Labels, &message.Labels); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal labels: %w", err)
+		}
+	}
+
 	// Convert headers
 	if len(dbMessage.Headers) > 0 {
 		var headers map[string]interface{}
@@ -862,7 +1475,11 @@ func (ds *DatabaseStorage) convertToTypesMessage(dbMessage *Message) (*types.Mes
 		message.Headers = headers
 	}
 
-	// Convert payload
+	// Convert payload, whether or not compression was enabled when the row
+	// was written
+	if err := decompressMessagePayload(dbMessage); err != nil {
+		return nil, err
+	}
 	if len(dbMessage.Payload) > 0 {
 		message.Payload = json.RawMessage(dbMessage.Payload)
 	}
@@ -925,6 +1542,7 @@ func (ds *DatabaseStorage) convertToTypesMessageStatus(messageStatus *MessageSta
 			InboxDelivered: rs.InboxDelivered,
 			Acknowledged:   rs.Acknowledged,
 			AcknowledgedAt: rs.AcknowledgedAt,
+			Response:       json.RawMessage(rs.Response),
 		})
 	}
 
@@ -940,8 +1558,12 @@ func (ds *DatabaseStorage) convertToDBAgent(agent *agents.LocalAgent) (*Agent, e
 	dbAgent := &Agent{
 		Address:        agent.Address,
 		DeliveryMode:   agent.DeliveryMode,
+		PushPolicy:     agent.PushPolicy,
+		PushStrategy:   agent.PushStrategy,
 		APIKey:         agent.APIKey,
 		RequiresSchema: agent.RequiresSchema,
+
+		MaxConcurrentDeliveries: agent.MaxConcurrentDeliveries,
 	}
 
 	if agent.PushTarget != "" {
@@ -949,6 +1571,30 @@ func (ds *DatabaseStorage) convertToDBAgent(agent *agents.LocalAgent) (*Agent, e
 		dbAgent.PushTarget = &pushTarget
 	}
 
+	if len(agent.PushTargets) > 0 {
+		targetsJSON, err := json.Marshal(agent.PushTargets)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal push targets: %w", err)
+		}
+		dbAgent.PushTargets = datatypes.JSON(targetsJSON)
+	}
+
+	if len(agent.PushTargetWeights) > 0 {
+		weightsJSON, err := json.Marshal(agent.PushTargetWeights)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal push target weights: %w", err)
+		}
+		dbAgent.PushTargetWeights = datatypes.JSON(weightsJSON)
+	}
+
+	if len(agent.AllowedSenders) > 0 {
+		sendersJSON, err := json.Marshal(agent.AllowedSenders)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal allowed senders: %w", err)
+		}
+		dbAgent.AllowedSenders = datatypes.JSON(sendersJSON)
+	}
+
 	if headersJSON, err := json.Marshal(agent.Headers); err != nil {
 		return nil, fmt.Errorf("failed to marshal headers: %w", err)
 	} else if string(headersJSON) != "null" {
@@ -975,6 +1621,11 @@ func (ds *DatabaseStorage) convertToDBAgent(agent *agents.LocalAgent) (*Agent, e
 		dbAgent.LastAccess = &lastAccess
 	}
 
+	if !agent.KeyRotatedAt.IsZero() {
+		keyRotatedAt := agent.KeyRotatedAt
+		dbAgent.KeyRotatedAt = &keyRotatedAt
+	}
+
 	return dbAgent, nil
 }
 
@@ -998,14 +1649,42 @@ func (ds *DatabaseStorage) convertToLocalAgent(dbAgent *Agent) (*agents.LocalAge
 		}
 	}
 
+	var pushTargets []string
+	if len(dbAgent.PushTargets) > 0 {
+		if err := json.Unmarshal(dbAgent.PushTargets, &pushTargets); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal push targets: %w", err)
+		}
+	}
+
+	var pushWeights map[string]int
+	if len(dbAgent.PushTargetWeights) > 0 {
+		if err := json.Unmarshal(dbAgent.PushTargetWeights, &pushWeights); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal push target weights: %w", err)
+		}
+	}
+
+	var allowedSenders []string
+	if len(dbAgent.AllowedSenders) > 0 {
+		if err := json.Unmarshal(dbAgent.AllowedSenders, &allowedSenders); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal allowed senders: %w", err)
+		}
+	}
+
 	localAgent := &agents.LocalAgent{
-		Address:          dbAgent.Address,
-		DeliveryMode:     dbAgent.DeliveryMode,
-		Headers:          headers,
-		APIKey:           dbAgent.APIKey,
-		SupportedSchemas: supportedSchemas,
-		RequiresSchema:   dbAgent.RequiresSchema,
-		CreatedAt:        dbAgent.CreatedAt,
+		Address:           dbAgent.Address,
+		DeliveryMode:      dbAgent.DeliveryMode,
+		PushTargets:       pushTargets,
+		PushPolicy:        dbAgent.PushPolicy,
+		PushStrategy:      dbAgent.PushStrategy,
+		PushTargetWeights: pushWeights,
+		Headers:           headers,
+		APIKey:            dbAgent.APIKey,
+		SupportedSchemas:  supportedSchemas,
+		RequiresSchema:    dbAgent.RequiresSchema,
+		AllowedSenders:    allowedSenders,
+		CreatedAt:         dbAgent.CreatedAt,
+
+		MaxConcurrentDeliveries: dbAgent.MaxConcurrentDeliveries,
 	}
 
 	if dbAgent.PushTarget != nil {
@@ -1016,27 +1695,68 @@ func (ds *DatabaseStorage) convertToLocalAgent(dbAgent *Agent) (*agents.LocalAge
 		localAgent.LastAccess = *dbAgent.LastAccess
 	}
 
+	if dbAgent.KeyRotatedAt != nil {
+		localAgent.KeyRotatedAt = *dbAgent.KeyRotatedAt
+	}
+
 	return localAgent, nil
 }
 
 // agentToUpdateMap prepares a map of fields to update for an agent
 func (ds *DatabaseStorage) agentToUpdateMap(agent *agents.LocalAgent) (map[string]interface{}, error) {
 	updates := map[string]interface{}{
-		"delivery_mode":   agent.DeliveryMode,
-		"api_key":         agent.APIKey,
-		"requires_schema": agent.RequiresSchema,
-		"push_target":     nil,
-		"last_access":     nil,
+		"delivery_mode":       agent.DeliveryMode,
+		"api_key":             agent.APIKey,
+		"requires_schema":     agent.RequiresSchema,
+		"push_target":         nil,
+		"push_targets":        nil,
+		"push_policy":         agent.PushPolicy,
+		"push_strategy":       agent.PushStrategy,
+		"push_target_weights": nil,
+		"allowed_senders":     nil,
+		"last_access":         nil,
+
+		"max_concurrent_deliveries": agent.MaxConcurrentDeliveries,
 	}
 
 	if agent.PushTarget != "" {
 		updates["push_target"] = agent.PushTarget
 	}
 
+	if len(agent.PushTargets) > 0 {
+		targetsJSON, err := json.Marshal(agent.PushTargets)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal push targets: %w", err)
+		}
+		updates["push_targets"] = datatypes.JSON(targetsJSON)
+	}
+
+	if len(agent.PushTargetWeights) > 0 {
+		weightsJSON, err := json.Marshal(agent.PushTargetWeights)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal push target weights: %w", err)
+		}
+		updates["push_target_weights"] = datatypes.JSON(weightsJSON)
+	}
+
+	if len(agent.AllowedSenders) > 0 {
+		sendersJSON, err := json.Marshal(agent.AllowedSenders)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal allowed senders: %w", err)
+		}
+		updates["allowed_senders"] = datatypes.JSON(sendersJSON)
+	}
+
 	if !agent.LastAccess.IsZero() {
 		updates["last_access"] = agent.LastAccess
 	}
 
+	// A rotation is never undone, so a copy of the agent read before it
+	// cannot clear it
+	if !agent.KeyRotatedAt.IsZero() {
+		updates["key_rotated_at"] = agent.KeyRotatedAt
+	}
+
 	headersJSON, err := json.Marshal(agent.Headers)
 	if err != nil {
 		return nil, fmt.Errorf("failed to marshal headers: %w", err)
@@ -38,18 +38,24 @@ const (
 	StatusRetrying   DeliveryStatus = "retrying"
 )
 
+// ErrorCodeAgentNotRegistered marks a queued recipient held until its agent
+// registers; ClaimMessagesForDelivery only leases messages with such recipients
+const ErrorCodeAgentNotRegistered = "AGENT_NOT_REGISTERED"
+
 // Message model
 type Message struct {
 	ID             uint      `gorm:"primarykey" json:"-"`
 	Version        string    `gorm:"size:10;not null;default:1.0" json:"version" validate:"required,eq=1.0"`
-	MessageID      string    `gorm:"type:uuid;uniqueIndex;not null" json:"message_id" validate:"required,uuidv7"`
+	MessageID      string    `gorm:"size:64;uniqueIndex;not null" json:"message_id" validate:"required,max=64"`
 	IdempotencyKey string    `gorm:"type:uuid;uniqueIndex;not null" json:"idempotency_key" validate:"required,uuid4"`
 	Timestamp      time.Time `gorm:"type:timestamptz;not null" json:"timestamp" validate:"required"`
 	Sender         string    `gorm:"size:255;not null" json:"sender" validate:"required,email"`
 	Subject        string    `gorm:"type:text" json:"subject,omitempty"`
 	Schema         string    `gorm:"type:text" json:"schema,omitempty"`
-	InReplyTo      *string   `gorm:"type:uuid" json:"in_reply_to,omitempty" validate:"omitempty,uuid"`
+	InReplyTo      *string   `gorm:"size:64" json:"in_reply_to,omitempty" validate:"omitempty,max=64"`
 	ResponseType   string    `gorm:"size:50" json:"response_type,omitempty"`
+	RequestReceipt bool      `gorm:"not null;default:false" json:"request_receipt,omitempty"`
+	Encrypted      bool      `gorm:"not null;default:false" json:"encrypted,omitempty"`
 
 	// JSON fields
 	Recipients   datatypes.JSON `gorm:"type:jsonb;not null" json:"recipients" validate:"required"`
@@ -58,6 +64,14 @@ type Message struct {
 	Payload      datatypes.JSON `gorm:"type:jsonb" json:"payload,omitempty"`
 	Attachments  datatypes.JSON `gorm:"type:jsonb" json:"attachments,omitempty"`
 	Signature    datatypes.JSON `gorm:"type:jsonb" json:"signature,omitempty"`
+	Labels       datatypes.JSON `gorm:"type:jsonb" json:"labels,omitempty"`
+
+	// Compressed payload and attachments, stored instead of the JSON columns
+	// when PayloadEncoding names a compression. Rows written without
+	// compression have an empty encoding, so both kinds can be read.
+	PayloadEncoding       string `gorm:"size:16;not null;default:''" json:"-"`
+	PayloadCompressed     []byte `gorm:"type:bytea" json:"-"`
+	AttachmentsCompressed []byte `gorm:"type:bytea" json:"-"`
 
 	// Relationships
 	MessageStatus   MessageStatus     `gorm:"foreignKey:MessageID;references:MessageID" json:"status,omitempty"`
@@ -67,19 +81,23 @@ type Message struct {
 // MessageStatus message status model
 type MessageStatus struct {
 	ID          uint           `gorm:"primarykey" json:"-"`
-	MessageID   string         `gorm:"type:uuid;uniqueIndex;not null" json:"message_id"`
+	MessageID   string         `gorm:"size:64;uniqueIndex;not null" json:"message_id"`
 	Status      DeliveryStatus `gorm:"type:delivery_status;not null;default:'pending'" json:"status"`
 	Attempts    int            `gorm:"not null;default:0" json:"attempts"`
 	NextRetry   *time.Time     `gorm:"type:timestamptz" json:"next_retry,omitempty"`
 	CreatedAt   time.Time      `gorm:"type:timestamptz;not null;default:now()" json:"created_at"`
 	UpdatedAt   time.Time      `gorm:"type:timestamptz;not null;default:now()" json:"updated_at"`
 	DeliveredAt *time.Time     `gorm:"type:timestamptz" json:"delivered_at,omitempty"`
+
+	// Lease held by the gateway retrying the message's queued recipients
+	LeaseOwner     *string    `gorm:"size:255" json:"lease_owner,omitempty"`
+	LeaseExpiresAt *time.Time `gorm:"type:timestamptz" json:"lease_expires_at,omitempty"`
 }
 
 // RecipientStatus recipient status model
 type RecipientStatus struct {
 	ID             uint           `gorm:"primarykey" json:"-"`
-	MessageID      string         `gorm:"type:uuid;index;not null" json:"message_id"`
+	MessageID      string         `gorm:"size:64;index;not null" json:"message_id"`
 	Address        string         `gorm:"size:255;not null" json:"address" validate:"email"`
 	Status         DeliveryStatus `gorm:"type:delivery_status;not null;default:'pending'" json:"status"`
 	Timestamp      time.Time      `gorm:"type:timestamptz;not null;default:now()" json:"timestamp"`
@@ -91,20 +109,40 @@ type RecipientStatus struct {
 	InboxDelivered bool           `gorm:"default:false" json:"inbox_delivered,omitempty"`
 	Acknowledged   bool           `gorm:"default:false" json:"acknowledged,omitempty"`
 	AcknowledgedAt *time.Time     `gorm:"type:timestamptz" json:"acknowledged_at,omitempty"`
+	Response       datatypes.JSON `gorm:"type:jsonb" json:"response,omitempty"`
 }
 
 // Agent model
 type Agent struct {
-	ID               uint           `gorm:"primarykey" json:"-"`
-	Address          string         `gorm:"size:255;uniqueIndex;not null" json:"address" validate:"required,email"`
-	DeliveryMode     string         `gorm:"size:10;not null;default:'push'" json:"delivery_mode" validate:"required,oneof=push pull"`
-	PushTarget       *string        `gorm:"type:text" json:"push_target,omitempty" validate:"omitempty,url"`
-	Headers          datatypes.JSON `gorm:"type:jsonb" json:"headers,omitempty"`
-	APIKey           string         `gorm:"size:64;not null" json:"api_key" validate:"required"`
-	SupportedSchemas datatypes.JSON `gorm:"type:jsonb;not null" json:"supported_schemas" validate:"required"`
-	RequiresSchema   bool           `gorm:"not null;default:false" json:"requires_schema"`
-	CreatedAt        time.Time      `gorm:"type:timestamptz;not null;default:now()" json:"created_at"`
-	LastAccess       *time.Time     `gorm:"type:timestamptz" json:"last_access,omitempty"`
+	ID                uint           `gorm:"primarykey" json:"-"`
+	Address           string         `gorm:"size:255;uniqueIndex;not null" json:"address" validate:"required,email"`
+	DeliveryMode      string         `gorm:"size:10;not null;default:'push'" json:"delivery_mode" validate:"required,oneof=push pull"`
+	PushTarget        *string        `gorm:"type:text" json:"push_target,omitempty" validate:"omitempty,url"`
+	PushTargets       datatypes.JSON `gorm:"type:jsonb" json:"push_targets,omitempty"`
+	PushPolicy        string         `gorm:"size:10" json:"push_policy,omitempty"`
+	PushStrategy      string         `gorm:"size:10" json:"push_strategy,omitempty"`
+	PushTargetWeights datatypes.JSON `gorm:"type:jsonb" json:"push_target_weights,omitempty"`
+	Headers           datatypes.JSON `gorm:"type:jsonb" json:"headers,omitempty"`
+	APIKey            string         `gorm:"size:64;index;not null" json:"api_key" validate:"required"`
+	SupportedSchemas  datatypes.JSON `gorm:"type:jsonb;not null" json:"supported_schemas" validate:"required"`
+	RequiresSchema    bool           `gorm:"not null;default:false" json:"requires_schema"`
+	AllowedSenders    datatypes.JSON `gorm:"type:jsonb" json:"allowed_senders,omitempty"`
+	CreatedAt         time.Time      `gorm:"type:timestamptz;not null;default:now()" json:"created_at"`
+	LastAccess        *time.Time     `gorm:"type:timestamptz" json:"last_access,omitempty"`
+	KeyRotatedAt      *time.Time     `gorm:"type:timestamptz" json:"key_rotated_at,omitempty"`
+
+	MaxConcurrentDeliveries int `gorm:"not null;default:0" json:"max_concurrent_deliveries,omitempty"`
+}
+
+// RawRequest raw send request model
+type RawRequest struct {
+	ID         uint           `gorm:"primarykey" json:"-"`
+	MessageID  string         `gorm:"size:64;uniqueIndex;not null" json:"message_id"`
+	ReceivedAt time.Time      `gorm:"type:timestamptz;not null;default:now()" json:"received_at"`
+	Headers    datatypes.JSON `gorm:"type:jsonb" json:"headers,omitempty"`
+	Body       []byte         `gorm:"type:bytea;not null" json:"body"`
+	Size       int64          `gorm:"not null;default:0" json:"size"`
+	Truncated  bool           `gorm:"not null;default:false" json:"truncated,omitempty"`
 }
 
 // Custom Gorm hooks and utility methods
@@ -161,16 +199,19 @@ func (m *Message) SetCoordination(coordination *types.CoordinationConfig) error
 
 // Schema model for persistence
 type Schema struct {
-	ID          uint           `gorm:"primarykey" json:"-"`
-	Domain      string         `gorm:"size:255;not null;uniqueIndex:idx_schema_ver" json:"domain"`
-	Entity      string         `gorm:"size:255;not null;uniqueIndex:idx_schema_ver" json:"entity"`
-	Version     string         `gorm:"size:64;not null;uniqueIndex:idx_schema_ver" json:"version"`
-	Definition  datatypes.JSON `gorm:"type:jsonb;not null" json:"definition"`
-	PublishedAt time.Time      `gorm:"type:timestamptz" json:"published_at"`
-	Signature   string         `gorm:"size:512" json:"signature"`
-	Checksum    string         `gorm:"size:64" json:"checksum"`
-	Size        int64          `gorm:"not null;default:0" json:"size"`
-	UpdatedAt   time.Time      `gorm:"type:timestamptz;default:CURRENT_TIMESTAMP" json:"updated_at"`
+	ID             uint           `gorm:"primarykey" json:"-"`
+	Domain         string         `gorm:"size:255;not null;uniqueIndex:idx_schema_ver" json:"domain"`
+	Entity         string         `gorm:"size:255;not null;uniqueIndex:idx_schema_ver" json:"entity"`
+	Version        string         `gorm:"size:64;not null;uniqueIndex:idx_schema_ver" json:"version"`
+	Definition     datatypes.JSON `gorm:"type:jsonb;not null" json:"definition"`
+	PublishedAt    time.Time      `gorm:"type:timestamptz" json:"published_at"`
+	Signature      string         `gorm:"size:512" json:"signature"`
+	Checksum       string         `gorm:"size:64" json:"checksum"`
+	Size           int64          `gorm:"not null;default:0" json:"size"`
+	Strict         bool           `gorm:"not null;default:false" json:"strict"`
+	ValidationMode string         `gorm:"size:16;not null;default:''" json:"validation_mode"`
+	Defaults       datatypes.JSON `gorm:"type:jsonb" json:"defaults,omitempty"`
+	UpdatedAt      time.Time      `gorm:"type:timestamptz;default:CURRENT_TIMESTAMP" json:"updated_at"`
 }
 
 // TableName specify table name
@@ -189,3 +230,7 @@ func (RecipientStatus) TableName() string {
 func (Schema) TableName() string {
 	return "schemas"
 }
+
+func (RawRequest) TableName() string {
+	return "raw_requests"
+}
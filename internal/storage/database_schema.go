@@ -20,6 +20,7 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 
 	"github.com/amtp-protocol/agentry/internal/schema"
 	"gorm.io/datatypes"
@@ -29,12 +30,21 @@ import (
 // StoreSchema stores a schema in the database
 func (s *DatabaseStorage) StoreSchema(ctx context.Context, sc *schema.Schema, meta *schema.SchemaMetadata) error {
 	model := Schema{
-		Domain:      sc.ID.Domain,
-		Entity:      sc.ID.Entity,
-		Version:     sc.ID.Version,
-		Definition:  datatypes.JSON(sc.Definition),
-		PublishedAt: sc.PublishedAt,
-		Signature:   sc.Signature,
+		Domain:         sc.ID.Domain,
+		Entity:         sc.ID.Entity,
+		Version:        sc.ID.Version,
+		Definition:     datatypes.JSON(sc.Definition),
+		PublishedAt:    sc.PublishedAt,
+		Signature:      sc.Signature,
+		Strict:         sc.Strict,
+		ValidationMode: sc.ValidationMode,
+	}
+	if !sc.Defaults.IsEmpty() {
+		defaults, err := json.Marshal(sc.Defaults)
+		if err != nil {
+			return fmt.Errorf("failed to encode schema defaults: %w", err)
+		}
+		model.Defaults = datatypes.JSON(defaults)
 	}
 
 	if meta != nil {
@@ -44,8 +54,15 @@ func (s *DatabaseStorage) StoreSchema(ctx context.Context, sc *schema.Schema, me
 		// Using database timestamps instead of meta timestamps to reflect storage time
 	}
 
-	result := s.db.WithContext(ctx).Create(&model)
-	return result.Error
+	// The unique index on domain, entity and version settles concurrent
+	// registrations of one schema
+	if err := s.db.WithContext(ctx).Create(&model).Error; err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return fmt.Errorf("%w: %s", schema.ErrSchemaExists, sc.ID.String())
+		}
+		return err
+	}
+	return nil
 }
 
 // GetSchema retrieves a schema from the database
@@ -130,14 +147,23 @@ func (s *DatabaseStorage) GetRegistryStats(ctx context.Context) (*schema.Registr
 }
 
 func toSchemaDomain(m *Schema) *schema.Schema {
-	return &schema.Schema{
+	sc := &schema.Schema{
 		ID: schema.SchemaIdentifier{
 			Domain:  m.Domain,
 			Entity:  m.Entity,
 			Version: m.Version,
 		},
-		Definition:  json.RawMessage(m.Definition),
-		PublishedAt: m.PublishedAt,
-		Signature:   m.Signature,
+		Definition:     json.RawMessage(m.Definition),
+		PublishedAt:    m.PublishedAt,
+		Signature:      m.Signature,
+		Strict:         m.Strict,
+		ValidationMode: m.ValidationMode,
+	}
+	if len(m.Defaults) > 0 {
+		var defaults schema.MessageDefaults
+		if err := json.Unmarshal(m.Defaults, &defaults); err == nil {
+			sc.Defaults = &defaults
+		}
 	}
+	return sc
 }
@@ -19,6 +19,7 @@ package storage
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"testing"
 	"time"
 
@@ -65,7 +66,7 @@ func TestDatabaseStorage_StoreSchema(t *testing.T) {
 
 	mock.ExpectBegin()
 	mock.ExpectQuery(`INSERT INTO "schemas"`).
-		WithArgs(testSchema.ID.Domain, testSchema.ID.Entity, testSchema.ID.Version, string(testSchema.Definition), sqlmock.AnyArg(), testSchema.Signature, sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WithArgs(testSchema.ID.Domain, testSchema.ID.Entity, testSchema.ID.Version, string(testSchema.Definition), sqlmock.AnyArg(), testSchema.Signature, sqlmock.AnyArg(), sqlmock.AnyArg(), false, "").
 		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
 	mock.ExpectCommit()
 
@@ -74,6 +75,16 @@ func TestDatabaseStorage_StoreSchema(t *testing.T) {
 		t.Errorf("StoreSchema failed: %v", err)
 	}
 
+	// The unique index rejects a second registration of the same schema
+	mock.ExpectBegin()
+	mock.ExpectQuery(`INSERT INTO "schemas"`).WillReturnError(gorm.ErrDuplicatedKey)
+	mock.ExpectRollback()
+
+	err = storage.StoreSchema(ctx, testSchema, nil)
+	if !errors.Is(err, schema.ErrSchemaExists) {
+		t.Errorf("expected ErrSchemaExists, got %v", err)
+	}
+
 	if err := mock.ExpectationsWereMet(); err != nil {
 		t.Errorf("expectations not met: %v", err)
 	}
@@ -4,9 +4,12 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"os"
 	"reflect"
 	"regexp"
 	"sort"
+	"strings"
 	"testing"
 	"time"
 
@@ -161,6 +164,7 @@ func TestDeleteMessage_Success(t *testing.T) {
 	mock.ExpectQuery(regexp.QuoteMeta(`SELECT count(*) FROM "messages" WHERE message_id = $1`)).WithArgs("id").WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
 	mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM "recipient_statuses" WHERE message_id = $1`)).WithArgs("id").WillReturnResult(sqlmock.NewResult(0, 1))
 	mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM "message_statuses" WHERE message_id = $1`)).WithArgs("id").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM "raw_requests" WHERE message_id = $1`)).WithArgs("id").WillReturnResult(sqlmock.NewResult(0, 0))
 	mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM "messages" WHERE message_id = $1`)).WithArgs("id").WillReturnResult(sqlmock.NewResult(0, 1))
 	mock.ExpectCommit()
 
@@ -172,6 +176,72 @@ func TestDeleteMessage_Success(t *testing.T) {
 	}
 }
 
+func TestStoreRawRequest(t *testing.T) {
+	gormDB, mock := newMockDB(t)
+	sqlDB, _ := gormDB.DB()
+	defer sqlDB.Close()
+	storage := &DatabaseStorage{db: gormDB}
+
+	receivedAt := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "raw_requests"`)).WithArgs(
+		"id",
+		`{"Content-Type":"application/json"}`,
+		[]byte(`{"sender":"a@localhost"}`),
+		int64(24),
+		false,
+		receivedAt,
+	).WillReturnRows(sqlmock.NewRows([]string{"received_at", "id"}).AddRow(receivedAt, 1))
+	mock.ExpectCommit()
+
+	err := storage.StoreRawRequest(context.Background(), &types.RawRequest{
+		MessageID:  "id",
+		ReceivedAt: receivedAt,
+		Headers:    map[string]string{"Content-Type": "application/json"},
+		Body:       `{"sender":"a@localhost"}`,
+		Size:       24,
+	})
+	if err != nil {
+		t.Fatalf("StoreRawRequest failed: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unfulfilled expectations: %v", err)
+	}
+
+	if err := storage.StoreRawRequest(context.Background(), &types.RawRequest{}); err == nil {
+		t.Fatalf("expected error for empty message id")
+	}
+}
+
+func TestGetRawRequest(t *testing.T) {
+	gormDB, mock := newMockDB(t)
+	sqlDB, _ := gormDB.DB()
+	defer sqlDB.Close()
+	storage := &DatabaseStorage{db: gormDB}
+
+	query := regexp.QuoteMeta(`SELECT * FROM "raw_requests" WHERE message_id = $1 ORDER BY "raw_requests"."id" LIMIT $2`)
+	mock.ExpectQuery(query).WithArgs("id", 1).WillReturnRows(
+		sqlmock.NewRows([]string{"id", "message_id", "received_at", "headers", "body", "size", "truncated"}).
+			AddRow(1, "id", time.Now(), []byte(`{"User-Agent":"client/1.0"}`), []byte(`{"sen`), 24, true),
+	)
+	mock.ExpectQuery(query).WithArgs("not-exist", 1).WillReturnError(gorm.ErrRecordNotFound)
+
+	raw, err := storage.GetRawRequest(context.Background(), "id")
+	if err != nil {
+		t.Fatalf("GetRawRequest failed: %v", err)
+	}
+	if raw.Body != `{"sen` || raw.Size != 24 || !raw.Truncated || raw.Headers["User-Agent"] != "client/1.0" {
+		t.Errorf("unexpected raw request: %+v", raw)
+	}
+
+	if _, err := storage.GetRawRequest(context.Background(), "not-exist"); err == nil || !strings.Contains(err.Error(), "raw request not found") {
+		t.Errorf("expected not found error, got %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unfulfilled expectations: %v", err)
+	}
+}
+
 func TestDeleteMessage_NotFound(t *testing.T) {
 	gormDB, mock := newMockDB(t)
 	sqlDB, _ := gormDB.DB()
@@ -226,7 +296,7 @@ func TestListMessages_WithFilters(t *testing.T) {
 	}
 	// Expect the actual query generated by GORM with all filters applied
 	recipientsJSON := `["recipient@example.com"]`
-	mock.ExpectQuery(regexp.QuoteMeta(`SELECT "messages"."id","messages"."version","messages"."message_id","messages"."idempotency_key","messages"."timestamp","messages"."sender","messages"."subject","messages"."schema","messages"."in_reply_to","messages"."response_type","messages"."recipients","messages"."coordination","messages"."headers","messages"."payload","messages"."attachments","messages"."signature" FROM "messages" JOIN message_statuses ON messages.message_id = message_statuses.message_id WHERE sender = $1 AND recipients @> $2 AND message_statuses.status = $3 AND timestamp >= $4 ORDER BY created_at DESC LIMIT $5 OFFSET $6`)).WithArgs(
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT "messages"."id","messages"."version","messages"."message_id","messages"."idempotency_key","messages"."timestamp","messages"."sender","messages"."subject","messages"."schema","messages"."in_reply_to","messages"."response_type","messages"."request_receipt","messages"."encrypted","messages"."recipients","messages"."coordination","messages"."headers","messages"."payload","messages"."attachments","messages"."signature","messages"."labels","messages"."payload_encoding","messages"."payload_compressed","messages"."attachments_compressed" FROM "messages" JOIN message_statuses ON messages.message_id = message_statuses.message_id WHERE sender = $1 AND recipients @> $2 AND message_statuses.status = $3 AND timestamp >= $4 ORDER BY messages.timestamp DESC, messages.id DESC LIMIT $5 OFFSET $6`)).WithArgs(
 		filter.Sender,
 		recipientsJSON,
 		filter.Status,
@@ -246,6 +316,91 @@ func TestListMessages_WithFilters(t *testing.T) {
 	}
 }
 
+func TestListMessages_LabelFilter(t *testing.T) {
+	gormDB, mock := newMockDB(t)
+	sqlDB, _ := gormDB.DB()
+	defer sqlDB.Close()
+	storage := &DatabaseStorage{db: gormDB}
+
+	mock.ExpectQuery(`WHERE labels @> \$1 ORDER BY messages.timestamp DESC, messages.id DESC`).
+		WithArgs(`["campaign-2024","eu"]`).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "message_id", "recipients", "labels"}).
+			AddRow(1, "id", `["r@example.com"]`, `["campaign-2024","eu"]`))
+
+	msgs, err := storage.ListMessages(context.Background(), MessageFilter{Labels: []string{"campaign-2024", "eu"}})
+	if err != nil {
+		t.Fatalf("ListMessages with labels failed: %v", err)
+	}
+	if len(msgs) != 1 || strings.Join(msgs[0].Labels, ",") != "campaign-2024,eu" {
+		t.Errorf("Expected the message with its labels, got %+v", msgs)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+// messageColumns returns the columns of the messages table as created and
+// upgraded by deployment/db/01-message.sql
+func messageColumns(t *testing.T) map[string]bool {
+	t.Helper()
+	schema, err := os.ReadFile("../../deployment/db/01-message.sql")
+	if err != nil {
+		t.Fatalf("failed to read schema: %v", err)
+	}
+	table := regexp.MustCompile(`(?s)CREATE TABLE IF NOT EXISTS messages \((.*?)\n\);`).FindSubmatch(schema)
+	if table == nil {
+		t.Fatal("messages table not found in schema")
+	}
+	columns := make(map[string]bool)
+	for _, m := range regexp.MustCompile(`(?m)^\s+([a-z_]+)\s+[A-Z]`).FindAllSubmatch(table[1], -1) {
+		columns[string(m[1])] = true
+	}
+	for _, m := range regexp.MustCompile(`ALTER TABLE messages ADD COLUMN IF NOT EXISTS ([a-z_]+)`).FindAllSubmatch(schema, -1) {
+		columns[string(m[1])] = true
+	}
+	return columns
+}
+
+// TestListMessages_OrderMatchesSchema checks the columns ListMessages
+// orders by against the deployed schema, with and without the status join
+func TestListMessages_OrderMatchesSchema(t *testing.T) {
+	columns := messageColumns(t)
+
+	for _, filter := range []MessageFilter{{}, {Status: "pending"}} {
+		var query string
+		mockDB, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherFunc(func(_, actual string) error {
+			query = actual
+			return nil
+		})))
+		if err != nil {
+			t.Fatalf("failed to create sqlmock: %v", err)
+		}
+		gormDB, err := gorm.Open(postgres.New(postgres.Config{Conn: mockDB}), &gorm.Config{})
+		if err != nil {
+			t.Fatalf("failed to open gorm DB: %v", err)
+		}
+		mock.ExpectQuery("").WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+		storage := &DatabaseStorage{db: gormDB}
+		if _, err := storage.ListMessages(context.Background(), filter); err != nil {
+			t.Fatalf("ListMessages failed: %v", err)
+		}
+		mockDB.Close()
+
+		order := regexp.MustCompile(`ORDER BY (.*)$`).FindStringSubmatch(query)
+		if order == nil {
+			t.Fatalf("expected an ORDER BY clause in %q", query)
+		}
+		for _, term := range strings.Split(order[1], ",") {
+			column := strings.Fields(term)[0]
+			table, name, qualified := strings.Cut(column, ".")
+			if !qualified || table != "messages" || !columns[name] {
+				t.Errorf("ORDER BY %s is not a column of the messages table (status filter %q)", column, filter.Status)
+			}
+		}
+	}
+}
+
 func TestStoreStatus_NilStatus(t *testing.T) {
 	gormDB, _ := newMockDB(t)
 	sqlDB, _ := gormDB.DB()
@@ -316,6 +471,48 @@ func TestGetStatus_NotFound(t *testing.T) {
 	}
 }
 
+func TestGetStatusByIdempotencyKey_Success(t *testing.T) {
+	gormDB, mock := newMockDB(t)
+	sqlDB, _ := gormDB.DB()
+	defer sqlDB.Close()
+	ds := &DatabaseStorage{db: gormDB}
+
+	now := time.Now()
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT "message_id" FROM "messages" WHERE idempotency_key = $1 ORDER BY "messages"."id" LIMIT $2`)).WithArgs("ik", 1).WillReturnRows(
+		sqlmock.NewRows([]string{"message_id"}).AddRow("id"),
+	)
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "message_statuses" WHERE message_id = $1 ORDER BY "message_statuses"."id" LIMIT $2`)).WithArgs("id", 1).WillReturnRows(
+		sqlmock.NewRows([]string{"message_id", "status", "attempts", "created_at", "updated_at"}).AddRow("id", "delivered", 1, now, now),
+	)
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "recipient_statuses" WHERE message_id = $1`)).WithArgs("id").WillReturnRows(
+		sqlmock.NewRows([]string{"address", "status", "timestamp"}))
+
+	st, err := ds.GetStatusByIdempotencyKey(context.Background(), "ik")
+	if err != nil {
+		t.Fatalf("GetStatusByIdempotencyKey failed: %v", err)
+	}
+	if st.MessageID != "id" {
+		t.Fatalf("unexpected status: %+v", st)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestGetStatusByIdempotencyKey_NotFound(t *testing.T) {
+	gormDB, mock := newMockDB(t)
+	sqlDB, _ := gormDB.DB()
+	defer sqlDB.Close()
+	ds := &DatabaseStorage{db: gormDB}
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT "message_id" FROM "messages" WHERE idempotency_key = $1`)).WithArgs("missing", 1).WillReturnError(gorm.ErrRecordNotFound)
+	if _, err := ds.GetStatusByIdempotencyKey(context.Background(), "missing"); err == nil || !strings.Contains(err.Error(), "message not found") {
+		t.Fatalf("expected not found error, got %v", err)
+	}
+	if _, err := ds.GetStatusByIdempotencyKey(context.Background(), ""); err == nil {
+		t.Fatalf("expected error for empty idempotency key")
+	}
+}
+
 func TestUpdateStatus_Success(t *testing.T) {
 	gormDB, mock := newMockDB(t)
 	sqlDB, _ := gormDB.DB()
@@ -430,6 +627,219 @@ func TestGetInboxMessages_Success(t *testing.T) {
 	}
 }
 
+func TestListInboxMessages_Pagination(t *testing.T) {
+	gormDB, mock := newMockDB(t)
+	sqlDB, _ := gormDB.DB()
+	defer sqlDB.Close()
+	storage := &DatabaseStorage{db: gormDB}
+
+	now := time.Now()
+	columns := []string{"id", "version", "message_id", "idempotency_key", "timestamp", "sender", "subject", "schema", "in_reply_to", "response_type", "recipients"}
+	mock.ExpectQuery(`SELECT.*FROM "messages" JOIN recipient_statuses .* WHERE messages\.id > \$1 .* ORDER BY messages\.id ASC LIMIT \$6`).
+		WithArgs(7, "r@example.com", true, true, false, 3).
+		WillReturnRows(sqlmock.NewRows(columns).
+			AddRow(8, "1.0", "m8", "ik8", now, "s@example.com", "", "", nil, "", `["r@example.com"]`).
+			AddRow(9, "1.0", "m9", "ik9", now, "s@example.com", "", "", nil, "", `["r@example.com"]`).
+			AddRow(12, "1.0", "m12", "ik12", now, "s@example.com", "", "", nil, "", `["r@example.com"]`))
+
+	msgs, next, err := storage.ListInboxMessages(context.Background(), "r@example.com", "7", 2)
+	if err != nil {
+		t.Fatalf("ListInboxMessages failed: %v", err)
+	}
+	if len(msgs) != 2 || msgs[0].MessageID != "m8" || msgs[1].MessageID != "m9" {
+		t.Fatalf("unexpected page: %+v", msgs)
+	}
+	if next != "9" {
+		t.Errorf("expected next cursor 9, got %q", next)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unfulfilled expectations: %v", err)
+	}
+
+	if _, _, err := storage.ListInboxMessages(context.Background(), "r@example.com", "abc", 2); err == nil {
+		t.Error("expected error for invalid cursor")
+	}
+}
+
+func TestExportMessages_Pagination(t *testing.T) {
+	gormDB, mock := newMockDB(t)
+	sqlDB, _ := gormDB.DB()
+	defer sqlDB.Close()
+	storage := &DatabaseStorage{db: gormDB}
+
+	now := time.Now()
+	columns := []string{"id", "version", "message_id", "idempotency_key", "timestamp", "sender", "subject", "schema", "in_reply_to", "response_type", "recipients"}
+	mock.ExpectQuery(`SELECT \* FROM "messages" WHERE id > \$1 AND \(sender = \$2 OR recipients @> \$3\) ORDER BY id ASC LIMIT \$4`).
+		WithArgs(7, "r@example.com", `["r@example.com"]`, 3).
+		WillReturnRows(sqlmock.NewRows(columns).
+			AddRow(8, "1.0", "m8", "ik8", now, "r@example.com", "", "", nil, "", `["x@example.com"]`).
+			AddRow(9, "1.0", "m9", "ik9", now, "s@example.com", "", "", nil, "", `["r@example.com"]`).
+			AddRow(12, "1.0", "m12", "ik12", now, "s@example.com", "", "", nil, "", `["r@example.com"]`))
+
+	msgs, next, err := storage.ExportMessages(context.Background(), "r@example.com", "7", 2)
+	if err != nil {
+		t.Fatalf("ExportMessages failed: %v", err)
+	}
+	if len(msgs) != 2 || msgs[0].MessageID != "m8" || msgs[1].MessageID != "m9" {
+		t.Fatalf("unexpected page: %+v", msgs)
+	}
+	if next != "9" {
+		t.Errorf("expected next cursor 9, got %q", next)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestListMessagesBySchema_Pagination(t *testing.T) {
+	gormDB, mock := newMockDB(t)
+	sqlDB, _ := gormDB.DB()
+	defer sqlDB.Close()
+	storage := &DatabaseStorage{db: gormDB}
+
+	now := time.Now()
+	columns := []string{"id", "version", "message_id", "idempotency_key", "timestamp", "sender", "subject", "schema", "in_reply_to", "response_type", "recipients"}
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "messages" WHERE id > $1 AND schema IN ($2,$3) ORDER BY id ASC LIMIT $4`)).
+		WithArgs(0, "agntcy:commerce.order.v1", "agntcy:commerce.order", 3).
+		WillReturnRows(sqlmock.NewRows(columns).
+			AddRow(3, "1.0", "m3", "ik3", now, "s@example.com", "", "agntcy:commerce.order.v1", nil, "", `["r@example.com"]`).
+			AddRow(5, "1.0", "m5", "ik5", now, "s@example.com", "", "agntcy:commerce.order", nil, "", `["r@example.com"]`).
+			AddRow(6, "1.0", "m6", "ik6", now, "s@example.com", "", "agntcy:commerce.order.v1", nil, "", `["r@example.com"]`))
+
+	msgs, next, err := storage.ListMessagesBySchema(context.Background(), []string{"agntcy:commerce.order.v1", "agntcy:commerce.order"}, "", 2)
+	if err != nil {
+		t.Fatalf("ListMessagesBySchema failed: %v", err)
+	}
+	if len(msgs) != 2 || msgs[0].MessageID != "m3" || msgs[1].MessageID != "m5" {
+		t.Fatalf("unexpected page: %+v", msgs)
+	}
+	if next != "5" {
+		t.Errorf("expected next cursor 5, got %q", next)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unfulfilled expectations: %v", err)
+	}
+
+	if _, _, err := storage.ListMessagesBySchema(context.Background(), nil, "", 2); err == nil {
+		t.Error("expected error for empty schema ID")
+	}
+	if _, _, err := storage.ListMessagesBySchema(context.Background(), []string{"agntcy:commerce.order.v1"}, "abc", 2); !errors.Is(err, ErrInvalidCursor) {
+		t.Errorf("expected ErrInvalidCursor, got %v", err)
+	}
+}
+
+func TestListStatuses_Pagination(t *testing.T) {
+	gormDB, mock := newMockDB(t)
+	sqlDB, _ := gormDB.DB()
+	defer sqlDB.Close()
+	storage := &DatabaseStorage{db: gormDB}
+
+	now := time.Now()
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "message_statuses" WHERE id > $1 ORDER BY id ASC LIMIT $2`)).
+		WithArgs(4, 3).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "message_id", "status", "attempts", "created_at", "updated_at"}).
+			AddRow(5, "m5", "delivered", 1, now, now).
+			AddRow(7, "m7", "delivering", 1, now, now).
+			AddRow(8, "m8", "queued", 0, now, now))
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "recipient_statuses" WHERE message_id IN ($1,$2)`)).
+		WithArgs("m5", "m7").
+		WillReturnRows(sqlmock.NewRows([]string{"message_id", "address", "status", "timestamp"}).
+			AddRow("m5", "a@example.com", "failed", now).
+			AddRow("m7", "a@example.com", "delivered", now).
+			AddRow("m7", "b@example.com", "queued", now))
+
+	statuses, next, err := storage.ListStatuses(context.Background(), "4", 2)
+	if err != nil {
+		t.Fatalf("ListStatuses failed: %v", err)
+	}
+	if len(statuses) != 2 || statuses[0].MessageID != "m5" || statuses[1].MessageID != "m7" {
+		t.Fatalf("unexpected page: %+v", statuses)
+	}
+	if len(statuses[0].Recipients) != 1 || len(statuses[1].Recipients) != 2 {
+		t.Errorf("expected recipients grouped by message, got %+v", statuses)
+	}
+	if next != "7" {
+		t.Errorf("expected next cursor 7, got %q", next)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unfulfilled expectations: %v", err)
+	}
+
+	if _, _, err := storage.ListStatuses(context.Background(), "abc", 2); err == nil {
+		t.Error("expected error for invalid cursor")
+	}
+}
+
+func TestCountMessages(t *testing.T) {
+	gormDB, mock := newMockDB(t)
+	sqlDB, _ := gormDB.DB()
+	defer sqlDB.Close()
+	storage := &DatabaseStorage{db: gormDB}
+
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT count(*) FROM "messages" WHERE sender = $1`)).
+		WithArgs("sender@example.com").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(42))
+
+	count, err := storage.CountMessages(context.Background(), MessageFilter{Sender: "sender@example.com", Limit: 10, Offset: 20})
+	if err != nil {
+		t.Fatalf("CountMessages failed: %v", err)
+	}
+	if count != 42 {
+		t.Errorf("expected 42 messages, got %d", count)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestGetStatuses(t *testing.T) {
+	gormDB, mock := newMockDB(t)
+	sqlDB, _ := gormDB.DB()
+	defer sqlDB.Close()
+	storage := &DatabaseStorage{db: gormDB}
+
+	now := time.Now()
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "message_statuses" WHERE message_id IN ($1,$2,$3)`)).
+		WithArgs("m1", "m2", "m3").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "message_id", "status", "attempts", "created_at", "updated_at"}).
+			AddRow(1, "m1", "delivered", 1, now, now).
+			AddRow(2, "m2", "queued", 0, now, now))
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "recipient_statuses" WHERE message_id IN ($1,$2,$3)`)).
+		WithArgs("m1", "m2", "m3").
+		WillReturnRows(sqlmock.NewRows([]string{"message_id", "address", "status", "timestamp"}).
+			AddRow("m1", "a@example.com", "delivered", now).
+			AddRow("m2", "a@example.com", "queued", now).
+			AddRow("m2", "b@example.com", "queued", now))
+
+	statuses, err := storage.GetStatuses(context.Background(), []string{"m1", "m2", "m3"})
+	if err != nil {
+		t.Fatalf("GetStatuses failed: %v", err)
+	}
+	if len(statuses) != 2 || statuses["m1"].Status != types.StatusDelivered || len(statuses["m2"].Recipients) != 2 {
+		t.Errorf("unexpected statuses: %+v", statuses)
+	}
+	if _, ok := statuses["m3"]; ok {
+		t.Error("expected no status for a message without one")
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestExportMessages_InvalidArgs(t *testing.T) {
+	gormDB, _ := newMockDB(t)
+	sqlDB, _ := gormDB.DB()
+	defer sqlDB.Close()
+	storage := &DatabaseStorage{db: gormDB}
+
+	if _, _, err := storage.ExportMessages(context.Background(), "", "", 10); err == nil {
+		t.Error("expected error for empty address")
+	}
+	if _, _, err := storage.ExportMessages(context.Background(), "r@example.com", "abc", 10); err == nil {
+		t.Error("expected error for invalid cursor")
+	}
+}
+
 func TestGetInboxMessages_EmptyRecipient(t *testing.T) {
 	gormDB, _ := newMockDB(t)
 	sqlDB, _ := gormDB.DB()
@@ -455,7 +865,7 @@ func TestAcknowledgeMessage_Success(t *testing.T) {
 	mock.ExpectExec(regexp.QuoteMeta(`UPDATE "message_statuses" SET`)).WillReturnResult(sqlmock.NewResult(1, 1))
 	mock.ExpectCommit()
 
-	if err := storage.AcknowledgeMessage(context.Background(), "r@example.com", "id"); err != nil {
+	if err := storage.AcknowledgeMessage(context.Background(), "r@example.com", "id", nil); err != nil {
 		t.Fatalf("AcknowledgeMessage failed: %v", err)
 	}
 	if err := mock.ExpectationsWereMet(); err != nil {
@@ -471,7 +881,7 @@ func TestAcknowledgeMessage_AlreadyAcknowledged(t *testing.T) {
 	mock.ExpectBegin()
 	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "recipient_statuses" WHERE message_id = $1 AND address = $2 ORDER BY "recipient_statuses"."id" LIMIT $3`)).WithArgs("id", "recipient@example.com", 1).WillReturnRows(sqlmock.NewRows([]string{"local_delivery", "inbox_delivered", "acknowledged"}).AddRow(true, true, true))
 	mock.ExpectRollback()
-	err := storage.AcknowledgeMessage(context.Background(), "recipient@example.com", "id")
+	err := storage.AcknowledgeMessage(context.Background(), "recipient@example.com", "id", nil)
 	if err == nil || !regexp.MustCompile(`message already acknowledged`).MatchString(err.Error()) {
 		t.Errorf("expected already acknowledged error, got: %v", err)
 	}
@@ -480,15 +890,183 @@ func TestAcknowledgeMessage_AlreadyAcknowledged(t *testing.T) {
 	}
 }
 
+func TestClaimMessagesForDelivery(t *testing.T) {
+	gormDB, mock := newMockDB(t)
+	sqlDB, _ := gormDB.DB()
+	defer sqlDB.Close()
+	storage := &DatabaseStorage{db: gormDB}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT "id","message_id" FROM "message_statuses" WHERE (EXISTS (SELECT 1 FROM recipient_statuses WHERE recipient_statuses.message_id = message_statuses.message_id AND recipient_statuses.status = $1 AND recipient_statuses.error_code = $2)) AND (lease_expires_at IS NULL OR lease_expires_at < $3 OR lease_owner = $4) ORDER BY lease_expires_at ASC NULLS FIRST, id ASC LIMIT $5 FOR UPDATE SKIP LOCKED`)).
+		WithArgs(StatusQueued, ErrorCodeAgentNotRegistered, sqlmock.AnyArg(), "gateway-a", 10).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "message_id"}).AddRow(3, "m3").AddRow(7, "m7"))
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE "message_statuses" SET "lease_expires_at"=$1,"lease_owner"=$2 WHERE id IN ($3,$4)`)).
+		WithArgs(sqlmock.AnyArg(), "gateway-a", 3, 7).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectCommit()
+
+	claimed, err := storage.ClaimMessagesForDelivery(context.Background(), "gateway-a", 10, time.Minute)
+	if err != nil {
+		t.Fatalf("ClaimMessagesForDelivery failed: %v", err)
+	}
+	if len(claimed) != 2 || claimed[0] != "m3" || claimed[1] != "m7" {
+		t.Errorf("expected m3 and m7 to be claimed, got %v", claimed)
+	}
+
+	// Nothing claimable leaves the table untouched
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT "id","message_id" FROM "message_statuses"`)).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "message_id"}))
+	mock.ExpectCommit()
+
+	if claimed, err := storage.ClaimMessagesForDelivery(context.Background(), "gateway-a", 10, time.Minute); err != nil || len(claimed) != 0 {
+		t.Errorf("expected nothing claimed, got %v, %v", claimed, err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unfulfilled expectations: %v", err)
+	}
+
+	for _, tt := range []struct {
+		worker string
+		limit  int
+		lease  time.Duration
+	}{
+		{"", 10, time.Minute},
+		{"gateway-a", 0, time.Minute},
+		{"gateway-a", 10, 0},
+	} {
+		if _, err := storage.ClaimMessagesForDelivery(context.Background(), tt.worker, tt.limit, tt.lease); err == nil {
+			t.Errorf("expected an error for worker %q, limit %d, lease %v", tt.worker, tt.limit, tt.lease)
+		}
+	}
+}
+
+func TestDrainInbox_Success(t *testing.T) {
+	gormDB, mock := newMockDB(t)
+	sqlDB, _ := gormDB.DB()
+	defer sqlDB.Close()
+	storage := &DatabaseStorage{db: gormDB}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT "message_id" FROM "recipient_statuses" WHERE address = $1 AND local_delivery = $2 AND inbox_delivered = $3 AND acknowledged = $4`)).
+		WithArgs("r@example.com", true, true, false).
+		WillReturnRows(sqlmock.NewRows([]string{"message_id"}).AddRow("m1").AddRow("m2"))
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE "recipient_statuses" SET`)).WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE "message_statuses" SET "updated_at"=$1 WHERE message_id IN ($2,$3)`)).WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectCommit()
+
+	drained, err := storage.DrainInbox(context.Background(), "r@example.com")
+	if err != nil {
+		t.Fatalf("DrainInbox failed: %v", err)
+	}
+	if drained != 2 {
+		t.Errorf("expected 2 drained messages, got %d", drained)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestDrainInbox_RollsBackOnFailure(t *testing.T) {
+	gormDB, mock := newMockDB(t)
+	sqlDB, _ := gormDB.DB()
+	defer sqlDB.Close()
+	storage := &DatabaseStorage{db: gormDB}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT "message_id" FROM "recipient_statuses"`)).
+		WillReturnRows(sqlmock.NewRows([]string{"message_id"}).AddRow("m1"))
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE "recipient_statuses" SET`)).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE "message_statuses" SET`)).WillReturnError(gorm.ErrInvalidTransaction)
+	mock.ExpectRollback()
+
+	if drained, err := storage.DrainInbox(context.Background(), "r@example.com"); err == nil || drained != 0 {
+		t.Errorf("expected an error and no drained messages, got %d, %v", drained, err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestListDeliveryErrors(t *testing.T) {
+	gormDB, mock := newMockDB(t)
+	sqlDB, _ := gormDB.DB()
+	defer sqlDB.Close()
+	storage := &DatabaseStorage{db: gormDB}
+
+	since := time.Now().UTC().Add(-time.Hour)
+	failedAt := time.Now().UTC()
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "recipient_statuses" WHERE status = $1 AND timestamp >= $2 ORDER BY timestamp DESC,id DESC LIMIT $3`)).
+		WithArgs(StatusFailed, since, 50).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "message_id", "address", "status", "timestamp", "attempts", "error_code", "error_message", "delivery_mode"}).
+			AddRow(7, "m1", "a@remote.com", "failed", failedAt, 3, "DELIVERY_FAILED", "connection refused", "push"))
+
+	deliveryErrors, err := storage.ListDeliveryErrors(context.Background(), &since, 50)
+	if err != nil {
+		t.Fatalf("ListDeliveryErrors failed: %v", err)
+	}
+	expected := types.DeliveryError{
+		MessageID:    "m1",
+		Recipient:    "a@remote.com",
+		ErrorCode:    "DELIVERY_FAILED",
+		ErrorMessage: "connection refused",
+		Attempts:     3,
+		DeliveryMode: "push",
+		Timestamp:    failedAt,
+	}
+	if len(deliveryErrors) != 1 || deliveryErrors[0] != expected {
+		t.Errorf("unexpected delivery errors: %+v", deliveryErrors)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestListPendingInbox(t *testing.T) {
+	gormDB, mock := newMockDB(t)
+	sqlDB, _ := gormDB.DB()
+	defer sqlDB.Close()
+	storage := &DatabaseStorage{db: gormDB}
+
+	after := time.Unix(0, 1700000000000000000).UTC()
+	since := after.Add(time.Minute)
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "recipient_statuses" WHERE (local_delivery = $1 AND inbox_delivered = $2 AND acknowledged = $3) AND (timestamp > $4 OR (timestamp = $5 AND id > $6)) ORDER BY timestamp ASC,id ASC LIMIT $7`)).
+		WithArgs(true, true, false, after, after, 7, 2).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "message_id", "address", "timestamp"}).
+			AddRow(8, "m1", "a@localhost", since).
+			AddRow(9, "m2", "b@localhost", since))
+
+	pending, next, err := storage.ListPendingInbox(context.Background(), "1700000000000000000:7", 1)
+	if err != nil {
+		t.Fatalf("ListPendingInbox failed: %v", err)
+	}
+	expected := types.PendingInboxMessage{MessageID: "m1", Recipient: "a@localhost", Since: since}
+	if len(pending) != 1 || pending[0] != expected {
+		t.Errorf("unexpected page: %+v", pending)
+	}
+	if want := fmt.Sprintf("%d:8", since.UnixNano()); next != want {
+		t.Errorf("expected next cursor %q, got %q", want, next)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unfulfilled expectations: %v", err)
+	}
+
+	for _, cursor := range []string{"7", "abc:7", "1700000000000000000:x"} {
+		if _, _, err := storage.ListPendingInbox(context.Background(), cursor, 1); !errors.Is(err, ErrInvalidCursor) {
+			t.Errorf("cursor %q: expected ErrInvalidCursor, got %v", cursor, err)
+		}
+	}
+}
+
 func TestAcknowledgeMessage_EmptyArgs(t *testing.T) {
 	gormDB, _ := newMockDB(t)
 	sqlDB, _ := gormDB.DB()
 	defer sqlDB.Close()
 	ds := &DatabaseStorage{db: gormDB}
-	if err := ds.AcknowledgeMessage(context.Background(), "", "id"); err == nil {
+	if err := ds.AcknowledgeMessage(context.Background(), "", "id", nil); err == nil {
 		t.Fatalf("expected error for empty recipient")
 	}
-	if err := ds.AcknowledgeMessage(context.Background(), "r@example.com", ""); err == nil {
+	if err := ds.AcknowledgeMessage(context.Background(), "r@example.com", "", nil); err == nil {
 		t.Fatalf("expected error for empty message id")
 	}
 }
@@ -505,7 +1083,7 @@ func TestAcknowledgeMessage_NotAvailable(t *testing.T) {
 	)
 	mock.ExpectRollback()
 
-	err := storage.AcknowledgeMessage(context.Background(), "r@example.com", "id")
+	err := storage.AcknowledgeMessage(context.Background(), "r@example.com", "id", nil)
 	if err == nil || !regexp.MustCompile(`message not available in inbox`).MatchString(err.Error()) {
 		t.Fatalf("expected not available error, got: %v", err)
 	}
@@ -566,7 +1144,7 @@ func TestGetStats_Empty(t *testing.T) {
 	mock.ExpectQuery(regexp.QuoteMeta(`SELECT count(*) FROM "messages"`)).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
 	mock.ExpectQuery(regexp.QuoteMeta(`SELECT count(*) FROM "message_statuses"`)).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
 	mock.ExpectQuery(regexp.QuoteMeta(`SELECT status, COUNT(*) as count FROM "message_statuses" GROUP BY "status"`)).WillReturnRows(sqlmock.NewRows([]string{"status", "count"}))
-	mock.ExpectQuery(regexp.QuoteMeta(`SELECT inbox_delivered, acknowledged, COUNT(*) as count FROM "recipient_statuses" WHERE local_delivery = $1 GROUP BY inbox_delivered, acknowledged`)).WithArgs(true).WillReturnRows(sqlmock.NewRows([]string{"inbox_delivered", "acknowledged", "count"}))
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT inbox_delivered, acknowledged, COUNT(*) as count, MIN(timestamp) as oldest FROM "recipient_statuses" WHERE local_delivery = $1 GROUP BY inbox_delivered, acknowledged`)).WithArgs(true).WillReturnRows(sqlmock.NewRows([]string{"inbox_delivered", "acknowledged", "count", "oldest"}))
 
 	stats, err := storage.GetStats(context.Background())
 	if err != nil {
@@ -585,14 +1163,15 @@ func TestGetStats_NonEmpty(t *testing.T) {
 	sqlDB, _ := gormDB.DB()
 	defer sqlDB.Close()
 	storage := &DatabaseStorage{db: gormDB}
+	oldest := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
 
 	mock.ExpectQuery(regexp.QuoteMeta(`SELECT count(*) FROM "messages"`)).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(2))
 	mock.ExpectQuery(regexp.QuoteMeta(`SELECT count(*) FROM "message_statuses"`)).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(3))
 	mock.ExpectQuery(regexp.QuoteMeta(`SELECT status, COUNT(*) as count FROM "message_statuses" GROUP BY "status"`)).WillReturnRows(
 		sqlmock.NewRows([]string{"status", "count"}).AddRow("pending", 2).AddRow("delivered", 1),
 	)
-	mock.ExpectQuery(regexp.QuoteMeta(`SELECT inbox_delivered, acknowledged, COUNT(*) as count FROM "recipient_statuses" WHERE local_delivery = $1 GROUP BY inbox_delivered, acknowledged`)).WithArgs(true).WillReturnRows(
-		sqlmock.NewRows([]string{"inbox_delivered", "acknowledged", "count"}).AddRow(true, false, 1).AddRow(true, true, 1),
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT inbox_delivered, acknowledged, COUNT(*) as count, MIN(timestamp) as oldest FROM "recipient_statuses" WHERE local_delivery = $1 GROUP BY inbox_delivered, acknowledged`)).WithArgs(true).WillReturnRows(
+		sqlmock.NewRows([]string{"inbox_delivered", "acknowledged", "count", "oldest"}).AddRow(true, false, 1, oldest).AddRow(true, true, 1, oldest.Add(-time.Hour)),
 	)
 
 	stats, err := storage.GetStats(context.Background())
@@ -602,6 +1181,9 @@ func TestGetStats_NonEmpty(t *testing.T) {
 	if stats.TotalMessages != 2 || stats.TotalStatuses != 3 || stats.PendingMessages != 2 || stats.DeliveredMessages != 1 || stats.InboxMessages != 1 || stats.AcknowledgedMessages != 1 {
 		t.Fatalf("unexpected stats: %+v", stats)
 	}
+	if stats.OldestUnacknowledgedAt == nil || !stats.OldestUnacknowledgedAt.Equal(oldest) {
+		t.Fatalf("expected oldest unacknowledged at %v, got %v", oldest, stats.OldestUnacknowledgedAt)
+	}
 	if err := mock.ExpectationsWereMet(); err != nil {
 		t.Fatalf("unfulfilled expectations: %v", err)
 	}
@@ -776,9 +1358,15 @@ func TestCreateAgent(t *testing.T) {
 	storage := &DatabaseStorage{db: gormDB}
 
 	agent := &agents.LocalAgent{
-		Address:          "agent1@localhost",
-		DeliveryMode:     "push",
-		PushTarget:       "http://localhost:8080/agent1/webhook",
+		Address:      "agent1@localhost",
+		DeliveryMode: "push",
+		PushTarget:   "http://localhost:8080/agent1/webhook",
+		PushTargets:  []string{"http://localhost:8080/audit/webhook"},
+		PushPolicy:   "any",
+		PushStrategy: "balanced",
+		PushTargetWeights: map[string]int{
+			"http://localhost:8080/agent1/webhook": 4,
+		},
 		Headers:          map[string]string{"accept": "application/json"},
 		SupportedSchemas: []string{"schema1", "schema2"},
 		RequiresSchema:   true,
@@ -791,12 +1379,18 @@ func TestCreateAgent(t *testing.T) {
 		agent.Address,
 		agent.DeliveryMode,
 		agent.PushTarget,
+		`["http://localhost:8080/audit/webhook"]`,
+		"any",
+		"balanced",
+		`{"http://localhost:8080/agent1/webhook":4}`,
 		`{"accept":"application/json"}`,
 		agent.APIKey,
 		`["schema1","schema2"]`,
 		true,
 		sqlmock.AnyArg(),
 		sqlmock.AnyArg(),
+		0,
+		sqlmock.AnyArg(),
 	).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
 	mock.ExpectCommit()
 
@@ -853,12 +1447,16 @@ func TestCreateAgent_DuplicateAddress(t *testing.T) {
 		agent1.Address,
 		agent1.DeliveryMode,
 		agent1.PushTarget,
+		"",
+		"",
 		`{"accept":"application/json"}`,
 		agent1.APIKey,
 		`["schema1","schema2"]`,
 		agent1.RequiresSchema,
 		sqlmock.AnyArg(),
 		sqlmock.AnyArg(),
+		0,
+		sqlmock.AnyArg(),
 	).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
 	mock.ExpectCommit()
 
@@ -872,12 +1470,16 @@ func TestCreateAgent_DuplicateAddress(t *testing.T) {
 		agent2.Address,
 		agent2.DeliveryMode,
 		nil,
+		"",
+		"",
 		`{"accept":"application/xml"}`,
 		agent2.APIKey,
 		`["schema3"]`,
 		agent2.RequiresSchema,
 		sqlmock.AnyArg(),
 		sqlmock.AnyArg(),
+		0,
+		sqlmock.AnyArg(),
 	).WillReturnError(gorm.ErrDuplicatedKey)
 	mock.ExpectRollback()
 
@@ -898,11 +1500,13 @@ func TestGetAgent(t *testing.T) {
 	storage := &DatabaseStorage{db: gormDB}
 
 	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "agents" WHERE address = $1 ORDER BY "agents"."id" LIMIT $2`)).WithArgs("agent1@localhost", 1).WillReturnRows(
-		sqlmock.NewRows([]string{"id", "address", "delivery_mode", "push_target", "headers", "api_key", "supported_schemas", "requires_schema", "created_at", "last_access"}).AddRow(
+		sqlmock.NewRows([]string{"id", "address", "delivery_mode", "push_target", "push_strategy", "push_target_weights", "headers", "api_key", "supported_schemas", "requires_schema", "created_at", "last_access"}).AddRow(
 			1,
 			"agent1@localhost",
 			"push",
 			"http://localhost:8080/agent1/webhook",
+			"balanced",
+			`{"http://localhost:8080/agent1/webhook":3}`,
 			`{"accept":"application/json"}`,
 			"api-key-123",
 			`["schema1","schema2"]`,
@@ -919,6 +1523,9 @@ func TestGetAgent(t *testing.T) {
 	if agent == nil || agent.Address != "agent1@localhost" {
 		t.Fatalf("unexpected agent: %+v", agent)
 	}
+	if agent.PushStrategy != "balanced" || agent.PushTargetWeight("http://localhost:8080/agent1/webhook") != 3 {
+		t.Errorf("unexpected push strategy %q and weights %v", agent.PushStrategy, agent.PushTargetWeights)
+	}
 	if err := mock.ExpectationsWereMet(); err != nil {
 		t.Fatalf("unfulfilled expectations: %v", err)
 	}
@@ -940,6 +1547,37 @@ func TestGetAgent_NotFound(t *testing.T) {
 	}
 }
 
+func TestGetAgentByAPIKey(t *testing.T) {
+	gormDB, mock := newMockDB(t)
+	sqlDB, _ := gormDB.DB()
+	defer sqlDB.Close()
+	storage := &DatabaseStorage{db: gormDB}
+
+	query := regexp.QuoteMeta(`SELECT * FROM "agents" WHERE api_key = $1 ORDER BY "agents"."id" LIMIT $2`)
+	mock.ExpectQuery(query).WithArgs("hashed-key", 1).WillReturnRows(
+		sqlmock.NewRows([]string{"id", "address", "delivery_mode", "api_key", "supported_schemas", "created_at"}).
+			AddRow(1, "agent1@localhost", "pull", "hashed-key", `[]`, time.Now()),
+	)
+	mock.ExpectQuery(query).WithArgs("unknown-key", 1).WillReturnError(gorm.ErrRecordNotFound)
+
+	agent, err := storage.GetAgentByAPIKey(context.Background(), "hashed-key")
+	if err != nil {
+		t.Fatalf("GetAgentByAPIKey failed: %v", err)
+	}
+	if agent.Address != "agent1@localhost" {
+		t.Errorf("unexpected agent: %+v", agent)
+	}
+	if _, err := storage.GetAgentByAPIKey(context.Background(), "unknown-key"); !errors.Is(err, agents.ErrAgentNotFound) {
+		t.Errorf("expected ErrAgentNotFound, got %v", err)
+	}
+	if _, err := storage.GetAgentByAPIKey(context.Background(), ""); err == nil {
+		t.Error("expected error for empty API key")
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unfulfilled expectations: %v", err)
+	}
+}
+
 func TestGetAgent_EmptyAgentAddress(t *testing.T) {
 	gormDB, _ := newMockDB(t)
 	sqlDB, _ := gormDB.DB()
@@ -969,10 +1607,16 @@ func TestUpdateAgent(t *testing.T) {
 
 	mock.ExpectBegin()
 	mock.ExpectExec(regexp.QuoteMeta(`UPDATE "agents" SET`)).WithArgs(
+		nil,
 		updatedAgent.APIKey,
 		updatedAgent.DeliveryMode,
 		`{"accept":"application/xml"}`,
 		sqlmock.AnyArg(),
+		updatedAgent.MaxConcurrentDeliveries,
+		updatedAgent.PushPolicy,
+		updatedAgent.PushStrategy,
+		nil,
+		nil,
 		nil,
 		updatedAgent.RequiresSchema,
 		`["schema3"]`,
@@ -1263,6 +1907,37 @@ func TestAgentToUpdateMap(t *testing.T) {
 	}
 }
 
+func TestUpdateLastAccess(t *testing.T) {
+	gormDB, mock := newMockDB(t)
+	sqlDB, _ := gormDB.DB()
+	defer sqlDB.Close()
+	storage := &DatabaseStorage{db: gormDB}
+
+	at := time.Now().UTC()
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE "agents" SET "last_access"=$1 WHERE address = $2`)).
+		WithArgs(at, "agent1@localhost").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	if err := storage.UpdateLastAccess(context.Background(), "agent1@localhost", at); err != nil {
+		t.Fatalf("UpdateLastAccess failed: %v", err)
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE "agents" SET "last_access"=$1 WHERE address = $2`)).
+		WithArgs(at, "missing@localhost").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+
+	if err := storage.UpdateLastAccess(context.Background(), "missing@localhost", at); err == nil {
+		t.Error("Expected an error for an unknown agent")
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
 func TestUpdateAgent_NilAgent(t *testing.T) {
 	gormDB, _ := newMockDB(t)
 	sqlDB, _ := gormDB.DB()
@@ -122,6 +122,18 @@ func (db *DatabaseStorage) ListTimedOutWorkflows(ctx context.Context) ([]*types.
 	return results, nil
 }
 
+func (db *DatabaseStorage) CountActiveWorkflows(ctx context.Context) (int64, error) {
+	var count int64
+	err := db.db.WithContext(ctx).
+		Model(&Workflow{}).
+		Where("status IN (?)", []types.WorkflowStatus{types.WorkflowStatusPending, types.WorkflowStatusInProgress}).
+		Count(&count).Error
+	if err != nil {
+		return 0, fmt.Errorf("failed to count active workflows: %w", err)
+	}
+	return count, nil
+}
+
 // UpdateWorkflowParticipantAtomic updates a participant and atomically bumps the
 // workflow version. If the expectedVersion does not match the stored version,
 // ErrVersionConflict is returned and no changes are made.
@@ -18,7 +18,9 @@ package storage
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
+	"time"
 
 	"github.com/amtp-protocol/agentry/internal/agents"
 	"github.com/amtp-protocol/agentry/internal/types"
@@ -34,6 +36,10 @@ var ErrVersionConflict = errors.New("version conflict: workflow was modified con
 // "this replica does not own the workflow" (benign) from other failures.
 var ErrWorkflowNotFound = errors.New("workflow not found")
 
+// ErrInvalidCursor is returned by paginated listings given a cursor they did
+// not hand out
+var ErrInvalidCursor = errors.New("invalid cursor")
+
 // Storage defines the interface for message storage operations
 type Storage interface {
 	agents.AgentStore
@@ -43,12 +49,37 @@ type Storage interface {
 	GetMessage(ctx context.Context, messageID string) (*types.Message, error)
 	DeleteMessage(ctx context.Context, messageID string) error
 	ListMessages(ctx context.Context, filter MessageFilter) ([]*types.Message, error)
+	// CountMessages returns how many messages match the filter, ignoring its
+	// limit and offset
+	CountMessages(ctx context.Context, filter MessageFilter) (int64, error)
+
+	// Raw request operations. A raw request belongs to a stored message and
+	// is deleted with it.
+	StoreRawRequest(ctx context.Context, raw *types.RawRequest) error
+	GetRawRequest(ctx context.Context, messageID string) (*types.RawRequest, error)
 
 	// Status operations
 	StoreStatus(ctx context.Context, messageID string, status *types.MessageStatus) error
 	GetStatus(ctx context.Context, messageID string) (*types.MessageStatus, error)
+	// GetStatuses returns the statuses of several messages at once, keyed by
+	// message ID. Messages without a status are left out.
+	GetStatuses(ctx context.Context, messageIDs []string) (map[string]*types.MessageStatus, error)
+	GetStatusByIdempotencyKey(ctx context.Context, idempotencyKey string) (*types.MessageStatus, error)
 	UpdateStatus(ctx context.Context, messageID string, updater StatusUpdater) error
 	DeleteStatus(ctx context.Context, messageID string) error
+	// ListStatuses returns up to limit message statuses with their recipient
+	// statuses, in a stable order, starting after cursor. The returned cursor
+	// is passed to the next call; an empty cursor means every status was seen.
+	ListStatuses(ctx context.Context, cursor string, limit int) ([]*types.MessageStatus, string, error)
+	// ClaimMessagesForDelivery leases up to limit messages with recipients
+	// queued with ErrorCodeAgentNotRegistered to workerID for leaseDuration
+	// and returns their IDs.
+	// Messages leased to another worker are skipped until the lease expires,
+	// so gateways sharing storage never retry the same message at once and a
+	// crashed worker's messages are picked up again. A worker can claim its
+	// own messages again to renew the lease; unclaimed messages and those
+	// claimed longest ago come first.
+	ClaimMessagesForDelivery(ctx context.Context, workerID string, limit int, leaseDuration time.Duration) ([]string, error)
 
 	// Workflow operations
 	StoreWorkflow(ctx context.Context, state *types.Workflow) error
@@ -56,6 +87,8 @@ type Storage interface {
 	UpdateWorkflowStatus(ctx context.Context, workflowID string, status types.WorkflowStatus) error
 	UpdateWorkflowParticipant(ctx context.Context, workflowID string, address string, status types.ParticipantStatus, responsePayload []byte) error
 	ListTimedOutWorkflows(ctx context.Context) ([]*types.Workflow, error)
+	// CountActiveWorkflows returns how many workflows are pending or in progress
+	CountActiveWorkflows(ctx context.Context) (int64, error)
 
 	// Optimistic-concurrency workflow operations.
 	// These fail with ErrVersionConflict when the expected version does not match.
@@ -64,7 +97,37 @@ type Storage interface {
 
 	// Inbox operations (view-based queries)
 	GetInboxMessages(ctx context.Context, recipient string) ([]*types.Message, error)
-	AcknowledgeMessage(ctx context.Context, recipient, messageID string) error
+	// ListInboxMessages returns up to limit unacknowledged inbox messages for
+	// the recipient, in a stable order, starting after cursor. The returned
+	// cursor is passed to the next call; an empty cursor means the inbox has
+	// been read to the end.
+	ListInboxMessages(ctx context.Context, recipient, cursor string, limit int) ([]*types.Message, string, error)
+	// AcknowledgeMessage removes a message from the recipient's inbox. A
+	// non-empty response is recorded on the recipient's status.
+	AcknowledgeMessage(ctx context.Context, recipient, messageID string, response json.RawMessage) error
+	// DrainInbox acknowledges every message waiting in the recipient's inbox
+	// at once and returns how many there were
+	DrainInbox(ctx context.Context, recipient string) (int64, error)
+	// ListPendingInbox returns up to limit messages waiting in any local
+	// recipient's inbox, one per recipient, oldest first, starting after
+	// cursor. The returned cursor is passed to the next call; an empty cursor
+	// means every waiting message was seen.
+	ListPendingInbox(ctx context.Context, cursor string, limit int) ([]types.PendingInboxMessage, string, error)
+
+	// ListDeliveryErrors returns up to limit failed recipient deliveries,
+	// newest first, optionally only those that failed at or after since
+	ListDeliveryErrors(ctx context.Context, since *time.Time, limit int) ([]types.DeliveryError, error)
+
+	// Export operations.
+	// ExportMessages returns up to limit messages sent by or addressed to the
+	// given address, in a stable order, starting after cursor. The returned
+	// cursor is passed to the next call; an empty cursor means the export is
+	// complete.
+	ExportMessages(ctx context.Context, address, cursor string, limit int) ([]*types.Message, string, error)
+	// ListMessagesBySchema returns up to limit messages whose schema is one
+	// of schemaIDs, in the same order and with the same cursor as
+	// ExportMessages
+	ListMessagesBySchema(ctx context.Context, schemaIDs []string, cursor string, limit int) ([]*types.Message, string, error)
 
 	// Maintenance operations
 	Close() error
@@ -76,6 +139,7 @@ type Storage interface {
 type MessageFilter struct {
 	Sender     string
 	Recipients []string
+	Labels     []string // messages carrying all of these labels
 	Status     types.DeliveryStatus
 	Since      *int64 // Unix timestamp
 	Limit      int
@@ -94,6 +158,10 @@ type StorageStats struct {
 	FailedMessages       int64 `json:"failed_messages"`
 	InboxMessages        int64 `json:"inbox_messages"`
 	AcknowledgedMessages int64 `json:"acknowledged_messages"`
+
+	// OldestUnacknowledgedAt is when the oldest inbox message still waiting
+	// for acknowledgment was delivered; nil when no message is waiting
+	OldestUnacknowledgedAt *time.Time `json:"oldest_unacknowledged_at,omitempty"`
 }
 
 // StorageConfig defines configuration for storage implementations
@@ -122,6 +190,7 @@ type DatabaseStorageConfig struct {
 	ConnectionString string `yaml:"connection_string" json:"connection_string"`
 	MaxConnections   int    `yaml:"max_connections" json:"max_connections"`
 	MaxIdleTime      int    `yaml:"max_idle_time" json:"max_idle_time"`
+	CompressPayloads bool   `yaml:"compress_payloads" json:"compress_payloads"` // gzip large payloads and attachment metadata
 }
 
 // RedisStorageConfig configures Redis storage (placeholder for future)
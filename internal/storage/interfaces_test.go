@@ -270,7 +270,7 @@ func TestStorageInterfaceCompliance(t *testing.T) {
 	}
 
 	// Test AcknowledgeMessage (will fail because message not in inbox, but method should exist)
-	_ = storage.AcknowledgeMessage(ctx, "recipient@example.com", "interface-test")
+	_ = storage.AcknowledgeMessage(ctx, "recipient@example.com", "interface-test", nil)
 
 	// Test DeleteMessage
 	err = storage.DeleteMessage(ctx, "interface-test")
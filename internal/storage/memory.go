@@ -18,8 +18,13 @@ package storage
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"maps"
+	"slices"
 	"sort"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
@@ -31,7 +36,10 @@ import (
 type MemoryStorage struct {
 	config       MemoryStorageConfig
 	messages     map[string]*types.Message
+	byIdemKey    map[string]string            // idempotency key -> message ID, guarded by messagesMux
+	rawRequests  map[string]*types.RawRequest // guarded by messagesMux
 	statuses     map[string]*types.MessageStatus
+	claims       map[string]deliveryClaim // message ID -> lease, guarded by statusesMux
 	agents       map[string]*agents.LocalAgent
 	messagesMux  sync.RWMutex
 	statusesMux  sync.RWMutex
@@ -44,12 +52,15 @@ type MemoryStorage struct {
 // NewMemoryStorage creates a new in-memory storage instance
 func NewMemoryStorage(config MemoryStorageConfig) *MemoryStorage {
 	return &MemoryStorage{
-		config:    config,
-		messages:  make(map[string]*types.Message),
-		statuses:  make(map[string]*types.MessageStatus),
-		workflows: make(map[string]*types.Workflow),
-		agents:    make(map[string]*agents.LocalAgent),
-		createdAt: time.Now().UTC(),
+		config:      config,
+		messages:    make(map[string]*types.Message),
+		byIdemKey:   make(map[string]string),
+		rawRequests: make(map[string]*types.RawRequest),
+		statuses:    make(map[string]*types.MessageStatus),
+		claims:      make(map[string]deliveryClaim),
+		workflows:   make(map[string]*types.Workflow),
+		agents:      make(map[string]*agents.LocalAgent),
+		createdAt:   time.Now().UTC(),
 	}
 }
 
@@ -71,6 +82,9 @@ func (ms *MemoryStorage) StoreMessage(ctx context.Context, message *types.Messag
 	}
 
 	ms.messages[message.MessageID] = cloneMessage(message)
+	if message.IdempotencyKey != "" {
+		ms.byIdemKey[message.IdempotencyKey] = message.MessageID
+	}
 	return nil
 }
 
@@ -100,14 +114,60 @@ func (ms *MemoryStorage) DeleteMessage(ctx context.Context, messageID string) er
 	ms.messagesMux.Lock()
 	defer ms.messagesMux.Unlock()
 
-	if _, exists := ms.messages[messageID]; !exists {
+	message, exists := ms.messages[messageID]
+	if !exists {
 		return fmt.Errorf("message not found: %s", messageID)
 	}
 
+	if ms.byIdemKey[message.IdempotencyKey] == messageID {
+		delete(ms.byIdemKey, message.IdempotencyKey)
+	}
 	delete(ms.messages, messageID)
+	delete(ms.rawRequests, messageID)
+	return nil
+}
+
+// StoreRawRequest stores the raw request of a stored message
+func (ms *MemoryStorage) StoreRawRequest(ctx context.Context, raw *types.RawRequest) error {
+	if raw == nil {
+		return fmt.Errorf("raw request cannot be nil")
+	}
+	if raw.MessageID == "" {
+		return fmt.Errorf("message ID cannot be empty")
+	}
+
+	ms.messagesMux.Lock()
+	defer ms.messagesMux.Unlock()
+
+	if _, exists := ms.messages[raw.MessageID]; !exists {
+		return fmt.Errorf("message not found: %s", raw.MessageID)
+	}
+
+	stored := *raw
+	stored.Headers = maps.Clone(raw.Headers)
+	ms.rawRequests[raw.MessageID] = &stored
 	return nil
 }
 
+// GetRawRequest retrieves the raw request of a message
+func (ms *MemoryStorage) GetRawRequest(ctx context.Context, messageID string) (*types.RawRequest, error) {
+	if messageID == "" {
+		return nil, fmt.Errorf("message ID cannot be empty")
+	}
+
+	ms.messagesMux.RLock()
+	defer ms.messagesMux.RUnlock()
+
+	raw, exists := ms.rawRequests[messageID]
+	if !exists {
+		return nil, fmt.Errorf("raw request not found: %s", messageID)
+	}
+
+	clone := *raw
+	clone.Headers = maps.Clone(raw.Headers)
+	return &clone, nil
+}
+
 // ListMessages returns messages matching the filter criteria
 func (ms *MemoryStorage) ListMessages(ctx context.Context, filter MessageFilter) ([]*types.Message, error) {
 	ms.messagesMux.RLock()
@@ -152,6 +212,22 @@ func (ms *MemoryStorage) ListMessages(ctx context.Context, filter MessageFilter)
 	return matched, nil
 }
 
+// CountMessages returns how many messages match the filter criteria
+func (ms *MemoryStorage) CountMessages(ctx context.Context, filter MessageFilter) (int64, error) {
+	ms.messagesMux.RLock()
+	ms.statusesMux.RLock()
+	defer ms.messagesMux.RUnlock()
+	defer ms.statusesMux.RUnlock()
+
+	var count int64
+	for messageID, message := range ms.messages {
+		if ms.matchesFilter(message, messageID, filter) {
+			count++
+		}
+	}
+	return count, nil
+}
+
 // StoreStatus stores message status
 func (ms *MemoryStorage) StoreStatus(ctx context.Context, messageID string, status *types.MessageStatus) error {
 	if messageID == "" {
@@ -185,6 +261,37 @@ func (ms *MemoryStorage) GetStatus(ctx context.Context, messageID string) (*type
 	return cloneStatus(status), nil
 }
 
+// GetStatuses retrieves the statuses of several messages
+func (ms *MemoryStorage) GetStatuses(ctx context.Context, messageIDs []string) (map[string]*types.MessageStatus, error) {
+	ms.statusesMux.RLock()
+	defer ms.statusesMux.RUnlock()
+
+	statuses := make(map[string]*types.MessageStatus, len(messageIDs))
+	for _, messageID := range messageIDs {
+		if status, exists := ms.statuses[messageID]; exists {
+			statuses[messageID] = cloneStatus(status)
+		}
+	}
+	return statuses, nil
+}
+
+// GetStatusByIdempotencyKey retrieves the status of the message stored with
+// the given idempotency key
+func (ms *MemoryStorage) GetStatusByIdempotencyKey(ctx context.Context, idempotencyKey string) (*types.MessageStatus, error) {
+	if idempotencyKey == "" {
+		return nil, fmt.Errorf("idempotency key cannot be empty")
+	}
+
+	ms.messagesMux.RLock()
+	messageID, exists := ms.byIdemKey[idempotencyKey]
+	ms.messagesMux.RUnlock()
+	if !exists {
+		return nil, fmt.Errorf("message not found for idempotency key: %s", idempotencyKey)
+	}
+
+	return ms.GetStatus(ctx, messageID)
+}
+
 // UpdateStatus updates message status using the provided updater function
 func (ms *MemoryStorage) UpdateStatus(ctx context.Context, messageID string, updater StatusUpdater) error {
 	if messageID == "" {
@@ -219,9 +326,93 @@ func (ms *MemoryStorage) DeleteStatus(ctx context.Context, messageID string) err
 	}
 
 	delete(ms.statuses, messageID)
+	delete(ms.claims, messageID)
 	return nil
 }
 
+// deliveryClaim is a worker's lease on a message's queued recipients
+type deliveryClaim struct {
+	worker    string
+	expiresAt time.Time
+}
+
+// ClaimMessagesForDelivery leases messages with held recipients
+func (ms *MemoryStorage) ClaimMessagesForDelivery(ctx context.Context, workerID string, limit int, leaseDuration time.Duration) ([]string, error) {
+	if workerID == "" {
+		return nil, fmt.Errorf("worker ID cannot be empty")
+	}
+	if limit <= 0 {
+		return nil, fmt.Errorf("limit must be positive")
+	}
+	if leaseDuration <= 0 {
+		return nil, fmt.Errorf("lease duration must be positive")
+	}
+
+	ms.statusesMux.Lock()
+	defer ms.statusesMux.Unlock()
+
+	now := time.Now().UTC()
+	var messageIDs []string
+	for messageID, status := range ms.statuses {
+		claim, claimed := ms.claims[messageID]
+		if claimed && claim.worker != workerID && claim.expiresAt.After(now) {
+			continue
+		}
+		for _, recipientStatus := range status.Recipients {
+			if recipientStatus.Status == types.StatusQueued && recipientStatus.ErrorCode == ErrorCodeAgentNotRegistered {
+				messageIDs = append(messageIDs, messageID)
+				break
+			}
+		}
+	}
+	sort.Slice(messageIDs, func(i, j int) bool {
+		a, b := ms.claims[messageIDs[i]].expiresAt, ms.claims[messageIDs[j]].expiresAt
+		if !a.Equal(b) {
+			return a.Before(b)
+		}
+		return messageIDs[i] < messageIDs[j]
+	})
+	if len(messageIDs) > limit {
+		messageIDs = messageIDs[:limit]
+	}
+
+	for _, messageID := range messageIDs {
+		ms.claims[messageID] = deliveryClaim{worker: workerID, expiresAt: now.Add(leaseDuration)}
+	}
+	return messageIDs, nil
+}
+
+// ListStatuses returns a page of message statuses ordered by message ID. The
+// cursor is the ID of the last message returned.
+func (ms *MemoryStorage) ListStatuses(ctx context.Context, cursor string, limit int) ([]*types.MessageStatus, string, error) {
+	if limit <= 0 {
+		return nil, "", fmt.Errorf("limit must be positive")
+	}
+
+	ms.statusesMux.RLock()
+	defer ms.statusesMux.RUnlock()
+
+	messageIDs := make([]string, 0, len(ms.statuses))
+	for messageID := range ms.statuses {
+		if messageID > cursor {
+			messageIDs = append(messageIDs, messageID)
+		}
+	}
+	sort.Strings(messageIDs)
+
+	nextCursor := ""
+	if len(messageIDs) > limit {
+		messageIDs = messageIDs[:limit]
+		nextCursor = messageIDs[limit-1]
+	}
+
+	statuses := make([]*types.MessageStatus, 0, len(messageIDs))
+	for _, messageID := range messageIDs {
+		statuses = append(statuses, cloneStatus(ms.statuses[messageID]))
+	}
+	return statuses, nextCursor, nil
+}
+
 // GetInboxMessages returns messages for a specific recipient using unified storage view
 func (ms *MemoryStorage) GetInboxMessages(ctx context.Context, recipient string) ([]*types.Message, error) {
 	if recipient == "" {
@@ -238,27 +429,90 @@ func (ms *MemoryStorage) GetInboxMessages(ctx context.Context, recipient string)
 	// Iterate through all messages and find those delivered to this recipient's inbox
 	for messageID, message := range ms.messages {
 		status, exists := ms.statuses[messageID]
-		if !exists {
-			continue
+		if exists && inInbox(status, recipient) {
+			inboxMessages = append(inboxMessages, cloneMessage(message))
 		}
+	}
 
-		// Check if this message has been delivered to the recipient's inbox
-		for _, recipientStatus := range status.Recipients {
-			if recipientStatus.Address == recipient &&
-				recipientStatus.LocalDelivery &&
-				recipientStatus.InboxDelivered &&
-				!recipientStatus.Acknowledged {
-				inboxMessages = append(inboxMessages, cloneMessage(message))
-				break
+	return inboxMessages, nil
+}
+
+// ListInboxMessages returns a page of the recipient's inbox, ordered
+// oldest-first, using the same timestamp and ID cursor as ExportMessages.
+func (ms *MemoryStorage) ListInboxMessages(ctx context.Context, recipient, cursor string, limit int) ([]*types.Message, string, error) {
+	if recipient == "" {
+		return nil, "", fmt.Errorf("recipient cannot be empty")
+	}
+	if limit <= 0 {
+		return nil, "", fmt.Errorf("limit must be positive")
+	}
+
+	var afterNanos int64
+	var afterID string
+	if cursor != "" {
+		nanos, id, ok := strings.Cut(cursor, ":")
+		parsed, err := strconv.ParseInt(nanos, 10, 64)
+		if !ok || err != nil {
+			return nil, "", fmt.Errorf("invalid inbox cursor: %s", cursor)
+		}
+		afterNanos, afterID = parsed, id
+	}
+
+	ms.messagesMux.RLock()
+	ms.statusesMux.RLock()
+	var matched []*types.Message
+	for messageID, message := range ms.messages {
+		status, exists := ms.statuses[messageID]
+		if !exists || !inInbox(status, recipient) {
+			continue
+		}
+		if cursor != "" {
+			nanos := message.Timestamp.UnixNano()
+			if nanos < afterNanos || (nanos == afterNanos && message.MessageID <= afterID) {
+				continue
 			}
 		}
+		matched = append(matched, message)
 	}
+	ms.statusesMux.RUnlock()
+	ms.messagesMux.RUnlock()
 
-	return inboxMessages, nil
+	sort.Slice(matched, func(i, j int) bool {
+		if matched[i].Timestamp.Equal(matched[j].Timestamp) {
+			return matched[i].MessageID < matched[j].MessageID
+		}
+		return matched[i].Timestamp.Before(matched[j].Timestamp)
+	})
+
+	nextCursor := ""
+	if len(matched) > limit {
+		matched = matched[:limit]
+		last := matched[limit-1]
+		nextCursor = fmt.Sprintf("%d:%s", last.Timestamp.UnixNano(), last.MessageID)
+	}
+
+	page := make([]*types.Message, len(matched))
+	for i, message := range matched {
+		page[i] = cloneMessage(message)
+	}
+	return page, nextCursor, nil
+}
+
+// inInbox reports whether the message is waiting in the recipient's inbox
+func inInbox(status *types.MessageStatus, recipient string) bool {
+	for _, recipientStatus := range status.Recipients {
+		if recipientStatus.Address == recipient &&
+			recipientStatus.LocalDelivery &&
+			recipientStatus.InboxDelivered &&
+			!recipientStatus.Acknowledged {
+			return true
+		}
+	}
+	return false
 }
 
 // AcknowledgeMessage marks a message as acknowledged for a specific recipient
-func (ms *MemoryStorage) AcknowledgeMessage(ctx context.Context, recipient, messageID string) error {
+func (ms *MemoryStorage) AcknowledgeMessage(ctx context.Context, recipient, messageID string, response json.RawMessage) error {
 	if recipient == "" {
 		return fmt.Errorf("recipient cannot be empty")
 	}
@@ -288,6 +542,9 @@ func (ms *MemoryStorage) AcknowledgeMessage(ctx context.Context, recipient, mess
 			now := time.Now().UTC()
 			status.Recipients[i].Acknowledged = true
 			status.Recipients[i].AcknowledgedAt = &now
+			if len(response) > 0 {
+				status.Recipients[i].Response = append(json.RawMessage(nil), response...)
+			}
 			status.UpdatedAt = now
 
 			return nil
@@ -297,6 +554,233 @@ func (ms *MemoryStorage) AcknowledgeMessage(ctx context.Context, recipient, mess
 	return fmt.Errorf("recipient not found for message: %s", recipient)
 }
 
+// DrainInbox acknowledges all of a recipient's inbox messages under one lock
+func (ms *MemoryStorage) DrainInbox(ctx context.Context, recipient string) (int64, error) {
+	if recipient == "" {
+		return 0, fmt.Errorf("recipient cannot be empty")
+	}
+
+	ms.statusesMux.Lock()
+	defer ms.statusesMux.Unlock()
+
+	var drained int64
+	now := time.Now().UTC()
+	for _, status := range ms.statuses {
+		for i, recipientStatus := range status.Recipients {
+			if recipientStatus.Address == recipient &&
+				recipientStatus.LocalDelivery &&
+				recipientStatus.InboxDelivered &&
+				!recipientStatus.Acknowledged {
+				status.Recipients[i].Acknowledged = true
+				status.Recipients[i].AcknowledgedAt = &now
+				status.UpdatedAt = now
+				drained++
+			}
+		}
+	}
+	return drained, nil
+}
+
+// ListPendingInbox returns a page of unacknowledged inbox deliveries, oldest
+// first. The cursor encodes the delivery time, message ID and recipient of
+// the last entry returned.
+func (ms *MemoryStorage) ListPendingInbox(ctx context.Context, cursor string, limit int) ([]types.PendingInboxMessage, string, error) {
+	if limit <= 0 {
+		return nil, "", fmt.Errorf("limit must be positive")
+	}
+
+	var after types.PendingInboxMessage
+	if cursor != "" {
+		parts := strings.SplitN(cursor, ":", 3)
+		if len(parts) != 3 {
+			return nil, "", fmt.Errorf("%w: %s", ErrInvalidCursor, cursor)
+		}
+		nanos, err := strconv.ParseInt(parts[0], 10, 64)
+		if err != nil {
+			return nil, "", fmt.Errorf("%w: %s", ErrInvalidCursor, cursor)
+		}
+		after = types.PendingInboxMessage{MessageID: parts[1], Recipient: parts[2], Since: time.Unix(0, nanos)}
+	}
+
+	ms.statusesMux.RLock()
+	var pending []types.PendingInboxMessage
+	for messageID, status := range ms.statuses {
+		for _, recipientStatus := range status.Recipients {
+			if !recipientStatus.LocalDelivery || !recipientStatus.InboxDelivered || recipientStatus.Acknowledged {
+				continue
+			}
+			entry := types.PendingInboxMessage{
+				MessageID: messageID,
+				Recipient: recipientStatus.Address,
+				Since:     recipientStatus.Timestamp,
+			}
+			if cursor != "" && !pendingBefore(after, entry) {
+				continue
+			}
+			pending = append(pending, entry)
+		}
+	}
+	ms.statusesMux.RUnlock()
+
+	sort.Slice(pending, func(i, j int) bool {
+		return pendingBefore(pending[i], pending[j])
+	})
+
+	nextCursor := ""
+	if len(pending) > limit {
+		pending = pending[:limit]
+		last := pending[limit-1]
+		nextCursor = fmt.Sprintf("%d:%s:%s", last.Since.UnixNano(), last.MessageID, last.Recipient)
+	}
+	return pending, nextCursor, nil
+}
+
+// pendingBefore orders pending inbox messages oldest first
+func pendingBefore(a, b types.PendingInboxMessage) bool {
+	if !a.Since.Equal(b.Since) {
+		return a.Since.Before(b.Since)
+	}
+	if a.MessageID != b.MessageID {
+		return a.MessageID < b.MessageID
+	}
+	return a.Recipient < b.Recipient
+}
+
+// ListDeliveryErrors returns failed recipient deliveries, newest first
+func (ms *MemoryStorage) ListDeliveryErrors(ctx context.Context, since *time.Time, limit int) ([]types.DeliveryError, error) {
+	if limit <= 0 {
+		return nil, fmt.Errorf("limit must be positive")
+	}
+
+	ms.statusesMux.RLock()
+	var deliveryErrors []types.DeliveryError
+	for messageID, status := range ms.statuses {
+		for _, recipientStatus := range status.Recipients {
+			if recipientStatus.Status != types.StatusFailed {
+				continue
+			}
+			if since != nil && recipientStatus.Timestamp.Before(*since) {
+				continue
+			}
+			deliveryErrors = append(deliveryErrors, types.DeliveryError{
+				MessageID:    messageID,
+				Recipient:    recipientStatus.Address,
+				ErrorCode:    recipientStatus.ErrorCode,
+				ErrorMessage: recipientStatus.ErrorMessage,
+				Attempts:     recipientStatus.Attempts,
+				DeliveryMode: recipientStatus.DeliveryMode,
+				Timestamp:    recipientStatus.Timestamp,
+			})
+		}
+	}
+	ms.statusesMux.RUnlock()
+
+	sort.Slice(deliveryErrors, func(i, j int) bool {
+		if !deliveryErrors[i].Timestamp.Equal(deliveryErrors[j].Timestamp) {
+			return deliveryErrors[i].Timestamp.After(deliveryErrors[j].Timestamp)
+		}
+		if deliveryErrors[i].MessageID != deliveryErrors[j].MessageID {
+			return deliveryErrors[i].MessageID < deliveryErrors[j].MessageID
+		}
+		return deliveryErrors[i].Recipient < deliveryErrors[j].Recipient
+	})
+	if len(deliveryErrors) > limit {
+		deliveryErrors = deliveryErrors[:limit]
+	}
+	return deliveryErrors, nil
+}
+
+// ExportMessages returns a page of messages involving the address, ordered
+// oldest-first. The cursor encodes the timestamp and ID of the last message
+// returned so pagination stays stable while messages are added or removed.
+func (ms *MemoryStorage) ExportMessages(ctx context.Context, address, cursor string, limit int) ([]*types.Message, string, error) {
+	if address == "" {
+		return nil, "", fmt.Errorf("address cannot be empty")
+	}
+	return ms.pageMessages(cursor, limit, func(message *types.Message) bool {
+		return messageInvolves(message, address)
+	})
+}
+
+// ListMessagesBySchema returns a page of messages using one of the schemas,
+// in the same order and with the same cursor as ExportMessages
+func (ms *MemoryStorage) ListMessagesBySchema(ctx context.Context, schemaIDs []string, cursor string, limit int) ([]*types.Message, string, error) {
+	if len(schemaIDs) == 0 {
+		return nil, "", fmt.Errorf("schema ID cannot be empty")
+	}
+	return ms.pageMessages(cursor, limit, func(message *types.Message) bool {
+		return slices.Contains(schemaIDs, message.Schema)
+	})
+}
+
+// pageMessages returns up to limit matching messages after cursor, oldest
+// first, and the cursor of the next page
+func (ms *MemoryStorage) pageMessages(cursor string, limit int, match func(*types.Message) bool) ([]*types.Message, string, error) {
+	if limit <= 0 {
+		return nil, "", fmt.Errorf("limit must be positive")
+	}
+
+	var afterNanos int64
+	var afterID string
+	if cursor != "" {
+		nanos, id, ok := strings.Cut(cursor, ":")
+		parsed, err := strconv.ParseInt(nanos, 10, 64)
+		if !ok || err != nil {
+			return nil, "", fmt.Errorf("%w: %s", ErrInvalidCursor, cursor)
+		}
+		afterNanos, afterID = parsed, id
+	}
+
+	ms.messagesMux.RLock()
+	var matched []*types.Message
+	for _, message := range ms.messages {
+		if !match(message) {
+			continue
+		}
+		if cursor != "" {
+			nanos := message.Timestamp.UnixNano()
+			if nanos < afterNanos || (nanos == afterNanos && message.MessageID <= afterID) {
+				continue
+			}
+		}
+		matched = append(matched, message)
+	}
+	ms.messagesMux.RUnlock()
+
+	sort.Slice(matched, func(i, j int) bool {
+		if matched[i].Timestamp.Equal(matched[j].Timestamp) {
+			return matched[i].MessageID < matched[j].MessageID
+		}
+		return matched[i].Timestamp.Before(matched[j].Timestamp)
+	})
+
+	nextCursor := ""
+	if len(matched) > limit {
+		matched = matched[:limit]
+		last := matched[limit-1]
+		nextCursor = fmt.Sprintf("%d:%s", last.Timestamp.UnixNano(), last.MessageID)
+	}
+
+	page := make([]*types.Message, len(matched))
+	for i, message := range matched {
+		page[i] = cloneMessage(message)
+	}
+	return page, nextCursor, nil
+}
+
+// messageInvolves reports whether the address is the sender or a recipient
+func messageInvolves(message *types.Message, address string) bool {
+	if message.Sender == address {
+		return true
+	}
+	for _, recipient := range message.Recipients {
+		if recipient == address {
+			return true
+		}
+	}
+	return false
+}
+
 // Close closes the storage (no-op for memory storage)
 func (ms *MemoryStorage) Close() error {
 	// No resources to clean up for memory storage
@@ -339,6 +823,10 @@ func (ms *MemoryStorage) GetStats(ctx context.Context) (StorageStats, error) {
 					stats.AcknowledgedMessages++
 				} else {
 					stats.InboxMessages++
+					delivered := recipientStatus.Timestamp
+					if !delivered.IsZero() && (stats.OldestUnacknowledgedAt == nil || delivered.Before(*stats.OldestUnacknowledgedAt)) {
+						stats.OldestUnacknowledgedAt = &delivered
+					}
 				}
 			}
 		}
@@ -373,6 +861,13 @@ func (ms *MemoryStorage) matchesFilter(message *types.Message, messageID string,
 		}
 	}
 
+	// Check labels filter; the message must carry every label
+	for _, label := range filter.Labels {
+		if !slices.Contains(message.Labels, label) {
+			return false
+		}
+	}
+
 	// Check status filter
 	if filter.Status != "" {
 		status, exists := ms.statuses[messageID]
@@ -419,12 +914,29 @@ func (ms *MemoryStorage) GetAgent(ctx context.Context, agentAddress string) (*ag
 
 	agent, exists := ms.agents[agentAddress]
 	if !exists {
-		return nil, fmt.Errorf("agent not found: %s", agentAddress)
+		return nil, fmt.Errorf("%w: %s", agents.ErrAgentNotFound, agentAddress)
 	}
 
 	return cloneAgent(agent), nil
 }
 
+// GetAgentByAPIKey retrieves the local agent with the hashed API key
+func (ms *MemoryStorage) GetAgentByAPIKey(ctx context.Context, hashedKey string) (*agents.LocalAgent, error) {
+	if hashedKey == "" {
+		return nil, fmt.Errorf("API key cannot be empty")
+	}
+
+	ms.agentsMux.RLock()
+	defer ms.agentsMux.RUnlock()
+
+	for _, agent := range ms.agents {
+		if agent.APIKey == hashedKey {
+			return cloneAgent(agent), nil
+		}
+	}
+	return nil, agents.ErrAgentNotFound
+}
+
 // UpdateAgent updates an existing local agent
 func (ms *MemoryStorage) UpdateAgent(ctx context.Context, agent *agents.LocalAgent) error {
 	if agent == nil {
@@ -442,6 +954,20 @@ func (ms *MemoryStorage) UpdateAgent(ctx context.Context, agent *agents.LocalAge
 	return nil
 }
 
+// UpdateLastAccess sets a local agent's last access time
+func (ms *MemoryStorage) UpdateLastAccess(ctx context.Context, agentAddress string, at time.Time) error {
+	ms.agentsMux.Lock()
+	defer ms.agentsMux.Unlock()
+
+	agent, exists := ms.agents[agentAddress]
+	if !exists {
+		return fmt.Errorf("agent not found: %s", agentAddress)
+	}
+
+	agent.LastAccess = at
+	return nil
+}
+
 // DeleteAgent removes a local agent from storage
 func (ms *MemoryStorage) DeleteAgent(ctx context.Context, agentAddress string) error {
 	if agentAddress == "" {
@@ -508,6 +1034,9 @@ func cloneMessage(m *types.Message) *types.Message {
 	if m.Recipients != nil {
 		c.Recipients = append([]string(nil), m.Recipients...)
 	}
+	if m.Labels != nil {
+		c.Labels = append([]string(nil), m.Labels...)
+	}
 	if m.Headers != nil {
 		c.Headers = make(map[string]interface{}, len(m.Headers))
 		for k, v := range m.Headers {
@@ -561,8 +1090,17 @@ func cloneAgent(a *agents.LocalAgent) *agents.LocalAgent {
 			c.Headers[k] = v
 		}
 	}
+	if a.PushTargets != nil {
+		c.PushTargets = append([]string(nil), a.PushTargets...)
+	}
+	if a.PushTargetWeights != nil {
+		c.PushTargetWeights = maps.Clone(a.PushTargetWeights)
+	}
 	if a.SupportedSchemas != nil {
 		c.SupportedSchemas = append([]string(nil), a.SupportedSchemas...)
 	}
+	if a.AllowedSenders != nil {
+		c.AllowedSenders = append([]string(nil), a.AllowedSenders...)
+	}
 	return &c
 }
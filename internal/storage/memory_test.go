@@ -18,7 +18,9 @@ package storage
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strings"
 	"sync"
 	"testing"
 	"time"
@@ -263,6 +265,45 @@ func TestMemoryStorage_DeleteMessage_NotFound(t *testing.T) {
 	}
 }
 
+func TestMemoryStorage_RawRequest(t *testing.T) {
+	storage := NewMemoryStorage(MemoryStorageConfig{})
+	ctx := context.Background()
+
+	raw := &types.RawRequest{
+		MessageID: "test-message-1",
+		Headers:   map[string]string{"User-Agent": "client/1.0"},
+		Body:      `{"sender":"sender@example.com"}`,
+		Size:      31,
+	}
+	if err := storage.StoreRawRequest(ctx, raw); err == nil {
+		t.Error("Expected error storing the raw request of an unknown message")
+	}
+
+	if err := storage.StoreMessage(ctx, &types.Message{MessageID: "test-message-1", Sender: "sender@example.com"}); err != nil {
+		t.Fatalf("Failed to store message: %v", err)
+	}
+	if err := storage.StoreRawRequest(ctx, raw); err != nil {
+		t.Fatalf("Failed to store raw request: %v", err)
+	}
+	raw.Headers["User-Agent"] = "changed"
+
+	stored, err := storage.GetRawRequest(ctx, "test-message-1")
+	if err != nil {
+		t.Fatalf("Failed to get raw request: %v", err)
+	}
+	if stored.Body != raw.Body || stored.Size != 31 || stored.Headers["User-Agent"] != "client/1.0" {
+		t.Errorf("Unexpected raw request: %+v", stored)
+	}
+
+	// The raw request goes with its message
+	if err := storage.DeleteMessage(ctx, "test-message-1"); err != nil {
+		t.Fatalf("Failed to delete message: %v", err)
+	}
+	if _, err := storage.GetRawRequest(ctx, "test-message-1"); err == nil {
+		t.Error("Expected the raw request to be deleted with its message")
+	}
+}
+
 func TestMemoryStorage_StoreStatus(t *testing.T) {
 	storage := NewMemoryStorage(MemoryStorageConfig{})
 	ctx := context.Background()
@@ -315,6 +356,42 @@ func TestMemoryStorage_StoreStatus_NilStatus(t *testing.T) {
 	}
 }
 
+func TestMemoryStorage_GetStatusByIdempotencyKey(t *testing.T) {
+	storage := NewMemoryStorage(MemoryStorageConfig{})
+	ctx := context.Background()
+
+	message := &types.Message{MessageID: "test-message-1", IdempotencyKey: "key-1"}
+	if err := storage.StoreMessage(ctx, message); err != nil {
+		t.Fatalf("Failed to store message: %v", err)
+	}
+	if err := storage.StoreStatus(ctx, message.MessageID, &types.MessageStatus{MessageID: message.MessageID, Status: types.StatusQueued}); err != nil {
+		t.Fatalf("Failed to store status: %v", err)
+	}
+
+	status, err := storage.GetStatusByIdempotencyKey(ctx, "key-1")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if status.MessageID != message.MessageID || status.Status != types.StatusQueued {
+		t.Errorf("Unexpected status: %+v", status)
+	}
+
+	if _, err := storage.GetStatusByIdempotencyKey(ctx, "unknown"); err == nil {
+		t.Error("Expected error for unknown idempotency key")
+	}
+	if _, err := storage.GetStatusByIdempotencyKey(ctx, ""); err == nil {
+		t.Error("Expected error for empty idempotency key")
+	}
+
+	// Deleting the message drops it from the index
+	if err := storage.DeleteMessage(ctx, message.MessageID); err != nil {
+		t.Fatalf("Failed to delete message: %v", err)
+	}
+	if _, err := storage.GetStatusByIdempotencyKey(ctx, "key-1"); err == nil {
+		t.Error("Expected error after the message was deleted")
+	}
+}
+
 func TestMemoryStorage_UpdateStatus(t *testing.T) {
 	storage := NewMemoryStorage(MemoryStorageConfig{})
 	ctx := context.Background()
@@ -484,7 +561,7 @@ func TestMemoryStorage_AcknowledgeMessage(t *testing.T) {
 	storage.StoreStatus(ctx, "test-message-1", status)
 
 	// Acknowledge message
-	err := storage.AcknowledgeMessage(ctx, "agent1@localhost", "test-message-1")
+	err := storage.AcknowledgeMessage(ctx, "agent1@localhost", "test-message-1", nil)
 	if err != nil {
 		t.Fatalf("Expected no error acknowledging message, got %v", err)
 	}
@@ -504,6 +581,217 @@ func TestMemoryStorage_AcknowledgeMessage(t *testing.T) {
 	}
 }
 
+func TestMemoryStorage_DrainInbox(t *testing.T) {
+	storage := NewMemoryStorage(MemoryStorageConfig{})
+	ctx := context.Background()
+
+	inbox := func(address string, acknowledged bool) types.RecipientStatus {
+		return types.RecipientStatus{
+			Address:        address,
+			Status:         types.StatusDelivered,
+			LocalDelivery:  true,
+			InboxDelivered: true,
+			Acknowledged:   acknowledged,
+		}
+	}
+	statuses := map[string][]types.RecipientStatus{
+		"m1": {inbox("agent1@localhost", false), inbox("agent2@localhost", false)},
+		"m2": {inbox("agent1@localhost", false)},
+		"m3": {inbox("agent1@localhost", true)},
+		"m4": {{Address: "agent1@localhost", Status: types.StatusDelivered, LocalDelivery: true}},
+	}
+	for id, recipients := range statuses {
+		storage.StoreMessage(ctx, &types.Message{MessageID: id, Recipients: []string{"agent1@localhost"}})
+		storage.StoreStatus(ctx, id, &types.MessageStatus{MessageID: id, Recipients: recipients})
+	}
+
+	drained, err := storage.DrainInbox(ctx, "agent1@localhost")
+	if err != nil {
+		t.Fatalf("Expected no error draining inbox, got %v", err)
+	}
+	if drained != 2 {
+		t.Errorf("Expected 2 drained messages, got %d", drained)
+	}
+
+	messages, _ := storage.GetInboxMessages(ctx, "agent1@localhost")
+	if len(messages) != 0 {
+		t.Errorf("Expected an empty inbox, got %d messages", len(messages))
+	}
+	if others, _ := storage.GetInboxMessages(ctx, "agent2@localhost"); len(others) != 1 {
+		t.Errorf("Expected other inboxes to be untouched, got %d messages", len(others))
+	}
+
+	if drained, _ := storage.DrainInbox(ctx, "agent1@localhost"); drained != 0 {
+		t.Errorf("Expected draining an empty inbox to report 0, got %d", drained)
+	}
+	if _, err := storage.DrainInbox(ctx, ""); err == nil {
+		t.Error("Expected an error for an empty recipient")
+	}
+}
+
+func TestMemoryStorage_ListPendingInbox(t *testing.T) {
+	storage := NewMemoryStorage(MemoryStorageConfig{})
+	ctx := context.Background()
+
+	now := time.Now().UTC()
+	inbox := func(address string, age time.Duration, acknowledged bool) types.RecipientStatus {
+		return types.RecipientStatus{
+			Address:        address,
+			Status:         types.StatusDelivered,
+			Timestamp:      now.Add(-age),
+			LocalDelivery:  true,
+			InboxDelivered: true,
+			Acknowledged:   acknowledged,
+		}
+	}
+	statuses := map[string][]types.RecipientStatus{
+		"m1": {inbox("a@localhost", time.Minute, false), inbox("b@localhost", time.Minute, false)},
+		"m2": {inbox("a@localhost", time.Hour, true), {Address: "c@remote.com", Status: types.StatusDelivered, Timestamp: now}},
+		"m3": {inbox("c@localhost", time.Hour, false)},
+	}
+	for id, recipients := range statuses {
+		storage.StoreStatus(ctx, id, &types.MessageStatus{MessageID: id, Recipients: recipients})
+	}
+
+	var seen []string
+	cursor := ""
+	for {
+		page, next, err := storage.ListPendingInbox(ctx, cursor, 2)
+		if err != nil {
+			t.Fatalf("Expected no error listing the pending inbox, got %v", err)
+		}
+		for _, pending := range page {
+			seen = append(seen, pending.MessageID+" "+pending.Recipient)
+		}
+		if next == "" {
+			break
+		}
+		cursor = next
+	}
+	if strings.Join(seen, ",") != "m3 c@localhost,m1 a@localhost,m1 b@localhost" {
+		t.Errorf("Expected pending inbox messages oldest first, got %v", seen)
+	}
+
+	if _, _, err := storage.ListPendingInbox(ctx, "bogus", 2); !errors.Is(err, ErrInvalidCursor) {
+		t.Errorf("Expected ErrInvalidCursor, got %v", err)
+	}
+	if _, _, err := storage.ListPendingInbox(ctx, "", 0); err == nil {
+		t.Error("Expected an error for a non-positive limit")
+	}
+}
+
+func TestMemoryStorage_ClaimMessagesForDelivery(t *testing.T) {
+	storage := NewMemoryStorage(MemoryStorageConfig{})
+	ctx := context.Background()
+
+	statuses := map[string]types.RecipientStatus{
+		"m1": {Status: types.StatusQueued, ErrorCode: ErrorCodeAgentNotRegistered},
+		"m2": {Status: types.StatusDelivered},
+		"m3": {Status: types.StatusQueued, ErrorCode: ErrorCodeAgentNotRegistered},
+		"m4": {Status: types.StatusQueued, ErrorCode: "DELIVERY_FAILED"},
+	}
+	for id, recipient := range statuses {
+		recipient.Address = "a@localhost"
+		storage.StoreStatus(ctx, id, &types.MessageStatus{MessageID: id, Recipients: []types.RecipientStatus{recipient}})
+	}
+
+	claimed, err := storage.ClaimMessagesForDelivery(ctx, "gateway-a", 10, time.Hour)
+	if err != nil {
+		t.Fatalf("Expected no error claiming, got %v", err)
+	}
+	if strings.Join(claimed, ",") != "m1,m3" {
+		t.Errorf("Expected only the held messages to be claimed, got %v", claimed)
+	}
+
+	// Another gateway cannot take leased messages
+	if claimed, _ := storage.ClaimMessagesForDelivery(ctx, "gateway-b", 10, time.Hour); len(claimed) != 0 {
+		t.Errorf("Expected nothing claimable by another gateway, got %v", claimed)
+	}
+
+	// The owner renews its lease, oldest claim first
+	if claimed, _ := storage.ClaimMessagesForDelivery(ctx, "gateway-a", 1, time.Nanosecond); strings.Join(claimed, ",") != "m1" {
+		t.Errorf("Expected the owner to renew m1, got %v", claimed)
+	}
+	if claimed, _ := storage.ClaimMessagesForDelivery(ctx, "gateway-a", 1, time.Hour); strings.Join(claimed, ",") != "m1" {
+		t.Errorf("Expected the claim that expired first to come first, got %v", claimed)
+	}
+	if claimed, _ := storage.ClaimMessagesForDelivery(ctx, "gateway-a", 1, time.Nanosecond); strings.Join(claimed, ",") != "m3" {
+		t.Errorf("Expected m3 to be renewed next, got %v", claimed)
+	}
+
+	// An expired lease is taken over
+	time.Sleep(time.Millisecond)
+	if claimed, _ := storage.ClaimMessagesForDelivery(ctx, "gateway-b", 10, time.Hour); strings.Join(claimed, ",") != "m3" {
+		t.Errorf("Expected the expired lease on m3 to be taken over, got %v", claimed)
+	}
+
+	for _, tt := range []struct {
+		worker string
+		limit  int
+		lease  time.Duration
+	}{
+		{"", 10, time.Minute},
+		{"gateway-a", 0, time.Minute},
+		{"gateway-a", 10, 0},
+	} {
+		if _, err := storage.ClaimMessagesForDelivery(ctx, tt.worker, tt.limit, tt.lease); err == nil {
+			t.Errorf("Expected an error for worker %q, limit %d, lease %v", tt.worker, tt.limit, tt.lease)
+		}
+	}
+}
+
+func TestMemoryStorage_ListDeliveryErrors(t *testing.T) {
+	storage := NewMemoryStorage(MemoryStorageConfig{})
+	ctx := context.Background()
+
+	now := time.Now().UTC()
+	failed := func(address string, age time.Duration) types.RecipientStatus {
+		return types.RecipientStatus{
+			Address:      address,
+			Status:       types.StatusFailed,
+			Timestamp:    now.Add(-age),
+			Attempts:     3,
+			ErrorCode:    "DELIVERY_FAILED",
+			ErrorMessage: "connection refused",
+		}
+	}
+	statuses := map[string][]types.RecipientStatus{
+		"m1": {failed("a@remote.com", time.Minute), {Address: "b@remote.com", Status: types.StatusDelivered, Timestamp: now}},
+		"m2": {failed("c@remote.com", 2*time.Hour)},
+		"m3": {failed("d@remote.com", 10*time.Second)},
+	}
+	for id, recipients := range statuses {
+		storage.StoreStatus(ctx, id, &types.MessageStatus{MessageID: id, Recipients: recipients})
+	}
+
+	deliveryErrors, err := storage.ListDeliveryErrors(ctx, nil, 10)
+	if err != nil {
+		t.Fatalf("Expected no error listing delivery errors, got %v", err)
+	}
+	var recipients []string
+	for _, deliveryError := range deliveryErrors {
+		recipients = append(recipients, deliveryError.Recipient)
+	}
+	if strings.Join(recipients, ",") != "d@remote.com,a@remote.com,c@remote.com" {
+		t.Errorf("Expected failed recipients newest first, got %v", recipients)
+	}
+	if deliveryErrors[1].MessageID != "m1" || deliveryErrors[1].ErrorCode != "DELIVERY_FAILED" ||
+		deliveryErrors[1].ErrorMessage != "connection refused" || deliveryErrors[1].Attempts != 3 {
+		t.Errorf("Unexpected delivery error: %+v", deliveryErrors[1])
+	}
+
+	since := now.Add(-time.Hour)
+	if recent, _ := storage.ListDeliveryErrors(ctx, &since, 10); len(recent) != 2 {
+		t.Errorf("Expected 2 delivery errors in the last hour, got %d", len(recent))
+	}
+	if limited, _ := storage.ListDeliveryErrors(ctx, nil, 1); len(limited) != 1 || limited[0].Recipient != "d@remote.com" {
+		t.Errorf("Expected only the newest delivery error, got %+v", limited)
+	}
+	if _, err := storage.ListDeliveryErrors(ctx, nil, 0); err == nil {
+		t.Error("Expected an error for a non-positive limit")
+	}
+}
+
 func TestMemoryStorage_AcknowledgeMessage_AlreadyAcknowledged(t *testing.T) {
 	storage := NewMemoryStorage(MemoryStorageConfig{})
 	ctx := context.Background()
@@ -527,7 +815,7 @@ func TestMemoryStorage_AcknowledgeMessage_AlreadyAcknowledged(t *testing.T) {
 	storage.StoreStatus(ctx, "test-message-1", status)
 
 	// Try to acknowledge again
-	err := storage.AcknowledgeMessage(ctx, "agent1@localhost", "test-message-1")
+	err := storage.AcknowledgeMessage(ctx, "agent1@localhost", "test-message-1", nil)
 	if err == nil {
 		t.Error("Expected error acknowledging already acknowledged message")
 	}
@@ -653,6 +941,239 @@ func TestMemoryStorage_ListMessages_FilterWithPagination(t *testing.T) {
 	if result[0].MessageID != "match-2" || result[1].MessageID != "match-3" {
 		t.Errorf("Expected [match-2, match-3], got [%s, %s]", result[0].MessageID, result[1].MessageID)
 	}
+
+	// The count covers every match, not just the page
+	count, err := storage.CountMessages(ctx, filter)
+	if err != nil {
+		t.Fatalf("CountMessages failed: %v", err)
+	}
+	if count != 5 {
+		t.Errorf("Expected 5 matching messages, got %d", count)
+	}
+}
+
+func TestMemoryStorage_ListMessages_Labels(t *testing.T) {
+	storage := NewMemoryStorage(MemoryStorageConfig{})
+	ctx := context.Background()
+
+	base := time.Now()
+	for i, labels := range [][]string{{"campaign-2024", "eu"}, {"campaign-2024"}, {"eu"}, nil} {
+		if err := storage.StoreMessage(ctx, &types.Message{
+			MessageID:  fmt.Sprintf("msg-%d", i),
+			Sender:     "sender@example.com",
+			Recipients: []string{"recipient@example.com"},
+			Labels:     labels,
+			Timestamp:  base.Add(time.Duration(-i) * time.Minute),
+		}); err != nil {
+			t.Fatalf("store msg-%d: %v", i, err)
+		}
+	}
+
+	tests := []struct {
+		labels []string
+		want   []string
+	}{
+		{[]string{"campaign-2024"}, []string{"msg-0", "msg-1"}},
+		{[]string{"campaign-2024", "eu"}, []string{"msg-0"}},
+		{[]string{"us"}, nil},
+	}
+
+	for _, tt := range tests {
+		result, err := storage.ListMessages(ctx, MessageFilter{Labels: tt.labels})
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		var got []string
+		for _, msg := range result {
+			got = append(got, msg.MessageID)
+		}
+		if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+			t.Errorf("Labels %v: expected %v, got %v", tt.labels, tt.want, got)
+		}
+	}
+}
+
+func TestMemoryStorage_ExportMessages(t *testing.T) {
+	storage := NewMemoryStorage(MemoryStorageConfig{})
+	ctx := context.Background()
+
+	base := time.Now()
+	for i := 0; i < 5; i++ {
+		msg := &types.Message{
+			MessageID:  fmt.Sprintf("in-%d", i),
+			Sender:     "other@example.com",
+			Recipients: []string{"subject@example.com"},
+			Timestamp:  base.Add(time.Duration(i) * time.Minute),
+		}
+		if i == 4 {
+			// Messages sent by the address are part of the export too
+			msg.MessageID = "out-4"
+			msg.Sender = "subject@example.com"
+			msg.Recipients = []string{"other@example.com"}
+		}
+		if err := storage.StoreMessage(ctx, msg); err != nil {
+			t.Fatalf("store %s: %v", msg.MessageID, err)
+		}
+	}
+	if err := storage.StoreMessage(ctx, &types.Message{
+		MessageID:  "unrelated",
+		Sender:     "a@example.com",
+		Recipients: []string{"b@example.com"},
+		Timestamp:  base,
+	}); err != nil {
+		t.Fatalf("store unrelated: %v", err)
+	}
+
+	var exported []string
+	cursor := ""
+	pages := 0
+	for {
+		page, next, err := storage.ExportMessages(ctx, "subject@example.com", cursor, 2)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if len(page) > 2 {
+			t.Fatalf("Expected at most 2 messages per page, got %d", len(page))
+		}
+		for _, msg := range page {
+			exported = append(exported, msg.MessageID)
+		}
+		pages++
+		if next == "" {
+			break
+		}
+		cursor = next
+	}
+
+	expected := []string{"in-0", "in-1", "in-2", "in-3", "out-4"}
+	if fmt.Sprint(exported) != fmt.Sprint(expected) {
+		t.Errorf("Expected %v, got %v", expected, exported)
+	}
+	if pages != 3 {
+		t.Errorf("Expected 3 pages, got %d", pages)
+	}
+
+	if _, _, err := storage.ExportMessages(ctx, "subject@example.com", "bogus", 2); err == nil {
+		t.Error("Expected error for invalid cursor")
+	}
+	if _, _, err := storage.ExportMessages(ctx, "", "", 2); err == nil {
+		t.Error("Expected error for empty address")
+	}
+}
+
+func TestMemoryStorage_ListMessagesBySchema(t *testing.T) {
+	storage := NewMemoryStorage(MemoryStorageConfig{})
+	ctx := context.Background()
+
+	base := time.Now()
+	for i := 0; i < 5; i++ {
+		schemaID := "agntcy:commerce.order.v1"
+		switch i {
+		case 2:
+			schemaID = "agntcy:commerce.order.v2"
+		case 4:
+			schemaID = "agntcy:commerce.order"
+		}
+		if err := storage.StoreMessage(ctx, &types.Message{
+			MessageID:  fmt.Sprintf("m-%d", i),
+			Sender:     "a@example.com",
+			Recipients: []string{"b@example.com"},
+			Schema:     schemaID,
+			Timestamp:  base.Add(time.Duration(i) * time.Minute),
+		}); err != nil {
+			t.Fatalf("store m-%d: %v", i, err)
+		}
+	}
+
+	page, next, err := storage.ListMessagesBySchema(ctx, []string{"agntcy:commerce.order.v1"}, "", 2)
+	if err != nil || len(page) != 2 || page[0].MessageID != "m-0" || page[1].MessageID != "m-1" || next == "" {
+		t.Fatalf("Unexpected first page: %v %q %v", page, next, err)
+	}
+	page, next, err = storage.ListMessagesBySchema(ctx, []string{"agntcy:commerce.order.v1"}, next, 2)
+	if err != nil || len(page) != 1 || page[0].MessageID != "m-3" || next != "" {
+		t.Fatalf("Unexpected last page: %v %q %v", page, next, err)
+	}
+
+	// Any of several references matches
+	page, _, err = storage.ListMessagesBySchema(ctx, []string{"agntcy:commerce.order.v1", "agntcy:commerce.order"}, "", 10)
+	if err != nil || len(page) != 4 || page[3].MessageID != "m-4" {
+		t.Fatalf("Unexpected page for several schemas: %v %v", page, err)
+	}
+
+	if _, _, err := storage.ListMessagesBySchema(ctx, []string{"agntcy:commerce.order.v1"}, "bogus", 2); !errors.Is(err, ErrInvalidCursor) {
+		t.Errorf("Expected ErrInvalidCursor, got %v", err)
+	}
+	if _, _, err := storage.ListMessagesBySchema(ctx, nil, "", 2); err == nil {
+		t.Error("Expected error for empty schema ID")
+	}
+}
+
+func TestMemoryStorage_ListInboxMessages(t *testing.T) {
+	storage := NewMemoryStorage(MemoryStorageConfig{})
+	ctx := context.Background()
+
+	base := time.Now()
+	for i := 0; i < 5; i++ {
+		msg := &types.Message{
+			MessageID:  fmt.Sprintf("msg-%d", i),
+			Sender:     "sender@example.com",
+			Recipients: []string{"agent@localhost"},
+			Timestamp:  base.Add(time.Duration(i) * time.Minute),
+		}
+		if err := storage.StoreMessage(ctx, msg); err != nil {
+			t.Fatalf("store %s: %v", msg.MessageID, err)
+		}
+		if err := storage.StoreStatus(ctx, msg.MessageID, &types.MessageStatus{
+			MessageID: msg.MessageID,
+			Status:    types.StatusDelivered,
+			Recipients: []types.RecipientStatus{{
+				Address:        "agent@localhost",
+				Status:         types.StatusDelivered,
+				LocalDelivery:  true,
+				InboxDelivered: true,
+				// An acknowledged message has left the inbox
+				Acknowledged: i == 2,
+			}},
+		}); err != nil {
+			t.Fatalf("store status %s: %v", msg.MessageID, err)
+		}
+	}
+
+	var listed []string
+	cursor := ""
+	pages := 0
+	for {
+		page, next, err := storage.ListInboxMessages(ctx, "agent@localhost", cursor, 2)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if len(page) > 2 {
+			t.Fatalf("Expected at most 2 messages per page, got %d", len(page))
+		}
+		for _, msg := range page {
+			listed = append(listed, msg.MessageID)
+		}
+		pages++
+		if next == "" {
+			break
+		}
+		cursor = next
+	}
+
+	expected := []string{"msg-0", "msg-1", "msg-3", "msg-4"}
+	if fmt.Sprint(listed) != fmt.Sprint(expected) {
+		t.Errorf("Expected %v, got %v", expected, listed)
+	}
+	if pages != 2 {
+		t.Errorf("Expected 2 pages, got %d", pages)
+	}
+
+	if _, _, err := storage.ListInboxMessages(ctx, "agent@localhost", "bogus", 2); err == nil {
+		t.Error("Expected error for invalid cursor")
+	}
+	if _, _, err := storage.ListInboxMessages(ctx, "", "", 2); err == nil {
+		t.Error("Expected error for empty recipient")
+	}
 }
 
 func TestMemoryStorage_GetStats(t *testing.T) {
@@ -670,6 +1191,7 @@ func TestMemoryStorage_GetStats(t *testing.T) {
 	}
 
 	// Store some messages and statuses
+	delivered := time.Now().Add(-time.Minute).UTC()
 	message := &types.Message{
 		MessageID: "test-message-1",
 		Sender:    "sender@example.com",
@@ -682,10 +1204,19 @@ func TestMemoryStorage_GetStats(t *testing.T) {
 			{
 				Address:        "agent1@localhost",
 				Status:         types.StatusDelivered,
+				Timestamp:      delivered,
 				LocalDelivery:  true,
 				InboxDelivered: true,
 				Acknowledged:   false,
 			},
+			{
+				Address:        "agent2@localhost",
+				Status:         types.StatusDelivered,
+				Timestamp:      delivered.Add(-time.Hour),
+				LocalDelivery:  true,
+				InboxDelivered: true,
+				Acknowledged:   true,
+			},
 		},
 	}
 
@@ -713,6 +1244,10 @@ func TestMemoryStorage_GetStats(t *testing.T) {
 	if stats.InboxMessages != 1 {
 		t.Errorf("Expected 1 inbox message, got %d", stats.InboxMessages)
 	}
+
+	if stats.OldestUnacknowledgedAt == nil || !stats.OldestUnacknowledgedAt.Equal(delivered) {
+		t.Errorf("Expected oldest unacknowledged at %v, got %v", delivered, stats.OldestUnacknowledgedAt)
+	}
 }
 
 func TestMemoryStorage_HealthCheck(t *testing.T) {
@@ -871,6 +1406,31 @@ func TestMemoryStorage_GetAgent_NotFound(t *testing.T) {
 	}
 }
 
+func TestMemoryStorage_GetAgentByAPIKey(t *testing.T) {
+	storage := NewMemoryStorage(MemoryStorageConfig{})
+	ctx := context.Background()
+
+	for _, agent := range []*agents.LocalAgent{
+		{Address: "alice@localhost", DeliveryMode: "pull", APIKey: "hash-a"},
+		{Address: "bob@localhost", DeliveryMode: "pull", APIKey: "hash-b"},
+	} {
+		if err := storage.CreateAgent(ctx, agent); err != nil {
+			t.Fatalf("Failed to create agent: %v", err)
+		}
+	}
+
+	agent, err := storage.GetAgentByAPIKey(ctx, "hash-b")
+	if err != nil || agent.Address != "bob@localhost" {
+		t.Fatalf("Expected bob, got %+v, %v", agent, err)
+	}
+	if _, err := storage.GetAgentByAPIKey(ctx, "hash-c"); !errors.Is(err, agents.ErrAgentNotFound) {
+		t.Errorf("Expected ErrAgentNotFound, got %v", err)
+	}
+	if _, err := storage.GetAgentByAPIKey(ctx, ""); err == nil {
+		t.Error("Expected error for empty API key")
+	}
+}
+
 func TestMemoryStorage_GetAgent_EmptyAddress(t *testing.T) {
 	storage := NewMemoryStorage(MemoryStorageConfig{})
 	ctx := context.Background()
@@ -1230,3 +1790,47 @@ func TestMemoryStorage_ConcurrentReadDuringUpdate(t *testing.T) {
 	}()
 	wg.Wait()
 }
+
+func TestMemoryStorage_ListStatuses(t *testing.T) {
+	storage := NewMemoryStorage(MemoryStorageConfig{})
+	ctx := context.Background()
+
+	for _, id := range []string{"m3", "m1", "m5", "m2", "m4"} {
+		if err := storage.StoreStatus(ctx, id, &types.MessageStatus{MessageID: id, Status: types.StatusQueued}); err != nil {
+			t.Fatalf("store %s: %v", id, err)
+		}
+	}
+
+	var listed []string
+	cursor := ""
+	pages := 0
+	for {
+		page, next, err := storage.ListStatuses(ctx, cursor, 2)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		for _, status := range page {
+			listed = append(listed, status.MessageID)
+		}
+		pages++
+		if next == "" {
+			break
+		}
+		cursor = next
+	}
+
+	if fmt.Sprint(listed) != "[m1 m2 m3 m4 m5]" || pages != 3 {
+		t.Errorf("Expected all statuses in order over 3 pages, got %v over %d", listed, pages)
+	}
+
+	// Listed statuses are copies
+	page, _, _ := storage.ListStatuses(ctx, "", 1)
+	page[0].Status = types.StatusFailed
+	if status, _ := storage.GetStatus(ctx, "m1"); status.Status != types.StatusQueued {
+		t.Error("Expected the stored status to be unaffected by changes to a listed copy")
+	}
+
+	if _, _, err := storage.ListStatuses(ctx, "", 0); err == nil {
+		t.Error("Expected an error for a non-positive limit")
+	}
+}
@@ -119,6 +119,19 @@ func (ms *MemoryStorage) ListTimedOutWorkflows(ctx context.Context) ([]*types.Wo
 	return results, nil
 }
 
+func (ms *MemoryStorage) CountActiveWorkflows(ctx context.Context) (int64, error) {
+	ms.workflowsMux.RLock()
+	defer ms.workflowsMux.RUnlock()
+
+	var count int64
+	for _, state := range ms.workflows {
+		if state.Status == types.WorkflowStatusPending || state.Status == types.WorkflowStatusInProgress {
+			count++
+		}
+	}
+	return count, nil
+}
+
 // UpdateWorkflowParticipantAtomic updates a participant only if the workflow
 // version matches expectedVersion. On success the version is bumped.
 func (ms *MemoryStorage) UpdateWorkflowParticipantAtomic(ctx context.Context, workflowID string, address string, status types.ParticipantStatus, responsePayload []byte, expectedVersion int) error {
@@ -76,4 +76,15 @@ func TestMemoryStorage_Workflow(t *testing.T) {
 	require.NoError(t, err)
 	require.Len(t, timeouts, 1)
 	assert.Equal(t, "wf-timeout", timeouts[0].WorkflowID)
+
+	// 6. Count active workflows
+	active, err := storage.CountActiveWorkflows(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), active)
+
+	err = storage.UpdateWorkflowStatus(ctx, workflowID, types.WorkflowStatusCompleted)
+	require.NoError(t, err)
+	active, err = storage.CountActiveWorkflows(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), active)
 }
@@ -0,0 +1,70 @@
+/*
+ * Copyright 2026 Cong Wang
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package types
+
+import (
+	"net/mail"
+	"strings"
+)
+
+// NormalizeAddress returns the canonical form of an agent address: the bare
+// address without a display name, in lower case. Agent addresses are matched
+// case-insensitively, so every address the gateway stores or compares goes
+// through here. An address that does not parse is only trimmed and lowered,
+// leaving validation to reject it.
+func NormalizeAddress(address string) string {
+	if parsed, err := mail.ParseAddress(address); err == nil {
+		address = parsed.Address
+	}
+	return strings.ToLower(strings.TrimSpace(address))
+}
+
+// NormalizeRecipients normalizes addresses and drops repeats, keeping the
+// first occurrence of each. It also returns the addresses dropped, as given.
+func NormalizeRecipients(addresses []string) ([]string, []string) {
+	normalized := make([]string, 0, len(addresses))
+	var duplicates []string
+	seen := make(map[string]struct{}, len(addresses))
+	for _, address := range addresses {
+		canonical := NormalizeAddress(address)
+		if _, ok := seen[canonical]; ok {
+			duplicates = append(duplicates, address)
+			continue
+		}
+		seen[canonical] = struct{}{}
+		normalized = append(normalized, canonical)
+	}
+	return normalized, duplicates
+}
+
+// NormalizeAddresses normalizes every address the coordination refers to,
+// so they match the normalized recipients
+func (c *CoordinationConfig) NormalizeAddresses() {
+	normalizeAll(c.RequiredResponses)
+	normalizeAll(c.OptionalResponses)
+	normalizeAll(c.Sequence)
+	for i := range c.Conditions {
+		normalizeAll(c.Conditions[i].Then)
+		normalizeAll(c.Conditions[i].Else)
+	}
+}
+
+func normalizeAll(addresses []string) {
+	for i, address := range addresses {
+		addresses[i] = NormalizeAddress(address)
+	}
+}
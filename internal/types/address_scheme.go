@@ -0,0 +1,145 @@
+/*
+ * Copyright 2026 Cong Wang
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package types
+
+import (
+	"fmt"
+	"net/mail"
+	"regexp"
+	"strings"
+)
+
+// Address schemes
+const (
+	AddressSchemeEmail = "email"
+	AddressSchemeURN   = "urn"
+)
+
+// AddressScheme recognizes one form of agent address. Addresses of every
+// scheme are compared in the form NormalizeAddress gives them.
+type AddressScheme interface {
+	// Name identifies the scheme in configuration
+	Name() string
+	// IsValid reports whether address is well formed under the scheme
+	IsValid(address string) bool
+	// Domain returns the domain that routes address, or "" when the scheme
+	// only addresses agents of the local gateway
+	Domain(address string) string
+}
+
+// EmailScheme accepts local@domain addresses, routed by their domain. It is
+// the protocol's address form and the default.
+var EmailScheme AddressScheme = emailScheme{}
+
+// URNScheme accepts opaque agent IDs such as urn:agent:1234. They carry no
+// domain, so they only ever address agents of the local gateway.
+var URNScheme AddressScheme = urnScheme{}
+
+// DefaultAddressSchemes accepts email-style addresses only
+var DefaultAddressSchemes = AddressSchemes{EmailScheme}
+
+// NewAddressSchemes returns the schemes with the given names, in order. No
+// names means the default. Email-style addresses are always accepted, since
+// agent names and other gateways rely on them.
+func NewAddressSchemes(names []string) (AddressSchemes, error) {
+	schemes := AddressSchemes{EmailScheme}
+	seen := map[string]bool{AddressSchemeEmail: true}
+	for _, name := range names {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" || seen[name] {
+			continue
+		}
+		seen[name] = true
+		switch name {
+		case AddressSchemeURN:
+			schemes = append(schemes, URNScheme)
+		default:
+			return nil, fmt.Errorf("unknown address scheme %q, must be '%s' or '%s'",
+				name, AddressSchemeEmail, AddressSchemeURN)
+		}
+	}
+	return schemes, nil
+}
+
+// AddressSchemes is the set of address schemes a gateway accepts
+type AddressSchemes []AddressScheme
+
+// Scheme returns the first scheme address is valid under, or nil
+func (s AddressSchemes) Scheme(address string) AddressScheme {
+	for _, scheme := range s {
+		if scheme.IsValid(address) {
+			return scheme
+		}
+	}
+	return nil
+}
+
+// IsValid reports whether address is valid under any of the schemes
+func (s AddressSchemes) IsValid(address string) bool {
+	return s.Scheme(address) != nil
+}
+
+// Domain returns the domain that routes address, localDomain for addresses
+// of local-only schemes, and false when no scheme accepts address
+func (s AddressSchemes) Domain(address, localDomain string) (string, bool) {
+	scheme := s.Scheme(address)
+	if scheme == nil {
+		return "", false
+	}
+	if domain := scheme.Domain(address); domain != "" {
+		return domain, true
+	}
+	return localDomain, true
+}
+
+// Names returns the names of the schemes
+func (s AddressSchemes) Names() []string {
+	names := make([]string, len(s))
+	for i, scheme := range s {
+		names[i] = scheme.Name()
+	}
+	return names
+}
+
+type emailScheme struct{}
+
+func (emailScheme) Name() string { return AddressSchemeEmail }
+
+func (emailScheme) IsValid(address string) bool {
+	_, err := mail.ParseAddress(address)
+	return err == nil
+}
+
+func (emailScheme) Domain(address string) string {
+	address = NormalizeAddress(address)
+	return address[strings.LastIndex(address, "@")+1:]
+}
+
+// urnRegex follows RFC 8141: a namespace ID of 2 to 32 characters and a
+// namespace-specific string. "@" is left out so a URN is never mistaken for
+// an email-style address.
+var urnRegex = regexp.MustCompile(`(?i)^urn:[a-z0-9][a-z0-9-]{0,30}[a-z0-9]:[a-z0-9()+,\-.:=;$_!*'%/]+$`)
+
+type urnScheme struct{}
+
+func (urnScheme) Name() string { return AddressSchemeURN }
+
+func (urnScheme) IsValid(address string) bool {
+	return len(address) <= 255 && urnRegex.MatchString(address)
+}
+
+func (urnScheme) Domain(string) string { return "" }
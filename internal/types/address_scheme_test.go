@@ -0,0 +1,97 @@
+/*
+ * Copyright 2026 Cong Wang
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package types
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestNewAddressSchemes(t *testing.T) {
+	tests := []struct {
+		names    []string
+		expected []string
+		err      string
+	}{
+		{nil, []string{"email"}, ""},
+		{[]string{"email"}, []string{"email"}, ""},
+		{[]string{" URN ", "urn", ""}, []string{"email", "urn"}, ""},
+		{[]string{"urn", "email"}, []string{"email", "urn"}, ""},
+		{[]string{"did"}, nil, "unknown address scheme"},
+	}
+
+	for _, tt := range tests {
+		schemes, err := NewAddressSchemes(tt.names)
+		if tt.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("NewAddressSchemes(%v): expected error %q, got %v", tt.names, tt.err, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("NewAddressSchemes(%v): unexpected error %v", tt.names, err)
+			continue
+		}
+		if got := schemes.Names(); !reflect.DeepEqual(got, tt.expected) {
+			t.Errorf("NewAddressSchemes(%v) = %v, want %v", tt.names, got, tt.expected)
+		}
+	}
+}
+
+func TestAddressSchemes_Domain(t *testing.T) {
+	schemes := AddressSchemes{EmailScheme, URNScheme}
+
+	tests := []struct {
+		address string
+		scheme  string
+		domain  string
+	}{
+		{"bob@example.com", AddressSchemeEmail, "example.com"},
+		{"Bob <Bob@Example.com>", AddressSchemeEmail, "example.com"},
+		{"urn:agent:1234", AddressSchemeURN, "localhost"},
+		{"URN:Agent:a-b.c/d", AddressSchemeURN, "localhost"},
+		{"urn:x:1234", "", ""},                // namespace ID too short
+		{"urn:agent:", "", ""},                // no namespace-specific string
+		{"urn:agent:bob@example.com", "", ""}, // neither a URN nor an email address
+		{"agent-1234", "", ""},
+	}
+
+	for _, tt := range tests {
+		scheme := schemes.Scheme(tt.address)
+		if tt.scheme == "" {
+			if scheme != nil {
+				t.Errorf("Expected %q to be rejected, got scheme %s", tt.address, scheme.Name())
+			}
+			if _, ok := schemes.Domain(tt.address, "localhost"); ok {
+				t.Errorf("Expected no domain for %q", tt.address)
+			}
+			continue
+		}
+		if scheme == nil || scheme.Name() != tt.scheme {
+			t.Errorf("Expected %q to be a %s address, got %v", tt.address, tt.scheme, scheme)
+			continue
+		}
+		if domain, ok := schemes.Domain(tt.address, "localhost"); !ok || domain != tt.domain {
+			t.Errorf("Domain(%q) = %q, %v, want %q", tt.address, domain, ok, tt.domain)
+		}
+	}
+
+	if DefaultAddressSchemes.IsValid("urn:agent:1234") {
+		t.Error("Expected the default schemes to reject URNs")
+	}
+}
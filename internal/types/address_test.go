@@ -0,0 +1,83 @@
+/*
+ * Copyright 2026 Cong Wang
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package types
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNormalizeAddress(t *testing.T) {
+	tests := []struct {
+		address  string
+		expected string
+	}{
+		{"bob@x.com", "bob@x.com"},
+		{"Bob@X.com", "bob@x.com"},
+		{"  bob@x.com ", "bob@x.com"},
+		{"Bob <Bob@X.com>", "bob@x.com"},
+		{"Not An Address", "not an address"},
+	}
+
+	for _, tt := range tests {
+		if got := NormalizeAddress(tt.address); got != tt.expected {
+			t.Errorf("NormalizeAddress(%q) = %q, want %q", tt.address, got, tt.expected)
+		}
+	}
+}
+
+func TestNormalizeRecipients(t *testing.T) {
+	normalized, duplicates := NormalizeRecipients([]string{"bob@x.com", "Bob@X.com", "alice@y.com", "bob@x.com", "ALICE@y.com"})
+
+	if want := []string{"bob@x.com", "alice@y.com"}; !reflect.DeepEqual(normalized, want) {
+		t.Errorf("Expected recipients %v, got %v", want, normalized)
+	}
+	if want := []string{"Bob@X.com", "bob@x.com", "ALICE@y.com"}; !reflect.DeepEqual(duplicates, want) {
+		t.Errorf("Expected duplicates %v, got %v", want, duplicates)
+	}
+
+	if _, duplicates := NormalizeRecipients([]string{"Bob@X.com"}); duplicates != nil {
+		t.Errorf("Expected no duplicates, got %v", duplicates)
+	}
+}
+
+func TestCoordinationConfig_NormalizeAddresses(t *testing.T) {
+	coord := &CoordinationConfig{
+		Type:              "conditional",
+		RequiredResponses: []string{"Bob@X.com"},
+		OptionalResponses: []string{"Carol@X.com"},
+		Sequence:          []string{"Dave@X.com"},
+		Conditions: []ConditionalRule{
+			{If: "approved", Then: []string{"Erin@X.com"}, Else: []string{"Frank@X.com"}},
+		},
+	}
+
+	coord.NormalizeAddresses()
+
+	want := &CoordinationConfig{
+		Type:              "conditional",
+		RequiredResponses: []string{"bob@x.com"},
+		OptionalResponses: []string{"carol@x.com"},
+		Sequence:          []string{"dave@x.com"},
+		Conditions: []ConditionalRule{
+			{If: "approved", Then: []string{"erin@x.com"}, Else: []string{"frank@x.com"}},
+		},
+	}
+	if !reflect.DeepEqual(coord, want) {
+		t.Errorf("Expected %+v, got %+v", want, coord)
+	}
+}
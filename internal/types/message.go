@@ -25,7 +25,7 @@ import (
 // Message represents an AMTP message according to the protocol specification
 type Message struct {
 	Version        string                 `json:"version" validate:"required,eq=1.0"`
-	MessageID      string                 `json:"message_id" validate:"required,uuidv7"`
+	MessageID      string                 `json:"message_id" validate:"required,max=64"`
 	IdempotencyKey string                 `json:"idempotency_key" validate:"required,uuid4"`
 	Timestamp      time.Time              `json:"timestamp" validate:"required"`
 	Sender         string                 `json:"sender" validate:"required,email"`
@@ -37,8 +37,13 @@ type Message struct {
 	Payload        json.RawMessage        `json:"payload,omitempty"`
 	Attachments    []Attachment           `json:"attachments,omitempty"`
 	Signature      *MessageSignature      `json:"signature,omitempty"`
-	InReplyTo      string                 `json:"in_reply_to,omitempty" validate:"omitempty,uuidv7"`
+	InReplyTo      string                 `json:"in_reply_to,omitempty" validate:"omitempty,max=64"`
 	ResponseType   string                 `json:"response_type,omitempty"`
+	Labels         []string               `json:"labels,omitempty"`          // sender-defined, for filtering message history
+	RequestReceipt bool                   `json:"request_receipt,omitempty"` // send the sender a read receipt on acknowledgement
+	// Encrypted marks the payload as ciphertext for the recipients, a JSON
+	// string the gateway stores and relays without reading it
+	Encrypted bool `json:"encrypted,omitempty"`
 }
 
 // CoordinationConfig defines multi-agent coordination parameters
@@ -85,21 +90,73 @@ type MessageStatus struct {
 	CreatedAt   time.Time         `json:"created_at"`
 	UpdatedAt   time.Time         `json:"updated_at"`
 	DeliveredAt *time.Time        `json:"delivered_at,omitempty"`
+	Labels      []string          `json:"labels,omitempty"` // the message's, filled in by the status endpoints
+}
+
+// MessageSummary is a message listing entry with its overall delivery status
+type MessageSummary struct {
+	MessageID  string         `json:"message_id"`
+	Sender     string         `json:"sender"`
+	Recipients []string       `json:"recipients"`
+	Subject    string         `json:"subject,omitempty"`
+	Status     DeliveryStatus `json:"status,omitempty"`
+	Labels     []string       `json:"labels,omitempty"`
+	Timestamp  time.Time      `json:"timestamp"`
+}
+
+// MessageWithStatus is a complete message listing entry: the message itself
+// with its overall delivery status and the status of each recipient
+type MessageWithStatus struct {
+	*Message
+	Status            DeliveryStatus    `json:"status,omitempty"`
+	RecipientStatuses []RecipientStatus `json:"recipient_statuses,omitempty"`
 }
 
 // RecipientStatus represents the delivery status for a specific recipient
 type RecipientStatus struct {
-	Address        string         `json:"address"`
-	Status         DeliveryStatus `json:"status"`
-	Timestamp      time.Time      `json:"timestamp"`
-	Attempts       int            `json:"attempts"`
-	ErrorCode      string         `json:"error_code,omitempty"`
-	ErrorMessage   string         `json:"error_message,omitempty"`
-	DeliveryMode   string         `json:"delivery_mode,omitempty"`   // "push" or "pull"
-	LocalDelivery  bool           `json:"local_delivery,omitempty"`  // true if delivered locally
-	InboxDelivered bool           `json:"inbox_delivered,omitempty"` // true if available in inbox
-	Acknowledged   bool           `json:"acknowledged,omitempty"`    // true if acknowledged by recipient
-	AcknowledgedAt *time.Time     `json:"acknowledged_at,omitempty"` // when acknowledged
+	Address        string          `json:"address"`
+	Status         DeliveryStatus  `json:"status"`
+	Timestamp      time.Time       `json:"timestamp"`
+	Attempts       int             `json:"attempts"`
+	ErrorCode      string          `json:"error_code,omitempty"`
+	ErrorMessage   string          `json:"error_message,omitempty"`
+	DeliveryMode   string          `json:"delivery_mode,omitempty"`   // "push" or "pull"
+	LocalDelivery  bool            `json:"local_delivery,omitempty"`  // true if delivered locally
+	InboxDelivered bool            `json:"inbox_delivered,omitempty"` // true if available in inbox
+	Acknowledged   bool            `json:"acknowledged,omitempty"`    // true if acknowledged by recipient
+	AcknowledgedAt *time.Time      `json:"acknowledged_at,omitempty"` // when acknowledged
+	Response       json.RawMessage `json:"response,omitempty"`        // payload the recipient acknowledged with
+}
+
+// DeliveryError is a failed delivery to one recipient
+type DeliveryError struct {
+	MessageID    string    `json:"message_id"`
+	Recipient    string    `json:"recipient"`
+	ErrorCode    string    `json:"error_code,omitempty"`
+	ErrorMessage string    `json:"error_message,omitempty"`
+	Attempts     int       `json:"attempts"`
+	DeliveryMode string    `json:"delivery_mode,omitempty"`
+	Timestamp    time.Time `json:"timestamp"`
+}
+
+// PendingInboxMessage is a message waiting in a local recipient's inbox for
+// the recipient to acknowledge it
+type PendingInboxMessage struct {
+	MessageID string    `json:"message_id"`
+	Recipient string    `json:"recipient"`
+	Since     time.Time `json:"since"` // when it was delivered to the inbox
+}
+
+// RawRequest is the send request a message was created from, kept as the
+// client sent it for debugging. Body holds at most the configured number of
+// bytes; Size is the length of the whole body.
+type RawRequest struct {
+	MessageID  string            `json:"message_id"`
+	ReceivedAt time.Time         `json:"received_at"`
+	Headers    map[string]string `json:"headers,omitempty"`
+	Body       string            `json:"body"`
+	Size       int64             `json:"size"`
+	Truncated  bool              `json:"truncated,omitempty"`
 }
 
 // DeliveryStatus represents possible message delivery states
@@ -114,9 +171,34 @@ const (
 	StatusRetrying   DeliveryStatus = "retrying"
 )
 
+// IsFinal reports whether no further delivery happens in this status
+func (s DeliveryStatus) IsFinal() bool {
+	return s == StatusDelivered || s == StatusFailed
+}
+
+// AggregateStatus derives a message's overall status from its recipients:
+// delivered once every recipient is delivered, including acknowledged inbox
+// deliveries; failed as soon as any recipient has failed; and delivering
+// while any recipient is still outstanding.
+func AggregateStatus(recipients []RecipientStatus) DeliveryStatus {
+	allDelivered := true
+	for _, recipient := range recipients {
+		if recipient.Status == StatusFailed {
+			return StatusFailed
+		}
+		if recipient.Status != StatusDelivered {
+			allDelivered = false
+		}
+	}
+	if allDelivered {
+		return StatusDelivered
+	}
+	return StatusDelivering
+}
+
 // SendMessageRequest represents the API request to send a message
 type SendMessageRequest struct {
-	MessageID      string                 `json:"message_id,omitempty" validate:"omitempty,uuidv7"`
+	MessageID      string                 `json:"message_id,omitempty" validate:"omitempty,max=64"`
 	IdempotencyKey string                 `json:"idempotency_key,omitempty" validate:"omitempty,max=255"`
 	Timestamp      string                 `json:"timestamp,omitempty" validate:"omitempty,datetime"`
 	Sender         string                 `json:"sender" validate:"required,email"`
@@ -129,18 +211,103 @@ type SendMessageRequest struct {
 	InReplyTo      string                 `json:"in_reply_to,omitempty"`
 	Payload        json.RawMessage        `json:"payload,omitempty"`
 	Attachments    []Attachment           `json:"attachments,omitempty"`
+	Signature      *MessageSignature      `json:"signature,omitempty"`
+	Labels         []string               `json:"labels,omitempty"`
+	RequestReceipt bool                   `json:"request_receipt,omitempty"`
+	Encrypted      bool                   `json:"encrypted,omitempty"`
+	// MaxRetries and RetryDelay override the gateway's delivery retry policy
+	// for this message, up to the configured limits
+	MaxRetries int    `json:"max_retries,omitempty"`
+	RetryDelay string `json:"retry_delay,omitempty"` // Go duration, e.g. "500ms"
+	// ValidationMode overrides the schema's validation mode for this message:
+	// "full", or "partial" to skip required-field checks
+	ValidationMode string `json:"validation_mode,omitempty"`
 }
 
 // SendMessageResponse represents the API response for sending a message
 type SendMessageResponse struct {
 	MessageID  string            `json:"message_id"`
 	Status     string            `json:"status"`
+	Partial    bool              `json:"partial,omitempty"` // some recipients failed, others did not
 	Recipients []RecipientStatus `json:"recipients"`
+	Warnings   []Warning         `json:"warnings,omitempty"`
+}
+
+// Warning is a non-fatal observation about an accepted request
+type Warning struct {
+	Code    string      `json:"code"`
+	Message string      `json:"message"`
+	Value   interface{} `json:"value,omitempty"`
+}
+
+// DetectedSchemaHeader is the message header holding the schema a schemaless
+// payload was detected to match. It is a hint only; the message's schema
+// stays empty.
+const DetectedSchemaHeader = "detected_schema"
+
+// ResponseTypeNonDelivery marks a non-delivery report. A gateway never
+// reports the failed delivery of a report, so bounces cannot loop.
+const ResponseTypeNonDelivery = "non_delivery_report"
+
+// NonDeliveryReport is the payload of a message reporting recipients that
+// could not be delivered to; the report's in_reply_to is the failed message
+type NonDeliveryReport struct {
+	OriginalMessageID string            `json:"original_message_id"`
+	OriginalSubject   string            `json:"original_subject,omitempty"`
+	FailedRecipients  []FailedRecipient `json:"failed_recipients"`
+}
+
+// FailedRecipient is a recipient in a non-delivery report with its final error
+type FailedRecipient struct {
+	Address      string `json:"address"`
+	ErrorCode    string `json:"error_code,omitempty"`
+	ErrorMessage string `json:"error_message,omitempty"`
+}
+
+// ResponseTypeReadReceipt marks a read receipt. Receipts are never sent for
+// receipts, so they cannot loop.
+const ResponseTypeReadReceipt = "read_receipt"
+
+// ReadReceipt is the payload of a message telling the sender that a recipient
+// acknowledged its message; the receipt's in_reply_to is that message
+type ReadReceipt struct {
+	OriginalMessageID string    `json:"original_message_id"`
+	OriginalSubject   string    `json:"original_subject,omitempty"`
+	Recipient         string    `json:"recipient"`
+	AcknowledgedAt    time.Time `json:"acknowledged_at"`
+}
+
+// ResponseTypeDeadLetter marks a message the gateway could not deliver to
+// unregistered local recipients, forwarded to its dead-letter address. Dead
+// letters are never dead-lettered themselves.
+const ResponseTypeDeadLetter = "dead_letter"
+
+// ResponseTypeRequired marks a message whose sender expects a response. A
+// recipient that acknowledges it with a response payload has the payload
+// sent back to the sender as an acknowledgement response.
+const ResponseTypeRequired = "required"
+
+// ResponseTypeAckResponse marks an acknowledgement response: a message from
+// the acknowledging recipient carrying the response it acknowledged with as
+// its payload; its in_reply_to is the acknowledged message
+const ResponseTypeAckResponse = "ack_response"
+
+// DeadLetter is the payload of a dead letter: the recipients no agent was
+// registered for and the original message, unchanged
+type DeadLetter struct {
+	Recipients []FailedRecipient `json:"recipients"`
+	Message    *Message          `json:"message"`
 }
 
+// APIVersion is the version of the HTTP API response shapes. It is reported
+// as api_version in response bodies and selected with the Accept-Version
+// request header. Adding fields does not change it; breaking changes do.
+const APIVersion = "1"
+
 // ErrorResponse represents an API error response
 type ErrorResponse struct {
-	Error ErrorDetail `json:"error"`
+	APIVersion string      `json:"api_version,omitempty"`
+	Error      ErrorDetail `json:"error"`
 }
 
 // ErrorDetail provides detailed error information
@@ -206,9 +373,34 @@ func (c *CoordinationConfig) Validate() error {
 	return nil
 }
 
-// Size returns the approximate size of the message in bytes
+// Size returns the approximate size of the message in bytes as storage
+// persists it: the envelope plus the payload exactly as received. Marshaling
+// the whole message would compact the payload and undercount it.
 func (m *Message) Size() int64 {
-	data, err := json.Marshal(m)
+	return m.EnvelopeSize() + m.PayloadSize()
+}
+
+// PayloadSize returns the size of the payload in bytes as received
+func (m *Message) PayloadSize() int64 {
+	return int64(len(m.Payload))
+}
+
+// AttachmentsSize returns the total size the attachments declare. The content
+// lives behind the attachment URLs, so it is not part of Size.
+func (m *Message) AttachmentsSize() int64 {
+	var size int64
+	for _, attachment := range m.Attachments {
+		size += attachment.Size
+	}
+	return size
+}
+
+// EnvelopeSize returns the size in bytes of the message without its payload,
+// including the attachment references
+func (m *Message) EnvelopeSize() int64 {
+	envelope := *m
+	envelope.Payload = nil
+	data, err := json.Marshal(&envelope)
 	if err != nil {
 		return 0
 	}
@@ -225,39 +417,13 @@ func (m *Message) Clone() *Message {
 		copy(clone.Recipients, m.Recipients)
 	}
 
-	if m.Coordination != nil {
-		coord := *m.Coordination
-
-		if m.Coordination.RequiredResponses != nil {
-			coord.RequiredResponses = make([]string, len(m.Coordination.RequiredResponses))
-			copy(coord.RequiredResponses, m.Coordination.RequiredResponses)
-		}
-		if m.Coordination.OptionalResponses != nil {
-			coord.OptionalResponses = make([]string, len(m.Coordination.OptionalResponses))
-			copy(coord.OptionalResponses, m.Coordination.OptionalResponses)
-		}
-		if m.Coordination.Sequence != nil {
-			coord.Sequence = make([]string, len(m.Coordination.Sequence))
-			copy(coord.Sequence, m.Coordination.Sequence)
-		}
-		if m.Coordination.Conditions != nil {
-			coord.Conditions = make([]ConditionalRule, len(m.Coordination.Conditions))
-			for i, cond := range m.Coordination.Conditions {
-				c := cond
-				if cond.Then != nil {
-					c.Then = make([]string, len(cond.Then))
-					copy(c.Then, cond.Then)
-				}
-				if cond.Else != nil {
-					c.Else = make([]string, len(cond.Else))
-					copy(c.Else, cond.Else)
-				}
-				coord.Conditions[i] = c
-			}
-		}
-		clone.Coordination = &coord
+	if m.Labels != nil {
+		clone.Labels = make([]string, len(m.Labels))
+		copy(clone.Labels, m.Labels)
 	}
 
+	clone.Coordination = m.Coordination.Clone()
+
 	if m.Headers != nil {
 		clone.Headers = make(map[string]interface{}, len(m.Headers))
 		for k, v := range m.Headers {
@@ -282,3 +448,40 @@ func (m *Message) Clone() *Message {
 
 	return &clone
 }
+
+// Clone returns a deep copy of the coordination config
+func (c *CoordinationConfig) Clone() *CoordinationConfig {
+	if c == nil {
+		return nil
+	}
+	coord := *c
+
+	if c.RequiredResponses != nil {
+		coord.RequiredResponses = make([]string, len(c.RequiredResponses))
+		copy(coord.RequiredResponses, c.RequiredResponses)
+	}
+	if c.OptionalResponses != nil {
+		coord.OptionalResponses = make([]string, len(c.OptionalResponses))
+		copy(coord.OptionalResponses, c.OptionalResponses)
+	}
+	if c.Sequence != nil {
+		coord.Sequence = make([]string, len(c.Sequence))
+		copy(coord.Sequence, c.Sequence)
+	}
+	if c.Conditions != nil {
+		coord.Conditions = make([]ConditionalRule, len(c.Conditions))
+		for i, cond := range c.Conditions {
+			rule := cond
+			if cond.Then != nil {
+				rule.Then = make([]string, len(cond.Then))
+				copy(rule.Then, cond.Then)
+			}
+			if cond.Else != nil {
+				rule.Else = make([]string, len(cond.Else))
+				copy(rule.Else, cond.Else)
+			}
+			coord.Conditions[i] = rule
+		}
+	}
+	return &coord
+}
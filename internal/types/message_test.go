@@ -169,6 +169,47 @@ func TestMessageSize(t *testing.T) {
 	}
 }
 
+func TestMessageSize_Components(t *testing.T) {
+	payload := "{\n  \"order\": 42\n}"
+	message := &Message{
+		Version:    "1.0",
+		MessageID:  "01234567-89ab-7def-8123-456789abcdef",
+		Sender:     "test@example.com",
+		Recipients: []string{"recipient@example.com"},
+		Payload:    json.RawMessage(payload),
+		Attachments: []Attachment{
+			{Filename: "a.pdf", Size: 1000},
+			{Filename: "b.png", Size: 2500},
+		},
+	}
+
+	if got := message.PayloadSize(); got != int64(len(payload)) {
+		t.Errorf("Expected payload size %d, got %d", len(payload), got)
+	}
+	if got := message.AttachmentsSize(); got != 3500 {
+		t.Errorf("Expected attachments size 3500, got %d", got)
+	}
+
+	envelope := *message
+	envelope.Payload = nil
+	data, err := json.Marshal(&envelope)
+	if err != nil {
+		t.Fatalf("Failed to marshal envelope: %v", err)
+	}
+	if got := message.EnvelopeSize(); got != int64(len(data)) {
+		t.Errorf("Expected envelope size %d, got %d", len(data), got)
+	}
+
+	// The payload counts as received, whitespace included, since that is
+	// what storage persists
+	if got := message.Size(); got != int64(len(data)+len(payload)) {
+		t.Errorf("Expected size %d, got %d", len(data)+len(payload), got)
+	}
+	if message.Payload == nil {
+		t.Error("Expected EnvelopeSize to leave the payload in place")
+	}
+}
+
 func TestMessageJSONSerialization(t *testing.T) {
 	originalMessage := &Message{
 		Version:        "1.0",
@@ -254,3 +295,28 @@ func BenchmarkMessageSize(b *testing.B) {
 		_ = message.Size()
 	}
 }
+
+func TestAggregateStatus(t *testing.T) {
+	tests := []struct {
+		name     string
+		statuses []DeliveryStatus
+		expected DeliveryStatus
+	}{
+		{"all delivered", []DeliveryStatus{StatusDelivered, StatusDelivered}, StatusDelivered},
+		{"any failed", []DeliveryStatus{StatusDelivered, StatusFailed}, StatusFailed},
+		{"failed wins over in progress", []DeliveryStatus{StatusQueued, StatusFailed}, StatusFailed},
+		{"some in progress", []DeliveryStatus{StatusDelivered, StatusQueued}, StatusDelivering},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recipients := make([]RecipientStatus, len(tt.statuses))
+			for i, status := range tt.statuses {
+				recipients[i] = RecipientStatus{Status: status}
+			}
+			if got := AggregateStatus(recipients); got != tt.expected {
+				t.Errorf("AggregateStatus() = %s, want %s", got, tt.expected)
+			}
+		})
+	}
+}
@@ -17,14 +17,19 @@
 package validation
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
-	"net/mail"
+	"io"
 	"regexp"
 	"strings"
+	"time"
 
 	"github.com/amtp-protocol/agentry/internal/schema"
 	"github.com/amtp-protocol/agentry/internal/types"
+	"github.com/amtp-protocol/agentry/internal/workflow"
 	"github.com/amtp-protocol/agentry/pkg/uuid"
 )
 
@@ -40,17 +45,44 @@ type AgentManager interface {
 	GetLocalAgents() map[string]*LocalAgent
 }
 
+// Attachment limit errors, wrapped by ValidateSendRequest so callers can
+// report them with a dedicated error code
+var (
+	ErrTooManyAttachments  = errors.New("too many attachments")
+	ErrAttachmentsTooLarge = errors.New("attachments too large")
+)
+
+// ErrPayloadTooComplex is wrapped when a payload is nested too deeply or has
+// too many keys or elements in one object or array
+var ErrPayloadTooComplex = errors.New("payload too complex")
+
 // Validator provides message validation functionality
 type Validator struct {
 	maxMessageSize int64
 	schemaManager  *schema.Manager
 	agentManager   AgentManager
+
+	// Attachment limits; zero means unlimited
+	maxAttachments          int
+	maxTotalAttachmentBytes int64
+
+	// Payload structure limits; zero means unlimited
+	maxPayloadDepth    int
+	maxPayloadElements int
+
+	// messageIDs recognizes valid message IDs
+	messageIDs uuid.Generator
+
+	// addresses are the accepted sender and recipient address forms
+	addresses types.AddressSchemes
 }
 
 // New creates a new validator with the given configuration
 func New(maxMessageSize int64) *Validator {
 	return &Validator{
 		maxMessageSize: maxMessageSize,
+		messageIDs:     uuid.V7,
+		addresses:      types.DefaultAddressSchemes,
 	}
 }
 
@@ -59,6 +91,8 @@ func NewWithSchemaManager(maxMessageSize int64, schemaManager *schema.Manager) *
 	return &Validator{
 		maxMessageSize: maxMessageSize,
 		schemaManager:  schemaManager,
+		messageIDs:     uuid.V7,
+		addresses:      types.DefaultAddressSchemes,
 	}
 }
 
@@ -68,9 +102,38 @@ func NewWithAgentManager(maxMessageSize int64, schemaManager *schema.Manager, ag
 		maxMessageSize: maxMessageSize,
 		schemaManager:  schemaManager,
 		agentManager:   agentManager,
+		messageIDs:     uuid.V7,
+		addresses:      types.DefaultAddressSchemes,
 	}
 }
 
+// SetAttachmentLimits limits the number of attachments per message and their
+// total declared size. A zero limit disables the corresponding check.
+func (v *Validator) SetAttachmentLimits(maxCount int, maxTotalBytes int64) {
+	v.maxAttachments = maxCount
+	v.maxTotalAttachmentBytes = maxTotalBytes
+}
+
+// SetPayloadLimits limits how deeply a payload may nest and how many keys or
+// elements one object or array may hold. A zero limit disables the
+// corresponding check.
+func (v *Validator) SetPayloadLimits(maxDepth, maxElements int) {
+	v.maxPayloadDepth = maxDepth
+	v.maxPayloadElements = maxElements
+}
+
+// SetMessageIDGenerator makes message_id and in_reply_to accept the IDs of
+// the gateway's message ID strategy
+func (v *Validator) SetMessageIDGenerator(ids uuid.Generator) {
+	v.messageIDs = ids
+}
+
+// SetAddressSchemes makes senders, recipients and coordination addresses
+// accept the given address schemes
+func (v *Validator) SetAddressSchemes(schemes types.AddressSchemes) {
+	v.addresses = schemes
+}
+
 // ValidateMessage validates an AMTP message according to the protocol specification
 func (v *Validator) ValidateMessage(msg *types.Message) error {
 	return v.ValidateMessageWithContext(context.Background(), msg)
@@ -88,6 +151,16 @@ func (v *Validator) ValidateMessageWithContext(ctx context.Context, msg *types.M
 		return fmt.Errorf("required field validation failed: %w", err)
 	}
 
+	// Check the payload's structure before anything decodes it
+	if err := v.validatePayload(msg.Payload, msg.Encrypted); err != nil {
+		return fmt.Errorf("payload validation failed: %w", err)
+	}
+
+	// Pin versionless schema references before anything checks the schema
+	if err := v.resolveSchemaVersion(ctx, msg); err != nil {
+		return fmt.Errorf("schema resolution failed: %w", err)
+	}
+
 	// Validate field formats
 	if err := v.validateFieldFormats(msg); err != nil {
 		return fmt.Errorf("field format validation failed: %w", err)
@@ -114,8 +187,9 @@ func (v *Validator) ValidateMessageWithContext(ctx context.Context, msg *types.M
 		}
 	}
 
-	// Perform schema validation if schema manager is available and message has schema
-	if v.schemaManager != nil && msg.Schema != "" {
+	// Perform schema validation if schema manager is available and message
+	// has schema; an encrypted payload cannot be checked against it
+	if v.schemaManager != nil && msg.Schema != "" && !msg.Encrypted {
 		if err := v.validateWithSchemaManager(ctx, msg); err != nil {
 			return fmt.Errorf("schema validation failed: %w", err)
 		}
@@ -124,6 +198,29 @@ func (v *Validator) ValidateMessageWithContext(ctx context.Context, msg *types.M
 	return nil
 }
 
+// resolveSchemaVersion rewrites a versionless or @latest schema reference on
+// the message to the latest registered version, so agent support checks,
+// validation, and recipients all see a concrete schema. Without a schema
+// manager the reference is left as is and fails format validation.
+func (v *Validator) resolveSchemaVersion(ctx context.Context, msg *types.Message) error {
+	if v.schemaManager == nil || msg.Schema == "" {
+		return nil
+	}
+
+	ref, err := schema.ParseSchemaReference(msg.Schema)
+	if err != nil || !ref.IsLatest() {
+		// Malformed identifiers are reported by format validation
+		return nil
+	}
+
+	resolved, err := v.schemaManager.ResolveLatest(ctx, ref.Domain, ref.Entity)
+	if err != nil {
+		return err
+	}
+	msg.Schema = resolved.String()
+	return nil
+}
+
 // validateWithSchemaManager performs schema validation using the schema manager
 func (v *Validator) validateWithSchemaManager(ctx context.Context, msg *types.Message) error {
 	report, err := v.schemaManager.ValidateMessage(ctx, msg)
@@ -198,44 +295,50 @@ func (v *Validator) agentSupportsSchema(agent *LocalAgent, messageSchema string)
 		return false
 	}
 
-	// Check for exact match or wildcard match
 	for _, supportedSchema := range agent.SupportedSchemas {
-		if supportedSchema == messageSchema {
+		if SchemaMatches(supportedSchema, messageSchema) {
 			return true
 		}
-
-		// Check wildcard patterns (e.g., "agntcy:commerce.*")
-		if strings.HasSuffix(supportedSchema, "*") {
-			prefix := strings.TrimSuffix(supportedSchema, "*")
-			if strings.HasPrefix(messageSchema, prefix) {
-				return true
-			}
-		}
 	}
 	return false
 }
 
+// SchemaMatches reports whether a schema an agent declares support for
+// covers messageSchema, either exactly or as a wildcard pattern such as
+// "agntcy:commerce.*" that matches every schema with the same prefix
+func SchemaMatches(supportedSchema, messageSchema string) bool {
+	if supportedSchema == messageSchema {
+		return true
+	}
+	prefix, wildcard := strings.CutSuffix(supportedSchema, "*")
+	return wildcard && strings.HasPrefix(messageSchema, prefix)
+}
+
 // ValidateSendRequest validates a send message request
 func (v *Validator) ValidateSendRequest(req *types.SendMessageRequest) error {
-	if req.MessageID != "" && !uuid.IsValidV7(req.MessageID) {
-		return fmt.Errorf("invalid message_id format, must be UUIDv7: %s", req.MessageID)
+	if req.MessageID != "" && !v.messageIDs.IsValid(req.MessageID) {
+		return fmt.Errorf("invalid message_id format, must be %s: %s", v.messageIDs.Format(), req.MessageID)
 	}
 
 	if req.Sender == "" {
 		return fmt.Errorf("sender is required")
 	}
 
-	if !v.isValidEmail(req.Sender) {
-		return fmt.Errorf("invalid sender email format: %s", req.Sender)
+	if !v.isValidAddress(req.Sender) {
+		return fmt.Errorf("invalid sender address format: %s", req.Sender)
 	}
 
 	if len(req.Recipients) == 0 {
 		return fmt.Errorf("at least one recipient is required")
 	}
 
+	if err := v.validatePayload(req.Payload, req.Encrypted); err != nil {
+		return fmt.Errorf("payload validation failed: %w", err)
+	}
+
 	for _, recipient := range req.Recipients {
-		if !v.isValidEmail(recipient) {
-			return fmt.Errorf("invalid recipient email format: %s", recipient)
+		if !v.isValidAddress(recipient) {
+			return fmt.Errorf("invalid recipient address format: %s", recipient)
 		}
 	}
 
@@ -246,11 +349,42 @@ func (v *Validator) ValidateSendRequest(req *types.SendMessageRequest) error {
 		}
 	}
 
+	// Retry overrides are clamped to the gateway's limits later, but must
+	// make sense on their own
+	if req.MaxRetries < 0 {
+		return fmt.Errorf("max_retries cannot be negative: %d", req.MaxRetries)
+	}
+	if req.RetryDelay != "" {
+		delay, err := time.ParseDuration(req.RetryDelay)
+		if err != nil {
+			return fmt.Errorf("invalid retry_delay format: %s", req.RetryDelay)
+		}
+		if delay <= 0 {
+			return fmt.Errorf("retry_delay must be positive: %s", req.RetryDelay)
+		}
+	}
+	// Workflow steps are dispatched later with the gateway's retry policy
+	if req.Coordination != nil && (req.MaxRetries != 0 || req.RetryDelay != "") {
+		return fmt.Errorf("max_retries and retry_delay cannot be used with coordination")
+	}
+
+	if err := validateLabels(req.Labels); err != nil {
+		return err
+	}
+
+	if !schema.IsValidValidationMode(req.ValidationMode) {
+		return fmt.Errorf("invalid validation_mode, must be %s or %s: %s",
+			schema.ValidationModeFull, schema.ValidationModePartial, req.ValidationMode)
+	}
+
 	// Validate attachments if present
 	if len(req.Attachments) > 0 {
 		if err := v.validateAttachments(req.Attachments); err != nil {
 			return fmt.Errorf("attachment validation failed: %w", err)
 		}
+		if err := v.validateAttachmentLimits(req.Attachments); err != nil {
+			return fmt.Errorf("attachment validation failed: %w", err)
+		}
 	}
 
 	return nil
@@ -292,9 +426,9 @@ func (v *Validator) validateFieldFormats(msg *types.Message) error {
 		return fmt.Errorf("unsupported protocol version: %s", msg.Version)
 	}
 
-	// Validate message ID (should be UUIDv7)
-	if !uuid.IsValidV7(msg.MessageID) {
-		return fmt.Errorf("invalid message_id format, must be UUIDv7: %s", msg.MessageID)
+	// Validate message ID
+	if !v.messageIDs.IsValid(msg.MessageID) {
+		return fmt.Errorf("invalid message_id format, must be %s: %s", v.messageIDs.Format(), msg.MessageID)
 	}
 
 	// Validate idempotency key (should be UUIDv4)
@@ -303,20 +437,20 @@ func (v *Validator) validateFieldFormats(msg *types.Message) error {
 	}
 
 	// Validate sender email
-	if !v.isValidEmail(msg.Sender) {
-		return fmt.Errorf("invalid sender email format: %s", msg.Sender)
+	if !v.isValidAddress(msg.Sender) {
+		return fmt.Errorf("invalid sender address format: %s", msg.Sender)
 	}
 
 	// Validate recipient emails
 	for _, recipient := range msg.Recipients {
-		if !v.isValidEmail(recipient) {
-			return fmt.Errorf("invalid recipient email format: %s", recipient)
+		if !v.isValidAddress(recipient) {
+			return fmt.Errorf("invalid recipient address format: %s", recipient)
 		}
 	}
 
 	// Validate in_reply_to if present
-	if msg.InReplyTo != "" && !uuid.IsValidV7(msg.InReplyTo) {
-		return fmt.Errorf("invalid in_reply_to format, must be UUIDv7: %s", msg.InReplyTo)
+	if msg.InReplyTo != "" && !v.messageIDs.IsValid(msg.InReplyTo) {
+		return fmt.Errorf("invalid in_reply_to format, must be %s: %s", v.messageIDs.Format(), msg.InReplyTo)
 	}
 
 	// Validate schema format if present
@@ -326,9 +460,41 @@ func (v *Validator) validateFieldFormats(msg *types.Message) error {
 		}
 	}
 
+	if err := validateLabels(msg.Labels); err != nil {
+		return err
+	}
+
 	return nil
 }
 
+// Label limits, keeping labels cheap to store, index and filter on
+const (
+	MaxLabels      = 20
+	MaxLabelLength = 64
+)
+
+// validateLabels checks the number of labels and the format of each
+func validateLabels(labels []string) error {
+	if len(labels) > MaxLabels {
+		return fmt.Errorf("too many labels: %d, at most %d allowed", len(labels), MaxLabels)
+	}
+	for _, label := range labels {
+		if len(label) > MaxLabelLength {
+			return fmt.Errorf("label too long: %q, at most %d characters allowed", label, MaxLabelLength)
+		}
+		if !labelRegex.MatchString(label) {
+			return fmt.Errorf("invalid label %q: only letters, numbers, and . _ : / - allowed, starting with a letter or number", label)
+		}
+	}
+	return nil
+}
+
+// ValidateCoordination validates a coordination configuration on its own,
+// such as a schema's default coordination
+func (v *Validator) ValidateCoordination(coord *types.CoordinationConfig) error {
+	return v.validateCoordination(coord)
+}
+
 // validateCoordination validates coordination configuration
 func (v *Validator) validateCoordination(coord *types.CoordinationConfig) error {
 	// Validate coordination type
@@ -351,8 +517,8 @@ func (v *Validator) validateCoordination(coord *types.CoordinationConfig) error
 			return fmt.Errorf("sequence is required for sequential coordination")
 		}
 		for _, addr := range coord.Sequence {
-			if !v.isValidEmail(addr) {
-				return fmt.Errorf("invalid email in sequence: %s", addr)
+			if !v.isValidAddress(addr) {
+				return fmt.Errorf("invalid address in sequence: %s", addr)
 			}
 		}
 
@@ -364,17 +530,20 @@ func (v *Validator) validateCoordination(coord *types.CoordinationConfig) error
 			if condition.If == "" {
 				return fmt.Errorf("condition %d: 'if' clause is required", i)
 			}
+			if _, err := workflow.ParseCondition(condition.If); err != nil {
+				return fmt.Errorf("condition %d: %w", i, err)
+			}
 			if len(condition.Then) == 0 {
 				return fmt.Errorf("condition %d: 'then' clause is required", i)
 			}
 			for _, addr := range condition.Then {
-				if !v.isValidEmail(addr) {
-					return fmt.Errorf("condition %d: invalid email in 'then' clause: %s", i, addr)
+				if !v.isValidAddress(addr) {
+					return fmt.Errorf("condition %d: invalid address in 'then' clause: %s", i, addr)
 				}
 			}
 			for _, addr := range condition.Else {
-				if !v.isValidEmail(addr) {
-					return fmt.Errorf("condition %d: invalid email in 'else' clause: %s", i, addr)
+				if !v.isValidAddress(addr) {
+					return fmt.Errorf("condition %d: invalid address in 'else' clause: %s", i, addr)
 				}
 			}
 		}
@@ -382,14 +551,14 @@ func (v *Validator) validateCoordination(coord *types.CoordinationConfig) error
 
 	// Validate required and optional responses
 	for _, addr := range coord.RequiredResponses {
-		if !v.isValidEmail(addr) {
-			return fmt.Errorf("invalid email in required_responses: %s", addr)
+		if !v.isValidAddress(addr) {
+			return fmt.Errorf("invalid address in required_responses: %s", addr)
 		}
 	}
 
 	for _, addr := range coord.OptionalResponses {
-		if !v.isValidEmail(addr) {
-			return fmt.Errorf("invalid email in optional_responses: %s", addr)
+		if !v.isValidAddress(addr) {
+			return fmt.Errorf("invalid address in optional_responses: %s", addr)
 		}
 	}
 
@@ -433,10 +602,109 @@ func (v *Validator) validateAttachments(attachments []types.Attachment) error {
 	return nil
 }
 
-// isValidEmail validates email address format
-func (v *Validator) isValidEmail(email string) bool {
-	_, err := mail.ParseAddress(email)
-	return err == nil
+// validateAttachmentLimits enforces the configured attachment count and total
+// declared size. Sizes are assumed non-negative (see validateAttachments).
+func (v *Validator) validateAttachmentLimits(attachments []types.Attachment) error {
+	if v.maxAttachments > 0 && len(attachments) > v.maxAttachments {
+		return fmt.Errorf("%w: %d attachments exceeds maximum of %d",
+			ErrTooManyAttachments, len(attachments), v.maxAttachments)
+	}
+
+	if v.maxTotalAttachmentBytes > 0 {
+		var total int64
+		for _, attachment := range attachments {
+			// Compare before adding so absurd declared sizes cannot overflow
+			if attachment.Size > v.maxTotalAttachmentBytes-total {
+				return fmt.Errorf("%w: total attachment size exceeds maximum of %d bytes",
+					ErrAttachmentsTooLarge, v.maxTotalAttachmentBytes)
+			}
+			total += attachment.Size
+		}
+	}
+
+	return nil
+}
+
+// validatePayload checks a plaintext payload against the structure limits.
+// An encrypted payload is only checked to be ciphertext, a non-empty JSON
+// string; its content is never parsed.
+func (v *Validator) validatePayload(payload json.RawMessage, encrypted bool) error {
+	if !encrypted {
+		return v.validatePayloadLimits(payload)
+	}
+
+	trimmed := bytes.TrimSpace(payload)
+	if len(trimmed) < 3 || trimmed[0] != '"' || trimmed[len(trimmed)-1] != '"' {
+		return fmt.Errorf("an encrypted payload must be a non-empty JSON string of ciphertext")
+	}
+	return nil
+}
+
+// validatePayloadLimits walks the payload token by token, so a pathological
+// payload is rejected after reading no more than the limits allow and
+// without building it in memory
+func (v *Validator) validatePayloadLimits(payload json.RawMessage) error {
+	if len(payload) == 0 || (v.maxPayloadDepth <= 0 && v.maxPayloadElements <= 0) {
+		return nil
+	}
+
+	// One frame per open object or array; in an object, tokens alternate
+	// between keys and values
+	type frame struct {
+		object    bool
+		expectKey bool
+		count     int
+	}
+	var stack []frame
+
+	decoder := json.NewDecoder(bytes.NewReader(payload))
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("invalid payload: %w", err)
+		}
+
+		delim, isDelim := token.(json.Delim)
+		if isDelim && (delim == '}' || delim == ']') {
+			stack = stack[:len(stack)-1]
+			continue
+		}
+
+		if n := len(stack); n > 0 {
+			top := &stack[n-1]
+			isKey := top.object && top.expectKey
+			if top.object {
+				top.expectKey = !isKey
+			}
+			// Objects count their keys, arrays their elements
+			if isKey || !top.object {
+				top.count++
+				if v.maxPayloadElements > 0 && top.count > v.maxPayloadElements {
+					return fmt.Errorf("%w: an object or array has more than %d keys or elements",
+						ErrPayloadTooComplex, v.maxPayloadElements)
+				}
+			}
+			if isKey {
+				continue
+			}
+		}
+
+		if isDelim {
+			stack = append(stack, frame{object: delim == '{', expectKey: true})
+			if v.maxPayloadDepth > 0 && len(stack) > v.maxPayloadDepth {
+				return fmt.Errorf("%w: nesting depth exceeds maximum of %d",
+					ErrPayloadTooComplex, v.maxPayloadDepth)
+			}
+		}
+	}
+}
+
+// isValidAddress validates an agent address against the accepted schemes
+func (v *Validator) isValidAddress(address string) bool {
+	return v.addresses.IsValid(address)
 }
 
 // Validation patterns compiled once at package load.
@@ -444,6 +712,7 @@ var (
 	urlRegex       = regexp.MustCompile(`^https?://[^\s/$.?#].[^\s]*$`)
 	hashRegex      = regexp.MustCompile(`^(sha256|sha512|md5):[a-fA-F0-9]+$`)
 	schemaFmtRegex = regexp.MustCompile(`^agntcy:[a-zA-Z0-9_-]+\.[a-zA-Z0-9_-]+\.v[0-9]+$`)
+	labelRegex     = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9._:/-]*$`)
 )
 
 // isValidURL validates URL format (basic validation)
@@ -19,9 +19,14 @@ package validation
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/amtp-protocol/agentry/internal/schema"
 	"github.com/amtp-protocol/agentry/internal/types"
 )
 
@@ -103,6 +108,121 @@ func TestValidateSendRequest(t *testing.T) {
 	}
 }
 
+func TestValidateSendRequest_AddressSchemes(t *testing.T) {
+	validator := New(10 * 1024 * 1024)
+	req := &types.SendMessageRequest{
+		Sender:     "urn:agent:orders",
+		Recipients: []string{"recipient@example.com", "urn:agent:1234"},
+		Coordination: &types.CoordinationConfig{
+			Type:     "sequential",
+			Timeout:  30,
+			Sequence: []string{"urn:agent:1234", "recipient@example.com"},
+		},
+	}
+
+	if err := validator.ValidateSendRequest(req); err == nil || !strings.Contains(err.Error(), "invalid sender address format") {
+		t.Errorf("Expected URNs to be rejected by default, got %v", err)
+	}
+
+	validator.SetAddressSchemes(types.AddressSchemes{types.EmailScheme, types.URNScheme})
+	if err := validator.ValidateSendRequest(req); err != nil {
+		t.Errorf("Expected URNs to be accepted with the urn scheme, got %v", err)
+	}
+
+	req.Recipients = append(req.Recipients, "agent-1234")
+	if err := validator.ValidateSendRequest(req); err == nil || !strings.Contains(err.Error(), "invalid recipient address format: agent-1234") {
+		t.Errorf("Expected an invalid recipient error, got %v", err)
+	}
+}
+
+func TestValidateSendRequest_RetryPolicy(t *testing.T) {
+	validator := New(10 * 1024 * 1024)
+
+	sequential := &types.CoordinationConfig{Type: "sequential", Timeout: 60, Sequence: []string{"recipient@example.com"}}
+
+	tests := []struct {
+		name         string
+		maxRetries   int
+		retryDelay   string
+		coordination *types.CoordinationConfig
+		wantErr      bool
+	}{
+		{"no override", 0, "", nil, false},
+		{"valid override", 5, "500ms", nil, false},
+		{"negative max retries", -1, "", nil, true},
+		{"unparseable retry delay", 0, "soon", nil, true},
+		{"zero retry delay", 0, "0s", nil, true},
+		{"coordination without override", 0, "", sequential, false},
+		{"max retries with coordination", 5, "", sequential, true},
+		{"retry delay with coordination", 0, "500ms", sequential, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validator.ValidateSendRequest(&types.SendMessageRequest{
+				Sender:       "test@example.com",
+				Recipients:   []string{"recipient@example.com"},
+				MaxRetries:   tt.maxRetries,
+				RetryDelay:   tt.retryDelay,
+				Coordination: tt.coordination,
+			})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateSendRequest() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateSendRequest_Labels(t *testing.T) {
+	validator := New(10 * 1024 * 1024)
+
+	tooMany := make([]string, MaxLabels+1)
+	for i := range tooMany {
+		tooMany[i] = fmt.Sprintf("label-%d", i)
+	}
+
+	tests := []struct {
+		name    string
+		labels  []string
+		wantErr bool
+	}{
+		{"no labels", nil, false},
+		{"valid labels", []string{"campaign-2024", "team:sales", "eu/west", "v1.2_rc"}, false},
+		{"too many labels", tooMany, true},
+		{"label too long", []string{strings.Repeat("a", MaxLabelLength+1)}, true},
+		{"empty label", []string{""}, true},
+		{"label with space", []string{"campaign 2024"}, true},
+		{"label starting with punctuation", []string{"-campaign"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validator.ValidateSendRequest(&types.SendMessageRequest{
+				Sender:     "test@example.com",
+				Recipients: []string{"recipient@example.com"},
+				Labels:     tt.labels,
+			})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateSendRequest() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	// Messages relayed from other gateways are held to the same limits
+	message := &types.Message{
+		Version:        "1.0",
+		MessageID:      "01234567-89ab-7def-8123-456789abcdef",
+		IdempotencyKey: "01234567-89ab-4def-8123-456789abcdef",
+		Timestamp:      time.Now(),
+		Sender:         "test@example.com",
+		Recipients:     []string{"recipient@example.com"},
+		Labels:         tooMany,
+	}
+	if err := validator.ValidateMessage(message); err == nil || !strings.Contains(err.Error(), "too many labels") {
+		t.Errorf("Expected a too many labels error, got %v", err)
+	}
+}
+
 func TestValidateCoordination(t *testing.T) {
 	validator := New(10 * 1024 * 1024)
 
@@ -146,6 +266,26 @@ func TestValidateCoordination(t *testing.T) {
 	if err == nil {
 		t.Error("Sequential coordination without sequence should fail validation")
 	}
+
+	// Valid conditional coordination
+	conditional := &types.CoordinationConfig{
+		Type:    "conditional",
+		Timeout: 3600,
+		Conditions: []types.ConditionalRule{
+			{If: `status == "approved" && payload.amount < 1000`, Then: []string{"next@example.com"}},
+		},
+	}
+	err = validator.validateCoordination(conditional)
+	if err != nil {
+		t.Errorf("Valid conditional coordination should pass: %v", err)
+	}
+
+	// Conditional with a malformed expression
+	conditional.Conditions[0].If = `status = "approved"`
+	err = validator.validateCoordination(conditional)
+	if err == nil {
+		t.Error("Conditional coordination with a malformed condition should fail validation")
+	}
 }
 
 func TestValidateAttachments(t *testing.T) {
@@ -197,6 +337,174 @@ func TestValidateAttachments(t *testing.T) {
 	}
 }
 
+func TestValidateSendRequest_AttachmentLimits(t *testing.T) {
+	validator := New(10 * 1024 * 1024)
+	validator.SetAttachmentLimits(3, 3000)
+
+	attachments := func(sizes ...int64) []types.Attachment {
+		var result []types.Attachment
+		for i, size := range sizes {
+			result = append(result, types.Attachment{
+				Filename:    fmt.Sprintf("file%d.pdf", i),
+				ContentType: "application/pdf",
+				Size:        size,
+				Hash:        "sha256:abcdef1234567890",
+				URL:         fmt.Sprintf("https://example.com/files/file%d.pdf", i),
+			})
+		}
+		return result
+	}
+
+	tests := []struct {
+		name        string
+		attachments []types.Attachment
+		wantErr     error
+	}{
+		{"no attachments", nil, nil},
+		{"count at limit", attachments(1, 1, 1), nil},
+		{"count over limit", attachments(1, 1, 1, 1), ErrTooManyAttachments},
+		{"total size at limit", attachments(1000, 1000, 1000), nil},
+		{"total size over limit", attachments(1000, 1000, 1001), ErrAttachmentsTooLarge},
+		{"single attachment over limit", attachments(3001), ErrAttachmentsTooLarge},
+		{"overflowing sizes", attachments(math.MaxInt64, math.MaxInt64), ErrAttachmentsTooLarge},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := &types.SendMessageRequest{
+				Sender:      "test@example.com",
+				Recipients:  []string{"recipient@example.com"},
+				Attachments: tt.attachments,
+			}
+			err := validator.ValidateSendRequest(req)
+			if tt.wantErr == nil && err != nil {
+				t.Errorf("Expected request to pass, got %v", err)
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("Expected %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+
+	// Zero limits disable the checks
+	validator.SetAttachmentLimits(0, 0)
+	req := &types.SendMessageRequest{
+		Sender:      "test@example.com",
+		Recipients:  []string{"recipient@example.com"},
+		Attachments: attachments(5000, 5000, 5000, 5000),
+	}
+	if err := validator.ValidateSendRequest(req); err != nil {
+		t.Errorf("Expected no limits to apply, got %v", err)
+	}
+}
+
+func TestValidateSendRequest_PayloadLimits(t *testing.T) {
+	validator := New(10 * 1024 * 1024)
+	validator.SetPayloadLimits(4, 3)
+
+	nested := func(depth int) string {
+		return strings.Repeat(`{"a":[`, depth/2) + strings.Repeat("[", depth%2) + "1" +
+			strings.Repeat("]", depth%2) + strings.Repeat("]}", depth/2)
+	}
+
+	tests := []struct {
+		name    string
+		payload string
+		wantErr error
+	}{
+		{"scalar", `"text"`, nil},
+		{"depth at limit", nested(4), nil},
+		{"depth over limit", nested(5), ErrPayloadTooComplex},
+		{"keys at limit", `{"a":1,"b":{"c":2},"d":[]}`, nil},
+		{"keys over limit", `{"a":1,"b":2,"c":3,"d":4}`, ErrPayloadTooComplex},
+		{"elements at limit", `[1,[2,3,4],{"x":5}]`, nil},
+		{"elements over limit", `[1,2,3,[]]`, ErrPayloadTooComplex},
+		{"nested elements over limit", `{"a":[1,2,3,4]}`, ErrPayloadTooComplex},
+		{"pathological nesting", strings.Repeat("[", 100000) + strings.Repeat("]", 100000), ErrPayloadTooComplex},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := &types.SendMessageRequest{
+				Sender:     "test@example.com",
+				Recipients: []string{"recipient@example.com"},
+				Payload:    json.RawMessage(tt.payload),
+			}
+			err := validator.ValidateSendRequest(req)
+			if tt.wantErr == nil && err != nil {
+				t.Errorf("Expected request to pass, got %v", err)
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("Expected %v, got %v", tt.wantErr, err)
+			}
+
+			// Messages that did not come through a send request are checked too
+			message := &types.Message{
+				Version:        "1.0",
+				MessageID:      "01234567-89ab-7def-8123-456789abcdef",
+				IdempotencyKey: "01234567-89ab-4def-8123-456789abcdef",
+				Timestamp:      time.Now(),
+				Sender:         "test@example.com",
+				Recipients:     []string{"recipient@example.com"},
+				Payload:        json.RawMessage(tt.payload),
+			}
+			err = validator.ValidateMessage(message)
+			if tt.wantErr == nil && err != nil {
+				t.Errorf("Expected message to pass, got %v", err)
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("Expected message to fail with %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+
+	validator.SetPayloadLimits(0, 0)
+	req := &types.SendMessageRequest{
+		Sender:     "test@example.com",
+		Recipients: []string{"recipient@example.com"},
+		Payload:    json.RawMessage(nested(200)),
+	}
+	if err := validator.ValidateSendRequest(req); err != nil {
+		t.Errorf("Expected no limits to apply, got %v", err)
+	}
+}
+
+func TestValidateSendRequest_EncryptedPayload(t *testing.T) {
+	validator := New(10 * 1024 * 1024)
+	validator.SetPayloadLimits(1, 1)
+
+	tests := []struct {
+		name    string
+		payload string
+		valid   bool
+	}{
+		{"ciphertext", `"eyJhbGciOiJFQ0RILUVTIn0.c2VjcmV0"`, true},
+		{"ciphertext that looks like JSON", `"{\"a\":[[[1,2,3]]]}"`, true},
+		{"empty string", `""`, false},
+		{"object", `{"order_id":"o-1"}`, false},
+		{"number", `42`, false},
+		{"missing", ``, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := &types.SendMessageRequest{
+				Sender:     "test@example.com",
+				Recipients: []string{"recipient@example.com"},
+				Payload:    json.RawMessage(tt.payload),
+				Encrypted:  true,
+			}
+			err := validator.ValidateSendRequest(req)
+			if tt.valid && err != nil {
+				t.Errorf("Expected payload %s to pass, got %v", tt.payload, err)
+			}
+			if !tt.valid && err == nil {
+				t.Errorf("Expected payload %s to be rejected", tt.payload)
+			}
+		})
+	}
+}
+
 func TestValidateSchemaFormat(t *testing.T) {
 	validator := New(10 * 1024 * 1024)
 
@@ -241,7 +549,7 @@ func TestIsValidEmail(t *testing.T) {
 	}
 
 	for _, email := range validEmails {
-		if !validator.isValidEmail(email) {
+		if !validator.isValidAddress(email) {
 			t.Errorf("Valid email %s should pass validation", email)
 		}
 	}
@@ -254,7 +562,7 @@ func TestIsValidEmail(t *testing.T) {
 	}
 
 	for _, email := range invalidEmails {
-		if validator.isValidEmail(email) {
+		if validator.isValidAddress(email) {
 			t.Errorf("Invalid email %s should fail validation", email)
 		}
 	}
@@ -314,6 +622,71 @@ func TestValidateMessageWithContext_NoAgentManager(t *testing.T) {
 	}
 }
 
+func TestValidateMessageWithContext_ResolvesLatestSchema(t *testing.T) {
+	sm, err := schema.NewManager(schema.ManagerConfig{
+		RegistryType: "local",
+		LocalRegistry: schema.LocalRegistryConfig{
+			BasePath:   t.TempDir(),
+			CreateDirs: true,
+		},
+		Validation: schema.ValidatorConfig{Enabled: true},
+		Pipeline:   schema.PipelineConfig{Enabled: true},
+	})
+	if err != nil {
+		t.Fatalf("failed to create schema manager: %v", err)
+	}
+	defer sm.Shutdown(context.Background())
+
+	for _, version := range []string{"v1", "v2"} {
+		id := schema.SchemaIdentifier{Domain: "commerce", Entity: "order", Version: version}
+		if err := sm.RegisterSchema(context.Background(), &schema.Schema{
+			ID:          id,
+			Definition:  json.RawMessage(`{"type": "object"}`),
+			PublishedAt: time.Now(),
+		}, nil); err != nil {
+			t.Fatalf("failed to register %s: %v", id.String(), err)
+		}
+	}
+
+	// The recipient only supports v2, so the reference must be resolved
+	// before agent schema support is checked
+	agentManager := NewMockAgentManager()
+	agentManager.AddAgent("agent@example.com", []string{"agntcy:commerce.order.v2"})
+	validator := NewWithAgentManager(10*1024*1024, sm, agentManager)
+
+	newMessage := func(schemaRef string) *types.Message {
+		return &types.Message{
+			Version:        "1.0",
+			MessageID:      "01234567-89ab-7def-8123-456789abcdef",
+			IdempotencyKey: "01234567-89ab-4def-8123-456789abcdef",
+			Timestamp:      time.Now(),
+			Sender:         "test@example.com",
+			Recipients:     []string{"agent@example.com"},
+			Schema:         schemaRef,
+			Payload:        json.RawMessage(`{"order_id": "1"}`),
+		}
+	}
+
+	for _, ref := range []string{"agntcy:commerce.order", "agntcy:commerce.order@latest"} {
+		msg := newMessage(ref)
+		if err := validator.ValidateMessageWithContext(context.Background(), msg); err != nil {
+			t.Errorf("%s: unexpected error: %v", ref, err)
+		}
+		if msg.Schema != "agntcy:commerce.order.v2" {
+			t.Errorf("%s: expected schema to resolve to agntcy:commerce.order.v2, got %s", ref, msg.Schema)
+		}
+	}
+
+	if err := validator.ValidateMessageWithContext(context.Background(), newMessage("agntcy:commerce.refund")); err == nil {
+		t.Error("expected error for a reference with no registered versions")
+	}
+
+	// Without a schema manager there is nothing to resolve against
+	if err := New(10 * 1024 * 1024).ValidateMessage(newMessage("agntcy:commerce.order")); err == nil {
+		t.Error("expected versionless schema to be rejected without a schema manager")
+	}
+}
+
 func TestValidateMessageWithContext_AgentSchemaSupport(t *testing.T) {
 	agentManager := NewMockAgentManager()
 	validator := NewWithAgentManager(10*1024*1024, nil, agentManager)
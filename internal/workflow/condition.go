@@ -0,0 +1,590 @@
+package workflow
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Conditional coordination rules choose their next recipients with a small
+// expression language. Expressions cannot call functions or modify anything;
+// they only read JSON data. The grammar is:
+//
+//	expr     = and { "||" and }
+//	and      = unary { "&&" unary }
+//	unary    = "!" unary | primary
+//	primary  = "(" expr ")" | "exists" "(" path ")" | operand [ compare operand ]
+//	compare  = "==" | "!=" | "<" | "<=" | ">" | ">="
+//	operand  = path | string | number | "true" | "false" | "null"
+//	path     = ident { "." ident | "[" ( string | integer ) "]" }
+//
+// Strings use single or double quotes with backslash escapes. A path that
+// starts with "payload" reads the original message payload, "response" reads
+// the reply that triggered the evaluation, and "responses['agent@example.com']"
+// reads the reply of any participant that has already answered. Other paths
+// are shorthand for a field of "response", so `status == "ok"` and
+// `response.status == "ok"` mean the same thing.
+//
+// Missing fields are null. == and != compare values of the same JSON type;
+// values of different types are never equal. Ordering comparisons apply to
+// two numbers or two strings and are false otherwise. An operand on its own
+// is true only if it is the boolean true.
+
+const (
+	maxConditionLength = 1024
+	maxConditionDepth  = 32
+)
+
+// Condition roots available to paths
+const (
+	conditionRootPayload   = "payload"
+	conditionRootResponse  = "response"
+	conditionRootResponses = "responses"
+)
+
+// ConditionData is the data a condition is evaluated against
+type ConditionData struct {
+	// Payload is the payload of the message that started the workflow
+	Payload json.RawMessage
+	// Response is the reply that triggered the evaluation
+	Response json.RawMessage
+	// Responses holds the replies received so far, keyed by participant address
+	Responses map[string]json.RawMessage
+}
+
+// Condition is a parsed conditional rule expression
+type Condition struct {
+	expr string
+	root condNode
+}
+
+// ParseCondition parses expr, returning an error describing the first
+// problem if it is malformed
+func ParseCondition(expr string) (*Condition, error) {
+	if strings.TrimSpace(expr) == "" {
+		return nil, fmt.Errorf("invalid condition: expression is empty")
+	}
+	if len(expr) > maxConditionLength {
+		return nil, fmt.Errorf("invalid condition: expression exceeds %d characters", maxConditionLength)
+	}
+
+	tokens, err := lexCondition(expr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid condition %q: %w", expr, err)
+	}
+
+	p := &conditionParser{tokens: tokens}
+	root, err := p.parseOr(0)
+	if err == nil && p.peek().kind != tokEOF {
+		err = p.unexpected()
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid condition %q: %w", expr, err)
+	}
+
+	return &Condition{expr: expr, root: root}, nil
+}
+
+// String returns the source expression
+func (c *Condition) String() string {
+	return c.expr
+}
+
+// Evaluate reports whether the condition holds for data. Payloads that are
+// not valid JSON behave as if they were empty.
+func (c *Condition) Evaluate(data ConditionData) bool {
+	responses := make(map[string]interface{}, len(data.Responses))
+	for addr, raw := range data.Responses {
+		responses[addr] = decodeConditionJSON(raw)
+	}
+	env := map[string]interface{}{
+		conditionRootPayload:   decodeConditionJSON(data.Payload),
+		conditionRootResponse:  decodeConditionJSON(data.Response),
+		conditionRootResponses: responses,
+	}
+	return c.root.eval(env)
+}
+
+func decodeConditionJSON(raw json.RawMessage) interface{} {
+	if len(raw) == 0 {
+		return nil
+	}
+	var v interface{}
+	if err := json.Unmarshal(raw, &v); err != nil {
+		return nil
+	}
+	return v
+}
+
+// Expression tree
+
+type condNode interface {
+	eval(env map[string]interface{}) bool
+}
+
+type orNode struct{ left, right condNode }
+
+func (n orNode) eval(env map[string]interface{}) bool { return n.left.eval(env) || n.right.eval(env) }
+
+type andNode struct{ left, right condNode }
+
+func (n andNode) eval(env map[string]interface{}) bool { return n.left.eval(env) && n.right.eval(env) }
+
+type notNode struct{ operand condNode }
+
+func (n notNode) eval(env map[string]interface{}) bool { return !n.operand.eval(env) }
+
+type existsNode struct{ path pathOperand }
+
+func (n existsNode) eval(env map[string]interface{}) bool {
+	_, ok := n.path.lookup(env)
+	return ok
+}
+
+type truthNode struct{ operand condOperand }
+
+func (n truthNode) eval(env map[string]interface{}) bool {
+	v, _ := n.operand.value(env).(bool)
+	return v
+}
+
+type compareNode struct {
+	op          string
+	left, right condOperand
+}
+
+func (n compareNode) eval(env map[string]interface{}) bool {
+	left, right := n.left.value(env), n.right.value(env)
+	switch n.op {
+	case "==":
+		return conditionEqual(left, right)
+	case "!=":
+		return !conditionEqual(left, right)
+	}
+
+	var cmp int
+	switch l := left.(type) {
+	case float64:
+		r, ok := right.(float64)
+		if !ok {
+			return false
+		}
+		switch {
+		case l < r:
+			cmp = -1
+		case l > r:
+			cmp = 1
+		}
+	case string:
+		r, ok := right.(string)
+		if !ok {
+			return false
+		}
+		cmp = strings.Compare(l, r)
+	default:
+		return false
+	}
+
+	switch n.op {
+	case "<":
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	case ">":
+		return cmp > 0
+	default: // ">="
+		return cmp >= 0
+	}
+}
+
+// conditionEqual compares decoded JSON values, which are only equal when they
+// have the same type
+func conditionEqual(a, b interface{}) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	if reflect.TypeOf(a) != reflect.TypeOf(b) {
+		return false
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+type condOperand interface {
+	value(env map[string]interface{}) interface{}
+}
+
+type literalOperand struct{ v interface{} }
+
+func (o literalOperand) value(map[string]interface{}) interface{} { return o.v }
+
+// pathOperand is a sequence of map keys (string) and array indexes (int)
+type pathOperand struct{ segments []interface{} }
+
+func (o pathOperand) value(env map[string]interface{}) interface{} {
+	v, _ := o.lookup(env)
+	return v
+}
+
+func (o pathOperand) lookup(env map[string]interface{}) (interface{}, bool) {
+	var cur interface{} = env
+	for _, seg := range o.segments {
+		switch s := seg.(type) {
+		case string:
+			m, ok := cur.(map[string]interface{})
+			if !ok {
+				return nil, false
+			}
+			if cur, ok = m[s]; !ok {
+				return nil, false
+			}
+		case int:
+			arr, ok := cur.([]interface{})
+			if !ok || s >= len(arr) {
+				return nil, false
+			}
+			cur = arr[s]
+		}
+	}
+	return cur, true
+}
+
+// Lexer
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokIdent
+	tokString
+	tokNumber
+	tokOp
+	tokLParen
+	tokRParen
+	tokLBracket
+	tokRBracket
+	tokDot
+)
+
+type condToken struct {
+	kind tokenKind
+	text string // operator or identifier text, unquoted string value
+	num  float64
+	pos  int
+}
+
+func (t condToken) describe() string {
+	switch t.kind {
+	case tokEOF:
+		return "end of expression"
+	case tokString:
+		return strconv.Quote(t.text)
+	default:
+		return fmt.Sprintf("%q", t.text)
+	}
+}
+
+func lexCondition(expr string) ([]condToken, error) {
+	var tokens []condToken
+	i := 0
+	for i < len(expr) {
+		c := expr[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '(':
+			tokens = append(tokens, condToken{kind: tokLParen, text: "(", pos: i})
+			i++
+		case c == ')':
+			tokens = append(tokens, condToken{kind: tokRParen, text: ")", pos: i})
+			i++
+		case c == '[':
+			tokens = append(tokens, condToken{kind: tokLBracket, text: "[", pos: i})
+			i++
+		case c == ']':
+			tokens = append(tokens, condToken{kind: tokRBracket, text: "]", pos: i})
+			i++
+		case c == '.':
+			tokens = append(tokens, condToken{kind: tokDot, text: ".", pos: i})
+			i++
+		case c == '"' || c == '\'':
+			s, n, err := lexConditionString(expr[i:])
+			if err != nil {
+				return nil, fmt.Errorf("%w at position %d", err, i)
+			}
+			tokens = append(tokens, condToken{kind: tokString, text: s, pos: i})
+			i += n
+		case isDigit(c) || (c == '-' && i+1 < len(expr) && isDigit(expr[i+1])):
+			start := i
+			i++
+			for i < len(expr) && (isDigit(expr[i]) || expr[i] == '.') {
+				i++
+			}
+			num, err := strconv.ParseFloat(expr[start:i], 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid number %q at position %d", expr[start:i], start)
+			}
+			tokens = append(tokens, condToken{kind: tokNumber, text: expr[start:i], num: num, pos: start})
+		case isIdentStart(c):
+			start := i
+			for i < len(expr) && isIdentPart(expr[i]) {
+				i++
+			}
+			tokens = append(tokens, condToken{kind: tokIdent, text: expr[start:i], pos: start})
+		default:
+			op := ""
+			for _, candidate := range []string{"==", "!=", "<=", ">=", "&&", "||", "<", ">", "!"} {
+				if strings.HasPrefix(expr[i:], candidate) {
+					op = candidate
+					break
+				}
+			}
+			if op == "" {
+				return nil, fmt.Errorf("unexpected character %q at position %d", c, i)
+			}
+			tokens = append(tokens, condToken{kind: tokOp, text: op, pos: i})
+			i += len(op)
+		}
+	}
+	return append(tokens, condToken{kind: tokEOF, pos: len(expr)}), nil
+}
+
+// lexConditionString reads a quoted string at the start of s, returning its
+// value and the number of bytes consumed
+func lexConditionString(s string) (string, int, error) {
+	quote := s[0]
+	var b strings.Builder
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case quote:
+			return b.String(), i + 1, nil
+		case '\\':
+			if i+1 == len(s) {
+				return "", 0, fmt.Errorf("unterminated string")
+			}
+			i++
+			switch s[i] {
+			case 'n':
+				b.WriteByte('\n')
+			case 't':
+				b.WriteByte('\t')
+			default:
+				b.WriteByte(s[i])
+			}
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return "", 0, fmt.Errorf("unterminated string")
+}
+
+func isDigit(c byte) bool { return c >= '0' && c <= '9' }
+
+func isIdentStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isIdentPart(c byte) bool { return isIdentStart(c) || isDigit(c) || c == '-' }
+
+// Parser
+
+type conditionParser struct {
+	tokens []condToken
+	pos    int
+}
+
+func (p *conditionParser) peek() condToken { return p.tokens[p.pos] }
+
+func (p *conditionParser) next() condToken {
+	t := p.tokens[p.pos]
+	if t.kind != tokEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *conditionParser) unexpected() error {
+	t := p.peek()
+	return fmt.Errorf("unexpected %s at position %d", t.describe(), t.pos)
+}
+
+func (p *conditionParser) expect(kind tokenKind, what string) error {
+	if p.peek().kind != kind {
+		t := p.peek()
+		return fmt.Errorf("expected %s but found %s at position %d", what, t.describe(), t.pos)
+	}
+	p.next()
+	return nil
+}
+
+func (p *conditionParser) isOp(op string) bool {
+	t := p.peek()
+	return t.kind == tokOp && t.text == op
+}
+
+func (p *conditionParser) parseOr(depth int) (condNode, error) {
+	left, err := p.parseAnd(depth)
+	if err != nil {
+		return nil, err
+	}
+	for p.isOp("||") {
+		p.next()
+		right, err := p.parseAnd(depth)
+		if err != nil {
+			return nil, err
+		}
+		left = orNode{left, right}
+	}
+	return left, nil
+}
+
+func (p *conditionParser) parseAnd(depth int) (condNode, error) {
+	left, err := p.parseUnary(depth)
+	if err != nil {
+		return nil, err
+	}
+	for p.isOp("&&") {
+		p.next()
+		right, err := p.parseUnary(depth)
+		if err != nil {
+			return nil, err
+		}
+		left = andNode{left, right}
+	}
+	return left, nil
+}
+
+func (p *conditionParser) parseUnary(depth int) (condNode, error) {
+	if depth > maxConditionDepth {
+		return nil, fmt.Errorf("expression nested deeper than %d levels", maxConditionDepth)
+	}
+	if p.isOp("!") {
+		p.next()
+		operand, err := p.parseUnary(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		return notNode{operand}, nil
+	}
+	return p.parsePrimary(depth)
+}
+
+func (p *conditionParser) parsePrimary(depth int) (condNode, error) {
+	t := p.peek()
+
+	if t.kind == tokLParen {
+		p.next()
+		node, err := p.parseOr(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(tokRParen, `")"`); err != nil {
+			return nil, err
+		}
+		return node, nil
+	}
+
+	if t.kind == tokIdent && t.text == "exists" && p.tokens[p.pos+1].kind == tokLParen {
+		p.next()
+		p.next()
+		if p.peek().kind != tokIdent {
+			return nil, fmt.Errorf("exists() takes a field path, found %s at position %d", p.peek().describe(), p.peek().pos)
+		}
+		path, err := p.parsePath()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(tokRParen, `")"`); err != nil {
+			return nil, err
+		}
+		return existsNode{path}, nil
+	}
+
+	left, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+
+	op := p.peek()
+	if op.kind != tokOp {
+		return truthNode{left}, nil
+	}
+	switch op.text {
+	case "==", "!=", "<", "<=", ">", ">=":
+	default:
+		return truthNode{left}, nil
+	}
+	p.next()
+
+	right, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+	return compareNode{op: op.text, left: left, right: right}, nil
+}
+
+func (p *conditionParser) parseOperand() (condOperand, error) {
+	t := p.peek()
+	switch t.kind {
+	case tokString:
+		p.next()
+		return literalOperand{t.text}, nil
+	case tokNumber:
+		p.next()
+		return literalOperand{t.num}, nil
+	case tokIdent:
+		switch t.text {
+		case "true":
+			p.next()
+			return literalOperand{true}, nil
+		case "false":
+			p.next()
+			return literalOperand{false}, nil
+		case "null":
+			p.next()
+			return literalOperand{nil}, nil
+		}
+		return p.parsePath()
+	}
+	return nil, fmt.Errorf("expected a value or field path but found %s at position %d", t.describe(), t.pos)
+}
+
+func (p *conditionParser) parsePath() (pathOperand, error) {
+	first := p.next()
+	segments := []interface{}{first.text}
+	switch first.text {
+	case conditionRootPayload, conditionRootResponse, conditionRootResponses:
+	default:
+		segments = []interface{}{conditionRootResponse, first.text}
+	}
+
+	for {
+		switch p.peek().kind {
+		case tokDot:
+			p.next()
+			t := p.peek()
+			if t.kind != tokIdent {
+				return pathOperand{}, fmt.Errorf("expected a field name after \".\" but found %s at position %d", t.describe(), t.pos)
+			}
+			p.next()
+			segments = append(segments, t.text)
+		case tokLBracket:
+			p.next()
+			t := p.next()
+			switch {
+			case t.kind == tokString:
+				segments = append(segments, t.text)
+			case t.kind == tokNumber && t.num >= 0 && t.num == float64(int(t.num)):
+				segments = append(segments, int(t.num))
+			default:
+				return pathOperand{}, fmt.Errorf("expected a quoted key or array index but found %s at position %d", t.describe(), t.pos)
+			}
+			if err := p.expect(tokRBracket, `"]"`); err != nil {
+				return pathOperand{}, err
+			}
+		default:
+			return pathOperand{segments}, nil
+		}
+	}
+}
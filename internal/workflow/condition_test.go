@@ -0,0 +1,161 @@
+package workflow
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestCondition_Evaluate(t *testing.T) {
+	data := ConditionData{
+		Payload:  json.RawMessage(`{"order":{"total":250,"currency":"USD","items":[{"sku":"A1"}]},"priority":"high"}`),
+		Response: json.RawMessage(`{"status":"ok","score":0.92,"approved":true,"notes":null,"tags":["x","y"]}`),
+		Responses: map[string]json.RawMessage{
+			"reviewer@example.com": json.RawMessage(`{"decision":"approve"}`),
+			"auditor@example.com":  json.RawMessage(`{"decision":"reject","risk":7}`),
+		},
+	}
+
+	tests := []struct {
+		expr string
+		want bool
+	}{
+		// Equality against the triggering response, with and without the root
+		{`status == "ok"`, true},
+		{`status == 'ok'`, true},
+		{`response.status == "ok"`, true},
+		{`status != "ok"`, false},
+		{`status == "failed"`, false},
+
+		// Payload and prior responses
+		{`payload.priority == "high"`, true},
+		{`payload.order.currency == "USD"`, true},
+		{`payload.order.items[0].sku == "A1"`, true},
+		{`payload.order.items[1].sku == "A1"`, false},
+		{`responses['reviewer@example.com'].decision == "approve"`, true},
+		{`responses["auditor@example.com"].risk >= 7`, true},
+		{`responses['unknown@example.com'].decision == "approve"`, false},
+
+		// Numeric and string comparisons
+		{`payload.order.total > 100`, true},
+		{`payload.order.total >= 250`, true},
+		{`payload.order.total < 250`, false},
+		{`payload.order.total <= 250.0`, true},
+		{`score > 0.9`, true},
+		{`score > -1`, true},
+		{`status < "pending"`, true},
+		{`payload.order.total == 250`, true},
+
+		// Mismatched types never compare equal or ordered
+		{`payload.order.total == "250"`, false},
+		{`payload.order.total != "250"`, true},
+		{`status > 1`, false},
+		{`approved == "true"`, false},
+
+		// Field existence and null
+		{`exists(status)`, true},
+		{`exists(payload.order.total)`, true},
+		{`exists(missing)`, false},
+		{`exists(notes)`, true},
+		{`notes == null`, true},
+		{`missing == null`, true},
+		{`missing != "ok"`, true},
+		{`missing > 1`, false},
+		{`!exists(payload.order.discount)`, true},
+
+		// Bare operands are true only for boolean true
+		{`approved`, true},
+		{`response.approved`, true},
+		{`status`, false},
+		{`missing`, false},
+		{`true`, true},
+		{`false`, false},
+
+		// Boolean operators, precedence and grouping
+		{`status == "ok" && score > 0.5`, true},
+		{`status == "ok" && score > 0.95`, false},
+		{`status == "failed" || payload.priority == "high"`, true},
+		{`status == "failed" || score > 0.95 && approved`, false},
+		{`(status == "failed" || score > 0.5) && approved`, true},
+		{`!(status == "ok")`, false},
+		{`!!approved`, true},
+		{`tags == tags`, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			c, err := ParseCondition(tt.expr)
+			if err != nil {
+				t.Fatalf("ParseCondition(%q) failed: %v", tt.expr, err)
+			}
+			if got := c.Evaluate(data); got != tt.want {
+				t.Errorf("Evaluate(%q) = %v, want %v", tt.expr, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCondition_EvaluateInvalidData(t *testing.T) {
+	c, err := ParseCondition(`status == "ok"`)
+	if err != nil {
+		t.Fatalf("ParseCondition failed: %v", err)
+	}
+
+	for _, data := range []ConditionData{
+		{},
+		{Response: json.RawMessage(`not json`)},
+		{Response: json.RawMessage(`["status"]`)},
+		{Response: json.RawMessage(`"ok"`)},
+	} {
+		if c.Evaluate(data) {
+			t.Errorf("Expected condition to be false for %+v", data)
+		}
+	}
+}
+
+func TestParseCondition_Malformed(t *testing.T) {
+	tests := []struct {
+		expr    string
+		wantErr string
+	}{
+		{``, "expression is empty"},
+		{`   `, "expression is empty"},
+		{`status ==`, "expected a value or field path but found end of expression"},
+		{`== "ok"`, `expected a value or field path but found "=="`},
+		{`status = "ok"`, `unexpected character '='`},
+		{`status == "ok`, "unterminated string"},
+		{`(status == "ok"`, `expected ")" but found end of expression`},
+		{`status == "ok")`, `unexpected ")"`},
+		{`status == "ok" &&`, "expected a value or field path"},
+		{`status == "ok" "extra"`, `unexpected "extra" at position 15`},
+		{`status == "ok" == "ok"`, `unexpected "=="`},
+		{`payload.`, `expected a field name after "."`},
+		{`responses[agent].x == 1`, "expected a quoted key or array index"},
+		{`tags[-1] == "x"`, "expected a quoted key or array index"},
+		{`tags[0 == "x"`, `expected "]"`},
+		{`exists("status")`, "exists() takes a field path"},
+		{`exists(status`, `expected ")"`},
+		{`total > 1.2.3`, "invalid number"},
+		{`status == "ok" ; drop`, `unexpected character ';'`},
+		{`approval@manager.com responds with approve=true`, `unexpected character '@'`},
+		{strings.Repeat("(", 40) + "true" + strings.Repeat(")", 40), "nested deeper than"},
+		{strings.Repeat("!", 40) + "true", "nested deeper than"},
+		{`status == "` + strings.Repeat("x", maxConditionLength) + `"`, "exceeds"},
+	}
+
+	for _, tt := range tests {
+		name := tt.expr
+		if len(name) > 50 {
+			name = name[:50]
+		}
+		t.Run(name, func(t *testing.T) {
+			_, err := ParseCondition(tt.expr)
+			if err == nil {
+				t.Fatalf("Expected ParseCondition(%q) to fail", tt.expr)
+			}
+			if !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
@@ -5,7 +5,6 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"strings"
 	"sync"
 	"time"
 
@@ -79,7 +78,10 @@ func (m *managerImpl) Initialize(ctx context.Context, msg *types.Message) (*type
 	case "conditional":
 		// Add initial participants and all conditional branches
 		participants = append(participants, msg.Recipients...)
-		for _, condition := range msg.Coordination.Conditions {
+		for i, condition := range msg.Coordination.Conditions {
+			if _, err := ParseCondition(condition.If); err != nil {
+				return nil, fmt.Errorf("condition %d: %w", i, err)
+			}
 			participants = append(participants, condition.Then...)
 			participants = append(participants, condition.Else...)
 		}
@@ -164,6 +166,76 @@ func (m *managerImpl) executeConditional(ctx context.Context, _ *types.Workflow,
 	return m.dispatcher.Dispatch(ctx, msg)
 }
 
+// executeConditionalBranches evaluates each rule and dispatches to its Then or
+// Else recipients, marking the other branch as skipped. Participants of a rule
+// whose condition cannot be parsed are marked failed.
+func (m *managerImpl) executeConditionalBranches(ctx context.Context, workflow *types.Workflow, coord *types.CoordinationConfig, replyMsg *types.Message) {
+	data := ConditionData{
+		Payload:   workflow.Payload,
+		Response:  replyMsg.Payload,
+		Responses: make(map[string]json.RawMessage),
+	}
+	for _, p := range workflow.Participants {
+		if len(p.ResponsePayload) > 0 {
+			data.Responses[p.Address] = p.ResponsePayload
+		}
+	}
+
+	for i, rule := range coord.Conditions {
+		condition, err := ParseCondition(rule.If)
+		if err != nil {
+			m.logger.Errorf(err, "Conditional rule %d of workflow %s is malformed", i, workflow.WorkflowID)
+			failure, _ := json.Marshal(map[string]string{"status": "failed", "error": err.Error()})
+			for _, addr := range append(append([]string(nil), rule.Then...), rule.Else...) {
+				if err := m.storage.UpdateWorkflowParticipant(ctx, workflow.WorkflowID, addr, types.ParticipantStatusFailed, failure); err != nil {
+					m.logger.Errorf(err, "Failed to update status for participant %s", addr)
+				}
+			}
+			continue
+		}
+
+		targets, skipped := rule.Else, rule.Then
+		if condition.Evaluate(data) {
+			targets, skipped = rule.Then, rule.Else
+		}
+
+		for _, s := range skipped {
+			if err := m.storage.UpdateWorkflowParticipant(ctx, workflow.WorkflowID, s, types.ParticipantStatusCompleted, []byte(`{"status":"skipped"}`)); err != nil {
+				m.logger.Errorf(err, "Failed to update status for skipped participant %s", s)
+			}
+		}
+
+		if len(targets) > 0 {
+			msgCopy := m.buildTemplateMessage(workflow)
+			msgCopy.Recipients = targets
+			if err := m.dispatcher.Dispatch(ctx, msgCopy); err != nil {
+				m.logger.Error("Failed to dispatch conditional branch messages", err)
+			}
+		}
+	}
+}
+
+// isOriginalRecipient reports whether address was a recipient of the message
+// that started the workflow
+func isOriginalRecipient(wf *types.Workflow, address string) bool {
+	for _, rec := range wf.OriginalRecipients {
+		if rec == address {
+			return true
+		}
+	}
+	return false
+}
+
+// initialStagePending reports whether any original recipient has yet to answer
+func initialStagePending(wf *types.Workflow) bool {
+	for _, p := range wf.Participants {
+		if p.Status == types.ParticipantStatusPending && isOriginalRecipient(wf, p.Address) {
+			return true
+		}
+	}
+	return false
+}
+
 func (m *managerImpl) ProcessResponse(ctx context.Context, workflowID string, replyMsg *types.Message) error {
 	for {
 		workflow, err := m.storage.GetWorkflow(ctx, workflowID)
@@ -373,62 +445,10 @@ func (m *managerImpl) evaluateWorkflow(ctx context.Context, workflowID string, r
 			return err
 		}
 
-		// Only evaluate condition if the reply is from an initial recipient
-		if replyMsg != nil {
-			isInitial := false
-			for _, rec := range workflow.OriginalRecipients {
-				if rec == replyMsg.Sender {
-					isInitial = true
-					break
-				}
-			}
-
-			if isInitial && len(replyMsg.Payload) > 0 {
-				var payload map[string]interface{}
-				if err := json.Unmarshal(replyMsg.Payload, &payload); err != nil {
-					m.logger.Error("Failed to unmarshal payload for conditional evaluation", err)
-				}
-
-				for _, condition := range coord.Conditions {
-					matched := false
-					if strings.Contains(condition.If, " == ") {
-						parts := strings.Split(condition.If, " == ")
-						if len(parts) == 2 {
-							key := strings.TrimSpace(parts[0])
-							val := strings.Trim(strings.TrimSpace(parts[1]), "\"'")
-							if v, ok := payload[key]; ok {
-								matched = (fmt.Sprintf("%v", v) == val)
-							}
-						}
-					} else {
-						matched = strings.Contains(string(replyMsg.Payload), condition.If)
-					}
-
-					var targets []string
-					var skipped []string
-					if matched {
-						targets = condition.Then
-						skipped = condition.Else
-					} else {
-						targets = condition.Else
-						skipped = condition.Then
-					}
-
-					for _, s := range skipped {
-						if err := m.storage.UpdateWorkflowParticipant(ctx, workflow.WorkflowID, s, types.ParticipantStatusCompleted, []byte(`{"status":"skipped"}`)); err != nil {
-							m.logger.Errorf(err, "Failed to update status for skipped participant %s", s)
-						}
-					}
-
-					if len(targets) > 0 {
-						msgCopy := m.buildTemplateMessage(workflow)
-						msgCopy.Recipients = targets
-						if err := m.dispatcher.Dispatch(ctx, msgCopy); err != nil {
-							m.logger.Error("Failed to dispatch conditional branch messages", err)
-						}
-					}
-				}
-			}
+		// Branch once every initial recipient has answered, so conditions can
+		// see all of their responses
+		if replyMsg != nil && isOriginalRecipient(workflow, replyMsg.Sender) && !initialStagePending(workflow) {
+			m.executeConditionalBranches(ctx, workflow, coord, replyMsg)
 		}
 
 		// Re-fetch to evaluate final status, in case participants got skipped
@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"strings"
 	"testing"
 	"time"
 
@@ -362,6 +363,174 @@ func TestManager_ProcessResponse_Conditional(t *testing.T) {
 	}
 }
 
+func TestManager_ProcessResponse_ConditionalElse(t *testing.T) {
+	st := newMockStorage()
+	dp := &mockDispatcher{}
+	mgr := NewManager(st, dp, nil)
+
+	msg := &types.Message{
+		MessageID:  "msg-ce",
+		Recipients: []string{"eval"},
+		Payload:    json.RawMessage(`{"amount":500}`),
+		Coordination: &types.CoordinationConfig{
+			Type: "conditional",
+			Conditions: []types.ConditionalRule{
+				{
+					If:   `score >= 0.8 && payload.amount < 1000`,
+					Then: []string{"approve"},
+					Else: []string{"review"},
+				},
+			},
+		},
+	}
+
+	wf, err := mgr.Initialize(context.Background(), msg)
+	if err != nil {
+		t.Fatalf("Initialize failed: %v", err)
+	}
+	dp.dispatched = nil
+
+	err = mgr.ProcessResponse(context.Background(), wf.WorkflowID, &types.Message{
+		Sender:  "eval",
+		Payload: json.RawMessage(`{"score":0.5}`),
+	})
+	if err != nil {
+		t.Fatalf("ProcessResponse failed: %v", err)
+	}
+
+	if len(dp.dispatched) != 1 || dp.dispatched[0].Recipients[0] != "review" {
+		t.Fatalf("Expected dispatch to the else branch, got %+v", dp.dispatched)
+	}
+
+	w, _ := st.GetWorkflow(context.Background(), wf.WorkflowID)
+	for _, p := range w.Participants {
+		if p.Address == "approve" && (p.Status != types.ParticipantStatusCompleted || string(p.ResponsePayload) != `{"status":"skipped"}`) {
+			t.Errorf("Expected then branch to be skipped, got %s %s", p.Status, p.ResponsePayload)
+		}
+	}
+	if w.Status == types.WorkflowStatusCompleted {
+		t.Errorf("Workflow should wait for the else branch")
+	}
+}
+
+func TestManager_ProcessResponse_ConditionalPriorResponses(t *testing.T) {
+	st := newMockStorage()
+	dp := &mockDispatcher{}
+	mgr := NewManager(st, dp, nil)
+
+	msg := &types.Message{
+		MessageID:  "msg-cp",
+		Recipients: []string{"legal", "finance"},
+		Coordination: &types.CoordinationConfig{
+			Type: "conditional",
+			Conditions: []types.ConditionalRule{
+				{
+					If:   `responses['legal'].approved && responses['finance'].approved`,
+					Then: []string{"fulfil"},
+					Else: []string{"escalate"},
+				},
+			},
+		},
+	}
+
+	wf, err := mgr.Initialize(context.Background(), msg)
+	if err != nil {
+		t.Fatalf("Initialize failed: %v", err)
+	}
+	dp.dispatched = nil
+
+	mgr.ProcessResponse(context.Background(), wf.WorkflowID, &types.Message{
+		Sender:  "legal",
+		Payload: json.RawMessage(`{"approved":true}`),
+	})
+	if len(dp.dispatched) != 0 {
+		t.Fatalf("Should not branch before every initial recipient answered, got %d dispatches", len(dp.dispatched))
+	}
+
+	mgr.ProcessResponse(context.Background(), wf.WorkflowID, &types.Message{
+		Sender:  "finance",
+		Payload: json.RawMessage(`{"approved":true}`),
+	})
+	if len(dp.dispatched) != 1 || dp.dispatched[0].Recipients[0] != "fulfil" {
+		t.Fatalf("Expected a single dispatch to fulfil, got %+v", dp.dispatched)
+	}
+}
+
+func TestManager_Initialize_MalformedCondition(t *testing.T) {
+	st := newMockStorage()
+	dp := &mockDispatcher{}
+	mgr := NewManager(st, dp, nil)
+
+	msg := &types.Message{
+		MessageID:  "msg-cm",
+		Recipients: []string{"eval"},
+		Coordination: &types.CoordinationConfig{
+			Type: "conditional",
+			Conditions: []types.ConditionalRule{
+				{If: `status == "ok"`, Then: []string{"a1"}},
+				{If: `status = "ok"`, Then: []string{"a2"}},
+			},
+		},
+	}
+
+	_, err := mgr.Initialize(context.Background(), msg)
+	if err == nil || !strings.Contains(err.Error(), "condition 1") {
+		t.Fatalf("Expected malformed condition 1 to be rejected, got %v", err)
+	}
+	if len(st.workflows) != 0 || len(dp.dispatched) != 0 {
+		t.Errorf("Malformed workflow should be neither stored nor dispatched")
+	}
+}
+
+func TestManager_ProcessResponse_MalformedStoredCondition(t *testing.T) {
+	st := newMockStorage()
+	dp := &mockDispatcher{}
+	mgr := NewManager(st, dp, nil)
+
+	// Workflows persisted before conditions were validated may still hold
+	// expressions that do not parse
+	now := time.Now()
+	st.workflows["wf-bad"] = &types.Workflow{
+		WorkflowID:       "wf-bad",
+		Status:           types.WorkflowStatusInProgress,
+		CoordinationType: "conditional",
+		CoordinationConfig: &types.CoordinationConfig{
+			Type: "conditional",
+			Conditions: []types.ConditionalRule{
+				{If: `status ==`, Then: []string{"a1"}, Else: []string{"a2"}},
+			},
+		},
+		OriginalRecipients: []string{"eval"},
+		Participants: []types.WorkflowParticipant{
+			{WorkflowID: "wf-bad", Address: "eval", Status: types.ParticipantStatusPending, CreatedAt: now},
+			{WorkflowID: "wf-bad", Address: "a1", Status: types.ParticipantStatusPending, CreatedAt: now},
+			{WorkflowID: "wf-bad", Address: "a2", Status: types.ParticipantStatusPending, CreatedAt: now},
+		},
+	}
+
+	err := mgr.ProcessResponse(context.Background(), "wf-bad", &types.Message{
+		Sender:  "eval",
+		Payload: json.RawMessage(`{"status":"ok"}`),
+	})
+	if err != nil {
+		t.Fatalf("ProcessResponse failed: %v", err)
+	}
+
+	if len(dp.dispatched) != 0 {
+		t.Errorf("Malformed condition should not dispatch to either branch, got %+v", dp.dispatched)
+	}
+
+	w, _ := st.GetWorkflow(context.Background(), "wf-bad")
+	if w.Status != types.WorkflowStatusFailed {
+		t.Errorf("Expected workflow to fail, got %s", w.Status)
+	}
+	for _, p := range w.Participants[1:] {
+		if p.Status != types.ParticipantStatusFailed {
+			t.Errorf("Expected %s to be failed, got %s", p.Address, p.Status)
+		}
+	}
+}
+
 func TestManager_TimeoutSweeper(t *testing.T) {
 	st := newMockStorage()
 	dp := &mockDispatcher{}
@@ -22,13 +22,16 @@ import (
 	"fmt"
 	"io"
 	"log"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 
 	"github.com/amtp-protocol/agentry/internal/config"
+	"github.com/amtp-protocol/agentry/internal/features"
 	"github.com/amtp-protocol/agentry/internal/server"
 	"github.com/amtp-protocol/agentry/internal/version"
 )
@@ -38,14 +41,25 @@ func printVersion(w io.Writer) {
 }
 
 func runHealthCheck(addr string) error {
-	// If addr starts with :, prepend localhost
-	if len(addr) > 0 && addr[0] == ':' {
-		addr = "localhost" + addr
-	}
 	// Simple GET request to /health
 	client := &http.Client{
 		Timeout: 2 * time.Second,
 	}
+
+	if path, ok := config.UnixSocketPath(addr); ok {
+		// The host is ignored; every connection is dialed to the socket
+		client.Transport = &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				var d net.Dialer
+				return d.DialContext(ctx, "unix", path)
+			},
+		}
+		addr = "localhost"
+	} else if len(addr) > 0 && addr[0] == ':' {
+		// If addr starts with :, prepend localhost
+		addr = "localhost" + addr
+	}
+
 	resp, err := client.Get("http://" + addr + "/health")
 	if err != nil {
 		return err
@@ -57,6 +71,18 @@ func runHealthCheck(addr string) error {
 	return nil
 }
 
+// reloadFeatures re-reads the configuration and applies its feature flags.
+// Everything else in it only takes effect on restart. A configuration that
+// fails to load leaves the current flags in place.
+func reloadFeatures(configFile, adminKeyFile string) error {
+	cfg, err := config.Load(configFile, adminKeyFile)
+	if err != nil {
+		return err
+	}
+	features.Set(cfg.Features)
+	return nil
+}
+
 func main() {
 	healthCheck := flag.Bool("health-check", false, "Run health check")
 	showVersion := flag.Bool("version", false, "Print version and exit")
@@ -83,6 +109,10 @@ func main() {
 		os.Exit(0)
 	}
 
+	if len(cfg.Files) > 0 {
+		log.Printf("Loaded configuration from %s", strings.Join(cfg.Files, ", "))
+	}
+
 	// Create HTTP server
 	srv, err := server.New(cfg)
 	if err != nil {
@@ -97,10 +127,25 @@ func main() {
 		}
 	}()
 
-	// Wait for interrupt signal to gracefully shutdown the server
+	// Reload feature flags on SIGHUP until an interrupt signal asks to
+	// gracefully shutdown the server
+	reload := make(chan os.Signal, 1)
+	signal.Notify(reload, syscall.SIGHUP)
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
-	<-quit
+	for running := true; running; {
+		select {
+		case <-reload:
+			if err := reloadFeatures(*configFile, *adminKeyFile); err != nil {
+				log.Printf("Failed to reload configuration, keeping feature flags %v: %v", features.List(), err)
+				continue
+			}
+			log.Printf("Reloaded feature flags: %v", features.List())
+		case <-quit:
+			running = false
+		}
+	}
+	signal.Stop(reload)
 	log.Println("Shutting down server...")
 
 	// Create a deadline for shutdown
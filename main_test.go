@@ -18,8 +18,13 @@ package main
 
 import (
 	"bytes"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
 	"testing"
 
+	"github.com/amtp-protocol/agentry/internal/features"
 	"github.com/amtp-protocol/agentry/internal/version"
 )
 
@@ -32,3 +37,60 @@ func TestPrintVersion(t *testing.T) {
 		t.Errorf("printVersion() = %q, want %q", got, want)
 	}
 }
+
+func TestRunHealthCheck_UnixSocket(t *testing.T) {
+	socketPath := filepath.Join(t.TempDir(), "agentry.sock")
+	listener, err := net.Listen("unix", socketPath)
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+
+	status := http.StatusOK
+	srv := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/health" {
+			http.NotFound(w, r)
+			return
+		}
+		w.WriteHeader(status)
+	})}
+	go func() { _ = srv.Serve(listener) }()
+	defer srv.Close()
+
+	if err := runHealthCheck("unix:" + socketPath); err != nil {
+		t.Errorf("runHealthCheck() error = %v", err)
+	}
+
+	status = http.StatusServiceUnavailable
+	if err := runHealthCheck("unix:" + socketPath); err == nil {
+		t.Error("runHealthCheck() expected error for unhealthy gateway")
+	}
+}
+
+func TestReloadFeatures(t *testing.T) {
+	defer features.Set(nil)
+
+	configFile := filepath.Join(t.TempDir(), "config.yaml")
+	writeConfig := func(content string) {
+		t.Helper()
+		if err := os.WriteFile(configFile, []byte(content), 0o600); err != nil {
+			t.Fatalf("write config: %v", err)
+		}
+	}
+
+	writeConfig("tls:\n  enabled: false\nfeatures:\n  strict_schema: true\n")
+	if err := reloadFeatures(configFile, ""); err != nil {
+		t.Fatalf("reloadFeatures() error = %v", err)
+	}
+	if !features.Enabled(features.StrictSchema) {
+		t.Error("Expected strict_schema to be on after reload")
+	}
+
+	// A broken configuration keeps the flags already in place
+	writeConfig("tls:\n  enabled: false\nfeatures:\n  strict_shcema: true\n")
+	if err := reloadFeatures(configFile, ""); err == nil {
+		t.Error("reloadFeatures() expected error for an unknown flag")
+	}
+	if !features.Enabled(features.StrictSchema) {
+		t.Error("Expected strict_schema to stay on after a failed reload")
+	}
+}
@@ -0,0 +1,142 @@
+/*
+ * Copyright 2026 Cong Wang
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package uuid
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Message ID strategies
+const (
+	StrategyUUIDv7   = "uuidv7"
+	StrategyULID     = "ulid"
+	StrategyPrefixed = "prefixed"
+)
+
+// maxPrefixLength bounds the prefix of prefixed message IDs
+const maxPrefixLength = 16
+
+// Generator creates message IDs under one strategy and recognizes the IDs
+// it accepts. Every strategy also accepts UUIDv7, the protocol's message ID
+// format, so messages from other gateways and messages stored before a
+// change of strategy stay valid. Other gateways only receive IDs of another
+// strategy if they advertise its Feature.
+type Generator interface {
+	// Generate returns a new message ID
+	Generate() (string, error)
+	// IsValid reports whether id is a message ID the strategy accepts
+	IsValid(id string) bool
+	// Format describes the accepted IDs for error messages
+	Format() string
+}
+
+// V7 is the default generator, creating UUIDv7 message IDs
+var V7 Generator = v7Generator{}
+
+// NewGenerator returns the generator for a strategy. Prefix is required by
+// the prefixed strategy and ignored by the others.
+func NewGenerator(strategy, prefix string) (Generator, error) {
+	switch strategy {
+	case "", StrategyUUIDv7:
+		return V7, nil
+	case StrategyULID:
+		return ulidGenerator{}, nil
+	case StrategyPrefixed:
+		if !IsValidPrefix(prefix) {
+			return nil, fmt.Errorf("message ID prefix %q must be 1 to %d lowercase letters or digits", prefix, maxPrefixLength)
+		}
+		return prefixedGenerator{prefix: prefix + "-"}, nil
+	default:
+		return nil, fmt.Errorf("unknown message ID strategy %q, must be '%s', '%s' or '%s'",
+			strategy, StrategyUUIDv7, StrategyULID, StrategyPrefixed)
+	}
+}
+
+// Strategy returns the strategy that creates id, or "" if none does
+func Strategy(id string) string {
+	switch {
+	case IsValidV7(id):
+		return StrategyUUIDv7
+	case IsValidULID(id):
+		return StrategyULID
+	}
+	if prefix, rest, ok := strings.Cut(id, "-"); ok && IsValidPrefix(prefix) && IsValidV7(rest) {
+		return StrategyPrefixed
+	}
+	return ""
+}
+
+// Feature is the capability feature a gateway advertises when it accepts
+// message IDs of a strategy other than UUIDv7, e.g. message-ids-ulid
+func Feature(strategy string) string {
+	return "message-ids-" + strategy
+}
+
+// IsValidPrefix validates a prefix for prefixed message IDs, such as a shard
+// or region name
+func IsValidPrefix(prefix string) bool {
+	if prefix == "" || len(prefix) > maxPrefixLength {
+		return false
+	}
+	for _, c := range prefix {
+		if (c < 'a' || c > 'z') && (c < '0' || c > '9') {
+			return false
+		}
+	}
+	return true
+}
+
+type v7Generator struct{}
+
+func (v7Generator) Generate() (string, error) { return GenerateV7() }
+func (v7Generator) IsValid(id string) bool    { return IsValidV7(id) }
+func (v7Generator) Format() string            { return "UUIDv7" }
+
+// ulidGenerator creates ULIDs
+type ulidGenerator struct{}
+
+func (ulidGenerator) Generate() (string, error) { return GenerateULID() }
+func (ulidGenerator) IsValid(id string) bool    { return IsValidULID(id) || IsValidV7(id) }
+func (ulidGenerator) Format() string            { return "ULID or UUIDv7" }
+
+// prefixedGenerator creates UUIDv7s behind a fixed prefix and a hyphen,
+// e.g. eu1-01890a5d-ac96-774b-bcce-b302099a8057. It accepts any prefix, as
+// other shards and regions relay IDs carrying theirs.
+type prefixedGenerator struct {
+	prefix string
+}
+
+func (g prefixedGenerator) Generate() (string, error) {
+	id, err := GenerateV7()
+	if err != nil {
+		return "", err
+	}
+	return g.prefix + id, nil
+}
+
+func (g prefixedGenerator) IsValid(id string) bool {
+	switch Strategy(id) {
+	case StrategyUUIDv7, StrategyPrefixed:
+		return true
+	}
+	return false
+}
+
+func (g prefixedGenerator) Format() string {
+	return "prefixed UUIDv7 or UUIDv7"
+}
@@ -0,0 +1,125 @@
+/*
+ * Copyright 2026 Cong Wang
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package uuid
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNewGenerator(t *testing.T) {
+	const v7 = "01890a5d-ac96-774b-bcce-b302099a8057"
+	const ulid = "01ARZ3NDEKTSV4RRFFQ69G5FAV"
+
+	tests := []struct {
+		strategy string
+		prefix   string
+		valid    []string
+		invalid  []string
+	}{
+		{
+			strategy: "",
+			valid:    []string{v7},
+			invalid:  []string{ulid, "eu1-" + v7, "550e8400-e29b-41d4-a716-446655440000"},
+		},
+		{
+			strategy: StrategyUUIDv7,
+			valid:    []string{v7},
+			invalid:  []string{ulid, "eu1-" + v7},
+		},
+		{
+			strategy: StrategyULID,
+			valid:    []string{ulid, v7},
+			invalid:  []string{strings.ToLower(ulid), "eu1-" + v7},
+		},
+		{
+			strategy: StrategyPrefixed,
+			prefix:   "eu1",
+			valid:    []string{"eu1-" + v7, "us2-" + v7, v7},
+			invalid:  []string{"EU1-" + v7, "eu1-" + ulid, "eu1" + v7, ulid},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.strategy, func(t *testing.T) {
+			g, err := NewGenerator(tt.strategy, tt.prefix)
+			if err != nil {
+				t.Fatalf("NewGenerator failed: %v", err)
+			}
+
+			id, err := g.Generate()
+			if err != nil {
+				t.Fatalf("Generate failed: %v", err)
+			}
+			if !g.IsValid(id) {
+				t.Errorf("Generated ID %q is not valid", id)
+			}
+			if tt.prefix != "" && !strings.HasPrefix(id, tt.prefix+"-") {
+				t.Errorf("Expected ID %q to start with %s-", id, tt.prefix)
+			}
+
+			for _, id := range tt.valid {
+				if !g.IsValid(id) {
+					t.Errorf("Expected %q to be valid", id)
+				}
+			}
+			for _, id := range tt.invalid {
+				if g.IsValid(id) {
+					t.Errorf("Expected %q to be invalid", id)
+				}
+			}
+		})
+	}
+}
+
+func TestStrategy(t *testing.T) {
+	const v7 = "01890a5d-ac96-774b-bcce-b302099a8057"
+
+	tests := map[string]string{
+		v7:                                     StrategyUUIDv7,
+		"01ARZ3NDEKTSV4RRFFQ69G5FAV":           StrategyULID,
+		"eu1-" + v7:                            StrategyPrefixed,
+		"eu1" + v7:                             "",
+		"550e8400-e29b-41d4-a716-446655440000": "",
+		"":                                     "",
+	}
+
+	for id, want := range tests {
+		if got := Strategy(id); got != want {
+			t.Errorf("Strategy(%q) = %q, want %q", id, got, want)
+		}
+	}
+}
+
+func TestNewGenerator_Invalid(t *testing.T) {
+	tests := []struct {
+		strategy string
+		prefix   string
+	}{
+		{"uuidv4", ""},
+		{StrategyPrefixed, ""},
+		{StrategyPrefixed, "EU1"},
+		{StrategyPrefixed, "eu-1"},
+		{StrategyPrefixed, strings.Repeat("a", maxPrefixLength+1)},
+	}
+
+	for _, tt := range tests {
+		if _, err := NewGenerator(tt.strategy, tt.prefix); err == nil {
+			t.Errorf("Expected strategy %q with prefix %q to be rejected", tt.strategy, tt.prefix)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+/*
+ * Copyright 2026 Cong Wang
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package uuid
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// crockford is the Crockford base32 alphabet ULIDs are written in
+const crockford = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// ulidLength is the length of a ULID string: 128 bits in 5-bit characters
+const ulidLength = 26
+
+// GenerateULID generates a ULID: a 48-bit Unix timestamp in milliseconds
+// followed by 80 random bits, in canonical uppercase Crockford base32, so
+// ULIDs sort by creation time as plain strings
+func GenerateULID() (string, error) {
+	var ulid [16]byte
+
+	// Set timestamp (first 48 bits)
+	timestamp := time.Now().UnixMilli()
+	binary.BigEndian.PutUint16(ulid[0:2], uint16(timestamp>>32)) // #nosec G115 -- false positive
+	binary.BigEndian.PutUint32(ulid[2:6], uint32(timestamp))     // #nosec G115 -- false positive
+
+	// Generate random bytes for the rest
+	if _, err := rand.Read(ulid[6:]); err != nil {
+		return "", fmt.Errorf("failed to generate random bytes: %w", err)
+	}
+
+	// 26 characters hold 130 bits; the two leading bits are always zero
+	out := make([]byte, ulidLength)
+	for i := range out {
+		var v byte
+		for j := 0; j < 5; j++ {
+			bit := i*5 + j - 2
+			v <<= 1
+			if bit >= 0 && ulid[bit/8]&(0x80>>(bit%8)) != 0 {
+				v |= 1
+			}
+		}
+		out[i] = crockford[v]
+	}
+	return string(out), nil
+}
+
+// IsValidULID validates that a string is a ULID in canonical uppercase
+func IsValidULID(ulid string) bool {
+	if len(ulid) != ulidLength {
+		return false
+	}
+
+	// The first character carries only three bits
+	if ulid[0] > '7' {
+		return false
+	}
+
+	for i := 0; i < len(ulid); i++ {
+		if !strings.ContainsRune(crockford, rune(ulid[i])) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,67 @@
+/*
+ * Copyright 2026 Cong Wang
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package uuid
+
+import (
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestGenerateULID(t *testing.T) {
+	var ids []string
+	for i := 0; i < 3; i++ {
+		id, err := GenerateULID()
+		if err != nil {
+			t.Fatalf("Failed to generate ULID: %v", err)
+		}
+		if !IsValidULID(id) {
+			t.Errorf("Generated ULID is not valid: %s", id)
+		}
+		ids = append(ids, id)
+		time.Sleep(2 * time.Millisecond)
+	}
+
+	if ids[0] == ids[1] || ids[1] == ids[2] {
+		t.Error("Generated ULIDs should be unique")
+	}
+	if !sort.StringsAreSorted(ids) {
+		t.Errorf("Expected ULIDs to sort by creation time, got %v", ids)
+	}
+}
+
+func TestIsValidULID(t *testing.T) {
+	tests := []struct {
+		id    string
+		valid bool
+	}{
+		{"01ARZ3NDEKTSV4RRFFQ69G5FAV", true},
+		{"7ZZZZZZZZZZZZZZZZZZZZZZZZZ", true},
+		{"8ZZZZZZZZZZZZZZZZZZZZZZZZZ", false}, // overflows 128 bits
+		{"01arz3ndektsv4rrffq69g5fav", false}, // not canonical
+		{"01ARZ3NDEKTSV4RRFFQ69G5FAI", false}, // I is not in the alphabet
+		{"01ARZ3NDEKTSV4RRFFQ69G5FA", false},
+		{"01890a5d-ac96-774b-bcce-b302099a8057", false},
+		{"", false},
+	}
+
+	for _, tt := range tests {
+		if got := IsValidULID(tt.id); got != tt.valid {
+			t.Errorf("IsValidULID(%q) = %v, want %v", tt.id, got, tt.valid)
+		}
+	}
+}